@@ -1,163 +0,0 @@
-// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
-// versions:
-// - protoc-gen-go-grpc v1.6.0
-// - protoc             v3.19.6
-// source: api/proto/machine.proto
-
-package proto
-
-import (
-	context "context"
-	grpc "google.golang.org/grpc"
-	codes "google.golang.org/grpc/codes"
-	status "google.golang.org/grpc/status"
-)
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.64.0 or later.
-const _ = grpc.SupportPackageIsVersion9
-
-const (
-	MachineService_GetStatus_FullMethodName    = "/openmachinecore.v1.MachineService/GetStatus"
-	MachineService_StreamStatus_FullMethodName = "/openmachinecore.v1.MachineService/StreamStatus"
-)
-
-// MachineServiceClient is the client API for MachineService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type MachineServiceClient interface {
-	GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*MachineStatusResponse, error)
-	StreamStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MachineStatusResponse], error)
-}
-
-type machineServiceClient struct {
-	cc grpc.ClientConnInterface
-}
-
-func NewMachineServiceClient(cc grpc.ClientConnInterface) MachineServiceClient {
-	return &machineServiceClient{cc}
-}
-
-func (c *machineServiceClient) GetStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*MachineStatusResponse, error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(MachineStatusResponse)
-	err := c.cc.Invoke(ctx, MachineService_GetStatus_FullMethodName, in, out, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *machineServiceClient) StreamStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MachineStatusResponse], error) {
-	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &MachineService_ServiceDesc.Streams[0], MachineService_StreamStatus_FullMethodName, cOpts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &grpc.GenericClientStream[StatusRequest, MachineStatusResponse]{ClientStream: stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
-}
-
-// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type MachineService_StreamStatusClient = grpc.ServerStreamingClient[MachineStatusResponse]
-
-// MachineServiceServer is the server API for MachineService service.
-// All implementations must embed UnimplementedMachineServiceServer
-// for forward compatibility.
-type MachineServiceServer interface {
-	GetStatus(context.Context, *StatusRequest) (*MachineStatusResponse, error)
-	StreamStatus(*StatusRequest, grpc.ServerStreamingServer[MachineStatusResponse]) error
-	mustEmbedUnimplementedMachineServiceServer()
-}
-
-// UnimplementedMachineServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedMachineServiceServer struct{}
-
-func (UnimplementedMachineServiceServer) GetStatus(context.Context, *StatusRequest) (*MachineStatusResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
-}
-func (UnimplementedMachineServiceServer) StreamStatus(*StatusRequest, grpc.ServerStreamingServer[MachineStatusResponse]) error {
-	return status.Error(codes.Unimplemented, "method StreamStatus not implemented")
-}
-func (UnimplementedMachineServiceServer) mustEmbedUnimplementedMachineServiceServer() {}
-func (UnimplementedMachineServiceServer) testEmbeddedByValue()                        {}
-
-// UnsafeMachineServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to MachineServiceServer will
-// result in compilation errors.
-type UnsafeMachineServiceServer interface {
-	mustEmbedUnimplementedMachineServiceServer()
-}
-
-func RegisterMachineServiceServer(s grpc.ServiceRegistrar, srv MachineServiceServer) {
-	// If the following call panics, it indicates UnimplementedMachineServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
-	}
-	s.RegisterService(&MachineService_ServiceDesc, srv)
-}
-
-func _MachineService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(StatusRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(MachineServiceServer).GetStatus(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: MachineService_GetStatus_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MachineServiceServer).GetStatus(ctx, req.(*StatusRequest))
-	}
-	return interceptor(ctx, in, info, handler)
-}
-
-func _MachineService_StreamStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(StatusRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
-	}
-	return srv.(MachineServiceServer).StreamStatus(m, &grpc.GenericServerStream[StatusRequest, MachineStatusResponse]{ServerStream: stream})
-}
-
-// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type MachineService_StreamStatusServer = grpc.ServerStreamingServer[MachineStatusResponse]
-
-// MachineService_ServiceDesc is the grpc.ServiceDesc for MachineService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var MachineService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "openmachinecore.v1.MachineService",
-	HandlerType: (*MachineServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "GetStatus",
-			Handler:    _MachineService_GetStatus_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "StreamStatus",
-			Handler:       _MachineService_StreamStatus_Handler,
-			ServerStreams: true,
-		},
-	},
-	Metadata: "api/proto/machine.proto",
-}
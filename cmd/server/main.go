@@ -1,16 +1,24 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	grpcapi "github.com/KevinKickass/OpenMachineCore/internal/api/grpc"
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/buildinfo"
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/demo"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/service"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/system"
 	"go.uber.org/zap"
@@ -18,8 +26,14 @@ import (
 
 var (
 	generateToken = flag.String("generate-machine-token", "", "Generate a new machine token with the given name")
-	createAdmin   = flag.Bool("create-admin", false, "Create default admin user (username: admin, password: admin123)")
+	createAdmin   = flag.Bool("create-admin", false, "Create the initial admin from OMC_ADMIN_USERNAME and OMC_ADMIN_PASSWORD (deprecated, use /api/v1/setup)")
 	configPath    = flag.String("config", "configs/config.yaml", "Path to configuration file")
+	genMasterKey  = flag.Bool("generate-master-key", false, "Generate a new master key for encrypted secrets")
+	encryptSecret = flag.Bool("encrypt-secret", false, "Encrypt a value read from stdin for the config file (e.g. database.password)")
+	validateCfg   = flag.Bool("validate-config", false, "Check the configuration file and print the effective configuration")
+	checkConnect  = flag.Bool("check-connections", false, "With -validate-config, also connect to the database and resolve referenced secrets")
+	seedDemo      = flag.Bool("seed-demo", false, "Install demo data: a simulated device, home/stop/production workflows, an operator and a machine token")
+	removeDemo    = flag.Bool("remove-demo", false, "Remove the demo data installed with -seed-demo")
 )
 
 func main() {
@@ -29,12 +43,70 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	// Generate Master Key (needs no config)
+	if *genMasterKey {
+		key, err := secrets.GenerateMasterKey()
+		if err != nil {
+			logger.Fatal("Failed to generate master key", zap.Error(err))
+		}
+
+		fmt.Println("\nMaster Key Generated Successfully!")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("Key: %s\n", key)
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Println("\nIMPORTANT: Store this key outside the database backup!")
+		fmt.Println("   Secrets can't be decrypted without it.")
+		fmt.Printf("   export OMC_MASTER_KEY=%s\n\n", key)
+
+		os.Exit(0)
+	}
+
+	// Validate Config (reports all problems instead of failing on the first)
+	if *validateCfg {
+		os.Exit(validateConfig(config.ResolvePath(*configPath), *checkConnect))
+	}
+
 	// Config laden (verwendet Viper - unterstützt YAML + ENV)
-	cfg, err := config.Load(*configPath)
+	cfg, err := config.Load(config.ResolvePath(*configPath))
 	if err != nil {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
+	// Master key for encrypted secrets (optional)
+	keyring, err := secrets.NewKeyring(cfg.Secrets)
+	if err != nil && !errors.Is(err, secrets.ErrNoMasterKey) {
+		logger.Fatal("Failed to load master key", zap.Error(err))
+	}
+
+	// Encrypt Secret
+	if *encryptSecret {
+		if keyring == nil {
+			logger.Fatal("Failed to encrypt secret", zap.Error(secrets.ErrNoMasterKey))
+		}
+		fmt.Fprintln(os.Stderr, "Enter the value to encrypt:")
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && value == "" {
+			logger.Fatal("Failed to read value", zap.Error(err))
+		}
+		encrypted, err := keyring.EncryptValue(strings.TrimRight(value, "\r\n"))
+		if err != nil {
+			logger.Fatal("Failed to encrypt secret", zap.Error(err))
+		}
+		fmt.Println(encrypted)
+
+		os.Exit(0)
+	}
+
+	// Encrypted database password
+	if secrets.IsEncrypted(cfg.Database.Password) {
+		if cfg.Database.Password, err = keyring.DecryptValue(cfg.Database.Password); err != nil {
+			logger.Fatal("Failed to decrypt database password", zap.Error(err))
+		}
+	} else if keyring != nil && cfg.Database.Password != "" {
+		logger.Warn("Database password is stored in plaintext",
+			zap.String("recommendation", "Encrypt it with -encrypt-secret"))
+	}
+
 	// Security Check: JWT Secret
 	if !cfg.Auth.IsProductionReady() {
 		logger.Warn("WARNING: Using default or insecure JWT secret!",
@@ -49,10 +121,19 @@ func main() {
 	defer pgClient.Close()
 
 	// Auth Service (verwendet Config inkl. JWT Secret aus ENV)
-	authService := auth.NewAuthService(pgClient, cfg.Auth)
+	authService, err := auth.NewAuthService(pgClient, cfg.Auth, keyring)
+	if err != nil {
+		logger.Fatal("Failed to initialize authentication", zap.Error(err))
+	}
 
 	ctx := context.Background()
 
+	// Rotated JWT signing keys. Without them the server would sign with the
+	// configured secret again, which may have been retired.
+	if err := authService.LoadSigningKeys(ctx); err != nil {
+		logger.Fatal("Failed to load JWT signing keys", zap.Error(err))
+	}
+
 	// ==================== CLI COMMANDS ====================
 
 	// Generate Machine Token
@@ -86,34 +167,95 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Create Admin User
+	// Create the initial admin, superseded by the setup API
 	if *createAdmin {
-		user, err := authService.CreateUser(ctx, "admin", "admin123", "admin")
+		logger.Warn("-create-admin is deprecated, provision new machines with the /api/v1/setup endpoints")
+
+		username := os.Getenv("OMC_ADMIN_USERNAME")
+		if username == "" {
+			username = "admin"
+		}
+		password := os.Getenv("OMC_ADMIN_PASSWORD")
+		if password == "" {
+			logger.Fatal("Set OMC_ADMIN_PASSWORD to the password of the initial admin")
+		}
+
+		user, err := authService.CreateInitialAdmin(ctx, username, password, "cli")
 		if err != nil {
+			if errors.Is(err, storage.ErrUsersExist) || errors.Is(err, storage.ErrSetupCompleted) {
+				logger.Fatal("Initial admin already exists, manage users with /api/v1/users")
+			}
 			logger.Fatal("Failed to create admin user", zap.Error(err))
 		}
 
-		fmt.Println("\nAdmin User Created Successfully!")
+		fmt.Println("\nInitial Admin Created Successfully!")
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		fmt.Printf("Username: %s\n", user.Username)
-		fmt.Printf("Password: admin123\n")
 		fmt.Printf("Role:     %s\n", user.Role)
 		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		fmt.Println("\nCHANGE THE PASSWORD IMMEDIATELY IN PRODUCTION!")
+		fmt.Println("\nLog in and finish with POST /api/v1/setup/complete")
+
+		os.Exit(0)
+	}
+
+	// Install demo data for evaluation
+	if *seedDemo {
+		result, err := demo.Seed(ctx, pgClient, authService)
+		if err != nil {
+			if errors.Is(err, demo.ErrInstalled) {
+				logger.Fatal("Demo data already installed, remove it first with -remove-demo")
+			}
+			logger.Fatal("Failed to install demo data, remove the partial data with -remove-demo", zap.Error(err))
+		}
+
+		fmt.Println("\nDemo Data Installed Successfully!")
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Printf("Device:      %s (Modbus TCP simulator on 127.0.0.1:5020)\n", demo.Device)
+		fmt.Printf("Home:        %s\n", result.Workflows["home"])
+		fmt.Printf("Stop:        %s\n", result.Workflows["stop"])
+		fmt.Printf("Production:  %s\n", result.Workflows["production"])
+		fmt.Printf("Operator:    %s / %s\n", demo.Username, result.Password)
+		fmt.Printf("Token:       %s\n", result.Token)
+		fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Println("\nThe password and token will NOT be displayed again.")
+		fmt.Println("   Assign the workflows to the machine with POST /api/v1/machine/configure")
+		fmt.Println("   Remove all demo data with -remove-demo")
+		fmt.Println()
+
+		os.Exit(0)
+	}
+
+	// Remove demo data
+	if *removeDemo {
+		removed, err := demo.Remove(ctx, pgClient)
+		if err != nil {
+			logger.Fatal("Failed to remove demo data", zap.Error(err), zap.Int("removed", removed))
+		}
+		fmt.Printf("Removed %d demo record(s)\n", removed)
 
 		os.Exit(0)
 	}
 
 	// ==================== NORMAL SERVER START ====================
 
+	// Report to systemd / the Windows service control manager
+	notifier, err := service.Start(logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to service manager", zap.Error(err))
+	}
+
 	logger.Info("Starting OpenMachineCore",
-		zap.String("version", "0.1.0"),
+		zap.String("version", buildinfo.Version),
+		zap.String("commit", buildinfo.Get().Commit),
 		zap.Int("http_port", cfg.Server.HTTPPort),
-		zap.Int("grpc_port", cfg.Server.GRPCPort))
+		zap.Int("grpc_port", cfg.Server.GRPCPort),
+		zap.String("http_socket", cfg.Server.HTTPSocket),
+		zap.String("grpc_socket", cfg.Server.GRPCSocket))
 
 	// System Lifecycle Manager MIT authService
 	// KORRIGIERT: Richtige Parameter-Reihenfolge
 	lifecycleManager := system.NewLifecycleManager(pgClient, cfg, logger, authService)
+	lifecycleManager.SetGRPCServices(grpcapi.NewServices(lifecycleManager))
 
 	// Start system - direkt ohne Initialize()
 	if err := lifecycleManager.Start(); err != nil {
@@ -121,13 +263,20 @@ func main() {
 	}
 
 	logger.Info("OpenMachineCore started successfully")
+	notifier.Ready(func() bool {
+		return lifecycleManager.GetCurrentStatus().State != system.StateError.String()
+	})
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case <-notifier.StopRequested():
+	}
 
 	logger.Info("Shutting down OpenMachineCore...")
+	notifier.Stopping()
 
 	// KORRIGIERT: Shutdown mit Context
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -138,4 +287,5 @@ func main() {
 	}
 
 	logger.Info("OpenMachineCore stopped")
+	notifier.Stopped()
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// connectTimeout bounds the database check of -validate-config
+const connectTimeout = 10 * time.Second
+
+// validateConfig checks the configuration file, optionally connects to the
+// database and resolves referenced secrets, and prints the problems and the
+// effective configuration. It returns the process exit code.
+func validateConfig(path string, connect bool) int {
+	result := config.Validate(path)
+	if result.Config != nil {
+		if err := workflow.CheckLintConfig(result.Config.Lint); err != nil {
+			result.Problems = append(result.Problems, config.Problem{Key: "lint", Severity: config.SeverityError, Message: err.Error()})
+		}
+	}
+	if result.Config != nil && connect {
+		result.Problems = append(result.Problems, checkConnections(result.Config)...)
+	}
+
+	fmt.Printf("Configuration: %s\n", path)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if len(result.Problems) == 0 {
+		fmt.Println("No problems found")
+	}
+	for _, p := range result.Problems {
+		fmt.Println(p.String())
+	}
+
+	if result.Config != nil {
+		out, err := yaml.Marshal(config.EffectiveSettings())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print effective configuration: %v\n", err)
+		} else {
+			fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+			fmt.Println("Effective configuration (defaults, file and OMC_* environment):")
+			fmt.Println()
+			fmt.Print(string(out))
+		}
+	}
+
+	if result.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// checkConnections verifies the master key, the database and the secrets
+// referenced by the configuration
+func checkConnections(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	fail := func(key, format string, args ...any) {
+		problems = append(problems, config.Problem{Key: key, Severity: config.SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+
+	keyring, err := secrets.NewKeyring(cfg.Secrets)
+	if err != nil && !errors.Is(err, secrets.ErrNoMasterKey) {
+		fail("secrets", "%v", err)
+	}
+
+	db := cfg.Database
+	if secrets.IsEncrypted(db.Password) {
+		if keyring == nil {
+			fail("database.password", "is encrypted but no master key is available")
+			return problems
+		}
+		if db.Password, err = keyring.DecryptValue(db.Password); err != nil {
+			fail("database.password", "failed to decrypt: %v", err)
+			return problems
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	type result struct {
+		client *storage.PostgresClient
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		client, err := storage.NewPostgresClient(db)
+		done <- result{client, err}
+	}()
+	var pg *storage.PostgresClient
+	select {
+	case r := <-done:
+		if r.err != nil {
+			fail("database", "%v", r.err)
+			return problems
+		}
+		pg = r.client
+	case <-ctx.Done():
+		fail("database", "no connection to %s:%d within %s", db.Host, db.Port, connectTimeout)
+		return problems
+	}
+	defer pg.Close()
+
+	store := secrets.NewManager(keyring, pg, zap.NewNop())
+	for i, sink := range cfg.Outbox.Sinks {
+		for header, name := range sink.SecretHeaders {
+			key := fmt.Sprintf("outbox.sinks[%d].secret_headers.%s", i, header)
+			if keyring == nil {
+				fail(key, "secret %q needs a master key", name)
+				continue
+			}
+			if _, err := store.Get(ctx, name); err != nil {
+				fail(key, "secret %q: %v", name, err)
+			}
+		}
+	}
+	return problems
+}
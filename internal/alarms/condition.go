@@ -0,0 +1,123 @@
+package alarms
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// Priorities in ascending order
+var priorities = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// DefaultPriority is used when a definition doesn't set one
+const DefaultPriority = "medium"
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// ValidateDefinition checks an alarm definition before it is stored and
+// fills in the default priority
+func ValidateDefinition(def *storage.AlarmDefinition) error {
+	var errs []error
+
+	if def.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if def.Text == "" {
+		errs = append(errs, errors.New("text is required"))
+	}
+	if def.Priority == "" {
+		def.Priority = DefaultPriority
+	}
+	if _, ok := priorities[def.Priority]; !ok {
+		errs = append(errs, fmt.Errorf("unknown priority %q (low, medium, high, critical)", def.Priority))
+	}
+
+	switch {
+	case def.Condition != nil && def.EventType != "":
+		errs = append(errs, errors.New("condition and event_type are mutually exclusive"))
+	case def.Condition == nil && def.EventType == "":
+		errs = append(errs, errors.New("either condition or event_type is required"))
+	case def.Condition != nil:
+		cond := def.Condition
+		if cond.Device == "" || cond.Register == "" {
+			errs = append(errs, errors.New("condition needs device and register"))
+		}
+		if !operators[cond.Operator] {
+			errs = append(errs, fmt.Errorf("unknown condition operator %q (eq, ne, gt, ge, lt, le)", cond.Operator))
+		}
+		if _, ok := toFloat(cond.Value); !ok {
+			errs = append(errs, fmt.Errorf("condition value must be a number or bool, got %T", cond.Value))
+		}
+	default:
+		if _, err := path.Match(def.EventType, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid event_type pattern: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// evaluate compares a register value with the condition
+func evaluate(cond *storage.AlarmCondition, value any) (bool, bool) {
+	got, ok := toFloat(value)
+	if !ok {
+		return false, false
+	}
+	want, ok := toFloat(cond.Value)
+	if !ok {
+		return false, false
+	}
+
+	switch cond.Operator {
+	case "eq":
+		return got == want, true
+	case "ne":
+		return got != want, true
+	case "gt":
+		return got > want, true
+	case "ge":
+		return got >= want, true
+	case "lt":
+		return got < want, true
+	case "le":
+		return got <= want, true
+	}
+	return false, false
+}
+
+// toFloat converts polled and JSON values; bools count as 0/1
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	}
+	return 0, false
+}
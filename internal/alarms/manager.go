@@ -0,0 +1,397 @@
+package alarms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// storeTimeout bounds persisting a single alarm transition
+const storeTimeout = 5 * time.Second
+
+var (
+	ErrAlarmNotFound = errors.New("alarm not found or already closed")
+	ErrAlarmActive   = errors.New("alarm condition is still active")
+)
+
+// ChangeHandler is called with every alarm transition: raised, cleared,
+// acknowledged or closed. It runs while the manager is locked and must not
+// block or call back into the manager.
+type ChangeHandler func(change string, alarm storage.Alarm)
+
+// Manager evaluates alarm definitions and owns the active alarm list.
+// Register conditions are checked against the values cached by the pollers;
+// event alarms are raised through HandleEvent.
+type Manager struct {
+	cfg      config.AlarmsConfig
+	storage  *storage.PostgresClient
+	devices  *devices.Manager
+	wsHub    *websocket.Hub
+	logger   *zap.Logger
+	onChange ChangeHandler
+
+	mu          sync.Mutex
+	definitions []storage.AlarmDefinition
+	open        map[uuid.UUID]*storage.Alarm // By alarm ID
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewManager(cfg config.AlarmsConfig, store *storage.PostgresClient, deviceManager *devices.Manager, wsHub *websocket.Hub, logger *zap.Logger) *Manager {
+	return &Manager{
+		cfg:     cfg,
+		storage: store,
+		devices: deviceManager,
+		wsHub:   wsHub,
+		logger:  logger,
+		open:    make(map[uuid.UUID]*storage.Alarm),
+	}
+}
+
+// SetChangeHandler installs the handler notified of alarm transitions. Set
+// it before Start.
+func (m *Manager) SetChangeHandler(handler ChangeHandler) {
+	m.onChange = handler
+}
+
+// Enabled reports whether the alarm engine runs
+func (m *Manager) Enabled() bool {
+	return m.cfg.Enabled
+}
+
+// Start loads definitions and the alarms left open by the last run and
+// starts evaluating register conditions
+func (m *Manager) Start(ctx context.Context) error {
+	if !m.cfg.Enabled {
+		return nil
+	}
+
+	if err := m.Reload(ctx); err != nil {
+		return err
+	}
+
+	open, err := m.storage.ListOpenAlarms(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load open alarms: %w", err)
+	}
+	m.mu.Lock()
+	for i := range open {
+		m.open[open[i].ID] = &open[i]
+	}
+	definitions := len(m.definitions)
+	if m.running {
+		m.mu.Unlock()
+		return nil
+	}
+	m.running = true
+	m.stopChan = make(chan struct{})
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.scanLoop(m.stopChan)
+
+	m.logger.Info("Alarm engine started",
+		zap.Int("definitions", definitions),
+		zap.Int("open_alarms", len(open)),
+		zap.Duration("scan_interval", m.cfg.ScanInterval))
+	return nil
+}
+
+// Stop ends condition evaluation; Start may be called again afterwards
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Reload re-reads the alarm definitions; call it after definitions change
+func (m *Manager) Reload(ctx context.Context) error {
+	defs, err := m.storage.ListAlarmDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load alarm definitions: %w", err)
+	}
+
+	m.mu.Lock()
+	m.definitions = defs
+	m.mu.Unlock()
+	return nil
+}
+
+// Active returns the open alarms, highest priority first, then oldest first
+func (m *Manager) Active() []storage.Alarm {
+	m.mu.Lock()
+	alarms := make([]storage.Alarm, 0, len(m.open))
+	for _, alarm := range m.open {
+		alarms = append(alarms, *alarm)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(alarms, func(i, j int) bool {
+		pi, pj := priorities[alarms[i].Priority], priorities[alarms[j].Priority]
+		if pi != pj {
+			return pi > pj
+		}
+		return alarms[i].RaisedAt.Before(alarms[j].RaisedAt)
+	})
+	return alarms
+}
+
+// HandleEvent raises the event alarms whose event_type matches. Event alarms
+// have no condition to wait for, so they are cleared as soon as they are raised.
+func (m *Manager) HandleEvent(eventType string, details map[string]any) {
+	if !m.cfg.Enabled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.definitions {
+		def := &m.definitions[i]
+		if !def.Enabled || def.EventType == "" {
+			continue
+		}
+		if ok, _ := path.Match(def.EventType, eventType); !ok {
+			continue
+		}
+		if m.openFor(def.ID) != nil {
+			continue
+		}
+
+		eventDetails := map[string]any{"event_type": eventType}
+		for k, v := range details {
+			eventDetails[k] = v
+		}
+		alarm := m.raise(def, eventDetails)
+		m.clear(alarm)
+	}
+}
+
+// Acknowledge marks an alarm as seen. Non-latching alarms whose condition
+// has gone are closed.
+func (m *Manager) Acknowledge(id uuid.UUID, actor string) (*storage.Alarm, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alarm, exists := m.open[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAlarmNotFound, id)
+	}
+	if alarm.AcknowledgedAt != nil {
+		result := *alarm
+		return &result, nil
+	}
+
+	now := time.Now()
+	alarm.AcknowledgedAt = &now
+	alarm.AcknowledgedBy = actor
+
+	if alarm.ClearedAt != nil && !alarm.Latch {
+		m.close(alarm, actor)
+	} else {
+		m.persist(alarm)
+		m.broadcast(websocket.MessageTypeAlarmAcknowledged, alarm)
+	}
+
+	result := *alarm
+	return &result, nil
+}
+
+// Reset closes an alarm whose condition has gone. It acknowledges the alarm
+// if that hasn't happened yet and is required to close latched alarms.
+func (m *Manager) Reset(id uuid.UUID, actor string) (*storage.Alarm, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alarm, exists := m.open[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAlarmNotFound, id)
+	}
+	if alarm.ClearedAt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAlarmActive, alarm.Name)
+	}
+
+	if alarm.AcknowledgedAt == nil {
+		now := time.Now()
+		alarm.AcknowledgedAt = &now
+		alarm.AcknowledgedBy = actor
+	}
+	m.close(alarm, actor)
+
+	result := *alarm
+	return &result, nil
+}
+
+func (m *Manager) scanLoop(stop <-chan struct{}) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.scan()
+		}
+	}
+}
+
+// scan evaluates all register conditions once
+func (m *Manager) scan() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.definitions {
+		def := &m.definitions[i]
+		if !def.Enabled || def.Condition == nil {
+			continue
+		}
+
+		value, ok := m.conditionValue(def.Condition)
+		if !ok {
+			continue
+		}
+		present, ok := evaluate(def.Condition, value)
+		if !ok {
+			continue
+		}
+
+		alarm := m.openFor(def.ID)
+		switch {
+		case present && alarm == nil:
+			m.raise(def, map[string]any{
+				"device":   def.Condition.Device,
+				"register": def.Condition.Register,
+				"value":    value,
+			})
+		case present && alarm.ClearedAt != nil:
+			// Condition returned before the alarm was closed; needs a new acknowledge
+			alarm.ClearedAt = nil
+			alarm.AcknowledgedAt = nil
+			alarm.AcknowledgedBy = ""
+			m.persist(alarm)
+			m.broadcast(websocket.MessageTypeAlarmRaised, alarm)
+		case !present && alarm != nil && alarm.ClearedAt == nil:
+			m.clear(alarm)
+		}
+	}
+}
+
+// conditionValue returns the last polled value of the condition register
+func (m *Manager) conditionValue(cond *storage.AlarmCondition) (any, bool) {
+	device, exists := m.devices.GetDeviceByName(cond.Device)
+	if !exists {
+		return nil, false
+	}
+
+	register := cond.Register
+	if mapped, ok := device.IOMapping[register]; ok {
+		register = mapped
+	}
+	return device.GetLastValue(register)
+}
+
+func (m *Manager) openFor(definitionID uuid.UUID) *storage.Alarm {
+	for _, alarm := range m.open {
+		if alarm.DefinitionID != nil && *alarm.DefinitionID == definitionID {
+			return alarm
+		}
+	}
+	return nil
+}
+
+func (m *Manager) raise(def *storage.AlarmDefinition, details map[string]any) *storage.Alarm {
+	defID := def.ID
+	alarm := &storage.Alarm{
+		ID:           uuid.New(),
+		DefinitionID: &defID,
+		Name:         def.Name,
+		Text:         def.Text,
+		Priority:     def.Priority,
+		Latch:        def.Latch,
+		Details:      details,
+		RaisedAt:     time.Now(),
+	}
+	alarm.UpdateState()
+	m.open[alarm.ID] = alarm
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.storage.CreateAlarm(ctx, alarm); err != nil {
+		m.logger.Error("Failed to store alarm", zap.String("alarm", alarm.Name), zap.Error(err))
+	}
+
+	m.logger.Warn("Alarm raised",
+		zap.String("alarm", alarm.Name),
+		zap.String("priority", alarm.Priority),
+		zap.String("text", alarm.Text))
+	m.broadcast(websocket.MessageTypeAlarmRaised, alarm)
+	return alarm
+}
+
+// clear records that the alarm condition has gone. Acknowledged, non-latching
+// alarms are closed right away.
+func (m *Manager) clear(alarm *storage.Alarm) {
+	now := time.Now()
+	alarm.ClearedAt = &now
+
+	if alarm.AcknowledgedAt != nil && !alarm.Latch {
+		m.close(alarm, "")
+		return
+	}
+	m.persist(alarm)
+	m.broadcast(websocket.MessageTypeAlarmCleared, alarm)
+}
+
+func (m *Manager) close(alarm *storage.Alarm, actor string) {
+	now := time.Now()
+	alarm.ClosedAt = &now
+	alarm.ClosedBy = actor
+	delete(m.open, alarm.ID)
+
+	m.persist(alarm)
+	m.broadcast(websocket.MessageTypeAlarmClosed, alarm)
+}
+
+func (m *Manager) persist(alarm *storage.Alarm) {
+	alarm.UpdateState()
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.storage.UpdateAlarm(ctx, alarm); err != nil {
+		m.logger.Error("Failed to update alarm", zap.String("alarm", alarm.Name), zap.Error(err))
+	}
+}
+
+func (m *Manager) broadcast(msgType websocket.MessageType, alarm *storage.Alarm) {
+	if m.onChange != nil {
+		m.onChange(strings.TrimPrefix(string(msgType), "alarm_"), *alarm)
+	}
+	if m.wsHub == nil {
+		return
+	}
+	m.wsHub.Broadcast(websocket.NewMessage(msgType, *alarm))
+}
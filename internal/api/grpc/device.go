@@ -0,0 +1,211 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	pb "github.com/KevinKickass/OpenMachineCore/api/proto"
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// minStreamInterval bounds how often StreamIO polls a device
+const minStreamInterval = 10 * time.Millisecond
+
+// DeviceService exposes the device manager as DeviceService
+type DeviceService struct {
+	pb.UnimplementedDeviceServiceServer
+	manager         *devices.Manager
+	defaultInterval time.Duration
+	requireSession  bool // Writes need a manual control session, which gRPC can't open
+}
+
+func NewDeviceService(manager *devices.Manager, defaultInterval time.Duration) *DeviceService {
+	return &DeviceService{
+		manager:         manager,
+		defaultInterval: defaultInterval,
+	}
+}
+
+// SetRequireSession rejects writes like REST does without a manual control
+// session
+func (s *DeviceService) SetRequireSession(required bool) {
+	s.requireSession = required
+}
+
+func (s *DeviceService) ListDevices(ctx context.Context, req *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	devices := s.manager.ListDevices()
+
+	resp := &pb.ListDevicesResponse{}
+	for _, device := range devices {
+		resp.Devices = append(resp.Devices, &pb.DeviceInfo{
+			Id:        device.ID.String(),
+			Name:      device.Name,
+			Profile:   device.Profile.DeviceProfile.Model,
+			Connected: device.Client != nil,
+		})
+	}
+	return resp, nil
+}
+
+func (s *DeviceService) ReadRegister(ctx context.Context, req *pb.ReadRegisterRequest) (*pb.RegisterValue, error) {
+	if req.Register == "" {
+		return nil, status.Error(codes.InvalidArgument, "register is required")
+	}
+	device, err := s.lookup(req.DeviceId, req.Register)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := device.ReadLogical(ctx, req.Register)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read register: %v", err)
+	}
+
+	return registerValue(device, req.Register, value), nil
+}
+
+func (s *DeviceService) WriteRegister(ctx context.Context, req *pb.WriteRegisterRequest) (*pb.WriteRegisterResponse, error) {
+	if req.Register == "" || req.Value == nil {
+		return nil, status.Error(codes.InvalidArgument, "register and value are required")
+	}
+
+	if s.requireSession {
+		return nil, status.Error(codes.FailedPrecondition, "manual control session required, write over REST with session_id")
+	}
+
+	device, err := s.lookup(req.DeviceId, req.Register)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticated by the server's interceptors
+	principal, _ := auth.GRPCPrincipal(ctx)
+	ctx = modbus.WithWriter(ctx, principal.Actor())
+
+	if err := device.WriteLogical(ctx, req.Register, req.Value.AsInterface()); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to write register: %v", err)
+	}
+
+	return &pb.WriteRegisterResponse{
+		Success: true,
+		Message: "Register written successfully",
+	}, nil
+}
+
+func (s *DeviceService) StreamIO(req *pb.StreamIORequest, stream pb.DeviceService_StreamIOServer) error {
+	device, err := s.lookup(req.DeviceId, "")
+	if err != nil {
+		return err
+	}
+
+	registers := req.Registers
+	if len(registers) == 0 {
+		for name := range device.IOMapping {
+			registers = append(registers, name)
+		}
+		sort.Strings(registers)
+	}
+	for _, name := range registers {
+		if _, ok := device.IOMapping[name]; !ok {
+			return status.Errorf(codes.NotFound, "logical name not mapped: %s", name)
+		}
+	}
+
+	interval := s.defaultInterval
+	if req.IntervalMs > 0 {
+		interval = time.Duration(req.IntervalMs) * time.Millisecond
+	}
+	if interval < minStreamInterval {
+		interval = minStreamInterval
+	}
+
+	ctx := stream.Context()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := make(map[string]any, len(registers))
+	for {
+		for _, name := range registers {
+			value, err := device.ReadLogical(ctx, name)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				msg := registerValue(device, name, nil)
+				msg.Error = err.Error()
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if prev, seen := last[name]; req.ChangesOnly && seen && reflect.DeepEqual(prev, value) {
+				continue
+			}
+			last[name] = value
+
+			if err := stream.Send(registerValue(device, name, value)); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// lookup finds a device by runtime UUID or instance name and checks that
+// register, if given, is a mapped logical name
+func (s *DeviceService) lookup(deviceID, register string) (*modbus.Device, error) {
+	if deviceID == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_id is required")
+	}
+
+	var device *modbus.Device
+	var exists bool
+	if id, err := uuid.Parse(deviceID); err == nil {
+		device, exists = s.manager.GetDevice(id)
+	} else {
+		device, exists = s.manager.GetDeviceByName(deviceID)
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "device not found: %s", deviceID)
+	}
+
+	if register != "" {
+		if _, ok := device.IOMapping[register]; !ok {
+			return nil, status.Errorf(codes.NotFound, "logical name not mapped: %s", register)
+		}
+	}
+	return device, nil
+}
+
+func registerValue(device *modbus.Device, register string, value any) *pb.RegisterValue {
+	msg := &pb.RegisterValue{
+		DeviceId:  device.ID.String(),
+		Register:  register,
+		Timestamp: time.Now().Unix(),
+	}
+	if value == nil {
+		return msg
+	}
+
+	v, err := structpb.NewValue(value)
+	if err != nil {
+		v = structpb.NewStringValue(fmt.Sprint(value))
+	}
+	msg.Value = v
+	return msg
+}
@@ -0,0 +1,121 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/KevinKickass/OpenMachineCore/api/proto"
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MachineService exposes the machine controller as MachineService
+type MachineService struct {
+	pb.UnimplementedMachineServiceServer
+	controller *machine.Controller
+}
+
+func NewMachineService(controller *machine.Controller) *MachineService {
+	return &MachineService{controller: controller}
+}
+
+func (s *MachineService) GetStatus(ctx context.Context, req *pb.StatusRequest) (*pb.MachineStatusResponse, error) {
+	return statusToProto(s.controller.GetStatus(), ""), nil
+}
+
+// StreamStatus is kept for existing clients and behaves like StreamState
+func (s *MachineService) StreamStatus(req *pb.StatusRequest, stream pb.MachineService_StreamStatusServer) error {
+	return s.streamState(stream.Context(), stream.Send)
+}
+
+func (s *MachineService) StreamState(req *pb.StatusRequest, stream pb.MachineService_StreamStateServer) error {
+	return s.streamState(stream.Context(), stream.Send)
+}
+
+func (s *MachineService) streamState(ctx context.Context, send func(*pb.MachineStatusResponse) error) error {
+	changes := s.controller.SubscribeState()
+	defer s.controller.UnsubscribeState(changes)
+
+	if err := send(statusToProto(s.controller.GetStatus(), "")); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			if err := send(statusToProto(change.Status, change.PreviousState)); err != nil {
+				return err
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *MachineService) ExecuteCommand(ctx context.Context, req *pb.MachineCommandRequest) (*pb.MachineCommandResponse, error) {
+	if req.Command == "" {
+		return nil, status.Error(codes.InvalidArgument, "command is required")
+	}
+
+	// Authenticated by the server's interceptors
+	principal, _ := auth.GRPCPrincipal(ctx)
+
+	result, err := s.controller.ExecuteCommand(ctx, machine.Command(req.Command), machine.CommandOptions{
+		Queue:  req.Queue,
+		Reason: req.Reason,
+		Actor:  principal.Actor(),
+		Correlation: storage.Correlation{
+			OrderID:      req.OrderId,
+			BatchID:      req.BatchId,
+			SerialNumber: req.SerialNumber,
+		},
+	})
+	if err != nil {
+		var rejection *machine.CommandRejection
+		if errors.As(err, &rejection) {
+			code := codes.FailedPrecondition
+			if rejection.Code == machine.RejectUnknownCommand {
+				code = codes.InvalidArgument
+			}
+			return nil, status.Errorf(code, "%s: %s", rejection.Code, rejection.Reason)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	message := "Command accepted"
+	if result == machine.CommandQueued {
+		message = "Command queued"
+	}
+
+	return &pb.MachineCommandResponse{
+		Command: req.Command,
+		Result:  string(result),
+		Message: message,
+	}, nil
+}
+
+func statusToProto(st machine.MachineStatus, previous machine.State) *pb.MachineStatusResponse {
+	allowed := make([]string, 0, len(st.AllowedCommands))
+	for _, cmd := range st.AllowedCommands {
+		allowed = append(allowed, string(cmd))
+	}
+
+	return &pb.MachineStatusResponse{
+		State:            string(st.State),
+		Timestamp:        time.Now().Unix(),
+		ExecutionId:      st.ExecutionID,
+		ErrorMessage:     st.ErrorMessage,
+		ProductionCycles: int32(st.ProductionCycles),
+		AllowedCommands:  allowed,
+		QueuedCommand:    string(st.QueuedCommand),
+		PreviousState:    string(previous),
+	}
+}
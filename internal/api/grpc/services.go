@@ -0,0 +1,97 @@
+// Package grpc implements the gRPC API. It is the only package built on the
+// code generated from api/proto, run make proto before building it.
+package grpc
+
+import (
+	pb "github.com/KevinKickass/OpenMachineCore/api/proto"
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/streaming"
+	grpclib "google.golang.org/grpc"
+)
+
+// serviceNames are the registered services reported by the health service
+var serviceNames = []string{
+	pb.WorkflowService_ServiceDesc.ServiceName,
+	pb.MachineService_ServiceDesc.ServiceName,
+	pb.DeviceService_ServiceDesc.ServiceName,
+}
+
+// permissions are the permissions of the gRPC methods, the same as the REST
+// routes they mirror
+var permissions = map[string]auth.Permission{
+	pb.WorkflowService_StreamExecutionStatus_FullMethodName: auth.PermOperator,
+	pb.WorkflowService_GetExecutionStatus_FullMethodName:    auth.PermOperator,
+	pb.WorkflowService_ExecuteWorkflowStream_FullMethodName: auth.PermOperator,
+
+	pb.MachineService_GetStatus_FullMethodName:      auth.PermOperator,
+	pb.MachineService_StreamStatus_FullMethodName:   auth.PermOperator,
+	pb.MachineService_StreamState_FullMethodName:    auth.PermOperator,
+	pb.MachineService_ExecuteCommand_FullMethodName: auth.PermOperator,
+
+	pb.DeviceService_ListDevices_FullMethodName:   auth.PermOperator,
+	pb.DeviceService_ReadRegister_FullMethodName:  auth.PermOperator,
+	pb.DeviceService_StreamIO_FullMethodName:      auth.PermOperator,
+	pb.DeviceService_WriteRegister_FullMethodName: auth.PermTechnician,
+}
+
+// Backend is what the services use of the running system; implemented by
+// the lifecycle manager
+type Backend interface {
+	Config() *config.Config
+	Storage() *storage.PostgresClient
+	DeviceManager() *devices.Manager
+	MachineController() *machine.Controller
+	WorkflowEngine() *engine.Engine
+	EventStreamer() *streaming.EventStreamer
+}
+
+// Services are the services of the gRPC API
+type Services struct {
+	workflow *WorkflowService
+	machine  *MachineService
+	device   *DeviceService
+}
+
+func NewServices(backend Backend) *Services {
+	cfg := backend.Config()
+
+	workflowService := NewWorkflowService(backend.EventStreamer(), backend.Storage(), backend.WorkflowEngine(), streaming.SubscribeOptions{
+		Buffer:       cfg.Server.GRPC.EventStream.Buffer,
+		Policy:       cfg.Server.GRPC.EventStream.Policy,
+		BlockTimeout: cfg.Server.GRPC.EventStream.BlockTimeout,
+	})
+	workflowService.SetExecuteAuthorizer(&workflowACLAuthorizer{storage: backend.Storage(), engine: backend.WorkflowEngine()})
+
+	deviceService := NewDeviceService(backend.DeviceManager(), cfg.Modbus.DefaultPollInterval)
+	deviceService.SetRequireSession(cfg.Manual.RequireSession)
+
+	return &Services{
+		workflow: workflowService,
+		machine:  NewMachineService(backend.MachineController()),
+		device:   deviceService,
+	}
+}
+
+// Register registers the services on a server
+func (s *Services) Register(server grpclib.ServiceRegistrar) {
+	pb.RegisterWorkflowServiceServer(server, s.workflow)
+	pb.RegisterMachineServiceServer(server, s.machine)
+	pb.RegisterDeviceServiceServer(server, s.device)
+}
+
+// Names returns the names of the registered services
+func (s *Services) Names() []string {
+	return serviceNames
+}
+
+// Permission returns the permission of a method of the services; false if
+// the method isn't one of theirs
+func (s *Services) Permission(fullMethod string) (auth.Permission, bool) {
+	permission, ok := permissions[fullMethod]
+	return permission, ok
+}
@@ -0,0 +1,327 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pb "github.com/KevinKickass/OpenMachineCore/api/proto"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/streaming"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resultCheckInterval is how often ExecuteWorkflowStream checks the stored
+// execution, in case its final event was dropped for a slow client
+const resultCheckInterval = 5 * time.Second
+
+// WorkflowRunner starts executions; implemented by the workflow engine
+type WorkflowRunner interface {
+	ExecuteWorkflowStream(ctx context.Context, workflowID uuid.UUID, input map[string]any, correlation storage.Correlation, opts streaming.SubscribeOptions) (uuid.UUID, <-chan *storage.ExecutionEvent, error)
+}
+
+// ExecuteAuthorizer decides whether the caller may execute a workflow or see
+// its executions; it returns a gRPC status error if not
+type ExecuteAuthorizer interface {
+	AuthorizeExecute(ctx context.Context, workflowID uuid.UUID) error
+	AuthorizeRead(ctx context.Context, workflowID uuid.UUID) error
+}
+
+// WorkflowService streams workflow executions as WorkflowService
+type WorkflowService struct {
+	pb.UnimplementedWorkflowServiceServer
+	streamer   *streaming.EventStreamer
+	storage    *storage.PostgresClient
+	runner     WorkflowRunner
+	opts       streaming.SubscribeOptions // Buffering of the clients' event streams
+	authorizer ExecuteAuthorizer          // Optional
+}
+
+func NewWorkflowService(streamer *streaming.EventStreamer, storage *storage.PostgresClient, runner WorkflowRunner, opts streaming.SubscribeOptions) *WorkflowService {
+	return &WorkflowService{
+		streamer: streamer,
+		storage:  storage,
+		runner:   runner,
+		opts:     opts,
+	}
+}
+
+// SetExecuteAuthorizer checks executions started or read over gRPC
+func (s *WorkflowService) SetExecuteAuthorizer(authorizer ExecuteAuthorizer) {
+	s.authorizer = authorizer
+}
+
+// authorizeRead checks that the caller may see an execution
+func (s *WorkflowService) authorizeRead(ctx context.Context, executionID uuid.UUID) error {
+	if s.authorizer == nil {
+		return nil
+	}
+	exec, err := s.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "execution not found: %s", executionID)
+	}
+	return s.authorizer.AuthorizeRead(ctx, exec.WorkflowID)
+}
+
+// errSlowConsumer ends a stream whose client didn't keep up with the events
+var errSlowConsumer = status.Error(codes.ResourceExhausted, "stream disconnected, client too slow to receive events")
+
+func (s *WorkflowService) StreamExecutionStatus(req *pb.ExecutionStreamRequest, stream pb.WorkflowService_StreamExecutionStatusServer) error {
+	executionID, err := uuid.Parse(req.ExecutionId)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeRead(stream.Context(), executionID); err != nil {
+		return err
+	}
+
+	eventCh := s.streamer.SubscribeWith(executionID, s.opts)
+	defer s.streamer.Unsubscribe(executionID, eventCh)
+
+	disconnected := false
+	for {
+		select {
+		case event, ok := <-eventCh:
+			if !ok {
+				if disconnected {
+					return errSlowConsumer
+				}
+				return nil
+			}
+			disconnected = event.EventType == streaming.EventDisconnected
+
+			status := &pb.ExecutionStatus{
+				ExecutionId: event.ExecutionID.String(),
+				EventType:   event.EventType,
+				Payload:     string(event.Payload),
+				Timestamp:   event.Timestamp.Unix(),
+			}
+
+			if err := stream.Send(status); err != nil {
+				return err
+			}
+
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// ExecuteWorkflowStream starts an execution and streams its events until it
+// finishes, then sends the result. Closing the stream doesn't cancel the
+// execution.
+func (s *WorkflowService) ExecuteWorkflowStream(req *pb.ExecuteWorkflowRequest, stream pb.WorkflowService_ExecuteWorkflowStreamServer) error {
+	workflowID, err := uuid.Parse(req.WorkflowId)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid workflow_id: %v", err)
+	}
+	if s.authorizer != nil {
+		if err := s.authorizer.AuthorizeExecute(stream.Context(), workflowID); err != nil {
+			return err
+		}
+	}
+
+	input := make(map[string]any)
+	if req.Input != "" {
+		if err := json.Unmarshal([]byte(req.Input), &input); err != nil {
+			return status.Errorf(codes.InvalidArgument, "input is not a JSON object: %v", err)
+		}
+	}
+
+	correlation := storage.Correlation{
+		OrderID:      req.OrderId,
+		BatchID:      req.BatchId,
+		SerialNumber: req.SerialNumber,
+	}
+
+	ctx := stream.Context()
+	executionID, events, err := s.runner.ExecuteWorkflowStream(ctx, workflowID, input, correlation, s.opts)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "failed to start execution: %v", err)
+	}
+	defer s.streamer.Unsubscribe(executionID, events)
+
+	// The first message tells the client the execution ID
+	created, _ := json.Marshal(map[string]any{"workflow_id": workflowID})
+	if err := s.sendEvent(stream, &storage.ExecutionEvent{
+		ExecutionID: executionID,
+		EventType:   "execution.created",
+		Payload:     created,
+		Timestamp:   time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	check := time.NewTicker(resultCheckInterval)
+	defer check.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.sendEvent(stream, event); err != nil {
+				return err
+			}
+			if event.EventType == streaming.EventDisconnected {
+				return errSlowConsumer
+			}
+			if streaming.FinalEvent(event.EventType) {
+				return s.sendResult(ctx, stream, executionID)
+			}
+
+		case <-check.C:
+			exec, err := s.storage.GetExecution(ctx, executionID)
+			if err != nil || !finished(exec.Status) {
+				continue
+			}
+			// Forward what is still buffered before the result
+			for drained := false; !drained; {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						drained = true
+						break
+					}
+					if err := s.sendEvent(stream, event); err != nil {
+						return err
+					}
+				default:
+					drained = true
+				}
+			}
+			return s.sendResult(ctx, stream, executionID)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *WorkflowService) sendEvent(stream pb.WorkflowService_ExecuteWorkflowStreamServer, event *storage.ExecutionEvent) error {
+	return stream.Send(&pb.ExecutionStreamMessage{
+		Message: &pb.ExecutionStreamMessage_Event{Event: &pb.ExecutionStatus{
+			ExecutionId: event.ExecutionID.String(),
+			EventType:   event.EventType,
+			Payload:     string(event.Payload),
+			Timestamp:   event.Timestamp.Unix(),
+		}},
+	})
+}
+
+func (s *WorkflowService) sendResult(ctx context.Context, stream pb.WorkflowService_ExecuteWorkflowStreamServer, executionID uuid.UUID) error {
+	exec, err := s.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to load execution result: %v", err)
+	}
+
+	result := &pb.ExecutionResult{
+		ExecutionId: exec.ID.String(),
+		Status:      string(exec.Status),
+		Output:      string(exec.Output),
+		Error:       exec.Error,
+		StartedAt:   exec.StartedAt.Unix(),
+	}
+	if result.Error == "" && exec.Status == storage.StatusFailed {
+		result.Error = s.failedStepError(ctx, executionID)
+	}
+	if exec.CompletedAt != nil {
+		result.CompletedAt = exec.CompletedAt.Unix()
+	}
+
+	return stream.Send(&pb.ExecutionStreamMessage{
+		Message: &pb.ExecutionStreamMessage_Result{Result: result},
+	})
+}
+
+// failedStepError returns the error of the last failed step, for executions
+// that failed without storing an error
+func (s *WorkflowService) failedStepError(ctx context.Context, executionID uuid.UUID) string {
+	steps, err := s.storage.GetExecutionSteps(ctx, executionID)
+	if err != nil {
+		return ""
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].Status == storage.StatusFailed && steps[i].Error != "" {
+			return fmt.Sprintf("step %s: %s", steps[i].StepName, steps[i].Error)
+		}
+	}
+	return ""
+}
+
+func finished(s storage.ExecutionStatus) bool {
+	return s == storage.StatusSuccess || s == storage.StatusFailed || s == storage.StatusCancelled
+}
+
+func (s *WorkflowService) GetExecutionStatus(ctx context.Context, req *pb.ExecutionStatusRequest) (*pb.ExecutionStatusResponse, error) {
+	executionID, err := uuid.Parse(req.ExecutionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeRead(ctx, executionID); err != nil {
+		return nil, err
+	}
+
+	// Retrieve execution from storage
+	exec, err := s.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve execution steps
+	steps, err := s.storage.GetExecutionSteps(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build response with hierarchical step information
+	resp := &pb.ExecutionStatusResponse{
+		ExecutionId:   exec.ID.String(),
+		WorkflowId:    exec.WorkflowID.String(),
+		Status:        string(exec.Status),
+		CurrentStep:   int32(exec.CurrentStep),
+		CurrentStepId: exec.CurrentStepID,
+		Error:         exec.Error,
+		StartedAt:     exec.StartedAt.Unix(),
+	}
+
+	if exec.CompletedAt != nil {
+		resp.CompletedAt = exec.CompletedAt.Unix()
+	}
+
+	// Deserialize call stack if available
+	if exec.CallStack != nil {
+		var callStack []definition.CallFrame
+		if err := json.Unmarshal(exec.CallStack, &callStack); err == nil {
+			for _, frame := range callStack {
+				resp.CallStack = append(resp.CallStack, &pb.CallFrame{
+					WorkflowId:  frame.WorkflowID,
+					ProgramName: frame.ProgramName,
+					StepNumber:  frame.StepNumber,
+				})
+			}
+		}
+	}
+
+	// Convert steps to protobuf format with hierarchical information
+	for _, step := range steps {
+		stepStatus := &pb.StepStatus{
+			StepIndex:          int32(step.StepIndex),
+			StepName:           step.StepName,
+			Status:             string(step.Status),
+			Output:             string(step.Output),
+			Error:              step.Error,
+			HierarchicalStepId: step.HierarchicalStepID,
+			Depth:              int32(step.Depth),
+		}
+		resp.Steps = append(resp.Steps, stepStatus)
+	}
+
+	return resp, nil
+}
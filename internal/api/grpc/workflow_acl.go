@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// workflowACLAuthorizer enforces workflow access lists on executions started
+// over gRPC, including the sub-workflows they call, and on reading
+// executions. The caller was authenticated by the server's interceptors.
+type workflowACLAuthorizer struct {
+	storage *storage.PostgresClient
+	engine  *engine.Engine
+}
+
+func (a *workflowACLAuthorizer) AuthorizeExecute(ctx context.Context, workflowID uuid.UUID) error {
+	principal, ok := auth.GRPCPrincipal(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	acl, err := a.storage.GetWorkflowACL(ctx, workflowID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to check workflow access: %v", err)
+	}
+	if !principal.CanExecute(acl) {
+		return status.Errorf(codes.PermissionDenied, "%s may not execute this workflow", principal)
+	}
+
+	for _, subID := range a.engine.SubWorkflows(ctx, workflowID) {
+		acl, err := a.storage.GetWorkflowACL(ctx, subID)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to check workflow access: %v", err)
+		}
+		if !principal.CanExecute(acl) {
+			return status.Errorf(codes.PermissionDenied, "%s may not execute sub-workflow %s", principal, subID)
+		}
+	}
+	return nil
+}
+
+// AuthorizeRead checks that the caller may execute the workflow, which is
+// needed to see its executions
+func (a *workflowACLAuthorizer) AuthorizeRead(ctx context.Context, workflowID uuid.UUID) error {
+	principal, ok := auth.GRPCPrincipal(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	acl, err := a.storage.GetWorkflowACL(ctx, workflowID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to check workflow access: %v", err)
+	}
+	if !principal.CanExecute(acl) {
+		return status.Errorf(codes.PermissionDenied, "%s may not see executions of this workflow", principal)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultAlarmHistoryLimit bounds history responses without ?limit
+const defaultAlarmHistoryLimit = 100
+
+type alarmDefinitionRequest struct {
+	Name      string                  `json:"name"`
+	Text      string                  `json:"text"`
+	Priority  string                  `json:"priority"`
+	Latch     bool                    `json:"latch"`
+	Enabled   *bool                   `json:"enabled"`
+	Condition *storage.AlarmCondition `json:"condition"`
+	EventType string                  `json:"event_type"`
+}
+
+// GET /api/v1/alarms
+func (s *Server) listActiveAlarms(c *gin.Context) {
+	active := s.lm.AlarmManager().Active()
+
+	c.JSON(http.StatusOK, gin.H{
+		"alarms": active,
+		"count":  len(active),
+	})
+}
+
+// GET /api/v1/alarms/history
+func (s *Server) getAlarmHistory(c *gin.Context) {
+	filter := storage.AlarmHistoryFilter{
+		Name:  c.Query("name"),
+		Limit: defaultAlarmHistoryLimit,
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid from", err.Error()))
+			return
+		}
+		filter.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid to", err.Error()))
+			return
+		}
+		filter.To = &to
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid limit", v))
+			return
+		}
+		filter.Limit = n
+	}
+
+	history, err := s.lm.Storage().ListAlarmHistory(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to load alarm history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("ALARM_500", "Failed to load alarm history", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alarms": history,
+		"count":  len(history),
+	})
+}
+
+// POST /api/v1/alarms/:id/acknowledge
+func (s *Server) acknowledgeAlarm(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid alarm ID", err.Error()))
+		return
+	}
+
+	alarm, err := s.lm.AlarmManager().Acknowledge(id, requestActor(c))
+	if err != nil {
+		s.alarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alarm)
+}
+
+// POST /api/v1/alarms/:id/reset
+func (s *Server) resetAlarm(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid alarm ID", err.Error()))
+		return
+	}
+
+	alarm, err := s.lm.AlarmManager().Reset(id, requestActor(c))
+	if err != nil {
+		s.alarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alarm)
+}
+
+// GET /api/v1/alarms/definitions
+func (s *Server) listAlarmDefinitions(c *gin.Context) {
+	defs, err := s.lm.Storage().ListAlarmDefinitions(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to list alarm definitions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("ALARM_500", "Failed to list alarm definitions", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"definitions": defs,
+		"count":       len(defs),
+	})
+}
+
+// POST /api/v1/alarms/definitions
+func (s *Server) createAlarmDefinition(c *gin.Context) {
+	def, ok := s.bindAlarmDefinition(c)
+	if !ok {
+		return
+	}
+
+	if err := s.lm.Storage().CreateAlarmDefinition(c.Request.Context(), def); err != nil {
+		s.alarmError(c, err)
+		return
+	}
+	s.reloadAlarms(c)
+
+	c.JSON(http.StatusCreated, def)
+}
+
+// PUT /api/v1/alarms/definitions/:id
+func (s *Server) updateAlarmDefinition(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid alarm definition ID", err.Error()))
+		return
+	}
+
+	def, ok := s.bindAlarmDefinition(c)
+	if !ok {
+		return
+	}
+	def.ID = id
+
+	if err := s.lm.Storage().UpdateAlarmDefinition(c.Request.Context(), def); err != nil {
+		s.alarmError(c, err)
+		return
+	}
+	s.reloadAlarms(c)
+
+	c.JSON(http.StatusOK, def)
+}
+
+// DELETE /api/v1/alarms/definitions/:id
+// Open alarms of the definition stay open until they are closed.
+func (s *Server) deleteAlarmDefinition(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid alarm definition ID", err.Error()))
+		return
+	}
+
+	if err := s.lm.Storage().DeleteAlarmDefinition(c.Request.Context(), id); err != nil {
+		s.alarmError(c, err)
+		return
+	}
+	s.reloadAlarms(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alarm definition deleted successfully",
+	})
+}
+
+func (s *Server) bindAlarmDefinition(c *gin.Context) (*storage.AlarmDefinition, bool) {
+	var req alarmDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid request body", err.Error()))
+		return nil, false
+	}
+
+	def := &storage.AlarmDefinition{
+		Name:      req.Name,
+		Text:      req.Text,
+		Priority:  req.Priority,
+		Latch:     req.Latch,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		Condition: req.Condition,
+		EventType: req.EventType,
+	}
+	if err := alarms.ValidateDefinition(def); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ALARM_400", "Invalid alarm definition", err.Error()))
+		return nil, false
+	}
+	return def, true
+}
+
+// reloadAlarms makes definition changes effective immediately
+func (s *Server) reloadAlarms(c *gin.Context) {
+	if err := s.lm.AlarmManager().Reload(c.Request.Context()); err != nil {
+		s.logger.Warn("Failed to reload alarm definitions", zap.Error(err))
+	}
+}
+
+// alarmError maps alarm and alarm definition errors to responses
+func (s *Server) alarmError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, alarms.ErrAlarmNotFound), errors.Is(err, storage.ErrAlarmDefinitionNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("ALARM_404", "Alarm not found", err.Error()))
+	case errors.Is(err, alarms.ErrAlarmActive), errors.Is(err, storage.ErrAlarmDefinitionExists):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("ALARM_409", "Alarm conflict", err.Error()))
+	default:
+		s.logger.Error("Alarm operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("ALARM_500", "Alarm operation failed", err.Error()))
+	}
+}
@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -305,3 +307,51 @@ func (s *Server) deleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted"})
 }
+
+// Signing Key Rotation (Admin only)
+
+// GET /api/v1/auth/keys
+func (s *Server) listSigningKeys(c *gin.Context) {
+	keys := s.authService.SigningKeys()
+	c.JSON(http.StatusOK, gin.H{
+		"keys":  keys,
+		"count": len(keys),
+		"grace": s.cfg.Auth.JWTKeyGrace.String(),
+	})
+}
+
+// POST /api/v1/auth/keys/rotate
+// New tokens are signed with a new key; tokens signed with the previous key
+// stay valid for auth.jwt_key_grace.
+func (s *Server) rotateSigningKey(c *gin.Context) {
+	key, err := s.authService.RotateSigningKey(c.Request.Context(), requestActor(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrKeyFiles) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("AUTH_409", "Key rotation unavailable", err.Error()))
+			return
+		}
+		if errors.Is(err, secrets.ErrNoMasterKey) {
+			c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("AUTH_503", "Key rotation unavailable",
+				"No master key configured (secrets.master_key_env or secrets.master_key_file)"))
+			return
+		}
+		s.logger.Error("Failed to rotate signing key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("AUTH_500", "Failed to rotate signing key", err.Error()))
+		return
+	}
+
+	s.logger.Info("JWT signing key rotated",
+		zap.String("key_id", key.KeyID),
+		zap.String("actor", requestActor(c)))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signing key rotated",
+		"key":     key,
+	})
+}
+
+// GET /.well-known/jwks.json
+// Public keys for services verifying tokens (RS256/EdDSA). Empty with HS256.
+func (s *Server) getJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.authService.JWKS())
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultCalibrationHistoryLimit bounds history responses without ?limit
+const defaultCalibrationHistoryLimit = 100
+
+type calibrationRequest struct {
+	Offset float64  `json:"offset"`
+	Gain   *float64 `json:"gain"`
+	Note   string   `json:"note"`
+}
+
+// GET /api/v1/devices/:id/calibration
+func (s *Server) listCalibrations(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+
+	cals, err := s.lm.Storage().ListCalibrations(c.Request.Context(), device.Name)
+	if err != nil {
+		s.logger.Error("Failed to list calibrations", zap.String("device", device.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("CALIBRATION_500", "Failed to list calibrations", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"device":       device.Name,
+		"calibrations": cals,
+		"count":        len(cals),
+	})
+}
+
+// PUT /api/v1/devices/:id/calibration/:register
+func (s *Server) setCalibration(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+	register := c.Param("register")
+
+	var req calibrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("CALIBRATION_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	cal := types.Calibration{Offset: req.Offset, Gain: 1}
+	if req.Gain != nil {
+		cal.Gain = *req.Gain
+	}
+
+	// Apply to the runtime device first; it rejects unknown or non-numeric registers
+	previous, hadPrevious := device.Calibrations()[register]
+	if err := device.SetCalibration(register, cal); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("CALIBRATION_400", "Invalid calibration", err.Error()))
+		return
+	}
+
+	stored := &storage.DeviceCalibration{
+		DeviceName:   device.Name,
+		RegisterName: register,
+		Offset:       cal.Offset,
+		Gain:         cal.Gain,
+		Note:         req.Note,
+		UpdatedBy:    requestActor(c),
+	}
+	if err := s.lm.Storage().SetCalibration(c.Request.Context(), stored); err != nil {
+		if hadPrevious {
+			device.SetCalibration(register, previous)
+		} else {
+			device.ClearCalibration(register)
+		}
+		s.logger.Error("Failed to save calibration", zap.String("device", device.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("CALIBRATION_500", "Failed to save calibration", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, stored)
+}
+
+// DELETE /api/v1/devices/:id/calibration/:register
+func (s *Server) clearCalibration(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+	register := c.Param("register")
+
+	err := s.lm.Storage().ClearCalibration(c.Request.Context(), device.Name, register, requestActor(c), c.Query("note"))
+	if err != nil {
+		if errors.Is(err, storage.ErrCalibrationNotFound) {
+			c.JSON(http.StatusNotFound, types.NewErrorResponse("CALIBRATION_404", "Calibration not found", register))
+			return
+		}
+		s.logger.Error("Failed to clear calibration", zap.String("device", device.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("CALIBRATION_500", "Failed to clear calibration", err.Error()))
+		return
+	}
+	device.ClearCalibration(register)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Calibration cleared",
+		"register": register,
+	})
+}
+
+// GET /api/v1/devices/:id/calibration/history
+func (s *Server) getCalibrationHistory(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultCalibrationHistoryLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("CALIBRATION_400", "Invalid limit", v))
+			return
+		}
+		limit = n
+	}
+
+	changes, err := s.lm.Storage().CalibrationHistory(c.Request.Context(), device.Name, c.Query("register"), limit)
+	if err != nil {
+		s.logger.Error("Failed to load calibration history", zap.String("device", device.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("CALIBRATION_500", "Failed to load calibration history", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"device":  device.Name,
+		"history": changes,
+		"count":   len(changes),
+	})
+}
+
+// deviceParam resolves :id as runtime device ID or instance name
+func (s *Server) deviceParam(c *gin.Context) (*modbus.Device, bool) {
+	idStr := c.Param("id")
+
+	var device *modbus.Device
+	var exists bool
+	if id, err := uuid.Parse(idStr); err == nil {
+		device, exists = s.lm.DeviceManager().GetDevice(id)
+	} else {
+		device, exists = s.lm.DeviceManager().GetDeviceByName(idStr)
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Device not found", idStr))
+		return nil, false
+	}
+	return device, true
+}
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GET /api/v1/composition-templates
+func (s *Server) listCompositionTemplates(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	templates, err := s.lm.Storage().ListCompositionTemplates(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list composition templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("COMPOSITION_500", "Failed to list composition templates", err.Error()))
+		return
+	}
+
+	views := make([]gin.H, 0, len(templates))
+	for i := range templates {
+		views = append(views, compositionTemplateView(&templates[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": views,
+		"count":     len(views),
+	})
+}
+
+// GET /api/v1/composition-templates/:name
+func (s *Server) getCompositionTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	tmpl, err := s.lm.Storage().LoadCompositionTemplate(ctx, name)
+	if err != nil {
+		s.compositionTemplateError(c, name, "Failed to load composition template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, compositionTemplateView(tmpl))
+}
+
+// POST /api/v1/composition-templates
+func (s *Server) createCompositionTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req devices.CompositionTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	tmpl, ok := buildCompositionTemplate(c, &req)
+	if !ok {
+		return
+	}
+
+	if err := s.lm.Storage().SaveCompositionTemplate(ctx, tmpl); err != nil {
+		if errors.Is(err, storage.ErrCompositionTemplateExists) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("COMPOSITION_409", "Composition template already exists", tmpl.Name))
+			return
+		}
+		s.logger.Error("Failed to create composition template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("COMPOSITION_500", "Failed to create composition template", err.Error()))
+		return
+	}
+
+	s.logger.Info("Composition template created", zap.String("template", tmpl.Name))
+
+	c.JSON(http.StatusCreated, compositionTemplateView(tmpl))
+}
+
+// PUT /api/v1/composition-templates/:name
+func (s *Server) updateCompositionTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	var req devices.CompositionTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Invalid request body", err.Error()))
+		return
+	}
+	if req.Name != "" && req.Name != name {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Composition templates cannot be renamed", gin.H{"name": name, "requested": req.Name}))
+		return
+	}
+	req.Name = name
+
+	tmpl, ok := buildCompositionTemplate(c, &req)
+	if !ok {
+		return
+	}
+
+	if err := s.lm.Storage().UpdateCompositionTemplate(ctx, tmpl); err != nil {
+		s.compositionTemplateError(c, name, "Failed to update composition template", err)
+		return
+	}
+
+	s.logger.Info("Composition template updated",
+		zap.String("template", name),
+		zap.Int("version", tmpl.Version))
+
+	c.JSON(http.StatusOK, compositionTemplateView(tmpl))
+}
+
+// DELETE /api/v1/composition-templates/:name
+func (s *Server) deleteCompositionTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	if err := s.lm.Storage().DeleteCompositionTemplate(ctx, name); err != nil {
+		s.compositionTemplateError(c, name, "Failed to delete composition template", err)
+		return
+	}
+
+	s.logger.Info("Composition template deleted", zap.String("template", name))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Composition template deleted successfully",
+	})
+}
+
+// POST /api/v1/devices/from-template
+func (s *Server) createDeviceFromTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		Template   string            `json:"template" binding:"required"`
+		InstanceID string            `json:"instance_id" binding:"required"`
+		Parameters map[string]string `json:"parameters"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	stored, err := s.lm.Storage().LoadCompositionTemplate(ctx, req.Template)
+	if err != nil {
+		s.compositionTemplateError(c, req.Template, "Failed to load composition template", err)
+		return
+	}
+	tmpl, err := devices.ParseCompositionTemplate(stored.Definition)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("COMPOSITION_500", "Stored composition template is invalid", err.Error()))
+		return
+	}
+
+	comp, err := tmpl.Instantiate(req.InstanceID, req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Failed to instantiate composition template", err.Error()))
+		return
+	}
+
+	view, ok := s.installDevice(c, comp, fmt.Sprintf("from template %s version %d", stored.Name, stored.Version))
+	if !ok {
+		return
+	}
+
+	s.logger.Info("Device created from composition template",
+		zap.String("instance_id", comp.InstanceID),
+		zap.String("template", stored.Name),
+		zap.Int("template_version", stored.Version))
+
+	view["template"] = stored.Name
+	view["template_version"] = stored.Version
+	view["composition"] = comp.Composition
+	view["io_mapping"] = comp.IOMapping
+	c.JSON(http.StatusCreated, view)
+}
+
+// buildCompositionTemplate validates a request and encodes it for storage
+func buildCompositionTemplate(c *gin.Context, def *devices.CompositionTemplate) (*storage.CompositionTemplate, bool) {
+	if err := def.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Invalid composition template", err.Error()))
+		return nil, false
+	}
+
+	data, err := json.Marshal(def)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("COMPOSITION_400", "Invalid composition template", err.Error()))
+		return nil, false
+	}
+
+	return &storage.CompositionTemplate{
+		Name:        def.Name,
+		Description: def.Description,
+		Definition:  data,
+	}, true
+}
+
+func (s *Server) compositionTemplateError(c *gin.Context, name, message string, err error) {
+	if errors.Is(err, storage.ErrCompositionTemplateNotFound) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("COMPOSITION_404", "Composition template not found", name))
+		return
+	}
+	s.logger.Error(message, zap.String("template", name), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, types.NewErrorResponse("COMPOSITION_500", message, err.Error()))
+}
+
+func compositionTemplateView(tmpl *storage.CompositionTemplate) gin.H {
+	view := gin.H{
+		"id":          tmpl.ID,
+		"name":        tmpl.Name,
+		"description": tmpl.Description,
+		"version":     tmpl.Version,
+		"created_at":  tmpl.CreatedAt,
+		"updated_at":  tmpl.UpdatedAt,
+	}
+	if def, err := devices.ParseCompositionTemplate(tmpl.Definition); err == nil {
+		view["parameters"] = def.Parameters
+		view["composition"] = def.Composition
+		view["io_mapping"] = def.IOMapping
+	}
+	return view
+}
@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GET /api/v1/devices/:id/compositions
+// Lists the composition versions of a device, newest first
+func (s *Server) listCompositionVersions(c *gin.Context) {
+	instanceID := c.Param("id")
+
+	versions, err := s.lm.Storage().ListCompositionVersions(c.Request.Context(), instanceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to list composition versions", err.Error()))
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Device has no composition versions", instanceID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": instanceID,
+		"current":     versions[0].Version,
+		"versions":    versions,
+	})
+}
+
+// GET /api/v1/devices/:id/compositions/:version
+func (s *Server) getCompositionVersion(c *gin.Context) {
+	version, ok := s.loadCompositionVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, version)
+}
+
+// GET /api/v1/devices/:id/compositions/:version/diff?against=3
+// Lists the changes from a version to another one, by default the current
+func (s *Server) diffCompositionVersions(c *gin.Context) {
+	from, ok := s.loadCompositionVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+	to, ok := s.loadCompositionVersion(c, c.DefaultQuery("against", "0"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instance_id": from.InstanceID,
+		"from":        from.Version,
+		"to":          to.Version,
+		"changes":     devices.DiffCompositions(from.DeviceComposition(), to.DeviceComposition()),
+	})
+}
+
+// POST /api/v1/devices/:id/compositions/:version/rollback
+// Saves a previous composition as new version and reloads the device with it
+func (s *Server) rollbackComposition(c *gin.Context) {
+	target, ok := s.loadCompositionVersion(c, c.Param("version"))
+	if !ok {
+		return
+	}
+	current, ok := s.loadCompositionVersion(c, "0")
+	if !ok {
+		return
+	}
+	if target.Version == current.Version {
+		c.JSON(http.StatusConflict, types.NewErrorResponse("DEVICE_409", "Version is already the current composition", target.Version))
+		return
+	}
+
+	comp := target.DeviceComposition()
+	changes := devices.DiffCompositions(current.DeviceComposition(), comp)
+
+	view, ok := s.installDevice(c, comp, fmt.Sprintf("rollback to version %d", target.Version))
+	if !ok {
+		return
+	}
+
+	s.logger.Info("Device composition rolled back",
+		zap.String("instance_id", comp.InstanceID),
+		zap.Int("from_version", current.Version),
+		zap.Int("to_version", target.Version),
+		zap.String("actor", requestActor(c)))
+
+	view["message"] = "Composition rolled back and device reloaded"
+	view["rolled_back_to"] = target.Version
+	view["changes"] = changes
+	c.JSON(http.StatusOK, view)
+}
+
+// loadCompositionVersion loads a composition version of the device in the
+// path; "0" is the current version. On failure the error response is
+// written.
+func (s *Server) loadCompositionVersion(c *gin.Context, param string) (*storage.CompositionVersion, bool) {
+	instanceID := c.Param("id")
+
+	number, err := strconv.Atoi(param)
+	if err != nil || number < 0 {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid composition version", param))
+		return nil, false
+	}
+
+	version, err := s.lm.Storage().GetCompositionVersion(c.Request.Context(), instanceID, number)
+	if errors.Is(err, storage.ErrCompositionVersionNotFound) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Composition version not found", err.Error()))
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to load composition version", err.Error()))
+		return nil, false
+	}
+	return version, true
+}
@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultCounterHistoryLimit bounds history responses without ?limit
+const defaultCounterHistoryLimit = 1000
+
+// GET /api/v1/counters
+func (s *Server) listCounters(c *gin.Context) {
+	counters := s.lm.Counters().List()
+
+	c.JSON(http.StatusOK, gin.H{
+		"counters": counters,
+		"count":    len(counters),
+	})
+}
+
+// GET /api/v1/counters/:name
+func (s *Server) getCounter(c *gin.Context) {
+	counter, ok := s.lm.Counters().Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("COUNTER_404", "Counter not found", c.Param("name")))
+		return
+	}
+
+	c.JSON(http.StatusOK, counter)
+}
+
+// GET /api/v1/counters/:name/history?from=...&to=...&limit=1000
+// Defaults to the last 24 hours.
+func (s *Server) getCounterHistory(c *gin.Context) {
+	name := c.Param("name")
+	if _, ok := s.lm.Counters().Get(name); !ok {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("COUNTER_404", "Counter not found", name))
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	limit := defaultCounterHistoryLimit
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("COUNTER_400", "Invalid from", err.Error()))
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("COUNTER_400", "Invalid to", err.Error()))
+			return
+		}
+		to = t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("COUNTER_400", "Invalid limit", v))
+			return
+		}
+		limit = n
+	}
+
+	samples, err := s.lm.Storage().CounterHistory(c.Request.Context(), name, from, to, limit)
+	if err != nil {
+		s.logger.Error("Failed to load counter history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("COUNTER_500", "Failed to load counter history", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":    name,
+		"from":    from,
+		"to":      to,
+		"samples": samples,
+		"count":   len(samples),
+	})
+}
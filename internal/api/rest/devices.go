@@ -1,9 +1,13 @@
 package rest
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/KevinKickass/OpenMachineCore/internal/manual"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -46,14 +50,61 @@ func (s *Server) getDevice(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":         device.ID,
-		"name":       device.Name,
-		"profile":    device.Profile.DeviceProfile,
-		"registers":  device.Profile.Registers,
-		"io_mapping": device.IOMapping,
+		"id":                device.ID,
+		"name":              device.Name,
+		"profile":           device.Profile.DeviceProfile,
+		"registers":         device.Profile.Registers,
+		"io_mapping":        device.IOMapping,
+		"identity":          device.Identity(),
+		"calibrations":      device.Calibrations(),
+		"virtual_registers": device.Profile.VirtualRegisters,
+		"aliases":           device.Aliases(),
+		"documentation":     device.Profile.Documentation,
+		"timeout":           s.deviceTimeout(device),
+		"scheduler":         device.Client.SchedulerStats(),
+		"trace":             device.Trace(),
 	})
 }
 
+// GET /api/v1/devices/:id/operations
+func (s *Server) getDeviceOperations(c *gin.Context) {
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid device ID", err.Error()))
+		return
+	}
+
+	device, exists := s.lm.DeviceManager().GetDevice(deviceID)
+	if !exists {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Device not found", deviceID.String()))
+		return
+	}
+
+	operations := device.Operations()
+	c.JSON(http.StatusOK, gin.H{
+		"device_id":  device.ID,
+		"operations": operations,
+		"count":      len(operations),
+	})
+}
+
+// deviceTimeout describes the effective request timeout of a device.
+// Steps with a timeout override it for their requests.
+func (s *Server) deviceTimeout(device *modbus.Device) gin.H {
+	global := s.lm.Config().Modbus.DefaultTimeout
+	_, source := modbus.EffectiveTimeout(device.Profile, global)
+
+	timeout := gin.H{
+		"effective": device.Client.Timeout().String(),
+		"source":    source,
+		"global":    global.String(),
+	}
+	if ms := device.Profile.Connection.TimeoutMs; ms > 0 {
+		timeout["device_profile"] = (time.Duration(ms) * time.Millisecond).String()
+	}
+	return timeout
+}
+
 // POST /api/v1/devices
 func (s *Server) createDevice(c *gin.Context) {
 	var req struct {
@@ -73,18 +124,33 @@ func (s *Server) createDevice(c *gin.Context) {
 		IOMapping:   req.IOMapping,
 	}
 
+	view, ok := s.installDevice(c, comp, "")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+// installDevice persists a composition as new composition version, loads the
+// device and starts its poller. A loaded device of the same name is replaced.
+// On failure the error response is written.
+func (s *Server) installDevice(c *gin.Context, comp types.DeviceComposition, note string) (gin.H, bool) {
 	// Save to database first (upsert)
-	deviceID, err := s.lm.Storage().SaveOrUpdateDeviceComposition(c.Request.Context(), comp)
+	deviceID, version, err := s.lm.Storage().SaveOrUpdateDeviceComposition(c.Request.Context(), comp, requestActor(c), note)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to save device", err.Error()))
-		return
+		return nil, false
 	}
 
-	// Load device from composition
-	device, err := s.lm.DeviceManager().LoadDeviceFromComposition(comp, 2*time.Second)
+	// Load device from composition, a loaded device keeps running until the
+	// new one is connected
+	device, replaced, err := s.lm.DeviceManager().ReplaceDeviceFromComposition(comp, s.lm.Config().Modbus.DefaultTimeout)
+	if replaced {
+		s.logger.Info("Replaced loaded device", zap.String("instance_id", comp.InstanceID), zap.Int("composition_version", version))
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to load device", err.Error()))
-		return
+		return nil, false
 	}
 
 	// Start poller
@@ -93,12 +159,13 @@ func (s *Server) createDevice(c *gin.Context) {
 		s.logger.Warn("Failed to start poller", zap.Error(err))
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"id":         deviceID,
-		"runtime_id": device.ID,
-		"name":       device.Name,
-		"message":    "Device created and persisted successfully",
-	})
+	return gin.H{
+		"id":                  deviceID,
+		"runtime_id":          device.ID,
+		"name":                device.Name,
+		"composition_version": version,
+		"message":             "Device created and persisted successfully",
+	}, true
 }
 
 // DELETE /api/v1/devices/:id
@@ -112,17 +179,20 @@ func (s *Server) deleteDevice(c *gin.Context) {
 		return
 	}
 
-	// Disconnect device
-	if err := device.Disconnect(); err != nil {
-		s.logger.Warn("Failed to disconnect device", zap.Error(err))
-	}
-
-	// Delete from database
+	// Delete from database first so a referenced device stays connected
 	if err := s.lm.Storage().DeleteDevice(c.Request.Context(), instanceID); err != nil {
+		var refErr *storage.ReferenceError
+		if errors.As(err, &refErr) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("DEVICE_409", "Device is still referenced", refErr))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to delete device", err.Error()))
 		return
 	}
 
+	// Stop polling and disconnect device
+	s.lm.DeviceManager().UnloadDevice(device.Name)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Device deleted successfully",
 	})
@@ -152,16 +222,40 @@ func (s *Server) readRegister(c *gin.Context) {
 		return
 	}
 
-	value, err := device.ReadLogical(c.Request.Context(), req.Register)
+	sample, err := device.ReadLogicalSample(c.Request.Context(), req.Register)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to read register", err.Error()))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"register":  req.Register,
-		"value":     value,
-		"timestamp": time.Now().Unix(),
+		"register":   req.Register,
+		"value":      sample.Value,
+		"quality":    sample.Quality,
+		"timestamp":  sample.Timestamp.Unix(),
+		"sampled_at": sample.Timestamp,
+	})
+}
+
+// GET /api/v1/devices/:id/values
+// Returns the cached register values with quality and timestamp, without
+// reading the device
+func (s *Server) getDeviceValues(c *gin.Context) {
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid device ID", err.Error()))
+		return
+	}
+
+	device, exists := s.lm.DeviceManager().GetDevice(deviceID)
+	if !exists {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Device not found", deviceID.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"device_id": device.ID,
+		"values":    device.LastSamples(),
 	})
 }
 
@@ -175,8 +269,11 @@ func (s *Server) writeRegister(c *gin.Context) {
 	}
 
 	var req struct {
-		Register string      `json:"register" binding:"required"`
-		Value    interface{} `json:"value" binding:"required"`
+		Register  string      `json:"register" binding:"required"`
+		Value     interface{} `json:"value" binding:"required"`
+		SessionID *uuid.UUID  `json:"session_id"` // Manual control session, see POST /manual-sessions
+		Verify    bool        `json:"verify"`     // Read the register back and compare
+		Tolerance float64     `json:"tolerance"`  // Accepted deviation of scaled values when verifying
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -190,14 +287,59 @@ func (s *Server) writeRegister(c *gin.Context) {
 		return
 	}
 
-	if err := device.WriteLogical(c.Request.Context(), req.Register, req.Value); err != nil {
+	ctx := modbus.WithWriter(c.Request.Context(), requestActor(c))
+	var written modbus.Sample
+	switch {
+	case req.SessionID != nil:
+		written, err = s.lm.Manual().Write(ctx, *req.SessionID, requestActor(c), device, req.Register, req.Value)
+		if errors.Is(err, manual.ErrSessionNotFound) || errors.Is(err, manual.ErrNotOwner) {
+			manualSessionError(c, err)
+			return
+		}
+	case s.lm.Manual().RequireSession():
+		c.JSON(http.StatusPreconditionRequired, types.NewErrorResponse("MANUAL_428", "Manual control session required", "open one with POST /api/v1/manual-sessions"))
+		return
+	default:
+		written, err = device.WriteLogicalSample(ctx, req.Register, req.Value)
+	}
+	var limitErr *modbus.WriteLimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusUnprocessableEntity, types.NewErrorResponse("DEVICE_422", "Write limit violated", limitErr))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to write register", err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":  "Register written successfully",
 		"register": req.Register,
 		"value":    req.Value,
-	})
+	}
+	if req.Verify {
+		readBack, err := device.VerifyWrite(c.Request.Context(), req.Register, written, req.Tolerance)
+		var verifyErr *modbus.VerifyError
+		if errors.As(err, &verifyErr) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("DEVICE_409", "Write not accepted by device", verifyErr))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("DEVICE_500", "Failed to verify write", err.Error()))
+			return
+		}
+		response["verified"] = true
+		response["read_back"] = readBack.Value
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// deviceAliases returns the deprecated names of a loaded device
+func (s *Server) deviceAliases(deviceName string) map[string]string {
+	device, exists := s.lm.DeviceManager().GetDeviceByName(deviceName)
+	if !exists {
+		return nil
+	}
+	return device.Aliases()
 }
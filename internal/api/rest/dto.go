@@ -0,0 +1,162 @@
+package rest
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+)
+
+// API representations of stored workflows and executions. Handlers answer
+// with these instead of the storage structs, so a changed storage struct
+// doesn't change the API: the JSON names below are the contract with HMI
+// clients. Lists are never null and times are UTC.
+
+type WorkflowDTO struct {
+	ID           uuid.UUID                   `json:"id"`
+	WorkflowName string                      `json:"workflow_name"`
+	Definition   json.RawMessage             `json:"definition"` // Workflow definition as JSON object
+	Active       bool                        `json:"active"`
+	CreatedAt    time.Time                   `json:"created_at"`
+	UpdatedAt    time.Time                   `json:"updated_at"`
+	Validation   *storage.WorkflowValidation `json:"validation,omitempty"`
+	Access       *WorkflowAccessDTO          `json:"access,omitempty"` // What the caller may do, see workflow access lists
+}
+
+type ExecutionDTO struct {
+	ID            uuid.UUID           `json:"id"`
+	WorkflowID    uuid.UUID           `json:"workflow_id"`
+	Status        string              `json:"status"`
+	CurrentStep   int                 `json:"current_step"`
+	CurrentStepID string              `json:"current_step_id"`
+	CallStack     json.RawMessage     `json:"call_stack"`
+	Input         json.RawMessage     `json:"input,omitempty"`
+	Output        json.RawMessage     `json:"output,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	CancelSource  string              `json:"cancel_source,omitempty"`
+	CancelReason  string              `json:"cancel_reason,omitempty"`
+	CancelledBy   string              `json:"cancelled_by,omitempty"`
+	RetryOf       *uuid.UUID          `json:"retry_of,omitempty"`
+	StartStep     int                 `json:"start_step,omitempty"`
+	SkipSteps     []string            `json:"skip_steps,omitempty"`
+	RetriedBy     []string            `json:"retried_by,omitempty"`
+	Correlation   storage.Correlation `json:"correlation"`
+	Tags          []string            `json:"tags"`
+	Metadata      json.RawMessage     `json:"metadata,omitempty"`
+	StartedAt     time.Time           `json:"started_at"`
+	CompletedAt   *time.Time          `json:"completed_at"`
+}
+
+type ExecutionStepDTO struct {
+	ID                 uuid.UUID       `json:"id"`
+	ExecutionID        uuid.UUID       `json:"execution_id"`
+	StepIndex          int             `json:"step_index"`
+	StepName           string          `json:"step_name"`
+	HierarchicalStepID string          `json:"hierarchical_step_id"`
+	Depth              int             `json:"depth"`
+	Status             string          `json:"status"`
+	Input              json.RawMessage `json:"input,omitempty"`
+	Output             json.RawMessage `json:"output,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	StartedAt          time.Time       `json:"started_at"`
+	CompletedAt        *time.Time      `json:"completed_at"`
+}
+
+func newWorkflowDTO(w *storage.Workflow) WorkflowDTO {
+	return WorkflowDTO{
+		ID:           w.ID,
+		WorkflowName: w.WorkflowName,
+		Definition:   jsonOr(w.Definition, "{}"),
+		Active:       w.Active,
+		CreatedAt:    w.CreatedAt.UTC(),
+		UpdatedAt:    w.UpdatedAt.UTC(),
+		Validation:   w.Validation,
+	}
+}
+
+func newWorkflowDTOs(workflows []storage.Workflow) []WorkflowDTO {
+	dtos := make([]WorkflowDTO, len(workflows))
+	for i := range workflows {
+		dtos[i] = newWorkflowDTO(&workflows[i])
+	}
+	return dtos
+}
+
+func newExecutionDTO(e *storage.WorkflowExecution) ExecutionDTO {
+	return ExecutionDTO{
+		ID:            e.ID,
+		WorkflowID:    e.WorkflowID,
+		Status:        string(e.Status),
+		CurrentStep:   e.CurrentStep,
+		CurrentStepID: e.CurrentStepID,
+		CallStack:     jsonOr(e.CallStack, "[]"),
+		Input:         e.Input,
+		Output:        e.Output,
+		Error:         e.Error,
+		CancelSource:  e.CancelSource,
+		CancelReason:  e.CancelReason,
+		CancelledBy:   e.CancelledBy,
+		RetryOf:       e.RetryOf,
+		StartStep:     e.StartStep,
+		SkipSteps:     e.SkipSteps,
+		RetriedBy:     e.RetriedBy,
+		Correlation:   e.Correlation,
+		Tags:          nonNil(e.Tags),
+		Metadata:      e.Metadata,
+		StartedAt:     e.StartedAt.UTC(),
+		CompletedAt:   utcOrNil(e.CompletedAt),
+	}
+}
+
+func newExecutionDTOs(executions []storage.WorkflowExecution) []ExecutionDTO {
+	dtos := make([]ExecutionDTO, len(executions))
+	for i := range executions {
+		dtos[i] = newExecutionDTO(&executions[i])
+	}
+	return dtos
+}
+
+func newExecutionStepDTOs(steps []storage.ExecutionStep) []ExecutionStepDTO {
+	dtos := make([]ExecutionStepDTO, len(steps))
+	for i, s := range steps {
+		dtos[i] = ExecutionStepDTO{
+			ID:                 s.ID,
+			ExecutionID:        s.ExecutionID,
+			StepIndex:          s.StepIndex,
+			StepName:           s.StepName,
+			HierarchicalStepID: s.HierarchicalStepID,
+			Depth:              s.Depth,
+			Status:             string(s.Status),
+			Input:              s.Input,
+			Output:             s.Output,
+			Error:              s.Error,
+			StartedAt:          s.StartedAt.UTC(),
+			CompletedAt:        utcOrNil(s.CompletedAt),
+		}
+	}
+	return dtos
+}
+
+// jsonOr returns stored JSON, or fallback if nothing is stored
+func jsonOr(data []byte, fallback string) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage(fallback)
+	}
+	return json.RawMessage(data)
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
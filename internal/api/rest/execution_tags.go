@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AnnotateExecutionRequest struct {
+	Add      []string        `json:"add"`
+	Remove   []string        `json:"remove"`
+	Metadata json.RawMessage `json:"metadata"` // Merged into the metadata, null removes a key
+}
+
+type SearchExecutionsRequest struct {
+	WorkflowID   *uuid.UUID              `json:"workflow_id"`
+	Status       storage.ExecutionStatus `json:"status"`
+	OrderID      string                  `json:"order_id"`
+	BatchID      string                  `json:"batch_id"`
+	SerialNumber string                  `json:"serial_number"`
+	Tags         []string                `json:"tags"`     // All must be present
+	Input        json.RawMessage         `json:"input"`    // Contained in the input
+	Metadata     json.RawMessage         `json:"metadata"` // Contained in the metadata
+	From         *time.Time              `json:"from"`
+	To           *time.Time              `json:"to"`
+	Limit        int                     `json:"limit"`
+}
+
+// queryList returns the values of a repeated or comma separated query parameter
+func queryList(c *gin.Context, name string) []string {
+	var list []string
+	for _, v := range c.QueryArray(name) {
+		for item := range strings.SplitSeq(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// isJSONObject reports whether raw is empty or a JSON object
+func isJSONObject(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return true
+	}
+	var obj map[string]any
+	return json.Unmarshal(raw, &obj) == nil && obj != nil
+}
+
+// POST /api/v1/executions/search
+// Finds executions by tags and by JSON contained in their input or metadata,
+// e.g. {"tags": ["recipe:X"], "input": {"part_type": "Y"}, "from": "..."}
+func (s *Server) searchExecutions(c *gin.Context) {
+	var req SearchExecutionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid request body", err.Error()))
+		return
+	}
+	if !isJSONObject(req.Input) || !isJSONObject(req.Metadata) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "input and metadata must be JSON objects", nil))
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultExecutionListLimit
+	}
+	if req.Limit < 0 || req.Limit > maxExecutionListLimit {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid limit", req.Limit))
+		return
+	}
+
+	filter := storage.ExecutionFilter{
+		WorkflowID:   req.WorkflowID,
+		Status:       req.Status,
+		OrderID:      req.OrderID,
+		BatchID:      req.BatchID,
+		SerialNumber: req.SerialNumber,
+		Tags:         req.Tags,
+		Input:        req.Input,
+		Metadata:     req.Metadata,
+		From:         req.From,
+		To:           req.To,
+		Limit:        req.Limit,
+	}
+	executions, err := s.lm.Storage().ListExecutions(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to search executions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to search executions", err.Error()))
+		return
+	}
+	executions, ok := s.readableExecutions(c, executions)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": newExecutionDTOs(executions),
+		"count":      len(executions),
+	})
+}
+
+// PATCH /api/v1/executions/:id/tags
+func (s *Server) annotateExecution(c *gin.Context) {
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	var req AnnotateExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid request body", err.Error()))
+		return
+	}
+	if !isJSONObject(req.Metadata) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "metadata must be a JSON object", nil))
+		return
+	}
+	add, err := storage.NormalizeTags(req.Add)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid tags", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.lm.Storage().GetExecution(ctx, executionID); err != nil {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("EXEC_404", "Execution not found", executionID.String()))
+		return
+	}
+
+	tags, metadata, err := s.lm.Storage().AnnotateExecution(ctx, executionID, add, req.Remove, req.Metadata)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid tags", err.Error()))
+			return
+		}
+		s.logger.Error("Failed to annotate execution", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to annotate execution", err.Error()))
+		return
+	}
+
+	s.logger.Info("Execution annotated",
+		zap.String("execution_id", executionID.String()),
+		zap.Strings("tags", tags),
+		zap.String("actor", requestActor(c)))
+	c.JSON(http.StatusOK, gin.H{
+		"execution_id": executionID,
+		"tags":         tags,
+		"metadata":     metadata,
+	})
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/faults"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GET /api/v1/faults
+func (s *Server) listFaults(c *gin.Context) {
+	list := s.lm.FaultInjector().List()
+
+	c.JSON(http.StatusOK, gin.H{
+		"faults": list,
+		"count":  len(list),
+	})
+}
+
+// POST /api/v1/faults
+func (s *Server) injectFault(c *gin.Context) {
+	var spec faults.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FAULT_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	fault, err := s.lm.FaultInjector().Add(spec, requestActor(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FAULT_400", "Invalid fault", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, fault)
+}
+
+// DELETE /api/v1/faults/:id
+func (s *Server) removeFault(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FAULT_400", "Invalid fault ID", err.Error()))
+		return
+	}
+
+	if err := s.lm.FaultInjector().Remove(id); err != nil {
+		if errors.Is(err, faults.ErrFaultNotFound) {
+			c.JSON(http.StatusNotFound, types.NewErrorResponse("FAULT_404", "Fault not found", id.String()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("FAULT_500", "Failed to remove fault", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fault removed",
+	})
+}
+
+// DELETE /api/v1/faults
+func (s *Server) clearFaults(c *gin.Context) {
+	cleared := s.lm.FaultInjector().Clear()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "All faults removed",
+		"cleared": cleared,
+	})
+}
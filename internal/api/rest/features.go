@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/features"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type setFeatureRequest struct {
+	Enabled   *bool            `json:"enabled"`
+	Workflows map[string]*bool `json:"workflows"` // null removes an override
+}
+
+// GET /api/v1/features
+func (s *Server) listFeatures(c *gin.Context) {
+	flags := s.lm.Features().List()
+	c.JSON(http.StatusOK, gin.H{
+		"features": flags,
+		"count":    len(flags),
+	})
+}
+
+// GET /api/v1/features/:name
+func (s *Server) getFeature(c *gin.Context) {
+	flag, err := s.lm.Features().Get(c.Param("name"))
+	if err != nil {
+		s.featureError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// PUT /api/v1/features/:name
+func (s *Server) setFeature(c *gin.Context) {
+	var req setFeatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FEATURE_400", "Invalid request body", err.Error()))
+		return
+	}
+	if req.Enabled == nil && len(req.Workflows) == 0 {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FEATURE_400", "Nothing to change", "set enabled or workflows"))
+		return
+	}
+
+	flag, err := s.lm.Features().Set(c.Request.Context(), c.Param("name"), req.Enabled, req.Workflows, requestActor(c))
+	if err != nil {
+		s.featureError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// DELETE /api/v1/features/:name
+// Restores the default and removes the workflow overrides.
+func (s *Server) resetFeature(c *gin.Context) {
+	flag, err := s.lm.Features().Reset(c.Request.Context(), c.Param("name"), requestActor(c))
+	if err != nil {
+		s.featureError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+func (s *Server) featureError(c *gin.Context, err error) {
+	if errors.Is(err, features.ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("FEATURE_404", "Unknown feature flag", err.Error()))
+		return
+	}
+	if errors.Is(err, features.ErrInvalidWorkflow) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("FEATURE_400", "Invalid workflow override", err.Error()))
+		return
+	}
+	s.logger.Error("Feature flag operation failed", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, types.NewErrorResponse("FEATURE_500", "Feature flag operation failed", err.Error()))
+}
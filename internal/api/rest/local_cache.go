@@ -0,0 +1,234 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Sections of the HMI snapshot
+const (
+	snapshotDevices    = "devices"
+	snapshotWorkflows  = "workflows"
+	snapshotMachine    = "machine"
+	snapshotExecutions = "executions"
+)
+
+// snapshotHistorySize is the number of versions delta requests can start from
+const snapshotHistorySize = 64
+
+// snapshotItem is an entry of a snapshot section with the hash of its JSON
+type snapshotItem struct {
+	id    string
+	value any
+	hash  uint64
+}
+
+// snapshotHistory remembers the item hashes of recently served snapshot
+// versions, so a client that knows a version only gets what changed since
+type snapshotHistory struct {
+	mu       sync.Mutex
+	versions map[string]map[string]uint64 // version -> "section/id" -> hash
+	order    []string
+}
+
+func newSnapshotHistory() *snapshotHistory {
+	return &snapshotHistory{versions: make(map[string]map[string]uint64)}
+}
+
+// add records a version, dropping the oldest beyond snapshotHistorySize
+func (h *snapshotHistory) add(version string, hashes map[string]uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.versions[version]; ok {
+		return
+	}
+	h.versions[version] = hashes
+	h.order = append(h.order, version)
+	if len(h.order) > snapshotHistorySize {
+		delete(h.versions, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// get returns the item hashes of a version
+func (h *snapshotHistory) get(version string) (map[string]uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hashes, ok := h.versions[version]
+	return hashes, ok
+}
+
+// GET /api/v1/snapshot?since=<version>
+// Returns devices, workflow summaries, the machine status and the active
+// executions in one response, so HMIs on flaky networks can bootstrap their
+// local cache with a single request. The version is also sent as ETag;
+// If-None-Match answers 304 while nothing changed. With ?since= set to a
+// recent version only changed and removed items are returned.
+func (s *Server) getSnapshot(c *gin.Context) {
+	sections, err := s.collectSnapshot(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to collect snapshot", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SNAPSHOT_500", "Failed to collect snapshot", err.Error()))
+		return
+	}
+
+	hashes := make(map[string]uint64)
+	for section, items := range sections {
+		for _, item := range items {
+			hashes[section+"/"+item.id] = item.hash
+		}
+	}
+	version := snapshotVersion(hashes)
+	s.snapshots.add(version, hashes)
+
+	etag := `"` + version + `"`
+	c.Header("ETag", etag)
+	since := c.Query("since")
+	if c.GetHeader("If-None-Match") == etag || since == version {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Unknown or expired versions get the full snapshot
+	previous, delta := s.snapshots.get(since)
+
+	response := gin.H{
+		"version":      version,
+		"full":         !delta,
+		"generated_at": time.Now(),
+	}
+	if delta {
+		response["since"] = since
+	}
+
+	for _, section := range []string{snapshotDevices, snapshotWorkflows, snapshotExecutions} {
+		changed := make([]any, 0)
+		present := make(map[string]bool, len(sections[section]))
+		for _, item := range sections[section] {
+			key := section + "/" + item.id
+			present[key] = true
+			if hash, ok := previous[key]; !delta || !ok || hash != item.hash {
+				changed = append(changed, item.value)
+			}
+		}
+
+		removed := make([]string, 0)
+		if delta {
+			prefix := section + "/"
+			for key := range previous {
+				if id, ok := strings.CutPrefix(key, prefix); ok && !present[key] {
+					removed = append(removed, id)
+				}
+			}
+			slices.Sort(removed)
+		}
+
+		response[section] = gin.H{
+			"changed": changed,
+			"removed": removed,
+		}
+	}
+
+	// The machine status is sent whole, and left out of deltas while unchanged
+	status := sections[snapshotMachine][0]
+	if hash, ok := previous[snapshotMachine+"/"+status.id]; !delta || !ok || hash != status.hash {
+		response[snapshotMachine] = status.value
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// collectSnapshot gathers the snapshot sections with their item hashes
+func (s *Server) collectSnapshot(ctx context.Context) (map[string][]snapshotItem, error) {
+	sections := make(map[string][]snapshotItem)
+
+	for _, device := range s.lm.DeviceManager().ListDevices() {
+		sections[snapshotDevices] = append(sections[snapshotDevices], newSnapshotItem(device.ID.String(), gin.H{
+			"id":        device.ID,
+			"name":      device.Name,
+			"profile":   device.Profile.DeviceProfile.Model,
+			"connected": device.Client != nil,
+		}))
+	}
+
+	workflows, err := s.lm.Storage().ListWorkflows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	validations, err := s.lm.Storage().ListWorkflowValidations(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load workflow validations", zap.Error(err))
+	}
+	for _, workflow := range workflows {
+		summary := gin.H{
+			"id":            workflow.ID,
+			"workflow_name": workflow.WorkflowName,
+			"active":        workflow.Active,
+			"updated_at":    workflow.UpdatedAt,
+		}
+		if validation := validations[workflow.ID]; validation != nil {
+			summary["valid"] = validation.Valid
+		}
+		sections[snapshotWorkflows] = append(sections[snapshotWorkflows], newSnapshotItem(workflow.ID.String(), summary))
+	}
+
+	for _, status := range []storage.ExecutionStatus{storage.StatusPending, storage.StatusRunning} {
+		executions, err := s.lm.Storage().ListExecutions(ctx, storage.ExecutionFilter{
+			Status: status,
+			Limit:  maxExecutionListLimit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s executions: %w", status, err)
+		}
+		for _, exec := range executions {
+			sections[snapshotExecutions] = append(sections[snapshotExecutions], newSnapshotItem(exec.ID.String(), newExecutionDTO(&exec)))
+		}
+	}
+
+	// LastStateChange is the time of the request, keep it out of the hash
+	status := s.lm.MachineController().GetStatus()
+	hashed := status
+	hashed.LastStateChange = time.Time{}
+	item := newSnapshotItem("status", hashed)
+	item.value = status
+	sections[snapshotMachine] = []snapshotItem{item}
+
+	return sections, nil
+}
+
+func newSnapshotItem(id string, value any) snapshotItem {
+	data, _ := json.Marshal(value)
+	h := fnv.New64a()
+	h.Write(data)
+	return snapshotItem{id: id, value: value, hash: h.Sum64()}
+}
+
+// snapshotVersion derives the version from the item hashes, so the same
+// state always has the same version, also across restarts
+func snapshotVersion(hashes map[string]uint64) string {
+	keys := make([]string, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	h := fnv.New64a()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%x;", key, hashes[key])
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
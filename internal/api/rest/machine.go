@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,6 +22,13 @@ func (s *Server) getMachineStatus(c *gin.Context) {
 func (s *Server) executeMachineCommand(c *gin.Context) {
 	var req struct {
 		Command string `json:"command" binding:"required"`
+		Queue   bool   `json:"queue"`
+		Reason  string `json:"reason"` // Recorded on executions cancelled by stop
+
+		// Correlation IDs stored on the production execution (start)
+		OrderID      string `json:"order_id"`
+		BatchID      string `json:"batch_id"`
+		SerialNumber string `json:"serial_number"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -29,7 +38,34 @@ func (s *Server) executeMachineCommand(c *gin.Context) {
 
 	cmd := machine.Command(req.Command)
 
-	if err := s.lm.MachineController().ExecuteCommand(c.Request.Context(), cmd); err != nil {
+	result, err := s.lm.MachineController().ExecuteCommand(c.Request.Context(), cmd, machine.CommandOptions{
+		Queue:  req.Queue,
+		Reason: req.Reason,
+		Actor:  requestActor(c),
+		Correlation: storage.Correlation{
+			OrderID:      req.OrderID,
+			BatchID:      req.BatchID,
+			SerialNumber: req.SerialNumber,
+		},
+	})
+	if err != nil {
+		var rejection *machine.CommandRejection
+		if errors.As(err, &rejection) {
+			s.logger.Warn("Machine command rejected",
+				zap.String("command", req.Command),
+				zap.String("reason_code", string(rejection.Code)),
+				zap.String("state", string(rejection.State)))
+			c.JSON(http.StatusConflict, types.NewErrorResponse("MACHINE_409", "Command rejected", gin.H{
+				"reason_code":        rejection.Code,
+				"reason":             rejection.Reason,
+				"command":            rejection.Command,
+				"state":              rejection.State,
+				"allowed_commands":   machine.AllowedCommands(rejection.State),
+				"queueable_commands": machine.QueueableCommands(rejection.State),
+			}))
+			return
+		}
+
 		s.logger.Error("Machine command failed",
 			zap.String("command", req.Command),
 			zap.Error(err))
@@ -37,9 +73,15 @@ func (s *Server) executeMachineCommand(c *gin.Context) {
 		return
 	}
 
+	message := "Command accepted"
+	if result == machine.CommandQueued {
+		message = "Command queued"
+	}
+
 	c.JSON(http.StatusAccepted, gin.H{
-		"message": "Command accepted",
+		"message": message,
 		"command": req.Command,
+		"result":  result,
 	})
 }
 
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/manual"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// manualSessionError responds with the error of a session operation
+func manualSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, manual.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("MANUAL_404", "Manual control session not found or expired", nil))
+	case errors.Is(err, manual.ErrNotOwner):
+		c.JSON(http.StatusForbidden, types.NewErrorResponse("MANUAL_403", "Manual control session belongs to another user", nil))
+	case errors.Is(err, manual.ErrTooManySessions):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("MANUAL_409", "Too many manual control sessions", nil))
+	default:
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("MANUAL_500", "Manual control session failed", err.Error()))
+	}
+}
+
+// sessionParam parses the :id of a manual control session
+func sessionParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("MANUAL_400", "Invalid session ID", err.Error()))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// POST /api/v1/manual-sessions
+// Outputs written with the session ID revert to their safe states when no
+// heartbeat arrives within the heartbeat timeout
+func (s *Server) openManualSession(c *gin.Context) {
+	session, err := s.lm.Manual().Open(c.Request.Context(), requestActor(c))
+	if err != nil {
+		manualSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// GET /api/v1/manual-sessions
+func (s *Server) listManualSessions(c *gin.Context) {
+	sessions := s.lm.Manual().List()
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// POST /api/v1/manual-sessions/:id/heartbeat
+func (s *Server) manualSessionHeartbeat(c *gin.Context) {
+	id, ok := sessionParam(c)
+	if !ok {
+		return
+	}
+	session, err := s.lm.Manual().Heartbeat(id, requestActor(c))
+	if err != nil {
+		manualSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":         session.ID,
+		"expires_at": session.ExpiresAt,
+	})
+}
+
+// DELETE /api/v1/manual-sessions/:id
+// Reverts the outputs of the session and releases it
+func (s *Server) releaseManualSession(c *gin.Context) {
+	id, ok := sessionParam(c)
+	if !ok {
+		return
+	}
+	session, err := s.lm.Manual().Release(id, requestActor(c))
+	if err != nil {
+		manualSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
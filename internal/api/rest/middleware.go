@@ -1,9 +1,18 @@
 package rest
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/KevinKickass/OpenMachineCore/internal/usage"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -45,3 +54,169 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// BodyLimitMiddleware rejects request bodies larger than maxBytes with 413.
+// Bodies without Content-Length are capped while reading.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, types.NewErrorResponse(
+				"REQUEST_413",
+				"Request body too large",
+				gin.H{"size": c.Request.ContentLength, "limit": maxBytes},
+			))
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// TimeoutMiddleware bounds the request context of a handler. If the deadline
+// passes before a response is written, or the handler fails with a 5xx
+// because of it, the client receives 408 instead.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		tw := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx, timeout: timeout}
+		c.Writer = tw
+
+		c.Next()
+
+		if ctx.Err() == context.DeadlineExceeded && !tw.Written() {
+			tw.writeTimeout()
+		}
+	}
+}
+
+// timeoutWriter replaces error responses caused by an expired deadline
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timeout  time.Duration
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.timedOut {
+		return
+	}
+	if code >= http.StatusInternalServerError && w.ctx.Err() == context.DeadlineExceeded {
+		w.writeTimeout()
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.timedOut {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.timedOut {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) writeTimeout() {
+	w.timedOut = true
+
+	body, _ := json.Marshal(types.NewErrorResponse(
+		"REQUEST_408",
+		"Request timed out",
+		gin.H{"timeout": w.timeout.String()},
+	))
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(http.StatusRequestTimeout)
+	w.ResponseWriter.Write(body)
+}
+
+// UsageMiddleware counts every authenticated API call per principal and
+// endpoint class. Responses below 400 count as success.
+func UsageMiddleware(tracker *usage.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if !tracker.Enabled() {
+			return
+		}
+		principal, ok := requestPrincipal(c)
+		if !ok {
+			return
+		}
+		tracker.Record(principal, endpointClass(c.FullPath()), c.Writer.Status() < http.StatusBadRequest)
+	}
+}
+
+// QuotaMiddleware rejects requests of principals that used up a quota with 429.
+// It must run after authentication.
+func QuotaMiddleware(tracker *usage.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := requestPrincipal(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		err := tracker.Allow(principal, endpointClass(c.FullPath()))
+		var quotaErr *usage.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			retryAfter := int(time.Until(quotaErr.ResetAt).Seconds()) + 1
+			c.Header("Retry-After", fmt.Sprint(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, types.NewErrorResponse(
+				"QUOTA_429",
+				"Quota exceeded",
+				quotaErr,
+			))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requestPrincipal returns the authenticated user or machine token
+func requestPrincipal(c *gin.Context) (usage.Principal, bool) {
+	if userID, ok := c.Get("user_id"); ok {
+		id, _ := userID.(uuid.UUID)
+		return usage.Principal{Type: usage.PrincipalUser, ID: id.String(), Name: c.GetString("username")}, true
+	}
+	if tokenID := c.GetString("machine_token_id"); tokenID != "" {
+		return usage.Principal{Type: usage.PrincipalMachineToken, ID: tokenID, Name: c.GetString("machine_token_name")}, true
+	}
+	return usage.Principal{}, false
+}
+
+// endpointClass groups routes for usage accounting: "execution_start" for
+// routes starting executions, otherwise the route group ("devices", ...)
+func endpointClass(fullPath string) string {
+	_, route := versionedRoute(fullPath)
+	switch route {
+	case "/workflows/:id/execute", "/executions/:id/retry":
+		return "execution_start"
+	}
+
+	group, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	if group == "" {
+		return "other"
+	}
+	return group
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/descriptors"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GET /api/v1/modules/updates
+func (s *Server) getModuleUpdates(c *gin.Context) {
+	status, err := s.lm.DescriptorSync().Status()
+	if err != nil {
+		s.moduleSyncError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /api/v1/modules/updates/check
+func (s *Server) checkModuleUpdates(c *gin.Context) {
+	status, err := s.lm.DescriptorSync().Check(c.Request.Context())
+	if err != nil {
+		s.moduleSyncError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /api/v1/modules/updates/:vendor/install
+func (s *Server) installModulePack(c *gin.Context) {
+	vendor := c.Param("vendor")
+
+	installed, err := s.lm.DescriptorSync().Install(c.Request.Context(), vendor, requestActor(c))
+	if err != nil {
+		s.moduleSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"vendor":  vendor,
+		"pack":    installed,
+		"message": "Descriptor pack installed successfully",
+	})
+}
+
+// moduleSyncError maps descriptor sync errors to responses
+func (s *Server) moduleSyncError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, descriptors.ErrDisabled):
+		c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("MODULE_503", "Descriptor sync unavailable",
+			"Enable device_profiles.sync and configure a repository"))
+	case errors.Is(err, descriptors.ErrUnknownPack):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("MODULE_404", "Descriptor pack not found", err.Error()))
+	default:
+		s.logger.Error("Descriptor sync failed", zap.Error(err))
+		c.JSON(http.StatusBadGateway, types.NewErrorResponse("MODULE_502", "Descriptor sync failed", err.Error()))
+	}
+}
@@ -13,22 +13,6 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-type VendorIndex struct {
-	Vendor      string                 `yaml:"vendor"`
-	Description string                 `yaml:"description"`
-	Website     string                 `yaml:"website"`
-	Modules     map[string][]ModuleRef `yaml:"modules"`
-}
-
-type ModuleRef struct {
-	ID          string `yaml:"id"`
-	File        string `yaml:"file"`
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Tested      bool   `yaml:"tested"`
-	Datasheet   string `yaml:"datasheet"`
-}
-
 // GET /api/v1/modules
 func (s *Server) listModules(c *gin.Context) {
 	searchPaths := s.lm.Config().Devices.SearchPaths
@@ -44,8 +28,9 @@ func (s *Server) listModules(c *gin.Context) {
 		s.logger.Debug("Checking vendors path", zap.String("path", vendorsPath))
 
 		// Check if vendors directory exists
+		// Missing directories are normal, e.g. the optional system profile directory
 		if _, err := os.Stat(vendorsPath); os.IsNotExist(err) {
-			s.logger.Warn("Vendors directory does not exist", zap.String("path", vendorsPath))
+			s.logger.Debug("Vendors directory does not exist", zap.String("path", vendorsPath))
 			continue
 		}
 
@@ -90,7 +75,7 @@ func (s *Server) listModules(c *gin.Context) {
 				continue
 			}
 
-			var index VendorIndex
+			var index types.VendorIndex
 			if err := yaml.Unmarshal(data, &index); err != nil {
 				s.logger.Error("Failed to parse vendor index",
 					zap.String("vendor", vendorName),
@@ -100,7 +85,7 @@ func (s *Server) listModules(c *gin.Context) {
 			}
 
 			// Collect all modules from all categories
-			modules := make([]ModuleRef, 0)
+			modules := make([]types.ModuleRef, 0)
 			for category, categoryModules := range index.Modules {
 				s.logger.Debug("Found module category",
 					zap.String("vendor", vendorName),
@@ -157,7 +142,7 @@ func (s *Server) getVendorModules(c *gin.Context) {
 			continue
 		}
 
-		var index VendorIndex
+		var index types.VendorIndex
 		if err := yaml.Unmarshal(data, &index); err != nil {
 			s.logger.Error("Failed to parse vendor index",
 				zap.String("vendor", vendor),
@@ -214,7 +199,7 @@ func (s *Server) getModule(c *gin.Context) {
 			continue
 		}
 
-		var index VendorIndex
+		var index types.VendorIndex
 		if err := yaml.Unmarshal(data, &index); err != nil {
 			s.logger.Error("Failed to parse index", zap.Error(err))
 			continue
@@ -296,3 +281,15 @@ func (s *Server) getModule(c *gin.Context) {
 
 	c.JSON(http.StatusNotFound, types.NewErrorResponse("MODULE_404", "Module not found", map[string]string{"vendor": vendor, "model": model}))
 }
+
+// GET /api/v1/modules/errors
+func (s *Server) getModuleErrors(c *gin.Context) {
+	watcher := s.lm.DescriptorWatcher()
+	errors := watcher.Errors()
+
+	c.JSON(http.StatusOK, gin.H{
+		"errors":   errors,
+		"count":    len(errors),
+		"watching": watcher.Watching(),
+	})
+}
@@ -0,0 +1,222 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/onboarding"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// errInstallResponded marks a failed install whose error response is written
+var errInstallResponded = errors.New("install failed")
+
+// onboardingError responds with the error of an onboarding operation
+func onboardingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, onboarding.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("ONBOARDING_404", "Onboarding not found or ended", nil))
+	case errors.Is(err, onboarding.ErrCheckNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("ONBOARDING_404", "Check not found", err.Error()))
+	case errors.Is(err, onboarding.ErrNotOwner):
+		c.JSON(http.StatusForbidden, types.NewErrorResponse("ONBOARDING_403", "Onboarding belongs to another user", nil))
+	case errors.Is(err, onboarding.ErrInvalidAnswer):
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid answer", err.Error()))
+	case errors.Is(err, onboarding.ErrTooManySessions):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("ONBOARDING_409", "Too many onboardings", nil))
+	case errors.Is(err, onboarding.ErrInstanceBusy), errors.Is(err, devices.ErrDuplicateDevice):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("ONBOARDING_409", "Device is already loaded or being onboarded", err.Error()))
+	case errors.Is(err, onboarding.ErrWrongStage), errors.Is(err, onboarding.ErrCheckNotPrompted):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("ONBOARDING_409", "Not possible in the current stage", err.Error()))
+	case errors.Is(err, onboarding.ErrChecksFailed):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("ONBOARDING_409", "Checks failed", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("ONBOARDING_500", "Onboarding failed", err.Error()))
+	}
+}
+
+// onboardingParam parses the :id of an onboarding
+func onboardingParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid onboarding ID", err.Error()))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// POST /api/v1/onboarding
+// Previews a composition without connecting anything
+func (s *Server) openOnboarding(c *gin.Context) {
+	var req struct {
+		InstanceID  string                  `json:"instance_id" binding:"required"`
+		Composition types.CompositionConfig `json:"composition" binding:"required"`
+		IOMapping   map[string]string       `json:"io_mapping" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	session, err := s.lm.Onboarding().Open(requestActor(c), types.DeviceComposition{
+		InstanceID:  req.InstanceID,
+		Composition: req.Composition,
+		IOMapping:   req.IOMapping,
+	})
+	if errors.Is(err, devices.ErrDuplicateDevice) || errors.Is(err, onboarding.ErrInstanceBusy) || errors.Is(err, onboarding.ErrTooManySessions) {
+		onboardingError(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid composition", err.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// GET /api/v1/onboarding
+func (s *Server) listOnboardings(c *gin.Context) {
+	sessions := s.lm.Onboarding().List()
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// GET /api/v1/onboarding/:id
+func (s *Server) getOnboarding(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	session, found := s.lm.Onboarding().Get(id)
+	if !found {
+		onboardingError(c, onboarding.ErrSessionNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// POST /api/v1/onboarding/:id/connect
+// Connects and identifies the device; it is not loaded or polled
+func (s *Server) connectOnboarding(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	session, err := s.lm.Onboarding().Connect(id, requestActor(c))
+	if errors.Is(err, devices.ErrConnect) {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   types.NewErrorResponse("ONBOARDING_502", "Failed to connect device", err.Error()).Error,
+			"session": session,
+		})
+		return
+	}
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// POST /api/v1/onboarding/:id/verify
+// Starts the I/O checks; they are prompted over WebSocket as "onboarding_prompt"
+func (s *Server) verifyOnboarding(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	session, err := s.lm.Onboarding().Verify(id, requestActor(c))
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, session)
+}
+
+// POST /api/v1/onboarding/:id/checks/:index
+// Answers the prompted check, like the WebSocket command "onboarding_answer"
+func (s *Server) answerOnboardingCheck(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid check index", err.Error()))
+		return
+	}
+	var req struct {
+		Result string `json:"result" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	session, err := s.lm.Onboarding().Answer(id, requestActor(c), index, req.Result, req.Note)
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// POST /api/v1/onboarding/:id/confirm
+// Persists and loads the verified device like POST /devices
+func (s *Server) confirmOnboarding(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Force bool `json:"force"` // Persist despite failed checks
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("ONBOARDING_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	var device gin.H
+	session, err := s.lm.Onboarding().Complete(id, requestActor(c), req.Force, func(comp types.DeviceComposition) error {
+		view, installed := s.installDevice(c, comp, "Onboarding "+id.String())
+		if !installed {
+			return errInstallResponded
+		}
+		device = view
+		return nil
+	})
+	if errors.Is(err, errInstallResponded) {
+		return
+	}
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"session": session,
+		"device":  device,
+	})
+}
+
+// DELETE /api/v1/onboarding/:id
+// Restores outputs under check and disconnects the device without persisting it
+func (s *Server) cancelOnboarding(c *gin.Context) {
+	id, ok := onboardingParam(c)
+	if !ok {
+		return
+	}
+	session, err := s.lm.Onboarding().Cancel(id, requestActor(c))
+	if err != nil {
+		onboardingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
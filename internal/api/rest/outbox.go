@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/outbox"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GET /api/v1/outbox
+func (s *Server) getOutboxStatus(c *gin.Context) {
+	sinks, err := s.lm.Outbox().Sinks(c.Request.Context())
+	if err != nil {
+		s.outboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": s.lm.Outbox().Enabled(),
+		"sinks":   sinks,
+	})
+}
+
+// GET /api/v1/outbox/deliveries?sink=&status=retrying|dead&limit=
+func (s *Server) listOutboxDeliveries(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != storage.DeliveryRetrying && status != storage.DeliveryDead {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("OUTBOX_400", "Invalid status", "Use retrying or dead"))
+		return
+	}
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("OUTBOX_400", "Invalid limit", v))
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := s.lm.Outbox().Deliveries(c.Request.Context(), c.Query("sink"), status, limit)
+	if err != nil {
+		s.outboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// POST /api/v1/outbox/deliveries/:id/requeue
+func (s *Server) requeueOutboxDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("OUTBOX_400", "Invalid delivery ID", err.Error()))
+		return
+	}
+
+	delivery, err := s.lm.Outbox().Requeue(c.Request.Context(), id, requestActor(c))
+	if err != nil {
+		s.outboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Delivery requeued",
+		"delivery": delivery,
+	})
+}
+
+// POST /api/v1/outbox/sinks/:sink/requeue
+// Requeues all dead-lettered deliveries of the sink.
+func (s *Server) requeueOutboxSink(c *gin.Context) {
+	n, err := s.lm.Outbox().RequeueDead(c.Request.Context(), c.Param("sink"), requestActor(c))
+	if err != nil {
+		s.outboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Dead deliveries requeued",
+		"requeued": n,
+	})
+}
+
+// outboxError maps outbox errors to responses
+func (s *Server) outboxError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("OUTBOX_404", "Delivery not found", err.Error()))
+	case errors.Is(err, outbox.ErrUnknownSink):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("OUTBOX_404", "Sink not found", err.Error()))
+	default:
+		s.logger.Error("Outbox operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("OUTBOX_500", "Outbox operation failed", err.Error()))
+	}
+}
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+type pingRequest struct {
+	RegisterType types.RegisterType `json:"register_type"`
+	Address      *uint16            `json:"address"`
+}
+
+// POST /api/v1/devices/:id/ping
+// Reads one register and reports latency and any Modbus exception. Without
+// a body the first holding or input register of the profile is read.
+func (s *Server) pingDevice(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+
+	var req pingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	if req.Address == nil {
+		regType, address := types.RegisterTypeHoldingRegister, uint16(0)
+		for _, reg := range device.Profile.Registers {
+			if reg.Type == types.RegisterTypeHoldingRegister || reg.Type == types.RegisterTypeInputRegister {
+				regType, address = reg.Type, reg.Address
+				break
+			}
+		}
+		req.Address = &address
+		if req.RegisterType == "" {
+			req.RegisterType = regType
+		}
+	}
+	switch req.RegisterType {
+	case "":
+		req.RegisterType = types.RegisterTypeHoldingRegister
+	case types.RegisterTypeHoldingRegister, types.RegisterTypeInputRegister:
+	default:
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid register type", "ping supports holding_register and input_register"))
+		return
+	}
+
+	result := device.Ping(c.Request.Context(), req.RegisterType, *req.Address)
+
+	c.JSON(http.StatusOK, gin.H{
+		"device": device.Name,
+		"ping":   result,
+	})
+}
@@ -0,0 +1,12 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /api/v1/routing
+func (s *Server) getRoutingStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.lm.Router().Status())
+}
@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type saveSecretRequest struct {
+	Value       string `json:"value" binding:"required"`
+	Description string `json:"description"`
+}
+
+// GET /api/v1/secrets
+// Values are never returned.
+func (s *Server) listSecrets(c *gin.Context) {
+	list, err := s.lm.Secrets().List(c.Request.Context())
+	if err != nil {
+		s.secretError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secrets":   list,
+		"count":     len(list),
+		"available": s.lm.Secrets().Available(),
+	})
+}
+
+// GET /api/v1/secrets/:name
+func (s *Server) getSecret(c *gin.Context) {
+	secret, err := s.lm.Secrets().Metadata(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		s.secretError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, secret)
+}
+
+// PUT /api/v1/secrets/:name
+// Creates the secret or replaces its value.
+func (s *Server) saveSecret(c *gin.Context) {
+	name := c.Param("name")
+	if err := secrets.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SECRET_400", "Invalid secret name", err.Error()))
+		return
+	}
+
+	var req saveSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SECRET_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	secret, created, err := s.lm.Secrets().Save(c.Request.Context(), name, req.Value, req.Description, requestActor(c))
+	if err != nil {
+		s.secretError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, secret)
+}
+
+// DELETE /api/v1/secrets/:name
+func (s *Server) deleteSecret(c *gin.Context) {
+	if err := s.lm.Secrets().Delete(c.Request.Context(), c.Param("name"), requestActor(c)); err != nil {
+		s.secretError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Secret deleted successfully",
+	})
+}
+
+// secretError maps secret store errors to responses; details never contain values
+func (s *Server) secretError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrSecretNotFound):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("SECRET_404", "Secret not found", err.Error()))
+	case errors.Is(err, secrets.ErrNoMasterKey):
+		c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("SECRET_503", "Secrets unavailable",
+			"No master key configured (secrets.master_key_env or secrets.master_key_file)"))
+	default:
+		s.logger.Error("Secret operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SECRET_500", "Secret operation failed", err.Error()))
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
 	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
+	"github.com/KevinKickass/OpenMachineCore/internal/service"
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	wfengine "github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -18,11 +20,13 @@ import (
 
 type Server struct {
 	router      *gin.Engine
+	cfg         *config.Config
 	lm          interfaces.LifecycleManager
 	logger      *zap.Logger
 	server      *http.Server
 	wsHub       *websocket.Hub
 	authService *auth.AuthService // NEU
+	snapshots   *snapshotHistory  // Versions served by GET /snapshot
 }
 
 func NewServer(cfg *config.Config, lm interfaces.LifecycleManager, logger *zap.Logger, wsHub *websocket.Hub, authService *auth.AuthService) *Server {
@@ -30,29 +34,44 @@ func NewServer(cfg *config.Config, lm interfaces.LifecycleManager, logger *zap.L
 
 	s := &Server{
 		router:      gin.Default(),
+		cfg:         cfg,
 		lm:          lm,
 		logger:      logger,
 		wsHub:       wsHub,
 		authService: authService, // NEU
+		snapshots:   newSnapshotHistory(),
 	}
 
 	s.setupRoutes()
 
+	_, address := cfg.Server.HTTPListen()
 	s.server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.HTTPPort),
+		Addr:         address,
 		Handler:      s.router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: s.writeTimeout(),
 		IdleTimeout:  60 * time.Second,
 	}
 
 	return s
 }
 
+// Start listens on the configured address or unix socket and serves in the
+// background
 func (s *Server) Start() error {
-	s.logger.Info("Starting REST API server", zap.String("address", s.server.Addr))
+	network, address := s.cfg.Server.HTTPListen()
+	mode, err := s.cfg.Server.SocketFileMode()
+	if err != nil {
+		return err
+	}
+	lis, err := service.Listen(network, address, mode)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	s.logger.Info("Starting REST API server", zap.String("network", network), zap.String("address", address))
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.server.Serve(lis); err != nil && err != http.ErrServerClosed {
 			s.logger.Fatal("REST server failed", zap.Error(err))
 		}
 	}()
@@ -64,6 +83,34 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
+// routeLimits returns the body size and timeout middleware for a route group
+func (s *Server) routeLimits(group string) []gin.HandlerFunc {
+	limit := s.cfg.Server.RouteLimit(group)
+	return []gin.HandlerFunc{
+		BodyLimitMiddleware(limit.MaxBodyBytes),
+		TimeoutMiddleware(limit.Timeout),
+	}
+}
+
+// authenticated returns the authentication and quota middleware for a route group
+func (s *Server) authenticated() []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		s.authService.AuthMiddleware(),
+		QuotaMiddleware(s.lm.UsageTracker()),
+	}
+}
+
+// writeTimeout leaves room for the longest configured handler timeout
+func (s *Server) writeTimeout() time.Duration {
+	timeout := 15 * time.Second
+	for _, limit := range s.cfg.Server.RouteLimits {
+		if limit.Timeout+5*time.Second > timeout {
+			timeout = limit.Timeout + 5*time.Second
+		}
+	}
+	return timeout
+}
+
 func (s *Server) setupRoutes() {
 	// Middleware
 	s.router.Use(LoggerMiddleware(s.logger))
@@ -77,126 +124,421 @@ func (s *Server) setupRoutes() {
 
 	// Public routes (no auth required)
 	s.router.GET("/health", s.healthCheck)
+	s.router.GET("/.well-known/jwks.json", s.getJWKS)
+
+	// The API versions share the routes, see versioning.go
+	for _, version := range []string{APIVersion1, APIVersion2} {
+		api := s.router.Group("/api/" + version)
+		api.Use(APIVersionMiddleware(version))
+		api.Use(UsageMiddleware(s.lm.UsageTracker()))
+		s.registerRoutes(api)
+	}
+}
 
-	// API v1
-	v1 := s.router.Group("/api/v1")
+// registerRoutes registers the routes of an API version
+func (s *Server) registerRoutes(api *gin.RouterGroup) {
+	// ==================== AUTH ENDPOINTS (PUBLIC) ====================
+	authPublic := api.Group("/auth")
+	authPublic.Use(s.routeLimits("auth")...)
 	{
-		// ==================== AUTH ENDPOINTS (PUBLIC) ====================
-		authPublic := v1.Group("/auth")
-		{
-			authPublic.POST("/login", s.login)
-			authPublic.POST("/refresh", s.refreshToken)
-		}
+		authPublic.POST("/login", s.login)
+		authPublic.POST("/refresh", s.refreshToken)
+	}
 
-		// ==================== AUTH ENDPOINTS (AUTHENTICATED) ====================
-		authProtected := v1.Group("/auth")
-		authProtected.Use(s.authService.AuthMiddleware())
-		{
-			authProtected.POST("/logout", s.logout)
-			authProtected.GET("/me", s.getCurrentUser)
-		}
+	// ==================== FIRST-RUN SETUP ====================
+	// Creating the initial admin is public while no user exists, the other
+	// steps need that admin. All but the status are gone once complete.
+	setup := api.Group("/setup")
+	setup.Use(s.routeLimits("auth")...)
+	{
+		setup.GET("", s.getSetupStatus)
+		setup.POST("/admin", s.setupPending, s.createSetupAdmin)
+	}
 
-		// ==================== MACHINE TOKENS (ADMIN ONLY) ====================
-		machineTokens := v1.Group("/machine-tokens")
-		machineTokens.Use(s.authService.AuthMiddleware())
-		machineTokens.Use(auth.RequirePermission(auth.PermAdmin))
-		{
-			machineTokens.POST("", s.createMachineToken)
-			machineTokens.GET("", s.listMachineTokens)
-			machineTokens.PATCH("/:id", s.updateMachineToken)
-			machineTokens.DELETE("/:id", s.deleteMachineToken)
-		}
+	setupAdmin := api.Group("/setup")
+	setupAdmin.Use(s.routeLimits("auth")...)
+	setupAdmin.Use(s.authenticated()...)
+	setupAdmin.Use(auth.RequirePermission(auth.PermAdmin), s.setupPending)
+	{
+		setupAdmin.POST("/jwt-key", s.createSetupSigningKey)
+		setupAdmin.POST("/complete", s.completeSetup)
+	}
 
-		// ==================== USER MANAGEMENT (ADMIN ONLY) ====================
-		users := v1.Group("/users")
-		users.Use(s.authService.AuthMiddleware())
-		users.Use(auth.RequirePermission(auth.PermAdmin))
-		{
-			users.POST("", s.createUser)
-			users.GET("", s.listUsers)
-			users.PATCH("/:id", s.updateUser)
-			users.DELETE("/:id", s.deleteUser)
-		}
+	// ==================== AUTH ENDPOINTS (AUTHENTICATED) ====================
+	authProtected := api.Group("/auth")
+	authProtected.Use(s.routeLimits("auth")...)
+	authProtected.Use(s.authenticated()...)
+	{
+		authProtected.POST("/logout", s.logout)
+		authProtected.GET("/me", s.getCurrentUser)
+		authProtected.GET("/keys", auth.RequirePermission(auth.PermAdmin), s.listSigningKeys)
+		authProtected.POST("/keys/rotate", auth.RequirePermission(auth.PermAdmin), s.rotateSigningKey)
+	}
 
-		// ==================== SYSTEM (OPERATOR+) ====================
-		system := v1.Group("/system")
-		system.Use(s.authService.AuthMiddleware())
-		system.Use(auth.RequirePermission(auth.PermOperator))
-		{
-			system.GET("/status", s.getSystemStatus)
-			system.POST("/update", s.triggerUpdate) // Maybe restrict to Admin
-			system.POST("/shutdown", s.shutdown)    // Maybe restrict to Admin
-		}
+	// ==================== MACHINE TOKENS (ADMIN ONLY) ====================
+	machineTokens := api.Group("/machine-tokens")
+	machineTokens.Use(s.routeLimits("machine_tokens")...)
+	machineTokens.Use(s.authenticated()...)
+	machineTokens.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		machineTokens.POST("", s.createMachineToken)
+		machineTokens.GET("", s.listMachineTokens)
+		machineTokens.PATCH("/:id", s.updateMachineToken)
+		machineTokens.DELETE("/:id", s.deleteMachineToken)
+	}
 
-		// ==================== DEVICES ====================
-		devices := v1.Group("/devices")
-		devices.Use(s.authService.AuthMiddleware())
-		{
-			// Read operations: Operator+
-			devices.GET("", auth.RequirePermission(auth.PermOperator), s.listDevices)
-			devices.GET("/:id", auth.RequirePermission(auth.PermOperator), s.getDevice)
-			devices.POST("/:id/read", auth.RequirePermission(auth.PermOperator), s.readRegister)
-
-			// Write operations: Technician+
-			devices.POST("", auth.RequirePermission(auth.PermAdmin), s.createDevice)
-			devices.DELETE("/:id", auth.RequirePermission(auth.PermAdmin), s.deleteDevice)
-			devices.POST("/:id/write", auth.RequirePermission(auth.PermTechnician), s.writeRegister)
-		}
+	// ==================== USER MANAGEMENT (ADMIN ONLY) ====================
+	users := api.Group("/users")
+	users.Use(s.routeLimits("users")...)
+	users.Use(s.authenticated()...)
+	users.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		users.POST("", s.createUser)
+		users.GET("", s.listUsers)
+		users.PATCH("/:id", s.updateUser)
+		users.DELETE("/:id", s.deleteUser)
+	}
 
-		// ==================== WORKFLOWS ====================
-		workflows := v1.Group("/workflows")
-		workflows.Use(s.authService.AuthMiddleware())
-		{
-			// Read & Execute: Operator+
-			workflows.GET("", auth.RequirePermission(auth.PermOperator), s.listWorkflows)
-			workflows.GET("/:id", auth.RequirePermission(auth.PermOperator), s.getWorkflow)
-			workflows.POST("/:id/execute", auth.RequirePermission(auth.PermOperator), s.executeWorkflow)
-			workflows.POST("/:id/validate", auth.RequirePermission(auth.PermOperator), s.validateWorkflow)
-
-			// Modify: Admin only
-			workflows.POST("", auth.RequirePermission(auth.PermAdmin), s.createWorkflow)
-			workflows.PUT("/:id", auth.RequirePermission(auth.PermAdmin), s.updateWorkflow)
-			workflows.DELETE("/:id", auth.RequirePermission(auth.PermAdmin), s.deleteWorkflow)
-			workflows.POST("/:id/activate", auth.RequirePermission(auth.PermAdmin), s.activateWorkflow)
-		}
+	// ==================== SYSTEM (OPERATOR+) ====================
+	system := api.Group("/system")
+	system.Use(s.routeLimits("system")...)
+	system.Use(s.authenticated()...)
+	system.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		system.GET("/status", s.getSystemStatus)
+		system.POST("/update", s.triggerUpdate) // Maybe restrict to Admin
+		system.POST("/shutdown", s.shutdown)    // Maybe restrict to Admin
+	}
 
-		// ==================== EXECUTIONS (OPERATOR+) ====================
-		executions := v1.Group("/executions")
-		executions.Use(s.authService.AuthMiddleware())
-		executions.Use(auth.RequirePermission(auth.PermOperator))
-		{
-			executions.GET("/:id", s.getExecutionStatus)
-			executions.GET("/:id/steps", s.getExecutionSteps)
-			executions.POST("/:id/cancel", s.cancelExecution)
-		}
+	// ==================== DEVICES ====================
+	devices := api.Group("/devices")
+	devices.Use(s.routeLimits("devices")...)
+	devices.Use(s.authenticated()...)
+	{
+		// Read operations: Operator+
+		devices.GET("", auth.RequirePermission(auth.PermOperator), s.listDevices)
+		devices.GET("/:id", auth.RequirePermission(auth.PermOperator), s.getDevice)
+		devices.POST("/:id/read", auth.RequirePermission(auth.PermOperator), s.readRegister)
+		devices.GET("/:id/values", auth.RequirePermission(auth.PermOperator), s.getDeviceValues)
+		devices.GET("/:id/operations", auth.RequirePermission(auth.PermOperator), s.getDeviceOperations)
+		devices.GET("/:id/calibration", auth.RequirePermission(auth.PermOperator), s.listCalibrations)
+		devices.GET("/:id/calibration/history", auth.RequirePermission(auth.PermOperator), s.getCalibrationHistory)
+		devices.GET("/:id/snapshots", auth.RequirePermission(auth.PermOperator), s.listDeviceSnapshots)
+		devices.GET("/:id/compositions", auth.RequirePermission(auth.PermOperator), s.listCompositionVersions)
+		devices.GET("/:id/compositions/:version", auth.RequirePermission(auth.PermOperator), s.getCompositionVersion)
+		devices.GET("/:id/compositions/:version/diff", auth.RequirePermission(auth.PermOperator), s.diffCompositionVersions)
+
+		// Write operations: Technician+
+		devices.POST("", auth.RequirePermission(auth.PermAdmin), s.createDevice)
+		devices.POST("/from-template", auth.RequirePermission(auth.PermAdmin), s.createDeviceFromTemplate)
+		devices.DELETE("/:id", auth.RequirePermission(auth.PermAdmin), s.deleteDevice)
+		devices.POST("/:id/compositions/:version/rollback", auth.RequirePermission(auth.PermAdmin), s.rollbackComposition)
+		devices.PUT("/:id/trace", auth.RequirePermission(auth.PermAdmin), s.setDeviceTrace)
+		devices.POST("/:id/write", auth.RequirePermission(auth.PermTechnician), s.writeRegister)
+		devices.POST("/:id/ping", auth.RequirePermission(auth.PermTechnician), s.pingDevice)
+		devices.PUT("/:id/calibration/:register", auth.RequirePermission(auth.PermTechnician), s.setCalibration)
+		devices.DELETE("/:id/calibration/:register", auth.RequirePermission(auth.PermTechnician), s.clearCalibration)
+	}
 
-		// ==================== MODULES (OPERATOR+) ====================
-		modules := v1.Group("/modules")
-		modules.Use(s.authService.AuthMiddleware())
-		modules.Use(auth.RequirePermission(auth.PermOperator))
-		{
-			modules.GET("", s.listModules)
-			modules.GET("/:vendor", s.getVendorModules)
-			modules.GET("/:vendor/:model", s.getModule)
-		}
+	// ==================== WORKFLOWS ====================
+	workflows := api.Group("/workflows")
+	workflows.Use(s.routeLimits("workflows")...)
+	workflows.Use(s.authenticated()...)
+	{
+		// Read & Execute: Operator+
+		workflows.GET("", auth.RequirePermission(auth.PermOperator), s.listWorkflows)
+		workflows.POST("/validate-all", auth.RequirePermission(auth.PermOperator), s.validateAllWorkflows)
+		workflows.GET("/:id", auth.RequirePermission(auth.PermOperator), s.getWorkflow)
+		workflows.GET("/:id/statistics", auth.RequirePermission(auth.PermOperator), s.getWorkflowStatistics)
+		workflows.GET("/:id/docs", auth.RequirePermission(auth.PermOperator), s.getWorkflowDocs)
+		workflows.POST("/:id/execute", auth.RequirePermission(auth.PermOperator), s.executeWorkflow)
+		workflows.POST("/:id/preflight", auth.RequirePermission(auth.PermOperator), s.preflightWorkflow)
+		workflows.POST("/:id/estimate", auth.RequirePermission(auth.PermOperator), s.estimateWorkflow)
+		workflows.POST("/:id/validate", auth.RequirePermission(auth.PermOperator), s.validateWorkflow)
+
+		// Modify: Admin only
+		workflows.POST("", auth.RequirePermission(auth.PermAdmin), s.createWorkflow)
+		workflows.POST("/import-csv", auth.RequirePermission(auth.PermAdmin), s.importWorkflowCSV)
+		workflows.PUT("/:id", auth.RequirePermission(auth.PermAdmin), s.updateWorkflow)
+		workflows.DELETE("/:id", auth.RequirePermission(auth.PermAdmin), s.deleteWorkflow)
+		workflows.POST("/:id/activate", auth.RequirePermission(auth.PermAdmin), s.activateWorkflow)
+
+		// Access lists: read Operator+, change Admin
+		workflows.GET("/:id/acl", auth.RequirePermission(auth.PermOperator), s.getWorkflowACL)
+		workflows.PUT("/:id/acl", auth.RequirePermission(auth.PermAdmin), s.putWorkflowACL)
+		workflows.DELETE("/:id/acl", auth.RequirePermission(auth.PermAdmin), s.deleteWorkflowACL)
+	}
 
-		// ==================== MACHINE CONTROL (OPERATOR+) ====================
-		machine := v1.Group("/machine")
-		machine.Use(s.authService.AuthMiddleware())
-		machine.Use(auth.RequirePermission(auth.PermOperator))
-		{
-			machine.GET("/status", s.getMachineStatus)
-			machine.POST("/command", s.executeMachineCommand)
-			machine.POST("/configure", auth.RequirePermission(auth.PermAdmin), s.configureMachineWorkflows)
-		}
+	// ==================== STEP TEMPLATES ====================
+	stepTemplates := api.Group("/step-templates")
+	stepTemplates.Use(s.routeLimits("step_templates")...)
+	stepTemplates.Use(s.authenticated()...)
+	{
+		// Read: Operator+
+		stepTemplates.GET("", auth.RequirePermission(auth.PermOperator), s.listStepTemplates)
+		stepTemplates.GET("/:name", auth.RequirePermission(auth.PermOperator), s.getStepTemplate)
+		stepTemplates.GET("/:name/usage", auth.RequirePermission(auth.PermOperator), s.getStepTemplateUsage)
+
+		// Modify: Admin only
+		stepTemplates.POST("", auth.RequirePermission(auth.PermAdmin), s.createStepTemplate)
+		stepTemplates.PUT("/:name", auth.RequirePermission(auth.PermAdmin), s.updateStepTemplate)
+		stepTemplates.DELETE("/:name", auth.RequirePermission(auth.PermAdmin), s.deleteStepTemplate)
+	}
+
+	// ==================== COMPOSITION TEMPLATES ====================
+	compositionTemplates := api.Group("/composition-templates")
+	compositionTemplates.Use(s.routeLimits("composition_templates")...)
+	compositionTemplates.Use(s.authenticated()...)
+	{
+		// Read: Operator+
+		compositionTemplates.GET("", auth.RequirePermission(auth.PermOperator), s.listCompositionTemplates)
+		compositionTemplates.GET("/:name", auth.RequirePermission(auth.PermOperator), s.getCompositionTemplate)
+
+		// Modify: Admin only
+		compositionTemplates.POST("", auth.RequirePermission(auth.PermAdmin), s.createCompositionTemplate)
+		compositionTemplates.PUT("/:name", auth.RequirePermission(auth.PermAdmin), s.updateCompositionTemplate)
+		compositionTemplates.DELETE("/:name", auth.RequirePermission(auth.PermAdmin), s.deleteCompositionTemplate)
+	}
+
+	// ==================== USAGE (OPERATOR+) ====================
+	usageGroup := api.Group("/usage")
+	usageGroup.Use(s.routeLimits("usage")...)
+	usageGroup.Use(s.authenticated()...)
+	usageGroup.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		usageGroup.GET("", s.getUsage)
+	}
 
-		// ==================== WEBSOCKET (PUBLIC - Auth via first message) ====================
-		ws := v1.Group("/ws")
+	// ==================== ALARMS ====================
+	alarmsGroup := api.Group("/alarms")
+	alarmsGroup.Use(s.routeLimits("alarms")...)
+	alarmsGroup.Use(s.authenticated()...)
+	{
+		// Alarm list and acknowledge/reset flow: Operator+
+		alarmsGroup.GET("", auth.RequirePermission(auth.PermOperator), s.listActiveAlarms)
+		alarmsGroup.GET("/history", auth.RequirePermission(auth.PermOperator), s.getAlarmHistory)
+		alarmsGroup.POST("/:id/acknowledge", auth.RequirePermission(auth.PermOperator), s.acknowledgeAlarm)
+		alarmsGroup.POST("/:id/reset", auth.RequirePermission(auth.PermOperator), s.resetAlarm)
+		alarmsGroup.GET("/definitions", auth.RequirePermission(auth.PermOperator), s.listAlarmDefinitions)
+
+		// Definitions: Admin only
+		alarmsGroup.POST("/definitions", auth.RequirePermission(auth.PermAdmin), s.createAlarmDefinition)
+		alarmsGroup.PUT("/definitions/:id", auth.RequirePermission(auth.PermAdmin), s.updateAlarmDefinition)
+		alarmsGroup.DELETE("/definitions/:id", auth.RequirePermission(auth.PermAdmin), s.deleteAlarmDefinition)
+	}
+
+	// ==================== COUNTERS (OPERATOR+) ====================
+	countersGroup := api.Group("/counters")
+	countersGroup.Use(s.routeLimits("counters")...)
+	countersGroup.Use(s.authenticated()...)
+	countersGroup.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		countersGroup.GET("", s.listCounters)
+		countersGroup.GET("/:name", s.getCounter)
+		countersGroup.GET("/:name/history", s.getCounterHistory)
+	}
+
+	// ==================== SHIFTS ====================
+	shiftsGroup := api.Group("/shifts")
+	shiftsGroup.Use(s.routeLimits("shifts")...)
+	shiftsGroup.Use(s.authenticated()...)
+	{
+		// Read: Operator+
+		shiftsGroup.GET("/status", auth.RequirePermission(auth.PermOperator), s.getShiftStatus)
+		shiftsGroup.GET("/calendar", auth.RequirePermission(auth.PermOperator), s.getShiftCalendar)
+		shiftsGroup.GET("/schedule", auth.RequirePermission(auth.PermOperator), s.getShiftSchedule)
+
+		// Handover notes: Operator+
+		shiftsGroup.GET("/notes", auth.RequirePermission(auth.PermOperator), s.listShiftNotes)
+		shiftsGroup.POST("/notes", auth.RequirePermission(auth.PermOperator), s.createShiftNote)
+		shiftsGroup.POST("/notes/:id/acknowledge", auth.RequirePermission(auth.PermOperator), s.acknowledgeShiftNote)
+
+		// Suspend/resume automation: Technician+
+		shiftsGroup.POST("/override", auth.RequirePermission(auth.PermTechnician), s.suspendShiftAutomation)
+		shiftsGroup.DELETE("/override", auth.RequirePermission(auth.PermTechnician), s.resumeShiftAutomation)
+
+		// Calendar: Admin only
+		shiftsGroup.PUT("/patterns", auth.RequirePermission(auth.PermAdmin), s.replaceShiftPatterns)
+		shiftsGroup.POST("/exceptions", auth.RequirePermission(auth.PermAdmin), s.createShiftException)
+		shiftsGroup.DELETE("/exceptions/:id", auth.RequirePermission(auth.PermAdmin), s.deleteShiftException)
+	}
+
+	// ==================== AUDIT (ADMIN ONLY) ====================
+	auditGroup := api.Group("/audit")
+	auditGroup.Use(s.routeLimits("audit")...)
+	auditGroup.Use(s.authenticated()...)
+	auditGroup.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		auditGroup.GET("", s.listAuditLog)
+	}
+
+	// ==================== SECRETS (ADMIN) ====================
+	secretsGroup := api.Group("/secrets")
+	secretsGroup.Use(s.routeLimits("secrets")...)
+	secretsGroup.Use(s.authenticated()...)
+	secretsGroup.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		secretsGroup.GET("", s.listSecrets)
+		secretsGroup.GET("/:name", s.getSecret)
+		secretsGroup.PUT("/:name", s.saveSecret)
+		secretsGroup.DELETE("/:name", s.deleteSecret)
+	}
+
+	// ==================== FEATURE FLAGS ====================
+	featuresGroup := api.Group("/features")
+	featuresGroup.Use(s.routeLimits("features")...)
+	featuresGroup.Use(s.authenticated()...)
+	{
+		// Read: Operator+
+		featuresGroup.GET("", auth.RequirePermission(auth.PermOperator), s.listFeatures)
+		featuresGroup.GET("/:name", auth.RequirePermission(auth.PermOperator), s.getFeature)
+
+		// Modify: Admin only
+		featuresGroup.PUT("/:name", auth.RequirePermission(auth.PermAdmin), s.setFeature)
+		featuresGroup.DELETE("/:name", auth.RequirePermission(auth.PermAdmin), s.resetFeature)
+	}
+
+	// ==================== EVENT OUTBOX (ADMIN) ====================
+	outboxGroup := api.Group("/outbox")
+	outboxGroup.Use(s.routeLimits("outbox")...)
+	outboxGroup.Use(s.authenticated()...)
+	outboxGroup.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		outboxGroup.GET("", s.getOutboxStatus)
+		outboxGroup.GET("/deliveries", s.listOutboxDeliveries)
+		outboxGroup.POST("/deliveries/:id/requeue", s.requeueOutboxDelivery)
+		outboxGroup.POST("/sinks/:sink/requeue", s.requeueOutboxSink)
+	}
+
+	// ==================== EVENT ROUTING (ADMIN) ====================
+	routingGroup := api.Group("/routing")
+	routingGroup.Use(s.routeLimits("routing")...)
+	routingGroup.Use(s.authenticated()...)
+	routingGroup.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		routingGroup.GET("", s.getRoutingStatus)
+	}
+
+	// ==================== FAULT INJECTION (ADMIN, DEVELOPER MODE ONLY) ====================
+	if s.lm.FaultInjector() != nil {
+		faultsGroup := api.Group("/faults")
+		faultsGroup.Use(s.routeLimits("faults")...)
+		faultsGroup.Use(s.authenticated()...)
+		faultsGroup.Use(auth.RequirePermission(auth.PermAdmin))
 		{
-			ws.GET("/live", s.wsLiveConnection)
-			ws.GET("/status", auth.RequirePermission(auth.PermOperator), s.wsStatus)
+			faultsGroup.GET("", s.listFaults)
+			faultsGroup.POST("", s.injectFault)
+			faultsGroup.DELETE("", s.clearFaults)
+			faultsGroup.DELETE("/:id", s.removeFault)
 		}
 	}
+
+	// ==================== EXECUTIONS (OPERATOR+) ====================
+	executions := api.Group("/executions")
+	executions.Use(s.routeLimits("executions")...)
+	executions.Use(s.authenticated()...)
+	executions.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		executions.GET("", s.listExecutions)
+		executions.GET("/compare", s.compareExecutions)
+		executions.POST("/search", s.searchExecutions)
+		executions.GET("/:id", s.getExecutionStatus)
+		executions.GET("/:id/steps", s.getExecutionSteps)
+		executions.GET("/:id/logs", s.getExecutionLogs)
+		executions.GET("/:id/events", s.getExecutionEvents)
+		executions.PATCH("/:id/tags", s.annotateExecution)
+		executions.PUT("/:id/trace", auth.RequirePermission(auth.PermAdmin), s.setExecutionTrace)
+		executions.POST("/:id/cancel", s.cancelExecution)
+		executions.POST("/:id/retry", s.retryExecution)
+	}
+
+	// Long polls outlast the timeout of the other execution routes
+	executionWait := api.Group("/executions")
+	executionWait.Use(s.routeLimits("execution_wait")...)
+	executionWait.Use(s.authenticated()...)
+	executionWait.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		executionWait.GET("/:id/wait", s.waitExecution)
+	}
+
+	// ==================== RESOURCES (OPERATOR+) ====================
+	resources := api.Group("/resources")
+	resources.Use(s.routeLimits("resources")...)
+	resources.Use(s.authenticated()...)
+	resources.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		resources.GET("", s.listResources)
+	}
+
+	// ==================== MODULES (OPERATOR+) ====================
+	modules := api.Group("/modules")
+	modules.Use(s.routeLimits("modules")...)
+	modules.Use(s.authenticated()...)
+	modules.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		modules.GET("", s.listModules)
+		modules.GET("/errors", s.getModuleErrors)
+		modules.GET("/updates", s.getModuleUpdates)
+		modules.POST("/updates/check", auth.RequirePermission(auth.PermAdmin), s.checkModuleUpdates)
+		modules.POST("/updates/:vendor/install", auth.RequirePermission(auth.PermAdmin), s.installModulePack)
+		modules.GET("/:vendor", s.getVendorModules)
+		modules.GET("/:vendor/:model", s.getModule)
+	}
+
+	// ==================== HMI SNAPSHOT (OPERATOR+) ====================
+	snapshot := api.Group("/snapshot")
+	snapshot.Use(s.routeLimits("snapshot")...)
+	snapshot.Use(s.authenticated()...)
+	snapshot.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		snapshot.GET("", s.getSnapshot)
+	}
+
+	// ==================== MANUAL CONTROL (TECHNICIAN+) ====================
+	manualSessions := api.Group("/manual-sessions")
+	manualSessions.Use(s.routeLimits("manual_sessions")...)
+	manualSessions.Use(s.authenticated()...)
+	manualSessions.Use(auth.RequirePermission(auth.PermTechnician))
+	{
+		manualSessions.GET("", s.listManualSessions)
+		manualSessions.POST("", s.openManualSession)
+		manualSessions.POST("/:id/heartbeat", s.manualSessionHeartbeat)
+		manualSessions.DELETE("/:id", s.releaseManualSession)
+	}
+
+	// ==================== DEVICE ONBOARDING (ADMIN) ====================
+	onboardings := api.Group("/onboarding")
+	onboardings.Use(s.routeLimits("onboarding")...)
+	onboardings.Use(s.authenticated()...)
+	onboardings.Use(auth.RequirePermission(auth.PermAdmin))
+	{
+		onboardings.GET("", s.listOnboardings)
+		onboardings.POST("", s.openOnboarding)
+		onboardings.GET("/:id", s.getOnboarding)
+		onboardings.POST("/:id/connect", s.connectOnboarding)
+		onboardings.POST("/:id/verify", s.verifyOnboarding)
+		onboardings.POST("/:id/checks/:index", s.answerOnboardingCheck)
+		onboardings.POST("/:id/confirm", s.confirmOnboarding)
+		onboardings.DELETE("/:id", s.cancelOnboarding)
+	}
+
+	// ==================== MACHINE CONTROL (OPERATOR+) ====================
+	machine := api.Group("/machine")
+	machine.Use(s.routeLimits("machine")...)
+	machine.Use(s.authenticated()...)
+	machine.Use(auth.RequirePermission(auth.PermOperator))
+	{
+		machine.GET("/status", s.getMachineStatus)
+		machine.POST("/command", s.executeMachineCommand)
+		machine.POST("/configure", auth.RequirePermission(auth.PermAdmin), s.configureMachineWorkflows)
+	}
+
+	// ==================== WEBSOCKET (PUBLIC - Auth via first message) ====================
+	ws := api.Group("/ws")
+	{
+		ws.GET("/live", s.wsLiveConnection)
+		ws.GET("/status", auth.RequirePermission(auth.PermOperator), s.wsStatus)
+	}
 }
 
 // WebSocket handlers
@@ -236,10 +578,35 @@ func (s *Server) cancelExecution(c *gin.Context) {
 		return
 	}
 
-	if err := engine.CancelExecution(c.Request.Context(), execUUID); err != nil {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// Body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	reason := wfengine.CancelReason{
+		Source: wfengine.CancelSourceOperator,
+		Reason: req.Reason,
+		Actor:  requestActor(c),
+	}
+	if err := engine.CancelExecution(c.Request.Context(), execUUID, reason); err != nil {
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to cancel execution", err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "execution cancelled"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "execution cancelled",
+		"cancel":  reason,
+	})
+}
+
+// requestActor identifies the authenticated caller for audit fields: the
+// username, or token:<name> for machine tokens
+func requestActor(c *gin.Context) string {
+	return auth.RequestPrincipal(c).Actor()
 }
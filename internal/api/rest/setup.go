@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SetupAdminRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// setupPending rejects requests with 410 once setup is complete
+func (s *Server) setupPending(c *gin.Context) {
+	state, err := s.lm.Storage().GetSetupState(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get setup state", zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, types.NewErrorResponse("SETUP_500", "Failed to get setup state", err.Error()))
+		return
+	}
+	if state != nil {
+		c.AbortWithStatusJSON(http.StatusGone, types.NewErrorResponse("SETUP_410", "Setup already completed", gin.H{
+			"completed_at": state.CompletedAt,
+		}))
+		return
+	}
+	c.Next()
+}
+
+// GET /api/v1/setup
+func (s *Server) getSetupStatus(c *gin.Context) {
+	status, err := s.authService.SetupStatus(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to get setup status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SETUP_500", "Failed to get setup status", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"setup":               status,
+		"min_password_length": auth.MinPasswordLength,
+	})
+}
+
+// POST /api/v1/setup/admin
+// Unauthenticated, only while no user exists
+func (s *Server) createSetupAdmin(c *gin.Context) {
+	var req SetupAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SETUP_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	actor := "setup@" + c.ClientIP()
+	user, err := s.authService.CreateInitialAdmin(c.Request.Context(), req.Username, req.Password, actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SETUP_400", "Password too weak", err.Error()))
+		case errors.Is(err, storage.ErrUsersExist):
+			c.JSON(http.StatusConflict, types.NewErrorResponse("SETUP_409", "Initial admin already exists", "Log in as admin to continue setup"))
+		case errors.Is(err, storage.ErrSetupCompleted):
+			c.JSON(http.StatusGone, types.NewErrorResponse("SETUP_410", "Setup already completed", nil))
+		default:
+			s.logger.Error("Failed to create initial admin", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SETUP_500", "Failed to create initial admin", err.Error()))
+		}
+		return
+	}
+
+	s.logger.Info("Initial admin created",
+		zap.String("username", user.Username),
+		zap.String("client_ip", c.ClientIP()))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Initial admin created, log in to continue setup",
+		"user":    user,
+	})
+}
+
+// POST /api/v1/setup/jwt-key
+// Signs tokens with a generated key stored encrypted with the master key
+// instead of the secret from auth.jwt_secret_env
+func (s *Server) createSetupSigningKey(c *gin.Context) {
+	key, err := s.authService.RotateSigningKey(c.Request.Context(), requestActor(c))
+	if err != nil {
+		if errors.Is(err, auth.ErrKeyFiles) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("SETUP_409", "Signing keys are configured as files", err.Error()))
+			return
+		}
+		if errors.Is(err, secrets.ErrNoMasterKey) {
+			c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("SETUP_503", "Cannot store a signing key",
+				"No master key configured (secrets.master_key_env or secrets.master_key_file)"))
+			return
+		}
+		s.logger.Error("Failed to store signing key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SETUP_500", "Failed to store signing key", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signing key stored, log in again after the grace period",
+		"key":     key,
+	})
+}
+
+// POST /api/v1/setup/complete
+// Disables the setup endpoints
+func (s *Server) completeSetup(c *gin.Context) {
+	state, err := s.authService.CompleteSetup(c.Request.Context(), requestActor(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrNoAdmin), errors.Is(err, auth.ErrWeakSigningKey):
+			c.JSON(http.StatusConflict, types.NewErrorResponse("SETUP_409", "Setup cannot be completed", err.Error()))
+		case errors.Is(err, storage.ErrSetupCompleted):
+			c.JSON(http.StatusGone, types.NewErrorResponse("SETUP_410", "Setup already completed", nil))
+		default:
+			s.logger.Error("Failed to complete setup", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SETUP_500", "Failed to complete setup", err.Error()))
+		}
+		return
+	}
+
+	s.logger.Info("Setup completed", zap.String("actor", state.CompletedBy))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Setup completed",
+		"state":   state,
+	})
+}
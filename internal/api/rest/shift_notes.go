@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultShiftNoteLimit bounds note lists without ?limit
+const defaultShiftNoteLimit = 100
+
+type shiftNoteRequest struct {
+	Text         string      `json:"text" binding:"required"`
+	ExecutionIDs []uuid.UUID `json:"execution_ids"`
+	Devices      []string    `json:"devices"`
+	AlarmIDs     []uuid.UUID `json:"alarm_ids"`
+}
+
+// POST /api/v1/shifts/notes
+// The note is tagged with the shift running now.
+func (s *Server) createShiftNote(c *gin.Context) {
+	var req shiftNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid request body", err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid shift note", "text must not be empty"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	note := &storage.ShiftNote{
+		Text:         req.Text,
+		ExecutionIDs: req.ExecutionIDs,
+		Devices:      req.Devices,
+		AlarmIDs:     req.AlarmIDs,
+		CreatedBy:    requestActor(c),
+	}
+	if shift, err := s.lm.ShiftScheduler().CurrentShift(ctx); err != nil {
+		s.logger.Warn("Failed to determine current shift for note", zap.Error(err))
+	} else if shift != nil {
+		note.Shift = shift.Name
+	}
+
+	if err := s.lm.Storage().CreateShiftNote(ctx, note); err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// GET /api/v1/shifts/notes?from=&to=&unacknowledged=true&limit=100
+// Returns the notes newest first.
+func (s *Server) listShiftNotes(c *gin.Context) {
+	filter := storage.ShiftNoteFilter{Limit: defaultShiftNoteLimit}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid from", err.Error()))
+			return
+		}
+		filter.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid to", err.Error()))
+			return
+		}
+		filter.To = &to
+	}
+	if v := c.Query("unacknowledged"); v != "" {
+		unacknowledged, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid unacknowledged", v))
+			return
+		}
+		filter.Unacknowledged = unacknowledged
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid limit", v))
+			return
+		}
+		filter.Limit = n
+	}
+
+	notes, err := s.lm.Storage().ListShiftNotes(c.Request.Context(), filter)
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes": notes,
+		"count": len(notes),
+	})
+}
+
+// POST /api/v1/shifts/notes/:id/acknowledge
+func (s *Server) acknowledgeShiftNote(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid note ID", err.Error()))
+		return
+	}
+
+	note, err := s.lm.Storage().AcknowledgeShiftNote(c.Request.Context(), id, requestActor(c))
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
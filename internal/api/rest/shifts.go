@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/shifts"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultScheduleDays is the schedule range without ?days
+	defaultScheduleDays = 7
+	maxScheduleDays     = 62
+
+	// defaultAuditLimit bounds audit responses without ?limit
+	defaultAuditLimit = 100
+)
+
+type shiftPatternsRequest struct {
+	Patterns []storage.ShiftPattern `json:"patterns"`
+}
+
+type shiftOverrideRequest struct {
+	Until  time.Time `json:"until" binding:"required"`
+	Reason string    `json:"reason"`
+}
+
+// GET /api/v1/shifts/status
+func (s *Server) getShiftStatus(c *gin.Context) {
+	status, err := s.lm.ShiftScheduler().Status(c.Request.Context())
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GET /api/v1/shifts/calendar
+// Returns the weekly pattern and the upcoming exceptions.
+func (s *Server) getShiftCalendar(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	patterns, err := s.lm.Storage().ListShiftPatterns(ctx)
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	today := time.Now().In(s.lm.ShiftScheduler().Location())
+	exceptions, err := s.lm.Storage().ListShiftExceptions(ctx, today, today.AddDate(1, 0, 0))
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone":   s.lm.ShiftScheduler().Location().String(),
+		"patterns":   patterns,
+		"exceptions": exceptions,
+	})
+}
+
+// GET /api/v1/shifts/schedule?days=7
+// Returns the concrete shifts with exceptions applied.
+func (s *Server) getShiftSchedule(c *gin.Context) {
+	days := defaultScheduleDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxScheduleDays {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid days", v))
+			return
+		}
+		days = n
+	}
+
+	now := time.Now()
+	cal, err := s.lm.ShiftScheduler().Calendar(c.Request.Context(), now, now.AddDate(0, 0, days))
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+	schedule := cal.Upcoming(now, days)
+
+	c.JSON(http.StatusOK, gin.H{
+		"shifts": schedule,
+		"count":  len(schedule),
+	})
+}
+
+// PUT /api/v1/shifts/patterns
+// Replaces the whole weekly pattern.
+func (s *Server) replaceShiftPatterns(c *gin.Context) {
+	var req shiftPatternsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid request body", err.Error()))
+		return
+	}
+	for i := range req.Patterns {
+		if err := shifts.ValidatePattern(&req.Patterns[i]); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid shift pattern", err.Error()))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := s.lm.Storage().ReplaceShiftPatterns(ctx, req.Patterns); err != nil {
+		s.shiftError(c, err)
+		return
+	}
+	s.lm.ShiftScheduler().Audit(ctx, shifts.AuditCalendarChange, requestActor(c), map[string]any{
+		"change":   "patterns_replaced",
+		"patterns": len(req.Patterns),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"patterns": req.Patterns,
+		"count":    len(req.Patterns),
+	})
+}
+
+// POST /api/v1/shifts/exceptions
+// Without start and end the date is a non-production day.
+func (s *Server) createShiftException(c *gin.Context) {
+	var se storage.ShiftException
+	if err := c.ShouldBindJSON(&se); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid request body", err.Error()))
+		return
+	}
+	if err := shifts.ValidateException(&se); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid shift exception", err.Error()))
+		return
+	}
+	se.CreatedBy = requestActor(c)
+
+	ctx := c.Request.Context()
+	if err := s.lm.Storage().CreateShiftException(ctx, &se); err != nil {
+		s.shiftError(c, err)
+		return
+	}
+	s.lm.ShiftScheduler().Audit(ctx, shifts.AuditCalendarChange, se.CreatedBy, map[string]any{
+		"change": "exception_created",
+		"id":     se.ID,
+		"date":   se.Date,
+	})
+
+	c.JSON(http.StatusCreated, se)
+}
+
+// DELETE /api/v1/shifts/exceptions/:id
+func (s *Server) deleteShiftException(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid exception ID", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.lm.Storage().DeleteShiftException(ctx, id); err != nil {
+		s.shiftError(c, err)
+		return
+	}
+	s.lm.ShiftScheduler().Audit(ctx, shifts.AuditCalendarChange, requestActor(c), map[string]any{
+		"change": "exception_deleted",
+		"id":     id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shift exception deleted successfully",
+	})
+}
+
+// POST /api/v1/shifts/override
+// Suspends automatic shift actions until the given time.
+func (s *Server) suspendShiftAutomation(c *gin.Context) {
+	var req shiftOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid request body", err.Error()))
+		return
+	}
+	if !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("SHIFT_400", "Invalid override", "until must be in the future"))
+		return
+	}
+
+	override, err := s.lm.ShiftScheduler().Suspend(c.Request.Context(), req.Until, req.Reason, requestActor(c))
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+// DELETE /api/v1/shifts/override
+func (s *Server) resumeShiftAutomation(c *gin.Context) {
+	cleared, err := s.lm.ShiftScheduler().Resume(c.Request.Context(), requestActor(c))
+	if err != nil {
+		s.shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shift automation resumed",
+		"cleared": cleared,
+	})
+}
+
+// GET /api/v1/audit?action=shift.*&limit=100
+func (s *Server) listAuditLog(c *gin.Context) {
+	limit := defaultAuditLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("AUDIT_400", "Invalid limit", v))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := s.lm.Storage().ListAudit(c.Request.Context(), c.Query("action"), limit)
+	if err != nil {
+		s.logger.Error("Failed to load audit log", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("AUDIT_500", "Failed to load audit log", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// shiftError maps shift calendar errors to responses
+func (s *Server) shiftError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrShiftExceptionNotFound) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("SHIFT_404", "Shift exception not found", err.Error()))
+		return
+	}
+	if errors.Is(err, storage.ErrShiftNoteNotFound) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("SHIFT_404", "Shift note not found", err.Error()))
+		return
+	}
+	s.logger.Error("Shift operation failed", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SHIFT_500", "Shift operation failed", err.Error()))
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultSnapshotLimit bounds snapshot responses without ?limit
+const defaultSnapshotLimit = 100
+
+// GET /api/v1/devices/:id/snapshots?from=...&to=...&limit=100
+// Returns stored snapshots newest first. Defaults to the last hour.
+func (s *Server) listDeviceSnapshots(c *gin.Context) {
+	device, ok := s.deviceParam(c)
+	if !ok {
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-time.Hour)
+	limit := defaultSnapshotLimit
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SNAPSHOT_400", "Invalid from", err.Error()))
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SNAPSHOT_400", "Invalid to", err.Error()))
+			return
+		}
+		to = t
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("SNAPSHOT_400", "Invalid limit", v))
+			return
+		}
+		limit = n
+	}
+
+	snapshots, err := s.lm.Storage().ListDeviceSnapshots(c.Request.Context(), device.Name, from, to, limit)
+	if err != nil {
+		s.logger.Error("Failed to load device snapshots", zap.String("device", device.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("SNAPSHOT_500", "Failed to load device snapshots", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"device":    device.Name,
+		"from":      from,
+		"to":        to,
+		"snapshots": snapshots,
+		"count":     len(snapshots),
+	})
+}
@@ -7,8 +7,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GET /api/v1/system/status
+// GET /api/v1/system/status?detailed=true
+// The detailed status adds build, uptime, runtime, listener and database
+// information for support.
 func (s *Server) getSystemStatus(c *gin.Context) {
+	if c.Query("detailed") == "true" {
+		c.JSON(http.StatusOK, s.lm.GetCurrentStatusDetailed(c.Request.Context()))
+		return
+	}
 	status := s.lm.GetCurrentStatus()
 	c.JSON(http.StatusOK, status)
 }
@@ -0,0 +1,235 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type stepTemplateRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Arguments   []definition.InputParam `json:"arguments"`
+	Step        definition.Step         `json:"step"`
+}
+
+// GET /api/v1/step-templates
+func (s *Server) listStepTemplates(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	templates, err := s.lm.Storage().ListStepTemplates(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list step templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("TEMPLATE_500", "Failed to list step templates", err.Error()))
+		return
+	}
+
+	views := make([]gin.H, 0, len(templates))
+	for i := range templates {
+		views = append(views, stepTemplateView(&templates[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": views,
+		"count":     len(views),
+	})
+}
+
+// GET /api/v1/step-templates/:name
+func (s *Server) getStepTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	tmpl, err := s.lm.Storage().LoadStepTemplate(ctx, name)
+	if err != nil {
+		s.stepTemplateError(c, name, "Failed to load step template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stepTemplateView(tmpl))
+}
+
+// GET /api/v1/step-templates/:name/usage
+func (s *Server) getStepTemplateUsage(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	if _, err := s.lm.Storage().LoadStepTemplate(ctx, name); err != nil {
+		s.stepTemplateError(c, name, "Failed to load step template", err)
+		return
+	}
+
+	refs, err := s.lm.Storage().StepTemplateReferences(ctx, name)
+	if err != nil {
+		s.logger.Error("Failed to query step template usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("TEMPLATE_500", "Failed to query step template usage", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template":  name,
+		"workflows": refs,
+		"count":     len(refs),
+	})
+}
+
+// POST /api/v1/step-templates
+func (s *Server) createStepTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req stepTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("TEMPLATE_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	tmpl, ok := s.buildStepTemplate(c, req)
+	if !ok {
+		return
+	}
+
+	if err := s.lm.Storage().SaveStepTemplate(ctx, tmpl); err != nil {
+		if errors.Is(err, storage.ErrStepTemplateExists) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("TEMPLATE_409", "Step template already exists", tmpl.Name))
+			return
+		}
+		s.logger.Error("Failed to create step template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("TEMPLATE_500", "Failed to create step template", err.Error()))
+		return
+	}
+
+	s.logger.Info("Step template created", zap.String("template", tmpl.Name))
+
+	c.JSON(http.StatusCreated, stepTemplateView(tmpl))
+}
+
+// PUT /api/v1/step-templates/:name
+func (s *Server) updateStepTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	var req stepTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("TEMPLATE_400", "Invalid request body", err.Error()))
+		return
+	}
+	if req.Name != "" && req.Name != name {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("TEMPLATE_400", "Step templates cannot be renamed", gin.H{"name": name, "requested": req.Name}))
+		return
+	}
+	req.Name = name
+
+	tmpl, ok := s.buildStepTemplate(c, req)
+	if !ok {
+		return
+	}
+
+	// Workflows expand templates when they run, so the change reaches them
+	// all and the caller must be allowed to modify each
+	refs, err := s.lm.Storage().StepTemplateReferences(ctx, name)
+	if err != nil {
+		s.logger.Error("Failed to query step template usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("TEMPLATE_500", "Failed to query step template usage", err.Error()))
+		return
+	}
+	if !s.authorizeTemplateUsers(c, refs) {
+		return
+	}
+
+	if err := s.lm.Storage().UpdateStepTemplate(ctx, tmpl); err != nil {
+		s.stepTemplateError(c, name, "Failed to update step template", err)
+		return
+	}
+
+	s.logger.Info("Step template updated",
+		zap.String("template", name),
+		zap.Int("version", tmpl.Version),
+		zap.Int("affected_workflows", len(refs)))
+
+	view := stepTemplateView(tmpl)
+	view["affected_workflows"] = refs
+	c.JSON(http.StatusOK, view)
+}
+
+// DELETE /api/v1/step-templates/:name
+func (s *Server) deleteStepTemplate(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+
+	if err := s.lm.Storage().DeleteStepTemplate(ctx, name); err != nil {
+		var refErr *storage.ReferenceError
+		if errors.As(err, &refErr) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("TEMPLATE_409", "Step template is still used", refErr))
+			return
+		}
+		s.stepTemplateError(c, name, "Failed to delete step template", err)
+		return
+	}
+
+	s.logger.Info("Step template deleted", zap.String("template", name))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Step template deleted successfully",
+	})
+}
+
+// buildStepTemplate validates a request and encodes it for storage
+func (s *Server) buildStepTemplate(c *gin.Context, req stepTemplateRequest) (*storage.StepTemplate, bool) {
+	def := definition.StepTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Arguments:   req.Arguments,
+		Step:        req.Step,
+	}
+	if err := def.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("TEMPLATE_400", "Invalid step template", err.Error()))
+		return nil, false
+	}
+	if !s.checkParameterSizes(c, &definition.Workflow{Steps: []definition.Step{def.Step}}) {
+		return nil, false
+	}
+
+	data, err := json.Marshal(def)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("TEMPLATE_400", "Invalid step template", err.Error()))
+		return nil, false
+	}
+
+	return &storage.StepTemplate{
+		Name:        def.Name,
+		Description: def.Description,
+		Definition:  data,
+	}, true
+}
+
+func (s *Server) stepTemplateError(c *gin.Context, name, message string, err error) {
+	if errors.Is(err, storage.ErrStepTemplateNotFound) {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("TEMPLATE_404", "Step template not found", name))
+		return
+	}
+	s.logger.Error(message, zap.String("template", name), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, types.NewErrorResponse("TEMPLATE_500", message, err.Error()))
+}
+
+func stepTemplateView(tmpl *storage.StepTemplate) gin.H {
+	view := gin.H{
+		"id":          tmpl.ID,
+		"name":        tmpl.Name,
+		"description": tmpl.Description,
+		"version":     tmpl.Version,
+		"created_at":  tmpl.CreatedAt,
+		"updated_at":  tmpl.UpdatedAt,
+	}
+	if def, err := definition.ParseStepTemplate(tmpl.Definition); err == nil {
+		view["arguments"] = def.Arguments
+		view["step"] = def.Step
+	}
+	return view
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	wfengine "github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type traceRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// PUT /api/v1/devices/:id/trace
+// Switches tracing the raw Modbus frames of workflow steps on the device into
+// the execution logs. Not persisted, devices start untraced.
+func (s *Server) setDeviceTrace(c *gin.Context) {
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid device ID", err.Error()))
+		return
+	}
+
+	var req traceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("DEVICE_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	device, exists := s.lm.DeviceManager().GetDevice(deviceID)
+	if !exists {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("DEVICE_404", "Device not found", deviceID.String()))
+		return
+	}
+
+	device.SetTrace(*req.Enabled)
+	s.logger.Info("Device frame trace switched",
+		zap.String("device", device.Name),
+		zap.Bool("enabled", *req.Enabled),
+		zap.String("actor", requestActor(c)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"device_id": device.ID,
+		"trace":     device.Trace(),
+	})
+}
+
+// PUT /api/v1/executions/:id/trace
+// Switches tracing the raw Modbus frames of all device steps of a running
+// execution into its log
+func (s *Server) setExecutionTrace(c *gin.Context) {
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	var req traceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	err = s.lm.WorkflowEngine().SetExecutionTrace(executionID, *req.Enabled)
+	switch {
+	case errors.Is(err, wfengine.ErrNotRunning):
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("EXEC_404", "Execution not found or not running", executionID.String()))
+		return
+	case errors.Is(err, wfengine.ErrLogsDisabled):
+		c.JSON(http.StatusConflict, types.NewErrorResponse("EXEC_409", "Execution logs are disabled", "enable execution_logs to trace frames"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to switch frame trace", err.Error()))
+		return
+	}
+
+	s.logger.Info("Execution frame trace switched",
+		zap.String("execution_id", executionID.String()),
+		zap.Bool("enabled", *req.Enabled),
+		zap.String("actor", requestActor(c)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution_id": executionID,
+		"trace":        *req.Enabled,
+	})
+}
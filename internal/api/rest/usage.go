@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GET /api/v1/usage
+// Admins see every principal; others only their own usage.
+func (s *Server) getUsage(c *gin.Context) {
+	ctx := c.Request.Context()
+	tracker := s.lm.UsageTracker()
+
+	if !tracker.Enabled() {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("USAGE_404", "Usage tracking is disabled", nil))
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("USAGE_400", "Invalid from", err.Error()))
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("USAGE_400", "Invalid to", err.Error()))
+			return
+		}
+	}
+
+	filter := storage.UsageFilter{
+		From:          from,
+		To:            to,
+		PrincipalType: c.Query("principal_type"),
+		PrincipalID:   c.Query("principal_id"),
+		EndpointClass: c.Query("endpoint_class"),
+	}
+
+	principal, _ := requestPrincipal(c)
+	perms, _ := c.Get("permissions")
+	permissions, _ := perms.([]auth.Permission)
+	if !slices.Contains(permissions, auth.PermAdmin) {
+		filter.PrincipalType = principal.Type
+		filter.PrincipalID = principal.ID
+	}
+
+	// Include the counters not yet persisted
+	if err := tracker.Flush(ctx); err != nil {
+		s.logger.Warn("Failed to flush usage", zap.Error(err))
+	}
+
+	records, err := s.lm.Storage().QueryUsage(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to query usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("USAGE_500", "Failed to query usage", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   from,
+		"to":     to,
+		"usage":  records,
+		"count":  len(records),
+		"quotas": tracker.QuotaStatus(principal),
+	})
+}
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API versions. Both serve the same routes; v2 responses are converted from
+// the v1 responses of the handlers by response shims until handlers produce
+// v2 natively.
+const (
+	APIVersion1 = "v1"
+	APIVersion2 = "v2"
+
+	apiVersionHeader = "API-Version"
+)
+
+// Deprecation announces the removal of an endpoint. Responses carry the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers and a link to the
+// successor.
+type Deprecation struct {
+	Since     time.Time // When the endpoint was deprecated
+	Sunset    time.Time // Planned removal, zero if not planned yet
+	Successor string    // Replacement, e.g. "/api/v2/executions"
+}
+
+// deprecations by version and route, e.g. "GET /executions/:id/steps"
+var deprecations = map[string]map[string]Deprecation{
+	APIVersion1: {},
+	APIVersion2: {},
+}
+
+// ResponseShim converts the decoded JSON body of a successful v1 response
+// into its v2 form
+type ResponseShim func(body any) any
+
+// v2Shims by route replace the default list envelope of v2
+var v2Shims = map[string]ResponseShim{}
+
+// APIVersionMiddleware marks the responses of a version's route group and
+// applies its deprecations and response shims
+func APIVersionMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(apiVersionHeader, version)
+		c.Set("api_version", version)
+
+		_, route := versionedRoute(c.FullPath())
+		route = c.Request.Method + " " + route
+		if dep, ok := deprecations[version][route]; ok {
+			setDeprecationHeaders(c, dep)
+		}
+
+		if version == APIVersion1 || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		shim := v2Shims[route]
+		if shim == nil {
+			shim = listEnvelope
+		}
+		w := &shimWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(shim)
+	}
+}
+
+func setDeprecationHeaders(c *gin.Context, dep Deprecation) {
+	c.Header("Deprecation", "@"+strconv.FormatInt(dep.Since.Unix(), 10))
+	if !dep.Sunset.IsZero() {
+		c.Header("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if dep.Successor != "" {
+		c.Header("Link", "<"+dep.Successor+`>; rel="successor-version"`)
+	}
+}
+
+// versionedRoute splits a route path like "/api/v1/devices/:id" into the
+// version and the route within it ("/devices/:id")
+func versionedRoute(fullPath string) (version, route string) {
+	rest, ok := strings.CutPrefix(fullPath, "/api/")
+	if !ok {
+		return "", fullPath
+	}
+	version, route, _ = strings.Cut(rest, "/")
+	return version, "/" + route
+}
+
+// listEnvelope is the default v2 shim: list responses, an array next to a
+// "count", become {"data": [...], "meta": {"count": n, ...}}
+func listEnvelope(body any) any {
+	obj, ok := body.(map[string]any)
+	if !ok {
+		return body
+	}
+	if _, ok := obj["count"]; !ok {
+		return body
+	}
+
+	var field string
+	for key, value := range obj {
+		if _, isList := value.([]any); isList {
+			if field != "" {
+				return body // Ambiguous, needs a route shim
+			}
+			field = key
+		}
+	}
+	if field == "" {
+		return body
+	}
+
+	meta := make(map[string]any, len(obj)-1)
+	for key, value := range obj {
+		if key != field {
+			meta[key] = value
+		}
+	}
+	return map[string]any{"data": obj[field], "meta": meta}
+}
+
+// shimWriter buffers a response so a shim can rewrite it
+type shimWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *shimWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *shimWriter) WriteHeaderNow() {}
+
+func (w *shimWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *shimWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *shimWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *shimWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *shimWriter) Written() bool {
+	return w.status != 0 || w.body.Len() > 0
+}
+
+// flush writes the buffered response, converted by shim if it is a
+// successful JSON response
+func (w *shimWriter) flush(shim ResponseShim) {
+	status := w.Status()
+	data := w.body.Bytes()
+
+	if status >= http.StatusOK && status < http.StatusMultipleChoices &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		var body any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber() // Keep int64 IDs exact
+		if err := decoder.Decode(&body); err == nil {
+			if converted, err := json.Marshal(shim(body)); err == nil {
+				data = converted
+				w.Header().Del("Content-Length")
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+	if len(data) > 0 {
+		w.ResponseWriter.Write(data)
+	}
+}
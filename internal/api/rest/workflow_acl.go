@@ -0,0 +1,273 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Audit actions of workflow access lists
+const (
+	auditWorkflowACLUpdated = "workflow.acl_updated"
+	auditWorkflowACLRemoved = "workflow.acl_removed"
+)
+
+// WorkflowAccessDTO tells the caller what it may do with a workflow
+type WorkflowAccessDTO struct {
+	Restricted bool `json:"restricted"` // The workflow has an access list
+	Execute    bool `json:"execute"`
+	Modify     bool `json:"modify"`
+}
+
+func newWorkflowAccessDTO(principal auth.Principal, acl *storage.WorkflowACL) *WorkflowAccessDTO {
+	return &WorkflowAccessDTO{
+		Restricted: acl != nil,
+		Execute:    principal.CanExecute(acl),
+		Modify:     principal.CanModify(acl),
+	}
+}
+
+// authorizeWorkflow checks the access list of a workflow for executing, or
+// with modify for changing it. On failure the error response is written.
+func (s *Server) authorizeWorkflow(c *gin.Context, workflowID uuid.UUID, modify bool) bool {
+	acl, err := s.lm.Storage().GetWorkflowACL(c.Request.Context(), workflowID)
+	if err != nil {
+		s.logger.Error("Failed to load workflow access list", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to check workflow access", err.Error()))
+		return false
+	}
+
+	principal := auth.RequestPrincipal(c)
+	switch {
+	case modify && !principal.CanModify(acl):
+		c.JSON(http.StatusForbidden, types.NewErrorResponse("WORKFLOW_403", "Not permitted to modify this workflow", principal.String()))
+		return false
+	case !modify && !principal.CanExecute(acl):
+		c.JSON(http.StatusForbidden, types.NewErrorResponse("WORKFLOW_403", "Not permitted to execute this workflow", principal.String()))
+		return false
+	}
+	return true
+}
+
+// authorizeExecution checks before a workflow starts that the caller may
+// execute it and every sub-workflow it calls. On failure the error response
+// is written.
+func (s *Server) authorizeExecution(c *gin.Context, workflowID uuid.UUID) bool {
+	if !s.authorizeWorkflow(c, workflowID, false) {
+		return false
+	}
+
+	ctx := c.Request.Context()
+	principal := auth.RequestPrincipal(c)
+	for _, subID := range s.lm.WorkflowEngine().SubWorkflows(ctx, workflowID) {
+		acl, err := s.lm.Storage().GetWorkflowACL(ctx, subID)
+		if err != nil {
+			s.logger.Error("Failed to load workflow access list", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to check workflow access", err.Error()))
+			return false
+		}
+		if !principal.CanExecute(acl) {
+			c.JSON(http.StatusForbidden, types.NewErrorResponse("WORKFLOW_403", "Not permitted to execute a sub-workflow of this workflow", gin.H{
+				"principal":       principal.String(),
+				"sub_workflow_id": subID,
+			}))
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeExecutionRead checks that the caller may execute the workflow of
+// an execution, which is needed to see it. On failure the error response is
+// written.
+func (s *Server) authorizeExecutionRead(c *gin.Context, executionID uuid.UUID) bool {
+	exec, err := s.lm.Storage().GetExecution(c.Request.Context(), executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("EXEC_404", "Execution not found", executionID.String()))
+		return false
+	}
+	return s.authorizeWorkflow(c, exec.WorkflowID, false)
+}
+
+// readableExecutions drops the executions of workflows the caller may not
+// execute. On failure the error response is written.
+func (s *Server) readableExecutions(c *gin.Context, executions []storage.WorkflowExecution) ([]storage.WorkflowExecution, bool) {
+	ctx := c.Request.Context()
+	principal := auth.RequestPrincipal(c)
+
+	allowed := make(map[uuid.UUID]bool)
+	readable := executions[:0]
+	for _, exec := range executions {
+		ok, checked := allowed[exec.WorkflowID]
+		if !checked {
+			acl, err := s.lm.Storage().GetWorkflowACL(ctx, exec.WorkflowID)
+			if err != nil {
+				s.logger.Error("Failed to load workflow access list", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to check workflow access", err.Error()))
+				return nil, false
+			}
+			ok = principal.CanExecute(acl)
+			allowed[exec.WorkflowID] = ok
+		}
+		if ok {
+			readable = append(readable, exec)
+		}
+	}
+	return readable, true
+}
+
+// authorizeTemplateUsers checks that the caller may modify every workflow
+// using a step template, since changing the template changes them. On
+// failure the error response is written.
+func (s *Server) authorizeTemplateUsers(c *gin.Context, refs []storage.Reference) bool {
+	for _, ref := range refs {
+		if ref.Type != "workflow" {
+			continue
+		}
+		workflowID, err := uuid.Parse(ref.ID)
+		if err != nil {
+			continue
+		}
+		if !s.authorizeWorkflow(c, workflowID, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// GET /api/v1/workflows/:id/acl
+func (s *Server) getWorkflowACL(c *gin.Context) {
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	acl, err := s.lm.Storage().GetWorkflowACL(c.Request.Context(), workflowID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to load workflow access list", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"workflow_id": workflowID,
+		"acl":         acl,
+		"access":      newWorkflowAccessDTO(auth.RequestPrincipal(c), acl),
+	})
+}
+
+// PUT /api/v1/workflows/:id/acl
+// Restricts the workflow to its owner and the granted principals. The owner
+// defaults to the current one, or the caller for a new access list.
+func (s *Server) putWorkflowACL(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	var req struct {
+		Owner   string   `json:"owner"`
+		Execute []string `json:"execute"`
+		Modify  []string `json:"modify"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid request body", err.Error()))
+		return
+	}
+
+	exists, err := s.lm.Storage().WorkflowExists(ctx, workflowID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to load workflow", err.Error()))
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", workflowID.String()))
+		return
+	}
+
+	if req.Owner == "" {
+		current, err := s.lm.Storage().GetWorkflowACL(ctx, workflowID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to load workflow access list", err.Error()))
+			return
+		}
+		if current != nil {
+			req.Owner = current.Owner
+		} else {
+			req.Owner = auth.RequestPrincipal(c).String()
+		}
+	}
+	for _, entry := range append(append([]string{req.Owner}, req.Execute...), req.Modify...) {
+		if err := auth.ValidatePrincipal(entry); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid access list", err.Error()))
+			return
+		}
+	}
+
+	acl := &storage.WorkflowACL{
+		WorkflowID: workflowID,
+		Owner:      req.Owner,
+		Execute:    req.Execute,
+		Modify:     req.Modify,
+		UpdatedBy:  requestActor(c),
+	}
+	if err := s.lm.Storage().SaveWorkflowACL(ctx, acl); err != nil {
+		s.logger.Error("Failed to save workflow access list", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to save workflow access list", err.Error()))
+		return
+	}
+	s.auditWorkflowACL(c, auditWorkflowACLUpdated, workflowID, acl)
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow_id": workflowID,
+		"acl":         acl,
+		"access":      newWorkflowAccessDTO(auth.RequestPrincipal(c), acl),
+	})
+}
+
+// DELETE /api/v1/workflows/:id/acl
+// Lifts the restrictions; the workflow is open to all roles again
+func (s *Server) deleteWorkflowACL(c *gin.Context) {
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	deleted, err := s.lm.Storage().DeleteWorkflowACL(c.Request.Context(), workflowID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to delete workflow access list", err.Error()))
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow has no access list", workflowID.String()))
+		return
+	}
+	s.auditWorkflowACL(c, auditWorkflowACLRemoved, workflowID, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workflow access list removed",
+	})
+}
+
+func (s *Server) auditWorkflowACL(c *gin.Context, action string, workflowID uuid.UUID, acl *storage.WorkflowACL) {
+	details := map[string]any{"workflow_id": workflowID}
+	if acl != nil {
+		details["owner"] = acl.Owner
+		details["execute"] = acl.Execute
+		details["modify"] = acl.Modify
+	}
+	entry := &storage.AuditEntry{Action: action, Actor: requestActor(c), Details: details}
+	if err := s.lm.Storage().RecordAudit(c.Request.Context(), entry); err != nil {
+		s.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// POST /api/v1/workflows/import-csv?name=...&save=true
+// Converts a step chain CSV into a draft definition. With save=true it is
+// stored as an inactive workflow.
+func (s *Server) importWorkflowCSV(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "name is required", nil))
+		return
+	}
+
+	store := s.lm.Storage()
+	def, warnings, err := workflow.ImportStepChain(ctx, c.Request.Body, name, store.DeviceExistsEnabledByName)
+	if err != nil {
+		if errors.Is(err, workflow.ErrInvalidStepChain) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid step chain", err.Error()))
+			return
+		}
+		s.logger.Error("Failed to import step chain", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to import step chain", err.Error()))
+		return
+	}
+	if warnings == nil {
+		warnings = []workflow.Issue{}
+	}
+
+	response := gin.H{
+		"definition": def,
+		"warnings":   warnings,
+	}
+	if c.Query("save") != "true" {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	data, err := def.ToJSON()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to encode workflow definition", err.Error()))
+		return
+	}
+	wf := &storage.Workflow{
+		WorkflowName: name,
+		Definition:   data,
+	}
+	if err := store.SaveWorkflow(ctx, wf, nil); err != nil {
+		s.logger.Error("Failed to save imported workflow", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to create workflow", err.Error()))
+		return
+	}
+
+	s.logger.Info("Workflow imported from step chain",
+		zap.String("workflow_id", wf.ID.String()),
+		zap.String("workflow_name", name),
+		zap.Int("steps", len(def.Steps)),
+		zap.Int("warnings", len(warnings)),
+		zap.String("actor", requestActor(c)))
+	response["workflow_id"] = wf.ID.String()
+	c.JSON(http.StatusCreated, response)
+}
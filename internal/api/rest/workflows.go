@@ -2,19 +2,52 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/docs"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/estimate"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-// GET /api/v1/workflows
+const (
+	// defaultExecutionListLimit bounds execution lists without ?limit
+	defaultExecutionListLimit = 100
+	maxExecutionListLimit     = 1000
+
+	// defaultExecutionLogLimit bounds execution logs without ?limit
+	defaultExecutionLogLimit = 1000
+	maxExecutionLogLimit     = 10000
+
+	// defaultExecutionEventLimit bounds execution event pages without ?limit
+	defaultExecutionEventLimit = 500
+	maxExecutionEventLimit     = 5000
+
+	// defaultExecutionWait is the wait without ?timeout; longer waits are
+	// cut to the execution_wait route timeout
+	defaultExecutionWait = 30 * time.Second
+	maxExecutionWait     = 10 * time.Minute
+)
+
+// executionLogLevels are the captured log levels from lowest to highest
+var executionLogLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// GET /api/v1/workflows?executable=true
+// Each workflow tells what the caller may do with it; executable hides the
+// workflows the caller may not execute.
 func (s *Server) listWorkflows(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -25,9 +58,37 @@ func (s *Server) listWorkflows(c *gin.Context) {
 		return
 	}
 
+	// Surface the latest bulk validation, e.g. recipes broken by a disabled device
+	validations, err := s.lm.Storage().ListWorkflowValidations(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load workflow validations", zap.Error(err))
+	}
+	for i := range workflows {
+		workflows[i].Validation = validations[workflows[i].ID]
+	}
+
+	acls, err := s.lm.Storage().ListWorkflowACLs(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load workflow access lists", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to list workflows", err.Error()))
+		return
+	}
+	principal := auth.RequestPrincipal(c)
+	executableOnly := c.Query("executable") == "true"
+
+	dtos := make([]WorkflowDTO, 0, len(workflows))
+	for i := range workflows {
+		dto := newWorkflowDTO(&workflows[i])
+		dto.Access = newWorkflowAccessDTO(principal, acls[workflows[i].ID])
+		if executableOnly && !dto.Access.Execute {
+			continue
+		}
+		dtos = append(dtos, dto)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"workflows": workflows,
-		"count":     len(workflows),
+		"workflows": dtos,
+		"count":     len(dtos),
 	})
 }
 
@@ -50,12 +111,203 @@ func (s *Server) getWorkflow(c *gin.Context) {
 		return
 	}
 
+	acl, err := s.lm.Storage().GetWorkflowACL(ctx, workflowID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to load workflow access list", err.Error()))
+		return
+	}
+	dto := newWorkflowDTO(workflow)
+	dto.Access = newWorkflowAccessDTO(auth.RequestPrincipal(c), acl)
+
 	c.JSON(http.StatusOK, gin.H{
-		"workflow":     workflow,
+		"workflow":     dto,
 		"compositions": compositions,
 	})
 }
 
+// GET /api/v1/workflows/:id/statistics?from=...&to=...
+// Defaults to the last 7 days.
+func (s *Server) getWorkflowStatistics(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -7)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid from", err.Error()))
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid to", err.Error()))
+			return
+		}
+		to = t
+	}
+
+	workflow, _, err := s.lm.Storage().LoadWorkflow(ctx, workflowID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", workflowID.String()))
+		return
+	}
+
+	stats, err := s.lm.Storage().GetWorkflowStatistics(ctx, workflowID, from, to)
+	if err != nil {
+		s.logger.Error("Failed to load workflow statistics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to load workflow statistics", err.Error()))
+		return
+	}
+
+	response := gin.H{"statistics": stats}
+	// The current takt, to balance the line against the measured durations
+	if def, err := definition.ParseWorkflow(workflow.Definition); err == nil && def.CycleTime.Duration > 0 {
+		response["cycle_time_ms"] = def.CycleTime.Milliseconds()
+		if def.CycleTimeoutFactor > 0 {
+			response["cycle_timeout_factor"] = def.CycleTimeoutFactor
+		}
+		if stats.Executions > 0 {
+			response["violation_rate"] = float64(stats.CycleTimeViolations) / float64(stats.Executions)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /api/v1/workflows/:id/docs?format=markdown|html|json
+// Renders the definition for review by process engineers
+func (s *Server) getWorkflowDocs(c *gin.Context) {
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+	format := c.DefaultQuery("format", "markdown")
+	if !slices.Contains([]string{"markdown", "html", "json"}, format) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid format", "expected markdown, html or json"))
+		return
+	}
+
+	doc, err := docs.Generate(c.Request.Context(), s.lm.Storage(), workflowID)
+	if err != nil {
+		if errors.Is(err, docs.ErrWorkflowNotFound) {
+			c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", workflowID.String()))
+			return
+		}
+		s.logger.Error("Failed to generate workflow documentation",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to generate workflow documentation", err.Error()))
+		return
+	}
+
+	var body []byte
+	var contentType string
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, doc)
+		return
+	case "html":
+		body, err = doc.HTML()
+		contentType = "text/html; charset=utf-8"
+	default:
+		body, err = doc.Markdown()
+		contentType = "text/markdown; charset=utf-8"
+	}
+	if err != nil {
+		s.logger.Error("Failed to render workflow documentation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to render workflow documentation", err.Error()))
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// POST /api/v1/workflows/:id/preflight
+// Checks the workflow's preconditions for the optional input body without
+// starting an execution
+func (s *Server) preflightWorkflow(c *gin.Context) {
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	input := make(map[string]any)
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	report, err := s.lm.WorkflowEngine().Preflight(c.Request.Context(), workflowID, input)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/v1/workflows/:id/estimate
+// Predicts the run duration and device utilization of the workflow from its
+// definition and recent executions
+func (s *Server) estimateWorkflow(c *gin.Context) {
+	workflowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
+		return
+	}
+
+	var req struct {
+		Loops        map[string]int `json:"loops"`          // Step number with a backward transition -> jumps per run
+		DeviceStepMs int            `json:"device_step_ms"` // Assumed device step duration without history
+		HistoryDays  int            `json:"history_days"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+	for number, jumps := range req.Loops {
+		if jumps < 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid loops", "jumps of step "+number+" must not be negative"))
+			return
+		}
+	}
+	if req.DeviceStepMs < 0 || req.HistoryDays < 0 {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid request body", "device_step_ms and history_days must not be negative"))
+		return
+	}
+
+	result, err := estimate.Run(c.Request.Context(), s.lm.Storage(), workflowID, estimate.Options{
+		Loops:       req.Loops,
+		DeviceStep:  time.Duration(req.DeviceStepMs) * time.Millisecond,
+		HistoryDays: req.HistoryDays,
+	})
+	if err != nil {
+		if errors.Is(err, estimate.ErrWorkflowNotFound) {
+			c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", workflowID.String()))
+			return
+		}
+		s.logger.Error("Failed to estimate workflow",
+			zap.String("workflow_id", workflowID.String()),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to estimate workflow", err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // POST /api/v1/workflows/:id/validate
 func (s *Server) validateWorkflow(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -89,8 +341,19 @@ func (s *Server) validateWorkflow(c *gin.Context) {
 		return
 	}
 
-	v := workflow.NewValidator(s.lm.Storage())
-	report, err := v.ValidateByID(ctx, workflowID)
+	linter := workflow.NewLinter(s.lm.Config().Lint)
+	profile := c.DefaultQuery("profile", linter.DefaultProfile())
+	if !linter.HasProfile(profile) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse(
+			"WORKFLOW_400",
+			"Unknown lint profile",
+			gin.H{"profile": profile, "available": linter.Profiles()},
+		))
+		return
+	}
+
+	v := workflow.NewValidator(s.lm.Storage()).WithLinter(linter).WithAliases(s.deviceAliases)
+	report, err := v.ValidateByIDWithProfile(ctx, workflowID, profile)
 	if err != nil {
 		// echtes Infrastrukturproblem (LoadWorkflow kaputt o.ä.)
 		s.logger.Error("Validator failed", zap.Error(err))
@@ -106,6 +369,36 @@ func (s *Server) validateWorkflow(c *gin.Context) {
 	c.JSON(http.StatusOK, report)
 }
 
+// POST /api/v1/workflows/validate-all
+func (s *Server) validateAllWorkflows(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	linter := workflow.NewLinter(s.lm.Config().Lint)
+	profile := c.DefaultQuery("profile", s.lm.Validation().Profile())
+	if !linter.HasProfile(profile) {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse(
+			"WORKFLOW_400",
+			"Unknown lint profile",
+			gin.H{"profile": profile, "available": linter.Profiles()},
+		))
+		return
+	}
+
+	report, err := s.lm.Validation().Run(ctx, profile)
+	if err != nil {
+		s.logger.Error("Bulk validation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse(
+			"WORKFLOW_500",
+			"Failed to validate workflows",
+			err.Error(),
+		))
+		return
+	}
+
+	// 200 even if workflows are invalid, like the single validation
+	c.JSON(http.StatusOK, report)
+}
+
 // POST /api/v1/workflows
 func (s *Server) createWorkflow(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -123,11 +416,14 @@ func (s *Server) createWorkflow(c *gin.Context) {
 	}
 
 	// Validate workflow definition
-	_, err := definition.ParseWorkflow(req.Definition)
+	def, err := definition.ParseWorkflow(req.Definition)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow definition", err.Error()))
 		return
 	}
+	if !s.checkParameterSizes(c, def) {
+		return
+	}
 
 	workflow := &storage.Workflow{
 		WorkflowName: req.WorkflowName,
@@ -151,6 +447,22 @@ func (s *Server) createWorkflow(c *gin.Context) {
 	})
 }
 
+// checkParameterSizes rejects definitions whose step parameters exceed the limit
+func (s *Server) checkParameterSizes(c *gin.Context, def *definition.Workflow) bool {
+	err := s.lm.WorkflowEngine().CheckParameterSizes(def)
+	if err == nil {
+		return true
+	}
+
+	var sizeErr *engine.PayloadTooLargeError
+	if errors.As(err, &sizeErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, types.NewErrorResponse("WORKFLOW_413", "Step parameters too large", sizeErr))
+	} else {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid step parameters", err.Error()))
+	}
+	return false
+}
+
 // PUT /api/v1/workflows/:id
 func (s *Server) updateWorkflow(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -171,6 +483,9 @@ func (s *Server) updateWorkflow(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid request body", err.Error()))
 		return
 	}
+	if !s.authorizeWorkflow(c, workflowID, true) {
+		return
+	}
 
 	// Load existing workflow
 	workflow, _, err := s.lm.Storage().LoadWorkflow(ctx, workflowID)
@@ -185,10 +500,14 @@ func (s *Server) updateWorkflow(c *gin.Context) {
 	}
 	if req.Definition != nil {
 		// Validate new definition
-		if _, err := definition.ParseWorkflow(req.Definition); err != nil {
+		def, err := definition.ParseWorkflow(req.Definition)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow definition", err.Error()))
 			return
 		}
+		if !s.checkParameterSizes(c, def) {
+			return
+		}
 		workflow.Definition = req.Definition
 	}
 	if req.Active != nil {
@@ -217,8 +536,16 @@ func (s *Server) deleteWorkflow(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid workflow ID", err.Error()))
 		return
 	}
+	if !s.authorizeWorkflow(c, workflowID, true) {
+		return
+	}
 
 	if err := s.lm.Storage().DeleteWorkflow(ctx, workflowID); err != nil {
+		var refErr *storage.ReferenceError
+		if errors.As(err, &refErr) {
+			c.JSON(http.StatusConflict, types.NewErrorResponse("WORKFLOW_409", "Workflow is still referenced", refErr))
+			return
+		}
 		s.logger.Error("Failed to delete workflow", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to delete workflow", err.Error()))
 		return
@@ -231,7 +558,9 @@ func (s *Server) deleteWorkflow(c *gin.Context) {
 	})
 }
 
-// POST /api/v1/workflows/:id/activate
+// POST /api/v1/workflows/:id/activate?drain=true&timeout=20s&force=true
+// With drain the switch waits until the executions of the previously active
+// workflow finished; on timeout it is refused unless force is set
 func (s *Server) activateWorkflow(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -241,20 +570,55 @@ func (s *Server) activateWorkflow(c *gin.Context) {
 		return
 	}
 
-	if err := s.lm.Storage().ActivateWorkflow(ctx, workflowID); err != nil {
-		s.logger.Error("Failed to activate workflow", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to activate workflow", err.Error()))
+	if !s.authorizeWorkflow(c, workflowID, true) {
 		return
 	}
 
-	s.logger.Info("Workflow activated", zap.String("workflow_id", workflowID.String()))
+	opts := engine.ActivationOptions{
+		Drain: c.Query("drain") == "true",
+		Force: c.Query("force") == "true",
+		Actor: requestActor(c),
+	}
+	if v := c.Query("timeout"); v != "" {
+		if opts.Timeout, err = time.ParseDuration(v); err != nil || opts.Timeout < 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("WORKFLOW_400", "Invalid timeout", v))
+			return
+		}
+	}
+	// Answer before the route timeout aborts the request
+	if routeTimeout := s.cfg.Server.RouteLimit("workflows").Timeout; routeTimeout > time.Second {
+		if opts.Timeout == 0 {
+			opts.Timeout = engine.DefaultDrainTimeout
+		}
+		opts.Timeout = min(opts.Timeout, routeTimeout-time.Second)
+	}
+
+	result, err := s.lm.WorkflowEngine().ActivateWorkflow(ctx, workflowID, opts)
+	if err != nil {
+		var blocked *engine.ActivationBlockedError
+		switch {
+		case errors.Is(err, engine.ErrWorkflowNotFound):
+			c.JSON(http.StatusNotFound, types.NewErrorResponse("WORKFLOW_404", "Workflow not found", workflowID.String()))
+		case errors.As(err, &blocked):
+			c.JSON(http.StatusConflict, types.NewErrorResponse("WORKFLOW_409", "Activation blocked by running executions", blocked.ActivationEvent))
+		case ctx.Err() != nil:
+			// Client gone or route timeout
+		default:
+			s.logger.Error("Failed to activate workflow", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("WORKFLOW_500", "Failed to activate workflow", err.Error()))
+		}
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Workflow activated successfully",
+		"message":    "Workflow activated successfully",
+		"activation": result,
 	})
 }
 
-// POST /api/v1/workflows/:id/execute
+// POST /api/v1/workflows/:id/execute?order_id=...&batch_id=...&serial_number=...&tag=...&skip_steps=...&start_at=...
+// The body is the execution input; correlation IDs, tags and the step filter
+// are query parameters.
 func (s *Server) executeWorkflow(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -264,14 +628,69 @@ func (s *Server) executeWorkflow(c *gin.Context) {
 		return
 	}
 
+	if !s.authorizeExecution(c, workflowID) {
+		return
+	}
+
+	if limit := s.lm.Config().Limits.MaxInputBytes; limit > 0 && c.Request.ContentLength > int64(limit) {
+		c.JSON(http.StatusRequestEntityTooLarge, types.NewErrorResponse("EXEC_413", "Execution input too large",
+			&engine.PayloadTooLargeError{Field: "input", Size: int(c.Request.ContentLength), Limit: limit}))
+		return
+	}
+
 	var input map[string]interface{}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		// If no body or invalid JSON, use empty input
 		input = make(map[string]interface{})
 	}
 
-	executionID, err := s.lm.WorkflowEngine().ExecuteWorkflow(ctx, workflowID, input)
+	correlation := storage.Correlation{
+		OrderID:      c.Query("order_id"),
+		BatchID:      c.Query("batch_id"),
+		SerialNumber: c.Query("serial_number"),
+	}
+
+	if tags := queryList(c, "tag"); len(tags) > 0 {
+		ctx = engine.WithTags(ctx, tags...)
+	}
+	stepFilter := engine.StepFilter{Skip: queryList(c, "skip_steps"), StartAt: c.Query("start_at")}
+	if !stepFilter.IsZero() {
+		ctx = engine.WithStepFilter(ctx, stepFilter)
+	}
+
+	executionID, err := s.lm.WorkflowEngine().ExecuteWorkflow(ctx, workflowID, input, correlation)
 	if err != nil {
+		if errors.Is(err, engine.ErrInvalidCorrelation) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid correlation", err.Error()))
+			return
+		}
+		if errors.Is(err, engine.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid tags", err.Error()))
+			return
+		}
+		if errors.Is(err, engine.ErrInvalidStepFilter) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid step filter", err.Error()))
+			return
+		}
+		if errors.Is(err, engine.ErrOverloaded) {
+			c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("EXEC_503", "Execution rejected, system overloaded", err.Error()))
+			return
+		}
+		var sizeErr *engine.PayloadTooLargeError
+		if errors.As(err, &sizeErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, types.NewErrorResponse("EXEC_413", "Execution payload too large", sizeErr))
+			return
+		}
+		var inputErr *engine.InputValidationError
+		if errors.As(err, &inputErr) {
+			c.JSON(http.StatusUnprocessableEntity, types.NewErrorResponse("EXEC_422", "Execution input invalid", inputErr))
+			return
+		}
+		var preflightErr *engine.PreflightError
+		if errors.As(err, &preflightErr) {
+			c.JSON(http.StatusPreconditionFailed, types.NewErrorResponse("EXEC_412", "Workflow preconditions not met", preflightErr.Report))
+			return
+		}
 		s.logger.Error("Failed to execute workflow",
 			zap.String("workflow_id", workflowID.String()),
 			zap.Error(err))
@@ -281,7 +700,12 @@ func (s *Server) executeWorkflow(c *gin.Context) {
 
 	s.logger.Info("Workflow execution started",
 		zap.String("workflow_id", workflowID.String()),
-		zap.String("execution_id", executionID.String()))
+		zap.String("execution_id", executionID.String()),
+		zap.String("order_id", correlation.OrderID),
+		zap.String("batch_id", correlation.BatchID),
+		zap.String("serial_number", correlation.SerialNumber),
+		zap.Strings("skip_steps", stepFilter.Skip),
+		zap.String("start_at", stepFilter.StartAt))
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"execution_id": executionID.String(),
@@ -289,6 +713,51 @@ func (s *Server) executeWorkflow(c *gin.Context) {
 	})
 }
 
+// GET /api/v1/executions?workflow_id=...&status=...&order_id=...&batch_id=...&serial_number=...&tag=...&limit=100
+func (s *Server) listExecutions(c *gin.Context) {
+	filter := storage.ExecutionFilter{
+		Status:       storage.ExecutionStatus(c.Query("status")),
+		OrderID:      c.Query("order_id"),
+		BatchID:      c.Query("batch_id"),
+		SerialNumber: c.Query("serial_number"),
+		Tags:         queryList(c, "tag"),
+		Limit:        defaultExecutionListLimit,
+	}
+
+	if v := c.Query("workflow_id"); v != "" {
+		workflowID, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid workflow ID", err.Error()))
+			return
+		}
+		filter.WorkflowID = &workflowID
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxExecutionListLimit {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid limit", v))
+			return
+		}
+		filter.Limit = n
+	}
+
+	executions, err := s.lm.Storage().ListExecutions(c.Request.Context(), filter)
+	if err != nil {
+		s.logger.Error("Failed to list executions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to list executions", err.Error()))
+		return
+	}
+	executions, ok := s.readableExecutions(c, executions)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": newExecutionDTOs(executions),
+		"count":      len(executions),
+	})
+}
+
 // GET /api/v1/executions/:id
 func (s *Server) getExecutionStatus(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -299,6 +768,10 @@ func (s *Server) getExecutionStatus(c *gin.Context) {
 		return
 	}
 
+	if !s.authorizeExecutionRead(c, executionID) {
+		return
+	}
+
 	exec, steps, err := s.lm.WorkflowEngine().GetExecutionStatus(ctx, executionID)
 	if err != nil {
 		s.logger.Error("Failed to get execution status", zap.Error(err))
@@ -306,9 +779,62 @@ func (s *Server) getExecutionStatus(c *gin.Context) {
 		return
 	}
 
+	checkpoint, err := s.lm.Storage().GetExecutionCheckpoint(ctx, executionID)
+	if err != nil {
+		s.logger.Error("Failed to get execution checkpoint", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to get execution checkpoint", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution":  newExecutionDTO(exec),
+		"steps":      newExecutionStepDTOs(steps),
+		"checkpoint": checkpoint,
+	})
+}
+
+// GET /api/v1/executions/:id/wait?timeout=30s
+// Long poll: returns when the execution has finished or the timeout elapsed,
+// done tells which
+func (s *Server) waitExecution(c *gin.Context) {
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	timeout := defaultExecutionWait
+	if v := c.Query("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil || timeout < 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid timeout", v))
+			return
+		}
+	}
+	// Answer before the route timeout aborts the request
+	limit := maxExecutionWait
+	if routeTimeout := s.cfg.Server.RouteLimit("execution_wait").Timeout; routeTimeout > 0 {
+		limit = min(limit, routeTimeout-time.Second)
+	}
+	timeout = max(min(timeout, limit), 0)
+
+	if !s.authorizeExecutionRead(c, executionID) {
+		return
+	}
+
+	started := time.Now()
+	exec, done, err := s.lm.WorkflowEngine().WaitExecution(c.Request.Context(), executionID, timeout)
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			return // Client gone or route timeout
+		}
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("EXEC_404", "Execution not found", executionID.String()))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"execution": exec,
-		"steps":     steps,
+		"execution": newExecutionDTO(exec),
+		"done":      done,
+		"waited_ms": time.Since(started).Milliseconds(),
 	})
 }
 
@@ -322,6 +848,10 @@ func (s *Server) getExecutionSteps(c *gin.Context) {
 		return
 	}
 
+	if !s.authorizeExecutionRead(c, executionID) {
+		return
+	}
+
 	steps, err := s.lm.Storage().GetExecutionSteps(ctx, executionID)
 	if err != nil {
 		s.logger.Error("Failed to get execution steps", zap.Error(err))
@@ -330,7 +860,246 @@ func (s *Server) getExecutionSteps(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"steps": steps,
+		"steps": newExecutionStepDTOs(steps),
 		"count": len(steps),
 	})
 }
+
+// GET /api/v1/executions/compare?a=<id>&b=<id>&differences_only=true
+func (s *Server) compareExecutions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ids := make([]uuid.UUID, 0, 2)
+	for _, param := range []string{"a", "b"} {
+		id, err := uuid.Parse(c.Query(param))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", gin.H{"param": param, "error": err.Error()}))
+			return
+		}
+		if !s.authorizeExecutionRead(c, id) {
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	comparison, err := s.lm.WorkflowEngine().CompareExecutions(ctx, ids[0], ids[1])
+	if err != nil {
+		if errors.Is(err, engine.ErrDifferentWorkflows) {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Executions belong to different workflows", err.Error()))
+			return
+		}
+		s.logger.Error("Failed to compare executions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to compare executions", err.Error()))
+		return
+	}
+
+	if c.Query("differences_only") == "true" {
+		differing := comparison.Steps[:0]
+		for _, step := range comparison.Steps {
+			if step.Differs {
+				differing = append(differing, step)
+			}
+		}
+		comparison.Steps = differing
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// GET /api/v1/executions/:id/logs?level=warn&step_id=...&limit=1000&trace=true
+// level is the lowest returned level; traced Modbus frames are admin only.
+func (s *Server) getExecutionLogs(c *gin.Context) {
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	filter := storage.ExecutionLogFilter{Limit: defaultExecutionLogLimit}
+	if v := c.Query("level"); v != "" {
+		i := slices.Index(executionLogLevels, strings.ToLower(v))
+		if i < 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid level", v))
+			return
+		}
+		filter.Levels = executionLogLevels[i:]
+	}
+	if v := c.Query("step_id"); v != "" {
+		stepID, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid step ID", err.Error()))
+			return
+		}
+		filter.StepID = &stepID
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxExecutionLogLimit {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid limit", v))
+			return
+		}
+		filter.Limit = n
+	}
+	if c.Query("trace") == "true" {
+		// Frames may contain process data, only admins see them
+		perms, _ := c.Get("permissions")
+		permissions, _ := perms.([]auth.Permission)
+		if !slices.Contains(permissions, auth.PermAdmin) {
+			c.JSON(http.StatusForbidden, types.NewErrorResponse("EXEC_403", "Frame traces require admin permission", nil))
+			return
+		}
+		filter.Trace = true
+	}
+
+	if !s.authorizeExecutionRead(c, executionID) {
+		return
+	}
+
+	entries, err := s.lm.WorkflowEngine().ExecutionLogs(c.Request.Context(), executionID, filter)
+	if err != nil {
+		s.logger.Error("Failed to get execution logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to get execution logs", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":  entries,
+		"count": len(entries),
+	})
+}
+
+// GET /api/v1/executions/:id/events?event_type=step.failed&since=<sequence>&limit=500
+// Returns the stored events of an execution in sequence order. Pages are
+// continued with since=next_since while has_more is set.
+func (s *Server) getExecutionEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	filter := storage.ExecutionEventFilter{Limit: defaultExecutionEventLimit}
+	filter.EventTypes = queryList(c, "event_type")
+	if v := c.Query("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid since", v))
+			return
+		}
+		filter.Since = n
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxExecutionEventLimit {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid limit", v))
+			return
+		}
+		filter.Limit = n
+	}
+
+	if !s.authorizeExecutionRead(c, executionID) {
+		return
+	}
+
+	// One more than requested tells whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	events, err := s.lm.Storage().ListExecutionEvents(ctx, executionID, filter)
+	if err != nil {
+		s.logger.Error("Failed to get execution events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to get execution events", err.Error()))
+		return
+	}
+
+	hasMore := len(events) > limit
+	if hasMore {
+		events = events[:limit]
+	}
+	nextSince := filter.Since
+	if len(events) > 0 {
+		nextSince = events[len(events)-1].Sequence
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":     events,
+		"count":      len(events),
+		"has_more":   hasMore,
+		"next_since": nextSince,
+	})
+}
+
+// GET /api/v1/resources
+// Returns the resource tags used by steps since startup with their holder.
+func (s *Server) listResources(c *gin.Context) {
+	resources := s.lm.WorkflowEngine().Resources()
+	c.JSON(http.StatusOK, gin.H{
+		"resources": resources,
+		"count":     len(resources),
+	})
+}
+
+// POST /api/v1/executions/:id/retry
+func (s *Server) retryExecution(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	executionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid execution ID", err.Error()))
+		return
+	}
+
+	var req struct {
+		FromStep *int `json:"from_step"` // Defaults to the failed step
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	exec, err := s.lm.Storage().GetExecution(ctx, executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, types.NewErrorResponse("EXEC_404", "Execution not found", executionID.String()))
+		return
+	}
+	// A retry executes the workflow again
+	if !s.authorizeExecution(c, exec.WorkflowID) {
+		return
+	}
+
+	retry, err := s.lm.WorkflowEngine().RetryExecution(ctx, executionID, req.FromStep)
+	if err != nil {
+		var preflightErr *engine.PreflightError
+		switch {
+		case errors.Is(err, engine.ErrNotRetryable):
+			c.JSON(http.StatusConflict, types.NewErrorResponse("EXEC_409", "Execution cannot be retried", err.Error()))
+		case errors.Is(err, engine.ErrInvalidRetryStep):
+			c.JSON(http.StatusBadRequest, types.NewErrorResponse("EXEC_400", "Invalid from_step", err.Error()))
+		case errors.Is(err, engine.ErrOverloaded):
+			c.JSON(http.StatusServiceUnavailable, types.NewErrorResponse("EXEC_503", "Execution rejected, system overloaded", err.Error()))
+		case errors.As(err, &preflightErr):
+			c.JSON(http.StatusPreconditionFailed, types.NewErrorResponse("EXEC_412", "Workflow preconditions not met", preflightErr.Report))
+		default:
+			s.logger.Error("Failed to retry execution",
+				zap.String("execution_id", executionID.String()),
+				zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.NewErrorResponse("EXEC_500", "Failed to retry execution", err.Error()))
+		}
+		return
+	}
+
+	s.logger.Info("Execution retry started",
+		zap.String("execution_id", retry.ID.String()),
+		zap.String("retry_of", executionID.String()),
+		zap.Int("start_step", retry.StartStep))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"execution_id": retry.ID.String(),
+		"retry_of":     executionID.String(),
+		"start_step":   retry.StartStep,
+		"message":      "Execution retry started",
+	})
+}
@@ -3,7 +3,11 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
@@ -47,12 +51,62 @@ type Client struct {
 	authenticated bool
 	permissions   []auth.Permission
 	userID        *uuid.UUID
+	actor         string        // Username or token:<name>, for audit entries
+	resumeToken   string        // ID of the last message received before reconnecting
+	registeredAt  time.Time     // Set by the hub
+	done          chan struct{} // Closed when the write pump ended
+
+	// Guards send against sends after close and double close
+	sendMu      sync.Mutex
+	closed      bool
+	closeCode   int // Close frame status, 0 = empty close frame
+	closeReason string
+}
+
+// queue sends a frame without blocking. It reports false if the client is
+// closed or its send buffer is full.
+func (c *Client) queue(data []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send channel once; the write pump flushes the queued
+// frames and sends a close frame with code and reason
+func (c *Client) closeSend(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	close(c.send)
+}
+
+// closeMessage returns the payload of the close frame
+func (c *Client) closeMessage() []byte {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closeCode == 0 {
+		return []byte{}
+	}
+	return websocket.FormatCloseMessage(c.closeCode, c.closeReason)
 }
 
 // readPump handles reading messages from the WebSocket connection
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.leave(c)
 		c.conn.Close()
 	}()
 
@@ -91,7 +145,7 @@ func (c *Client) readPump() {
 
 			// Validate token via AuthService
 			authService := c.hub.authService
-			permissions, err := authService.ValidateToken(
+			principal, permissions, err := authService.AuthenticateToken(
 				context.Background(),
 				token,
 				c.conn.RemoteAddr().String(),
@@ -110,6 +164,8 @@ func (c *Client) readPump() {
 			// Authentication successful
 			c.authenticated = true
 			c.permissions = permissions
+			c.actor = principal.Actor()
+			c.resumeToken, _ = msg["resume_token"].(string)
 			c.conn.SetReadDeadline(time.Time{}) // Remove deadline
 
 			c.sendAuthSuccess(permissions)
@@ -117,8 +173,11 @@ func (c *Client) readPump() {
 				zap.String("remote_addr", c.conn.RemoteAddr().String()),
 				zap.Any("permissions", permissions))
 
-			// NOW register to hub (only after auth)
-			c.hub.register <- c
+			// NOW register to hub (only after auth); the hub replays missed
+			// messages first if a resume token was given
+			if !c.hub.join(c) {
+				return
+			}
 
 			// Send initial machine status if available
 			c.sendInitialMachineStatus()
@@ -137,7 +196,7 @@ func (c *Client) sendAuthSuccess(permissions []auth.Permission) {
 		"permissions": permissions,
 	}
 	data, _ := json.Marshal(msg)
-	c.send <- data
+	c.queue(data)
 }
 
 func (c *Client) sendAuthFailed(reason string) {
@@ -147,7 +206,7 @@ func (c *Client) sendAuthFailed(reason string) {
 		"reason":    reason,
 	}
 	data, _ := json.Marshal(msg)
-	c.send <- data
+	c.queue(data)
 }
 
 func (c *Client) sendInitialMachineStatus() {
@@ -162,7 +221,7 @@ func (c *Client) sendInitialMachineStatus() {
 		"payload":   status,
 	}
 	data, _ := json.Marshal(msg)
-	c.send <- data
+	c.queue(data)
 }
 
 func (c *Client) handleMessage(msg map[string]interface{}) {
@@ -171,7 +230,101 @@ func (c *Client) handleMessage(msg map[string]interface{}) {
 		zap.String("remote_addr", c.conn.RemoteAddr().String()),
 		zap.Any("message", msg))
 
-	// TODO: Implement subscription logic
+	msgType, _ := msg["type"].(string)
+	c.hub.dispatchCommand(c, msgType, msg)
+}
+
+// handleAlarmCommand acknowledges or resets an alarm; the result is sent back
+// as "alarm_command_result"
+func (c *Client) handleAlarmCommand(command string, msg map[string]interface{}) {
+	alarmID, _ := msg["alarm_id"].(string)
+	result := map[string]interface{}{
+		"type":      "alarm_command_result",
+		"timestamp": time.Now(),
+		"command":   command,
+		"alarm_id":  alarmID,
+	}
+
+	var err error
+	var alarm any
+	id, parseErr := uuid.Parse(alarmID)
+	switch {
+	case c.hub.alarmHandler == nil:
+		err = errors.New("alarms are not enabled")
+	case parseErr != nil:
+		err = fmt.Errorf("invalid alarm_id: %w", parseErr)
+	case command == "alarm_acknowledge":
+		alarm, err = c.hub.alarmHandler.AcknowledgeAlarm(id, c.actor)
+	default:
+		alarm, err = c.hub.alarmHandler.ResetAlarm(id, c.actor)
+	}
+
+	result["success"] = err == nil
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		result["alarm"] = alarm
+	}
+
+	data, _ := json.Marshal(result)
+	c.queue(data)
+}
+
+// handleOnboardingAnswer answers the prompted check of an onboarding; the
+// result is sent back as "onboarding_answer_result"
+func (c *Client) handleOnboardingAnswer(command string, msg map[string]interface{}) {
+	sessionID, _ := msg["session_id"].(string)
+	index, hasIndex := msg["index"].(float64)
+	answer, _ := msg["result"].(string)
+	note, _ := msg["note"].(string)
+	result := map[string]interface{}{
+		"type":       "onboarding_answer_result",
+		"timestamp":  time.Now(),
+		"session_id": sessionID,
+		"index":      msg["index"],
+	}
+
+	var err error
+	var session any
+	id, parseErr := uuid.Parse(sessionID)
+	switch {
+	case c.hub.onboardingHandler == nil:
+		err = errors.New("onboarding is not enabled")
+	case parseErr != nil:
+		err = fmt.Errorf("invalid session_id: %w", parseErr)
+	case !hasIndex || index != float64(int(index)):
+		err = errors.New("index must be the number of the check")
+	default:
+		session, err = c.hub.onboardingHandler.AnswerOnboardingCheck(id, c.actor, int(index), answer, note)
+	}
+
+	result["success"] = err == nil
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		result["session"] = session
+	}
+
+	data, _ := json.Marshal(result)
+	c.queue(data)
+}
+
+func (c *Client) hasPermission(required auth.Permission) bool {
+	for _, p := range c.permissions {
+		if p == required {
+			return true
+		}
+	}
+	return false
+}
+
+// faultTargets returns the names a fault injection target may match
+func (c *Client) faultTargets() []string {
+	targets := []string{c.conn.RemoteAddr().String()}
+	if host, _, err := net.SplitHostPort(targets[0]); err == nil {
+		targets = append(targets, host)
+	}
+	return targets
 }
 
 // writePump handles writing messages to the WebSocket connection
@@ -180,6 +333,7 @@ func (c *Client) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		close(c.done)
 	}()
 
 	for {
@@ -188,7 +342,7 @@ func (c *Client) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// Hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
 				return
 			}
 
@@ -232,11 +386,10 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		hub:    hub,
 		conn:   conn,
 		send:   make(chan []byte, sendBufferSize),
+		done:   make(chan struct{}),
 		logger: hub.logger, // <- Logger vom Hub übernehmen
 	}
 
-	client.hub.register <- client
-
 	// Start read and write pumps in separate goroutines
 	go client.writePump()
 	go client.readPump()
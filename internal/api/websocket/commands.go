@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"go.uber.org/zap"
+)
+
+// AuditCommandDenied is the audit action of a command rejected for missing permissions
+const AuditCommandDenied = "websocket.command_denied"
+
+// Error codes of "command_error" frames
+const (
+	CommandErrorUnknown   = "WS_400"
+	CommandErrorForbidden = "WS_403"
+)
+
+// CommandHandler handles an inbound client command
+type CommandHandler func(c *Client, command string, msg map[string]interface{})
+
+// CommandMiddleware wraps a command handler, like gin middleware wraps REST handlers
+type CommandMiddleware func(next CommandHandler) CommandHandler
+
+// CommandAuditor records security relevant client commands
+type CommandAuditor interface {
+	AuditCommand(action, actor string, details map[string]any)
+}
+
+// RequirePermission mirrors auth.RequirePermission for WebSocket commands:
+// without the permission the command is answered with a "command_error"
+// frame and the denial is audited
+func RequirePermission(required auth.Permission) CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(c *Client, command string, msg map[string]interface{}) {
+			if !c.hasPermission(required) {
+				c.denyCommand(command, required)
+				return
+			}
+			next(c, command, msg)
+		}
+	}
+}
+
+// HandleCommand registers the handler of an inbound command type. Middleware
+// runs in the given order before the handler.
+func (h *Hub) HandleCommand(command string, handler CommandHandler, middleware ...CommandMiddleware) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	h.commandsMu.Lock()
+	h.commands[command] = handler
+	h.commandsMu.Unlock()
+}
+
+// SetCommandAuditor enables auditing of denied commands
+func (h *Hub) SetCommandAuditor(auditor CommandAuditor) {
+	h.commandAuditor = auditor
+}
+
+// dispatchCommand runs the registered handler of a command
+func (h *Hub) dispatchCommand(c *Client, command string, msg map[string]interface{}) {
+	h.commandsMu.RLock()
+	handler, ok := h.commands[command]
+	h.commandsMu.RUnlock()
+
+	if !ok {
+		c.sendCommandError(command, CommandErrorUnknown, "unknown command", nil)
+		return
+	}
+	handler(c, command, msg)
+}
+
+// denyCommand answers and audits a command rejected for a missing permission
+func (c *Client) denyCommand(command string, required auth.Permission) {
+	remoteAddr := c.conn.RemoteAddr().String()
+	c.logger.Warn("WebSocket command denied",
+		zap.String("command", command),
+		zap.String("actor", c.actor),
+		zap.String("required", string(required)),
+		zap.String("remote_addr", remoteAddr))
+
+	c.sendCommandError(command, CommandErrorForbidden, "insufficient permissions", map[string]interface{}{
+		"required": string(required),
+	})
+
+	if c.hub.commandAuditor != nil {
+		c.hub.commandAuditor.AuditCommand(AuditCommandDenied, c.actor, map[string]any{
+			"command":     command,
+			"required":    required,
+			"permissions": c.permissions,
+			"remote_addr": remoteAddr,
+		})
+	}
+}
+
+// sendCommandError sends a structured "command_error" frame
+func (c *Client) sendCommandError(command, code, reason string, extra map[string]interface{}) {
+	msg := map[string]interface{}{
+		"type":      "command_error",
+		"timestamp": time.Now(),
+		"command":   command,
+		"code":      code,
+		"error":     reason,
+	}
+	for k, v := range extra {
+		msg[k] = v
+	}
+
+	data, _ := json.Marshal(msg)
+	c.queue(data)
+}
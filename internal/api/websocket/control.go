@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"go.uber.org/zap"
+)
+
+// Control commands change the machine or write device outputs. They are
+// executed one at a time in arrival order, so commands of several HMIs
+// cannot interleave, and answered with a "command_ack" frame.
+const (
+	CommandMachine     = "machine_command" // Same body as POST /machine/command
+	CommandDeviceWrite = "device_write"    // Same body as POST /devices/:id/write plus device_id
+)
+
+// Statuses of a control command in its "command_ack" frame
+const (
+	ControlAccepted = "accepted" // Machine command accepted
+	ControlQueued   = "queued"   // Machine command queued until the running workflow completes
+	ControlWritten  = "written"  // Device write done
+	ControlRejected = "rejected" // Not allowed in the current machine state
+	ControlFailed   = "failed"
+)
+
+// CommandErrorQueueFull is the "command_error" code of a control command
+// dropped because the queue is full
+const CommandErrorQueueFull = "WS_503"
+
+// controlTimeout bounds executing a single control command
+const controlTimeout = 30 * time.Second
+
+// ControlRequest is a control command of a client
+type ControlRequest struct {
+	RequestID string         // Client chosen, repeating it returns the first result
+	Command   string         // CommandMachine or CommandDeviceWrite
+	Actor     string         // Username or token:<name>
+	Params    map[string]any // The command frame without type and request_id
+}
+
+// ControlResult is the outcome of a control command
+type ControlResult struct {
+	Status  string         `json:"status"`
+	Code    string         `json:"code,omitempty"` // Error code as in REST responses
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// ControlHandler executes control commands with the permission checked. It
+// records and audits them; duplicate is set when the request ID was already
+// used by the actor and the stored result is returned.
+type ControlHandler interface {
+	ExecuteControl(ctx context.Context, req ControlRequest) (result ControlResult, duplicate bool)
+}
+
+// controlJob is a queued control command
+type controlJob struct {
+	client *Client
+	req    ControlRequest
+}
+
+// SetControlHandler enables control commands from clients. Up to queueSize
+// commands wait for execution, further ones are answered with WS_503.
+func (h *Hub) SetControlHandler(handler ControlHandler, queueSize int) {
+	h.controlHandler = handler
+	h.controlQueue = make(chan controlJob, queueSize)
+
+	// Same permissions as the REST endpoints
+	h.HandleCommand(CommandMachine, (*Client).enqueueControl, RequirePermission(auth.PermOperator))
+	h.HandleCommand(CommandDeviceWrite, (*Client).enqueueControl, RequirePermission(auth.PermTechnician))
+
+	go h.runControl()
+}
+
+// enqueueControl queues a control command for runControl
+func (c *Client) enqueueControl(command string, msg map[string]interface{}) {
+	requestID, _ := msg["request_id"].(string)
+	if requestID == "" {
+		c.sendCommandError(command, CommandErrorUnknown, "request_id is required", nil)
+		return
+	}
+
+	params := make(map[string]any, len(msg))
+	for k, v := range msg {
+		if k != "type" && k != "request_id" {
+			params[k] = v
+		}
+	}
+
+	job := controlJob{client: c, req: ControlRequest{
+		RequestID: requestID,
+		Command:   command,
+		Actor:     c.actor,
+		Params:    params,
+	}}
+	select {
+	case c.hub.controlQueue <- job:
+	default:
+		c.sendCommandError(command, CommandErrorQueueFull, "command queue full, retry later", map[string]interface{}{
+			"request_id": requestID,
+		})
+	}
+}
+
+// runControl executes queued control commands in order and acknowledges them
+func (h *Hub) runControl() {
+	for job := range h.controlQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+		result, duplicate := h.controlHandler.ExecuteControl(ctx, job.req)
+		cancel()
+
+		h.logger.Info("WebSocket control command executed",
+			zap.String("command", job.req.Command),
+			zap.String("request_id", job.req.RequestID),
+			zap.String("actor", job.req.Actor),
+			zap.String("status", result.Status),
+			zap.Bool("duplicate", duplicate))
+
+		data, _ := json.Marshal(map[string]interface{}{
+			"type":       "command_ack",
+			"timestamp":  time.Now(),
+			"command":    job.req.Command,
+			"request_id": job.req.RequestID,
+			"duplicate":  duplicate,
+			"result":     result,
+		})
+		h.sendTo(job.client, data)
+	}
+}
+
+// sendTo sends a frame to a client that may have disconnected meanwhile
+func (h *Hub) sendTo(client *Client, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[client] {
+		return
+	}
+	client.queue(data)
+}
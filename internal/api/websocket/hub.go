@@ -1,10 +1,15 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -13,6 +18,24 @@ type MachineStatusProvider interface {
 	GetStatus() any
 }
 
+// AlarmHandler acknowledges and resets alarms on behalf of clients
+type AlarmHandler interface {
+	AcknowledgeAlarm(id uuid.UUID, actor string) (any, error)
+	ResetAlarm(id uuid.UUID, actor string) (any, error)
+}
+
+// OnboardingHandler answers prompted checks of device onboardings on behalf
+// of clients
+type OnboardingHandler interface {
+	AnswerOnboardingCheck(id uuid.UUID, actor string, index int, result, note string) (any, error)
+}
+
+// ConnectionFaults decides whether a client connection is dropped by the
+// fault injection. Targets are the client's remote address and host.
+type ConnectionFaults interface {
+	DropConnection(targets ...string) bool
+}
+
 // Hub maintains active WebSocket clients and broadcasts messages
 type Hub struct {
 	// Registered clients
@@ -38,18 +61,62 @@ type Hub struct {
 
 	// Machine status provider (optional)
 	machineStatusProvider MachineStatusProvider
+
+	// Alarm handler (optional)
+	alarmHandler AlarmHandler
+
+	// Onboarding handler (optional)
+	onboardingHandler OnboardingHandler
+
+	// Fault injection (optional, developer mode)
+	connectionFaults ConnectionFaults
+
+	// Inbound command handlers by command type
+	commandsMu     sync.RWMutex
+	commands       map[string]CommandHandler
+	commandAuditor CommandAuditor
+
+	// Control commands (optional), executed in order by runControl
+	controlHandler ControlHandler
+	controlQueue   chan controlJob
+
+	// Replay for reconnecting clients, only used by Run
+	epoch      string
+	seq        uint64
+	replaySize int
+	replay     map[string][]replayEntry // Topic -> newest messages, oldest first
+	evicted    map[string]uint64        // Topic -> sequence of the newest dropped message
+
+	// Lifecycle, guarded by mu: Stop closes done, Run closes the clients and
+	// then stopped. Run after Stop starts a new run, e.g. after an update.
+	running    bool
+	done       chan struct{}
+	stopped    chan struct{}
+	stopReason string
+	draining   []*Client // Clients closed by the shutdown, flushing their send buffers
 }
 
 // NewHub creates a new Hub instance
 func NewHub(logger *zap.Logger, authService *auth.AuthService) *Hub {
-	return &Hub{
+	h := &Hub{
 		broadcast:   make(chan Message, 256),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
 		clients:     make(map[*Client]bool),
 		logger:      logger,
 		authService: authService,
+		commands:    make(map[string]CommandHandler),
+		epoch:       newEpoch(),
+		replay:      make(map[string][]replayEntry),
+		evicted:     make(map[string]uint64),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 	}
+
+	h.HandleCommand("alarm_acknowledge", (*Client).handleAlarmCommand, RequirePermission(auth.PermOperator))
+	h.HandleCommand("alarm_reset", (*Client).handleAlarmCommand, RequirePermission(auth.PermOperator))
+	h.HandleCommand("onboarding_answer", (*Client).handleOnboardingAnswer, RequirePermission(auth.PermAdmin))
+	return h
 }
 
 // SetMachineStatusProvider sets the machine status provider
@@ -57,24 +124,63 @@ func (h *Hub) SetMachineStatusProvider(provider MachineStatusProvider) {
 	h.machineStatusProvider = provider
 }
 
-// Run starts the hub's main event loop
+// SetAlarmHandler enables alarm commands from clients
+func (h *Hub) SetAlarmHandler(handler AlarmHandler) {
+	h.alarmHandler = handler
+}
+
+// SetOnboardingHandler enables answering onboarding checks from clients
+func (h *Hub) SetOnboardingHandler(handler OnboardingHandler) {
+	h.onboardingHandler = handler
+}
+
+// SetConnectionFaults enables simulated connection drops
+func (h *Hub) SetConnectionFaults(faults ConnectionFaults) {
+	h.connectionFaults = faults
+}
+
+// Run starts the hub's main event loop. It returns after Stop, once the
+// clients are closed.
 func (h *Hub) Run() {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		return
+	}
+	h.running = true
+	if isClosed(h.done) {
+		h.done = make(chan struct{})
+	}
+	h.stopped = make(chan struct{})
+	h.draining = nil
+	done, stopped := h.done, h.stopped
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.running = false
+		h.mu.Unlock()
+		close(stopped)
+	}()
+
 	h.logger.Info("WebSocket Hub started")
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			client.registeredAt = time.Now()
 			h.clients[client] = true
 			h.mu.Unlock()
 			h.logger.Info("WebSocket client registered",
 				zap.String("remote_addr", client.conn.RemoteAddr().String()),
-				zap.Int("total_clients", len(h.clients)))
+				zap.Int("total_clients", h.GetClientCount()))
+			h.startDelivery(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.closeSend(0, "")
 				h.logger.Info("WebSocket client unregistered",
 					zap.String("remote_addr", client.conn.RemoteAddr().String()),
 					zap.Int("total_clients", len(h.clients)))
@@ -82,34 +188,154 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			data, err := json.Marshal(message)
-			if err != nil {
-				h.logger.Error("Failed to marshal broadcast message",
-					zap.Error(err))
-				h.mu.RUnlock()
-				continue
-			}
+			h.deliver(message)
 
-			for client := range h.clients {
+		case <-done:
+			// Messages queued before the shutdown still reach the clients
+			for pending := true; pending; {
 				select {
-				case client.send <- data:
-					// Message sent successfully
+				case message := <-h.broadcast:
+					h.deliver(message)
 				default:
-					// Client send channel full - unregister slow/dead client
-					close(client.send)
-					delete(h.clients, client)
-					h.logger.Warn("Client send buffer full, unregistering",
-						zap.String("remote_addr", client.conn.RemoteAddr().String()))
+					pending = false
 				}
 			}
-			h.mu.RUnlock()
+			h.shutdown()
+			return
 		}
 	}
 }
 
+// deliver queues a broadcast message for every client
+func (h *Hub) deliver(message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	seq := h.seq + 1
+	message.ID = h.messageID(seq)
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("Failed to marshal broadcast message",
+			zap.Error(err))
+		return
+	}
+	h.seq = seq
+	h.remember(message.Type, seq, data)
+
+	for client := range h.clients {
+		if h.connectionFaults != nil && h.connectionFaults.DropConnection(client.faultTargets()...) {
+			// Simulated network failure: close without a close frame
+			client.closeSend(0, "")
+			delete(h.clients, client)
+			client.conn.Close()
+			h.logger.Warn("Injected fault: WebSocket connection dropped",
+				zap.String("remote_addr", client.conn.RemoteAddr().String()))
+			continue
+		}
+
+		if !client.queue(data) {
+			// Client send channel full - unregister slow/dead client
+			client.closeSend(0, "")
+			delete(h.clients, client)
+			h.logger.Warn("Client send buffer full, unregistering",
+				zap.String("remote_addr", client.conn.RemoteAddr().String()))
+		}
+	}
+}
+
+// shutdown sends every client a server_shutdown message and closes it with
+// a going away close frame after its queued messages
+func (h *Hub) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, _ := json.Marshal(NewMessage(MessageTypeServerShutdown, map[string]string{
+		"reason": h.stopReason,
+	}))
+	for client := range h.clients {
+		client.queue(data)
+		client.closeSend(websocket.CloseGoingAway, h.stopReason)
+		h.draining = append(h.draining, client)
+		delete(h.clients, client)
+	}
+	h.logger.Info("WebSocket Hub stopped", zap.Int("clients", len(h.draining)))
+}
+
+// Stop ends Run, tells the clients why they are disconnected and waits until
+// their queued messages are written or ctx ends
+func (h *Hub) Stop(ctx context.Context, reason string) error {
+	h.mu.Lock()
+	running, stopped := h.running, h.stopped
+	if !isClosed(h.done) {
+		h.stopReason = reason
+		close(h.done)
+	}
+	h.mu.Unlock()
+
+	if !running {
+		h.shutdown()
+	} else {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	h.mu.RLock()
+	draining := h.draining
+	h.mu.RUnlock()
+	for _, client := range draining {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// join registers an authenticated client; it reports false once the hub
+// stopped
+func (h *Hub) join(client *Client) bool {
+	select {
+	case h.register <- client:
+		return true
+	case <-h.stopDone():
+		return false
+	}
+}
+
+// leave unregisters a client whose connection ended
+func (h *Hub) leave(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.stopDone():
+	}
+}
+
+// stopDone returns the channel closed by Stop for the current run
+func (h *Hub) stopDone() <-chan struct{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.done
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
 // Broadcast sends a message to all connected clients
 func (h *Hub) Broadcast(msg Message) {
+	if isClosed(h.stopDone()) {
+		return // Stopped, nobody receives it
+	}
+
 	select {
 	case h.broadcast <- msg:
 		// Message queued for broadcast
@@ -125,3 +351,33 @@ func (h *Hub) GetClientCount() int {
 	defer h.mu.RUnlock()
 	return len(h.clients)
 }
+
+// Shed disconnects the most recently registered clients until at most max
+// remain, so long-running HMIs keep their connection. Returns the number of
+// disconnected clients.
+func (h *Hub) Shed(max int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	excess := len(h.clients) - max
+	if excess <= 0 {
+		return 0
+	}
+
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	slices.SortFunc(clients, func(a, b *Client) int {
+		return b.registeredAt.Compare(a.registeredAt)
+	})
+
+	for _, client := range clients[:excess] {
+		// The write pump sends a close frame once send is closed
+		client.closeSend(0, "")
+		delete(h.clients, client)
+		h.logger.Warn("WebSocket client shed",
+			zap.String("remote_addr", client.conn.RemoteAddr().String()))
+	}
+	return excess
+}
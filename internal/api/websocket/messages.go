@@ -10,6 +10,7 @@ const (
 	MessageTypeDeviceIO        MessageType = "device_io"
 	MessageTypeDeviceConnected MessageType = "device_connected"
 	MessageTypeDeviceError     MessageType = "device_error"
+	MessageTypeDeviceWarning   MessageType = "device_warning"
 
 	// Machine state messages
 	MessageTypeMachineState MessageType = "machine_state"
@@ -21,12 +22,38 @@ const (
 	MessageTypeWorkflowFailed    MessageType = "workflow_failed"
 	MessageTypeWorkflowCancelled MessageType = "workflow_cancelled"
 
+	// Phases of switching the active workflow; data is the activation
+	MessageTypeWorkflowActivation MessageType = "workflow_activation"
+
+	// Alarm messages; data is the alarm
+	MessageTypeAlarmRaised       MessageType = "alarm_raised"
+	MessageTypeAlarmCleared      MessageType = "alarm_cleared"
+	MessageTypeAlarmAcknowledged MessageType = "alarm_acknowledged"
+	MessageTypeAlarmClosed       MessageType = "alarm_closed"
+
 	// System messages
 	MessageTypeSystemStatus MessageType = "system_status"
+
+	// Sent before the server closes the connection on shutdown; data is the reason
+	MessageTypeServerShutdown MessageType = "server_shutdown"
+
+	// Feature flag changes; data is the flag
+	MessageTypeFeatureChanged MessageType = "feature_changed"
+
+	// Manual control session ended and its outputs reverted; data is the session
+	MessageTypeManualSessionEnded MessageType = "manual_session_ended"
+
+	// Device onboarding asks the technician to answer a check; data is the
+	// session ID, instance ID and check
+	MessageTypeOnboardingPrompt MessageType = "onboarding_prompt"
+
+	// Device onboarding changed its stage or finished a check; data is the session
+	MessageTypeOnboardingUpdated MessageType = "onboarding_updated"
 )
 
 // Message represents a WebSocket message
 type Message struct {
+	ID        string      `json:"id,omitempty"` // Set by the hub; resume_token for reconnects
 	Type      MessageType `json:"type"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data"`
@@ -34,10 +61,30 @@ type Message struct {
 
 // DeviceIOData represents device I/O update data
 type DeviceIOData struct {
-	DeviceID string                 `json:"device_id"`
-	Address  string                 `json:"address"`
-	Value    interface{}            `json:"value"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	DeviceID  string                 `json:"device_id"`
+	Address   string                 `json:"address"`
+	Value     interface{}            `json:"value"`
+	Quality   string                 `json:"quality"`   // good, stale or bad
+	Timestamp time.Time              `json:"timestamp"` // When the device answered
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// DeviceConnectionData is the data of "device_error" when the connection of a
+// device is lost and of "device_connected" when it is restored
+type DeviceConnectionData struct {
+	DeviceID   string  `json:"device_id"`
+	DeviceName string  `json:"device_name"`
+	Error      string  `json:"error,omitempty"`       // Why the connection was lost
+	DowntimeMs float64 `json:"downtime_ms,omitempty"` // How long it was lost
+}
+
+// DeviceWarningData represents a device warning, e.g. an identity mismatch
+type DeviceWarningData struct {
+	DeviceID   string      `json:"device_id"`
+	DeviceName string      `json:"device_name"`
+	Code       string      `json:"code"`
+	Message    string      `json:"message"`
+	Details    interface{} `json:"details,omitempty"`
 }
 
 // MachineStateData represents machine state change data
@@ -56,6 +103,14 @@ type WorkflowExecutionData struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// OnboardingPromptData asks the technician onboarding a device to answer a
+// check, with "onboarding_answer" or POST /onboarding/:id/checks/:index
+type OnboardingPromptData struct {
+	SessionID  string `json:"session_id"`
+	InstanceID string `json:"instance_id"`
+	Check      any    `json:"check"`
+}
+
 // NewMessage creates a new message with current timestamp
 func NewMessage(msgType MessageType, data interface{}) Message {
 	return Message{
@@ -67,11 +122,13 @@ func NewMessage(msgType MessageType, data interface{}) Message {
 
 // Helper functions for creating specific message types
 
-func NewDeviceIOMessage(deviceID, address string, value interface{}) Message {
+func NewDeviceIOMessage(deviceID, address string, value interface{}, quality string, timestamp time.Time) Message {
 	return NewMessage(MessageTypeDeviceIO, DeviceIOData{
-		DeviceID: deviceID,
-		Address:  address,
-		Value:    value,
+		DeviceID:  deviceID,
+		Address:   address,
+		Value:     value,
+		Quality:   quality,
+		Timestamp: timestamp,
 	})
 }
 
@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Broadcast messages get an ID "<epoch>-<sequence>". A reconnecting client
+// sends the ID of the last message it received as resume_token in its auth
+// message, and the hub replays the newer messages it still holds before
+// live delivery starts. The epoch changes with every server start, so
+// tokens of an earlier run are not mistaken for current ones.
+
+// replayEntry is a broadcast message kept for replay
+type replayEntry struct {
+	seq  uint64
+	data []byte
+}
+
+// newEpoch returns a random prefix for the message IDs of this hub
+func newEpoch() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SetReplayBuffer sets how many messages are kept per topic for reconnecting
+// clients (0 disables replay). Call before Run.
+func (h *Hub) SetReplayBuffer(size int) {
+	h.replaySize = size
+}
+
+// messageID formats the ID of a message sequence number
+func (h *Hub) messageID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", h.epoch, seq)
+}
+
+// topic groups message types for replay, e.g. "device" for device_io, so
+// frequent I/O updates don't push alarms out of the buffer
+func topic(t MessageType) string {
+	name, _, _ := strings.Cut(string(t), "_")
+	return name
+}
+
+// remember keeps a broadcast message for replay
+func (h *Hub) remember(msgType MessageType, seq uint64, data []byte) {
+	if h.replaySize <= 0 {
+		return
+	}
+
+	name := topic(msgType)
+	entries := append(h.replay[name], replayEntry{seq: seq, data: data})
+	if len(entries) > h.replaySize {
+		h.evicted[name] = entries[len(entries)-h.replaySize-1].seq
+		entries = slices.Clone(entries[len(entries)-h.replaySize:])
+	}
+	h.replay[name] = entries
+}
+
+// parseResumeToken returns the sequence of a message ID of this run
+func (h *Hub) parseResumeToken(token string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(token, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || n > h.seq {
+		return 0, false
+	}
+	return n, true
+}
+
+// startDelivery replays the messages a resuming client missed and tells it
+// that live delivery starts. Called by Run when the client registers, so no
+// broadcast is sent in between.
+func (h *Hub) startDelivery(client *Client) {
+	replayed, complete := 0, true
+	if client.resumeToken != "" {
+		replayed, complete = h.replayTo(client)
+	}
+
+	// Resuming from here replays everything sent after this message
+	msg := map[string]interface{}{
+		"type":         "live",
+		"timestamp":    time.Now(),
+		"resume_token": h.messageID(h.seq),
+		"replayed":     replayed,
+		"complete":     complete,
+	}
+	data, _ := json.Marshal(msg)
+	client.queue(data)
+}
+
+// replayTo queues the buffered messages newer than the client's resume
+// token. It reports false if messages were missed that are no longer held.
+func (h *Hub) replayTo(client *Client) (int, bool) {
+	last, ok := h.parseResumeToken(client.resumeToken)
+	if !ok {
+		h.logger.Info("WebSocket resume token not from this run, nothing replayed",
+			zap.String("remote_addr", client.conn.RemoteAddr().String()))
+		return 0, false
+	}
+
+	complete := true
+	var missed []replayEntry
+	for name, entries := range h.replay {
+		if h.evicted[name] > last {
+			complete = false
+		}
+		for _, entry := range entries {
+			if entry.seq > last {
+				missed = append(missed, entry)
+			}
+		}
+	}
+	slices.SortFunc(missed, func(a, b replayEntry) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	// Leave room in the send buffer for the live message and what follows
+	room := cap(client.send) - len(client.send) - 16
+	if len(missed) > room {
+		missed = missed[len(missed)-max(room, 0):]
+		complete = false
+	}
+
+	for _, entry := range missed {
+		client.queue(entry.data)
+	}
+	return len(missed), complete
+}
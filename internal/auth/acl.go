@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of principals in workflow access lists
+const (
+	PrincipalUser  = "user"  // user:<username>
+	PrincipalRole  = "role"  // role:<role> of users
+	PrincipalToken = "token" // token:<machine token name>
+)
+
+// Principal is a caller checked against workflow access lists
+type Principal struct {
+	Username  string // Users
+	Role      string // Users
+	TokenName string // Machine tokens
+}
+
+// RequestPrincipal returns the caller of a request authenticated by
+// AuthMiddleware
+func RequestPrincipal(c *gin.Context) Principal {
+	return Principal{
+		Username:  c.GetString("username"),
+		Role:      c.GetString("role"),
+		TokenName: c.GetString("machine_token_name"),
+	}
+}
+
+// String names the principal like access list entries
+func (p Principal) String() string {
+	if p.Username != "" {
+		return PrincipalUser + ":" + p.Username
+	}
+	return PrincipalToken + ":" + p.TokenName
+}
+
+// Actor names the principal in audit fields: the username of users,
+// token:<name> for machine tokens
+func (p Principal) Actor() string {
+	if p.Username != "" {
+		return p.Username
+	}
+	return p.String()
+}
+
+// CanExecute reports whether the principal may execute a workflow with the
+// access list; nil is unrestricted
+func (p Principal) CanExecute(acl *storage.WorkflowACL) bool {
+	return acl == nil || p.matches(acl.Owner) || p.matchesAny(acl.Execute) || p.matchesAny(acl.Modify)
+}
+
+// CanModify reports whether the principal may modify, activate or delete a
+// workflow with the access list; nil is unrestricted
+func (p Principal) CanModify(acl *storage.WorkflowACL) bool {
+	return acl == nil || p.matches(acl.Owner) || p.matchesAny(acl.Modify)
+}
+
+func (p Principal) matchesAny(entries []string) bool {
+	return slices.ContainsFunc(entries, p.matches)
+}
+
+func (p Principal) matches(entry string) bool {
+	kind, name, _ := strings.Cut(entry, ":")
+	switch kind {
+	case PrincipalUser:
+		return p.Username != "" && name == p.Username
+	case PrincipalRole:
+		return p.Username != "" && name == p.Role
+	case PrincipalToken:
+		return p.TokenName != "" && name == p.TokenName
+	}
+	return false
+}
+
+// ValidatePrincipal checks an access list entry
+func ValidatePrincipal(entry string) error {
+	kind, name, found := strings.Cut(entry, ":")
+	if !found || name == "" {
+		return fmt.Errorf("invalid principal %q, use user:<name>, role:<role> or token:<name>", entry)
+	}
+	switch kind {
+	case PrincipalUser, PrincipalToken:
+		return nil
+	case PrincipalRole:
+		if name != "admin" && name != "technician" && name != "operator" {
+			return fmt.Errorf("invalid principal %q, roles are admin, technician and operator", entry)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid principal %q, use user:<name>, role:<role> or token:<name>", entry)
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MethodPermission returns the permission a gRPC method requires; false
+// leaves the method public, e.g. the health service
+type MethodPermission func(fullMethod string) (Permission, bool)
+
+type principalKey struct{}
+
+// GRPCPrincipal returns the caller of a gRPC call authenticated by the
+// interceptors
+func GRPCPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// GRPCInterceptors authenticate gRPC calls like AuthMiddleware, with the
+// token in the "authorization" metadata, and require the permission of the
+// method like RequirePermission
+func (a *AuthService) GRPCInterceptors(required MethodPermission) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authorizeGRPC(ctx, info.FullMethod, required)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorizeGRPC(ss.Context(), info.FullMethod, required)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// authorizeGRPC returns the context with the authenticated principal, or a
+// gRPC status error
+func (a *AuthService) authorizeGRPC(ctx context.Context, fullMethod string, required MethodPermission) (context.Context, error) {
+	permission, protected := required(fullMethod)
+	if !protected {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token, userAgent string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	var ipAddress string
+	if p, ok := peer.FromContext(ctx); ok {
+		ipAddress, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	principal, permissions, err := a.AuthenticateToken(ctx, token, ipAddress, userAgent)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if !slices.Contains(permissions, permission) {
+		return nil, status.Errorf(codes.PermissionDenied, "insufficient permissions, requires %s", permission)
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// authenticatedStream carries the principal in the stream's context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -18,20 +19,84 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// signingKey is a JWT signing key. Retired keys no longer sign but are
+// accepted until the end of their grace period.
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	sign      any       // HMAC secret or private key, nil for verify-only keys
+	verify    any       // HMAC secret or public key
+	createdAt time.Time // Zero for configured keys
+	retiredAt time.Time // Zero while it is the signing key
+}
+
+func newHMACKey(id string, secret []byte) *signingKey {
+	return &signingKey{id: id, method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
 type JWTHandler struct {
-	secretKey       []byte
+	mu              sync.RWMutex
+	keys            map[string]*signingKey
+	primary         *signingKey
+	keyGrace        time.Duration
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 }
 
-func NewJWTHandler(secretKey string, accessTTL, refreshTTL time.Duration) *JWTHandler {
+// NewJWTHandler creates a handler signing with secretKey, the configured
+// secret. Rotated keys are set with setKeys.
+func NewJWTHandler(secretKey string, accessTTL, refreshTTL, keyGrace time.Duration) *JWTHandler {
+	key := newHMACKey(ConfigKeyID, []byte(secretKey))
 	return &JWTHandler{
-		secretKey:       []byte(secretKey),
+		keys:            map[string]*signingKey{key.id: key},
+		primary:         key,
+		keyGrace:        keyGrace,
 		accessTokenTTL:  accessTTL,
 		refreshTokenTTL: refreshTTL,
 	}
 }
 
+// setKeys replaces the accepted keys; primary signs new tokens
+func (j *JWTHandler) setKeys(keys []*signingKey, primary *signingKey) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.keys = make(map[string]*signingKey, len(keys))
+	for _, key := range keys {
+		j.keys[key.id] = key
+	}
+	j.primary = primary
+}
+
+// expiresAt returns the end of a retired key's grace period
+func (j *JWTHandler) expiresAt(key *signingKey) time.Time {
+	if key.retiredAt.IsZero() {
+		return time.Time{}
+	}
+	return key.retiredAt.Add(j.keyGrace)
+}
+
+// lookupKey returns the key a token names in its kid header. Tokens issued
+// before key rotation have no kid and are checked with the configured secret.
+func (j *JWTHandler) lookupKey(token *jwt.Token) (*signingKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = ConfigKeyID
+	}
+
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	j.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if expires := j.expiresAt(key); !expires.IsZero() && time.Now().After(expires) {
+		return nil, fmt.Errorf("signing key %q expired at %s", kid, expires.Format(time.RFC3339))
+	}
+	return key, nil
+}
+
 // GenerateAccessToken creates a new JWT access token
 func (j *JWTHandler) GenerateAccessToken(userID uuid.UUID, username, role string) (string, error) {
 	now := time.Now()
@@ -46,8 +111,13 @@ func (j *JWTHandler) GenerateAccessToken(userID uuid.UUID, username, role string
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	j.mu.RLock()
+	key := j.primary
+	j.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.sign)
 }
 
 // GenerateRefreshToken creates a cryptographically secure random token
@@ -62,10 +132,15 @@ func (j *JWTHandler) GenerateRefreshToken() (string, error) {
 // ValidateAccessToken validates and parses a JWT access token
 func (j *JWTHandler) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		key, err := j.lookupKey(token)
+		if err != nil {
+			return nil, err
+		}
+		// The key decides the algorithm, never the token
+		if token.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return j.secretKey, nil
+		return key.verify, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// signTestToken signs an access token with a key; an empty kid leaves the
+// header out like tokens issued before key rotation
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, secret any) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(method, JWTClaims{
+		UserID:   uuid.New(),
+		Username: "operator",
+		Role:     "operator",
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestValidateAccessTokenAfterRotation(t *testing.T) {
+	const grace = time.Hour
+	now := time.Now()
+
+	configKey := newHMACKey(ConfigKeyID, []byte("configured-secret"))
+	configKey.retiredAt = now.Add(-2 * grace)
+	retired := newHMACKey("retired", []byte("retired-secret"))
+	retired.retiredAt = now.Add(-grace / 2)
+	primary := newHMACKey("primary", []byte("primary-secret"))
+
+	j := NewJWTHandler("configured-secret", time.Minute, time.Hour, grace)
+	j.setKeys([]*signingKey{configKey, retired, primary}, primary)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"primary key", signTestToken(t, jwt.SigningMethodHS256, "primary", []byte("primary-secret")), false},
+		{"retired key within grace", signTestToken(t, jwt.SigningMethodHS256, "retired", []byte("retired-secret")), false},
+		{"retired key after grace", signTestToken(t, jwt.SigningMethodHS256, ConfigKeyID, []byte("configured-secret")), true},
+		{"no kid falls back to the expired configured secret", signTestToken(t, jwt.SigningMethodHS256, "", []byte("configured-secret")), true},
+		{"unknown kid", signTestToken(t, jwt.SigningMethodHS256, "deleted", []byte("primary-secret")), true},
+		{"wrong secret", signTestToken(t, jwt.SigningMethodHS256, "primary", []byte("retired-secret")), true},
+		{"algorithm other than the key's", signTestToken(t, jwt.SigningMethodHS384, "primary", []byte("primary-secret")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := j.ValidateAccessToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAccessToken() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateAccessTokenUsesPrimary(t *testing.T) {
+	j := NewJWTHandler("configured-secret", time.Minute, time.Hour, time.Hour)
+
+	tests := []struct {
+		name    string
+		primary *signingKey
+		wantKid string
+	}{
+		{"configured secret", nil, ConfigKeyID},
+		{"rotated key", newHMACKey("rotated", []byte("rotated-secret")), "rotated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.primary != nil {
+				configKey := newHMACKey(ConfigKeyID, []byte("configured-secret"))
+				configKey.retiredAt = time.Now()
+				j.setKeys([]*signingKey{configKey, tt.primary}, tt.primary)
+			}
+
+			signed, err := j.GenerateAccessToken(uuid.New(), "operator", "operator")
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			claims, err := j.ValidateAccessToken(signed)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken() error = %v", err)
+			}
+			if claims.Username != "operator" {
+				t.Errorf("username = %q, want operator", claims.Username)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(signed, &JWTClaims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified() error = %v", err)
+			}
+			if kid := token.Header["kid"]; kid != tt.wantKid {
+				t.Errorf("kid = %v, want %s", kid, tt.wantKid)
+			}
+		})
+	}
+}
+
+func TestSigningKeys(t *testing.T) {
+	const grace = time.Hour
+	now := time.Now()
+
+	primary := newHMACKey("primary", []byte("a"))
+	primary.createdAt = now
+	older := newHMACKey("older", []byte("b"))
+	older.retiredAt = now.Add(-grace / 2)
+	newer := newHMACKey("newer", []byte("c"))
+	newer.retiredAt = now.Add(-grace / 4)
+	expired := newHMACKey(ConfigKeyID, []byte("d"))
+	expired.retiredAt = now.Add(-2 * grace)
+
+	a := &AuthService{jwtHandler: NewJWTHandler("d", time.Minute, time.Hour, grace)}
+	a.jwtHandler.setKeys([]*signingKey{expired, older, primary, newer}, primary)
+
+	got := a.SigningKeys()
+	want := []string{"primary", "newer", "older"}
+	if len(got) != len(want) {
+		t.Fatalf("SigningKeys() = %+v, want keys %v", got, want)
+	}
+	for i, key := range got {
+		if key.KeyID != want[i] {
+			t.Errorf("SigningKeys()[%d] = %s, want %s", i, key.KeyID, want[i])
+		}
+		if key.Primary != (i == 0) {
+			t.Errorf("SigningKeys()[%d].Primary = %v", i, key.Primary)
+		}
+	}
+	if exp := got[1].ExpiresAt; exp == nil || !exp.Equal(newer.retiredAt.Add(grace)) {
+		t.Errorf("ExpiresAt of a retired key = %v, want %v", exp, newer.retiredAt.Add(grace))
+	}
+	if got[0].ExpiresAt != nil {
+		t.Errorf("primary key expires at %v", got[0].ExpiresAt)
+	}
+	if a.PrimarySigningKey() != "primary" {
+		t.Errorf("PrimarySigningKey() = %s, want primary", a.PrimarySigningKey())
+	}
+}
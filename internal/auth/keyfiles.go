@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrKeyFiles is returned by RotateSigningKey with asymmetric signing: the
+// keys come from files, rotate by replacing auth.jwt_private_key_file
+var ErrKeyFiles = errors.New("signing keys are configured as files, replace auth.jwt_private_key_file and list the old public key in auth.jwt_previous_public_key_files")
+
+// loadKeyFiles loads the private key and the public keys of replaced
+// private keys of asymmetric signing. The previous keys count as retired when
+// the private key file was last modified, so they expire after the key grace
+// like rotated keys.
+func loadKeyFiles(cfg config.AuthConfig) (primary *signingKey, previous []*signingKey, err error) {
+	data, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	info, err := os.Stat(cfg.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	switch cfg.JWTAlgorithm {
+	case config.JWTAlgorithmRS256:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid RS256 private key %s: %w", cfg.JWTPrivateKeyFile, err)
+		}
+		primary = &signingKey{method: jwt.SigningMethodRS256, sign: private, verify: &private.PublicKey}
+	case config.JWTAlgorithmEdDSA:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid EdDSA private key %s: %w", cfg.JWTPrivateKeyFile, err)
+		}
+		edPrivate, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid EdDSA private key %s: not an Ed25519 key", cfg.JWTPrivateKeyFile)
+		}
+		primary = &signingKey{method: jwt.SigningMethodEdDSA, sign: edPrivate, verify: edPrivate.Public()}
+	default:
+		return nil, nil, fmt.Errorf("algorithm %s doesn't use key files", cfg.JWTAlgorithm)
+	}
+	if primary.id, err = keyThumbprint(primary); err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range cfg.JWTPreviousPublicKeyFiles {
+		key, err := loadPublicKeyFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key.id != primary.id {
+			key.retiredAt = info.ModTime()
+			previous = append(previous, key)
+		}
+	}
+	return primary, previous, nil
+}
+
+// loadPublicKeyFile loads a verify-only RSA or Ed25519 public key
+func loadPublicKeyFile(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+
+	var key *signingKey
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		key = &signingKey{method: jwt.SigningMethodRS256, verify: public}
+	} else if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		key = &signingKey{method: jwt.SigningMethodEdDSA, verify: public}
+	} else {
+		return nil, fmt.Errorf("invalid JWT public key %s: no RSA or Ed25519 public key", file)
+	}
+
+	if key.id, err = keyThumbprint(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// publicJWK returns the public key as JWK (RFC 7517), nil for HMAC keys
+func publicJWK(key *signingKey) map[string]string {
+	switch public := key.verify.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(public),
+		}
+	}
+	return nil
+}
+
+// keyThumbprint is the JWK thumbprint (RFC 7638) of a public key, used as
+// key ID so it is stable across restarts and hosts
+func keyThumbprint(key *signingKey) (string, error) {
+	jwk := publicJWK(key)
+	if jwk == nil {
+		return "", fmt.Errorf("unsupported public key type %T", key.verify)
+	}
+	// encoding/json sorts map keys, which gives the required member order
+	data, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// JWKS returns the public keys tokens are verified with as JWK set, for
+// other services. Empty with HS256, whose secret is never published.
+func (a *AuthService) JWKS() map[string]any {
+	j := a.jwtHandler
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]map[string]string, 0, len(j.keys))
+	for _, key := range j.keys {
+		if expires := j.expiresAt(key); !expires.IsZero() && now.After(expires) {
+			continue
+		}
+		jwk := publicJWK(key)
+		if jwk == nil {
+			continue
+		}
+		jwk["kid"] = key.id
+		jwk["alg"] = key.method.Alg()
+		jwk["use"] = "sig"
+		keys = append(keys, jwk)
+	}
+	return map[string]any{"keys": keys}
+}
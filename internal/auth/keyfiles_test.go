@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// testEdKey is an Ed25519 key pair written as PEM files
+type testEdKey struct {
+	private     ed25519.PrivateKey
+	privateFile string
+	publicFile  string
+}
+
+func writeTestEdKey(t *testing.T, dir, name string) testEdKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := testEdKey{
+		private:     private,
+		privateFile: filepath.Join(dir, name+".pem"),
+		publicFile:  filepath.Join(dir, name+".pub.pem"),
+	}
+	if err := os.WriteFile(key.privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(key.publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeyFileRotation(t *testing.T) {
+	const grace = time.Hour
+	dir := t.TempDir()
+	current := writeTestEdKey(t, dir, "current")
+	old := writeTestEdKey(t, dir, "old")
+
+	tests := []struct {
+		name           string
+		replacedAgo    time.Duration // Age of the private key file
+		previous       []string
+		wantPrevious   int
+		wantOldValid   bool
+		wantJWKSLength int
+	}{
+		{"no previous keys", 0, nil, 0, false, 1},
+		{"previous key within grace", grace / 2, []string{old.publicFile}, 1, true, 2},
+		{"previous key after grace", 2 * grace, []string{old.publicFile}, 1, false, 1},
+		{"current key listed as previous", 0, []string{current.publicFile, old.publicFile}, 1, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modTime := time.Now().Add(-tt.replacedAgo)
+			if err := os.Chtimes(current.privateFile, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.AuthConfig{
+				JWTAlgorithm:              config.JWTAlgorithmEdDSA,
+				JWTPrivateKeyFile:         current.privateFile,
+				JWTPreviousPublicKeyFiles: tt.previous,
+				JWTKeyGrace:               grace,
+				AccessTokenTTL:            time.Minute,
+			}
+
+			primary, previous, err := loadKeyFiles(cfg)
+			if err != nil {
+				t.Fatalf("loadKeyFiles() error = %v", err)
+			}
+			if len(previous) != tt.wantPrevious {
+				t.Fatalf("loadKeyFiles() returned %d previous keys, want %d", len(previous), tt.wantPrevious)
+			}
+			for _, key := range previous {
+				if key.sign != nil {
+					t.Errorf("previous key %s can sign", key.id)
+				}
+				if !key.retiredAt.Equal(modTime) {
+					t.Errorf("previous key retired at %v, want the private key's mtime %v", key.retiredAt, modTime)
+				}
+			}
+
+			a, err := NewAuthService(nil, cfg, nil)
+			if err != nil {
+				t.Fatalf("NewAuthService() error = %v", err)
+			}
+			if a.PrimarySigningKey() != primary.id {
+				t.Errorf("PrimarySigningKey() = %s, want %s", a.PrimarySigningKey(), primary.id)
+			}
+
+			signed, err := a.jwtHandler.GenerateAccessToken(uuid.New(), "operator", "operator")
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error = %v", err)
+			}
+			if _, err := a.jwtHandler.ValidateAccessToken(signed); err != nil {
+				t.Errorf("token of the current key rejected: %v", err)
+			}
+
+			oldKid, err := keyThumbprint(&signingKey{verify: old.private.Public()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldToken := signTestToken(t, jwt.SigningMethodEdDSA, oldKid, old.private)
+			if _, err := a.jwtHandler.ValidateAccessToken(oldToken); (err == nil) != tt.wantOldValid {
+				t.Errorf("token of the old key: error = %v, want valid %v", err, tt.wantOldValid)
+			}
+
+			keys, _ := a.JWKS()["keys"].([]map[string]string)
+			if len(keys) != tt.wantJWKSLength {
+				t.Errorf("JWKS() has %d keys, want %d", len(keys), tt.wantJWKSLength)
+			}
+		})
+	}
+}
+
+func TestLoadKeyFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestEdKey(t, dir, "key")
+
+	tests := []struct {
+		name string
+		cfg  config.AuthConfig
+	}{
+		{"missing private key", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmEdDSA, JWTPrivateKeyFile: filepath.Join(dir, "missing.pem")}},
+		{"algorithm doesn't match the key", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmRS256, JWTPrivateKeyFile: key.privateFile}},
+		{"public key as private key", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmEdDSA, JWTPrivateKeyFile: key.publicFile}},
+		{"missing previous key", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmEdDSA, JWTPrivateKeyFile: key.privateFile, JWTPreviousPublicKeyFiles: []string{filepath.Join(dir, "missing.pub.pem")}}},
+		{"private key as previous key", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmEdDSA, JWTPrivateKeyFile: key.privateFile, JWTPreviousPublicKeyFiles: []string{key.privateFile}}},
+		{"HMAC algorithm", config.AuthConfig{JWTAlgorithm: config.JWTAlgorithmHS256, JWTPrivateKeyFile: key.privateFile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := loadKeyFiles(tt.cfg); err == nil {
+				t.Error("loadKeyFiles() succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// ConfigKeyID is the key ID of the secret from auth.jwt_secret_env
+const ConfigKeyID = "config"
+
+// AuditJWTKeyRotated is recorded in the audit log on every rotation
+const AuditJWTKeyRotated = "auth.jwt_key_rotated"
+
+// SigningKey describes an accepted JWT signing key without its secret
+type SigningKey struct {
+	KeyID     string     `json:"key_id"`
+	Algorithm string     `json:"algorithm"`
+	Primary   bool       `json:"primary"`              // Signs new tokens
+	CreatedAt *time.Time `json:"created_at,omitempty"` // Nil for the configured secret
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // End of the grace period of a retired key
+}
+
+// LoadSigningKeys loads the rotated signing keys. Keys retired longer than
+// the grace period ago are deleted. Without rotated keys, or if they can't
+// be decrypted, the configured secret keeps signing. Asymmetric keys come
+// from files and aren't rotated here.
+func (a *AuthService) LoadSigningKeys(ctx context.Context) error {
+	if a.keyFiles {
+		return nil // Loaded from files by NewAuthService
+	}
+
+	if _, err := a.storage.DeleteRetiredJWTSigningKeys(ctx, time.Now().Add(-a.jwtHandler.keyGrace)); err != nil {
+		return err
+	}
+
+	stored, err := a.storage.ListJWTSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	configKey := newHMACKey(ConfigKeyID, []byte(a.jwtSecret))
+	keys := []*signingKey{configKey}
+	primary := configKey
+
+	for _, s := range stored {
+		if s.KeyID == ConfigKeyID {
+			if s.RetiredAt != nil {
+				configKey.retiredAt = *s.RetiredAt
+			}
+			continue
+		}
+
+		if a.keyring == nil {
+			return fmt.Errorf("signing key %s: %w", s.KeyID, secrets.ErrNoMasterKey)
+		}
+		secret, err := a.keyring.Open(s.MasterKeyID, s.EncryptedKey, s.Ciphertext, signingKeyAAD(s.KeyID))
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", s.KeyID, err)
+		}
+
+		key := newHMACKey(s.KeyID, secret)
+		key.createdAt = s.CreatedAt
+		if s.RetiredAt != nil {
+			key.retiredAt = *s.RetiredAt
+		} else {
+			primary = key
+		}
+		keys = append(keys, key)
+	}
+
+	// A primary that can't be found (e.g. deleted by hand) falls back to the
+	// configured secret, which must then be accepted again
+	if primary == configKey {
+		configKey.retiredAt = time.Time{}
+	}
+
+	a.jwtHandler.setKeys(keys, primary)
+	return nil
+}
+
+// RotateSigningKey generates a new signing key. Tokens signed with the
+// previous key stay valid for auth.jwt_key_grace, so sessions survive the
+// rotation. Needs the master key, which encrypts the stored keys.
+func (a *AuthService) RotateSigningKey(ctx context.Context, actor string) (*SigningKey, error) {
+	if a.keyFiles {
+		return nil, ErrKeyFiles
+	}
+	if a.keyring == nil {
+		return nil, secrets.ErrNoMasterKey
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	keyID := hex.EncodeToString(id)
+
+	encryptedKey, ciphertext, err := a.keyring.Seal(secret, signingKeyAAD(keyID))
+	if err != nil {
+		return nil, err
+	}
+	stored := &storage.JWTSigningKey{
+		KeyID:        keyID,
+		MasterKeyID:  a.keyring.KeyID(),
+		EncryptedKey: encryptedKey,
+		Ciphertext:   ciphertext,
+		CreatedBy:    actor,
+	}
+	if err := a.storage.RotateJWTSigningKey(ctx, stored, ConfigKeyID); err != nil {
+		return nil, err
+	}
+
+	previous := a.PrimarySigningKey()
+	if err := a.LoadSigningKeys(ctx); err != nil {
+		return nil, fmt.Errorf("rotated, but failed to reload signing keys: %w", err)
+	}
+
+	entry := &storage.AuditEntry{
+		Action: AuditJWTKeyRotated,
+		Actor:  actor,
+		Details: map[string]any{
+			"key_id":          keyID,
+			"previous_key_id": previous,
+		},
+	}
+	_ = a.storage.RecordAudit(ctx, entry)
+
+	for _, key := range a.SigningKeys() {
+		if key.KeyID == keyID {
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("signing key %s not loaded", keyID)
+}
+
+// PrimarySigningKey returns the ID of the key signing new tokens
+func (a *AuthService) PrimarySigningKey() string {
+	a.jwtHandler.mu.RLock()
+	defer a.jwtHandler.mu.RUnlock()
+	return a.jwtHandler.primary.id
+}
+
+// SigningKeys lists the accepted signing keys, the primary key first
+func (a *AuthService) SigningKeys() []SigningKey {
+	j := a.jwtHandler
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	now := time.Now()
+	list := make([]SigningKey, 0, len(j.keys))
+	for _, key := range j.keys {
+		info := SigningKey{KeyID: key.id, Algorithm: key.method.Alg(), Primary: key == j.primary}
+		if !key.createdAt.IsZero() {
+			info.CreatedAt = &key.createdAt
+		}
+		if !key.retiredAt.IsZero() {
+			expires := j.expiresAt(key)
+			if now.After(expires) {
+				continue
+			}
+			info.RetiredAt = &key.retiredAt
+			info.ExpiresAt = &expires
+		}
+		list = append(list, info)
+	}
+
+	// Primary first, then the most recently retired
+	slices.SortFunc(list, func(x, y SigningKey) int {
+		switch {
+		case x.Primary != y.Primary:
+			if x.Primary {
+				return -1
+			}
+			return 1
+		case x.RetiredAt == nil || y.RetiredAt == nil:
+			return 0
+		default:
+			return y.RetiredAt.Compare(*x.RetiredAt)
+		}
+	})
+	return list
+}
+
+// signingKeyAAD binds an encrypted signing key to its key ID
+func signingKeyAAD(keyID string) []byte {
+	return []byte("jwt-key:" + keyID)
+}
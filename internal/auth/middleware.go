@@ -52,7 +52,7 @@ func (a *AuthService) AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Fall back to machine token (no user_id for machine tokens)
-		permissions, err := a.ValidateMachineToken(c.Request.Context(), token, ipAddress, userAgent)
+		machineToken, permissions, err := a.AuthenticateMachineToken(c.Request.Context(), token, ipAddress, userAgent)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "invalid or expired token",
@@ -63,6 +63,8 @@ func (a *AuthService) AuthMiddleware() gin.HandlerFunc {
 
 		// Store permissions in context (machine tokens don't have user_id)
 		c.Set("permissions", permissions)
+		c.Set("machine_token_id", machineToken.ID.String())
+		c.Set("machine_token_name", machineToken.Name)
 		c.Next()
 	}
 }
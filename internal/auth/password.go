@@ -4,9 +4,12 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"runtime"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -98,3 +101,46 @@ func (ph *PasswordHasher) VerifyPassword(password, encodedHash string) (bool, er
 
 	return false, nil
 }
+
+// MinPasswordLength is the minimum length of a strong password
+const MinPasswordLength = 12
+
+// ErrWeakPassword is wrapped by CheckPasswordStrength
+var ErrWeakPassword = errors.New("password too weak")
+
+// CheckPasswordStrength requires MinPasswordLength characters from at least
+// three of lower case, upper case, digits and symbols, not containing the
+// username
+func CheckPasswordStrength(username, password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, MinPasswordLength)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return fmt.Errorf("%w: use at least three of lower case, upper case, digits and symbols", ErrWeakPassword)
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("%w: must not contain the username", ErrWeakPassword)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/google/uuid"
 )
@@ -25,17 +26,38 @@ type AuthService struct {
 	jwtHandler      *JWTHandler
 	passwordHasher  *PasswordHasher
 	machineTokenGen *MachineTokenGenerator
+	keyring         *secrets.Keyring // Encrypts rotated signing keys, nil without master key
+	jwtSecret       string
+	weakSecret      bool // The configured secret isn't production ready
+	keyFiles        bool // Asymmetric keys from files instead of HMAC secrets
 }
 
-func NewAuthService(store *storage.PostgresClient, cfg config.AuthConfig) *AuthService {
+// NewAuthService signs with the configured secret until LoadSigningKeys
+// loads rotated keys. With RS256 or EdDSA it signs with the private key
+// file; HMAC tokens are no longer accepted then.
+func NewAuthService(store *storage.PostgresClient, cfg config.AuthConfig, keyring *secrets.Keyring) (*AuthService, error) {
 	jwtSecret := cfg.GetJWTSecret()
 
-	return &AuthService{
+	a := &AuthService{
 		storage:         store,
-		jwtHandler:      NewJWTHandler(jwtSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL),
+		keyring:         keyring,
+		jwtSecret:       jwtSecret,
+		weakSecret:      !cfg.IsProductionReady(),
+		jwtHandler:      NewJWTHandler(jwtSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cfg.JWTKeyGrace),
 		passwordHasher:  NewPasswordHasher(),
 		machineTokenGen: NewMachineTokenGenerator(),
+		keyFiles:        cfg.Asymmetric(),
 	}
+
+	if a.keyFiles {
+		primary, previous, err := loadKeyFiles(cfg)
+		if err != nil {
+			return nil, err
+		}
+		a.jwtHandler.setKeys(append(previous, primary), primary)
+	}
+
+	return a, nil
 }
 
 // LoginUser authenticates a user and returns tokens
@@ -89,15 +111,22 @@ func (a *AuthService) LoginUser(ctx context.Context, username, password, ipAddre
 
 // ValidateMachineToken validates a machine token and returns permissions
 func (a *AuthService) ValidateMachineToken(ctx context.Context, token, ipAddress, userAgent string) ([]Permission, error) {
+	_, permissions, err := a.AuthenticateMachineToken(ctx, token, ipAddress, userAgent)
+	return permissions, err
+}
+
+// AuthenticateMachineToken validates a machine token and returns the token
+// record along with its permissions
+func (a *AuthService) AuthenticateMachineToken(ctx context.Context, token, ipAddress, userAgent string) (*storage.MachineToken, []Permission, error) {
 	if !a.machineTokenGen.ValidateTokenFormat(token) {
-		return nil, fmt.Errorf("invalid token format")
+		return nil, nil, fmt.Errorf("invalid token format")
 	}
 
 	tokenHash := a.machineTokenGen.HashToken(token)
 	machineToken, err := a.storage.GetMachineTokenByHash(ctx, tokenHash)
 	if err != nil {
 		a.logAuthEvent(ctx, "machine_token_failed", nil, nil, ipAddress, userAgent, false, "token not found")
-		return nil, fmt.Errorf("invalid token")
+		return nil, nil, fmt.Errorf("invalid token")
 	}
 
 	// Update last used
@@ -109,18 +138,29 @@ func (a *AuthService) ValidateMachineToken(ctx context.Context, token, ipAddress
 		permissions[i] = Permission(p)
 	}
 
-	return permissions, nil
+	return machineToken, permissions, nil
 }
 
 // ValidateToken validates any token (JWT or Machine Token)
 func (a *AuthService) ValidateToken(ctx context.Context, token, ipAddress, userAgent string) ([]Permission, error) {
+	_, permissions, err := a.AuthenticateToken(ctx, token, ipAddress, userAgent)
+	return permissions, err
+}
+
+// AuthenticateToken validates any token like AuthMiddleware and returns its
+// caller along with the permissions
+func (a *AuthService) AuthenticateToken(ctx context.Context, token, ipAddress, userAgent string) (Principal, []Permission, error) {
 	// Try JWT first
 	if claims, err := a.jwtHandler.ValidateAccessToken(token); err == nil {
-		return a.roleToPermissions(claims.Role), nil
+		return Principal{Username: claims.Username, Role: claims.Role}, a.roleToPermissions(claims.Role), nil
 	}
 
 	// Try Machine Token
-	return a.ValidateMachineToken(ctx, token, ipAddress, userAgent)
+	machineToken, permissions, err := a.AuthenticateMachineToken(ctx, token, ipAddress, userAgent)
+	if err != nil {
+		return Principal{}, nil, err
+	}
+	return Principal{TokenName: machineToken.Name}, permissions, nil
 }
 
 func (a *AuthService) roleToPermissions(role string) []Permission {
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// Audit actions of the first-run setup
+const (
+	AuditSetupAdminCreated = "setup.admin_created"
+	AuditSetupCompleted    = "setup.completed"
+)
+
+// Sources of the key signing new tokens, see SetupStatus
+const (
+	SigningSourceConfig   = "config"    // Secret from auth.jwt_secret_env
+	SigningSourceStored   = "stored"    // Generated key, stored encrypted with the master key
+	SigningSourceKeyFiles = "key_files" // RS256/EdDSA private key file
+)
+
+// ErrWeakSigningKey is returned by CompleteSetup while tokens are signed
+// with the development or a short secret
+var ErrWeakSigningKey = errors.New("tokens are signed with an insecure secret, set auth.jwt_secret_env to at least 32 characters or store a generated key")
+
+// ErrNoAdmin is returned by CompleteSetup before the initial admin exists
+var ErrNoAdmin = errors.New("create the initial admin first")
+
+// SetupStatus describes the first-run setup
+type SetupStatus struct {
+	Completed     bool                `json:"completed"`
+	State         *storage.SetupState `json:"state,omitempty"`
+	AdminCreated  bool                `json:"admin_created"`
+	SigningSource string              `json:"signing_source"`
+	SigningSecure bool                `json:"signing_secure"`
+	MasterKey     bool                `json:"master_key"` // Needed to store a generated signing key
+}
+
+// SetupStatus returns the state of the first-run setup
+func (a *AuthService) SetupStatus(ctx context.Context) (*SetupStatus, error) {
+	state, err := a.storage.GetSetupState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := a.storage.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	source, secure := a.signingSource()
+	return &SetupStatus{
+		Completed:     state != nil,
+		State:         state,
+		AdminCreated:  users > 0,
+		SigningSource: source,
+		SigningSecure: secure,
+		MasterKey:     a.keyring != nil,
+	}, nil
+}
+
+// signingSource returns where the primary signing key comes from and
+// whether it is safe for production
+func (a *AuthService) signingSource() (source string, secure bool) {
+	switch {
+	case a.keyFiles:
+		return SigningSourceKeyFiles, true
+	case a.PrimarySigningKey() != ConfigKeyID:
+		return SigningSourceStored, true
+	default:
+		return SigningSourceConfig, !a.weakSecret
+	}
+}
+
+// CreateInitialAdmin creates the first admin while no user exists and setup
+// is pending. The password must pass CheckPasswordStrength.
+func (a *AuthService) CreateInitialAdmin(ctx context.Context, username, password, actor string) (*storage.User, error) {
+	if err := CheckPasswordStrength(username, password); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := a.passwordHasher.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, err := a.storage.CreateFirstUser(ctx, username, passwordHash, string(PermAdmin))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &storage.AuditEntry{
+		Action:  AuditSetupAdminCreated,
+		Actor:   actor,
+		Details: map[string]any{"user_id": user.ID, "username": user.Username},
+	}
+	_ = a.storage.RecordAudit(ctx, entry)
+
+	return user, nil
+}
+
+// CompleteSetup marks the first-run setup complete, which disables the
+// setup endpoints. Requires an admin and a secure signing key.
+func (a *AuthService) CompleteSetup(ctx context.Context, actor string) (*storage.SetupState, error) {
+	users, err := a.storage.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if users == 0 {
+		return nil, ErrNoAdmin
+	}
+	if _, secure := a.signingSource(); !secure {
+		return nil, ErrWeakSigningKey
+	}
+
+	state, err := a.storage.CompleteSetup(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	source, _ := a.signingSource()
+	entry := &storage.AuditEntry{
+		Action:  AuditSetupCompleted,
+		Actor:   actor,
+		Details: map[string]any{"signing_source": source},
+	}
+	_ = a.storage.RecordAudit(ctx, entry)
+
+	return state, nil
+}
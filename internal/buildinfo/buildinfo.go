@@ -0,0 +1,57 @@
+// Package buildinfo holds the version of the running binary, set at build
+// time with -ldflags "-X github.com/KevinKickass/OpenMachineCore/internal/buildinfo.Version=..."
+package buildinfo
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Set by the linker, see the Makefile
+var (
+	Version   = "0.1.0"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the build of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a working tree with changes
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information. Commit and build time fall back to the
+// VCS stamp of the Go toolchain if not set by the linker.
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		info.GoVersion = bi.GoVersion
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	})
+	return info
+}
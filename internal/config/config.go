@@ -1,25 +1,139 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	Modbus   ModbusConfig   `mapstructure:"modbus"`
-	Devices  DevicesConfig  `mapstructure:"device_profiles"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Modbus      ModbusConfig      `mapstructure:"modbus"`
+	Devices     DevicesConfig     `mapstructure:"device_profiles"`
+	Heartbeat   HeartbeatConfig   `mapstructure:"heartbeat"`
+	Resources   ResourcesConfig   `mapstructure:"resources"`
+	SafeState   SafeStateConfig   `mapstructure:"safe_state"`
+	Manual      ManualConfig      `mapstructure:"manual_control"`
+	Onboarding  OnboardingConfig  `mapstructure:"onboarding"`
+	Lint        LintConfig        `mapstructure:"lint"`
+	Validation  ValidationConfig  `mapstructure:"validation"`
+	Limits      LimitsConfig      `mapstructure:"limits"`
+	Usage       UsageConfig       `mapstructure:"usage"`
+	Alarms      AlarmsConfig      `mapstructure:"alarms"`
+	Shifts      ShiftsConfig      `mapstructure:"shifts"`
+	Counters    CountersConfig    `mapstructure:"counters"`
+	Snapshots   SnapshotsConfig   `mapstructure:"snapshots"`
+	Faults      FaultsConfig      `mapstructure:"fault_injection"`
+	Secrets     SecretsConfig     `mapstructure:"secrets"`
+	ExecLogs    ExecLogsConfig    `mapstructure:"execution_logs"`
+	Checkpoints CheckpointsConfig `mapstructure:"checkpoints"`
+	Outbox      OutboxConfig      `mapstructure:"outbox"`
+	Routing     RoutingConfig     `mapstructure:"routing"`
+	StepHooks   []StepHookConfig  `mapstructure:"step_hooks"`
 }
 
 type ServerConfig struct {
-	GRPCPort        int           `mapstructure:"grpc_port"`
-	HTTPPort        int           `mapstructure:"http_port"`
-	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	GRPCPort        int                         `mapstructure:"grpc_port"`
+	HTTPPort        int                         `mapstructure:"http_port"`
+	GRPCBind        string                      `mapstructure:"grpc_bind"`   // Listen address, empty = all interfaces
+	HTTPBind        string                      `mapstructure:"http_bind"`   // Listen address, empty = all interfaces
+	GRPCSocket      string                      `mapstructure:"grpc_socket"` // Unix socket path, replaces grpc_bind and grpc_port
+	HTTPSocket      string                      `mapstructure:"http_socket"` // Unix socket path, replaces http_bind and http_port
+	SocketMode      string                      `mapstructure:"socket_mode"` // Octal permissions of the unix sockets
+	ShutdownTimeout time.Duration               `mapstructure:"shutdown_timeout"`
+	RouteLimits     map[string]RouteLimitConfig `mapstructure:"route_limits"` // Keyed by route group, "default" for the rest
+	GRPC            GRPCConfig                  `mapstructure:"grpc"`
+	WebSocket       WebSocketConfig             `mapstructure:"websocket"`
+}
+
+// HTTPListen returns the network and address the REST server listens on
+func (s ServerConfig) HTTPListen() (network, address string) {
+	return listenAddress(s.HTTPSocket, s.HTTPBind, s.HTTPPort)
+}
+
+// GRPCListen returns the network and address the gRPC server listens on
+func (s ServerConfig) GRPCListen() (network, address string) {
+	return listenAddress(s.GRPCSocket, s.GRPCBind, s.GRPCPort)
+}
+
+func listenAddress(socket, bind string, port int) (string, string) {
+	if socket != "" {
+		return "unix", socket
+	}
+	return "tcp", net.JoinHostPort(bind, strconv.Itoa(port))
+}
+
+// SocketFileMode returns socket_mode as file permissions, 0660 if unset
+func (s ServerConfig) SocketFileMode() (os.FileMode, error) {
+	if s.SocketMode == "" {
+		return 0o660, nil
+	}
+	mode, err := strconv.ParseUint(s.SocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("expected octal permissions like 0660, got %q", s.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// WebSocketConfig tunes the live WebSocket
+type WebSocketConfig struct {
+	ReplayBuffer int `mapstructure:"replay_buffer"` // Messages kept per topic for reconnecting clients, 0 = no replay
+	CommandQueue int `mapstructure:"command_queue"` // Control commands waiting for execution, further ones are rejected
+}
+
+// GRPCConfig tunes the gRPC server
+type GRPCConfig struct {
+	Reflection     bool                `mapstructure:"reflection"`        // Register the reflection service (grpcurl)
+	MaxRecvMsgSize int                 `mapstructure:"max_recv_msg_size"` // Bytes, 0 = gRPC default (4 MiB)
+	MaxSendMsgSize int                 `mapstructure:"max_send_msg_size"` // Bytes, 0 = gRPC default
+	Keepalive      GRPCKeepaliveConfig `mapstructure:"keepalive"`
+	EventStream    EventStreamConfig   `mapstructure:"event_stream"`
+}
+
+// EventStreamConfig buffers the execution events of a streaming gRPC client
+type EventStreamConfig struct {
+	Buffer       int           `mapstructure:"buffer"`        // Events per client
+	Policy       string        `mapstructure:"policy"`        // Full buffer: drop_newest, drop_oldest, block or disconnect
+	BlockTimeout time.Duration `mapstructure:"block_timeout"` // Longest wait for room with policy block
+}
+
+type GRPCKeepaliveConfig struct {
+	Time                  time.Duration `mapstructure:"time"`                     // Ping idle clients after this
+	Timeout               time.Duration `mapstructure:"timeout"`                  // Close if ping is not acked
+	MaxConnectionIdle     time.Duration `mapstructure:"max_connection_idle"`      // 0 = infinite
+	MaxConnectionAge      time.Duration `mapstructure:"max_connection_age"`       // 0 = infinite
+	MaxConnectionAgeGrace time.Duration `mapstructure:"max_connection_age_grace"` // 0 = infinite
+	MinTime               time.Duration `mapstructure:"min_time"`                 // Minimum client ping interval
+	PermitWithoutStream   bool          `mapstructure:"permit_without_stream"`    // Allow client pings without active streams
+}
+
+// RouteLimitConfig bounds request bodies and handler time for a route group (0 = unlimited)
+type RouteLimitConfig struct {
+	MaxBodyBytes int64         `mapstructure:"max_body_bytes"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+}
+
+// RouteLimit returns the limits of a route group, falling back to "default"
+// for unset values
+func (s *ServerConfig) RouteLimit(group string) RouteLimitConfig {
+	limit := s.RouteLimits[group]
+	fallback := s.RouteLimits["default"]
+	if limit.MaxBodyBytes == 0 {
+		limit.MaxBodyBytes = fallback.MaxBodyBytes
+	}
+	if limit.Timeout == 0 {
+		limit.Timeout = fallback.Timeout
+	}
+	return limit
 }
 
 type DatabaseConfig struct {
@@ -27,7 +141,7 @@ type DatabaseConfig struct {
 	Port           int    `mapstructure:"port"`
 	Database       string `mapstructure:"database"`
 	User           string `mapstructure:"user"`
-	Password       string `mapstructure:"password"`
+	Password       string `mapstructure:"password"` // Plaintext or "enc:v1:..." (-encrypt-secret)
 	MaxConnections int    `mapstructure:"max_connections"`
 }
 
@@ -38,15 +152,337 @@ type AuthConfig struct {
 	RefreshTokenTTL        time.Duration `mapstructure:"refresh_token_ttl"`
 	MaxFailedLoginAttempts int           `mapstructure:"max_failed_login_attempts"`
 	AccountLockDuration    time.Duration `mapstructure:"account_lock_duration"`
+	JWTKeyGrace            time.Duration `mapstructure:"jwt_key_grace"` // Retired signing keys are accepted this long after a rotation
+
+	// Asymmetric signing lets other services verify tokens with the public
+	// keys from /.well-known/jwks.json instead of sharing the HMAC secret
+	JWTAlgorithm              string   `mapstructure:"jwt_algorithm"`                 // "HS256" (default), "RS256" or "EdDSA"
+	JWTPrivateKeyFile         string   `mapstructure:"jwt_private_key_file"`          // PEM, required for RS256 and EdDSA
+	JWTPreviousPublicKeyFiles []string `mapstructure:"jwt_previous_public_key_files"` // PEM, accepted for JWTKeyGrace after replacing the private key
+}
+
+// JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// Asymmetric reports whether tokens are signed with a private key
+func (a *AuthConfig) Asymmetric() bool {
+	return a.JWTAlgorithm == JWTAlgorithmRS256 || a.JWTAlgorithm == JWTAlgorithmEdDSA
 }
 
 type ModbusConfig struct {
 	DefaultTimeout      time.Duration `mapstructure:"default_timeout"`
 	DefaultPollInterval time.Duration `mapstructure:"default_poll_interval"`
+	StaleAfter          time.Duration `mapstructure:"stale_after"`         // Cached values older than this have quality "stale", 0 = never
+	ReconnectMinDelay   time.Duration `mapstructure:"reconnect_min_delay"` // First wait before dialing a lost connection again, doubled per failed attempt
+	ReconnectMaxDelay   time.Duration `mapstructure:"reconnect_max_delay"` // Longest wait between reconnect attempts
 }
 
+// DevicesConfig lists where device profiles are searched. Relative paths are
+// resolved with ResolvePath; the system profile directory is always searched last.
 type DevicesConfig struct {
-	SearchPaths []string `mapstructure:"search_paths"`
+	SearchPaths []string             `mapstructure:"search_paths"`
+	Watch       bool                 `mapstructure:"watch"` // Reload changed descriptors without a restart
+	Sync        DescriptorSyncConfig `mapstructure:"sync"`
+	Startup     DeviceStartupConfig  `mapstructure:"startup"`
+}
+
+// DeviceStartupConfig controls loading the stored devices at startup
+type DeviceStartupConfig struct {
+	Concurrency   int           `mapstructure:"concurrency"`    // Devices composed and connected at once
+	RetryInterval time.Duration `mapstructure:"retry_interval"` // Background retry of devices that failed to connect, 0 = no retry
+}
+
+// DescriptorSyncConfig pulls vendor descriptor packs from a remote index into
+// a managed directory, which is searched after search_paths
+type DescriptorSyncConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	Repository  string            `mapstructure:"repository"`   // URL of the pack index (index.json)
+	Directory   string            `mapstructure:"directory"`    // Managed search path, installed packs only
+	Interval    time.Duration     `mapstructure:"interval"`     // Update check interval, 0 = only on request
+	Timeout     time.Duration     `mapstructure:"timeout"`      // Per index or pack download
+	AutoInstall bool              `mapstructure:"auto_install"` // Install updates found by the periodic check
+	Pins        map[string]string `mapstructure:"pins"`         // Vendor -> exact pack version
+}
+
+// HeartbeatConfig configures the watchdog bit toggled on a device output
+type HeartbeatConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Device           string        `mapstructure:"device"`   // Device instance ID
+	Register         string        `mapstructure:"register"` // Logical name or register name
+	Interval         time.Duration `mapstructure:"interval"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failures before unhealthy
+}
+
+// ResourcesConfig configures the self-monitoring of the server's resource
+// usage. Thresholds of 0 are not monitored.
+type ResourcesConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`
+	MaxGoroutines    int           `mapstructure:"max_goroutines"`
+	MaxMemoryMB      int           `mapstructure:"max_memory_mb"`     // Memory obtained from the OS
+	MaxDBPoolUsage   float64       `mapstructure:"max_db_pool_usage"` // Acquired / max connections, 0-1
+	MaxWSClients     int           `mapstructure:"max_ws_clients"`
+	RejectExecutions bool          `mapstructure:"reject_executions"` // While goroutines, memory or the DB pool are above their threshold
+	ShedWSClients    bool          `mapstructure:"shed_ws_clients"`   // Disconnect the newest clients above max_ws_clients
+}
+
+// SafeStateConfig selects when outputs are driven to their safe states
+type SafeStateConfig struct {
+	OnShutdown    bool          `mapstructure:"on_shutdown"`
+	OnStopFailure bool          `mapstructure:"on_stop_failure"`
+	OnCancel      bool          `mapstructure:"on_cancel"`
+	WriteTimeout  time.Duration `mapstructure:"write_timeout"`
+	CancelTimeout time.Duration `mapstructure:"cancel_timeout"` // Longest wait on shutdown for cancelled executions to stop
+}
+
+// ManualConfig configures the dead-man switch of manual control sessions:
+// outputs written in a session return to their safe states when its
+// heartbeats stop
+type ManualConfig struct {
+	HeartbeatTimeout time.Duration `mapstructure:"heartbeat_timeout"` // Session expires without a heartbeat for this long
+	RequireSession   bool          `mapstructure:"require_session"`   // Reject manual device writes outside a session
+	MaxSessions      int           `mapstructure:"max_sessions"`
+}
+
+// OnboardingConfig configures the guided commissioning of new devices
+type OnboardingConfig struct {
+	SessionTimeout time.Duration `mapstructure:"session_timeout"` // Onboardings are cancelled after this long without activity
+	CheckTimeout   time.Duration `mapstructure:"check_timeout"`   // An I/O check without answer fails after this long
+	BlinkInterval  time.Duration `mapstructure:"blink_interval"`  // Half period of blinking outputs
+	MaxSessions    int           `mapstructure:"max_sessions"`
+}
+
+// LimitsConfig bounds the JSON payloads stored per execution (bytes, 0 = unlimited)
+type LimitsConfig struct {
+	MaxInputBytes     int `mapstructure:"max_input_bytes"`     // Execution input, rejected if larger
+	MaxParameterBytes int `mapstructure:"max_parameter_bytes"` // Parameters per step, rejected if larger
+	MaxOutputBytes    int `mapstructure:"max_output_bytes"`    // Output per step, truncated if larger
+	MaxCallDepth      int `mapstructure:"max_call_depth"`      // Sub-workflow nesting at runtime, deeper calls fail
+	MaxLoopJumps      int `mapstructure:"max_loop_jumps"`      // Jumps back per transition without max_jumps, more fail
+}
+
+// UsageConfig controls per-principal API usage accounting and quotas
+type UsageConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // How often counters are persisted
+	Quotas        []QuotaConfig `mapstructure:"quotas"`
+}
+
+// QuotaConfig limits the requests a single user or machine token may make
+// to an endpoint class within a fixed window
+type QuotaConfig struct {
+	Name          string        `mapstructure:"name"`
+	EndpointClass string        `mapstructure:"endpoint_class"` // e.g. "execution_start", "devices"
+	Principal     string        `mapstructure:"principal"`      // "user", "machine_token" or empty for both
+	Limit         int           `mapstructure:"limit"`
+	Window        time.Duration `mapstructure:"window"`
+}
+
+// checkQuotas rejects quotas that would never apply or count into another
+// quota's windows, which are kept per quota name
+func (u UsageConfig) checkQuotas() error {
+	var errs []error
+	names := make(map[string]bool, len(u.Quotas))
+	for i, q := range u.Quotas {
+		key := fmt.Sprintf("usage.quotas[%d]", i)
+		switch {
+		case q.Name == "":
+			errs = append(errs, fmt.Errorf("%s.name: required", key))
+		case names[q.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: duplicate quota %q", key, q.Name))
+		}
+		names[q.Name] = true
+
+		if q.EndpointClass == "" {
+			errs = append(errs, fmt.Errorf("%s.endpoint_class: required", key))
+		}
+		if q.Principal != "" && q.Principal != "user" && q.Principal != "machine_token" {
+			errs = append(errs, fmt.Errorf("%s.principal: must be user, machine_token or empty, got %q", key, q.Principal))
+		}
+		if q.Limit < 1 {
+			errs = append(errs, fmt.Errorf("%s.limit: must be at least 1, got %d", key, q.Limit))
+		}
+		if q.Window <= 0 {
+			errs = append(errs, fmt.Errorf("%s.window: must be positive, got %s", key, q.Window))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AlarmsConfig controls the alarm engine
+type AlarmsConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	ScanInterval time.Duration `mapstructure:"scan_interval"` // How often register conditions are evaluated
+}
+
+// ShiftsConfig controls the shift calendar automation of the machine
+type ShiftsConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Timezone      string        `mapstructure:"timezone"`       // IANA name or "Local"; shift times are in this zone
+	CheckInterval time.Duration `mapstructure:"check_interval"` // How often shift boundaries are checked
+	AutoHome      bool          `mapstructure:"auto_home"`      // Home a stopped machine at shift begin
+	AutoStart     bool          `mapstructure:"auto_start"`     // Start production at shift begin once ready
+	AutoStop      bool          `mapstructure:"auto_stop"`      // Run the stop workflow at shift end
+}
+
+// CountersConfig controls the accumulation of rolling device counters
+type CountersConfig struct {
+	Enabled         bool            `mapstructure:"enabled"`
+	SampleInterval  time.Duration   `mapstructure:"sample_interval"`  // How often counter registers are sampled
+	PersistInterval time.Duration   `mapstructure:"persist_interval"` // How often totals are stored and added to the history
+	RateWindow      time.Duration   `mapstructure:"rate_window"`      // Period the current rate is averaged over
+	Counters        []CounterConfig `mapstructure:"counters"`
+}
+
+// CounterConfig maps a counter register to a monotonic total
+type CounterConfig struct {
+	Name     string  `mapstructure:"name"`
+	Device   string  `mapstructure:"device"`   // Device instance ID
+	Register string  `mapstructure:"register"` // Logical name or register name
+	Rollover float64 `mapstructure:"rollover"` // Value the counter wraps at, 0 = range of the register data type
+	Unit     string  `mapstructure:"unit"`
+}
+
+// SnapshotsConfig controls periodic snapshots of the cached device values
+type SnapshotsConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`
+	Retention time.Duration `mapstructure:"retention"` // Older snapshots are deleted, 0 = keep forever
+	Registers []string      `mapstructure:"registers"` // Register name patterns (path.Match), empty = all
+}
+
+// ExecLogsConfig controls the log lines captured per workflow execution
+type ExecLogsConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Level      string `mapstructure:"level"`       // Lowest captured level: debug, info, warn, error
+	MaxEntries int    `mapstructure:"max_entries"` // Per execution, further lines are dropped
+
+	TraceMaxFrames     int `mapstructure:"trace_max_frames"`      // Traced Modbus frames per execution, 0 = unlimited
+	TraceMaxFrameBytes int `mapstructure:"trace_max_frame_bytes"` // Longer frames are truncated in the trace, 0 = unlimited
+}
+
+// CheckpointsConfig controls saving the state of running executions so they
+// can be resumed after a crash
+type CheckpointsConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Interval        time.Duration `mapstructure:"interval"`          // Minimum time between checkpoints of an execution
+	ResumeOnStartup bool          `mapstructure:"resume_on_startup"` // Resume interrupted executions, otherwise mark them failed
+}
+
+// OutboxConfig controls the delivery of workflow, machine and device events
+// to integration sinks. Events are stored first and delivered at least once.
+type OutboxConfig struct {
+	Enabled      bool               `mapstructure:"enabled"`
+	PollInterval time.Duration      `mapstructure:"poll_interval"` // How often sinks check for new events
+	BatchSize    int                `mapstructure:"batch_size"`    // Events read per poll and sink
+	MaxAttempts  int                `mapstructure:"max_attempts"`  // Failed deliveries are dead-lettered after this many attempts
+	RetryBackoff time.Duration      `mapstructure:"retry_backoff"` // Delay before the first retry, doubled per attempt
+	MaxBackoff   time.Duration      `mapstructure:"max_backoff"`
+	Retention    time.Duration      `mapstructure:"retention"` // Delivered events older than this are deleted
+	Sinks        []OutboxSinkConfig `mapstructure:"sinks"`
+}
+
+// OutboxSinkConfig is a destination of outbox events
+type OutboxSinkConfig struct {
+	Name          string            `mapstructure:"name"`
+	Type          string            `mapstructure:"type"` // "webhook"
+	URL           string            `mapstructure:"url"`
+	Events        []string          `mapstructure:"events"` // Event type patterns (path.Match), empty = all
+	Timeout       time.Duration     `mapstructure:"timeout"`
+	Headers       map[string]string `mapstructure:"headers"`
+	SecretHeaders map[string]string `mapstructure:"secret_headers"` // Header -> secret name, e.g. Authorization: "webhook.token"
+}
+
+// RoutingConfig forwards events to additional destinations by rules.
+// Unlike the outbox, routed events are delivered at most once.
+type RoutingConfig struct {
+	QueueSize int                 `mapstructure:"queue_size"` // Events waiting for routing, further events are dropped
+	Rules     []RoutingRuleConfig `mapstructure:"rules"`
+}
+
+// RoutingRuleConfig selects events and shapes their payload for its targets
+type RoutingRuleConfig struct {
+	Name    string                `mapstructure:"name"`
+	Events  []string              `mapstructure:"events"`  // Event type patterns (path.Match), empty = all
+	Sources []string              `mapstructure:"sources"` // workflow, machine, device, system, alarm; empty = all
+	Match   map[string]any        `mapstructure:"match"`   // Payload path -> required value or list of values
+	Fields  []string              `mapstructure:"fields"`  // Payload paths to keep, empty = all
+	Rename  map[string]string     `mapstructure:"rename"`  // Payload key -> new key
+	Set     map[string]any        `mapstructure:"set"`     // Static values added to the payload
+	Targets []RoutingTargetConfig `mapstructure:"targets"`
+}
+
+// RoutingTargetConfig is a destination of routed events
+type RoutingTargetConfig struct {
+	Type           string            `mapstructure:"type"`            // websocket, webhook, mqtt or alarm
+	Topic          string            `mapstructure:"topic"`           // WebSocket message type or MQTT topic; {source}, {event_type} and {rule} are replaced
+	URL            string            `mapstructure:"url"`             // Webhook URL or MQTT broker, e.g. tcp://broker:1883
+	Timeout        time.Duration     `mapstructure:"timeout"`         // 0 = 5s
+	Headers        map[string]string `mapstructure:"headers"`         // Webhook
+	SecretHeaders  map[string]string `mapstructure:"secret_headers"`  // Webhook, header -> secret name
+	ClientID       string            `mapstructure:"client_id"`       // MQTT, empty = random
+	Username       string            `mapstructure:"username"`        // MQTT
+	PasswordSecret string            `mapstructure:"password_secret"` // MQTT, secret name of the password
+	Retain         bool              `mapstructure:"retain"`          // MQTT
+	EventType      string            `mapstructure:"event_type"`      // Alarm: event type alarm definitions match, empty = the routed event's
+}
+
+// StepHookConfig is an HTTP callout before or after matching workflow
+// steps; the receiver allows or denies the step, e.g. an interlock server
+// vetoing critical writes
+type StepHookConfig struct {
+	Name          string            `mapstructure:"name"`
+	Phase         string            `mapstructure:"phase"` // "pre" or "post"
+	URL           string            `mapstructure:"url"`
+	StepTypes     []string          `mapstructure:"step_types"` // e.g. "device", empty = all
+	Operations    []string          `mapstructure:"operations"` // Device operations, e.g. "write_register", empty = all
+	Devices       []string          `mapstructure:"devices"`    // Device name patterns (path.Match), empty = all
+	Timeout       time.Duration     `mapstructure:"timeout"`    // 0 = 2s
+	FailOpen      bool              `mapstructure:"fail_open"`  // Allow the step when the hook can't be reached
+	Headers       map[string]string `mapstructure:"headers"`
+	SecretHeaders map[string]string `mapstructure:"secret_headers"` // Header -> secret name
+}
+
+// SecretsConfig locates the master key that encrypts stored credentials.
+// The key is 32 random bytes, base64 encoded (-generate-master-key).
+type SecretsConfig struct {
+	MasterKeyEnv  string `mapstructure:"master_key_env"`  // Environment variable holding the key
+	MasterKeyFile string `mapstructure:"master_key_file"` // Alternatively a file, e.g. written by a KMS agent or a Docker secret
+}
+
+// FaultsConfig enables the developer-mode fault injection API. Never enable
+// it on a production machine.
+type FaultsConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MaxDuration time.Duration `mapstructure:"max_duration"` // Upper bound for a fault's lifetime, 0 = unlimited
+}
+
+// LintConfig selects the default lint profile and overrides rules per profile.
+// Built-in profiles are "default", "strict" and "off"; other names create new profiles.
+type LintConfig struct {
+	DefaultProfile string                       `mapstructure:"default_profile"`
+	Profiles       map[string]LintProfileConfig `mapstructure:"profiles"`
+}
+
+// ValidationConfig schedules validating all stored workflows once a day, e.g.
+// to find recipes broken by a disabled device
+type ValidationConfig struct {
+	Nightly   bool   `mapstructure:"nightly"`
+	NightlyAt string `mapstructure:"nightly_at"` // Local time, HH:MM
+	Profile   string `mapstructure:"profile"`    // Lint profile, empty = lint.default_profile
+}
+
+type LintProfileConfig struct {
+	Rules map[string]LintRuleConfig `mapstructure:"rules"` // Keyed by rule ID
+}
+
+type LintRuleConfig struct {
+	Enabled  *bool  `mapstructure:"enabled"`
+	Severity string `mapstructure:"severity"` // "error" or "warning"
 }
 
 func Load(path string) (*Config, error) {
@@ -57,8 +493,150 @@ func Load(path string) (*Config, error) {
 	viper.SetDefault("server.grpc_port", 50051)
 	viper.SetDefault("server.http_port", 8080)
 	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.socket_mode", "0660")
+	// Route Limit Defaults
+	viper.SetDefault("server.route_limits.default.max_body_bytes", 1<<20)
+	viper.SetDefault("server.route_limits.default.timeout", "10s")
+	viper.SetDefault("server.route_limits.auth.max_body_bytes", 16<<10)
+	viper.SetDefault("server.route_limits.auth.timeout", "5s")
+	viper.SetDefault("server.route_limits.machine.max_body_bytes", 16<<10)
+	viper.SetDefault("server.route_limits.machine.timeout", "5s")
+	viper.SetDefault("server.route_limits.workflows.max_body_bytes", 4<<20)
+	viper.SetDefault("server.route_limits.workflows.timeout", "30s")
+	viper.SetDefault("server.route_limits.execution_wait.timeout", "5m")
+
+	// gRPC Defaults
+	viper.SetDefault("server.grpc.reflection", true)
+	viper.SetDefault("server.grpc.max_recv_msg_size", 4<<20)
+	viper.SetDefault("server.grpc.max_send_msg_size", 4<<20)
+	viper.SetDefault("server.grpc.keepalive.time", "2h")
+	viper.SetDefault("server.grpc.keepalive.timeout", "20s")
+	viper.SetDefault("server.grpc.keepalive.min_time", "10s")
+	viper.SetDefault("server.grpc.keepalive.permit_without_stream", true)
+	viper.SetDefault("server.grpc.event_stream.buffer", 100)
+	viper.SetDefault("server.grpc.event_stream.policy", "drop_newest")
+	viper.SetDefault("server.grpc.event_stream.block_timeout", "1s")
+	viper.SetDefault("server.websocket.replay_buffer", 100)
+	viper.SetDefault("server.websocket.command_queue", 64)
+
 	viper.SetDefault("modbus.default_timeout", "1s")
 	viper.SetDefault("modbus.default_poll_interval", "100ms")
+	viper.SetDefault("modbus.stale_after", "1s")
+	viper.SetDefault("modbus.reconnect_min_delay", "500ms")
+	viper.SetDefault("modbus.reconnect_max_delay", "30s")
+
+	// Heartbeat Defaults
+	viper.SetDefault("heartbeat.enabled", false)
+	viper.SetDefault("heartbeat.interval", "500ms")
+	viper.SetDefault("heartbeat.failure_threshold", 3)
+
+	// Resource Monitoring Defaults
+	viper.SetDefault("resources.enabled", true)
+	viper.SetDefault("resources.interval", "10s")
+	viper.SetDefault("resources.max_goroutines", 10000)
+	viper.SetDefault("resources.max_memory_mb", 0)
+	viper.SetDefault("resources.max_db_pool_usage", 0.9)
+	viper.SetDefault("resources.max_ws_clients", 100)
+	viper.SetDefault("resources.reject_executions", true)
+	viper.SetDefault("resources.shed_ws_clients", true)
+
+	// Safe State Defaults
+	viper.SetDefault("safe_state.on_shutdown", true)
+	viper.SetDefault("safe_state.on_stop_failure", true)
+	viper.SetDefault("safe_state.on_cancel", false)
+	viper.SetDefault("safe_state.write_timeout", "5s")
+	viper.SetDefault("safe_state.cancel_timeout", "10s")
+
+	// Manual Control Defaults
+	viper.SetDefault("manual_control.heartbeat_timeout", "3s")
+	viper.SetDefault("manual_control.require_session", false)
+	viper.SetDefault("manual_control.max_sessions", 10)
+
+	// Device Onboarding Defaults
+	viper.SetDefault("onboarding.session_timeout", "30m")
+	viper.SetDefault("onboarding.check_timeout", "2m")
+	viper.SetDefault("onboarding.blink_interval", "500ms")
+	viper.SetDefault("onboarding.max_sessions", 5)
+
+	// Payload Limit Defaults
+	viper.SetDefault("limits.max_input_bytes", 65536)
+	viper.SetDefault("limits.max_parameter_bytes", 16384)
+	viper.SetDefault("limits.max_output_bytes", 65536)
+	viper.SetDefault("limits.max_call_depth", 8)
+	viper.SetDefault("limits.max_loop_jumps", 1000)
+
+	// Usage Defaults
+	viper.SetDefault("usage.enabled", true)
+	viper.SetDefault("usage.flush_interval", "1m")
+
+	// Alarm Defaults
+	viper.SetDefault("alarms.enabled", true)
+	viper.SetDefault("alarms.scan_interval", "500ms")
+
+	// Shift Defaults
+	viper.SetDefault("shifts.enabled", false)
+	viper.SetDefault("shifts.timezone", "Local")
+	viper.SetDefault("shifts.check_interval", "5s")
+	viper.SetDefault("shifts.auto_home", true)
+	viper.SetDefault("shifts.auto_start", true)
+	viper.SetDefault("shifts.auto_stop", true)
+
+	// Counter Defaults
+	viper.SetDefault("counters.enabled", true)
+	viper.SetDefault("counters.sample_interval", "1s")
+	viper.SetDefault("counters.persist_interval", "1m")
+	viper.SetDefault("counters.rate_window", "1m")
+
+	// Snapshot Defaults
+	viper.SetDefault("snapshots.enabled", false)
+	viper.SetDefault("snapshots.interval", "10s")
+	viper.SetDefault("snapshots.retention", "72h")
+
+	// Fault Injection Defaults
+	viper.SetDefault("fault_injection.enabled", false)
+	viper.SetDefault("fault_injection.max_duration", "1h")
+
+	// Execution Log Defaults
+	viper.SetDefault("execution_logs.enabled", true)
+	viper.SetDefault("execution_logs.level", "debug")
+	viper.SetDefault("execution_logs.max_entries", 5000)
+	viper.SetDefault("execution_logs.trace_max_frames", 1000)
+	viper.SetDefault("execution_logs.trace_max_frame_bytes", 260)
+
+	// Checkpoint Defaults
+	viper.SetDefault("checkpoints.enabled", true)
+	viper.SetDefault("checkpoints.interval", "30s")
+	viper.SetDefault("checkpoints.resume_on_startup", false)
+
+	// Outbox Defaults
+	viper.SetDefault("outbox.enabled", false)
+	viper.SetDefault("outbox.poll_interval", "1s")
+	viper.SetDefault("outbox.batch_size", 100)
+	viper.SetDefault("outbox.max_attempts", 10)
+	viper.SetDefault("outbox.retry_backoff", "5s")
+	viper.SetDefault("outbox.max_backoff", "10m")
+	viper.SetDefault("outbox.retention", "168h")
+	viper.SetDefault("routing.queue_size", 1024)
+
+	// Secrets Defaults
+	viper.SetDefault("secrets.master_key_env", "OMC_MASTER_KEY")
+
+	// Descriptor Defaults
+	viper.SetDefault("device_profiles.watch", true)
+	viper.SetDefault("device_profiles.sync.enabled", false)
+	viper.SetDefault("device_profiles.sync.directory", "device-descriptors/managed")
+	viper.SetDefault("device_profiles.sync.interval", "24h")
+	viper.SetDefault("device_profiles.sync.timeout", "60s")
+	viper.SetDefault("device_profiles.sync.auto_install", false)
+	viper.SetDefault("device_profiles.startup.concurrency", 8)
+	viper.SetDefault("device_profiles.startup.retry_interval", "10s")
+
+	// Lint Defaults
+	viper.SetDefault("lint.default_profile", "default")
+
+	// Validation Defaults
+	viper.SetDefault("validation.nightly", false)
+	viper.SetDefault("validation.nightly_at", "02:00")
 
 	// Auth Defaults
 	viper.SetDefault("auth.jwt_secret_env", "JWT_SECRET")
@@ -66,10 +644,15 @@ func Load(path string) (*Config, error) {
 	viper.SetDefault("auth.refresh_token_ttl", "168h")
 	viper.SetDefault("auth.max_failed_login_attempts", 5)
 	viper.SetDefault("auth.account_lock_duration", "15m")
+	viper.SetDefault("auth.jwt_key_grace", "2h")
+	viper.SetDefault("auth.jwt_algorithm", JWTAlgorithmHS256)
+	viper.SetDefault("auth.jwt_private_key_file", "")
+	viper.SetDefault("auth.jwt_previous_public_key_files", []string{})
 
 	// Environment Variables automatisch binden (Viper Feature)
 	viper.AutomaticEnv()
-	viper.SetEnvPrefix("OMC") // Environment Variables mit Prefix OMC_
+	viper.SetEnvPrefix("OMC")                              // Environment Variables mit Prefix OMC_
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // e.g. OMC_DATABASE_HOST for database.host
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
@@ -79,13 +662,30 @@ func Load(path string) (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if config.Devices.Sync.Enabled {
+		config.Devices.Sync.Directory = ResolvePath(config.Devices.Sync.Directory)
+		config.Devices.SearchPaths = append(config.Devices.SearchPaths, config.Devices.Sync.Directory)
+	}
+	config.Devices.SearchPaths = resolveSearchPaths(config.Devices.SearchPaths)
+
+	// A quota that never applies would silently leave its endpoints unlimited
+	if err := config.Usage.checkQuotas(); err != nil {
+		return nil, fmt.Errorf("invalid quotas: %w", err)
+	}
 
 	return &config, nil
 }
 
+// DSN returns the connection URL; user and password are escaped
 func (c *DatabaseConfig) DSN() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		c.User, c.Password, c.Host, c.Port, c.Database)
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Database,
+		RawQuery: "sslmode=disable",
+	}
+	return dsn.String()
 }
 
 // JWT Secret aus Environment Variable laden
@@ -105,6 +705,9 @@ func (a *AuthConfig) GetJWTSecret() string {
 
 // Helper um zu prüfen ob Production-Ready
 func (a *AuthConfig) IsProductionReady() bool {
+	if a.Asymmetric() {
+		return true // The HMAC secret isn't used
+	}
 	secret := a.GetJWTSecret()
 	return secret != "dev-secret-change-in-production-min-32-chars" && len(secret) >= 32
 }
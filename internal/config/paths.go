@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ResolvePath makes a relative path absolute. It is taken relative to the
+// working directory; if nothing exists there, relative to the directory of
+// the executable, since service managers (the Windows service control
+// manager in particular) don't start the server in its installation directory.
+func ResolvePath(path string) string {
+	if path == "" {
+		return path
+	}
+	path = filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+		return path
+	}
+	if exe, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(exe), path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
+
+// resolveSearchPaths resolves the profile search paths and appends the
+// system profile directory
+func resolveSearchPaths(paths []string) []string {
+	systemDir := SystemProfileDir()
+	resolved := make([]string, 0, len(paths)+1)
+	for _, p := range paths {
+		p = ResolvePath(p)
+		if p != systemDir {
+			resolved = append(resolved, p)
+		}
+	}
+	return append(resolved, systemDir)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package config
+
+// SystemProfileDir is the machine-wide device profile directory
+func SystemProfileDir() string {
+	return "/etc/openmachinecore/profiles"
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SystemProfileDir is the machine-wide device profile directory
+func SystemProfileDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "OpenMachineCore", "profiles")
+}
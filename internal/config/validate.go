@@ -0,0 +1,623 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is a configuration error or warning
+type Problem struct {
+	Key      string `json:"key"` // Dotted config key, e.g. "server.shutdown_timeout"
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Key == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Key, p.Message)
+}
+
+// Validation is the result of checking a configuration file
+type Validation struct {
+	Config   *Config // Effective configuration, nil if it couldn't be loaded
+	Problems []Problem
+}
+
+// HasErrors reports whether the configuration can't be used
+func (v *Validation) HasErrors() bool {
+	for _, p := range v.Problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validation) add(severity, key, format string, args ...any) {
+	v.Problems = append(v.Problems, Problem{Key: key, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Validate checks a configuration file against the Config schema (unknown
+// keys, wrong value types, unparsable durations), loads it like the server
+// does, including defaults and environment overrides, and checks the
+// resulting values for consistency
+func Validate(path string) *Validation {
+	v := &Validation{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.add(SeverityError, "", "failed to read config: %v", err)
+		return v
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		v.add(SeverityError, "", "invalid YAML: %v", err)
+		return v
+	}
+
+	v.checkSchema("", raw, reflect.TypeOf(Config{}))
+	if v.HasErrors() {
+		return v // Loading would only repeat the type errors less clearly
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		v.add(SeverityError, "", "%v", err)
+		return v
+	}
+	v.Config = cfg
+	v.checkValues(cfg)
+
+	sort.SliceStable(v.Problems, func(i, j int) bool {
+		return v.Problems[i].Severity == SeverityError && v.Problems[j].Severity != SeverityError
+	})
+	return v
+}
+
+// EffectiveSettings returns the configuration loaded last, merged from
+// defaults, file and environment, with the database password masked
+func EffectiveSettings() map[string]any {
+	settings := viper.AllSettings()
+	if db, ok := settings["database"].(map[string]any); ok {
+		if pw, ok := db["password"].(string); ok && pw != "" {
+			db["password"] = "********"
+		}
+	}
+	return settings
+}
+
+// checkSchema compares a YAML value with the type it is decoded into
+func (v *Validation) checkSchema(key string, value any, t reflect.Type) {
+	if value == nil {
+		return // Empty YAML value, the default applies
+	}
+
+	if t == durationType {
+		switch d := value.(type) {
+		case string:
+			if _, err := time.ParseDuration(d); err != nil {
+				v.add(SeverityError, key, "invalid duration %q, use a number with unit, e.g. 500ms, 30s, 5m or 24h", d)
+			}
+		case int:
+			if d != 0 {
+				v.add(SeverityWarning, key, "%d has no unit and is read as nanoseconds, e.g. write %ds", d, d)
+			}
+		default:
+			v.add(SeverityError, key, "expected a duration, e.g. 30s, got %s", describe(value))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]any)
+		if !ok {
+			v.add(SeverityError, key, "expected a section, got %s", describe(value))
+			return
+		}
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if tag := t.Field(i).Tag.Get("mapstructure"); tag != "" {
+				fields[tag] = t.Field(i).Type
+			}
+		}
+		for _, k := range sortedKeys(m) {
+			fieldType, ok := fields[strings.ToLower(k)]
+			if !ok {
+				v.unknownKey(join(key, k), k, fields)
+				continue
+			}
+			v.checkSchema(join(key, k), m[k], fieldType)
+		}
+
+	case reflect.Map:
+		m, ok := value.(map[string]any)
+		if !ok {
+			v.add(SeverityError, key, "expected a map, got %s", describe(value))
+			return
+		}
+		for _, k := range sortedKeys(m) {
+			v.checkSchema(join(key, k), m[k], t.Elem())
+		}
+
+	case reflect.Slice:
+		list, ok := value.([]any)
+		if !ok {
+			v.add(SeverityError, key, "expected a list, got %s", describe(value))
+			return
+		}
+		for i, item := range list {
+			v.checkSchema(fmt.Sprintf("%s[%d]", key, i), item, t.Elem())
+		}
+
+	case reflect.Bool:
+		switch b := value.(type) {
+		case bool:
+		case string:
+			if _, err := strconv.ParseBool(b); err != nil {
+				v.add(SeverityError, key, "expected true or false, got %q", b)
+			}
+		default:
+			v.add(SeverityError, key, "expected true or false, got %s", describe(value))
+		}
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		switch n := value.(type) {
+		case int:
+		case float64:
+			if n != float64(int64(n)) {
+				v.add(SeverityError, key, "expected a whole number, got %v", n)
+			}
+		case string:
+			if _, err := strconv.ParseInt(n, 10, 64); err != nil {
+				v.add(SeverityError, key, "expected a whole number, got %q", n)
+			}
+		default:
+			v.add(SeverityError, key, "expected a whole number, got %s", describe(value))
+		}
+
+	case reflect.Float64:
+		switch n := value.(type) {
+		case int, float64:
+		case string:
+			if _, err := strconv.ParseFloat(n, 64); err != nil {
+				v.add(SeverityError, key, "expected a number, got %q", n)
+			}
+		default:
+			v.add(SeverityError, key, "expected a number, got %s", describe(value))
+		}
+
+	case reflect.String:
+		switch value.(type) {
+		case map[string]any, []any:
+			v.add(SeverityError, key, "expected a value, got %s", describe(value))
+		}
+
+	case reflect.Ptr:
+		v.checkSchema(key, value, t.Elem())
+	}
+}
+
+// unknownKey reports a key the server ignores, with the closest known key
+func (v *Validation) unknownKey(key, name string, fields map[string]reflect.Type) {
+	best, bestDist := "", 3
+	for known := range fields {
+		if d := editDistance(strings.ToLower(name), known); d < bestDist || d == bestDist && known < best {
+			best, bestDist = known, d
+		}
+	}
+	if best != "" {
+		v.add(SeverityWarning, key, "unknown key, it is ignored (did you mean %q?)", best)
+		return
+	}
+	v.add(SeverityWarning, key, "unknown key, it is ignored")
+}
+
+// checkValues checks the loaded configuration for values that are well-typed
+// but fail at runtime
+func (v *Validation) checkValues(cfg *Config) {
+	v.listener("http", cfg.Server.HTTPSocket, cfg.Server.HTTPBind, cfg.Server.HTTPPort)
+	v.listener("grpc", cfg.Server.GRPCSocket, cfg.Server.GRPCBind, cfg.Server.GRPCPort)
+	v.port("database.port", cfg.Database.Port)
+	httpNetwork, httpAddress := cfg.Server.HTTPListen()
+	grpcNetwork, grpcAddress := cfg.Server.GRPCListen()
+	if httpNetwork == grpcNetwork && httpAddress == grpcAddress {
+		v.add(SeverityError, "server.grpc_port", "HTTP and gRPC both listen on %s", httpAddress)
+	} else if httpNetwork == "tcp" && grpcNetwork == "tcp" && cfg.Server.HTTPPort == cfg.Server.GRPCPort &&
+		(cfg.Server.HTTPBind == "" || cfg.Server.GRPCBind == "") {
+		v.add(SeverityError, "server.grpc_port", "HTTP and gRPC use the same port %d", cfg.Server.HTTPPort)
+	}
+	if _, err := cfg.Server.SocketFileMode(); err != nil {
+		v.add(SeverityError, "server.socket_mode", "%v", err)
+	}
+	if cfg.Server.GRPC.EventStream.Buffer < 1 {
+		v.add(SeverityError, "server.grpc.event_stream.buffer", "must be at least 1")
+	}
+	switch cfg.Server.GRPC.EventStream.Policy {
+	case "drop_newest", "drop_oldest", "disconnect":
+	case "block":
+		v.positive("server.grpc.event_stream.block_timeout", cfg.Server.GRPC.EventStream.BlockTimeout)
+	default:
+		v.add(SeverityError, "server.grpc.event_stream.policy", "unknown policy %q, use drop_newest, drop_oldest, block or disconnect", cfg.Server.GRPC.EventStream.Policy)
+	}
+	if cfg.Server.WebSocket.ReplayBuffer < 0 {
+		v.add(SeverityError, "server.websocket.replay_buffer", "must not be negative")
+	}
+	if cfg.Server.WebSocket.CommandQueue < 1 {
+		v.add(SeverityError, "server.websocket.command_queue", "must be at least 1")
+	}
+
+	v.required("database.host", cfg.Database.Host)
+	v.required("database.database", cfg.Database.Database)
+	v.required("database.user", cfg.Database.User)
+	if cfg.Database.MaxConnections < 1 {
+		v.add(SeverityError, "database.max_connections", "must be at least 1")
+	}
+
+	if cfg.Limits.MaxCallDepth < 1 {
+		v.add(SeverityError, "limits.max_call_depth", "must be at least 1")
+	}
+	if cfg.Limits.MaxLoopJumps < 1 {
+		v.add(SeverityError, "limits.max_loop_jumps", "must be at least 1")
+	}
+
+	if !cfg.Auth.IsProductionReady() {
+		v.add(SeverityWarning, "auth.jwt_secret_env", "environment variable %s is not set or shorter than 32 characters, a development secret is used", cfg.Auth.JWTSecretEnv)
+	}
+
+	v.positive("modbus.default_timeout", cfg.Modbus.DefaultTimeout)
+	v.positive("modbus.default_poll_interval", cfg.Modbus.DefaultPollInterval)
+	if cfg.Modbus.StaleAfter < 0 {
+		v.add(SeverityError, "modbus.stale_after", "must not be negative")
+	} else if cfg.Modbus.StaleAfter > 0 && cfg.Modbus.StaleAfter <= cfg.Modbus.DefaultPollInterval {
+		v.add(SeverityWarning, "modbus.stale_after", "not longer than modbus.default_poll_interval (%s), polled values are reported stale between polls", cfg.Modbus.DefaultPollInterval)
+	}
+	v.positive("modbus.reconnect_min_delay", cfg.Modbus.ReconnectMinDelay)
+	v.positive("modbus.reconnect_max_delay", cfg.Modbus.ReconnectMaxDelay)
+	if cfg.Modbus.ReconnectMaxDelay > 0 && cfg.Modbus.ReconnectMaxDelay < cfg.Modbus.ReconnectMinDelay {
+		v.add(SeverityError, "modbus.reconnect_max_delay", "must not be shorter than modbus.reconnect_min_delay (%s)", cfg.Modbus.ReconnectMinDelay)
+	}
+	v.positive("auth.access_token_ttl", cfg.Auth.AccessTokenTTL)
+	v.positive("auth.refresh_token_ttl", cfg.Auth.RefreshTokenTTL)
+	v.positive("auth.jwt_key_grace", cfg.Auth.JWTKeyGrace)
+	switch cfg.Auth.JWTAlgorithm {
+	case JWTAlgorithmHS256:
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		if cfg.Auth.JWTPrivateKeyFile == "" {
+			v.add(SeverityError, "auth.jwt_private_key_file", "required for %s", cfg.Auth.JWTAlgorithm)
+		} else if _, err := os.Stat(cfg.Auth.JWTPrivateKeyFile); err != nil {
+			v.add(SeverityError, "auth.jwt_private_key_file", "%v", err)
+		}
+		for _, file := range cfg.Auth.JWTPreviousPublicKeyFiles {
+			if _, err := os.Stat(file); err != nil {
+				v.add(SeverityError, "auth.jwt_previous_public_key_files", "%v", err)
+			}
+		}
+	default:
+		v.add(SeverityError, "auth.jwt_algorithm", "unknown algorithm %q, use %s, %s or %s",
+			cfg.Auth.JWTAlgorithm, JWTAlgorithmHS256, JWTAlgorithmRS256, JWTAlgorithmEdDSA)
+	}
+	if cfg.Auth.JWTKeyGrace > 0 && cfg.Auth.JWTKeyGrace < cfg.Auth.AccessTokenTTL {
+		v.add(SeverityWarning, "auth.jwt_key_grace", "shorter than auth.access_token_ttl (%s), tokens issued shortly before a key rotation are rejected before they expire", cfg.Auth.AccessTokenTTL)
+	}
+
+	if cfg.Heartbeat.Enabled {
+		v.positive("heartbeat.interval", cfg.Heartbeat.Interval)
+		if cfg.Heartbeat.Device == "" || cfg.Heartbeat.Register == "" {
+			v.add(SeverityError, "heartbeat", "device and register are required when enabled")
+		}
+	}
+	if cfg.Resources.Enabled {
+		v.positive("resources.interval", cfg.Resources.Interval)
+		if cfg.Resources.MaxGoroutines < 0 || cfg.Resources.MaxMemoryMB < 0 || cfg.Resources.MaxWSClients < 0 {
+			v.add(SeverityError, "resources", "thresholds must not be negative")
+		}
+		if cfg.Resources.MaxDBPoolUsage < 0 || cfg.Resources.MaxDBPoolUsage > 1 {
+			v.add(SeverityError, "resources.max_db_pool_usage", "must be between 0 and 1, got %g", cfg.Resources.MaxDBPoolUsage)
+		}
+	}
+	if cfg.Alarms.Enabled {
+		v.positive("alarms.scan_interval", cfg.Alarms.ScanInterval)
+	}
+	if cfg.Usage.Enabled {
+		v.positive("usage.flush_interval", cfg.Usage.FlushInterval)
+	}
+	if cfg.Counters.Enabled {
+		v.positive("counters.sample_interval", cfg.Counters.SampleInterval)
+		v.positive("counters.persist_interval", cfg.Counters.PersistInterval)
+		for i, c := range cfg.Counters.Counters {
+			if c.Name == "" || c.Device == "" || c.Register == "" {
+				v.add(SeverityError, fmt.Sprintf("counters.counters[%d]", i), "name, device and register are required")
+			}
+		}
+	}
+	if cfg.Snapshots.Enabled {
+		v.positive("snapshots.interval", cfg.Snapshots.Interval)
+	}
+	v.patterns("snapshots.registers", cfg.Snapshots.Registers)
+
+	if cfg.Shifts.Enabled {
+		v.positive("shifts.check_interval", cfg.Shifts.CheckInterval)
+		if _, err := time.LoadLocation(cfg.Shifts.Timezone); err != nil {
+			v.add(SeverityError, "shifts.timezone", "unknown time zone %q, use an IANA name like Europe/Berlin or Local", cfg.Shifts.Timezone)
+		}
+	}
+
+	if cfg.Validation.Nightly {
+		if _, err := time.Parse("15:04", cfg.Validation.NightlyAt); err != nil {
+			v.add(SeverityError, "validation.nightly_at", "expected a time as HH:MM, got %q", cfg.Validation.NightlyAt)
+		}
+	}
+
+	switch cfg.ExecLogs.Level {
+	case "debug", "info":
+	case "warn", "error":
+		v.add(SeverityWarning, "execution_logs.level", "traced Modbus frames are logged at info and not captured at %s", cfg.ExecLogs.Level)
+	default:
+		v.add(SeverityError, "execution_logs.level", "expected debug, info, warn or error, got %q", cfg.ExecLogs.Level)
+	}
+	if cfg.ExecLogs.TraceMaxFrames < 0 {
+		v.add(SeverityError, "execution_logs.trace_max_frames", "must not be negative")
+	}
+	if cfg.ExecLogs.TraceMaxFrameBytes < 0 {
+		v.add(SeverityError, "execution_logs.trace_max_frame_bytes", "must not be negative")
+	}
+	if cfg.Checkpoints.Enabled {
+		v.positive("checkpoints.interval", cfg.Checkpoints.Interval)
+	}
+	v.positive("safe_state.cancel_timeout", cfg.SafeState.CancelTimeout)
+	v.positive("manual_control.heartbeat_timeout", cfg.Manual.HeartbeatTimeout)
+	if cfg.Manual.MaxSessions < 1 {
+		v.add(SeverityError, "manual_control.max_sessions", "must be at least 1")
+	}
+	v.positive("onboarding.session_timeout", cfg.Onboarding.SessionTimeout)
+	v.positive("onboarding.check_timeout", cfg.Onboarding.CheckTimeout)
+	v.positive("onboarding.blink_interval", cfg.Onboarding.BlinkInterval)
+	if cfg.Onboarding.MaxSessions < 1 {
+		v.add(SeverityError, "onboarding.max_sessions", "must be at least 1")
+	}
+
+	if cfg.Devices.Sync.Enabled {
+		v.url("device_profiles.sync.repository", cfg.Devices.Sync.Repository)
+	}
+	if cfg.Devices.Startup.Concurrency < 1 {
+		v.add(SeverityError, "device_profiles.startup.concurrency", "must be at least 1")
+	}
+	if cfg.Devices.Startup.RetryInterval < 0 {
+		v.add(SeverityError, "device_profiles.startup.retry_interval", "must not be negative")
+	}
+
+	if cfg.Outbox.Enabled {
+		v.positive("outbox.poll_interval", cfg.Outbox.PollInterval)
+		if cfg.Outbox.BatchSize < 1 {
+			v.add(SeverityError, "outbox.batch_size", "must be at least 1")
+		}
+		if cfg.Outbox.MaxAttempts < 1 {
+			v.add(SeverityError, "outbox.max_attempts", "must be at least 1")
+		}
+		if len(cfg.Outbox.Sinks) == 0 {
+			v.add(SeverityWarning, "outbox.sinks", "no sinks configured, events are not collected")
+		}
+		names := make(map[string]bool)
+		for i, sink := range cfg.Outbox.Sinks {
+			key := fmt.Sprintf("outbox.sinks[%d]", i)
+			switch {
+			case sink.Name == "":
+				v.add(SeverityError, key+".name", "is required")
+			case names[sink.Name]:
+				v.add(SeverityError, key+".name", "duplicate sink %q", sink.Name)
+			}
+			names[sink.Name] = true
+			if sink.Type != "webhook" {
+				v.add(SeverityError, key+".type", "unsupported type %q, use webhook", sink.Type)
+			}
+			v.url(key+".url", sink.URL)
+			v.patterns(key+".events", sink.Events)
+		}
+	}
+
+	if len(cfg.Routing.Rules) > 0 && cfg.Routing.QueueSize < 1 {
+		v.add(SeverityError, "routing.queue_size", "must be at least 1")
+	}
+	ruleNames := make(map[string]bool)
+	for i, rule := range cfg.Routing.Rules {
+		key := fmt.Sprintf("routing.rules[%d]", i)
+		switch {
+		case rule.Name == "":
+			v.add(SeverityError, key+".name", "is required")
+		case ruleNames[rule.Name]:
+			v.add(SeverityError, key+".name", "duplicate rule %q", rule.Name)
+		}
+		ruleNames[rule.Name] = true
+		v.patterns(key+".events", rule.Events)
+		for _, source := range rule.Sources {
+			switch source {
+			case "workflow", "machine", "device", "system", "alarm":
+			default:
+				v.add(SeverityError, key+".sources", "unknown source %q, use workflow, machine, device, system or alarm", source)
+			}
+		}
+		if len(rule.Targets) == 0 {
+			v.add(SeverityError, key+".targets", "at least one target is required")
+		}
+		for j, target := range rule.Targets {
+			targetKey := fmt.Sprintf("%s.targets[%d]", key, j)
+			switch target.Type {
+			case "webhook":
+				v.url(targetKey+".url", target.URL)
+			case "mqtt":
+				if target.URL == "" {
+					v.add(SeverityError, targetKey+".url", "is required")
+				}
+				if target.Topic == "" {
+					v.add(SeverityError, targetKey+".topic", "is required")
+				}
+			case "websocket", "alarm":
+			default:
+				v.add(SeverityError, targetKey+".type", "unsupported type %q, use websocket, webhook, mqtt or alarm", target.Type)
+			}
+		}
+	}
+
+	hookNames := make(map[string]bool)
+	for i, hook := range cfg.StepHooks {
+		key := fmt.Sprintf("step_hooks[%d]", i)
+		switch {
+		case hook.Name == "":
+			v.add(SeverityError, key+".name", "is required")
+		case hookNames[hook.Name]:
+			v.add(SeverityError, key+".name", "duplicate hook %q", hook.Name)
+		}
+		hookNames[hook.Name] = true
+		if hook.Phase != "pre" && hook.Phase != "post" {
+			v.add(SeverityError, key+".phase", "expected pre or post, got %q", hook.Phase)
+		}
+		v.url(key+".url", hook.URL)
+		v.patterns(key+".devices", hook.Devices)
+		if hook.Timeout < 0 {
+			v.add(SeverityError, key+".timeout", "must not be negative")
+		}
+		if hook.FailOpen {
+			v.add(SeverityWarning, key+".fail_open", "steps run unchecked while the hook is unreachable")
+		}
+	}
+
+	if cfg.Faults.Enabled {
+		v.add(SeverityWarning, "fault_injection.enabled", "fault injection is enabled, never use it on a production machine")
+	}
+	if cfg.Secrets.MasterKeyFile != "" {
+		if _, err := os.Stat(cfg.Secrets.MasterKeyFile); err != nil {
+			v.add(SeverityError, "secrets.master_key_file", "%v", err)
+		}
+	}
+
+	for _, searchPath := range cfg.Devices.SearchPaths {
+		if searchPath == SystemProfileDir() || searchPath == cfg.Devices.Sync.Directory {
+			continue // Optional, respectively created by the first pack install
+		}
+		if _, err := os.Stat(searchPath); err != nil {
+			v.add(SeverityWarning, "device_profiles.search_paths", "%s doesn't exist", searchPath)
+		}
+	}
+}
+
+func (v *Validation) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.add(SeverityError, key, "port %d is out of range 1-65535", port)
+	}
+}
+
+// listener checks the bind address and port of a server, or the directory
+// of its unix socket, which replaces them
+func (v *Validation) listener(name, socket, bind string, port int) {
+	if socket != "" {
+		if info, err := os.Stat(filepath.Dir(socket)); err != nil || !info.IsDir() {
+			v.add(SeverityError, "server."+name+"_socket", "directory of %s does not exist", socket)
+		}
+		return
+	}
+
+	v.port("server."+name+"_port", port)
+	if bind != "" && net.ParseIP(bind) == nil && strings.ContainsAny(bind, ":/[] ") {
+		v.add(SeverityError, "server."+name+"_bind", "expected an IP address or host name without port, got %q", bind)
+	}
+}
+
+func (v *Validation) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(SeverityError, key, "is required")
+	}
+}
+
+func (v *Validation) positive(key string, d time.Duration) {
+	if d <= 0 {
+		v.add(SeverityError, key, "must be greater than 0")
+	}
+}
+
+func (v *Validation) url(key, raw string) {
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(SeverityError, key, "expected an http or https URL, got %q", raw)
+	}
+}
+
+func (v *Validation) patterns(key string, patterns []string) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			v.add(SeverityError, key, "invalid pattern %q", pattern)
+		}
+	}
+}
+
+func describe(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "a section"
+	case []any:
+		return "a list"
+	case string:
+		return fmt.Sprintf("%q", value)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editDistance is the Levenshtein distance of two keys
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
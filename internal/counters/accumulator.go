@@ -0,0 +1,336 @@
+package counters
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// persistTimeout bounds storing all totals
+const persistTimeout = 10 * time.Second
+
+// Counter is the current state of an accumulated counter
+type Counter struct {
+	Name      string     `json:"name"`
+	Device    string     `json:"device"`
+	Register  string     `json:"register"`
+	Unit      string     `json:"unit,omitempty"`
+	Total     float64    `json:"total"`
+	Rate      float64    `json:"rate"` // Units per second over the rate window
+	LastValue *float64   `json:"last_value,omitempty"`
+	Rollovers int64      `json:"rollovers"`
+	Resets    int64      `json:"resets"`
+	SampledAt *time.Time `json:"sampled_at,omitempty"`
+	Error     string     `json:"error,omitempty"` // Why the last sample failed
+}
+
+type sample struct {
+	at    time.Time
+	total float64
+}
+
+type counter struct {
+	cfg       config.CounterConfig
+	state     storage.CounterTotal
+	samples   []sample // Within the rate window, oldest first
+	sampledAt *time.Time
+	err       string
+}
+
+// Accumulator turns rolling counter registers into monotonic totals.
+// Values are taken from the poller cache. A decrease is a rollover if the
+// wrapped step is at most half the counter range, otherwise the counter was
+// reset and counts again from zero.
+type Accumulator struct {
+	cfg     config.CountersConfig
+	storage *storage.PostgresClient
+	devices *devices.Manager
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	counters map[string]*counter // By counter name
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewAccumulator(cfg config.CountersConfig, store *storage.PostgresClient, deviceManager *devices.Manager, logger *zap.Logger) *Accumulator {
+	counters := make(map[string]*counter, len(cfg.Counters))
+	for _, cc := range cfg.Counters {
+		counters[cc.Name] = &counter{
+			cfg: cc,
+			state: storage.CounterTotal{
+				Name:     cc.Name,
+				Device:   cc.Device,
+				Register: cc.Register,
+			},
+		}
+	}
+
+	return &Accumulator{
+		cfg:      cfg,
+		storage:  store,
+		devices:  deviceManager,
+		logger:   logger,
+		counters: counters,
+	}
+}
+
+// Enabled reports whether counters are accumulated
+func (a *Accumulator) Enabled() bool {
+	return a.cfg.Enabled && len(a.cfg.Counters) > 0
+}
+
+// Start restores the stored totals and starts sampling
+func (a *Accumulator) Start(ctx context.Context) error {
+	if !a.Enabled() {
+		return nil
+	}
+
+	stored, err := a.storage.ListCounterTotals(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load counter totals: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return nil
+	}
+
+	for name, c := range a.counters {
+		if st, ok := stored[name]; ok {
+			c.state.Total = st.Total
+			c.state.Rollovers = st.Rollovers
+			c.state.Resets = st.Resets
+			// A different register has no comparable last value
+			if st.Device == c.cfg.Device && st.Register == c.cfg.Register {
+				c.state.LastValue = st.LastValue
+			}
+		}
+	}
+
+	a.running = true
+	a.stopChan = make(chan struct{})
+	a.wg.Add(1)
+	go a.loop(a.stopChan)
+
+	a.logger.Info("Counter accumulation started",
+		zap.Int("counters", len(a.counters)),
+		zap.Duration("sample_interval", a.cfg.SampleInterval))
+	return nil
+}
+
+// Stop ends sampling and stores the final totals; Start may be called again afterwards
+func (a *Accumulator) Stop() {
+	a.mu.Lock()
+	if !a.running {
+		a.mu.Unlock()
+		return
+	}
+	a.running = false
+	close(a.stopChan)
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	a.persist()
+}
+
+// List returns all counters ordered by name
+func (a *Accumulator) List() []Counter {
+	a.mu.Lock()
+	list := make([]Counter, 0, len(a.counters))
+	for _, c := range a.counters {
+		list = append(list, c.snapshot())
+	}
+	a.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a single counter
+func (a *Accumulator) Get(name string) (Counter, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.counters[name]
+	if !ok {
+		return Counter{}, false
+	}
+	return c.snapshot(), true
+}
+
+func (a *Accumulator) loop(stop <-chan struct{}) {
+	defer a.wg.Done()
+
+	sampleTicker := time.NewTicker(a.cfg.SampleInterval)
+	defer sampleTicker.Stop()
+	persistTicker := time.NewTicker(a.cfg.PersistInterval)
+	defer persistTicker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sampleTicker.C:
+			a.sample()
+		case <-persistTicker.C:
+			a.persist()
+		}
+	}
+}
+
+// sample reads all counter registers and accumulates their changes
+func (a *Accumulator) sample() {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, c := range a.counters {
+		value, span, err := a.read(&c.cfg)
+		if err != nil {
+			c.err = err.Error()
+			continue
+		}
+		c.err = ""
+		c.accumulate(value, span, now)
+		c.trimSamples(now.Add(-a.cfg.RateWindow))
+	}
+}
+
+// read returns the last polled value of a counter register and its range
+func (a *Accumulator) read(cc *config.CounterConfig) (float64, float64, error) {
+	device, exists := a.devices.GetDeviceByName(cc.Device)
+	if !exists {
+		return 0, 0, fmt.Errorf("device not found: %s", cc.Device)
+	}
+
+	register := cc.Register
+	if mapped, ok := device.IOMapping[register]; ok {
+		register = mapped
+	}
+
+	raw, ok := device.GetLastValue(register)
+	if !ok {
+		return 0, 0, fmt.Errorf("no value polled for %s", register)
+	}
+	value, ok := toFloat(raw)
+	if !ok {
+		return 0, 0, fmt.Errorf("register %s is not numeric", register)
+	}
+
+	span := cc.Rollover
+	if span <= 0 {
+		var err error
+		if span, err = device.CounterSpan(register); err != nil {
+			return 0, 0, err
+		}
+	}
+	return value, span, nil
+}
+
+func (a *Accumulator) persist() {
+	now := time.Now()
+
+	a.mu.Lock()
+	totals := make([]storage.CounterTotal, 0, len(a.counters))
+	rates := make(map[string]float64, len(a.counters))
+	for name, c := range a.counters {
+		totals = append(totals, c.state)
+		rates[name] = c.rate()
+	}
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	if err := a.storage.SaveCounterTotals(ctx, totals, rates, now); err != nil {
+		a.logger.Error("Failed to store counter totals", zap.Error(err))
+	}
+}
+
+// accumulate adds the change since the last value to the total
+func (c *counter) accumulate(value, span float64, now time.Time) {
+	if c.state.LastValue != nil {
+		delta := value - *c.state.LastValue
+		if delta < 0 {
+			if wrapped := delta + span; wrapped >= 0 && wrapped <= span/2 {
+				delta = wrapped
+				c.state.Rollovers++
+			} else {
+				delta = max(value, 0) // Counts since the reset
+				c.state.Resets++
+			}
+		}
+		c.state.Total += delta
+	}
+
+	last := value
+	c.state.LastValue = &last
+	c.sampledAt = &now
+	c.samples = append(c.samples, sample{at: now, total: c.state.Total})
+}
+
+// trimSamples drops samples before cutoff but keeps one as the rate baseline
+func (c *counter) trimSamples(cutoff time.Time) {
+	i := 0
+	for i < len(c.samples)-1 && !c.samples[i+1].at.After(cutoff) {
+		i++
+	}
+	c.samples = c.samples[i:]
+}
+
+// rate returns the average change per second over the sampled window
+func (c *counter) rate() float64 {
+	if len(c.samples) < 2 {
+		return 0
+	}
+	first, last := c.samples[0], c.samples[len(c.samples)-1]
+	seconds := last.at.Sub(first.at).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return (last.total - first.total) / seconds
+}
+
+func (c *counter) snapshot() Counter {
+	return Counter{
+		Name:      c.cfg.Name,
+		Device:    c.cfg.Device,
+		Register:  c.cfg.Register,
+		Unit:      c.cfg.Unit,
+		Total:     c.state.Total,
+		Rate:      c.rate(),
+		LastValue: c.state.LastValue,
+		Rollovers: c.state.Rollovers,
+		Resets:    c.state.Resets,
+		SampledAt: c.sampledAt,
+		Error:     c.err,
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,293 @@
+// Package demo installs and removes a demo dataset for evaluating
+// OpenMachineCore: a simulated device, home/stop/production workflows, an
+// operator and a machine token
+package demo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// Markers of the demo data; Remove deletes exactly the records carrying them
+const (
+	Tag       = "demo"          // Workflow tag and machine token metadata key
+	Device    = "demo-station"  // Device instance ID
+	Username  = "demo-operator" // Operator user
+	TokenName = "demo-token"    // Machine token
+)
+
+// ErrInstalled is returned when seeding over existing demo data
+var ErrInstalled = errors.New("demo data already installed")
+
+// Result lists the installed demo records. Password and Token are shown once.
+type Result struct {
+	DeviceID  uuid.UUID
+	Workflows map[string]uuid.UUID // By program name: home, stop, production
+	UserID    uuid.UUID
+	Password  string
+	TokenID   uuid.UUID
+	Token     string
+}
+
+// Seed installs the demo dataset. The device points to a Modbus TCP
+// simulator on localhost:5020 and stays offline until one listens there.
+func Seed(ctx context.Context, store *storage.PostgresClient, authService *auth.AuthService) (*Result, error) {
+	if installed, err := Installed(ctx, store); err != nil {
+		return nil, err
+	} else if installed {
+		return nil, ErrInstalled
+	}
+
+	result := &Result{Workflows: map[string]uuid.UUID{}}
+
+	deviceID, err := store.SaveDeviceComposition(ctx, composition())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save demo device: %w", err)
+	}
+	result.DeviceID = deviceID
+
+	for _, wf := range workflows() {
+		data, err := json.Marshal(wf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode demo workflow %s: %w", wf.ProgramName, err)
+		}
+		stored := &storage.Workflow{WorkflowName: wf.Name, Definition: data}
+		if err := store.SaveWorkflow(ctx, stored, nil); err != nil {
+			return nil, fmt.Errorf("failed to save demo workflow %s: %w", wf.ProgramName, err)
+		}
+		result.Workflows[wf.ProgramName] = stored.ID
+	}
+
+	result.Password = rand.Text()
+	user, err := authService.CreateUser(ctx, Username, result.Password, string(auth.PermOperator))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo user: %w", err)
+	}
+	result.UserID = user.ID
+
+	token, machineToken, err := authService.CreateMachineToken(ctx, TokenName, []string{string(auth.PermOperator)}, nil, map[string]any{
+		Tag:           true,
+		"created_via": "seed",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo machine token: %w", err)
+	}
+	result.TokenID = machineToken.ID
+	result.Token = token
+
+	return result, nil
+}
+
+// Installed reports whether any demo record exists
+func Installed(ctx context.Context, store *storage.PostgresClient) (bool, error) {
+	if _, err := store.GetUserByUsername(ctx, Username); err == nil {
+		return true, nil
+	}
+	if exists, _, err := store.DeviceExistsEnabledByName(ctx, Device); err != nil {
+		return false, fmt.Errorf("failed to check demo device: %w", err)
+	} else if exists {
+		return true, nil
+	}
+	ids, err := demoWorkflows(ctx, store)
+	if err != nil {
+		return false, err
+	}
+	if len(ids) > 0 {
+		return true, nil
+	}
+	tokens, err := demoTokens(ctx, store)
+	return len(tokens) > 0, err
+}
+
+// Remove deletes the demo records, also those of an incomplete Seed. Other
+// data is not touched; workflows are matched by their demo tag.
+func Remove(ctx context.Context, store *storage.PostgresClient) (int, error) {
+	removed := 0
+
+	tokens, err := demoTokens(ctx, store)
+	if err != nil {
+		return removed, err
+	}
+	for _, id := range tokens {
+		if err := store.DeleteMachineToken(ctx, id); err != nil {
+			return removed, fmt.Errorf("failed to delete demo machine token: %w", err)
+		}
+		removed++
+	}
+
+	if user, err := store.GetUserByUsername(ctx, Username); err == nil {
+		if err := store.DeleteUser(ctx, user.ID); err != nil {
+			return removed, fmt.Errorf("failed to delete demo user: %w", err)
+		}
+		removed++
+	}
+
+	// Workflows first, they reference the device
+	ids, err := demoWorkflows(ctx, store)
+	if err != nil {
+		return removed, err
+	}
+	for _, id := range ids {
+		if err := store.DeleteWorkflow(ctx, id); err != nil {
+			return removed, fmt.Errorf("failed to delete demo workflow %s: %w", id, err)
+		}
+		removed++
+	}
+
+	if exists, _, err := store.DeviceExistsEnabledByName(ctx, Device); err != nil {
+		return removed, fmt.Errorf("failed to check demo device: %w", err)
+	} else if exists {
+		if err := store.DeleteDevice(ctx, Device); err != nil {
+			return removed, fmt.Errorf("failed to delete demo device: %w", err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// demoWorkflows returns the IDs of the workflows tagged as demo
+func demoWorkflows(ctx context.Context, store *storage.PostgresClient) ([]uuid.UUID, error) {
+	stored, err := store.ListWorkflows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	var ids []uuid.UUID
+	for _, wf := range stored {
+		def, err := definition.ParseWorkflow(wf.Definition)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(def.Tags, Tag) {
+			ids = append(ids, wf.ID)
+		}
+	}
+	return ids, nil
+}
+
+// demoTokens returns the IDs of the machine tokens marked as demo
+func demoTokens(ctx context.Context, store *storage.PostgresClient) ([]uuid.UUID, error) {
+	tokens, err := store.ListMachineTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list machine tokens: %w", err)
+	}
+
+	var ids []uuid.UUID
+	for _, token := range tokens {
+		if demo, _ := token.Metadata[Tag].(bool); demo {
+			ids = append(ids, token.ID)
+		}
+	}
+	return ids, nil
+}
+
+// composition is a BK9100 coupler with a digital input and output terminal
+func composition() types.DeviceComposition {
+	return types.DeviceComposition{
+		InstanceID: Device,
+		Composition: types.CompositionConfig{
+			Coupler: types.CouplerConfig{
+				Module:    "beckhoff/modules/bk9100",
+				IPAddress: "127.0.0.1",
+				Port:      5020,
+				UnitID:    1,
+			},
+			Terminals: []types.TerminalConfig{
+				{Position: 0, Module: "beckhoff/modules/kl1408", Prefix: "inputs"},
+				{Position: 1, Module: "beckhoff/modules/kl2408", Prefix: "outputs"},
+			},
+			SafeStates: map[string]any{
+				"CONVEYOR":  false,
+				"LED_GREEN": false,
+			},
+		},
+		IOMapping: map[string]string{
+			"START_BUTTON": "inputs.input_0",
+			"PART_SENSOR":  "inputs.input_1",
+			"CONVEYOR":     "outputs.output_0",
+			"LED_GREEN":    "outputs.output_1",
+			"LED_RED":      "outputs.output_2",
+		},
+	}
+}
+
+// workflows returns the demo home, stop and production workflows
+func workflows() []definition.Workflow {
+	write := func(number, name, register string, value bool) definition.Step {
+		return definition.Step{
+			Number:     number,
+			Name:       name,
+			Type:       definition.StepTypeDevice,
+			DeviceID:   Device,
+			Operation:  "write_logical",
+			Parameters: map[string]any{"register": register, "value": value},
+		}
+	}
+	wait := func(number, name string, duration time.Duration) definition.Step {
+		return definition.Step{
+			Number:  number,
+			Name:    name,
+			Type:    definition.StepTypeWait,
+			Timeout: definition.Duration{Duration: duration},
+		}
+	}
+
+	return []definition.Workflow{
+		{
+			Name:        "Demo Home",
+			ProgramName: "home",
+			Description: "Demo data: drives the demo station to its start position",
+			Version:     "1.0",
+			Tags:        []string{Tag},
+			Steps: []definition.Step{
+				write("10", "Conveyor off", "CONVEYOR", false),
+				write("20", "Red light off", "LED_RED", false),
+				write("30", "Green light on", "LED_GREEN", true),
+			},
+		},
+		{
+			Name:        "Demo Stop",
+			ProgramName: "stop",
+			Description: "Demo data: stops the demo station",
+			Version:     "1.0",
+			Tags:        []string{Tag},
+			Steps: []definition.Step{
+				write("10", "Conveyor off", "CONVEYOR", false),
+				write("20", "Green light off", "LED_GREEN", false),
+				write("30", "Red light on", "LED_RED", true),
+			},
+		},
+		{
+			Name:        "Demo Production",
+			ProgramName: "production",
+			Description: "Demo data: transports a part and reads the part sensor",
+			Version:     "1.0",
+			Tags:        []string{Tag},
+			Steps: []definition.Step{
+				write("10", "Conveyor on", "CONVEYOR", true),
+				wait("20", "Transport part", 2*time.Second),
+				{
+					Number:     "30",
+					Name:       "Check part sensor",
+					Type:       definition.StepTypeDevice,
+					DeviceID:   Device,
+					Operation:  "read_logical",
+					Parameters: map[string]any{"register": "PART_SENSOR"},
+				},
+				write("40", "Conveyor off", "CONVEYOR", false),
+			},
+		},
+	}
+}
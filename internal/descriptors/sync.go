@@ -0,0 +1,600 @@
+package descriptors
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// AuditPackInstalled is the audit action of an installed descriptor pack
+const AuditPackInstalled = "descriptors.pack_installed"
+
+// Actor is recorded for packs installed by the periodic check
+const Actor = "descriptor_sync"
+
+const (
+	// maxIndexSize and maxPackSize bound downloads
+	maxIndexSize = 4 << 20
+	maxPackSize  = 64 << 20
+
+	// stateFile records the installed packs inside the managed directory
+	stateFile = ".sync-state.json"
+)
+
+var vendorPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ErrDisabled is returned while descriptor sync is not enabled
+var ErrDisabled = errors.New("descriptor sync is disabled")
+
+// ErrUnknownPack is returned for vendors or versions missing from the index
+var ErrUnknownPack = errors.New("pack not found in index")
+
+// Pack is an entry of the remote index. URL may be relative to the index.
+type Pack struct {
+	Vendor      string `json:"vendor"`
+	Version     string `json:"version"`
+	URL         string `json:"url"`    // tar.gz with index.yaml at its root
+	SHA256      string `json:"sha256"` // Hex checksum of the archive
+	Description string `json:"description,omitempty"`
+}
+
+// Index is the document served at the configured repository URL
+type Index struct {
+	Packs []Pack `json:"packs"`
+}
+
+// InstalledPack records a pack installed into the managed directory
+type InstalledPack struct {
+	Version     string    `json:"version"`
+	SHA256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installed_at"`
+	InstalledBy string    `json:"installed_by"`
+}
+
+// PackStatus compares the installed version of a vendor with the index
+type PackStatus struct {
+	Vendor          string     `json:"vendor"`
+	Installed       string     `json:"installed,omitempty"`
+	InstalledAt     *time.Time `json:"installed_at,omitempty"`
+	Available       string     `json:"available,omitempty"` // Pinned version, else the latest
+	Pinned          string     `json:"pinned,omitempty"`
+	UpdateAvailable bool       `json:"update_available"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Status describes descriptor sync at a point in time
+type Status struct {
+	Enabled    bool         `json:"enabled"`
+	Repository string       `json:"repository,omitempty"`
+	Directory  string       `json:"directory,omitempty"`
+	LastCheck  *time.Time   `json:"last_check,omitempty"`
+	LastError  string       `json:"last_error,omitempty"`
+	Packs      []PackStatus `json:"packs"`
+}
+
+// Syncer pulls vendor descriptor packs from a remote index into a managed
+// search path. Archives are verified against the checksum of the index and
+// replace the vendor directory as a whole.
+type Syncer struct {
+	cfg       config.DescriptorSyncConfig
+	storage   *storage.PostgresClient
+	onInstall func() // Invalidates cached profiles
+	logger    *zap.Logger
+	client    *http.Client
+
+	mu        sync.Mutex
+	index     *Index
+	lastCheck *time.Time
+	lastError string
+	installMu sync.Mutex
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewSyncer(cfg config.DescriptorSyncConfig, store *storage.PostgresClient, onInstall func(), logger *zap.Logger) *Syncer {
+	return &Syncer{
+		cfg:       cfg,
+		storage:   store,
+		onInstall: onInstall,
+		logger:    logger,
+		client:    &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Start begins the periodic update check if sync is enabled
+func (s *Syncer) Start() error {
+	if !s.cfg.Enabled {
+		return nil
+	}
+	if s.cfg.Repository == "" {
+		return errors.New("device_profiles.sync.repository is required")
+	}
+	if err := os.MkdirAll(s.cfg.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create managed descriptor directory: %w", err)
+	}
+	if s.cfg.Interval <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.loop(s.stopChan)
+
+	s.logger.Info("Descriptor sync started",
+		zap.String("repository", s.cfg.Repository),
+		zap.String("directory", s.cfg.Directory),
+		zap.Duration("interval", s.cfg.Interval),
+		zap.Bool("auto_install", s.cfg.AutoInstall))
+	return nil
+}
+
+// Stop ends the periodic update check
+func (s *Syncer) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Syncer) loop(stop chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	s.checkAndInstall(stop)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.checkAndInstall(stop)
+		}
+	}
+}
+
+func (s *Syncer) checkAndInstall(stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	status, err := s.Check(ctx)
+	if err != nil {
+		s.logger.Warn("Descriptor update check failed", zap.Error(err))
+		return
+	}
+	for _, pack := range status.Packs {
+		if !pack.UpdateAvailable {
+			continue
+		}
+		if !s.cfg.AutoInstall {
+			s.logger.Info("Descriptor pack update available",
+				zap.String("vendor", pack.Vendor),
+				zap.String("installed", pack.Installed),
+				zap.String("available", pack.Available))
+			continue
+		}
+		if _, err := s.Install(ctx, pack.Vendor, Actor); err != nil {
+			s.logger.Error("Failed to install descriptor pack",
+				zap.String("vendor", pack.Vendor),
+				zap.Error(err))
+		}
+	}
+}
+
+// Check fetches the index and returns the resulting status
+func (s *Syncer) Check(ctx context.Context) (*Status, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrDisabled
+	}
+
+	index, err := s.fetchIndex(ctx)
+	now := time.Now()
+	s.mu.Lock()
+	s.lastCheck = &now
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastError = ""
+		s.index = index
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return s.Status()
+}
+
+// Status compares the installed packs with the last fetched index
+func (s *Syncer) Status() (*Status, error) {
+	status := &Status{
+		Enabled:    s.cfg.Enabled,
+		Repository: s.cfg.Repository,
+		Directory:  s.cfg.Directory,
+		Packs:      make([]PackStatus, 0),
+	}
+	if !s.cfg.Enabled {
+		return status, nil
+	}
+
+	installed, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	index := s.index
+	status.LastCheck = s.lastCheck
+	status.LastError = s.lastError
+	s.mu.Unlock()
+
+	vendors := make(map[string]bool)
+	for vendor := range installed {
+		vendors[vendor] = true
+	}
+	for vendor := range s.cfg.Pins {
+		vendors[vendor] = true
+	}
+	if index != nil {
+		for _, p := range index.Packs {
+			vendors[p.Vendor] = true
+		}
+	}
+
+	for vendor := range vendors {
+		ps := PackStatus{Vendor: vendor, Pinned: s.cfg.Pins[vendor]}
+		if inst, ok := installed[vendor]; ok {
+			ps.Installed = inst.Version
+			installedAt := inst.InstalledAt
+			ps.InstalledAt = &installedAt
+		}
+		if index != nil {
+			target, err := s.target(index, vendor)
+			if err != nil {
+				ps.Error = err.Error()
+			} else {
+				ps.Available = target.Version
+				ps.UpdateAvailable = target.Version != ps.Installed
+			}
+		}
+		status.Packs = append(status.Packs, ps)
+	}
+
+	sort.Slice(status.Packs, func(i, j int) bool { return status.Packs[i].Vendor < status.Packs[j].Vendor })
+	return status, nil
+}
+
+// Install downloads, verifies and installs the pinned or latest pack of a
+// vendor from the last fetched index
+func (s *Syncer) Install(ctx context.Context, vendor, actor string) (*InstalledPack, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrDisabled
+	}
+
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+	if index == nil {
+		var err error
+		if index, err = s.fetchIndex(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	pack, err := s.target(index, vendor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+
+	if err := s.installPack(ctx, pack); err != nil {
+		return nil, err
+	}
+
+	installed, err := s.loadState()
+	if err != nil {
+		return nil, err
+	}
+	record := InstalledPack{
+		Version:     pack.Version,
+		SHA256:      strings.ToLower(pack.SHA256),
+		InstalledAt: time.Now(),
+		InstalledBy: actor,
+	}
+	previous := installed[vendor].Version
+	installed[vendor] = record
+	if err := s.saveState(installed); err != nil {
+		return nil, err
+	}
+
+	if s.onInstall != nil {
+		s.onInstall()
+	}
+
+	if err := s.storage.RecordAudit(ctx, &storage.AuditEntry{
+		Action: AuditPackInstalled,
+		Actor:  actor,
+		Details: map[string]any{
+			"vendor":   vendor,
+			"version":  pack.Version,
+			"previous": previous,
+			"sha256":   record.SHA256,
+		},
+	}); err != nil {
+		s.logger.Warn("Failed to audit descriptor pack install", zap.Error(err))
+	}
+
+	s.logger.Info("Descriptor pack installed",
+		zap.String("vendor", vendor),
+		zap.String("version", pack.Version),
+		zap.String("previous", previous))
+	return &record, nil
+}
+
+// target selects the pinned version of a vendor, else its latest version
+func (s *Syncer) target(index *Index, vendor string) (*Pack, error) {
+	pin := s.cfg.Pins[vendor]
+
+	var best *Pack
+	for i := range index.Packs {
+		p := &index.Packs[i]
+		if p.Vendor != vendor {
+			continue
+		}
+		if pin != "" {
+			if p.Version == pin {
+				return p, nil
+			}
+			continue
+		}
+		if best == nil || compareVersions(p.Version, best.Version) > 0 {
+			best = p
+		}
+	}
+
+	if best == nil {
+		if pin != "" {
+			return nil, fmt.Errorf("%w: %s %s (pinned)", ErrUnknownPack, vendor, pin)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPack, vendor)
+	}
+	return best, nil
+}
+
+func (s *Syncer) fetchIndex(ctx context.Context) (*Index, error) {
+	data, err := s.download(ctx, s.cfg.Repository, maxIndexSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pack index: %w", err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse pack index: %w", err)
+	}
+	for _, p := range index.Packs {
+		if !vendorPattern.MatchString(p.Vendor) {
+			return nil, fmt.Errorf("invalid vendor %q in pack index", p.Vendor)
+		}
+		if p.Version == "" || p.URL == "" || len(p.SHA256) != sha256.Size*2 {
+			return nil, fmt.Errorf("pack %s %s needs version, url and a sha256 checksum", p.Vendor, p.Version)
+		}
+	}
+	return &index, nil
+}
+
+func (s *Syncer) download(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+// installPack replaces the vendor directory with the verified archive
+func (s *Syncer) installPack(ctx context.Context, pack *Pack) error {
+	base, err := url.Parse(s.cfg.Repository)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL: %w", err)
+	}
+	ref, err := url.Parse(pack.URL)
+	if err != nil {
+		return fmt.Errorf("invalid pack URL: %w", err)
+	}
+
+	data, err := s.download(ctx, base.ResolveReference(ref).String(), maxPackSize)
+	if err != nil {
+		return fmt.Errorf("failed to download pack: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, pack.SHA256) {
+		return fmt.Errorf("checksum mismatch for %s %s: got %s, want %s", pack.Vendor, pack.Version, got, pack.SHA256)
+	}
+
+	tmp, err := os.MkdirTemp(s.cfg.Directory, "."+pack.Vendor+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extractTarGz(data, tmp); err != nil {
+		return fmt.Errorf("failed to extract pack: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "index.yaml")); err != nil {
+		return fmt.Errorf("pack %s %s has no index.yaml at its root", pack.Vendor, pack.Version)
+	}
+
+	target := filepath.Join(s.cfg.Directory, pack.Vendor)
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("failed to remove previous pack: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("failed to install pack: %w", err)
+	}
+	return nil
+}
+
+// extractTarGz unpacks regular files and directories below dir
+func extractTarGz(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("unsafe path in archive: %s", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			// Links and devices are not part of descriptor packs
+		}
+	}
+}
+
+func (s *Syncer) loadState() (map[string]InstalledPack, error) {
+	installed := make(map[string]InstalledPack)
+	data, err := os.ReadFile(filepath.Join(s.cfg.Directory, stateFile))
+	if os.IsNotExist(err) {
+		return installed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state: %w", err)
+	}
+	return installed, nil
+}
+
+func (s *Syncer) saveState(installed map[string]InstalledPack) error {
+	data, err := json.MarshalIndent(installed, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.cfg.Directory, stateFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// compareVersions compares dotted versions numerically where possible,
+// e.g. 1.10.0 > 1.9.2
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 type Composer struct {
@@ -49,20 +50,35 @@ func (c *Composer) ComposeDevice(comp types.DeviceComposition) (*types.DevicePro
 			Description: fmt.Sprintf("Composed device: %s", comp.InstanceID),
 		},
 		Connection: types.ConnectionConfig{
-			Protocol:       "modbus_tcp",
+			Protocol:       types.ProtocolModbusTCP,
 			Port:           comp.Composition.Coupler.Port,
 			UnitID:         comp.Composition.Coupler.UnitID,
 			PollIntervalMs: 50,
-			TimeoutMs:      1000,
+			TimeoutMs:      comp.Composition.Coupler.TimeoutMs,
+			Serial:         comp.Composition.Coupler.Serial,
+			ByteOrder:      comp.Composition.Coupler.ByteOrder,
 		},
 		Registers: make([]types.RegisterDefinition, 0),
 		Groups:    make([]types.RegisterGroup, 0),
+		Aliases:   make(map[string]string),
+	}
+
+	if comp.Composition.Coupler.Protocol != "" {
+		profile.Connection.Protocol = comp.Composition.Coupler.Protocol
 	}
 
 	// Add coupler registers (diagnostics, status, etc.)
 	if len(couplerModule.Registers) > 0 {
 		profile.Registers = append(profile.Registers, couplerModule.Registers...)
 	}
+	profile.Identification = append(profile.Identification, c.identificationRegisters(couplerModule, "")...)
+	c.addAliases(profile, couplerModule, "")
+	if datasheet := c.datasheet(comp.Composition.Coupler.Module); datasheet != "" {
+		profile.Documentation = append(profile.Documentation, types.ModuleDocumentation{
+			Module:    couplerModule.Module.ID,
+			Datasheet: datasheet,
+		})
+	}
 
 	// Calculate process image offsets
 	inputByteOffset := 0
@@ -89,6 +105,17 @@ func (c *Composer) ComposeDevice(comp types.DeviceComposition) (*types.DevicePro
 		)
 
 		profile.Registers = append(profile.Registers, terminalRegisters...)
+		profile.Identification = append(profile.Identification, c.identificationRegisters(terminalModule, terminal.Prefix)...)
+		c.addAliases(profile, terminalModule, terminal.Prefix)
+		if datasheet := c.datasheet(terminal.Module); datasheet != "" {
+			position := terminal.Position
+			profile.Documentation = append(profile.Documentation, types.ModuleDocumentation{
+				Module:    terminalModule.Module.ID,
+				Position:  &position,
+				Prefix:    terminal.Prefix,
+				Datasheet: datasheet,
+			})
+		}
 
 		// Update offsets for next terminal
 		inputByteOffset += terminalModule.ProcessImage.InputBytes
@@ -97,6 +124,10 @@ func (c *Composer) ComposeDevice(comp types.DeviceComposition) (*types.DevicePro
 
 	// Create register groups for efficient polling
 	profile.Groups = c.createRegisterGroups(profile.Registers)
+	profile.VirtualRegisters = comp.Composition.VirtualRegisters
+	for deprecated, current := range comp.Composition.Aliases {
+		profile.Aliases[deprecated] = current
+	}
 
 	c.logger.Info("Device composition complete",
 		zap.String("instance_id", comp.InstanceID),
@@ -133,6 +164,43 @@ func (c *Composer) loadModule(modulePath string) (*types.ModuleDefinition, error
 	return &module, nil
 }
 
+// datasheet looks up the datasheet URL of a module in the index.yaml of its
+// vendor directory; empty if the module is not indexed
+func (c *Composer) datasheet(modulePath string) string {
+	file := filepath.Clean(modulePath + ".json")
+
+	for _, searchPath := range c.searchPaths {
+		// The index may sit in any parent directory of the module, e.g.
+		// vendor/index.yaml for vendor/terminals/kl3064.json
+		for dir := filepath.Dir(file); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			data, err := os.ReadFile(filepath.Join(searchPath, dir, "index.yaml"))
+			if err != nil {
+				continue
+			}
+
+			var index types.VendorIndex
+			if err := yaml.Unmarshal(data, &index); err != nil {
+				c.logger.Warn("Failed to parse vendor index",
+					zap.String("path", filepath.Join(searchPath, dir, "index.yaml")),
+					zap.Error(err))
+				break
+			}
+
+			rel, _ := filepath.Rel(dir, file)
+			for _, refs := range index.Modules {
+				for _, ref := range refs {
+					if filepath.Clean(ref.File) == rel {
+						return ref.Datasheet
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return ""
+}
+
 func (c *Composer) channelsToRegisters(
 	module *types.ModuleDefinition,
 	prefix string,
@@ -149,6 +217,34 @@ func (c *Composer) channelsToRegisters(
 	return registers
 }
 
+// identificationRegisters tags the identification registers of a module with
+// the module ID; terminal fields are prefixed like their channels
+func (c *Composer) identificationRegisters(module *types.ModuleDefinition, prefix string) []types.IdentificationRegister {
+	registers := make([]types.IdentificationRegister, 0, len(module.Identification))
+
+	for _, reg := range module.Identification {
+		reg.Module = module.Module.ID
+		if prefix != "" {
+			reg.Field = fmt.Sprintf("%s.%s", prefix, reg.Field)
+		}
+		registers = append(registers, reg)
+	}
+
+	return registers
+}
+
+// addAliases adds the aliases of a module to the profile; terminal names are
+// prefixed like their channels
+func (c *Composer) addAliases(profile *types.DeviceProfileDefinition, module *types.ModuleDefinition, prefix string) {
+	for deprecated, current := range module.Aliases {
+		if prefix != "" {
+			deprecated = fmt.Sprintf("%s.%s", prefix, deprecated)
+			current = fmt.Sprintf("%s.%s", prefix, current)
+		}
+		profile.Aliases[deprecated] = current
+	}
+}
+
 func (c *Composer) channelToRegister(
 	channel types.ChannelInfo,
 	prefix string,
@@ -188,7 +284,7 @@ func (c *Composer) channelToRegister(
 		access = types.AccessTypeReadOnly
 	}
 
-	return types.RegisterDefinition{
+	reg := types.RegisterDefinition{
 		Name:        fullName,
 		Address:     address,
 		Type:        regType,
@@ -197,6 +293,13 @@ func (c *Composer) channelToRegister(
 		Access:      access,
 		Description: fmt.Sprintf("%s (bit %d)", channel.Description, channel.BitOffset),
 	}
+	// Digital channels of a module share its words, 16 channels per word
+	if channel.Type == "digital_input" || channel.Type == "digital_output" {
+		bit := channel.BitOffset % 16
+		reg.Address += uint16(channel.BitOffset / 16)
+		reg.Bit = &bit
+	}
+	return reg
 }
 
 func (c *Composer) createRegisterGroups(registers []types.RegisterDefinition) []types.RegisterGroup {
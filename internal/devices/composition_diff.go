@@ -0,0 +1,116 @@
+package devices
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// Composition change kinds
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// CompositionChange is a difference between two compositions of a device
+type CompositionChange struct {
+	Path   string `json:"path"` // e.g. "terminals[3].module", "io_mapping.START"
+	Change string `json:"change"`
+	From   any    `json:"from,omitempty"`
+	To     any    `json:"to,omitempty"`
+}
+
+// DiffCompositions lists the differences from one composition to another,
+// sorted by path. Terminals are compared by position, so a swapped terminal
+// order shows as changed modules.
+func DiffCompositions(from, to types.DeviceComposition) []CompositionChange {
+	before := flattenComposition(from)
+	after := flattenComposition(to)
+
+	paths := make([]string, 0, len(before)+len(after))
+	for path := range before {
+		paths = append(paths, path)
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := make([]CompositionChange, 0)
+	for _, path := range paths {
+		old, hadOld := before[path]
+		cur, hasCur := after[path]
+		switch {
+		case !hadOld:
+			changes = append(changes, CompositionChange{Path: path, Change: ChangeAdded, To: cur})
+		case !hasCur:
+			changes = append(changes, CompositionChange{Path: path, Change: ChangeRemoved, From: old})
+		case !reflect.DeepEqual(old, cur):
+			changes = append(changes, CompositionChange{Path: path, Change: ChangeChanged, From: old, To: cur})
+		}
+	}
+	return changes
+}
+
+// flattenComposition maps the settings of a composition by path; unset
+// optional values are left out
+func flattenComposition(comp types.DeviceComposition) map[string]any {
+	flat := make(map[string]any)
+	set := func(path string, value any) {
+		if value == "" || value == 0 {
+			return
+		}
+		flat[path] = value
+	}
+
+	coupler := comp.Composition.Coupler
+	set("coupler.module", coupler.Module)
+	set("coupler.protocol", coupler.Protocol)
+	set("coupler.ip_address", coupler.IPAddress)
+	set("coupler.port", coupler.Port)
+	set("coupler.unit_id", coupler.UnitID)
+	set("coupler.timeout_ms", coupler.TimeoutMs)
+	if serial := coupler.Serial; serial != nil {
+		set("coupler.serial.port", serial.Port)
+		set("coupler.serial.baud_rate", serial.BaudRate)
+		set("coupler.serial.parity", serial.Parity)
+		set("coupler.serial.stop_bits", serial.StopBits)
+	}
+
+	for _, terminal := range comp.Composition.Terminals {
+		base := fmt.Sprintf("terminals[%d]", terminal.Position)
+		set(base+".module", terminal.Module)
+		set(base+".prefix", terminal.Prefix)
+	}
+	for name, register := range comp.IOMapping {
+		set("io_mapping."+name, register)
+	}
+	for name, value := range comp.Composition.SafeStates {
+		flat["safe_states."+name] = value
+	}
+	for name, current := range comp.Composition.Aliases {
+		set("aliases."+name, current)
+	}
+	for name, limit := range comp.Composition.WriteLimits {
+		base := "write_limits." + name
+		set(base+".min_interval_ms", limit.MinIntervalMs)
+		for field, value := range map[string]*float64{"max_delta": limit.MaxDelta, "min": limit.Min, "max": limit.Max} {
+			if value != nil {
+				flat[base+"."+field] = *value
+			}
+		}
+	}
+	for _, v := range comp.Composition.VirtualRegisters {
+		base := "virtual_registers." + v.Name
+		set(base+".expression", v.Expression)
+		set(base+".data_type", string(v.DataType))
+		set(base+".unit", v.Unit)
+		set(base+".description", v.Description)
+	}
+	return flat
+}
@@ -2,6 +2,7 @@ package devices
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -12,13 +13,54 @@ import (
 	"go.uber.org/zap"
 )
 
+// CalibrationSource returns the stored calibrations of a device by register name
+type CalibrationSource func(ctx context.Context, deviceName string) (map[string]types.Calibration, error)
+
+// IdentitySource returns the identity a device was onboarded with, nil if none
+type IdentitySource func(ctx context.Context, deviceName string) (*types.DeviceIdentity, error)
+
+// IdentityHandler is called with the identification read after a device connects
+type IdentityHandler func(device *modbus.Device, identity *types.DeviceIdentity)
+
+// ConnectionHandler is called when the connection of a device is lost and
+// when it is restored
+type ConnectionHandler func(device *modbus.Device, event modbus.ConnectionEvent)
+
+// FaultSource returns the fault hook of a device for the fault injection
+type FaultSource func(deviceName string) modbus.FaultHook
+
+// ErrConnect is returned when a composed device could not connect; loading
+// it again may succeed once the device is reachable
+var ErrConnect = errors.New("failed to connect device")
+
+// ErrDuplicateDevice is returned when a device is loaded under the name of
+// a device that is already loaded; replace it instead
+var ErrDuplicateDevice = errors.New("device already loaded")
+
+// loadTimeout bounds reading the identification and calibrations of a
+// freshly loaded device
+const loadTimeout = 10 * time.Second
+
 type Manager struct {
-	loader   *ProfileLoader
-	composer *Composer // ADD THIS
-	devices  map[uuid.UUID]*modbus.Device
-	pollers  map[uuid.UUID]*modbus.Poller
-	mu       sync.RWMutex
-	logger   *zap.Logger
+	loader          *ProfileLoader
+	composer        *Composer // ADD THIS
+	devices         map[uuid.UUID]*modbus.Device
+	byName          map[string]uuid.UUID // Index of devices, names are unique
+	pollers         map[uuid.UUID]*modbus.Poller
+	mu              sync.RWMutex
+	logger          *zap.Logger
+	identityHandler IdentityHandler
+	limitHandler    modbus.WriteLimitHandler
+	connHandler     ConnectionHandler
+	calibrations    CalibrationSource
+	identities      IdentitySource
+	faults          FaultSource
+	staleAfter      time.Duration // Age at which cached values become stale
+	reconnectMin    time.Duration // First delay between reconnect attempts
+	reconnectMax    time.Duration // Longest delay between reconnect attempts
+
+	ctxMu sync.RWMutex
+	ctx   context.Context // Parent of pollers and device loading
 }
 
 func NewManager(searchPaths []string, logger *zap.Logger) (*Manager, error) {
@@ -33,12 +75,15 @@ func NewManager(searchPaths []string, logger *zap.Logger) (*Manager, error) {
 		loader:   loader,
 		composer: composer, // ADD THIS
 		devices:  make(map[uuid.UUID]*modbus.Device),
+		byName:   make(map[string]uuid.UUID),
 		pollers:  make(map[uuid.UUID]*modbus.Poller),
+		ctx:      context.Background(),
 		logger:   logger,
 	}, nil
 }
 
-// LoadDevice loads device from profile path (legacy method)
+// LoadDevice loads device from profile path (legacy method). The profile's
+// timeout_ms takes precedence over timeout.
 func (m *Manager) LoadDevice(
 	name string,
 	profilePath string,
@@ -48,6 +93,10 @@ func (m *Manager) LoadDevice(
 	ioMapping map[string]string,
 	timeout time.Duration,
 ) (*modbus.Device, error) {
+	if err := m.checkName(name); err != nil {
+		return nil, err
+	}
+
 	// Load profile (lazy)
 	profile, err := m.loader.Load(profilePath)
 	if err != nil {
@@ -55,19 +104,29 @@ func (m *Manager) LoadDevice(
 	}
 
 	// Create device
+	timeout, _ = modbus.EffectiveTimeout(profile, timeout)
 	device, err := modbus.NewDevice(name, ipAddress, port, unitID, profile, ioMapping, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create device: %w", err)
 	}
+	device.SetAliasHandler(m.reportAlias)
+	device.SetStaleAfter(m.staleAfter)
+	m.watchConnection(device)
+	if m.faults != nil {
+		device.Client.SetFaultHook(m.faults(device.Name))
+	}
 
 	// Connect
 	if err := device.Connect(); err != nil {
-		return nil, fmt.Errorf("failed to connect device: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrConnect, err)
 	}
+	m.identify(device)
+	m.applyCalibrations(device)
 
-	m.mu.Lock()
-	m.devices[device.ID] = device
-	m.mu.Unlock()
+	if _, _, err := m.add(device, false); err != nil {
+		device.Disconnect()
+		return nil, err
+	}
 
 	m.logger.Info("Device loaded",
 		zap.String("name", name),
@@ -77,11 +136,94 @@ func (m *Manager) LoadDevice(
 	return device, nil
 }
 
-// LoadDeviceFromComposition creates device from composition
+// LoadDeviceFromComposition creates device from composition. The coupler's
+// timeout_ms takes precedence over timeout. It fails with ErrDuplicateDevice
+// if a device of the instance ID is loaded.
 func (m *Manager) LoadDeviceFromComposition(
 	comp types.DeviceComposition,
 	timeout time.Duration,
 ) (*modbus.Device, error) {
+	if err := m.checkName(comp.InstanceID); err != nil {
+		return nil, err
+	}
+
+	device, err := m.composeDevice(comp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := m.add(device, false); err != nil {
+		device.Disconnect()
+		return nil, err
+	}
+
+	m.logger.Info("Device loaded from composition",
+		zap.String("instance_id", comp.InstanceID),
+		zap.String("coupler", comp.Composition.Coupler.Module),
+		zap.Int("terminals", len(comp.Composition.Terminals)))
+
+	return device, nil
+}
+
+// ReplaceDeviceFromComposition loads a device from composition in place of
+// the loaded device of the instance ID, e.g. for a hot reload. The old device
+// keeps running until the new one is connected, so a failed load leaves it
+// loaded. Its poller is stopped; start one for the new device. It reports
+// whether a device was replaced.
+func (m *Manager) ReplaceDeviceFromComposition(
+	comp types.DeviceComposition,
+	timeout time.Duration,
+) (*modbus.Device, bool, error) {
+	device, err := m.composeDevice(comp, timeout)
+	if err != nil {
+		return nil, false, err
+	}
+
+	old, oldPoller, _ := m.add(device, true)
+	if old == nil {
+		m.logger.Info("Device loaded from composition",
+			zap.String("instance_id", comp.InstanceID),
+			zap.String("coupler", comp.Composition.Coupler.Module),
+			zap.Int("terminals", len(comp.Composition.Terminals)))
+		return device, false, nil
+	}
+
+	if oldPoller != nil {
+		oldPoller.Stop()
+	}
+	if err := old.Disconnect(); err != nil {
+		m.logger.Warn("Failed to disconnect replaced device",
+			zap.String("device", old.Name),
+			zap.Error(err))
+	}
+	m.logger.Info("Device replaced",
+		zap.String("instance_id", comp.InstanceID),
+		zap.String("runtime_id", device.ID.String()),
+		zap.String("replaced_runtime_id", old.ID.String()))
+
+	return device, true, nil
+}
+
+// PreviewComposition composes the device profile of a composition without
+// connecting anything
+func (m *Manager) PreviewComposition(comp types.DeviceComposition) (*types.DeviceProfileDefinition, error) {
+	return m.composer.ComposeDevice(comp)
+}
+
+// ProbeComposition creates, connects and identifies a device from
+// composition without adding it to the manager, e.g. to commission it before
+// it is persisted. The caller disconnects the device. It fails with
+// ErrDuplicateDevice if a device of the instance ID is loaded, so the
+// hardware isn't connected twice.
+func (m *Manager) ProbeComposition(comp types.DeviceComposition, timeout time.Duration) (*modbus.Device, error) {
+	if err := m.checkName(comp.InstanceID); err != nil {
+		return nil, err
+	}
+	return m.composeDevice(comp, timeout)
+}
+
+// composeDevice creates and connects a device from composition without
+// adding it to the manager
+func (m *Manager) composeDevice(comp types.DeviceComposition, timeout time.Duration) (*modbus.Device, error) {
 	// Compose device profile from modules
 	profile, err := m.composer.ComposeDevice(comp)
 	if err != nil {
@@ -89,6 +231,7 @@ func (m *Manager) LoadDeviceFromComposition(
 	}
 
 	// Create device instance
+	timeout, _ = modbus.EffectiveTimeout(profile, timeout)
 	device, err := modbus.NewDevice(
 		comp.InstanceID,
 		comp.Composition.Coupler.IPAddress,
@@ -101,22 +244,241 @@ func (m *Manager) LoadDeviceFromComposition(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create device: %w", err)
 	}
+	device.SafeStates = comp.Composition.SafeStates
+	if err := device.SetWriteLimits(comp.Composition.WriteLimits); err != nil {
+		return nil, fmt.Errorf("invalid write limits: %w", err)
+	}
+	device.SetWriteLimitHandler(m.limitHandler)
+	device.SetAliasHandler(m.reportAlias)
+	device.SetStaleAfter(m.staleAfter)
+	m.watchConnection(device)
+	if m.faults != nil {
+		device.Client.SetFaultHook(m.faults(device.Name))
+	}
 
 	// Connect
 	if err := device.Connect(); err != nil {
-		return nil, fmt.Errorf("failed to connect device: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+	m.identify(device)
+	m.applyCalibrations(device)
+
+	return device, nil
+}
+
+// checkName fails if a device of the name is loaded. Loading checks before
+// connecting, so a duplicate doesn't open a second connection to the
+// hardware, and again when adding the device.
+func (m *Manager) checkName(name string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.byName[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateDevice, name)
 	}
+	return nil
+}
 
+// add adds a connected device. A loaded device of the same name fails with
+// ErrDuplicateDevice, or with replace is removed and returned together with
+// its poller for the caller to stop.
+func (m *Manager) add(device *modbus.Device, replace bool) (*modbus.Device, *modbus.Poller, error) {
 	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var old *modbus.Device
+	var oldPoller *modbus.Poller
+	if id, exists := m.byName[device.Name]; exists {
+		if !replace {
+			return nil, nil, fmt.Errorf("%w: %s", ErrDuplicateDevice, device.Name)
+		}
+		old = m.devices[id]
+		oldPoller = m.pollers[id]
+		delete(m.devices, id)
+		delete(m.pollers, id)
+	}
+
 	m.devices[device.ID] = device
-	m.mu.Unlock()
+	m.byName[device.Name] = device.ID
+	return old, oldPoller, nil
+}
 
-	m.logger.Info("Device loaded from composition",
-		zap.String("instance_id", comp.InstanceID),
-		zap.String("coupler", comp.Composition.Coupler.Module),
-		zap.Int("terminals", len(comp.Composition.Terminals)))
+// SetContext sets the parent context of pollers started and devices loaded
+// afterwards; cancelling it stops their background work
+func (m *Manager) SetContext(ctx context.Context) {
+	m.ctxMu.Lock()
+	defer m.ctxMu.Unlock()
+	m.ctx = ctx
+}
 
-	return device, nil
+func (m *Manager) context() context.Context {
+	m.ctxMu.RLock()
+	defer m.ctxMu.RUnlock()
+	return m.ctx
+}
+
+// SetStaleAfter sets the age at which cached values of devices loaded
+// afterwards are reported as stale
+func (m *Manager) SetStaleAfter(staleAfter time.Duration) {
+	m.staleAfter = staleAfter
+}
+
+// SetReconnectBackoff sets the delays between attempts to reconnect lost
+// devices loaded afterwards
+func (m *Manager) SetReconnectBackoff(minDelay, maxDelay time.Duration) {
+	m.reconnectMin = minDelay
+	m.reconnectMax = maxDelay
+}
+
+// SetConnectionHandler registers a handler for lost and restored connections
+// of devices loaded afterwards
+func (m *Manager) SetConnectionHandler(handler ConnectionHandler) {
+	m.connHandler = handler
+}
+
+// SetIdentityHandler registers a handler for identification results
+func (m *Manager) SetIdentityHandler(handler IdentityHandler) {
+	m.identityHandler = handler
+}
+
+// SetWriteLimitHandler registers a handler for writes rejected by the write
+// limits of devices loaded afterwards
+func (m *Manager) SetWriteLimitHandler(handler modbus.WriteLimitHandler) {
+	m.limitHandler = handler
+}
+
+// SetFaultSource enables fault injection for devices loaded afterwards
+func (m *Manager) SetFaultSource(source FaultSource) {
+	m.faults = source
+}
+
+// SetCalibrationSource registers where calibrations of loaded devices come from
+func (m *Manager) SetCalibrationSource(source CalibrationSource) {
+	m.calibrations = source
+}
+
+// SetIdentitySource registers where the onboarding identities of devices come
+// from, which identifications after each connect are compared against
+func (m *Manager) SetIdentitySource(source IdentitySource) {
+	m.identities = source
+}
+
+// ClearProfileCache makes devices loaded afterwards read their profiles
+// from disk again, e.g. after descriptors were updated
+func (m *Manager) ClearProfileCache() {
+	m.loader.ClearCache()
+}
+
+// applyCalibrations restores the stored calibrations of a loaded device.
+// Entries for registers no longer in the profile are skipped.
+func (m *Manager) applyCalibrations(device *modbus.Device) {
+	if m.calibrations == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(m.context(), loadTimeout)
+	defer cancel()
+
+	cals, err := m.calibrations(ctx, device.Name)
+	if err != nil {
+		m.logger.Warn("Failed to load device calibrations",
+			zap.String("device", device.Name),
+			zap.Error(err))
+		return
+	}
+
+	for register, cal := range cals {
+		if err := device.SetCalibration(register, cal); err != nil {
+			m.logger.Warn("Skipping device calibration",
+				zap.String("device", device.Name),
+				zap.String("register", register),
+				zap.Error(err))
+		}
+	}
+}
+
+// reportAlias logs the first use of a deprecated register or logical name
+func (m *Manager) reportAlias(device *modbus.Device, deprecated, current string) {
+	m.logger.Warn("Deprecated register name used, update the reference",
+		zap.String("device", device.Name),
+		zap.String("deprecated", deprecated),
+		zap.String("current", current))
+}
+
+// watchConnection sets up reconnecting a device and reports its connection
+// changes
+func (m *Manager) watchConnection(device *modbus.Device) {
+	device.Client.SetReconnectBackoff(m.reconnectMin, m.reconnectMax)
+	device.Client.SetConnectionHandler(func(event modbus.ConnectionEvent) {
+		switch event.State {
+		case modbus.ConnectionLost:
+			m.logger.Warn("Device connection lost, reconnecting",
+				zap.String("device", device.Name),
+				zap.Error(event.Err))
+		case modbus.ConnectionConnected:
+			m.logger.Info("Device reconnected",
+				zap.String("device", device.Name),
+				zap.Duration("downtime", event.Downtime))
+		}
+
+		if m.connHandler != nil {
+			m.connHandler(device, event)
+		}
+		if event.State == modbus.ConnectionConnected {
+			// The hardware may have been swapped while the connection was down
+			m.identify(device)
+		}
+	})
+}
+
+// identify reads the identification registers of a freshly connected device
+// and warns if the hardware doesn't match the configured modules or the
+// hardware it was onboarded with
+func (m *Manager) identify(device *modbus.Device) {
+	if len(device.Profile.Identification) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(m.context(), loadTimeout)
+	defer cancel()
+
+	var reference *types.DeviceIdentity
+	if m.identities != nil {
+		var err error
+		if reference, err = m.identities(ctx, device.Name); err != nil {
+			m.logger.Warn("Failed to load onboarding identity",
+				zap.String("device", device.Name),
+				zap.Error(err))
+		}
+	}
+
+	identity := device.Identify(ctx, reference)
+
+	for field, errMsg := range identity.Errors {
+		m.logger.Warn("Failed to read device identification",
+			zap.String("device", device.Name),
+			zap.String("field", field),
+			zap.String("error", errMsg))
+	}
+	for _, mismatch := range identity.Mismatches {
+		m.logger.Warn("Connected hardware doesn't match configured module",
+			zap.String("device", device.Name),
+			zap.String("module", mismatch.Module),
+			zap.String("field", mismatch.Field),
+			zap.String("expected", mismatch.Expected),
+			zap.String("actual", mismatch.Actual))
+	}
+	for _, change := range identity.Changes {
+		m.logger.Warn("Connected hardware differs from the onboarded hardware",
+			zap.String("device", device.Name),
+			zap.String("field", change.Field),
+			zap.String("onboarded", change.Expected),
+			zap.String("actual", change.Actual))
+	}
+
+	if m.identityHandler != nil {
+		m.identityHandler(device, identity)
+	}
 }
 
 // StartPoller starts poller for a device
@@ -129,7 +491,7 @@ func (m *Manager) StartPoller(deviceID uuid.UUID, interval time.Duration) error
 		return fmt.Errorf("device not found: %s", deviceID)
 	}
 
-	poller := modbus.NewPoller(device, interval, m.logger)
+	poller := modbus.NewPoller(m.context(), device, interval, m.logger)
 	if err := poller.Start(); err != nil {
 		return fmt.Errorf("failed to start poller: %w", err)
 	}
@@ -155,13 +517,76 @@ func (m *Manager) GetDeviceByName(name string) (*modbus.Device, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for _, device := range m.devices {
-		if device.Name == name {
-			return device, true
+	id, exists := m.byName[name]
+	if !exists {
+		return nil, false
+	}
+	return m.devices[id], true
+}
+
+// UnloadDevice stops the poller of a device, disconnects it and removes it
+// from the manager. It returns false if no device has the name.
+func (m *Manager) UnloadDevice(name string) bool {
+	m.mu.Lock()
+	var device *modbus.Device
+	var poller *modbus.Poller
+	if id, exists := m.byName[name]; exists {
+		device = m.devices[id]
+		poller = m.pollers[id]
+		delete(m.devices, id)
+		delete(m.pollers, id)
+		delete(m.byName, name)
+	}
+	m.mu.Unlock()
+
+	if device == nil {
+		return false
+	}
+	if poller != nil {
+		poller.Stop()
+	}
+	if err := device.Disconnect(); err != nil {
+		m.logger.Warn("Failed to disconnect device",
+			zap.String("device", name),
+			zap.Error(err))
+	}
+	m.logger.Info("Device unloaded", zap.String("name", name))
+	return true
+}
+
+// ApplySafeStates writes the safe state values of the named devices (all
+// devices if none are given). Devices without safe states are skipped.
+func (m *Manager) ApplySafeStates(ctx context.Context, reason string, deviceNames ...string) error {
+	targets := make(map[string]bool, len(deviceNames))
+	for _, name := range deviceNames {
+		targets[name] = true
+	}
+
+	var errs []error
+	for _, device := range m.ListDevices() {
+		if len(targets) > 0 && !targets[device.Name] {
+			continue
 		}
+		if len(device.SafeStates) == 0 {
+			continue
+		}
+
+		if err := device.WriteSafeStates(ctx); err != nil {
+			m.logger.Error("Failed to apply safe states",
+				zap.String("device", device.Name),
+				zap.String("reason", reason),
+				zap.Error(err))
+			errs = append(errs, fmt.Errorf("device %s: %w", device.Name, err))
+			continue
+		}
+
+		m.logger.Info("Safe states applied",
+			zap.String("device", device.Name),
+			zap.String("reason", reason),
+			zap.Int("outputs", len(device.SafeStates)))
 	}
 
-	return nil, false
+	return errors.Join(errs...)
 }
 
 // StopAll stops all pollers and disconnects all devices
@@ -0,0 +1,181 @@
+package devices
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// ParamInstanceID is bound to the instance ID of the created device in
+// every composition template
+const ParamInstanceID = "instance_id"
+
+// placeholderPattern matches "${name}" in template strings
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// CompositionTemplate is a coupler, terminal stack and IO mapping stored
+// centrally, e.g. per machine type. String values may contain "${name}"
+// placeholders bound when a device is created from the template.
+type CompositionTemplate struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Parameters  []TemplateParameter     `json:"parameters,omitempty"`
+	Composition types.CompositionConfig `json:"composition"`
+	IOMapping   map[string]string       `json:"io_mapping"`
+}
+
+// TemplateParameter is a placeholder of a composition template. Parameters
+// without default are required.
+type TemplateParameter struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+}
+
+func ParseCompositionTemplate(data []byte) (*CompositionTemplate, error) {
+	var t CompositionTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks the template structure and that every placeholder is a
+// declared parameter
+func (t *CompositionTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if t.Composition.Coupler.Module == "" {
+		return fmt.Errorf("coupler module is required")
+	}
+
+	declared := map[string]bool{ParamInstanceID: true}
+	for _, param := range t.Parameters {
+		if !placeholderPattern.MatchString("${" + param.Name + "}") {
+			return fmt.Errorf("invalid parameter name '%s'", param.Name)
+		}
+		if param.Name == ParamInstanceID {
+			return fmt.Errorf("parameter '%s' is reserved", ParamInstanceID)
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("duplicate parameter '%s'", param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	for _, name := range t.placeholders() {
+		if !declared[name] {
+			return fmt.Errorf("placeholder '${%s}' is not a declared parameter", name)
+		}
+	}
+	return nil
+}
+
+// placeholders returns the parameter names used by the template, sorted
+func (t *CompositionTemplate) placeholders() []string {
+	data, _ := json.Marshal(struct {
+		Composition types.CompositionConfig `json:"composition"`
+		IOMapping   map[string]string       `json:"io_mapping"`
+	}{t.Composition, t.IOMapping})
+
+	var names []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(string(data), -1) {
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Instantiate binds the parameters and returns the composition of a new
+// device. Placeholders are replaced in all strings of the composition and
+// in the IO mapping, keys included.
+func (t *CompositionTemplate) Instantiate(instanceID string, params map[string]string) (types.DeviceComposition, error) {
+	if strings.TrimSpace(instanceID) == "" {
+		return types.DeviceComposition{}, fmt.Errorf("instance_id is required")
+	}
+
+	values := map[string]string{ParamInstanceID: instanceID}
+	for _, param := range t.Parameters {
+		if value, ok := params[param.Name]; ok {
+			values[param.Name] = value
+		} else if param.Default != nil {
+			values[param.Name] = *param.Default
+		} else {
+			return types.DeviceComposition{}, fmt.Errorf("parameter '%s' is required", param.Name)
+		}
+	}
+	for name := range params {
+		if _, ok := values[name]; !ok || name == ParamInstanceID {
+			return types.DeviceComposition{}, fmt.Errorf("unknown parameter '%s'", name)
+		}
+	}
+
+	bind := func(s string) string {
+		return placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+			return values[m[2:len(m)-1]]
+		})
+	}
+
+	// Bound through a generic copy, so values are never parsed as JSON
+	data, err := json.Marshal(t.Composition)
+	if err != nil {
+		return types.DeviceComposition{}, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return types.DeviceComposition{}, err
+	}
+	if data, err = json.Marshal(bindStrings(generic, bind)); err != nil {
+		return types.DeviceComposition{}, err
+	}
+
+	comp := types.DeviceComposition{
+		InstanceID: instanceID,
+		IOMapping:  make(map[string]string, len(t.IOMapping)),
+	}
+	if err := json.Unmarshal(data, &comp.Composition); err != nil {
+		return types.DeviceComposition{}, fmt.Errorf("failed to bind composition: %w", err)
+	}
+	for logical, register := range t.IOMapping {
+		comp.IOMapping[bind(logical)] = bind(register)
+	}
+	if len(comp.IOMapping) != len(t.IOMapping) {
+		return types.DeviceComposition{}, fmt.Errorf("io_mapping has duplicate logical names after binding")
+	}
+
+	if comp.Composition.Coupler.Protocol == types.ProtocolModbusRTU {
+		if comp.Composition.Coupler.Serial == nil || comp.Composition.Coupler.Serial.Port == "" {
+			return types.DeviceComposition{}, fmt.Errorf("coupler serial port is empty")
+		}
+	} else if comp.Composition.Coupler.IPAddress == "" {
+		return types.DeviceComposition{}, fmt.Errorf("coupler ip_address is empty")
+	}
+	return comp, nil
+}
+
+// bindStrings applies bind to every string of a decoded JSON value
+func bindStrings(value any, bind func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return bind(v)
+	case map[string]any:
+		bound := make(map[string]any, len(v))
+		for key, item := range v {
+			bound[bind(key)] = bindStrings(item, bind)
+		}
+		return bound
+	case []any:
+		for i, item := range v {
+			v[i] = bindStrings(item, bind)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,310 @@
+package devices
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// watchDebounce collects the events of an editor save or a copied directory
+// into a single rescan
+const watchDebounce = 500 * time.Millisecond
+
+// DescriptorError is a descriptor file that failed to parse
+type DescriptorError struct {
+	Path       string    `json:"path"`
+	Kind       string    `json:"kind"` // "profile", "module", "index", "json" or "file"
+	Error      string    `json:"error"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// Watcher watches the descriptor search paths. Changes to profile, module
+// and vendor index files clear the profile cache and rescan all descriptors
+// for parse errors.
+type Watcher struct {
+	searchPaths []string
+	validator   *Validator
+	onChange    func()
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	errors   map[string]DescriptorError // Keyed by path
+	scanned  bool
+	fs       *fsnotify.Watcher
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewWatcher(searchPaths []string, onChange func(), logger *zap.Logger) (*Watcher, error) {
+	validator, err := NewValidator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validator: %w", err)
+	}
+
+	return &Watcher{
+		searchPaths: searchPaths,
+		validator:   validator,
+		onChange:    onChange,
+		logger:      logger,
+		errors:      make(map[string]DescriptorError),
+	}, nil
+}
+
+// Start scans all descriptors and watches the existing search paths.
+// Search paths created later are not watched.
+func (w *Watcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		return nil
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	for _, searchPath := range w.searchPaths {
+		if _, err := os.Stat(searchPath); err != nil {
+			continue // Missing directories are normal, e.g. the optional system profile directory
+		}
+		if err := w.addTree(fsw, searchPath); err != nil {
+			fsw.Close()
+			return err
+		}
+	}
+
+	w.fs = fsw
+	w.running = true
+	w.stopChan = make(chan struct{})
+	w.scanLocked()
+
+	w.wg.Add(1)
+	go w.loop(fsw, w.stopChan)
+
+	w.logger.Info("Watching device descriptors",
+		zap.Strings("search_paths", w.searchPaths),
+		zap.Int("errors", len(w.errors)))
+	return nil
+}
+
+// Stop ends watching
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.running = false
+	close(w.stopChan)
+	w.fs.Close()
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+// Watching reports whether the search paths are watched
+func (w *Watcher) Watching() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.running
+}
+
+// Errors returns the descriptors failing to parse, ordered by path. Without
+// watching, the search paths are scanned on every call.
+func (w *Watcher) Errors() []DescriptorError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.running || !w.scanned {
+		w.scanLocked()
+	}
+
+	list := make([]DescriptorError, 0, len(w.errors))
+	for _, e := range w.errors {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// addTree watches a directory and all directories below it
+func (w *Watcher) addTree(fsw *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := fsw.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) loop(fsw *fsnotify.Watcher, stop chan struct{}) {
+	defer w.wg.Done()
+
+	var debounce *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(fsw, event.Name); err != nil {
+						w.logger.Warn("Failed to watch new descriptor directory", zap.Error(err))
+					}
+				}
+			}
+			if !isDescriptorEvent(event) {
+				continue
+			}
+			if debounce == nil {
+				debounce = time.NewTimer(watchDebounce)
+			} else {
+				debounce.Reset(watchDebounce)
+			}
+			fire = debounce.C
+
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("Descriptor watch error", zap.Error(err))
+
+		case <-fire:
+			fire = nil
+			w.reload()
+		}
+	}
+}
+
+// isDescriptorEvent reports whether an event may change a descriptor
+func isDescriptorEvent(event fsnotify.Event) bool {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(event.Name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	case "":
+		return !event.Has(fsnotify.Write) // Directories created, removed or renamed
+	}
+	return false
+}
+
+// reload clears cached profiles and rescans after descriptors changed
+func (w *Watcher) reload() {
+	if w.onChange != nil {
+		w.onChange()
+	}
+
+	w.mu.Lock()
+	w.scanLocked()
+	errors := len(w.errors)
+	w.mu.Unlock()
+
+	if errors > 0 {
+		w.logger.Warn("Device descriptors reloaded with errors", zap.Int("errors", errors))
+	} else {
+		w.logger.Info("Device descriptors reloaded")
+	}
+}
+
+// scanLocked parses every descriptor below the search paths
+func (w *Watcher) scanLocked() {
+	previous := w.errors
+	w.errors = make(map[string]DescriptorError)
+	w.scanned = true
+
+	for _, searchPath := range w.searchPaths {
+		filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != searchPath && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir // e.g. staging directories of the descriptor sync
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			kind, parseErr := w.parse(path)
+			if parseErr == nil {
+				return nil
+			}
+
+			detectedAt := time.Now()
+			if prev, ok := previous[path]; ok && prev.Error == parseErr.Error() {
+				detectedAt = prev.DetectedAt
+			}
+			w.errors[path] = DescriptorError{
+				Path:       path,
+				Kind:       kind,
+				Error:      parseErr.Error(),
+				DetectedAt: detectedAt,
+			}
+			return nil
+		})
+	}
+}
+
+// parse checks a descriptor like the loader and composer would read it.
+// Files other than JSON and vendor indexes are ignored.
+func (w *Watcher) parse(path string) (string, error) {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(name))
+	if name != "index.yaml" && ext != ".json" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "file", err
+	}
+
+	if name == "index.yaml" {
+		var index map[string]any
+		return "index", yaml.Unmarshal(data, &index)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "json", fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch {
+	case fields["device_profile"] != nil:
+		return "profile", w.validator.ValidateProfile(data)
+	case fields["module"] != nil:
+		var module types.ModuleDefinition
+		if err := json.Unmarshal(data, &module); err != nil {
+			return "module", fmt.Errorf("failed to unmarshal module: %w", err)
+		}
+		if module.Module.ID == "" {
+			return "module", fmt.Errorf("module.id is required")
+		}
+	}
+	return "json", nil
+}
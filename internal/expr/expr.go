@@ -0,0 +1,441 @@
+// Package expr evaluates the numeric expressions of virtual registers and
+// workflow transitions.
+//
+// Expressions support numbers, true/false, references, arithmetic
+// (+ - * /), comparisons (< <= > >= == !=), logic (&& || !), parentheses and
+// the functions abs, min, max and scale(x, in_min, in_max, out_min, out_max).
+// Booleans evaluate to 1 and 0. References consist of letters, digits, "_"
+// and "." and may start with "$"; other names are written in backticks.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed expression
+type Expression struct {
+	root expression
+}
+
+// Parse parses an expression
+func Parse(src string) (*Expression, error) {
+	root, err := parseExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{root: root}, nil
+}
+
+// Eval evaluates the expression; resolve returns the value of a reference
+func (e *Expression) Eval(resolve func(name string) (float64, error)) (float64, error) {
+	return e.root.eval(resolve)
+}
+
+// Refs returns the names referenced by the expression
+func (e *Expression) Refs() []string {
+	return expressionRefs(e.root)
+}
+
+// expression is a node of a parsed expression
+type expression interface {
+	eval(resolve func(name string) (float64, error)) (float64, error)
+}
+
+type numberExpr float64
+
+type refExpr string
+
+type unaryExpr struct {
+	op      string
+	operand expression
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expression
+}
+
+type callExpr struct {
+	fn   string
+	args []expression
+}
+
+// functionArity lists the supported functions, -1 for variadic
+var functionArity = map[string]int{
+	"abs":   1,
+	"min":   -1,
+	"max":   -1,
+	"scale": 5,
+}
+
+func (e numberExpr) eval(func(string) (float64, error)) (float64, error) {
+	return float64(e), nil
+}
+
+func (e refExpr) eval(resolve func(string) (float64, error)) (float64, error) {
+	return resolve(string(e))
+}
+
+func (e *unaryExpr) eval(resolve func(string) (float64, error)) (float64, error) {
+	v, err := e.operand.eval(resolve)
+	if err != nil {
+		return 0, err
+	}
+	if e.op == "!" {
+		return boolValue(v == 0), nil
+	}
+	return -v, nil
+}
+
+func (e *binaryExpr) eval(resolve func(string) (float64, error)) (float64, error) {
+	l, err := e.left.eval(resolve)
+	if err != nil {
+		return 0, err
+	}
+
+	// Short-circuit logic
+	switch e.op {
+	case "&&":
+		if l == 0 {
+			return 0, nil
+		}
+	case "||":
+		if l != 0 {
+			return 1, nil
+		}
+	}
+
+	r, err := e.right.eval(resolve)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "<":
+		return boolValue(l < r), nil
+	case "<=":
+		return boolValue(l <= r), nil
+	case ">":
+		return boolValue(l > r), nil
+	case ">=":
+		return boolValue(l >= r), nil
+	case "==":
+		return boolValue(l == r), nil
+	case "!=":
+		return boolValue(l != r), nil
+	case "&&", "||":
+		return boolValue(r != 0), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", e.op)
+}
+
+func (e *callExpr) eval(resolve func(string) (float64, error)) (float64, error) {
+	args := make([]float64, len(e.args))
+	for i, arg := range e.args {
+		v, err := arg.eval(resolve)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+
+	switch e.fn {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "min":
+		m := args[0]
+		for _, v := range args[1:] {
+			m = math.Min(m, v)
+		}
+		return m, nil
+	case "max":
+		m := args[0]
+		for _, v := range args[1:] {
+			m = math.Max(m, v)
+		}
+		return m, nil
+	case "scale":
+		x, inMin, inMax, outMin, outMax := args[0], args[1], args[2], args[3], args[4]
+		if inMax == inMin {
+			return 0, fmt.Errorf("scale: empty input range")
+		}
+		return outMin + (x-inMin)*(outMax-outMin)/(inMax-inMin), nil
+	}
+	return 0, fmt.Errorf("unknown function %s", e.fn)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// expressionRefs returns the names referenced by an expression
+func expressionRefs(e expression) []string {
+	switch n := e.(type) {
+	case refExpr:
+		return []string{string(n)}
+	case *unaryExpr:
+		return expressionRefs(n.operand)
+	case *binaryExpr:
+		return append(expressionRefs(n.left), expressionRefs(n.right)...)
+	case *callExpr:
+		var refs []string
+		for _, arg := range n.args {
+			refs = append(refs, expressionRefs(arg)...)
+		}
+		return refs
+	}
+	return nil
+}
+
+// parseExpression parses an expression into its root node
+func parseExpression(src string) (expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "<=", ">=", "==", "!=", "<", ">", "+", "-", "*", "/", "!", "(", ")", ","}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		ch := rune(src[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+
+		case ch == '`':
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated name at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i+1 : i+1+end]})
+			i += end + 2
+
+		case unicode.IsDigit(ch) || (ch == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i]})
+
+		case unicode.IsLetter(ch) || ch == '_' || ch == '$':
+			start := i
+			i++
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i]})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if _, ok := p.peekOp(op); !ok {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q, got %q", op, p.tokens[p.pos].text)
+	}
+	p.pos++
+	return nil
+}
+
+// binary parses a left-associative chain of ops over next
+func (p *exprParser) binary(next func() (expression, error), ops ...string) (expression, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (expression, error) {
+	return p.binary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (expression, error) {
+	return p.binary(p.parseComparison, "&&")
+}
+
+func (p *exprParser) parseComparison() (expression, error) {
+	return p.binary(p.parseSum, "<=", ">=", "==", "!=", "<", ">")
+}
+
+func (p *exprParser) parseSum() (expression, error) {
+	return p.binary(p.parseProduct, "+", "-")
+}
+
+func (p *exprParser) parseProduct() (expression, error) {
+	return p.binary(p.parseUnary, "*", "/")
+}
+
+func (p *exprParser) parseUnary() (expression, error) {
+	if op, ok := p.peekOp("!", "-"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (expression, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return numberExpr(v), nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return numberExpr(1), nil
+		case "false":
+			return numberExpr(0), nil
+		}
+		if _, ok := p.peekOp("("); ok {
+			return p.parseCall(tok.text)
+		}
+		return refExpr(tok.text), nil
+
+	default:
+		if tok.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+}
+
+func (p *exprParser) parseCall(fn string) (expression, error) {
+	arity, ok := functionArity[fn]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", fn)
+	}
+	p.pos++ // "("
+
+	call := &callExpr{fn: fn}
+	if _, closed := p.peekOp(")"); !closed {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, more := p.peekOp(","); !more {
+				break
+			}
+			p.pos++
+		}
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+
+	if (arity >= 0 && len(call.args) != arity) || (arity < 0 && len(call.args) == 0) {
+		return nil, fmt.Errorf("wrong number of arguments for %s: %d", fn, len(call.args))
+	}
+	return call, nil
+}
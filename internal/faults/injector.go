@@ -0,0 +1,275 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Kind is the type of a simulated failure
+type Kind string
+
+const (
+	KindDeviceTimeout   Kind = "device_timeout"   // Device doesn't answer
+	KindModbusException Kind = "modbus_exception" // Device answers with an exception
+	KindDBLatency       Kind = "db_latency"       // Database queries are delayed
+	KindWSDrop          Kind = "ws_drop"          // WebSocket connections are dropped
+)
+
+// AllTargets matches every device or WebSocket client
+const AllTargets = "*"
+
+// defaultExceptionCode is "server device failure"
+const defaultExceptionCode = 0x04
+
+var ErrFaultNotFound = errors.New("fault not found")
+
+// Spec describes a fault to inject
+type Spec struct {
+	Kind          Kind    `json:"kind"`
+	Target        string  `json:"target,omitempty"`         // Device name or WebSocket client address/host, "*" for all
+	FunctionCode  uint8   `json:"function_code,omitempty"`  // Restricts device faults to one Modbus function, 0 = all
+	ExceptionCode uint8   `json:"exception_code,omitempty"` // modbus_exception, defaults to 0x04
+	LatencyMs     int     `json:"latency_ms,omitempty"`     // db_latency, delay per query
+	Probability   float64 `json:"probability,omitempty"`    // Chance per request/query/message, defaults to 1
+	Count         int     `json:"count,omitempty"`          // Number of injections, 0 = unlimited
+	DurationMs    int     `json:"duration_ms,omitempty"`    // Lifetime, 0 = until removed (bounded by max_duration)
+}
+
+// Fault is an active fault
+type Fault struct {
+	Spec
+	ID        uuid.UUID  `json:"id"`
+	Injected  int64      `json:"injected"` // Times the fault was applied
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks a spec and fills in defaults
+func (s *Spec) Validate() error {
+	switch s.Kind {
+	case KindDeviceTimeout, KindModbusException, KindWSDrop:
+		if s.Target == "" {
+			return fmt.Errorf("%s requires a target (use %q for all)", s.Kind, AllTargets)
+		}
+	case KindDBLatency:
+		if s.Target != "" && s.Target != AllTargets {
+			return fmt.Errorf("db_latency applies to all queries, target must be empty or %q", AllTargets)
+		}
+		s.Target = AllTargets
+		if s.LatencyMs <= 0 {
+			return errors.New("db_latency requires latency_ms > 0")
+		}
+	case "":
+		return errors.New("kind is required")
+	default:
+		return fmt.Errorf("unknown fault kind %q", s.Kind)
+	}
+
+	if s.Kind == KindModbusException && s.ExceptionCode == 0 {
+		s.ExceptionCode = defaultExceptionCode
+	}
+	if s.Probability < 0 || s.Probability > 1 {
+		return errors.New("probability must be between 0 and 1")
+	}
+	if s.Probability == 0 {
+		s.Probability = 1
+	}
+	if s.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	if s.DurationMs < 0 {
+		return errors.New("duration_ms must not be negative")
+	}
+	return nil
+}
+
+// Injector holds the active faults and decides per request whether one
+// applies. Only created when fault injection is enabled in the config.
+type Injector struct {
+	cfg    config.FaultsConfig
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	faults map[uuid.UUID]*Fault
+}
+
+func NewInjector(cfg config.FaultsConfig, logger *zap.Logger) *Injector {
+	return &Injector{
+		cfg:    cfg,
+		logger: logger,
+		faults: make(map[uuid.UUID]*Fault),
+	}
+}
+
+// Add activates a fault. The lifetime is capped at max_duration.
+func (i *Injector) Add(spec Spec, actor string) (*Fault, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	fault := &Fault{
+		Spec:      spec,
+		ID:        uuid.New(),
+		CreatedBy: actor,
+		CreatedAt: now,
+	}
+	lifetime := time.Duration(spec.DurationMs) * time.Millisecond
+	if i.cfg.MaxDuration > 0 && (lifetime == 0 || lifetime > i.cfg.MaxDuration) {
+		lifetime = i.cfg.MaxDuration
+	}
+	if lifetime > 0 {
+		expires := now.Add(lifetime)
+		fault.ExpiresAt = &expires
+	}
+
+	i.mu.Lock()
+	i.faults[fault.ID] = fault
+	result := *fault
+	i.mu.Unlock()
+
+	i.logger.Warn("Fault injection activated",
+		zap.String("id", fault.ID.String()),
+		zap.String("kind", string(fault.Kind)),
+		zap.String("target", fault.Target),
+		zap.String("actor", actor))
+	return &result, nil
+}
+
+// List returns the active faults, oldest first
+func (i *Injector) List() []Fault {
+	i.mu.Lock()
+	i.expire(time.Now())
+	list := make([]Fault, 0, len(i.faults))
+	for _, f := range i.faults {
+		list = append(list, *f)
+	}
+	i.mu.Unlock()
+
+	sort.Slice(list, func(a, b int) bool { return list[a].CreatedAt.Before(list[b].CreatedAt) })
+	return list
+}
+
+// Remove deactivates a fault
+func (i *Injector) Remove(id uuid.UUID) error {
+	i.mu.Lock()
+	_, ok := i.faults[id]
+	delete(i.faults, id)
+	i.mu.Unlock()
+
+	if !ok {
+		return ErrFaultNotFound
+	}
+	i.logger.Info("Fault injection removed", zap.String("id", id.String()))
+	return nil
+}
+
+// Clear deactivates all faults and returns how many were active
+func (i *Injector) Clear() int {
+	i.mu.Lock()
+	n := len(i.faults)
+	i.faults = make(map[uuid.UUID]*Fault)
+	i.mu.Unlock()
+
+	if n > 0 {
+		i.logger.Info("Fault injection cleared", zap.Int("faults", n))
+	}
+	return n
+}
+
+// DeviceHook returns the Modbus fault hook of a device
+func (i *Injector) DeviceHook(device string) modbus.FaultHook {
+	return func(functionCode uint8) *modbus.Fault {
+		f := i.fire(func(f *Fault) bool {
+			if f.Kind != KindDeviceTimeout && f.Kind != KindModbusException {
+				return false
+			}
+			if f.FunctionCode != 0 && f.FunctionCode != functionCode {
+				return false
+			}
+			return f.Target == AllTargets || f.Target == device
+		})
+		if f == nil {
+			return nil
+		}
+		if f.Kind == KindDeviceTimeout {
+			return &modbus.Fault{Timeout: true}
+		}
+		return &modbus.Fault{ExceptionCode: f.ExceptionCode}
+	}
+}
+
+// QueryLatency returns the delay for the next database query
+func (i *Injector) QueryLatency() time.Duration {
+	f := i.fire(func(f *Fault) bool { return f.Kind == KindDBLatency })
+	if f == nil {
+		return 0
+	}
+	return time.Duration(f.LatencyMs) * time.Millisecond
+}
+
+// DropConnection reports whether a WebSocket client is to be disconnected
+func (i *Injector) DropConnection(targets ...string) bool {
+	return i.fire(func(f *Fault) bool {
+		if f.Kind != KindWSDrop {
+			return false
+		}
+		if f.Target == AllTargets {
+			return true
+		}
+		for _, target := range targets {
+			if f.Target == target {
+				return true
+			}
+		}
+		return false
+	}) != nil
+}
+
+// fire returns a copy of the oldest matching fault if it applies to this
+// request and counts the injection; faults used up are removed
+func (i *Injector) fire(match func(f *Fault) bool) *Fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.faults) == 0 {
+		return nil
+	}
+	i.expire(time.Now())
+
+	var found *Fault
+	for _, f := range i.faults {
+		if match(f) && (found == nil || f.CreatedAt.Before(found.CreatedAt)) {
+			found = f
+		}
+	}
+	if found == nil || (found.Probability < 1 && rand.Float64() >= found.Probability) {
+		return nil
+	}
+
+	found.Injected++
+	if found.Count > 0 && found.Injected >= int64(found.Count) {
+		delete(i.faults, found.ID)
+	}
+	result := *found
+	return &result
+}
+
+// expire removes faults past their lifetime; called with i.mu held
+func (i *Injector) expire(now time.Time) {
+	for id, f := range i.faults {
+		if f.ExpiresAt != nil && now.After(*f.ExpiresAt) {
+			delete(i.faults, id)
+		}
+	}
+}
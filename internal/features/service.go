@@ -0,0 +1,283 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Flags guarding capabilities that are new or risky on some installations
+const (
+	FlagParallelSteps   = "parallel_steps"
+	FlagPipelinedModbus = "pipelined_modbus"
+	FlagModbusRTU       = "modbus_rtu"
+)
+
+// Audit actions
+const (
+	AuditFlagChanged = "feature.changed"
+	AuditFlagReset   = "feature.reset"
+)
+
+var (
+	// ErrUnknownFlag is returned for names not in Definitions
+	ErrUnknownFlag = errors.New("unknown feature flag")
+
+	// ErrInvalidWorkflow is returned for overrides keyed by something other
+	// than a workflow ID
+	ErrInvalidWorkflow = errors.New("invalid workflow ID")
+)
+
+// Definition describes a flag and its built-in default
+type Definition struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Definitions lists the known flags
+var Definitions = []Definition{
+	{FlagParallelSteps, "Run workflow steps marked as parallel concurrently", false},
+	{FlagPipelinedModbus, "Send Modbus TCP requests without waiting for the previous response", false},
+	{FlagModbusRTU, "Allow devices connected over Modbus RTU", false},
+}
+
+// Flag is the current state of a feature flag
+type Flag struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Default     bool            `json:"default"`
+	Enabled     bool            `json:"enabled"`
+	Workflows   map[string]bool `json:"workflows,omitempty"` // Workflow ID -> enabled, overrides Enabled
+	UpdatedBy   string          `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+}
+
+// Service keeps the flag states in memory; changes are stored in the
+// database and reported to the change handlers
+type Service struct {
+	storage *storage.PostgresClient
+	logger  *zap.Logger
+
+	mu    sync.RWMutex
+	flags map[string]*Flag
+
+	handlersMu sync.RWMutex
+	handlers   []func(Flag)
+}
+
+func NewService(store *storage.PostgresClient, logger *zap.Logger) *Service {
+	s := &Service{
+		storage: store,
+		logger:  logger,
+		flags:   make(map[string]*Flag, len(Definitions)),
+	}
+	for _, def := range Definitions {
+		s.flags[def.Name] = defaultFlag(def)
+	}
+	return s
+}
+
+func defaultFlag(def Definition) *Flag {
+	return &Flag{Name: def.Name, Description: def.Description, Default: def.Default, Enabled: def.Default}
+}
+
+// Load reads the stored flag states. Stored flags no longer known are
+// ignored.
+func (s *Service) Load(ctx context.Context) error {
+	states, err := s.storage.ListFeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, state := range states {
+		flag, ok := s.flags[state.Name]
+		if !ok {
+			s.logger.Warn("Ignoring stored state of unknown feature flag", zap.String("flag", state.Name))
+			continue
+		}
+		applyState(flag, state)
+	}
+	return nil
+}
+
+func applyState(flag *Flag, state storage.FeatureFlagState) {
+	updatedAt := state.UpdatedAt
+	flag.Enabled = state.Enabled
+	flag.Workflows = state.Workflows
+	flag.UpdatedBy = state.UpdatedBy
+	flag.UpdatedAt = &updatedAt
+}
+
+// OnChange registers a handler called after a flag was changed or reset
+func (s *Service) OnChange(handler func(Flag)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// Enabled reports whether a flag is enabled for the installation. Unknown
+// flags are disabled.
+func (s *Service) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.flags[name]
+	return ok && flag.Enabled
+}
+
+// EnabledFor reports whether a flag is enabled for a workflow, which may
+// override the installation state
+func (s *Service) EnabledFor(name string, workflowID uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.flags[name]
+	if !ok {
+		return false
+	}
+	if enabled, overridden := flag.Workflows[workflowID.String()]; overridden {
+		return enabled
+	}
+	return flag.Enabled
+}
+
+// List returns all flags in the order of Definitions
+func (s *Service) List() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flags := make([]Flag, 0, len(Definitions))
+	for _, def := range Definitions {
+		flags = append(flags, s.copyFlag(def.Name))
+	}
+	return flags
+}
+
+// Get returns a flag
+func (s *Service) Get(name string) (Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.flags[name]; !ok {
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	return s.copyFlag(name), nil
+}
+
+func (s *Service) copyFlag(name string) Flag {
+	flag := *s.flags[name]
+	flag.Workflows = maps.Clone(flag.Workflows)
+	return flag
+}
+
+// Set changes the installation state of a flag if enabled is given, and
+// its workflow overrides: true or false overrides, nil removes an override
+func (s *Service) Set(ctx context.Context, name string, enabled *bool, workflows map[string]*bool, actor string) (Flag, error) {
+	for id := range workflows {
+		if _, err := uuid.Parse(id); err != nil {
+			return Flag{}, fmt.Errorf("%w: %q", ErrInvalidWorkflow, id)
+		}
+	}
+
+	s.mu.Lock()
+	flag, ok := s.flags[name]
+	if !ok {
+		s.mu.Unlock()
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+
+	state := storage.FeatureFlagState{
+		Name:      name,
+		Enabled:   flag.Enabled,
+		Workflows: maps.Clone(flag.Workflows),
+		UpdatedBy: actor,
+	}
+	if state.Workflows == nil {
+		state.Workflows = make(map[string]bool)
+	}
+	if enabled != nil {
+		state.Enabled = *enabled
+	}
+	for id, value := range workflows {
+		if value == nil {
+			delete(state.Workflows, id)
+		} else {
+			state.Workflows[id] = *value
+		}
+	}
+
+	if err := s.storage.SaveFeatureFlag(ctx, &state); err != nil {
+		s.mu.Unlock()
+		return Flag{}, err
+	}
+	applyState(flag, state)
+	changed := s.copyFlag(name)
+	s.mu.Unlock()
+
+	s.logger.Info("Feature flag changed",
+		zap.String("flag", name),
+		zap.Bool("enabled", changed.Enabled),
+		zap.Int("workflow_overrides", len(changed.Workflows)),
+		zap.String("actor", actor))
+	s.audit(ctx, AuditFlagChanged, actor, map[string]any{
+		"flag":      name,
+		"enabled":   changed.Enabled,
+		"workflows": changed.Workflows,
+	})
+	s.notify(changed)
+	return changed, nil
+}
+
+// Reset restores the default of a flag and removes its workflow overrides
+func (s *Service) Reset(ctx context.Context, name, actor string) (Flag, error) {
+	s.mu.Lock()
+	def, ok := definition(name)
+	if !ok {
+		s.mu.Unlock()
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if err := s.storage.DeleteFeatureFlag(ctx, name); err != nil {
+		s.mu.Unlock()
+		return Flag{}, err
+	}
+	s.flags[name] = defaultFlag(def)
+	reset := s.copyFlag(name)
+	s.mu.Unlock()
+
+	s.logger.Info("Feature flag reset", zap.String("flag", name), zap.String("actor", actor))
+	s.audit(ctx, AuditFlagReset, actor, map[string]any{"flag": name, "enabled": reset.Enabled})
+	s.notify(reset)
+	return reset, nil
+}
+
+func definition(name string) (Definition, bool) {
+	for _, def := range Definitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+func (s *Service) notify(flag Flag) {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+	for _, handler := range s.handlers {
+		handler(flag)
+	}
+}
+
+func (s *Service) audit(ctx context.Context, action, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := s.storage.RecordAudit(ctx, entry); err != nil {
+		s.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
@@ -2,11 +2,26 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
+	"github.com/KevinKickass/OpenMachineCore/internal/buildinfo"
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/counters"
+	"github.com/KevinKickass/OpenMachineCore/internal/descriptors"
 	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/faults"
+	"github.com/KevinKickass/OpenMachineCore/internal/features"
 	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/manual"
+	"github.com/KevinKickass/OpenMachineCore/internal/onboarding"
+	"github.com/KevinKickass/OpenMachineCore/internal/outbox"
+	"github.com/KevinKickass/OpenMachineCore/internal/routing"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/shifts"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/usage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
 )
 
@@ -16,6 +31,136 @@ type SystemStatus struct {
 	ActiveWorkflow   string `json:"active_workflow,omitempty"`
 	DeviceCount      int    `json:"device_count"`
 	ConnectedDevices int    `json:"connected_devices"`
+
+	Heartbeat *HeartbeatStatus `json:"heartbeat,omitempty"`
+	Resources *ResourceStatus  `json:"resources,omitempty"`
+
+	// Feature flag states, for support
+	Features []features.Flag `json:"features,omitempty"`
+}
+
+// HeartbeatStatus represents the health of the watchdog heartbeat output
+type HeartbeatStatus struct {
+	Running             bool       `json:"running"`
+	Healthy             bool       `json:"healthy"`
+	Device              string     `json:"device"`
+	Register            string     `json:"register"`
+	Interval            string     `json:"interval"`
+	LastWrite           *time.Time `json:"last_write,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// ResourceStatus is the last sample of the resource self-monitoring
+type ResourceStatus struct {
+	Goroutines          int       `json:"goroutines"`
+	MemoryMB            int       `json:"memory_mb"`
+	DBPoolAcquired      int       `json:"db_pool_acquired"`
+	DBPoolMax           int       `json:"db_pool_max"`
+	WSClients           int       `json:"ws_clients"`
+	Breaches            []string  `json:"breaches"` // Metrics above their threshold
+	RejectingExecutions bool      `json:"rejecting_executions"`
+	SampledAt           time.Time `json:"sampled_at"`
+}
+
+// DetailedStatus extends the system status with the build, runtime and
+// storage information support needs
+type DetailedStatus struct {
+	SystemStatus
+
+	UpdateProgress UpdateStatus          `json:"update_progress"`
+	Build          buildinfo.Info        `json:"build"`
+	Clock          ClockStatus           `json:"clock"`
+	StartedAt      time.Time             `json:"started_at"`
+	Uptime         string                `json:"uptime"`
+	UptimeSeconds  int64                 `json:"uptime_seconds"`
+	Runtime        RuntimeStatus         `json:"runtime"`
+	Listeners      ListenerStatus        `json:"listeners"`
+	EventStream    EventStreamStatus     `json:"event_stream"`
+	DeviceStartup  *DeviceStartupReport  `json:"device_startup,omitempty"`
+	Database       *storage.DatabaseInfo `json:"database,omitempty"`
+	DatabaseError  string                `json:"database_error,omitempty"`
+	License        string                `json:"license"`
+	FeatureSummary FeatureSummary        `json:"feature_summary"`
+}
+
+// EventStreamStatus counts the execution events lost to slow stream
+// subscribers since start
+type EventStreamStatus struct {
+	Subscribers  int   `json:"subscribers"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"` // Subscribers closed by the disconnect policy
+}
+
+// Device startup states
+const (
+	DeviceStartupLoaded   = "loaded"
+	DeviceStartupRetrying = "retrying" // Offline at startup, retried in the background
+	DeviceStartupFailed   = "failed"
+)
+
+// DeviceStartupReport is how loading the stored devices went at startup.
+// Devices retried in the background update it when they come online.
+type DeviceStartupReport struct {
+	StartedAt   time.Time       `json:"started_at"`
+	DurationMs  int64           `json:"duration_ms"` // Until every device was tried once
+	Concurrency int             `json:"concurrency"`
+	Loaded      int             `json:"loaded"`
+	Retrying    int             `json:"retrying"`
+	Failed      int             `json:"failed"`
+	Devices     []DeviceStartup `json:"devices"`
+}
+
+// DeviceStartup is the startup result of a stored device
+type DeviceStartup struct {
+	InstanceID string     `json:"instance_id"`
+	Status     string     `json:"status"`
+	DurationMs int64      `json:"duration_ms"` // First attempt: compose, connect, identify
+	Attempts   int        `json:"attempts"`
+	Error      string     `json:"error,omitempty"` // Last error
+	LoadedAt   *time.Time `json:"loaded_at,omitempty"`
+}
+
+// UpdateStatus is the progress of a running system update
+type UpdateStatus struct {
+	Phase     string `json:"phase"`
+	Progress  int    `json:"progress"` // 0-100
+	Message   string `json:"message"`
+	StartedAt int64  `json:"started_at"`
+}
+
+// ClockStatus is the system clock, to spot wrong time zones and drift
+type ClockStatus struct {
+	Time      time.Time `json:"time"`
+	Unix      int64     `json:"unix"`
+	Timezone  string    `json:"timezone"`
+	UTCOffset string    `json:"utc_offset"` // "+01:00"
+}
+
+// RuntimeStatus describes the Go runtime of the process
+type RuntimeStatus struct {
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	CPUs         int    `json:"cpus"`
+	GOMAXPROCS   int    `json:"gomaxprocs"`
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapSys      uint64 `json:"heap_sys_bytes"`
+	Sys          uint64 `json:"sys_bytes"` // Total memory obtained from the OS
+	NumGC        uint32 `json:"num_gc"`
+	GCPauseTotal string `json:"gc_pause_total"`
+}
+
+// ListenerStatus is where the servers listen, "tcp :8080" or "unix /run/omc.sock"
+type ListenerStatus struct {
+	HTTP string `json:"http"`
+	GRPC string `json:"grpc"`
+}
+
+// FeatureSummary lists the enabled feature flags
+type FeatureSummary struct {
+	Total   int      `json:"total"`
+	Enabled []string `json:"enabled"`
 }
 
 type LifecycleManager interface {
@@ -24,7 +169,22 @@ type LifecycleManager interface {
 	DeviceManager() *devices.Manager
 	WorkflowEngine() *engine.Engine
 	MachineController() *machine.Controller
+	UsageTracker() *usage.Tracker
+	AlarmManager() *alarms.Manager
+	ShiftScheduler() *shifts.Scheduler
+	Validation() *workflow.NightlyValidation
+	DescriptorSync() *descriptors.Syncer
+	DescriptorWatcher() *devices.Watcher
+	Counters() *counters.Accumulator
+	FaultInjector() *faults.Injector
+	Secrets() *secrets.Manager
+	Outbox() *outbox.Dispatcher
+	Router() *routing.Router
+	Features() *features.Service
+	Manual() *manual.Manager
+	Onboarding() *onboarding.Manager
 	GetCurrentStatus() SystemStatus
+	GetCurrentStatusDetailed(ctx context.Context) DetailedStatus
 	TriggerUpdate(workflowPath string) error
 	Shutdown(ctx context.Context) error
 }
@@ -25,6 +25,19 @@ type Controller struct {
 	currentExecID    uuid.UUID
 	productionCycles int
 	errorMessage     string
+	queuedCommand    Command
+	queuedOptions    CommandOptions
+
+	// State change subscribers (gRPC StreamState)
+	subMu       sync.Mutex
+	subscribers []chan StateChange
+
+	// Parent of the workflow monitors and queued commands
+	ctx context.Context
+
+	// Optional: applied to all devices when the stop workflow fails
+	safeStates       engine.SafeStateApplier
+	safeStateTimeout time.Duration
 
 	// Workflow IDs für verschiedene Abläufe
 	stopWorkflowID       uuid.UUID
@@ -44,9 +57,13 @@ func NewController(
 		workflowEngine: workflowEngine,
 		storage:        storage,
 		currentState:   StateStopped,
+		ctx:            context.Background(),
 	}
 }
 
+// statusTimeout bounds a single execution status lookup of a monitor
+const statusTimeout = 5 * time.Second
+
 // SetWorkflows configures the workflow IDs for machine operations
 func (c *Controller) SetWorkflows(stopID, homeID, productionID uuid.UUID) {
 	c.mu.Lock()
@@ -62,41 +79,127 @@ func (c *Controller) SetWorkflows(stopID, homeID, productionID uuid.UUID) {
 		zap.String("production", productionID.String()))
 }
 
-// ExecuteCommand handles machine commands
-func (c *Controller) ExecuteCommand(ctx context.Context, cmd Command) error {
+// SetContext sets the parent context of workflow monitors and queued
+// commands; cancelling it stops monitoring
+func (c *Controller) SetContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
+}
+
+func (c *Controller) context() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx
+}
+
+// executionStatus looks up the status of a monitored execution
+func (c *Controller) executionStatus(ctx context.Context, execID uuid.UUID) (*storage.WorkflowExecution, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+	exec, _, err := c.workflowEngine.GetExecutionStatus(ctx, execID)
+	return exec, err
+}
+
+// SetSafeStateApplier enables writing safe states when the stop workflow fails
+func (c *Controller) SetSafeStateApplier(applier engine.SafeStateApplier, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.safeStates = applier
+	c.safeStateTimeout = timeout
+}
+
+// ExecuteCommand handles machine commands. Commands that are not valid for the
+// current state are rejected with a *CommandRejection.
+func (c *Controller) ExecuteCommand(ctx context.Context, cmd Command, opts CommandOptions) (CommandResult, error) {
+	// Invalid IDs must not leave the machine in error after the state changed
+	if err := opts.Correlation.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", engine.ErrInvalidCorrelation, err)
+	}
+
 	c.mu.Lock()
 	currentState := c.currentState
-	c.mu.Unlock()
 
 	c.logger.Info("Machine command received",
 		zap.String("command", string(cmd)),
-		zap.String("current_state", string(currentState)))
+		zap.String("current_state", string(currentState)),
+		zap.Bool("queue", opts.Queue))
+
+	rejection := ValidateCommand(currentState, cmd)
+	if rejection != nil && rejection.Code == RejectQueueRequired && opts.Queue {
+		if c.queuedCommand != "" {
+			c.mu.Unlock()
+			return "", &CommandRejection{
+				Code:    RejectAlreadyQueued,
+				Command: cmd,
+				State:   currentState,
+				Reason:  fmt.Sprintf("command %s is already queued", c.queuedCommand),
+			}
+		}
+
+		c.queuedCommand = cmd
+		c.queuedOptions = opts
+		c.queuedOptions.Queue = false
+		c.mu.Unlock()
+
+		c.logger.Info("Machine command queued",
+			zap.String("command", string(cmd)),
+			zap.String("current_state", string(currentState)))
+		return CommandQueued, nil
+	}
+	c.mu.Unlock()
 
+	if rejection != nil {
+		return "", rejection
+	}
+
+	var err error
 	switch cmd {
 	case CommandHome:
-		return c.executeHome(ctx)
+		err = c.executeHome(ctx)
 	case CommandStart:
-		return c.executeStart(ctx)
+		err = c.executeStart(ctx, opts)
 	case CommandStop:
-		return c.executeStop(ctx)
+		err = c.executeStop(ctx, opts)
 	case CommandReset:
-		return c.executeReset(ctx)
-	default:
-		return fmt.Errorf("unknown command: %s", cmd)
+		err = c.executeReset(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return CommandAccepted, nil
+}
+
+// checkWorkflowConfigured rejects commands whose workflow has not been configured
+func checkWorkflowConfigured(cmd Command, state State, workflowID uuid.UUID) *CommandRejection {
+	if workflowID != uuid.Nil {
+		return nil
+	}
+	return &CommandRejection{
+		Code:    RejectWorkflowNotConfigured,
+		Command: cmd,
+		State:   state,
+		Reason:  fmt.Sprintf("no workflow configured for command %s", cmd),
 	}
 }
 
 func (c *Controller) executeHome(ctx context.Context) error {
 	c.mu.Lock()
-	if c.currentState != StateStopped {
+	if rejection := ValidateCommand(c.currentState, CommandHome); rejection != nil {
 		c.mu.Unlock()
-		return fmt.Errorf("cannot home: machine must be stopped (current: %s)", c.currentState)
+		return rejection
+	}
+	if rejection := checkWorkflowConfigured(CommandHome, c.currentState, c.homeWorkflowID); rejection != nil {
+		c.mu.Unlock()
+		return rejection
 	}
 	c.currentState = StateHoming
 	c.mu.Unlock()
 
 	// Execute homing workflow
-	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.homeWorkflowID, nil)
+	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.homeWorkflowID, nil, storage.Correlation{})
 	if err != nil {
 		c.setState(StateError, err.Error())
 		return err
@@ -112,18 +215,22 @@ func (c *Controller) executeHome(ctx context.Context) error {
 	return nil
 }
 
-func (c *Controller) executeStart(ctx context.Context) error {
+func (c *Controller) executeStart(ctx context.Context, opts CommandOptions) error {
 	c.mu.Lock()
-	if c.currentState != StateReady {
+	if rejection := ValidateCommand(c.currentState, CommandStart); rejection != nil {
 		c.mu.Unlock()
-		return fmt.Errorf("cannot start: machine must be ready (current: %s)", c.currentState)
+		return rejection
+	}
+	if rejection := checkWorkflowConfigured(CommandStart, c.currentState, c.productionWorkflowID); rejection != nil {
+		c.mu.Unlock()
+		return rejection
 	}
 	c.currentState = StateRunning
 	c.productionCycles = 0
 	c.mu.Unlock()
 
 	// Execute production workflow (with continuous loop)
-	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.productionWorkflowID, nil)
+	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.productionWorkflowID, nil, opts.Correlation)
 	if err != nil {
 		c.setState(StateError, err.Error())
 		return err
@@ -139,23 +246,31 @@ func (c *Controller) executeStart(ctx context.Context) error {
 	return nil
 }
 
-func (c *Controller) executeStop(ctx context.Context) error {
+func (c *Controller) executeStop(ctx context.Context, opts CommandOptions) error {
 	c.mu.Lock()
-	if c.currentState != StateRunning {
+	if rejection := ValidateCommand(c.currentState, CommandStop); rejection != nil {
+		c.mu.Unlock()
+		return rejection
+	}
+	if rejection := checkWorkflowConfigured(CommandStop, c.currentState, c.stopWorkflowID); rejection != nil {
 		c.mu.Unlock()
-		return fmt.Errorf("cannot stop: machine not running (current: %s)", c.currentState)
+		return rejection
 	}
 
 	// Cancel running production workflow
-	if c.currentExecID != uuid.Nil {
-		c.workflowEngine.CancelExecution(ctx, c.currentExecID)
+	if c.currentState == StateRunning && c.currentExecID != uuid.Nil {
+		c.workflowEngine.CancelExecution(ctx, c.currentExecID, engine.CancelReason{
+			Source: engine.CancelSourceMachine,
+			Reason: opts.Reason,
+			Actor:  opts.Actor,
+		})
 	}
 
 	c.currentState = StateStopping
 	c.mu.Unlock()
 
 	// Execute stop workflow
-	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.stopWorkflowID, nil)
+	execID, err := c.workflowEngine.ExecuteWorkflow(ctx, c.stopWorkflowID, nil, storage.Correlation{})
 	if err != nil {
 		c.setState(StateError, err.Error())
 		return err
@@ -175,28 +290,70 @@ func (c *Controller) executeReset(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.currentState != StateError && c.currentState != StateEmergency {
-		return fmt.Errorf("cannot reset: no error state (current: %s)", c.currentState)
+	if rejection := ValidateCommand(c.currentState, CommandReset); rejection != nil {
+		return rejection
 	}
 
 	c.currentState = StateStopped
 	c.errorMessage = ""
 	c.currentExecID = uuid.Nil
+	c.queuedCommand = ""
 
 	c.logger.Info("Machine reset to stopped state")
 	return nil
 }
 
+// runQueuedCommand executes a command queued during the previous workflow
+func (c *Controller) runQueuedCommand() {
+	c.mu.Lock()
+	cmd := c.queuedCommand
+	opts := c.queuedOptions
+	c.queuedCommand = ""
+	c.mu.Unlock()
+
+	if cmd == "" {
+		return
+	}
+
+	c.logger.Info("Executing queued machine command", zap.String("command", string(cmd)))
+
+	if _, err := c.ExecuteCommand(c.context(), cmd, opts); err != nil {
+		c.logger.Error("Queued machine command failed",
+			zap.String("command", string(cmd)),
+			zap.Error(err))
+	}
+}
+
+// clearQueuedCommand drops a queued command after its workflow did not succeed
+func (c *Controller) clearQueuedCommand() {
+	c.mu.Lock()
+	cmd := c.queuedCommand
+	c.queuedCommand = ""
+	c.mu.Unlock()
+
+	if cmd != "" {
+		c.logger.Warn("Dropping queued machine command", zap.String("command", string(cmd)))
+	}
+}
+
 func (c *Controller) monitorWorkflow(execID uuid.UUID, targetState State) {
-	// Poll workflow status
-	ctx := context.Background()
+	// Poll workflow status until shutdown
+	ctx := c.context()
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		exec, _, err := c.workflowEngine.GetExecutionStatus(ctx, execID)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		exec, err := c.executionStatus(ctx, execID)
 		if err != nil {
-			c.logger.Error("Failed to get execution status", zap.Error(err))
+			if ctx.Err() == nil {
+				c.logger.Error("Failed to get execution status", zap.Error(err))
+			}
 			continue
 		}
 
@@ -206,29 +363,60 @@ func (c *Controller) monitorWorkflow(execID uuid.UUID, targetState State) {
 			c.logger.Info("Workflow completed successfully",
 				zap.String("execution_id", execID.String()),
 				zap.String("new_state", string(targetState)))
+			c.runQueuedCommand()
 			return
 
 		case storage.StatusFailed:
+			c.clearQueuedCommand()
 			c.setState(StateError, exec.Error)
 			c.logger.Error("Workflow failed",
 				zap.String("execution_id", execID.String()),
 				zap.String("error", exec.Error))
+			if targetState == StateStopped {
+				c.applySafeStates("stop_workflow_failed")
+			}
 			return
 
 		case storage.StatusCancelled:
 			// Expected for stop command
+			c.clearQueuedCommand()
 			return
 		}
 	}
 }
 
+// applySafeStates drives all device outputs to their safe states
+func (c *Controller) applySafeStates(reason string) {
+	c.mu.RLock()
+	applier := c.safeStates
+	timeout := c.safeStateTimeout
+	c.mu.RUnlock()
+
+	if applier == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := applier.ApplySafeStates(ctx, reason); err != nil {
+		c.logger.Error("Failed to apply safe states", zap.String("reason", reason), zap.Error(err))
+	}
+}
+
 func (c *Controller) monitorProductionWorkflow(execID uuid.UUID) {
-	// Monitor for errors during production
-	ctx := context.Background()
+	// Monitor for errors during production until shutdown
+	ctx := c.context()
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		c.mu.RLock()
 		state := c.currentState
 		c.mu.RUnlock()
@@ -237,7 +425,7 @@ func (c *Controller) monitorProductionWorkflow(execID uuid.UUID) {
 			return
 		}
 
-		exec, _, err := c.workflowEngine.GetExecutionStatus(ctx, execID)
+		exec, err := c.executionStatus(ctx, execID)
 		if err != nil {
 			continue
 		}
@@ -279,12 +467,56 @@ func (c *Controller) setState(state State, errorMsg string) {
 			string(previousState),
 		))
 	}
+
+	c.notifySubscribers(StateChange{Status: c.statusLocked(), PreviousState: previousState})
+}
+
+// SubscribeState returns a channel receiving every machine state change
+func (c *Controller) SubscribeState() <-chan StateChange {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	ch := make(chan StateChange, 16)
+	c.subscribers = append(c.subscribers, ch)
+	return ch
+}
+
+// UnsubscribeState removes and closes a channel returned by SubscribeState
+func (c *Controller) UnsubscribeState(ch <-chan StateChange) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for i, sub := range c.subscribers {
+		if sub == ch {
+			c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+			close(sub)
+			break
+		}
+	}
+}
+
+func (c *Controller) notifySubscribers(change StateChange) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- change:
+		default:
+			// Skip if channel is full
+		}
+	}
 }
 
 func (c *Controller) GetStatus() MachineStatus {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	return c.statusLocked()
+}
+
+// statusLocked builds the status; the caller holds c.mu
+func (c *Controller) statusLocked() MachineStatus {
 	// Build config if any workflow is configured
 	var config *MachineConfig
 	if c.stopWorkflowID != uuid.Nil || c.homeWorkflowID != uuid.Nil || c.productionWorkflowID != uuid.Nil {
@@ -306,6 +538,8 @@ func (c *Controller) GetStatus() MachineStatus {
 		ErrorMessage:     c.errorMessage,
 		ProductionCycles: c.productionCycles,
 		LastStateChange:  time.Now(),
+		AllowedCommands:  AllowedCommands(c.currentState),
+		QueuedCommand:    c.queuedCommand,
 		Config:           config,
 	}
 }
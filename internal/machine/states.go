@@ -1,6 +1,11 @@
 package machine
 
-import "time" // Hinzufügen
+import (
+	"fmt"
+	"time" // Hinzufügen
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
 
 type State string
 
@@ -23,6 +28,122 @@ const (
 	CommandReset Command = "reset"
 )
 
+// RejectionCode is a machine-readable reason why a command was not accepted
+type RejectionCode string
+
+const (
+	RejectUnknownCommand        RejectionCode = "UNKNOWN_COMMAND"
+	RejectInvalidState          RejectionCode = "INVALID_STATE"
+	RejectQueueRequired         RejectionCode = "QUEUE_REQUIRED"
+	RejectAlreadyQueued         RejectionCode = "ALREADY_QUEUED"
+	RejectWorkflowNotConfigured RejectionCode = "WORKFLOW_NOT_CONFIGURED"
+)
+
+// CommandRejection is returned when a command is not valid for the current state
+type CommandRejection struct {
+	Code    RejectionCode `json:"code"`
+	Command Command       `json:"command"`
+	State   State         `json:"state"`
+	Reason  string        `json:"reason"`
+}
+
+func (r *CommandRejection) Error() string {
+	return fmt.Sprintf("command %s rejected in state %s: %s", r.Command, r.State, r.Reason)
+}
+
+// CommandOptions controls how a command is accepted
+type CommandOptions struct {
+	// Queue allows a command to be deferred until the current workflow completes
+	Queue bool
+	// Reason and Actor are recorded on executions cancelled by the command
+	Reason string
+	Actor  string
+	// Correlation is stored on the production execution started by start
+	Correlation storage.Correlation
+}
+
+// CommandResult describes how an accepted command was handled
+type CommandResult string
+
+const (
+	CommandAccepted CommandResult = "accepted"
+	CommandQueued   CommandResult = "queued"
+)
+
+// validCommands is the machine state machine: commands allowed per state
+var validCommands = map[State][]Command{
+	StateStopped:   {CommandHome},
+	StateHoming:    {},
+	StateReady:     {CommandStart, CommandStop},
+	StateRunning:   {CommandStop},
+	StateStopping:  {},
+	StateError:     {CommandReset},
+	StateEmergency: {CommandReset},
+}
+
+// queueableCommands are commands that may be queued while a state is active
+// and are executed once the state's workflow has completed
+var queueableCommands = map[State][]Command{
+	StateHoming: {CommandStop},
+}
+
+// AllowedCommands returns the commands that can be executed in the given state
+func AllowedCommands(state State) []Command {
+	allowed := make([]Command, 0, len(validCommands[state]))
+	allowed = append(allowed, validCommands[state]...)
+	return allowed
+}
+
+// QueueableCommands returns the commands that can be queued in the given state
+func QueueableCommands(state State) []Command {
+	queueable := make([]Command, 0, len(queueableCommands[state]))
+	queueable = append(queueable, queueableCommands[state]...)
+	return queueable
+}
+
+// ValidateCommand checks a command against the state machine
+func ValidateCommand(state State, cmd Command) *CommandRejection {
+	switch cmd {
+	case CommandHome, CommandStart, CommandStop, CommandReset:
+	default:
+		return &CommandRejection{
+			Code:    RejectUnknownCommand,
+			Command: cmd,
+			State:   state,
+			Reason:  "unknown command",
+		}
+	}
+
+	if containsCommand(validCommands[state], cmd) {
+		return nil
+	}
+
+	if containsCommand(queueableCommands[state], cmd) {
+		return &CommandRejection{
+			Code:    RejectQueueRequired,
+			Command: cmd,
+			State:   state,
+			Reason:  fmt.Sprintf("command can only be queued while machine is %s", state),
+		}
+	}
+
+	return &CommandRejection{
+		Code:    RejectInvalidState,
+		Command: cmd,
+		State:   state,
+		Reason:  fmt.Sprintf("command not allowed while machine is %s", state),
+	}
+}
+
+func containsCommand(list []Command, cmd Command) bool {
+	for _, c := range list {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
 type MachineStatus struct {
 	State            State          `json:"state"`
 	CurrentWorkflow  string         `json:"current_workflow,omitempty"`
@@ -30,9 +151,17 @@ type MachineStatus struct {
 	ErrorMessage     string         `json:"error_message,omitempty"`
 	ProductionCycles int            `json:"production_cycles"`
 	LastStateChange  time.Time      `json:"last_state_change"`
+	AllowedCommands  []Command      `json:"allowed_commands"`
+	QueuedCommand    Command        `json:"queued_command,omitempty"`
 	Config           *MachineConfig `json:"config,omitempty"`
 }
 
+// StateChange is sent to state subscribers on every transition
+type StateChange struct {
+	Status        MachineStatus
+	PreviousState State
+}
+
 type MachineConfig struct {
 	StopWorkflowID       string `json:"stop_workflow_id,omitempty"`
 	HomeWorkflowID       string `json:"home_workflow_id,omitempty"`
@@ -0,0 +1,78 @@
+package machine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateCommand(t *testing.T) {
+	tests := []struct {
+		state    State
+		cmd      Command
+		wantCode RejectionCode // Empty = accepted
+	}{
+		{StateStopped, CommandHome, ""},
+		{StateStopped, CommandStart, RejectInvalidState},
+		{StateStopped, CommandReset, RejectInvalidState},
+		{StateHoming, CommandStop, RejectQueueRequired},
+		{StateHoming, CommandStart, RejectInvalidState},
+		{StateReady, CommandStart, ""},
+		{StateReady, CommandStop, ""},
+		{StateReady, CommandHome, RejectInvalidState},
+		{StateRunning, CommandStop, ""},
+		{StateRunning, CommandStart, RejectInvalidState},
+		{StateStopping, CommandStop, RejectInvalidState},
+		{StateError, CommandReset, ""},
+		{StateError, CommandStart, RejectInvalidState},
+		{StateEmergency, CommandReset, ""},
+		{StateEmergency, CommandHome, RejectInvalidState},
+		{StateReady, Command("jog"), RejectUnknownCommand},
+		{State("unknown"), CommandStart, RejectInvalidState},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state)+"/"+string(tt.cmd), func(t *testing.T) {
+			rejection := ValidateCommand(tt.state, tt.cmd)
+			if tt.wantCode == "" {
+				if rejection != nil {
+					t.Fatalf("ValidateCommand(%s, %s) rejected: %v", tt.state, tt.cmd, rejection)
+				}
+				return
+			}
+			if rejection == nil {
+				t.Fatalf("ValidateCommand(%s, %s) accepted, want %s", tt.state, tt.cmd, tt.wantCode)
+			}
+			if rejection.Code != tt.wantCode || rejection.State != tt.state || rejection.Command != tt.cmd {
+				t.Errorf("ValidateCommand(%s, %s) = %+v, want code %s", tt.state, tt.cmd, rejection, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAllowedCommands(t *testing.T) {
+	tests := []struct {
+		state State
+		want  []Command
+	}{
+		{StateStopped, []Command{CommandHome}},
+		{StateReady, []Command{CommandStart, CommandStop}},
+		{StateHoming, []Command{}},
+		{State("unknown"), []Command{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.state), func(t *testing.T) {
+			got := AllowedCommands(tt.state)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AllowedCommands(%s) = %v, want %v", tt.state, got, tt.want)
+			}
+			// Callers may modify the result
+			if len(got) > 0 {
+				got[0] = Command("changed")
+				if AllowedCommands(tt.state)[0] == got[0] {
+					t.Errorf("AllowedCommands(%s) shares its slice with the state machine", tt.state)
+				}
+			}
+		})
+	}
+}
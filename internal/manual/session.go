@@ -0,0 +1,453 @@
+// Package manual implements the dead-man switch of manual control sessions.
+// A technician driving outputs from a remote HMI opens a session and sends
+// heartbeats; when they stop, e.g. on a network drop, the outputs written in
+// the session return to their safe states and the session is released.
+package manual
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSessionNotFound = errors.New("manual control session not found")
+	ErrNotOwner        = errors.New("manual control session belongs to another user")
+	ErrTooManySessions = errors.New("too many manual control sessions")
+	ErrSessionRequired = errors.New("manual control session required")
+)
+
+// Audit actions of manual control sessions
+const (
+	AuditSessionOpened = "manual.session_opened"
+	AuditSessionEnded  = "manual.session_ended"
+)
+
+// auditTimeout bounds recording the audit entry of an expired session
+const auditTimeout = 5 * time.Second
+
+// Reasons a session ends
+const (
+	ReasonReleased = "released" // Ended by its owner
+	ReasonExpired  = "expired"  // Heartbeats stopped
+	ReasonShutdown = "shutdown"
+)
+
+// minCheckInterval bounds how often sessions are checked for expiry
+const minCheckInterval = 100 * time.Millisecond
+
+// Output is an output written in a session
+type Output struct {
+	Device    string    `json:"device"`
+	Register  string    `json:"register"` // As written, usually a logical name
+	Value     any       `json:"value"`
+	WrittenAt time.Time `json:"written_at"`
+}
+
+// Reverted is the result of returning an output to its safe state
+type Reverted struct {
+	Device   string `json:"device"`
+	Register string `json:"register"`
+	Status   string `json:"status"` // "safe_state", "no_safe_state" or "failed"
+	Error    string `json:"error,omitempty"`
+}
+
+// Session is a manual control session
+type Session struct {
+	ID               uuid.UUID  `json:"id"`
+	Actor            string     `json:"actor"`
+	StartedAt        time.Time  `json:"started_at"`
+	LastHeartbeat    time.Time  `json:"last_heartbeat"`
+	HeartbeatTimeout string     `json:"heartbeat_timeout"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	Outputs          []Output   `json:"outputs"` // Last write per output
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+	EndReason        string     `json:"end_reason,omitempty"`
+	Reverted         []Reverted `json:"reverted,omitempty"`
+}
+
+type session struct {
+	Session
+	mu sync.Mutex // Serializes writes and the revert of the session
+}
+
+// EndHandler is called after a session ended and its outputs were reverted
+type EndHandler func(session Session)
+
+// Manager holds the open sessions and reverts those whose heartbeats stop
+type Manager struct {
+	cfg          config.ManualConfig
+	storage      *storage.PostgresClient
+	devices      *devices.Manager
+	writeTimeout time.Duration
+	logger       *zap.Logger
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*session
+	onEnd    EndHandler
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewManager(cfg config.ManualConfig, store *storage.PostgresClient, deviceManager *devices.Manager, writeTimeout time.Duration, logger *zap.Logger) *Manager {
+	return &Manager{
+		cfg:          cfg,
+		storage:      store,
+		devices:      deviceManager,
+		writeTimeout: writeTimeout,
+		logger:       logger,
+		sessions:     make(map[uuid.UUID]*session),
+	}
+}
+
+// SetEndHandler installs the handler notified of ended sessions
+func (m *Manager) SetEndHandler(handler EndHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEnd = handler
+}
+
+// RequireSession reports whether manual writes need a session
+func (m *Manager) RequireSession() bool {
+	return m.cfg.RequireSession
+}
+
+// Start begins watching the heartbeats
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+
+	m.running = true
+	m.stopChan = make(chan struct{})
+	m.wg.Add(1)
+	go m.loop(m.stopChan)
+}
+
+// Stop ends all sessions, reverting their outputs, and stops watching
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	ids := make([]uuid.UUID, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	for _, id := range ids {
+		m.end(id, ReasonShutdown)
+	}
+}
+
+// Open starts a session of actor
+func (m *Manager) Open(ctx context.Context, actor string) (Session, error) {
+	m.mu.Lock()
+	if len(m.sessions) >= m.cfg.MaxSessions {
+		m.mu.Unlock()
+		return Session{}, ErrTooManySessions
+	}
+
+	now := time.Now()
+	s := &session{Session: Session{
+		ID:               uuid.New(),
+		Actor:            actor,
+		StartedAt:        now,
+		LastHeartbeat:    now,
+		HeartbeatTimeout: m.cfg.HeartbeatTimeout.String(),
+		ExpiresAt:        now.Add(m.cfg.HeartbeatTimeout),
+		Outputs:          []Output{},
+	}}
+	m.sessions[s.ID] = s
+	opened := s.snapshot()
+	m.mu.Unlock()
+
+	m.logger.Info("Manual control session opened",
+		zap.String("session_id", opened.ID.String()),
+		zap.String("actor", actor))
+	m.audit(ctx, AuditSessionOpened, actor, map[string]any{
+		"session_id":        opened.ID,
+		"heartbeat_timeout": opened.HeartbeatTimeout,
+	})
+	return opened, nil
+}
+
+// Heartbeat keeps a session alive
+func (m *Manager) Heartbeat(id uuid.UUID, actor string) (Session, error) {
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, open := m.sessions[id]; !open {
+		return Session{}, ErrSessionNotFound // Expired meanwhile
+	}
+	s.LastHeartbeat = time.Now()
+	s.ExpiresAt = s.LastHeartbeat.Add(m.cfg.HeartbeatTimeout)
+	return s.snapshot(), nil
+}
+
+// Write writes an output of a device in a session and records it for the
+// revert. A write counts as heartbeat. Returns the written sample.
+func (m *Manager) Write(ctx context.Context, id uuid.UUID, actor string, device *modbus.Device, register string, value any) (modbus.Sample, error) {
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return modbus.Sample{}, err
+	}
+
+	// Holding the session lock keeps an expiry from reverting in between
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.mu.Lock()
+	_, open := m.sessions[id]
+	if open {
+		s.LastHeartbeat = time.Now()
+		s.ExpiresAt = s.LastHeartbeat.Add(m.cfg.HeartbeatTimeout)
+	}
+	m.mu.Unlock()
+	if !open {
+		return modbus.Sample{}, ErrSessionNotFound
+	}
+
+	// Recorded first, so a partly applied write is reverted too
+	output := Output{Device: device.Name, Register: register, Value: value, WrittenAt: time.Now()}
+	idx := slices.IndexFunc(s.Outputs, func(o Output) bool {
+		return o.Device == output.Device && o.Register == output.Register
+	})
+	if idx >= 0 {
+		s.Outputs[idx] = output
+	} else {
+		s.Outputs = append(s.Outputs, output)
+	}
+
+	return device.WriteLogicalSample(ctx, register, value)
+}
+
+// Release ends a session and reverts its outputs
+func (m *Manager) Release(id uuid.UUID, actor string) (Session, error) {
+	if _, err := m.owned(id, actor); err != nil {
+		return Session{}, err
+	}
+	ended, ok := m.end(id, ReasonReleased)
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return ended, nil
+}
+
+// Get returns an open session
+func (m *Manager) Get(id uuid.UUID) (Session, bool) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	m.mu.Unlock()
+	if !ok {
+		return Session{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot(), true
+}
+
+// List returns the open sessions, oldest first
+func (m *Manager) List() []Session {
+	m.mu.Lock()
+	open := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		open = append(open, s)
+	}
+	m.mu.Unlock()
+
+	list := make([]Session, 0, len(open))
+	for _, s := range open {
+		s.mu.Lock()
+		list = append(list, s.snapshot())
+		s.mu.Unlock()
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// owned returns an open session of actor
+func (m *Manager) owned(id uuid.UUID, actor string) (*session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if s.Actor != actor {
+		return nil, ErrNotOwner
+	}
+	return s, nil
+}
+
+func (m *Manager) loop(stop <-chan struct{}) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(max(m.cfg.HeartbeatTimeout/4, minCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			m.expire(now)
+		}
+	}
+}
+
+// expire ends the sessions without heartbeat
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	var expired []uuid.UUID
+	for id, s := range m.sessions {
+		// A write in progress holds the session lock; it refreshes the
+		// heartbeat anyway
+		if !s.mu.TryLock() {
+			continue
+		}
+		if now.After(s.ExpiresAt) {
+			expired = append(expired, id)
+		}
+		s.mu.Unlock()
+	}
+	m.mu.Unlock()
+
+	for _, id := range expired {
+		m.end(id, ReasonExpired)
+	}
+}
+
+// end removes a session and reverts its outputs; ok is false if it already ended
+func (m *Manager) end(id uuid.UUID, reason string) (Session, bool) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	m.mu.Unlock()
+	if !ok {
+		return Session{}, false
+	}
+
+	// Session before manager lock, like Write
+	s.mu.Lock()
+	m.mu.Lock()
+	if m.sessions[id] != s {
+		m.mu.Unlock()
+		s.mu.Unlock()
+		return Session{}, false // Ended meanwhile
+	}
+	delete(m.sessions, id)
+	onEnd := m.onEnd
+	m.mu.Unlock()
+
+	s.Reverted = m.revert(s.Outputs)
+	now := time.Now()
+	s.EndedAt = &now
+	s.EndReason = reason
+	ended := s.snapshot()
+	s.mu.Unlock()
+
+	level := m.logger.Info
+	if reason == ReasonExpired {
+		level = m.logger.Warn
+	}
+	level("Manual control session ended",
+		zap.String("session_id", id.String()),
+		zap.String("actor", ended.Actor),
+		zap.String("reason", reason),
+		zap.Int("outputs", len(ended.Outputs)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	m.audit(ctx, AuditSessionEnded, ended.Actor, map[string]any{
+		"session_id": id,
+		"reason":     reason,
+		"outputs":    ended.Outputs,
+		"reverted":   ended.Reverted,
+	})
+	cancel()
+
+	if onEnd != nil {
+		onEnd(ended)
+	}
+	return ended, true
+}
+
+// revert writes the safe states of the outputs. Outputs without a safe state
+// keep their value and are reported.
+func (m *Manager) revert(outputs []Output) []Reverted {
+	ctx, cancel := context.WithTimeout(context.Background(), m.writeTimeout)
+	defer cancel()
+
+	results := make([]Reverted, 0, len(outputs))
+	for _, output := range outputs {
+		result := Reverted{Device: output.Device, Register: output.Register, Status: "safe_state"}
+
+		device, exists := m.devices.GetDeviceByName(output.Device)
+		configured := false
+		var err error
+		if !exists {
+			err = fmt.Errorf("device not found: %s", output.Device)
+		} else {
+			configured, err = device.WriteSafeState(ctx, output.Register)
+		}
+
+		switch {
+		case err != nil:
+			result.Status = "failed"
+			result.Error = err.Error()
+			m.logger.Error("Failed to revert manual output",
+				zap.String("device", output.Device),
+				zap.String("register", output.Register),
+				zap.Error(err))
+		case !configured:
+			result.Status = "no_safe_state"
+			m.logger.Warn("Manual output has no safe state and keeps its value",
+				zap.String("device", output.Device),
+				zap.String("register", output.Register))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (m *Manager) audit(ctx context.Context, action, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := m.storage.RecordAudit(ctx, entry); err != nil {
+		m.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
+
+func (s *session) snapshot() Session {
+	copied := s.Session
+	copied.Outputs = slices.Clone(s.Outputs)
+	copied.Reverted = slices.Clone(s.Reverted)
+	return copied
+}
@@ -0,0 +1,75 @@
+package modbus
+
+import (
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// AliasHandler is called the first time a deprecated name is used on a device
+type AliasHandler func(device *Device, deprecated, current string)
+
+// checkAliases rejects aliases that shadow existing names or point nowhere
+func checkAliases(aliases map[string]string, registers map[string]*types.RegisterDefinition, virtuals map[string]*virtualRegister, ioMapping map[string]string) error {
+	known := func(name string) bool {
+		_, isRegister := registers[name]
+		_, isVirtual := virtuals[name]
+		_, isLogical := ioMapping[name]
+		return isRegister || isVirtual || isLogical
+	}
+
+	for deprecated, current := range aliases {
+		if known(deprecated) {
+			return fmt.Errorf("alias %s shadows an existing register or logical name", deprecated)
+		}
+		if !known(current) {
+			return fmt.Errorf("alias %s points to unknown name %s", deprecated, current)
+		}
+	}
+	return nil
+}
+
+// SetAliasHandler registers a handler reporting the use of deprecated names
+func (d *Device) SetAliasHandler(handler AliasHandler) {
+	d.mu.Lock()
+	d.aliasHandler = handler
+	d.mu.Unlock()
+}
+
+// Aliases returns the deprecated names and their current names
+func (d *Device) Aliases() map[string]string {
+	return d.Profile.Aliases
+}
+
+// resolveAlias returns the current name of a deprecated name. The first use
+// of each deprecated name is reported to the alias handler.
+func (d *Device) resolveAlias(name string) (string, bool) {
+	current, ok := d.Profile.Aliases[name]
+	if !ok {
+		return name, false
+	}
+
+	d.mu.Lock()
+	first := !d.aliasReported[name]
+	d.aliasReported[name] = true
+	handler := d.aliasHandler
+	d.mu.Unlock()
+
+	if first && handler != nil {
+		handler(d, name, current)
+	}
+	return current, true
+}
+
+// aliasRegister returns the register a deprecated register or logical name
+// now refers to
+func (d *Device) aliasRegister(name string) (string, bool) {
+	current, ok := d.resolveAlias(name)
+	if !ok {
+		return "", false
+	}
+	if registerName, mapped := d.IOMapping[current]; mapped && registerName != name {
+		return registerName, true
+	}
+	return current, true
+}
@@ -0,0 +1,168 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// maxReadQuantity is the most registers a single read request may return
+const maxReadQuantity = 125
+
+type heldKey struct{}
+
+// Hold grants the connection to the caller until the returned function is
+// called. Requests made with the returned context don't queue again, so a
+// sequence of requests runs without polls or other writers in between.
+func (c *Client) Hold(ctx context.Context) (context.Context, func(), error) {
+	release, err := c.scheduler.acquire(ctx, isPriority(ctx, 0))
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return context.WithValue(ctx, heldKey{}, c), func() { once.Do(release) }, nil
+}
+
+type heldWordsKey struct{}
+
+// heldWords are the word locks taken by Exclusive
+type heldWords struct {
+	device    *Device
+	addresses map[uint16]bool
+}
+
+// Exclusive runs fn with the connection of the device held, see Client.Hold.
+// The words of the registers fn writes must be named in writes, given by
+// register name, logical name or alias: they are locked before the
+// connection is held, so a writer holding a word lock and waiting for the
+// connection can't block fn. Exclusive holds the connection in the priority
+// lane if anything is written.
+func (d *Device) Exclusive(ctx context.Context, writes []string, fn func(ctx context.Context) error) error {
+	addresses := make(map[uint16]bool, len(writes))
+	for _, name := range writes {
+		if reg, _, ok := d.resolveRegister(name); ok && reg.Type == types.RegisterTypeHoldingRegister {
+			addresses[reg.Address] = true
+		}
+	}
+
+	// Words are locked in address order, so two callers can't deadlock
+	sorted := make([]uint16, 0, len(addresses))
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for _, address := range sorted {
+		unlock := d.lockWord(ctx, address)
+		defer unlock()
+	}
+	ctx = context.WithValue(ctx, heldWordsKey{}, &heldWords{device: d, addresses: addresses})
+
+	if len(writes) > 0 {
+		ctx = WithPriority(ctx)
+	}
+	ctx, release, err := d.Client.Hold(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(ctx)
+}
+
+// resolveRegister returns the register behind a register name, logical name
+// or alias with its register name
+func (d *Device) resolveRegister(name string) (*types.RegisterDefinition, string, bool) {
+	registerName := name
+	if target, mapped := d.IOMapping[name]; mapped {
+		registerName = target
+	}
+
+	d.mu.RLock()
+	reg, exists := d.RegisterMap[registerName]
+	d.mu.RUnlock()
+	if exists {
+		return reg, registerName, true
+	}
+
+	if current, ok := d.aliasRegister(name); ok {
+		d.mu.RLock()
+		reg, exists = d.RegisterMap[current]
+		d.mu.RUnlock()
+		return reg, current, exists
+	}
+	return nil, "", false
+}
+
+// ReadSamples reads holding and input registers like ReadSample, combining
+// adjacent registers into one request of up to 125 words. Registers that
+// can't be read in a block, such as virtual registers, coils and unknown
+// names, are left out of the result. A failed request fails the call and
+// marks its registers bad.
+func (d *Device) ReadSamples(ctx context.Context, registerNames []string) (map[string]Sample, error) {
+	type entry struct {
+		name string
+		reg  *types.RegisterDefinition
+	}
+
+	byType := map[types.RegisterType][]entry{}
+	d.mu.RLock()
+	for _, name := range registerNames {
+		reg, exists := d.RegisterMap[name]
+		if !exists || (reg.Type != types.RegisterTypeHoldingRegister && reg.Type != types.RegisterTypeInputRegister) {
+			continue
+		}
+		byType[reg.Type] = append(byType[reg.Type], entry{name: name, reg: reg})
+	}
+	d.mu.RUnlock()
+
+	samples := make(map[string]Sample, len(registerNames))
+	unitID := uint8(d.Profile.Connection.UnitID)
+
+	for regType, entries := range byType {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].reg.Address < entries[j].reg.Address })
+
+		for start := 0; start < len(entries); {
+			first := entries[start].reg.Address
+			end := first + d.getRegisterQuantity(entries[start].reg.DataType)
+			next := start + 1
+			for ; next < len(entries); next++ {
+				reg := entries[next].reg
+				regEnd := max(end, reg.Address+d.getRegisterQuantity(reg.DataType))
+				if reg.Address > end || regEnd-first > maxReadQuantity {
+					break
+				}
+				end = regEnd
+			}
+			block := entries[start:next]
+			start = next
+
+			var values []uint16
+			var err error
+			if regType == types.RegisterTypeHoldingRegister {
+				values, err = d.Client.ReadHoldingRegisters(ctx, unitID, first, end-first)
+			} else {
+				values, err = d.Client.ReadInputRegisters(ctx, unitID, first, end-first)
+			}
+			if err == nil && len(values) < int(end-first) {
+				err = fmt.Errorf("short response: %d of %d registers", len(values), end-first)
+			}
+			if err != nil {
+				for _, e := range block {
+					d.markBad(e.name)
+				}
+				return nil, fmt.Errorf("failed to read registers %d-%d: %w", first, end-1, err)
+			}
+
+			for _, e := range block {
+				offset := e.reg.Address - first
+				words := values[offset : offset+d.getRegisterQuantity(e.reg.DataType)]
+				samples[e.name] = d.registerSample(e.name, e.reg, words)
+			}
+		}
+	}
+
+	return samples, nil
+}
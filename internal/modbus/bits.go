@@ -0,0 +1,157 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// lockWord serializes writes to a holding register word, so read-modify-write
+// updates of single bits aren't clobbered by concurrent writes from
+// workflows, safe states or the API. Words held by an Exclusive caller are
+// not locked again.
+func (d *Device) lockWord(ctx context.Context, address uint16) func() {
+	if held, ok := ctx.Value(heldWordsKey{}).(*heldWords); ok && held.device == d && held.addresses[address] {
+		return func() {}
+	}
+
+	d.wordLocksMu.Lock()
+	if d.wordLocks == nil {
+		d.wordLocks = make(map[uint16]*sync.Mutex)
+	}
+	lock, ok := d.wordLocks[address]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.wordLocks[address] = lock
+	}
+	d.wordLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// WriteBits atomically sets the masked bits of a holding register word to
+// the given bits and returns the word before and after. The register may be
+// given by register name, logical name or alias; its bit is ignored, the
+// whole word is masked.
+func (d *Device) WriteBits(ctx context.Context, name string, mask, bits uint16) (uint16, uint16, error) {
+	registerName := name
+	if target, mapped := d.IOMapping[name]; mapped {
+		registerName = target
+	}
+
+	d.mu.RLock()
+	reg, exists := d.RegisterMap[registerName]
+	d.mu.RUnlock()
+
+	if !exists {
+		if current, ok := d.aliasRegister(name); ok {
+			return d.WriteBits(ctx, current, mask, bits)
+		}
+		return 0, 0, fmt.Errorf("register not found: %s", name)
+	}
+	if reg.Type != types.RegisterTypeHoldingRegister {
+		return 0, 0, fmt.Errorf("register %s is not a holding register", registerName)
+	}
+	if reg.Access != types.AccessTypeReadWrite {
+		return 0, 0, fmt.Errorf("register %s is read-only", registerName)
+	}
+
+	unlock := d.lockWord(ctx, reg.Address)
+	defer unlock()
+	return d.modifyWord(ctx, reg.Address, mask, bits)
+}
+
+// modifyWord reads a word, replaces the masked bits and writes it back if it
+// changed. The caller holds the word lock.
+func (d *Device) modifyWord(ctx context.Context, address, mask, bits uint16) (uint16, uint16, error) {
+	unitID := uint8(d.Profile.Connection.UnitID)
+
+	values, err := d.Client.ReadHoldingRegisters(ctx, unitID, address, 1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read word %d: %w", address, err)
+	}
+	if len(values) == 0 {
+		return 0, 0, fmt.Errorf("failed to read word %d: empty response", address)
+	}
+
+	previous := values[0]
+	word := previous&^mask | bits&mask
+	if word == previous {
+		return previous, word, nil
+	}
+	if err := d.Client.WriteSingleRegister(ctx, unitID, address, word); err != nil {
+		return previous, previous, fmt.Errorf("failed to write word %d: %w", address, err)
+	}
+	return previous, word, nil
+}
+
+// bitValue extracts the bit of a bool register from its word
+func bitValue(word uint16, bit int) bool {
+	return word>>uint(bit)&1 != 0
+}
+
+// maxWriteCoils is the most coils a single write request may switch
+const maxWriteCoils = 1968
+
+// writeCoils switches a coil. A list of states switches the consecutive
+// coils from the register's address with one request; the sample holds the
+// written states then.
+func (d *Device) writeCoils(ctx context.Context, reg *types.RegisterDefinition, value any) (Sample, error) {
+	unitID := uint8(d.Profile.Connection.UnitID)
+
+	list, ok := value.([]any)
+	if !ok {
+		on, err := coilState(reg, value)
+		if err != nil {
+			return Sample{}, err
+		}
+		if err := d.Client.WriteSingleCoil(ctx, unitID, reg.Address, on); err != nil {
+			return Sample{}, err
+		}
+		return newSample(on, []uint16{boolWord(on)}, reg.Unit), nil
+	}
+
+	if len(list) == 0 || len(list) > maxWriteCoils {
+		return Sample{}, fmt.Errorf("%d coil states, write 1 to %d", len(list), maxWriteCoils)
+	}
+	coils := make([]bool, len(list))
+	raw := make([]uint16, len(list))
+	for i, v := range list {
+		on, err := coilState(reg, v)
+		if err != nil {
+			return Sample{}, fmt.Errorf("coil %d: %w", i, err)
+		}
+		coils[i], raw[i] = on, boolWord(on)
+	}
+	if err := d.Client.WriteMultipleCoils(ctx, unitID, reg.Address, coils); err != nil {
+		return Sample{}, err
+	}
+	return newSample(coils, raw, reg.Unit), nil
+}
+
+// coilState converts a written value to a coil state: a bool, a number
+// (non-zero is on) or a switch state like "on"
+func coilState(reg *types.RegisterDefinition, value any) (bool, error) {
+	if s, ok := value.(string); ok {
+		resolved, err := resolveString(&types.RegisterDefinition{DataType: types.DataTypeBool}, s)
+		if err != nil {
+			return false, err
+		}
+		value = resolved
+	}
+	if n, ok := numericValue(value); ok {
+		return n != 0, nil
+	}
+	return false, fmt.Errorf("unsupported value type for coil %d: %T", reg.Address, value)
+}
+
+// boolWord is the raw word of a bit state
+func boolWord(on bool) uint16 {
+	if on {
+		return 1
+	}
+	return 0
+}
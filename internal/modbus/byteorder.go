@@ -0,0 +1,59 @@
+package modbus
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// checkByteOrders rejects unknown byte orders of the connection and the
+// registers of a profile
+func checkByteOrders(profile *types.DeviceProfileDefinition) error {
+	if !validByteOrder(profile.Connection.ByteOrder) {
+		return fmt.Errorf("unknown byte order %q, use ABCD, CDAB, BADC or DCBA", profile.Connection.ByteOrder)
+	}
+	for _, reg := range profile.Registers {
+		if !validByteOrder(reg.ByteOrder) {
+			return fmt.Errorf("register %s: unknown byte order %q, use ABCD, CDAB, BADC or DCBA", reg.Name, reg.ByteOrder)
+		}
+	}
+	return nil
+}
+
+func validByteOrder(order types.ByteOrder) bool {
+	switch order {
+	case "", types.ByteOrderABCD, types.ByteOrderCDAB, types.ByteOrderBADC, types.ByteOrderDCBA:
+		return true
+	}
+	return false
+}
+
+// byteOrder returns the byte order of a register: its own, or the one of the
+// connection
+func (d *Device) byteOrder(reg *types.RegisterDefinition) types.ByteOrder {
+	if reg.ByteOrder != "" {
+		return reg.ByteOrder
+	}
+	return d.Profile.Connection.ByteOrder
+}
+
+// reorderWords converts the registers of a 32 or 64 bit value between the
+// device's byte order and ABCD, high word first. The conversion is its own
+// inverse, so it serves reads and writes. Single registers are unchanged.
+func reorderWords(words []uint16, order types.ByteOrder) []uint16 {
+	if len(words) < 2 {
+		return words
+	}
+
+	reordered := slices.Clone(words)
+	if order == types.ByteOrderCDAB || order == types.ByteOrderDCBA {
+		slices.Reverse(reordered)
+	}
+	if order == types.ByteOrderBADC || order == types.ByteOrderDCBA {
+		for i, word := range reordered {
+			reordered[i] = word<<8 | word>>8
+		}
+	}
+	return reordered
+}
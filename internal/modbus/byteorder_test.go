@@ -0,0 +1,64 @@
+package modbus
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+func TestReorderWords(t *testing.T) {
+	abcd := []uint16{0xAABB, 0xCCDD}
+	tests := []struct {
+		name  string
+		words []uint16
+		order types.ByteOrder
+		want  []uint16
+	}{
+		{"default", abcd, "", []uint16{0xAABB, 0xCCDD}},
+		{"ABCD", abcd, types.ByteOrderABCD, []uint16{0xAABB, 0xCCDD}},
+		{"CDAB", abcd, types.ByteOrderCDAB, []uint16{0xCCDD, 0xAABB}},
+		{"BADC", abcd, types.ByteOrderBADC, []uint16{0xBBAA, 0xDDCC}},
+		{"DCBA", abcd, types.ByteOrderDCBA, []uint16{0xDDCC, 0xBBAA}},
+		{"DCBA 64 bit", []uint16{0x0102, 0x0304, 0x0506, 0x0708}, types.ByteOrderDCBA, []uint16{0x0807, 0x0605, 0x0403, 0x0201}},
+		{"single register", []uint16{0xAABB}, types.ByteOrderDCBA, []uint16{0xAABB}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reorderWords(tt.words, tt.order)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("reorderWords(%#04x, %q) = %#04x, want %#04x", tt.words, tt.order, got, tt.want)
+			}
+			if back := reorderWords(got, tt.order); !slices.Equal(back, tt.words) {
+				t.Errorf("reorderWords is not its own inverse for %q: %#04x", tt.order, back)
+			}
+		})
+	}
+}
+
+func TestCheckByteOrders(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection types.ByteOrder
+		register   types.ByteOrder
+		wantErr    bool
+	}{
+		{"defaults", "", "", false},
+		{"valid", types.ByteOrderCDAB, types.ByteOrderDCBA, false},
+		{"unknown connection order", "BACD", "", true},
+		{"unknown register order", "", "abcd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &types.DeviceProfileDefinition{
+				Registers: []types.RegisterDefinition{{Name: "R1", ByteOrder: tt.register}},
+			}
+			profile.Connection.ByteOrder = tt.connection
+			if err := checkByteOrders(profile); (err != nil) != tt.wantErr {
+				t.Errorf("checkByteOrders() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
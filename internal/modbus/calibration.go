@@ -0,0 +1,58 @@
+package modbus
+
+import (
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// SetCalibration applies an offset/gain correction to reads and writes of a
+// numeric register
+func (d *Device) SetCalibration(registerName string, cal types.Calibration) error {
+	reg, exists := d.RegisterMap[registerName]
+	if !exists {
+		return fmt.Errorf("register not found: %s", registerName)
+	}
+	if reg.DataType == types.DataTypeBool || reg.DataType == types.DataTypeString {
+		return fmt.Errorf("register %s is not numeric (%s)", registerName, reg.DataType)
+	}
+	if cal.Gain == 0 {
+		return fmt.Errorf("calibration gain for %s must not be zero", registerName)
+	}
+
+	d.mu.Lock()
+	d.calibrations[registerName] = cal
+	d.mu.Unlock()
+
+	return nil
+}
+
+// ClearCalibration removes the correction of a register
+func (d *Device) ClearCalibration(registerName string) {
+	d.mu.Lock()
+	delete(d.calibrations, registerName)
+	d.mu.Unlock()
+}
+
+// Calibrations returns a copy of the active corrections by register name
+func (d *Device) Calibrations() map[string]types.Calibration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	cals := make(map[string]types.Calibration, len(d.calibrations))
+	for name, cal := range d.calibrations {
+		cals[name] = cal
+	}
+	return cals
+}
+
+// calibration returns the correction of a register, identity if none is set
+func (d *Device) calibration(registerName string) types.Calibration {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if cal, ok := d.calibrations[registerName]; ok {
+		return cal
+	}
+	return types.Calibration{Gain: 1}
+}
@@ -5,16 +5,31 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
 )
 
 type Client struct {
 	address       string
 	conn          net.Conn
+	serial        *types.SerialConfig // Modbus RTU instead of TCP
+	bus           *serialBus          // Serial line while connected
 	mu            sync.Mutex
 	transactionID uint16
 	timeout       time.Duration
 	connected     bool
+	scheduler     requestScheduler
+	faultHook     FaultHook
+
+	connHandler   ConnectionHandler
+	minDelay      time.Duration // First reconnect delay, doubled per failed attempt
+	maxDelay      time.Duration // Longest reconnect delay
+	timeouts      int           // Timeouts in a row on the TCP connection
+	lostAt        time.Time     // When the connection was lost
+	reconnectStop chan struct{} // Closed to end a running reconnect
+	reconnecting  atomic.Bool   // Readable while a request holds c.mu
 }
 
 func NewClient(address string, timeout time.Duration) *Client {
@@ -22,18 +37,62 @@ func NewClient(address string, timeout time.Duration) *Client {
 		address:       address,
 		timeout:       timeout,
 		transactionID: 0,
+		minDelay:      DefaultReconnectMinDelay,
+		maxDelay:      DefaultReconnectMaxDelay,
 	}
 }
 
-// Connect stellt TCP-Verbindung her
+// NewRTUClient creates a Modbus RTU client on a serial line. Clients of the
+// same port share it, e.g. several couplers on one RS-485 bus.
+func NewRTUClient(serial types.SerialConfig, timeout time.Duration) (*Client, error) {
+	serial, err := normalizeSerial(serial)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		address: serial.Port,
+		serial:  &serial,
+		timeout: timeout,
+	}, nil
+}
+
+// Timeout returns the device timeout used without an operation timeout
+func (c *Client) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Connect stellt TCP-Verbindung her. A lost connection is dialed at once
+// instead of waiting for the next reconnect attempt.
 func (c *Client) Connect() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	reconnecting := c.reconnectStop != nil
+	lostAt := c.lostAt
+	err := c.connect()
+	handler := c.connHandler
+	c.mu.Unlock()
 
+	if err == nil && reconnecting && handler != nil {
+		handler(ConnectionEvent{State: ConnectionConnected, Downtime: time.Since(lostAt)})
+	}
+	return err
+}
+
+// connect opens the connection. Callers hold c.mu.
+func (c *Client) connect() error {
 	if c.connected {
 		return nil
 	}
 
+	if c.serial != nil {
+		bus, err := openSerialBus(*c.serial)
+		if err != nil {
+			return fmt.Errorf("connection failed: %w", err)
+		}
+		c.bus = bus
+		c.connected = true
+		return nil
+	}
+
 	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
 	if err != nil {
 		return fmt.Errorf("connection failed: %w", err)
@@ -41,6 +100,8 @@ func (c *Client) Connect() error {
 
 	c.conn = conn
 	c.connected = true
+	c.timeouts = 0
+	c.stopReconnect()
 
 	return nil
 }
@@ -50,23 +111,47 @@ func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.stopReconnect()
 	if !c.connected {
 		return nil
 	}
 
-	err := c.conn.Close()
+	var err error
+	if c.bus != nil {
+		err = c.bus.release()
+		c.bus = nil
+	} else {
+		err = c.conn.Close()
+		c.conn = nil
+	}
 	c.connected = false
-	c.conn = nil
 
 	return err
 }
 
+// SchedulerStats returns how long requests waited for the connection
+func (c *Client) SchedulerStats() SchedulerStats {
+	return c.scheduler.stats()
+}
+
 // SendFrame sendet ein Frame und wartet auf Response
 func (c *Client) SendFrame(ctx context.Context, request *ModbusFrame) (*ModbusFrame, error) {
+	// Requests of a Hold caller already own the connection
+	if held, _ := ctx.Value(heldKey{}).(*Client); held != c {
+		release, err := c.scheduler.acquire(ctx, isPriority(ctx, request.FunctionCode))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if !c.connected {
+		if c.reconnecting.Load() {
+			return nil, ErrReconnecting
+		}
 		return nil, fmt.Errorf("not connected")
 	}
 
@@ -74,15 +159,50 @@ func (c *Client) SendFrame(ctx context.Context, request *ModbusFrame) (*ModbusFr
 	c.transactionID++
 	request.TransactionID = c.transactionID
 
+	// Timeout setzen
+	timeout := c.timeout
+	if d, ok := operationTimeout(ctx); ok {
+		timeout = d
+	}
+	if c.faultHook != nil {
+		if fault := c.faultHook(request.FunctionCode); fault != nil {
+			return nil, c.injectFault(ctx, fault, request.FunctionCode, timeout)
+		}
+	}
+
+	var response *ModbusFrame
+	var err error
+	if c.bus != nil {
+		response, err = c.bus.send(request, timeout, frameTracer(ctx))
+	} else {
+		response, err = c.sendTCP(request, timeout, frameTracer(ctx))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if exc := response.Exception(); exc != nil {
+		return nil, exc
+	}
+
+	return response, nil
+}
+
+// sendTCP sends a request over the TCP connection and reads its response.
+// Callers hold c.mu.
+func (c *Client) sendTCP(request *ModbusFrame, timeout time.Duration, tracer FrameTracer) (*ModbusFrame, error) {
 	// Request senden
 	requestData := request.Encode()
 
-	// Timeout setzen
-	deadline := time.Now().Add(c.timeout)
+	start := time.Now()
+	deadline := start.Add(timeout)
 	c.conn.SetWriteDeadline(deadline)
 
-	_, err := c.conn.Write(requestData)
-	if err != nil {
+	if _, err := c.conn.Write(requestData); err != nil {
+		if tracer != nil {
+			tracer(requestData, nil, time.Since(start), err)
+		}
+		c.connectionFailed(err)
 		return nil, fmt.Errorf("write failed: %w", err)
 	}
 
@@ -91,9 +211,14 @@ func (c *Client) SendFrame(ctx context.Context, request *ModbusFrame) (*ModbusFr
 
 	responseBuffer := make([]byte, 260) // Max Modbus TCP Frame
 	n, err := c.conn.Read(responseBuffer)
+	if tracer != nil {
+		tracer(requestData, responseBuffer[:n], time.Since(start), err)
+	}
 	if err != nil {
+		c.connectionFailed(err)
 		return nil, fmt.Errorf("read failed: %w", err)
 	}
+	c.timeouts = 0
 
 	response, err := DecodeFrame(responseBuffer[:n])
 	if err != nil {
@@ -129,6 +254,15 @@ func (c *Client) WriteSingleRegister(ctx context.Context, unitID uint8, addr uin
 	return err
 }
 
+// WriteMultipleRegisters writes consecutive holding registers (function code
+// 0x10)
+func (c *Client) WriteMultipleRegisters(ctx context.Context, unitID uint8, startAddr uint16, values []uint16) error {
+	request := WriteMultipleRegistersRequest(0, unitID, startAddr, values)
+
+	_, err := c.SendFrame(ctx, request)
+	return err
+}
+
 // ReadInputRegisters reads input registers (function code 0x04)
 func (c *Client) ReadInputRegisters(ctx context.Context, unitID uint8, startAddr uint16, quantity uint16) ([]uint16, error) {
 	request := ReadInputRegistersRequest(0, unitID, startAddr, quantity)
@@ -140,3 +274,43 @@ func (c *Client) ReadInputRegisters(ctx context.Context, unitID uint8, startAddr
 
 	return response.ParseRegisterResponse()
 }
+
+// ReadCoils reads coils (function code 0x01)
+func (c *Client) ReadCoils(ctx context.Context, unitID uint8, startAddr uint16, quantity uint16) ([]bool, error) {
+	request := ReadBitsRequest(0, unitID, FuncCodeReadCoils, startAddr, quantity)
+
+	response, err := c.SendFrame(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.ParseBitResponse(quantity)
+}
+
+// ReadDiscreteInputs reads discrete inputs (function code 0x02)
+func (c *Client) ReadDiscreteInputs(ctx context.Context, unitID uint8, startAddr uint16, quantity uint16) ([]bool, error) {
+	request := ReadBitsRequest(0, unitID, FuncCodeReadDiscreteInputs, startAddr, quantity)
+
+	response, err := c.SendFrame(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.ParseBitResponse(quantity)
+}
+
+// WriteSingleCoil switches a single coil (function code 0x05)
+func (c *Client) WriteSingleCoil(ctx context.Context, unitID uint8, addr uint16, on bool) error {
+	request := WriteSingleCoilRequest(0, unitID, addr, on)
+
+	_, err := c.SendFrame(ctx, request)
+	return err
+}
+
+// WriteMultipleCoils switches consecutive coils (function code 0x0F)
+func (c *Client) WriteMultipleCoils(ctx context.Context, unitID uint8, startAddr uint16, coils []bool) error {
+	request := WriteMultipleCoilsRequest(0, unitID, startAddr, coils)
+
+	_, err := c.SendFrame(ctx, request)
+	return err
+}
@@ -2,8 +2,11 @@ package modbus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
@@ -17,9 +20,28 @@ type Device struct {
 	Client      *Client
 	IOMapping   map[string]string // logicalName -> registerName
 	RegisterMap map[string]*types.RegisterDefinition
+	SafeStates  map[string]any // logical/register name -> safe value
 	mu          sync.RWMutex
-	lastValues  map[string]interface{}
+	lastValues  map[string]Sample
+	staleAfter  time.Duration
 	connected   bool
+	identity    *types.DeviceIdentity
+
+	calibrations map[string]types.Calibration // registerName -> correction
+
+	virtuals     map[string]*virtualRegister
+	virtualOrder []string // Dependencies first
+
+	aliasHandler  AliasHandler
+	aliasReported map[string]bool // Deprecated names already reported
+
+	limits       map[string]*writeLimit // registerName -> write limit
+	limitHandler WriteLimitHandler
+
+	wordLocksMu sync.Mutex
+	wordLocks   map[uint16]*sync.Mutex // Holding register address -> write lock
+
+	trace atomic.Bool // Trace the frames of workflow steps
 }
 
 func NewDevice(
@@ -37,8 +59,32 @@ func NewDevice(
 		registerMap[reg.Name] = reg
 	}
 
-	address := fmt.Sprintf("%s:%d", ipAddress, port)
-	client := NewClient(address, timeout)
+	virtuals, virtualOrder, err := buildVirtualRegisters(profile.VirtualRegisters, registerMap, ioMapping)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAliases(profile.Aliases, registerMap, virtuals, ioMapping); err != nil {
+		return nil, err
+	}
+	if err := checkByteOrders(profile); err != nil {
+		return nil, fmt.Errorf("device %s: %w", name, err)
+	}
+
+	var client *Client
+	switch profile.Connection.Protocol {
+	case "", types.ProtocolModbusTCP:
+		client = NewClient(fmt.Sprintf("%s:%d", ipAddress, port), timeout)
+	case types.ProtocolModbusRTU:
+		if profile.Connection.Serial == nil {
+			return nil, fmt.Errorf("modbus_rtu device %s without serial settings", name)
+		}
+		client, err = NewRTUClient(*profile.Connection.Serial, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("device %s: unsupported protocol %q, use modbus_tcp or modbus_rtu", name, profile.Connection.Protocol)
+	}
 
 	return &Device{
 		ID:          uuid.New(),
@@ -47,8 +93,14 @@ func NewDevice(
 		Client:      client,
 		IOMapping:   ioMapping,
 		RegisterMap: registerMap,
-		lastValues:  make(map[string]interface{}),
+		lastValues:  make(map[string]Sample),
 		connected:   false,
+
+		calibrations: make(map[string]types.Calibration),
+		virtuals:     virtuals,
+		virtualOrder: virtualOrder,
+
+		aliasReported: make(map[string]bool),
 	}, nil
 }
 
@@ -80,26 +132,63 @@ func (d *Device) Disconnect() error {
 	return nil
 }
 
+// Connected reports whether the device is connected; not while a lost
+// connection is being dialed again
+func (d *Device) Connected() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.connected && !d.Client.Reconnecting()
+}
+
 // ReadRegister liest einen Register nach Name
 func (d *Device) ReadRegister(ctx context.Context, registerName string) (interface{}, error) {
+	sample, err := d.ReadSample(ctx, registerName)
+	if err != nil {
+		return nil, err
+	}
+	return sample.Value, nil
+}
+
+// ReadSample reads a register like ReadRegister and also returns the raw
+// register words and the unit
+func (d *Device) ReadSample(ctx context.Context, registerName string) (Sample, error) {
+	if v, ok := d.virtuals[registerName]; ok {
+		return d.readVirtual(ctx, v)
+	}
+
 	d.mu.RLock()
 	reg, exists := d.RegisterMap[registerName]
 	d.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("register not found: %s", registerName)
+		if current, ok := d.aliasRegister(registerName); ok {
+			return d.ReadSample(ctx, current)
+		}
+		return Sample{}, fmt.Errorf("register not found: %s", registerName)
 	}
 
-	// Support for Coils and Discrete Inputs
+	// Coils and discrete inputs are single bits, their raw word is 0 or 1
 	if reg.Type == types.RegisterTypeCoil || reg.Type == types.RegisterTypeDiscreteInput {
-		// For single bit, read as coil/discrete input
-		// TODO: Implement ReadCoils/ReadDiscreteInputs
-		return nil, fmt.Errorf("coil/discrete input reading not yet implemented")
+		var bits []bool
+		var err error
+		if reg.Type == types.RegisterTypeCoil {
+			bits, err = d.Client.ReadCoils(ctx, uint8(d.Profile.Connection.UnitID), reg.Address, 1)
+		} else {
+			bits, err = d.Client.ReadDiscreteInputs(ctx, uint8(d.Profile.Connection.UnitID), reg.Address, 1)
+		}
+		if err != nil {
+			d.markBad(registerName)
+			return Sample{}, fmt.Errorf("failed to read register %s: %w", registerName, err)
+		}
+
+		sample := newSample(bits[0], []uint16{boolWord(bits[0])}, reg.Unit)
+		d.cache(registerName, sample)
+		return sample, nil
 	}
 
 	// For registers (holding/input)
 	if reg.Type != types.RegisterTypeHoldingRegister && reg.Type != types.RegisterTypeInputRegister {
-		return nil, fmt.Errorf("unsupported register type: %s", reg.Type)
+		return Sample{}, fmt.Errorf("unsupported register type: %s", reg.Type)
 	}
 
 	quantity := d.getRegisterQuantity(reg.DataType)
@@ -115,32 +204,96 @@ func (d *Device) ReadRegister(ctx context.Context, registerName string) (interfa
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read register %s: %w", registerName, err)
+		d.markBad(registerName)
+		return Sample{}, fmt.Errorf("failed to read register %s: %w", registerName, err)
 	}
 
+	return d.registerSample(registerName, reg, values), nil
+}
+
+// registerSample converts the words read from a register and caches the
+// sample
+func (d *Device) registerSample(registerName string, reg *types.RegisterDefinition, values []uint16) Sample {
 	// Convert value based on data type
-	value := d.convertRegisterValue(values, reg.DataType, reg.ScaleFactor)
+	var value interface{}
+	if reg.Bit != nil {
+		value = bitValue(values[0], *reg.Bit)
+	} else {
+		words := reorderWords(values, d.byteOrder(reg))
+		value = d.convertRegisterValue(words, reg.DataType, reg.ScaleFactor, d.calibration(registerName))
+	}
 
 	// Cache update
-	d.mu.Lock()
-	d.lastValues[registerName] = value
-	d.mu.Unlock()
+	sample := newSample(value, values, reg.Unit)
+	d.cache(registerName, sample)
 
-	return value, nil
+	return sample
 }
 
 // WriteRegister schreibt einen Register
 func (d *Device) WriteRegister(ctx context.Context, registerName string, value interface{}) error {
+	_, err := d.WriteSample(ctx, registerName, value)
+	return err
+}
+
+// WriteSample writes a register like WriteRegister and returns the value
+// written with the raw register word sent to the device
+func (d *Device) WriteSample(ctx context.Context, registerName string, value interface{}) (Sample, error) {
 	d.mu.RLock()
 	reg, exists := d.RegisterMap[registerName]
 	d.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("register not found: %s", registerName)
+		if d.IsVirtual(registerName) {
+			return Sample{}, fmt.Errorf("virtual register %s is read-only", registerName)
+		}
+		if current, ok := d.aliasRegister(registerName); ok {
+			return d.WriteSample(ctx, current, value)
+		}
+		return Sample{}, fmt.Errorf("register not found: %s", registerName)
 	}
 
 	if reg.Access != types.AccessTypeReadWrite {
-		return fmt.Errorf("register %s is read-only", registerName)
+		return Sample{}, fmt.Errorf("register %s is read-only", registerName)
+	}
+
+	// Engineering values like "2.5 bar" are converted to the register unit
+	if str, ok := value.(string); ok {
+		resolved, err := resolveString(reg, str)
+		if err != nil {
+			return Sample{}, fmt.Errorf("register %s: %w", registerName, err)
+		}
+		value = resolved
+	}
+
+	recordWrite, err := d.checkWriteLimit(ctx, registerName, value)
+	if err != nil {
+		return Sample{}, err
+	}
+
+	if reg.Type == types.RegisterTypeCoil {
+		sample, err := d.writeCoils(ctx, reg, value)
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to write register %s: %w", registerName, err)
+		}
+		recordWrite()
+		return sample, nil
+	}
+
+	// 32 and 64 bit values span consecutive registers and are written at once
+	if d.getRegisterQuantity(reg.DataType) > 1 && reg.Bit == nil {
+		words, err := d.registerWords(registerName, reg, value)
+		if err != nil {
+			return Sample{}, fmt.Errorf("register %s: %w", registerName, err)
+		}
+
+		unlock := d.lockWord(ctx, reg.Address)
+		defer unlock()
+		if err := d.Client.WriteMultipleRegisters(ctx, uint8(d.Profile.Connection.UnitID), reg.Address, words); err != nil {
+			return Sample{}, err
+		}
+		recordWrite()
+		return newSample(value, words, reg.Unit), nil
 	}
 
 	var regValue uint16
@@ -168,39 +321,175 @@ func (d *Device) WriteRegister(ctx context.Context, registerName string, value i
 				regValue = 0
 			}
 		} else {
-			regValue = uint16(v / reg.ScaleFactor)
+			// Undo scale factor and calibration so the device receives the raw value
+			scaleFactor := reg.ScaleFactor
+			if scaleFactor == 0 {
+				scaleFactor = 1.0
+			}
+			cal := d.calibration(registerName)
+			word, err := encodeWord((v-cal.Offset)/cal.Gain/scaleFactor, reg.DataType)
+			if err != nil {
+				return Sample{}, fmt.Errorf("register %s: %v: %w", registerName, v, err)
+			}
+			regValue = word
 		}
 	default:
-		return fmt.Errorf("unsupported value type: %T", value)
+		return Sample{}, fmt.Errorf("unsupported value type: %T", value)
 	}
 
-	return d.Client.WriteSingleRegister(ctx, uint8(d.Profile.Connection.UnitID), reg.Address, regValue)
+	unlock := d.lockWord(ctx, reg.Address)
+	defer unlock()
+
+	// Bits sharing a word are updated without touching their neighbours
+	if reg.Bit != nil {
+		mask := uint16(1) << uint(*reg.Bit)
+		_, word, err := d.modifyWord(ctx, reg.Address, mask, regValue<<uint(*reg.Bit))
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to write register %s: %w", registerName, err)
+		}
+		recordWrite()
+		return newSample(value, []uint16{word}, reg.Unit), nil
+	}
+	if err := d.Client.WriteSingleRegister(ctx, uint8(d.Profile.Connection.UnitID), reg.Address, regValue); err != nil {
+		return Sample{}, err
+	}
+	recordWrite()
+	return newSample(value, []uint16{regValue}, reg.Unit), nil
 }
 
 func (d *Device) ReadLogical(ctx context.Context, logicalName string) (interface{}, error) {
 	registerName, exists := d.IOMapping[logicalName]
 	if !exists {
-		return nil, fmt.Errorf("logical name not mapped: %s", logicalName)
+		if registerName, exists = d.aliasRegister(logicalName); !exists {
+			return nil, fmt.Errorf("logical name not mapped: %s", logicalName)
+		}
 	}
 
 	return d.ReadRegister(ctx, registerName)
 }
 
+// ReadLogicalSample is ReadSample for a logical name
+func (d *Device) ReadLogicalSample(ctx context.Context, logicalName string) (Sample, error) {
+	registerName, exists := d.IOMapping[logicalName]
+	if !exists {
+		if registerName, exists = d.aliasRegister(logicalName); !exists {
+			return Sample{}, fmt.Errorf("logical name not mapped: %s", logicalName)
+		}
+	}
+
+	return d.ReadSample(ctx, registerName)
+}
+
 func (d *Device) WriteLogical(ctx context.Context, logicalName string, value interface{}) error {
+	_, err := d.WriteLogicalSample(ctx, logicalName, value)
+	return err
+}
+
+// WriteLogicalSample is WriteSample for a logical name
+func (d *Device) WriteLogicalSample(ctx context.Context, logicalName string, value interface{}) (Sample, error) {
 	registerName, exists := d.IOMapping[logicalName]
 	if !exists {
-		return fmt.Errorf("logical name not mapped: %s", logicalName)
+		if registerName, exists = d.aliasRegister(logicalName); !exists {
+			return Sample{}, fmt.Errorf("logical name not mapped: %s", logicalName)
+		}
+	}
+
+	return d.WriteSample(ctx, registerName, value)
+}
+
+// WriteSafeStates writes all configured safe state values. Every entry is
+// attempted even if earlier writes fail; failures are joined into one error.
+// Write limits don't apply.
+func (d *Device) WriteSafeStates(ctx context.Context) error {
+	var errs []error
+	ctx = withoutLimits(ctx)
+
+	for name, value := range d.SafeStates {
+		var err error
+		if _, mapped := d.IOMapping[name]; mapped {
+			err = d.WriteLogical(ctx, name, value)
+		} else {
+			err = d.WriteRegister(ctx, name, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
 	}
 
-	return d.WriteRegister(ctx, registerName, value)
+	return errors.Join(errs...)
 }
 
+// WriteSafeState writes the safe state of a single output given by logical,
+// alias or register name. configured is false if the output has no safe
+// state; nothing is written then. Write limits don't apply.
+func (d *Device) WriteSafeState(ctx context.Context, name string) (configured bool, err error) {
+	ctx = withoutLimits(ctx)
+	registerName, mapped := d.IOMapping[name]
+	if !mapped {
+		if registerName, mapped = d.aliasRegister(name); !mapped {
+			registerName = name
+		}
+	}
+
+	for key, value := range d.SafeStates {
+		if key != name && key != registerName && d.IOMapping[key] != registerName {
+			continue
+		}
+		if _, logical := d.IOMapping[key]; logical {
+			return true, d.WriteLogical(ctx, key, value)
+		}
+		return true, d.WriteRegister(ctx, key, value)
+	}
+	return false, nil
+}
+
+// GetLastValue returns the cached value of a register regardless of its
+// quality, see LastSample
 func (d *Device) GetLastValue(registerName string) (interface{}, bool) {
+	sample, exists := d.LastSample(registerName)
+	if !exists || sample.Value == nil {
+		return nil, false // Never read successfully
+	}
+	return sample.Value, true
+}
+
+// LastValues returns a copy of all cached register values
+func (d *Device) LastValues() map[string]interface{} {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	value, exists := d.lastValues[registerName]
-	return value, exists
+	values := make(map[string]interface{}, len(d.lastValues))
+	for name, sample := range d.lastValues {
+		if sample.Value != nil {
+			values[name] = sample.Value
+		}
+	}
+	return values
+}
+
+// CounterSpan returns the value range of an integer register in its scaled
+// and calibrated units, i.e. the step a counter makes when it rolls over
+func (d *Device) CounterSpan(registerName string) (float64, error) {
+	reg, exists := d.RegisterMap[registerName]
+	if !exists {
+		return 0, fmt.Errorf("register not found: %s", registerName)
+	}
+
+	var span float64
+	switch reg.DataType {
+	case types.DataTypeInt16, types.DataTypeUint16:
+		span = 1 << 16
+	case types.DataTypeInt32, types.DataTypeUint32:
+		span = 1 << 32
+	default:
+		return 0, fmt.Errorf("register %s is not an integer register (%s)", registerName, reg.DataType)
+	}
+
+	scaleFactor := reg.ScaleFactor
+	if scaleFactor == 0 {
+		scaleFactor = 1.0
+	}
+	return span * scaleFactor * d.calibration(registerName).Gain, nil
 }
 
 func (d *Device) getRegisterQuantity(dataType types.DataType) uint16 {
@@ -216,10 +505,101 @@ func (d *Device) getRegisterQuantity(dataType types.DataType) uint16 {
 	}
 }
 
-func (d *Device) convertRegisterValue(registers []uint16, dataType types.DataType, scaleFactor float64) interface{} {
+// registerWords encodes a value of a 32 or 64 bit register into its
+// registers in the register's byte order. Scale factor and calibration are
+// undone; integers are rounded and must fit the data type.
+func (d *Device) registerWords(registerName string, reg *types.RegisterDefinition, value interface{}) ([]uint16, error) {
+	var v float64
+	switch n := value.(type) {
+	case int:
+		v = float64(n)
+	case int16:
+		v = float64(n)
+	case int32:
+		v = float64(n)
+	case int64:
+		v = float64(n)
+	case uint16:
+		v = float64(n)
+	case uint32:
+		v = float64(n)
+	case float32:
+		v = float64(n)
+	case float64:
+		v = n
+	default:
+		return nil, fmt.Errorf("unsupported value type for %s: %T", reg.DataType, value)
+	}
+
+	scaleFactor := reg.ScaleFactor
 	if scaleFactor == 0 {
 		scaleFactor = 1.0
 	}
+	cal := d.calibration(registerName)
+	raw := (v - cal.Offset) / cal.Gain / scaleFactor
+
+	words, err := encodeWords(raw, reg.DataType)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", v, err)
+	}
+	return reorderWords(words, d.byteOrder(reg)), nil
+}
+
+// encodeWord encodes a raw value into a 16 bit register. The value is
+// rounded and must fit the data type; int16 is written as two's complement.
+func encodeWord(raw float64, dataType types.DataType) (uint16, error) {
+	rounded := math.Round(raw)
+	if dataType == types.DataTypeInt16 {
+		if math.IsNaN(rounded) || rounded < math.MinInt16 || rounded > math.MaxInt16 {
+			return 0, fmt.Errorf("out of range of %s", dataType)
+		}
+		return uint16(int16(rounded)), nil
+	}
+	if math.IsNaN(rounded) || rounded < 0 || rounded > math.MaxUint16 {
+		return 0, fmt.Errorf("out of range of %s", dataType)
+	}
+	return uint16(rounded), nil
+}
+
+// encodeWords encodes a raw value into the registers of a 32 or 64 bit data
+// type, ABCD
+func encodeWords(raw float64, dataType types.DataType) ([]uint16, error) {
+	switch dataType {
+	case types.DataTypeInt32:
+		rounded := math.Round(raw)
+		if math.IsNaN(rounded) || rounded < math.MinInt32 || rounded > math.MaxInt32 {
+			return nil, fmt.Errorf("out of range of %s", dataType)
+		}
+		bits := uint32(int32(rounded))
+		return []uint16{uint16(bits >> 16), uint16(bits)}, nil
+
+	case types.DataTypeUint32:
+		rounded := math.Round(raw)
+		if math.IsNaN(rounded) || rounded < 0 || rounded > math.MaxUint32 {
+			return nil, fmt.Errorf("out of range of %s", dataType)
+		}
+		bits := uint32(rounded)
+		return []uint16{uint16(bits >> 16), uint16(bits)}, nil
+
+	case types.DataTypeFloat32:
+		bits := math.Float32bits(float32(raw))
+		return []uint16{uint16(bits >> 16), uint16(bits)}, nil
+
+	case types.DataTypeFloat64:
+		bits := math.Float64bits(raw)
+		return []uint16{uint16(bits >> 48), uint16(bits >> 32), uint16(bits >> 16), uint16(bits)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported data type %s", dataType)
+}
+
+// convertRegisterValue decodes raw registers and applies scale factor and
+// calibration to numeric values
+func (d *Device) convertRegisterValue(registers []uint16, dataType types.DataType, scaleFactor float64, cal types.Calibration) interface{} {
+	if scaleFactor == 0 {
+		scaleFactor = 1.0
+	}
+	scaleFactor *= cal.Gain
 
 	switch dataType {
 	case types.DataTypeBool:
@@ -227,29 +607,35 @@ func (d *Device) convertRegisterValue(registers []uint16, dataType types.DataTyp
 		return registers[0] != 0
 
 	case types.DataTypeUint16:
-		return float64(registers[0]) * scaleFactor
+		return float64(registers[0])*scaleFactor + cal.Offset
 
 	case types.DataTypeInt16:
-		return float64(int16(registers[0])) * scaleFactor
+		return float64(int16(registers[0]))*scaleFactor + cal.Offset
 
 	case types.DataTypeUint32:
 		if len(registers) >= 2 {
 			val := uint32(registers[0])<<16 | uint32(registers[1])
-			return float64(val) * scaleFactor
+			return float64(val)*scaleFactor + cal.Offset
 		}
 
 	case types.DataTypeInt32:
 		if len(registers) >= 2 {
 			val := int32(registers[0])<<16 | int32(registers[1])
-			return float64(val) * scaleFactor
+			return float64(val)*scaleFactor + cal.Offset
 		}
 
 	case types.DataTypeFloat32:
 		if len(registers) >= 2 {
 			// IEEE 754 float32 from 2 registers
 			bits := uint32(registers[0])<<16 | uint32(registers[1])
-			// TODO: Convert to float32
-			return float64(bits)
+			return float64(math.Float32frombits(bits))*scaleFactor + cal.Offset
+		}
+
+	case types.DataTypeFloat64:
+		if len(registers) >= 4 {
+			// IEEE 754 float64 from 4 registers
+			bits := uint64(registers[0])<<48 | uint64(registers[1])<<32 | uint64(registers[2])<<16 | uint64(registers[3])
+			return math.Float64frombits(bits)*scaleFactor + cal.Offset
 		}
 	}
 
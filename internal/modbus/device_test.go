@@ -0,0 +1,143 @@
+package modbus
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+func TestEncodeWord(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      float64
+		dataType types.DataType
+		want     uint16
+		wantErr  bool
+	}{
+		{"uint16", 1234, types.DataTypeUint16, 1234, false},
+		{"uint16 rounds", 12.5, types.DataTypeUint16, 13, false},
+		{"uint16 rounds down", 12.4, types.DataTypeUint16, 12, false},
+		{"uint16 max", math.MaxUint16, types.DataTypeUint16, math.MaxUint16, false},
+		{"uint16 above max", math.MaxUint16 + 1, types.DataTypeUint16, 0, true},
+		{"uint16 negative", -1, types.DataTypeUint16, 0, true},
+		{"uint16 NaN", math.NaN(), types.DataTypeUint16, 0, true},
+		{"int16 positive", 300, types.DataTypeInt16, 300, false},
+		{"int16 negative", -1, types.DataTypeInt16, 0xFFFF, false},
+		{"int16 min", math.MinInt16, types.DataTypeInt16, 0x8000, false},
+		{"int16 max", math.MaxInt16, types.DataTypeInt16, 0x7FFF, false},
+		{"int16 rounds", -2.5, types.DataTypeInt16, 0xFFFD, false},
+		{"int16 below min", math.MinInt16 - 1, types.DataTypeInt16, 0, true},
+		{"int16 above max", math.MaxInt16 + 1, types.DataTypeInt16, 0, true},
+		{"int16 NaN", math.NaN(), types.DataTypeInt16, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeWord(tt.raw, tt.dataType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encodeWord(%v, %s) error = %v, want error %v", tt.raw, tt.dataType, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("encodeWord(%v, %s) = %#04x, want %#04x", tt.raw, tt.dataType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      float64
+		dataType types.DataType
+		want     []uint16
+		wantErr  bool
+	}{
+		{"int32", 70000, types.DataTypeInt32, []uint16{0x0001, 0x1170}, false},
+		{"int32 negative", -1, types.DataTypeInt32, []uint16{0xFFFF, 0xFFFF}, false},
+		{"int32 rounds", 1.5, types.DataTypeInt32, []uint16{0x0000, 0x0002}, false},
+		{"int32 above max", math.MaxInt32 + 1, types.DataTypeInt32, nil, true},
+		{"uint32", math.MaxUint32, types.DataTypeUint32, []uint16{0xFFFF, 0xFFFF}, false},
+		{"uint32 negative", -1, types.DataTypeUint32, nil, true},
+		{"float32", 1.5, types.DataTypeFloat32, []uint16{0x3FC0, 0x0000}, false},
+		{"float64", 1.5, types.DataTypeFloat64, []uint16{0x3FF8, 0x0000, 0x0000, 0x0000}, false},
+		{"unsupported", 1, types.DataTypeBool, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeWords(tt.raw, tt.dataType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encodeWords(%v, %s) error = %v, want error %v", tt.raw, tt.dataType, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("encodeWords(%v, %s) = %#04x, want %#04x", tt.raw, tt.dataType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRegisterValue(t *testing.T) {
+	unity := types.Calibration{Gain: 1}
+	tests := []struct {
+		name        string
+		registers   []uint16
+		dataType    types.DataType
+		scaleFactor float64
+		cal         types.Calibration
+		want        any
+	}{
+		{"bool set", []uint16{0x0004}, types.DataTypeBool, 1, unity, true},
+		{"bool clear", []uint16{0x0000}, types.DataTypeBool, 1, unity, false},
+		{"uint16", []uint16{0xFFFF}, types.DataTypeUint16, 1, unity, 65535.0},
+		{"uint16 scaled", []uint16{250}, types.DataTypeUint16, 0.1, unity, 25.0},
+		{"uint16 scale factor 0", []uint16{250}, types.DataTypeUint16, 0, unity, 250.0},
+		{"int16 negative", []uint16{0xFFFE}, types.DataTypeInt16, 1, unity, -2.0},
+		{"int16 calibrated", []uint16{100}, types.DataTypeInt16, 1, types.Calibration{Offset: -5, Gain: 2}, 195.0},
+		{"uint32", []uint16{0x0001, 0x0000}, types.DataTypeUint32, 1, unity, 65536.0},
+		{"int32 negative", []uint16{0xFFFF, 0xFFFF}, types.DataTypeInt32, 1, unity, -1.0},
+		{"float32", []uint16{0x3FC0, 0x0000}, types.DataTypeFloat32, 1, unity, 1.5},
+		{"float64", []uint16{0x3FF8, 0x0000, 0x0000, 0x0000}, types.DataTypeFloat64, 1, unity, 1.5},
+		{"float32 short", []uint16{0x3FC0}, types.DataTypeFloat32, 1, unity, uint16(0x3FC0)},
+	}
+
+	d := &Device{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := d.convertRegisterValue(tt.registers, tt.dataType, tt.scaleFactor, tt.cal)
+			if got != tt.want {
+				t.Errorf("convertRegisterValue(%#04x, %s) = %v (%T), want %v (%T)", tt.registers, tt.dataType, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		raw      float64
+		dataType types.DataType
+	}{
+		{-12345, types.DataTypeInt32},
+		{4000000000, types.DataTypeUint32},
+		{-0.25, types.DataTypeFloat32},
+		{math.Pi, types.DataTypeFloat64},
+	}
+
+	d := &Device{}
+	for _, tt := range tests {
+		t.Run(string(tt.dataType), func(t *testing.T) {
+			for _, order := range []types.ByteOrder{types.ByteOrderABCD, types.ByteOrderCDAB, types.ByteOrderBADC, types.ByteOrderDCBA} {
+				words, err := encodeWords(tt.raw, tt.dataType)
+				if err != nil {
+					t.Fatalf("encodeWords(%v): %v", tt.raw, err)
+				}
+				onWire := reorderWords(words, order)
+				got := d.convertRegisterValue(reorderWords(onWire, order), tt.dataType, 1, types.Calibration{Gain: 1})
+				if got != tt.raw {
+					t.Errorf("%s: round trip of %v = %v", order, tt.raw, got)
+				}
+			}
+		})
+	}
+}
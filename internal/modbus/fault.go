@@ -0,0 +1,40 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Fault is a simulated failure of a single request
+type Fault struct {
+	Timeout       bool  // Don't answer; the request fails after its timeout
+	ExceptionCode uint8 // Answer with this Modbus exception
+}
+
+// FaultHook returns the fault to simulate for a request, nil to send it to
+// the device. Used by the developer-mode fault injection.
+type FaultHook func(functionCode uint8) *Fault
+
+// SetFaultHook installs a fault hook on the client; nil removes it
+func (c *Client) SetFaultHook(hook FaultHook) {
+	c.mu.Lock()
+	c.faultHook = hook
+	c.mu.Unlock()
+}
+
+// injectFault simulates a fault the way the device would fail. Called with
+// c.mu held, so a simulated timeout blocks the connection like a real one.
+func (c *Client) injectFault(ctx context.Context, fault *Fault, functionCode uint8, timeout time.Duration) error {
+	if fault.Timeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return fmt.Errorf("read failed: %w", os.ErrDeadlineExceeded)
+	}
+	return newExceptionError(functionCode, fault.ExceptionCode)
+}
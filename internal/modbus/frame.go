@@ -27,6 +27,54 @@ const (
 	FuncCodeWriteMultipleRegisters = 0x10
 )
 
+// exceptionFlag is set in the function code of exception responses
+const exceptionFlag = 0x80
+
+// Modbus exception codes
+var exceptionNames = map[uint8]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0A: "gateway path unavailable",
+	0x0B: "gateway target device failed to respond",
+}
+
+// ExceptionError is a Modbus exception response of the device
+type ExceptionError struct {
+	FunctionCode uint8  `json:"function_code"`
+	Code         uint8  `json:"code"`
+	Name         string `json:"name"`
+}
+
+func (e *ExceptionError) Error() string {
+	return fmt.Sprintf("modbus exception 0x%02X (%s) for function 0x%02X", e.Code, e.Name, e.FunctionCode)
+}
+
+// Exception returns the exception carried by a response, nil for normal responses
+func (f *ModbusFrame) Exception() *ExceptionError {
+	if f.FunctionCode&exceptionFlag == 0 {
+		return nil
+	}
+
+	var code uint8
+	if len(f.Data) > 0 {
+		code = f.Data[0]
+	}
+	return newExceptionError(f.FunctionCode&^exceptionFlag, code)
+}
+
+func newExceptionError(functionCode, code uint8) *ExceptionError {
+	exc := &ExceptionError{FunctionCode: functionCode, Code: code, Name: exceptionNames[code]}
+	if exc.Name == "" {
+		exc.Name = "unknown exception"
+	}
+	return exc
+}
+
 // Encode erstellt das komplette TCP Frame
 func (f *ModbusFrame) Encode() []byte {
 	// PDU Length = Function Code (1) + Data
@@ -119,6 +167,25 @@ func WriteSingleRegisterRequest(transactionID uint16, unitID uint8, addr uint16,
 	}
 }
 
+// WriteMultipleRegistersRequest creates a request for function code 0x10
+func WriteMultipleRegistersRequest(transactionID uint16, unitID uint8, startAddr uint16, values []uint16) *ModbusFrame {
+	data := make([]byte, 5+2*len(values))
+	binary.BigEndian.PutUint16(data[0:2], startAddr)
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(values)))
+	data[4] = byte(2 * len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint16(data[5+2*i:], value)
+	}
+
+	return &ModbusFrame{
+		TransactionID: transactionID,
+		ProtocolID:    0x0000,
+		UnitID:        unitID,
+		FunctionCode:  FuncCodeWriteMultipleRegisters,
+		Data:          data,
+	}
+}
+
 // ParseRegisterResponse parst Holding/Input Register Response
 func (f *ModbusFrame) ParseRegisterResponse() ([]uint16, error) {
 	if len(f.Data) < 1 {
@@ -140,3 +207,79 @@ func (f *ModbusFrame) ParseRegisterResponse() ([]uint16, error) {
 
 	return registers, nil
 }
+
+// ReadBitsRequest creates a request for function code 0x01 (coils) or 0x02
+// (discrete inputs)
+func ReadBitsRequest(transactionID uint16, unitID uint8, functionCode uint8, startAddr uint16, quantity uint16) *ModbusFrame {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], startAddr)
+	binary.BigEndian.PutUint16(data[2:4], quantity)
+
+	return &ModbusFrame{
+		TransactionID: transactionID,
+		ProtocolID:    0x0000,
+		UnitID:        unitID,
+		FunctionCode:  functionCode,
+		Data:          data,
+	}
+}
+
+// WriteSingleCoilRequest creates a request for function code 0x05
+func WriteSingleCoilRequest(transactionID uint16, unitID uint8, addr uint16, on bool) *ModbusFrame {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:2], addr)
+	if on {
+		binary.BigEndian.PutUint16(data[2:4], 0xFF00)
+	}
+
+	return &ModbusFrame{
+		TransactionID: transactionID,
+		ProtocolID:    0x0000,
+		UnitID:        unitID,
+		FunctionCode:  FuncCodeWriteSingleCoil,
+		Data:          data,
+	}
+}
+
+// WriteMultipleCoilsRequest creates a request for function code 0x0F. The
+// coils are packed eight per byte, the first coil in the lowest bit.
+func WriteMultipleCoilsRequest(transactionID uint16, unitID uint8, startAddr uint16, coils []bool) *ModbusFrame {
+	byteCount := (len(coils) + 7) / 8
+	data := make([]byte, 5+byteCount)
+	binary.BigEndian.PutUint16(data[0:2], startAddr)
+	binary.BigEndian.PutUint16(data[2:4], uint16(len(coils)))
+	data[4] = byte(byteCount)
+	for i, on := range coils {
+		if on {
+			data[5+i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	return &ModbusFrame{
+		TransactionID: transactionID,
+		ProtocolID:    0x0000,
+		UnitID:        unitID,
+		FunctionCode:  FuncCodeWriteMultipleCoils,
+		Data:          data,
+	}
+}
+
+// ParseBitResponse parses a coil or discrete input response into the states
+// of the requested quantity of bits
+func (f *ModbusFrame) ParseBitResponse(quantity uint16) ([]bool, error) {
+	if len(f.Data) < 1 {
+		return nil, fmt.Errorf("response too short")
+	}
+
+	byteCount := int(f.Data[0])
+	if len(f.Data) < byteCount+1 || byteCount*8 < int(quantity) {
+		return nil, fmt.Errorf("incomplete response data")
+	}
+
+	bits := make([]bool, quantity)
+	for i := range bits {
+		bits[i] = f.Data[1+i/8]>>uint(i%8)&1 != 0
+	}
+
+	return bits, nil
+}
@@ -0,0 +1,129 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// Identify reads the identification registers of the profile and compares
+// them with the values expected for the configured modules and with the
+// reference identity read at onboarding, if given. Read failures are recorded
+// per field; the result is kept on the device.
+func (d *Device) Identify(ctx context.Context, reference *types.DeviceIdentity) *types.DeviceIdentity {
+	identity := &types.DeviceIdentity{
+		Fields: make(map[string]string),
+		ReadAt: time.Now(),
+	}
+
+	for _, reg := range d.Profile.Identification {
+		value, err := d.readIdentification(ctx, reg)
+		if err != nil {
+			if identity.Errors == nil {
+				identity.Errors = make(map[string]string)
+			}
+			identity.Errors[reg.Field] = err.Error()
+			continue
+		}
+
+		identity.Fields[reg.Field] = value
+		if reg.Expected != "" && !identityMatches(reg.Expected, value) {
+			identity.Mismatches = append(identity.Mismatches, types.IdentityMismatch{
+				Module:   reg.Module,
+				Field:    reg.Field,
+				Expected: reg.Expected,
+				Actual:   value,
+			})
+		}
+	}
+
+	identity.Changes = identity.Compare(reference)
+
+	d.mu.Lock()
+	d.identity = identity
+	d.mu.Unlock()
+
+	return identity
+}
+
+// Identity returns the identification read after connect, nil if the device
+// was not identified
+func (d *Device) Identity() *types.DeviceIdentity {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.identity
+}
+
+func (d *Device) readIdentification(ctx context.Context, reg types.IdentificationRegister) (string, error) {
+	quantity := d.getRegisterQuantity(reg.DataType)
+	if reg.DataType == types.DataTypeString {
+		if reg.Length <= 0 {
+			return "", fmt.Errorf("string field %s has no length", reg.Field)
+		}
+		quantity = uint16(reg.Length)
+	}
+
+	unitID := uint8(d.Profile.Connection.UnitID)
+
+	var values []uint16
+	var err error
+
+	switch reg.Type {
+	case types.RegisterTypeHoldingRegister:
+		values, err = d.Client.ReadHoldingRegisters(ctx, unitID, reg.Address, quantity)
+	case types.RegisterTypeInputRegister:
+		values, err = d.Client.ReadInputRegisters(ctx, unitID, reg.Address, quantity)
+	default:
+		return "", fmt.Errorf("unsupported register type for identification: %s", reg.Type)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", reg.Field, err)
+	}
+	if len(values) < int(quantity) {
+		return "", fmt.Errorf("short read for %s: got %d registers, want %d", reg.Field, len(values), quantity)
+	}
+
+	if reg.DataType != types.DataTypeString {
+		values = reorderWords(values, d.Profile.Connection.ByteOrder)
+	}
+
+	switch reg.DataType {
+	case types.DataTypeString:
+		return decodeASCII(values), nil
+	case types.DataTypeUint32:
+		return strconv.FormatUint(uint64(values[0])<<16|uint64(values[1]), 10), nil
+	case types.DataTypeInt16:
+		return strconv.Itoa(int(int16(values[0]))), nil
+	case types.DataTypeInt32:
+		return strconv.Itoa(int(int32(values[0])<<16 | int32(values[1]))), nil
+	default:
+		return strconv.Itoa(int(values[0])), nil
+	}
+}
+
+// decodeASCII converts registers holding two characters each (high byte
+// first) into a string, dropping NUL padding and surrounding spaces
+func decodeASCII(values []uint16) string {
+	buf := make([]byte, 0, len(values)*2)
+	for _, v := range values {
+		buf = append(buf, byte(v>>8), byte(v))
+	}
+	return strings.TrimSpace(strings.TrimRight(string(buf), "\x00"))
+}
+
+// identityMatches compares an expected value with the value read. Numbers may
+// be written in any base Go understands ("0x0002", "2").
+func identityMatches(expected, actual string) bool {
+	if strings.EqualFold(strings.TrimSpace(expected), actual) {
+		return true
+	}
+
+	want, errWant := strconv.ParseInt(strings.TrimSpace(expected), 0, 64)
+	got, errGot := strconv.ParseInt(actual, 10, 64)
+	return errWant == nil && errGot == nil && want == got
+}
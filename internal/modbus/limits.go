@@ -0,0 +1,248 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// ErrWriteLimit is wrapped by every WriteLimitError
+var ErrWriteLimit = errors.New("write limit violated")
+
+// Write limit rules
+const (
+	LimitMinInterval = "min_interval"
+	LimitMaxDelta    = "max_delta"
+	LimitRange       = "range"
+)
+
+// WriteLimitError is returned when a write violates the limits of its
+// register; nothing was written
+type WriteLimitError struct {
+	Device   string  `json:"device"`
+	Register string  `json:"register"`
+	Rule     string  `json:"rule"`
+	Value    any     `json:"value"`
+	Limit    any     `json:"limit"`
+	Previous any     `json:"previous,omitempty"` // Value max_delta was checked against
+	WaitMs   float64 `json:"wait_ms,omitempty"`  // Until min_interval allows the next write
+	Writer   string  `json:"writer,omitempty"`   // See WithWriter
+}
+
+func (e *WriteLimitError) Error() string {
+	switch e.Rule {
+	case LimitMinInterval:
+		return fmt.Sprintf("%s: %s written again within %v ms", ErrWriteLimit, e.Register, e.Limit)
+	case LimitMaxDelta:
+		return fmt.Sprintf("%s: %s changes from %v to %v, more than %v", ErrWriteLimit, e.Register, e.Previous, e.Value, e.Limit)
+	default:
+		return fmt.Sprintf("%s: %v is outside the range %v of %s", ErrWriteLimit, e.Value, e.Limit, e.Register)
+	}
+}
+
+func (e *WriteLimitError) Unwrap() error {
+	return ErrWriteLimit
+}
+
+// WriteLimitHandler is called with every rejected write, e.g. to audit it
+type WriteLimitHandler func(ctx context.Context, device *Device, violation *WriteLimitError)
+
+type writerKey struct{}
+
+// WithWriter names who writes with the returned context, e.g. a user or an
+// execution; rejected writes are reported with it
+func WithWriter(ctx context.Context, writer string) context.Context {
+	return context.WithValue(ctx, writerKey{}, writer)
+}
+
+// Writer returns the writer set with WithWriter
+func Writer(ctx context.Context) string {
+	writer, _ := ctx.Value(writerKey{}).(string)
+	return writer
+}
+
+type unlimitedKey struct{}
+
+// withoutLimits exempts the writes of the returned context from write
+// limits; safe states must always be written
+func withoutLimits(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unlimitedKey{}, true)
+}
+
+// writeLimit is the state of a register's limits
+type writeLimit struct {
+	cfg types.WriteLimit
+
+	mu        sync.Mutex
+	lastWrite time.Time
+	lastValue *float64
+}
+
+// SetWriteLimits sets the write limits by logical or register name
+func (d *Device) SetWriteLimits(limits map[string]types.WriteLimit) error {
+	resolved := make(map[string]*writeLimit, len(limits))
+	for name, cfg := range limits {
+		registerName := name
+		if target, mapped := d.IOMapping[name]; mapped {
+			registerName = target
+		}
+		reg, exists := d.RegisterMap[registerName]
+		if !exists {
+			return fmt.Errorf("write limit for unknown register %s", name)
+		}
+		if reg.Access != types.AccessTypeReadWrite {
+			return fmt.Errorf("write limit for read-only register %s", name)
+		}
+		if _, duplicate := resolved[registerName]; duplicate {
+			return fmt.Errorf("register %s has more than one write limit", registerName)
+		}
+		if cfg.MinIntervalMs < 0 {
+			return fmt.Errorf("write limit of %s: min_interval_ms must not be negative", name)
+		}
+		if cfg.MaxDelta != nil && *cfg.MaxDelta <= 0 {
+			return fmt.Errorf("write limit of %s: max_delta must be positive", name)
+		}
+		if cfg.Min != nil && cfg.Max != nil && *cfg.Min > *cfg.Max {
+			return fmt.Errorf("write limit of %s: min is above max", name)
+		}
+		if reg.DataType == types.DataTypeBool && (cfg.MaxDelta != nil || cfg.Min != nil || cfg.Max != nil) {
+			return fmt.Errorf("write limit of %s: only min_interval_ms applies to bool registers", name)
+		}
+		resolved[registerName] = &writeLimit{cfg: cfg}
+	}
+
+	d.mu.Lock()
+	d.limits = resolved
+	d.mu.Unlock()
+	return nil
+}
+
+// WriteLimits returns the write limits by register name
+func (d *Device) WriteLimits() map[string]types.WriteLimit {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	limits := make(map[string]types.WriteLimit, len(d.limits))
+	for name, limit := range d.limits {
+		limits[name] = limit.cfg
+	}
+	return limits
+}
+
+// SetWriteLimitHandler registers a handler for rejected writes
+func (d *Device) SetWriteLimitHandler(handler WriteLimitHandler) {
+	d.mu.Lock()
+	d.limitHandler = handler
+	d.mu.Unlock()
+}
+
+// checkWriteLimit rejects a write violating the register's limits. An
+// accepted write starts the min_interval right away, so concurrent writes
+// can't both pass. The returned function records the written value.
+func (d *Device) checkWriteLimit(ctx context.Context, registerName string, value any) (func(), error) {
+	d.mu.RLock()
+	limit := d.limits[registerName]
+	handler := d.limitHandler
+	d.mu.RUnlock()
+
+	if limit == nil {
+		return func() {}, nil
+	}
+	// Safe states pass, but later steps are measured from them
+	if ctx.Value(unlimitedKey{}) != nil {
+		return func() { limit.record(value) }, nil
+	}
+
+	violation := limit.check(d, registerName, value)
+	if violation == nil {
+		return func() { limit.record(value) }, nil
+	}
+
+	violation.Device = d.Name
+	violation.Writer = Writer(ctx)
+	if handler != nil {
+		handler(ctx, d, violation)
+	}
+	return nil, violation
+}
+
+func (l *writeLimit) check(d *Device, registerName string, value any) *WriteLimitError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.cfg.MinIntervalMs > 0 && !l.lastWrite.IsZero() {
+		interval := time.Duration(l.cfg.MinIntervalMs) * time.Millisecond
+		if elapsed := now.Sub(l.lastWrite); elapsed < interval {
+			return &WriteLimitError{
+				Register: registerName,
+				Rule:     LimitMinInterval,
+				Value:    value,
+				Limit:    l.cfg.MinIntervalMs,
+				WaitMs:   float64((interval - elapsed).Microseconds()) / 1000,
+			}
+		}
+	}
+
+	if n, ok := numericValue(value); ok && (l.cfg.Min != nil || l.cfg.Max != nil || l.cfg.MaxDelta != nil) {
+		if (l.cfg.Min != nil && n < *l.cfg.Min) || (l.cfg.Max != nil && n > *l.cfg.Max) {
+			return &WriteLimitError{
+				Register: registerName,
+				Rule:     LimitRange,
+				Value:    value,
+				Limit:    [2]any{floatOrNil(l.cfg.Min), floatOrNil(l.cfg.Max)},
+			}
+		}
+
+		if l.cfg.MaxDelta != nil {
+			previous, known := l.previous(d, registerName)
+			if known && math.Abs(n-previous) > *l.cfg.MaxDelta {
+				return &WriteLimitError{
+					Register: registerName,
+					Rule:     LimitMaxDelta,
+					Value:    value,
+					Limit:    *l.cfg.MaxDelta,
+					Previous: previous,
+				}
+			}
+		}
+	}
+
+	l.lastWrite = now
+	return nil
+}
+
+// previous returns the value a step is measured from: the last written
+// value, otherwise the last polled one. The first write of a register that
+// is not polled is not limited by max_delta.
+func (l *writeLimit) previous(d *Device, registerName string) (float64, bool) {
+	if l.lastValue != nil {
+		return *l.lastValue, true
+	}
+	if polled, ok := d.GetLastValue(registerName); ok {
+		return numericValue(polled)
+	}
+	return 0, false
+}
+
+func (l *writeLimit) record(value any) {
+	n, ok := numericValue(value)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	l.lastValue = &n
+	l.mu.Unlock()
+}
+
+func floatOrNil(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
@@ -0,0 +1,181 @@
+package modbus
+
+import (
+	"sort"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// OperationParameter describes a parameter of a device step
+type OperationParameter struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"` // "string", "bool" or "number"
+	Required bool     `json:"required"`
+	Value    any      `json:"value,omitempty"` // Fixed value, e.g. the register name
+	Min      *float64 `json:"min,omitempty"`   // Engineering units, after scale factor and calibration
+	Max      *float64 `json:"max,omitempty"`
+	Unit     string   `json:"unit,omitempty"`
+}
+
+// Operation is a device step the executor can run on a device, ready to be
+// offered by the workflow editor
+type Operation struct {
+	Operation   string               `json:"operation"` // read_logical, write_logical, read_register or write_register
+	Register    string               `json:"register"`
+	Target      string               `json:"target,omitempty"` // Register behind a logical name
+	DataType    types.DataType       `json:"data_type"`
+	Unit        string               `json:"unit,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Virtual     bool                 `json:"virtual,omitempty"`
+	Parameters  []OperationParameter `json:"parameters"`
+}
+
+// Operations lists the operations available on the device: logical names
+// first, then registers and virtual registers, each ordered by name.
+// Deprecated aliases are not offered.
+func (d *Device) Operations() []Operation {
+	ops := make([]Operation, 0, 2*(len(d.IOMapping)+len(d.RegisterMap))+len(d.virtuals))
+
+	for _, logical := range sortedKeys(d.IOMapping) {
+		target := d.IOMapping[logical]
+		if v, ok := d.virtuals[target]; ok {
+			ops = append(ops, d.virtualOperation("read_logical", logical, target, v))
+			continue
+		}
+		reg, ok := d.RegisterMap[target]
+		if !ok || !operable(reg) {
+			continue
+		}
+		ops = append(ops, d.readOperation("read_logical", logical, target, reg))
+		if reg.Access == types.AccessTypeReadWrite {
+			ops = append(ops, d.writeOperation("write_logical", logical, target, reg))
+		}
+	}
+
+	for _, name := range sortedKeys(d.RegisterMap) {
+		reg := d.RegisterMap[name]
+		if !operable(reg) {
+			continue
+		}
+		ops = append(ops, d.readOperation("read_register", name, "", reg))
+		if reg.Access == types.AccessTypeReadWrite {
+			ops = append(ops, d.writeOperation("write_register", name, "", reg))
+		}
+		if bitfield(reg) {
+			ops = append(ops, bitsOperation(name, reg))
+		}
+	}
+
+	for _, name := range sortedKeys(d.virtuals) {
+		ops = append(ops, d.virtualOperation("read_register", name, "", d.virtuals[name]))
+	}
+
+	return ops
+}
+
+// operable reports whether ReadRegister supports the register type
+func operable(reg *types.RegisterDefinition) bool {
+	switch reg.Type {
+	case types.RegisterTypeHoldingRegister, types.RegisterTypeInputRegister,
+		types.RegisterTypeCoil, types.RegisterTypeDiscreteInput:
+		return true
+	}
+	return false
+}
+
+func (d *Device) readOperation(op, name, target string, reg *types.RegisterDefinition) Operation {
+	return Operation{
+		Operation:   op,
+		Register:    name,
+		Target:      target,
+		DataType:    reg.DataType,
+		Unit:        reg.Unit,
+		Description: reg.Description,
+		Parameters:  []OperationParameter{registerParameter(name)},
+	}
+}
+
+func (d *Device) writeOperation(op, name, target string, reg *types.RegisterDefinition) Operation {
+	value := OperationParameter{Name: "value", Type: "number", Required: true, Unit: reg.Unit}
+	if reg.DataType == types.DataTypeBool || reg.Type == types.RegisterTypeCoil {
+		value.Type = "bool"
+	} else {
+		registerName := target
+		if registerName == "" {
+			registerName = name
+		}
+		value.Min, value.Max = d.writeRange(registerName, reg)
+	}
+
+	operation := d.readOperation(op, name, target, reg)
+	operation.Parameters = append(operation.Parameters, value)
+	return operation
+}
+
+// bitfield reports whether a register is a writable word of bits
+func bitfield(reg *types.RegisterDefinition) bool {
+	return reg.Type == types.RegisterTypeHoldingRegister && reg.Access == types.AccessTypeReadWrite &&
+		reg.DataType == types.DataTypeUint16 && reg.Bit == nil
+}
+
+func bitsOperation(name string, reg *types.RegisterDefinition) Operation {
+	lo, hi := 0.0, 65535.0
+	word := func(param string) OperationParameter {
+		return OperationParameter{Name: param, Type: "word", Required: true, Min: &lo, Max: &hi}
+	}
+
+	return Operation{
+		Operation:   "write_bits",
+		Register:    name,
+		DataType:    reg.DataType,
+		Description: reg.Description,
+		Parameters:  []OperationParameter{registerParameter(name), word("mask"), word("value")},
+	}
+}
+
+func (d *Device) virtualOperation(op, name, target string, v *virtualRegister) Operation {
+	return Operation{
+		Operation:   op,
+		Register:    name,
+		Target:      target,
+		DataType:    v.def.DataType,
+		Unit:        v.def.Unit,
+		Description: v.def.Description,
+		Virtual:     true,
+		Parameters:  []OperationParameter{registerParameter(name)},
+	}
+}
+
+func registerParameter(name string) OperationParameter {
+	return OperationParameter{Name: "register", Type: "string", Required: true, Value: name}
+}
+
+// writeRange returns the values WriteRegister can send in engineering
+// units. Writes go to a single register, so wider types are limited to it.
+func (d *Device) writeRange(registerName string, reg *types.RegisterDefinition) (*float64, *float64) {
+	rawMin, rawMax := 0.0, 65535.0
+	if reg.DataType == types.DataTypeInt16 {
+		rawMin, rawMax = -32768, 32767
+	}
+
+	scale := reg.ScaleFactor
+	if scale == 0 {
+		scale = 1
+	}
+	cal := d.calibration(registerName)
+	lo := rawMin*scale*cal.Gain + cal.Offset
+	hi := rawMax*scale*cal.Gain + cal.Offset
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return &lo, &hi
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
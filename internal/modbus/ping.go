@@ -0,0 +1,67 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// PingResult is the outcome of a single test transaction
+type PingResult struct {
+	Success      bool               `json:"success"`
+	RegisterType types.RegisterType `json:"register_type"`
+	Address      uint16             `json:"address"`
+	Value        *uint16            `json:"value,omitempty"`
+	LatencyMs    float64            `json:"latency_ms"` // Round trip of the read, without connecting
+	Error        string             `json:"error,omitempty"`
+	Exception    *ExceptionError    `json:"exception,omitempty"` // Set if the device answered with an exception
+}
+
+// Ping reads a single holding or input register to verify the connection.
+// An exception response still proves the device is reachable.
+func (d *Device) Ping(ctx context.Context, registerType types.RegisterType, address uint16) *PingResult {
+	result := &PingResult{RegisterType: registerType, Address: address}
+
+	if registerType != types.RegisterTypeHoldingRegister && registerType != types.RegisterTypeInputRegister {
+		result.Error = fmt.Sprintf("unsupported register type: %s", registerType)
+		return result
+	}
+
+	// No-op if the connection is up
+	if err := d.Connect(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	unitID := uint8(d.Profile.Connection.UnitID)
+	start := time.Now()
+
+	var values []uint16
+	var err error
+	if registerType == types.RegisterTypeHoldingRegister {
+		values, err = d.Client.ReadHoldingRegisters(ctx, unitID, address, 1)
+	} else {
+		values, err = d.Client.ReadInputRegisters(ctx, unitID, address, 1)
+	}
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		result.Error = err.Error()
+		var exc *ExceptionError
+		if errors.As(err, &exc) {
+			result.Exception = exc
+		}
+		return result
+	}
+	if len(values) == 0 {
+		result.Error = "empty response"
+		return result
+	}
+
+	result.Success = true
+	result.Value = &values[0]
+	return result
+}
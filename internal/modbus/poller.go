@@ -12,18 +12,22 @@ type Poller struct {
 	device   *Device
 	interval time.Duration
 	logger   *zap.Logger
-	stopChan chan struct{}
+	ctx      context.Context // Cancelled by Stop, aborts a running poll
+	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 	running  bool
 	mu       sync.Mutex
 }
 
-func NewPoller(device *Device, interval time.Duration, logger *zap.Logger) *Poller {
+// NewPoller creates a poller that polls until Stop is called or ctx is done
+func NewPoller(ctx context.Context, device *Device, interval time.Duration, logger *zap.Logger) *Poller {
+	ctx, cancel := context.WithCancel(ctx)
 	return &Poller{
 		device:   device,
 		interval: interval,
 		logger:   logger,
-		stopChan: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
@@ -57,7 +61,7 @@ func (p *Poller) Stop() {
 	}
 	p.mu.Unlock()
 
-	close(p.stopChan)
+	p.cancel()
 	p.wg.Wait()
 
 	p.mu.Lock()
@@ -75,7 +79,7 @@ func (p *Poller) pollLoop() {
 
 	for {
 		select {
-		case <-p.stopChan:
+		case <-p.ctx.Done():
 			return
 		case <-ticker.C:
 			p.pollDevice()
@@ -84,11 +88,20 @@ func (p *Poller) pollLoop() {
 }
 
 func (p *Poller) pollDevice() {
-	ctx, cancel := context.WithTimeout(context.Background(), p.interval/2)
+	// The requests would fail at once; cached values turn stale meanwhile
+	if p.device.Client.Reconnecting() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.interval/2)
 	defer cancel()
 
 	// Alle Register im Profile pollen
 	for _, reg := range p.device.Profile.Registers {
+		if p.ctx.Err() != nil {
+			// Stopped while polling
+			return
+		}
 		if reg.Access == "read_only" || reg.Access == "read_write" {
 			_, err := p.device.ReadRegister(ctx, reg.Name)
 			if err != nil {
@@ -99,6 +112,8 @@ func (p *Poller) pollDevice() {
 			}
 		}
 	}
+
+	p.device.RefreshVirtualRegisters()
 }
 
 // IsRunning gibt an ob Poller läuft
@@ -0,0 +1,170 @@
+package modbus
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"time"
+)
+
+// A TCP connection that fails is closed and dialed again in the background,
+// waiting twice as long after each failed attempt up to a maximum. Requests
+// fail at once with ErrReconnecting meanwhile instead of each waiting for a
+// timeout. Serial lines are kept open; a missing answer on a shared bus says
+// nothing about the port.
+
+// Default delays between reconnect attempts
+const (
+	DefaultReconnectMinDelay = 500 * time.Millisecond
+	DefaultReconnectMaxDelay = 30 * time.Second
+)
+
+// lossTimeouts is the number of timeouts in a row after which a connection
+// counts as lost, e.g. after a cable was pulled and no reset arrives
+const lossTimeouts = 3
+
+// ErrReconnecting is returned for requests while a lost connection is being
+// dialed again
+var ErrReconnecting = errors.New("not connected, reconnecting")
+
+// ConnectionState is a change of the connection reported to the
+// ConnectionHandler
+type ConnectionState string
+
+const (
+	ConnectionLost      ConnectionState = "lost"      // Failed, reconnecting in the background
+	ConnectionConnected ConnectionState = "connected" // Reconnected after a loss
+)
+
+// ConnectionEvent describes a lost or restored connection
+type ConnectionEvent struct {
+	State    ConnectionState
+	Err      error         // Why the connection was lost
+	Downtime time.Duration // How long it was lost, when reconnected
+}
+
+// ConnectionHandler is called when the connection is lost and when it is
+// restored. It is not called while a request holds the connection.
+type ConnectionHandler func(event ConnectionEvent)
+
+// SetConnectionHandler registers a handler for connection changes; nil
+// removes it
+func (c *Client) SetConnectionHandler(handler ConnectionHandler) {
+	c.mu.Lock()
+	c.connHandler = handler
+	c.mu.Unlock()
+}
+
+// SetReconnectBackoff sets the delay before the first reconnect attempt and
+// the longest delay it doubles to. Zero keeps the default.
+func (c *Client) SetReconnectBackoff(minDelay, maxDelay time.Duration) {
+	if minDelay <= 0 {
+		minDelay = DefaultReconnectMinDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultReconnectMaxDelay
+	}
+
+	c.mu.Lock()
+	c.minDelay = minDelay
+	c.maxDelay = max(maxDelay, minDelay)
+	c.mu.Unlock()
+}
+
+// Reconnecting reports whether the connection was lost and is being dialed
+// again
+func (c *Client) Reconnecting() bool {
+	return c.reconnecting.Load()
+}
+
+// connectionFailed counts a failed read or write on the TCP connection and
+// gives the connection up once it is considered lost. Callers hold c.mu.
+func (c *Client) connectionFailed(err error) {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		c.timeouts++
+		if c.timeouts < lossTimeouts {
+			return
+		}
+	}
+
+	c.conn.Close()
+	c.conn = nil
+	c.connected = false
+	c.timeouts = 0
+	c.lostAt = time.Now()
+
+	stop := make(chan struct{})
+	c.reconnectStop = stop
+	c.reconnecting.Store(true)
+	go c.reconnect(stop, err, c.minDelay, c.maxDelay, c.connHandler)
+}
+
+// stopReconnect ends a running reconnect, e.g. because the connection was
+// closed or dialed by Connect. Callers hold c.mu.
+func (c *Client) stopReconnect() {
+	if c.reconnectStop == nil {
+		return
+	}
+	close(c.reconnectStop)
+	c.reconnectStop = nil
+	c.reconnecting.Store(false)
+}
+
+// reconnect dials the lost connection until it succeeds or stop is closed
+func (c *Client) reconnect(stop <-chan struct{}, cause error, minDelay, maxDelay time.Duration, handler ConnectionHandler) {
+	if handler != nil {
+		handler(ConnectionEvent{State: ConnectionLost, Err: cause})
+	}
+
+	delay := minDelay
+	for {
+		timer := time.NewTimer(jitter(delay))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+		if err != nil {
+			delay = min(delay*2, maxDelay)
+			continue
+		}
+
+		downtime, restored := c.restore(stop, conn)
+		if restored && handler != nil {
+			handler(ConnectionEvent{State: ConnectionConnected, Downtime: downtime})
+		}
+		return
+	}
+}
+
+// restore installs a dialed connection unless the reconnect was stopped
+// meanwhile
+func (c *Client) restore(stop <-chan struct{}, conn net.Conn) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-stop:
+		conn.Close()
+		return 0, false
+	default:
+	}
+
+	c.conn = conn
+	c.connected = true
+	c.stopReconnect()
+	return time.Since(c.lostAt), true
+}
+
+// jitter spreads a delay over its upper half, so devices that went down
+// together don't reconnect in lockstep
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
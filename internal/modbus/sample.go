@@ -0,0 +1,102 @@
+package modbus
+
+import "time"
+
+// Quality of a register value
+const (
+	QualityGood  = "good"  // Read from or confirmed by the device
+	QualityStale = "stale" // Last read succeeded, but longer ago than the device's stale_after
+	QualityBad   = "bad"   // Last read failed, e.g. the device is disconnected; the value is the last good one
+)
+
+// Sample is a register value as read from or written to a device
+type Sample struct {
+	Value     any       `json:"value"`         // Engineering value after scaling and calibration
+	Raw       []uint16  `json:"raw,omitempty"` // Register words on the wire, nil for virtual registers
+	Unit      string    `json:"unit,omitempty"`
+	Quality   string    `json:"quality"`
+	Timestamp time.Time `json:"timestamp"` // When the device answered
+}
+
+func newSample(value any, raw []uint16, unit string) Sample {
+	return Sample{Value: value, Raw: raw, Unit: unit, Quality: QualityGood, Timestamp: time.Now()}
+}
+
+// worseQuality returns the worse of two qualities
+func worseQuality(a, b string) string {
+	rank := map[string]int{QualityGood: 0, QualityStale: 1, QualityBad: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// SetStaleAfter sets the age after which cached values are reported as
+// stale; 0 never marks them stale
+func (d *Device) SetStaleAfter(staleAfter time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.staleAfter = staleAfter
+}
+
+// LastSample returns the cached value of a register with its quality
+func (d *Device) LastSample(registerName string) (Sample, bool) {
+	d.mu.RLock()
+	sample, exists := d.lastValues[registerName]
+	staleAfter := d.staleAfter
+	d.mu.RUnlock()
+
+	if !exists {
+		if current, ok := d.aliasRegister(registerName); ok {
+			return d.LastSample(current)
+		}
+		return Sample{}, false
+	}
+	return aged(sample, staleAfter), true
+}
+
+// LastLogicalSample is LastSample for a logical name
+func (d *Device) LastLogicalSample(name string) (Sample, bool) {
+	if registerName, mapped := d.IOMapping[name]; mapped {
+		name = registerName
+	}
+	return d.LastSample(name)
+}
+
+// LastSamples returns a copy of all cached register values with their quality
+func (d *Device) LastSamples() map[string]Sample {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	samples := make(map[string]Sample, len(d.lastValues))
+	for name, sample := range d.lastValues {
+		samples[name] = aged(sample, d.staleAfter)
+	}
+	return samples
+}
+
+// aged marks a good sample older than staleAfter as stale
+func aged(sample Sample, staleAfter time.Duration) Sample {
+	if sample.Quality == QualityGood && staleAfter > 0 && time.Since(sample.Timestamp) > staleAfter {
+		sample.Quality = QualityStale
+	}
+	return sample
+}
+
+// cache stores a read value. Callers must not hold d.mu.
+func (d *Device) cache(registerName string, sample Sample) {
+	d.mu.Lock()
+	d.lastValues[registerName] = sample
+	d.mu.Unlock()
+}
+
+// markBad flags the cached value of a register after a failed read, keeping
+// the last good value and its timestamp
+func (d *Device) markBad(registerName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sample := d.lastValues[registerName]
+	sample.Quality = QualityBad
+	d.lastValues[registerName] = sample
+}
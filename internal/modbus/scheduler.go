@@ -0,0 +1,171 @@
+package modbus
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Requests of a device share one connection. The scheduler grants it to one
+// request at a time; writes and priority requests go first, so they wait at
+// most for the request in flight instead of a whole poll cycle.
+
+// latencySamples is the number of recent waits kept per lane for percentiles
+const latencySamples = 256
+
+type priorityKey struct{}
+
+// WithPriority sends the Modbus requests made with the returned context
+// through the priority lane. Writes always use it.
+func WithPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, true)
+}
+
+func isPriority(ctx context.Context, functionCode uint8) bool {
+	switch functionCode {
+	case FuncCodeWriteSingleCoil, FuncCodeWriteSingleRegister, FuncCodeWriteMultipleCoils, FuncCodeWriteMultipleRegisters:
+		return true
+	}
+	priority, _ := ctx.Value(priorityKey{}).(bool)
+	return priority
+}
+
+// LaneStats describes how long requests of a lane waited for the connection
+type LaneStats struct {
+	Requests   int64   `json:"requests"`
+	Waiting    int     `json:"waiting"`
+	LastWaitMs float64 `json:"last_wait_ms"`
+	AvgWaitMs  float64 `json:"avg_wait_ms"`
+	P99WaitMs  float64 `json:"p99_wait_ms"` // Over the last 256 requests
+	MaxWaitMs  float64 `json:"max_wait_ms"`
+}
+
+// SchedulerStats holds the wait times of both lanes
+type SchedulerStats struct {
+	Priority LaneStats `json:"priority"`
+	Normal   LaneStats `json:"normal"`
+}
+
+type lane struct {
+	waiters   []chan struct{}
+	requests  int64
+	totalWait time.Duration
+	lastWait  time.Duration
+	maxWait   time.Duration
+	samples   []time.Duration // Ring buffer of recent waits
+	next      int
+}
+
+type requestScheduler struct {
+	mu       sync.Mutex
+	busy     bool
+	priority lane
+	normal   lane
+}
+
+// acquire waits until the request may use the connection; the returned
+// function releases it
+func (s *requestScheduler) acquire(ctx context.Context, priority bool) (func(), error) {
+	start := time.Now()
+
+	s.mu.Lock()
+	l := &s.normal
+	if priority {
+		l = &s.priority
+	}
+	if !s.busy {
+		s.busy = true
+		l.record(0)
+		s.mu.Unlock()
+		return s.release, nil
+	}
+
+	granted := make(chan struct{})
+	l.waiters = append(l.waiters, granted)
+	s.mu.Unlock()
+
+	select {
+	case <-granted:
+		s.mu.Lock()
+		l.record(time.Since(start))
+		s.mu.Unlock()
+		return s.release, nil
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		for i, ch := range l.waiters {
+			if ch == granted {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				s.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		s.mu.Unlock()
+		// Granted while giving up: pass the connection on
+		s.release()
+		return nil, ctx.Err()
+	}
+}
+
+// release hands the connection to the next waiter, priority lane first
+func (s *requestScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range []*lane{&s.priority, &s.normal} {
+		if len(l.waiters) > 0 {
+			next := l.waiters[0]
+			l.waiters = l.waiters[1:]
+			close(next)
+			return
+		}
+	}
+	s.busy = false
+}
+
+func (s *requestScheduler) stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SchedulerStats{
+		Priority: s.priority.stats(),
+		Normal:   s.normal.stats(),
+	}
+}
+
+func (l *lane) record(wait time.Duration) {
+	l.requests++
+	l.totalWait += wait
+	l.lastWait = wait
+	l.maxWait = max(l.maxWait, wait)
+
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, wait)
+	} else {
+		l.samples[l.next] = wait
+		l.next = (l.next + 1) % latencySamples
+	}
+}
+
+func (l *lane) stats() LaneStats {
+	stats := LaneStats{
+		Requests:   l.requests,
+		Waiting:    len(l.waiters),
+		LastWaitMs: milliseconds(l.lastWait),
+		MaxWaitMs:  milliseconds(l.maxWait),
+	}
+	if l.requests > 0 {
+		stats.AvgWaitMs = milliseconds(l.totalWait / time.Duration(l.requests))
+	}
+	if len(l.samples) > 0 {
+		sorted := append([]time.Duration(nil), l.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		stats.P99WaitMs = milliseconds(sorted[(len(sorted)*99)/100])
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,250 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// Serial defaults of Modbus RTU, as recommended by the Modbus over serial
+// line specification
+const (
+	defaultBaudRate = 19200
+	defaultParity   = "even"
+	defaultStopBits = 1
+)
+
+// supportedBaudRates are the baud rates serial ports are opened with
+var supportedBaudRates = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400}
+
+// serialPort is an opened serial port in raw mode
+type serialPort interface {
+	io.ReadWriteCloser
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	flush() error // Discards received but unread bytes
+}
+
+// normalizeSerial fills the defaults of a serial config and checks it
+func normalizeSerial(cfg types.SerialConfig) (types.SerialConfig, error) {
+	if cfg.Port == "" {
+		return cfg, errors.New("serial port is empty")
+	}
+	cfg.Port = SerialPortPath(cfg.Port)
+
+	if cfg.BaudRate == 0 {
+		cfg.BaudRate = defaultBaudRate
+	}
+	supported := false
+	for _, rate := range supportedBaudRates {
+		supported = supported || rate == cfg.BaudRate
+	}
+	if !supported {
+		return cfg, fmt.Errorf("unsupported baud rate %d, use one of %v", cfg.BaudRate, supportedBaudRates)
+	}
+
+	switch cfg.Parity {
+	case "":
+		cfg.Parity = defaultParity
+	case "none", "even", "odd":
+	default:
+		return cfg, fmt.Errorf("invalid parity %q, use none, even or odd", cfg.Parity)
+	}
+
+	switch cfg.StopBits {
+	case 0:
+		cfg.StopBits = defaultStopBits
+	case 1, 2:
+	default:
+		return cfg, fmt.Errorf("invalid stop bits %d, use 1 or 2", cfg.StopBits)
+	}
+	return cfg, nil
+}
+
+// serialBus is a serial line shared by the RTU clients of its port. One
+// request at a time is on the line.
+type serialBus struct {
+	cfg  types.SerialConfig
+	refs int // Connected clients, guarded by serialBuses.mu
+
+	mu       sync.Mutex // Held for a request and its response
+	port     serialPort
+	lastIO   time.Time     // End of the last frame on the line
+	frameGap time.Duration // Silence required between frames
+}
+
+var serialBuses = struct {
+	mu    sync.Mutex
+	ports map[string]*serialBus
+}{ports: make(map[string]*serialBus)}
+
+// openSerialBus opens the port of a normalized config or joins the clients
+// already using it. The settings must match theirs.
+func openSerialBus(cfg types.SerialConfig) (*serialBus, error) {
+	serialBuses.mu.Lock()
+	defer serialBuses.mu.Unlock()
+
+	if bus, ok := serialBuses.ports[cfg.Port]; ok {
+		if bus.cfg != cfg {
+			return nil, fmt.Errorf("serial port %s is in use with %d baud, parity %s, %d stop bits",
+				cfg.Port, bus.cfg.BaudRate, bus.cfg.Parity, bus.cfg.StopBits)
+		}
+		bus.refs++
+		return bus, nil
+	}
+
+	port, err := openSerialPort(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port %s: %w", cfg.Port, err)
+	}
+	bus := &serialBus{cfg: cfg, refs: 1, port: port, frameGap: frameGap(cfg)}
+	serialBuses.ports[cfg.Port] = bus
+	return bus, nil
+}
+
+// release closes the port once its last client disconnected
+func (b *serialBus) release() error {
+	serialBuses.mu.Lock()
+	defer serialBuses.mu.Unlock()
+
+	b.refs--
+	if b.refs > 0 {
+		return nil
+	}
+	delete(serialBuses.ports, b.cfg.Port)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.port.Close()
+}
+
+// frameGap is the silence of 3.5 characters that separates RTU frames, at
+// least 1.75 ms above 19200 baud
+func frameGap(cfg types.SerialConfig) time.Duration {
+	bits := 1 + 8 + cfg.StopBits // Start, data and stop bits
+	if cfg.Parity != "none" {
+		bits++
+	}
+	gap := time.Duration(float64(time.Second) * 3.5 * float64(bits) / float64(cfg.BaudRate))
+	return max(gap, 1750*time.Microsecond)
+}
+
+// send writes a request as RTU frame and reads its response
+func (b *serialBus) send(request *ModbusFrame, timeout time.Duration, tracer FrameTracer) (*ModbusFrame, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if wait := time.Until(b.lastIO.Add(b.frameGap)); wait > 0 {
+		time.Sleep(wait)
+	}
+	// Drop late answers to earlier requests that timed out
+	b.port.flush()
+
+	requestData := request.EncodeRTU()
+	start := time.Now()
+	deadline := start.Add(timeout)
+	defer func() { b.lastIO = time.Now() }()
+
+	b.port.SetWriteDeadline(deadline)
+	if _, err := b.port.Write(requestData); err != nil {
+		if tracer != nil {
+			tracer(requestData, nil, time.Since(start), err)
+		}
+		return nil, fmt.Errorf("write failed: %w", err)
+	}
+
+	b.port.SetReadDeadline(deadline)
+	responseData, err := readRTUFrame(b.port)
+	if tracer != nil {
+		tracer(requestData, responseData, time.Since(start), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+
+	response, err := DecodeRTUFrame(responseData)
+	if err != nil {
+		return nil, fmt.Errorf("decode failed: %w", err)
+	}
+	if response.UnitID != request.UnitID || response.FunctionCode&^exceptionFlag != request.FunctionCode {
+		return nil, fmt.Errorf("unexpected response: unit %d function 0x%02X to unit %d function 0x%02X",
+			response.UnitID, response.FunctionCode, request.UnitID, request.FunctionCode)
+	}
+	// RTU has no transaction ID, the response answers the request on the line
+	response.TransactionID = request.TransactionID
+	return response, nil
+}
+
+// readRTUFrame reads a response frame. RTU frames carry no length, so it
+// follows from the function code and, for reads, the byte count.
+func readRTUFrame(r io.Reader) ([]byte, error) {
+	frame := make([]byte, 3, 256) // Max RTU frame
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return frame[:0], err
+	}
+
+	var rest int // After unit, function code and first data byte
+	switch functionCode := frame[1]; {
+	case functionCode&exceptionFlag != 0:
+		rest = 2 // Exception code read, CRC follows
+	case functionCode <= FuncCodeReadInputRegisters:
+		rest = int(frame[2]) + 2 // Byte count read, data and CRC follow
+	case functionCode == FuncCodeWriteSingleCoil, functionCode == FuncCodeWriteSingleRegister,
+		functionCode == FuncCodeWriteMultipleCoils, functionCode == FuncCodeWriteMultipleRegisters:
+		rest = 3 + 2 // Address and value or quantity, then CRC
+	default:
+		return frame, fmt.Errorf("unsupported function code 0x%02X in response", functionCode)
+	}
+
+	frame = frame[:3+rest]
+	n, err := io.ReadFull(r, frame[3:])
+	return frame[:3+n], err
+}
+
+// EncodeRTU builds the RTU frame of a request: unit, function code, data
+// and the CRC
+func (f *ModbusFrame) EncodeRTU() []byte {
+	frame := make([]byte, 0, 2+len(f.Data)+2)
+	frame = append(frame, f.UnitID, f.FunctionCode)
+	frame = append(frame, f.Data...)
+	return binary.LittleEndian.AppendUint16(frame, crc16(frame))
+}
+
+// DecodeRTUFrame parses an RTU frame and checks its CRC
+func DecodeRTUFrame(data []byte) (*ModbusFrame, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("frame too short: %d bytes", len(data))
+	}
+
+	body := data[:len(data)-2]
+	if received, computed := binary.LittleEndian.Uint16(data[len(data)-2:]), crc16(body); received != computed {
+		return nil, fmt.Errorf("CRC mismatch: received 0x%04X, computed 0x%04X", received, computed)
+	}
+
+	return &ModbusFrame{
+		UnitID:       body[0],
+		FunctionCode: body[1],
+		Data:         body[2:],
+	}, nil
+}
+
+// crc16 is the Modbus CRC (polynomial 0xA001, initial value 0xFFFF)
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
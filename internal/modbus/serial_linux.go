@@ -0,0 +1,91 @@
+//go:build linux
+
+package modbus
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// linuxSerialPort is a tty in raw mode. It is opened non-blocking, so the
+// runtime poller serves reads and deadlines work as for sockets.
+type linuxSerialPort struct {
+	*os.File
+}
+
+func openSerialPort(cfg types.SerialConfig) (serialPort, error) {
+	fd, err := unix.Open(cfg.Port, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := configureTermios(fd, cfg); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return &linuxSerialPort{File: os.NewFile(uintptr(fd), cfg.Port)}, nil
+}
+
+// configureTermios sets raw mode, 8 data bits and the line settings
+func configureTermios(fd int, cfg types.SerialConfig) error {
+	baud, ok := baudRates[cfg.BaudRate]
+	if !ok {
+		return fmt.Errorf("unsupported baud rate %d", cfg.BaudRate)
+	}
+
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("not a serial port: %w", err)
+	}
+
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CBAUD | unix.CRTSCTS
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | baud
+	switch cfg.Parity {
+	case "even":
+		t.Cflag |= unix.PARENB
+	case "odd":
+		t.Cflag |= unix.PARENB | unix.PARODD
+	}
+	if cfg.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+	t.Ispeed = baud
+	t.Ospeed = baud
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+func (p *linuxSerialPort) flush() error {
+	conn, err := p.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var flushErr error
+	err = conn.Control(func(fd uintptr) {
+		flushErr = unix.IoctlSetInt(int(fd), unix.TCFLSH, unix.TCIFLUSH)
+	})
+	if err != nil {
+		return err
+	}
+	return flushErr
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package modbus
+
+import "path/filepath"
+
+// SerialPortPath returns the device path of a serial port. Bare names such as
+// "ttyUSB0" or "ttyAMA0" (ARM boards) are taken from /dev.
+func SerialPortPath(port string) string {
+	if port == "" || filepath.IsAbs(port) {
+		return port
+	}
+	return filepath.Join("/dev", port)
+}
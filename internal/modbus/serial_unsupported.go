@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package modbus
+
+import (
+	"errors"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+func openSerialPort(cfg types.SerialConfig) (serialPort, error) {
+	return nil, errors.New("serial ports are only supported on Linux and Windows")
+}
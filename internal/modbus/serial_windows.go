@@ -0,0 +1,150 @@
+//go:build windows
+
+package modbus
+
+import (
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"golang.org/x/sys/windows"
+)
+
+// SerialPortPath returns the device path of a serial port. Windows only
+// opens COM10 and above through the \\.\ device namespace, which works for
+// COM1-COM9 as well.
+func SerialPortPath(port string) string {
+	if strings.HasPrefix(port, `\\.\`) {
+		return port
+	}
+	if len(port) > 3 && strings.EqualFold(port[:3], "COM") {
+		return `\\.\` + strings.ToUpper(port)
+	}
+	return port
+}
+
+// windowsSerialPort is a COM port. Deadlines are applied as comm timeouts
+// before each read and write.
+type windowsSerialPort struct {
+	handle windows.Handle
+
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func openSerialPort(cfg types.SerialConfig) (serialPort, error) {
+	path, err := windows.UTF16PtrFromString(cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := windows.CreateFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	dcb := windows.DCB{
+		BaudRate: uint32(cfg.BaudRate),
+		Flags:    0x01, // fBinary
+		ByteSize: 8,
+		Parity:   windows.NOPARITY,
+		StopBits: windows.ONESTOPBIT,
+	}
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+	switch cfg.Parity {
+	case "even":
+		dcb.Parity = windows.EVENPARITY
+		dcb.Flags |= 0x02 // fParity
+	case "odd":
+		dcb.Parity = windows.ODDPARITY
+		dcb.Flags |= 0x02
+	}
+	if cfg.StopBits == 2 {
+		dcb.StopBits = windows.TWOSTOPBITS
+	}
+	if err := windows.SetCommState(handle, &dcb); err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
+	}
+	return &windowsSerialPort{handle: handle}, nil
+}
+
+func (p *windowsSerialPort) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	deadline := p.readDeadline
+	p.mu.Unlock()
+
+	// Return as soon as a byte arrived, or after the time left
+	timeouts := windows.CommTimeouts{
+		ReadIntervalTimeout:        math.MaxUint32,
+		ReadTotalTimeoutMultiplier: math.MaxUint32,
+		ReadTotalTimeoutConstant:   remainingMs(deadline),
+	}
+	if err := windows.SetCommTimeouts(p.handle, &timeouts); err != nil {
+		return 0, err
+	}
+
+	var n uint32
+	if err := windows.ReadFile(p.handle, b, &n, nil); err != nil {
+		return int(n), err
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return int(n), nil
+}
+
+func (p *windowsSerialPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	deadline := p.writeDeadline
+	p.mu.Unlock()
+
+	timeouts := windows.CommTimeouts{WriteTotalTimeoutConstant: remainingMs(deadline)}
+	if err := windows.SetCommTimeouts(p.handle, &timeouts); err != nil {
+		return 0, err
+	}
+
+	var n uint32
+	if err := windows.WriteFile(p.handle, b, &n, nil); err != nil {
+		return int(n), err
+	}
+	if int(n) < len(b) {
+		return int(n), os.ErrDeadlineExceeded
+	}
+	return int(n), nil
+}
+
+func (p *windowsSerialPort) SetReadDeadline(t time.Time) error {
+	p.mu.Lock()
+	p.readDeadline = t
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *windowsSerialPort) SetWriteDeadline(t time.Time) error {
+	p.mu.Lock()
+	p.writeDeadline = t
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *windowsSerialPort) flush() error {
+	return windows.PurgeComm(p.handle, windows.PURGE_RXCLEAR)
+}
+
+func (p *windowsSerialPort) Close() error {
+	return windows.CloseHandle(p.handle)
+}
+
+// remainingMs is the time until a deadline as comm timeout, at least 1 ms;
+// no deadline waits indefinitely
+func remainingMs(deadline time.Time) uint32 {
+	if deadline.IsZero() {
+		return 0
+	}
+	return uint32(max(time.Until(deadline).Milliseconds(), 1))
+}
@@ -0,0 +1,40 @@
+package modbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// Sources of a device timeout. A step timeout (WithOperationTimeout) takes
+// precedence over both for the requests of the step.
+const (
+	TimeoutSourceProfile = "device_profile"
+	TimeoutSourceGlobal  = "global"
+)
+
+type operationTimeoutKey struct{}
+
+// WithOperationTimeout overrides the device timeout for Modbus requests made
+// with the returned context, e.g. for a workflow step with its own timeout
+func WithOperationTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, operationTimeoutKey{}, timeout)
+}
+
+func operationTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(operationTimeoutKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// EffectiveTimeout returns the device timeout of a profile: its timeout_ms,
+// otherwise the global default
+func EffectiveTimeout(profile *types.DeviceProfileDefinition, global time.Duration) (time.Duration, string) {
+	if profile != nil && profile.Connection.TimeoutMs > 0 {
+		return time.Duration(profile.Connection.TimeoutMs) * time.Millisecond, TimeoutSourceProfile
+	}
+	return global, TimeoutSourceGlobal
+}
@@ -0,0 +1,34 @@
+package modbus
+
+import (
+	"context"
+	"time"
+)
+
+// FrameTracer receives the raw frames of a Modbus transaction for
+// troubleshooting. response is nil if none was received, err is set if
+// writing or reading the connection failed.
+type FrameTracer func(request, response []byte, elapsed time.Duration, err error)
+
+type frameTracerKey struct{}
+
+// WithFrameTracer makes Modbus requests sent with the returned context report
+// their frames to tracer
+func WithFrameTracer(ctx context.Context, tracer FrameTracer) context.Context {
+	return context.WithValue(ctx, frameTracerKey{}, tracer)
+}
+
+func frameTracer(ctx context.Context) FrameTracer {
+	tracer, _ := ctx.Value(frameTracerKey{}).(FrameTracer)
+	return tracer
+}
+
+// SetTrace enables tracing the frames of workflow steps on the device
+func (d *Device) SetTrace(enabled bool) {
+	d.trace.Store(enabled)
+}
+
+// Trace reports whether the frames of workflow steps on the device are traced
+func (d *Device) Trace() bool {
+	return d.trace.Load()
+}
@@ -0,0 +1,198 @@
+package modbus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+// Quantity is a number with an optional unit, e.g. "2.5 bar"
+type Quantity struct {
+	Value float64
+	Unit  string
+}
+
+type unitDef struct {
+	dimension string
+	factor    float64 // To the base unit of the dimension
+	offset    float64 // Added after the factor, for temperatures
+}
+
+// units maps unit symbols to their dimension. Symbols are case sensitive
+// (mbar vs. Mbar); common spellings are listed separately.
+var units = map[string]unitDef{
+	// Pressure, base Pa
+	"Pa": {"pressure", 1, 0}, "hPa": {"pressure", 1e2, 0}, "kPa": {"pressure", 1e3, 0}, "MPa": {"pressure", 1e6, 0},
+	"mbar": {"pressure", 1e2, 0}, "bar": {"pressure", 1e5, 0}, "psi": {"pressure", 6894.757293168, 0},
+
+	// Temperature, base K
+	"K": {"temperature", 1, 0}, "°C": {"temperature", 1, 273.15}, "degC": {"temperature", 1, 273.15}, "C": {"temperature", 1, 273.15},
+	"°F": {"temperature", 5.0 / 9, 459.67 * 5 / 9}, "degF": {"temperature", 5.0 / 9, 459.67 * 5 / 9}, "F": {"temperature", 5.0 / 9, 459.67 * 5 / 9},
+
+	// Length, base m
+	"µm": {"length", 1e-6, 0}, "um": {"length", 1e-6, 0}, "mm": {"length", 1e-3, 0}, "cm": {"length", 1e-2, 0},
+	"m": {"length", 1, 0}, "km": {"length", 1e3, 0}, "in": {"length", 0.0254, 0}, "ft": {"length", 0.3048, 0},
+
+	// Time, base s
+	"ms": {"time", 1e-3, 0}, "s": {"time", 1, 0}, "min": {"time", 60, 0}, "h": {"time", 3600, 0},
+
+	// Speed, base m/s
+	"mm/s": {"speed", 1e-3, 0}, "m/s": {"speed", 1, 0}, "mm/min": {"speed", 1e-3 / 60, 0}, "m/min": {"speed", 1.0 / 60, 0},
+
+	// Rotational speed and frequency, base Hz
+	"Hz": {"frequency", 1, 0}, "kHz": {"frequency", 1e3, 0}, "rpm": {"frequency", 1.0 / 60, 0}, "1/min": {"frequency", 1.0 / 60, 0},
+
+	// Volume, base m³
+	"ml": {"volume", 1e-6, 0}, "mL": {"volume", 1e-6, 0}, "l": {"volume", 1e-3, 0}, "L": {"volume", 1e-3, 0},
+	"m³": {"volume", 1, 0}, "m3": {"volume", 1, 0},
+
+	// Flow, base m³/s
+	"l/min": {"flow", 1e-3 / 60, 0}, "L/min": {"flow", 1e-3 / 60, 0}, "l/h": {"flow", 1e-3 / 3600, 0}, "L/h": {"flow", 1e-3 / 3600, 0},
+	"m³/h": {"flow", 1.0 / 3600, 0}, "m3/h": {"flow", 1.0 / 3600, 0},
+
+	// Mass, base kg
+	"mg": {"mass", 1e-6, 0}, "g": {"mass", 1e-3, 0}, "kg": {"mass", 1, 0}, "t": {"mass", 1e3, 0},
+
+	// Force and torque, base N and N·m
+	"N": {"force", 1, 0}, "kN": {"force", 1e3, 0}, "Nm": {"torque", 1, 0}, "N·m": {"torque", 1, 0},
+
+	// Electrical, base V, A, W
+	"mV": {"voltage", 1e-3, 0}, "V": {"voltage", 1, 0}, "kV": {"voltage", 1e3, 0},
+	"µA": {"current", 1e-6, 0}, "uA": {"current", 1e-6, 0}, "mA": {"current", 1e-3, 0}, "A": {"current", 1, 0},
+	"mW": {"power", 1e-3, 0}, "W": {"power", 1, 0}, "kW": {"power", 1e3, 0},
+
+	// Angle, base degree
+	"°": {"angle", 1, 0}, "deg": {"angle", 1, 0}, "rad": {"angle", 57.29577951308232, 0},
+
+	// Ratio, base 1
+	"%": {"ratio", 1e-2, 0}, "‰": {"ratio", 1e-3, 0}, "ppm": {"ratio", 1e-6, 0},
+}
+
+// quantityPattern splits "1 234,5 bar" into number and unit. Digit groups
+// may be separated by spaces, apostrophes or (narrow) no-break spaces.
+var quantityPattern = regexp.MustCompile(`^([+\-−]?\d(?:[\d.,' \x{00A0}\x{202F}]*\d)?)\s*(.*)$`)
+
+// ParseQuantity parses a number with an optional unit. Both decimal points
+// and decimal commas are accepted: with both separators the last one is the
+// decimal separator ("1.234,5" and "1,234.5"), a single separator is always
+// decimal ("2,5" = 2.5) and repeated separators group thousands ("1.000.000").
+func ParseQuantity(s string) (Quantity, error) {
+	m := quantityPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Quantity{}, fmt.Errorf("%q is not a number", s)
+	}
+
+	value, err := parseNumber(m[1])
+	if err != nil {
+		return Quantity{}, fmt.Errorf("%q is not a number", s)
+	}
+	return Quantity{Value: value, Unit: strings.TrimSpace(m[2])}, nil
+}
+
+func parseNumber(s string) (float64, error) {
+	s = strings.NewReplacer("−", "-", " ", "", "'", "", "\u00a0", "", "\u202f", "").Replace(s)
+
+	dots, commas := strings.Count(s, "."), strings.Count(s, ",")
+	switch {
+	case dots > 0 && commas > 0:
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case commas == 1:
+		s = strings.Replace(s, ",", ".", 1)
+	case commas > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case dots > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// ConvertUnit converts a value between units of the same dimension
+func ConvertUnit(value float64, from, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	src, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	dst, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if src.dimension != dst.dimension {
+		return 0, fmt.Errorf("can't convert %s (%s) to %s (%s)", from, src.dimension, to, dst.dimension)
+	}
+	converted := (value*src.factor + src.offset - dst.offset) / dst.factor
+
+	// Drop floating point noise, so 100 °C gives 212 °F rather than 211.99999
+	return strconv.ParseFloat(strconv.FormatFloat(converted, 'g', 12, 64), 64)
+}
+
+// ResolveValue converts a string value such as "2.5 bar", "2,5" or "on" to
+// the engineering unit of a register, logical name or alias. Other values
+// are returned unchanged.
+func (d *Device) ResolveValue(name string, value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	registerName := name
+	if target, mapped := d.IOMapping[name]; mapped {
+		registerName = target
+	} else if current, ok := d.Profile.Aliases[name]; ok {
+		return d.ResolveValue(current, value)
+	}
+
+	d.mu.RLock()
+	reg, exists := d.RegisterMap[registerName]
+	d.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("register not found: %s", name)
+	}
+
+	resolved, err := resolveString(reg, s)
+	if err != nil {
+		return nil, fmt.Errorf("register %s: %w", name, err)
+	}
+	return resolved, nil
+}
+
+// resolveString parses a string written to a register: a switch state for
+// bool registers, otherwise a quantity converted to the register unit
+func resolveString(reg *types.RegisterDefinition, s string) (any, error) {
+	if reg.DataType == types.DataTypeBool {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "on", "yes", "1":
+			return true, nil
+		case "false", "off", "no", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a switch state, use true/false or on/off", s)
+	}
+
+	q, err := ParseQuantity(s)
+	if err != nil {
+		return nil, err
+	}
+	if q.Unit == "" || q.Unit == reg.Unit {
+		return q.Value, nil
+	}
+	if reg.Unit == "" {
+		return nil, fmt.Errorf("%q has unit %s but the register has none, write the plain number", s, q.Unit)
+	}
+
+	value, err := ConvertUnit(q.Value, q.Unit, reg.Unit)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", s, err)
+	}
+	return value, nil
+}
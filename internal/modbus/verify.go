@@ -0,0 +1,74 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// VerifyError is returned when a register read back after a write does not
+// hold the written value, e.g. because a coupler silently ignored the write
+type VerifyError struct {
+	Register  string  `json:"register"`
+	Written   any     `json:"written"`
+	ReadBack  any     `json:"read_back"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("write verification failed for %s: wrote %v, read back %v", e.Register, e.Written, e.ReadBack)
+}
+
+// VerifyWrite reads a register back after a write and compares it with the
+// written sample. The register may be given by register name, logical name
+// or alias. Scaled values differing from the written word still match if
+// they are within the tolerance of the written engineering value.
+func (d *Device) VerifyWrite(ctx context.Context, name string, written Sample, tolerance float64) (Sample, error) {
+	registerName := name
+	if target, mapped := d.IOMapping[name]; mapped {
+		registerName = target
+	} else if current, ok := d.aliasRegister(name); ok {
+		registerName = current
+	}
+
+	d.mu.RLock()
+	reg, exists := d.RegisterMap[registerName]
+	d.mu.RUnlock()
+	if !exists {
+		return Sample{}, fmt.Errorf("register not found: %s", name)
+	}
+
+	readBack, err := d.ReadSample(ctx, registerName)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to verify write: %w", err)
+	}
+	if len(written.Raw) == 0 || len(readBack.Raw) == 0 {
+		return readBack, nil
+	}
+
+	// Bits sharing a word only compare their own bit
+	if reg.Bit != nil {
+		if bitValue(written.Raw[0], *reg.Bit) == bitValue(readBack.Raw[0], *reg.Bit) {
+			return readBack, nil
+		}
+		return readBack, &VerifyError{Register: name, Written: written.Value, ReadBack: readBack.Value}
+	}
+
+	// 32 and 64 bit values compare all their registers
+	if d.getRegisterQuantity(reg.DataType) > 1 {
+		if slices.Equal(written.Raw, readBack.Raw) {
+			return readBack, nil
+		}
+	} else if written.Raw[0] == readBack.Raw[0] {
+		return readBack, nil
+	}
+	if tolerance > 0 {
+		want, ok1 := numericValue(written.Value)
+		got, ok2 := numericValue(readBack.Value)
+		if ok1 && ok2 && math.Abs(want-got) <= tolerance {
+			return readBack, nil
+		}
+	}
+	return readBack, &VerifyError{Register: name, Written: written.Value, ReadBack: readBack.Value, Tolerance: tolerance}
+}
@@ -0,0 +1,187 @@
+package modbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/expr"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+type virtualRegister struct {
+	def  types.VirtualRegister
+	expr *expr.Expression
+}
+
+// buildVirtualRegisters parses the virtual register expressions, checks their
+// references and returns the registers in evaluation order (dependencies first)
+func buildVirtualRegisters(defs []types.VirtualRegister, registers map[string]*types.RegisterDefinition, ioMapping map[string]string) (map[string]*virtualRegister, []string, error) {
+	virtuals := make(map[string]*virtualRegister, len(defs))
+	for _, def := range defs {
+		if def.Name == "" {
+			return nil, nil, fmt.Errorf("virtual register without name")
+		}
+		if _, exists := registers[def.Name]; exists {
+			return nil, nil, fmt.Errorf("virtual register %s conflicts with a device register", def.Name)
+		}
+		if _, exists := virtuals[def.Name]; exists {
+			return nil, nil, fmt.Errorf("duplicate virtual register %s", def.Name)
+		}
+		switch def.DataType {
+		case "":
+			def.DataType = types.DataTypeFloat64
+		case types.DataTypeBool, types.DataTypeFloat64:
+		default:
+			return nil, nil, fmt.Errorf("virtual register %s: data type must be bool or float64", def.Name)
+		}
+
+		parsed, err := expr.Parse(def.Expression)
+		if err != nil {
+			return nil, nil, fmt.Errorf("virtual register %s: %w", def.Name, err)
+		}
+		virtuals[def.Name] = &virtualRegister{def: def, expr: parsed}
+	}
+
+	// resolve maps a reference to a register or virtual register name
+	resolve := func(ref string) (string, bool) {
+		if mapped, ok := ioMapping[ref]; ok {
+			ref = mapped
+		}
+		_, isRegister := registers[ref]
+		_, isVirtual := virtuals[ref]
+		return ref, isRegister || isVirtual
+	}
+
+	// Depth-first ordering with cycle detection
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(virtuals))
+	order := make([]string, 0, len(virtuals))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("virtual register %s references itself", name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+
+		for _, ref := range virtuals[name].expr.Refs() {
+			target, ok := resolve(ref)
+			if !ok {
+				return fmt.Errorf("virtual register %s: unknown register %s", name, ref)
+			}
+			if _, isVirtual := virtuals[target]; isVirtual {
+				if err := visit(target); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+
+	for _, def := range defs {
+		if err := visit(def.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return virtuals, order, nil
+}
+
+// IsVirtual reports whether a register is computed
+func (d *Device) IsVirtual(registerName string) bool {
+	_, ok := d.virtuals[registerName]
+	return ok
+}
+
+// readVirtual evaluates a virtual register from fresh reads of its inputs
+func (d *Device) readVirtual(ctx context.Context, v *virtualRegister) (Sample, error) {
+	value, err := d.evalVirtual(v, func(name string) (interface{}, error) {
+		return d.ReadRegister(ctx, name)
+	})
+	if err != nil {
+		return Sample{}, err
+	}
+
+	sample := newSample(value, nil, v.def.Unit)
+	d.cache(v.def.Name, sample)
+	return sample, nil
+}
+
+// RefreshVirtualRegisters recomputes all virtual registers from the cached
+// values of the last poll; registers whose inputs have no value yet are
+// skipped. A virtual register has the worst quality of its inputs.
+func (d *Device) RefreshVirtualRegisters() {
+	for _, name := range d.virtualOrder {
+		v := d.virtuals[name]
+		quality := QualityGood
+		value, err := d.evalVirtual(v, func(name string) (interface{}, error) {
+			sample, ok := d.LastSample(name)
+			if !ok || sample.Value == nil {
+				return nil, fmt.Errorf("no value for %s", name)
+			}
+			quality = worseQuality(quality, sample.Quality)
+			return sample.Value, nil
+		})
+		if err != nil {
+			continue
+		}
+
+		sample := newSample(value, nil, v.def.Unit)
+		sample.Quality = quality
+		d.cache(name, sample)
+	}
+}
+
+func (d *Device) evalVirtual(v *virtualRegister, read func(name string) (interface{}, error)) (interface{}, error) {
+	result, err := v.expr.Eval(func(ref string) (float64, error) {
+		if mapped, ok := d.IOMapping[ref]; ok {
+			ref = mapped
+		}
+		raw, err := read(ref)
+		if err != nil {
+			return 0, err
+		}
+		f, ok := numericValue(raw)
+		if !ok {
+			return 0, fmt.Errorf("register %s is not numeric", ref)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate virtual register %s: %w", v.def.Name, err)
+	}
+
+	if v.def.DataType == types.DataTypeBool {
+		return result != 0, nil
+	}
+	return result, nil
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,925 @@
+// Package onboarding guides the commissioning of a new device. Its
+// composition is previewed, the device is connected and identified without
+// being loaded, its I/O is checked together with the technician - outputs
+// blink, inputs are watched for a change - and only after confirmation the
+// device is persisted and loaded.
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSessionNotFound  = errors.New("onboarding not found")
+	ErrNotOwner         = errors.New("onboarding belongs to another user")
+	ErrTooManySessions  = errors.New("too many onboardings")
+	ErrInstanceBusy     = errors.New("device is already being onboarded")
+	ErrWrongStage       = errors.New("not possible in the current stage")
+	ErrCheckNotFound    = errors.New("check not found")
+	ErrCheckNotPrompted = errors.New("check is not waiting for an answer")
+	ErrInvalidAnswer    = errors.New("invalid answer, use passed, failed or skipped")
+	ErrChecksFailed     = errors.New("checks failed")
+)
+
+// Audit actions of onboardings
+const (
+	AuditCompleted = "onboarding.completed"
+	AuditCancelled = "onboarding.cancelled"
+)
+
+// Stage is the progress of an onboarding
+type Stage string
+
+const (
+	StagePreviewed Stage = "previewed" // Composition checked, nothing connected
+	StageConnected Stage = "connected" // Device connected and identified
+	StageVerifying Stage = "verifying" // I/O checks running
+	StageVerified  Stage = "verified"  // All checks answered, ready to confirm
+	StageCompleted Stage = "completed" // Device persisted and loaded
+	StageCancelled Stage = "cancelled"
+	StageExpired   Stage = "expired" // No activity for onboarding.session_timeout
+)
+
+// Kinds of I/O checks
+const (
+	CheckBlinkOutput = "blink_output" // The output toggles until the technician answers
+	CheckReadInput   = "read_input"   // Passes when the input changes, e.g. a sensor is triggered
+)
+
+// Statuses of I/O checks; passed, failed and skipped are answers
+const (
+	CheckPending  = "pending"
+	CheckPrompted = "prompted"
+	CheckPassed   = "passed"
+	CheckFailed   = "failed"
+	CheckSkipped  = "skipped"
+)
+
+// Answerer of checks passed by the device itself
+const answeredByDevice = "device"
+
+// inputPollInterval is how often an input under check is read
+const inputPollInterval = 200 * time.Millisecond
+
+// restoreTimeout bounds writing an output back after its check
+const restoreTimeout = 5 * time.Second
+
+// storeTimeout bounds storing the identity of a completed onboarding
+const storeTimeout = 5 * time.Second
+
+// IO is a logical input or output of the composed device
+type IO struct {
+	Logical  string             `json:"logical"`
+	Register string             `json:"register"`
+	Type     types.RegisterType `json:"type"`
+	DataType types.DataType     `json:"data_type"`
+	Address  uint16             `json:"address"`
+	Bit      *int               `json:"bit,omitempty"`
+	Writable bool               `json:"writable"`
+}
+
+// Preview is the composed device before anything is connected
+type Preview struct {
+	Model          string   `json:"model"`
+	Endpoint       string   `json:"endpoint"` // host:port, or the serial port of Modbus RTU
+	Registers      int      `json:"registers"`
+	Identification int      `json:"identification"` // Identification registers read on connect
+	IO             []IO     `json:"io"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// Connection is the result of connecting the device
+type Connection struct {
+	ConnectedAt time.Time             `json:"connected_at,omitempty"`
+	Identity    *types.DeviceIdentity `json:"identity,omitempty"`
+	Ping        *modbus.PingResult    `json:"ping,omitempty"`
+	Error       string                `json:"error,omitempty"` // Set if the last attempt failed
+}
+
+// Check is an I/O check of the verification
+type Check struct {
+	Index      int        `json:"index"`
+	Kind       string     `json:"kind"`
+	Logical    string     `json:"logical"`
+	Register   string     `json:"register"`
+	Prompt     string     `json:"prompt"`
+	Status     string     `json:"status"`
+	Initial    any        `json:"initial,omitempty"` // read_input: value when prompted
+	Value      any        `json:"value,omitempty"`   // read_input: changed value
+	Toggles    int        `json:"toggles,omitempty"` // blink_output
+	Note       string     `json:"note,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	AnsweredBy string     `json:"answered_by,omitempty"` // Username, or "device" for inputs seen changing
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+// Session is an onboarding
+type Session struct {
+	ID         uuid.UUID   `json:"id"`
+	Actor      string      `json:"actor"`
+	InstanceID string      `json:"instance_id"`
+	Stage      Stage       `json:"stage"`
+	Preview    Preview     `json:"preview"`
+	Connection *Connection `json:"connection,omitempty"`
+	Checks     []Check     `json:"checks"`
+	StartedAt  time.Time   `json:"started_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	EndedAt    *time.Time  `json:"ended_at,omitempty"`
+}
+
+type session struct {
+	Session
+	comp types.DeviceComposition
+
+	mu         sync.Mutex // Guards the session; held while connecting or installing
+	ending     bool
+	device     *modbus.Device // Connected, not loaded device
+	stopChecks context.CancelFunc
+	checksDone chan struct{}
+	answered   chan struct{} // Signals an answer to the running check
+}
+
+// PromptHandler is called when a check asks the technician for an answer
+type PromptHandler func(session Session, check Check)
+
+// UpdateHandler is called after a session changed its stage or a check
+// finished
+type UpdateHandler func(session Session)
+
+// Manager holds the onboardings
+type Manager struct {
+	cfg     config.OnboardingConfig
+	storage *storage.PostgresClient
+	devices *devices.Manager
+	timeout time.Duration // Modbus timeout of connected devices
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*session
+	onPrompt PromptHandler
+	onUpdate UpdateHandler
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewManager(cfg config.OnboardingConfig, store *storage.PostgresClient, deviceManager *devices.Manager, timeout time.Duration, logger *zap.Logger) *Manager {
+	return &Manager{
+		cfg:      cfg,
+		storage:  store,
+		devices:  deviceManager,
+		timeout:  timeout,
+		logger:   logger,
+		sessions: make(map[uuid.UUID]*session),
+	}
+}
+
+// SetHandlers installs the handlers notified of prompts and updates
+func (m *Manager) SetHandlers(onPrompt PromptHandler, onUpdate UpdateHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPrompt = onPrompt
+	m.onUpdate = onUpdate
+}
+
+// Start begins cancelling inactive onboardings
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+
+	m.running = true
+	m.stopChan = make(chan struct{})
+	m.wg.Add(1)
+	go m.loop(m.stopChan)
+}
+
+// Stop cancels all onboardings, disconnecting their devices
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	ids := make([]uuid.UUID, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	for _, id := range ids {
+		m.end(id, StageCancelled)
+	}
+}
+
+// Open previews a composition and starts its onboarding
+func (m *Manager) Open(actor string, comp types.DeviceComposition) (Session, error) {
+	if _, loaded := m.devices.GetDeviceByName(comp.InstanceID); loaded {
+		return Session{}, fmt.Errorf("%w: %s", devices.ErrDuplicateDevice, comp.InstanceID)
+	}
+	profile, err := m.devices.PreviewComposition(comp)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to compose device: %w", err)
+	}
+
+	m.mu.Lock()
+	if len(m.sessions) >= m.cfg.MaxSessions {
+		m.mu.Unlock()
+		return Session{}, ErrTooManySessions
+	}
+	for _, other := range m.sessions {
+		if other.InstanceID == comp.InstanceID {
+			m.mu.Unlock()
+			return Session{}, fmt.Errorf("%w: %s", ErrInstanceBusy, comp.InstanceID)
+		}
+	}
+
+	now := time.Now()
+	s := &session{
+		Session: Session{
+			ID:         uuid.New(),
+			Actor:      actor,
+			InstanceID: comp.InstanceID,
+			Stage:      StagePreviewed,
+			Preview:    preview(comp, profile),
+			Checks:     []Check{},
+			StartedAt:  now,
+			ExpiresAt:  now.Add(m.cfg.SessionTimeout),
+		},
+		comp:     comp,
+		answered: make(chan struct{}, 1),
+	}
+	m.sessions[s.ID] = s
+	opened := s.snapshot()
+	m.mu.Unlock()
+
+	m.logger.Info("Device onboarding started",
+		zap.String("onboarding_id", opened.ID.String()),
+		zap.String("instance_id", comp.InstanceID),
+		zap.String("actor", actor))
+	return opened, nil
+}
+
+// Connect connects and identifies the device. A failed attempt is recorded
+// in the connection and may be repeated, e.g. after wiring the coupler.
+func (m *Manager) Connect(id uuid.UUID, actor string) (Session, error) {
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	if err := s.usable(StagePreviewed, StageConnected, StageVerified); err != nil {
+		s.mu.Unlock()
+		return Session{}, err
+	}
+	m.touch(s)
+	s.disconnect(m.logger)
+
+	device, err := m.devices.ProbeComposition(s.comp, m.timeout)
+	if err != nil {
+		s.Stage = StagePreviewed
+		s.Connection = &Connection{Error: err.Error()}
+		s.Checks = []Check{}
+		failed := s.snapshot()
+		s.mu.Unlock()
+		m.updated(failed)
+		return failed, err
+	}
+
+	s.device = device
+	s.Stage = StageConnected
+	s.Connection = &Connection{ConnectedAt: time.Now(), Identity: device.Identity()}
+	if regType, address, ok := pingRegister(device.Profile); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		s.Connection.Ping = device.Ping(ctx, regType, address)
+		cancel()
+	}
+	s.Checks = []Check{}
+	connected := s.snapshot()
+	s.mu.Unlock()
+
+	m.logger.Info("Onboarded device connected",
+		zap.String("onboarding_id", id.String()),
+		zap.String("instance_id", connected.InstanceID))
+	m.updated(connected)
+	return connected, nil
+}
+
+// Verify starts the I/O checks of the connected device, one at a time:
+// writable bool outputs blink until the technician answers, inputs pass
+// when their value changes. Running it again repeats all checks.
+func (m *Manager) Verify(id uuid.UUID, actor string) (Session, error) {
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	if err := s.usable(StageConnected, StageVerified); err != nil {
+		s.mu.Unlock()
+		return Session{}, err
+	}
+	m.touch(s)
+
+	s.Checks = checks(s.Preview.IO)
+	if len(s.Checks) == 0 {
+		s.Stage = StageVerified
+		verified := s.snapshot()
+		s.mu.Unlock()
+		m.updated(verified)
+		return verified, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Stage = StageVerifying
+	s.stopChecks = cancel
+	s.checksDone = make(chan struct{})
+	go m.runChecks(ctx, s, s.device, s.checksDone)
+	verifying := s.snapshot()
+	s.mu.Unlock()
+
+	m.updated(verifying)
+	return verifying, nil
+}
+
+// Answer records the technician's answer to the prompted check
+func (m *Manager) Answer(id uuid.UUID, actor string, index int, status, note string) (Session, error) {
+	if status != CheckPassed && status != CheckFailed && status != CheckSkipped {
+		return Session{}, ErrInvalidAnswer
+	}
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.usable(StageVerifying); err != nil {
+		return Session{}, err
+	}
+	if index < 0 || index >= len(s.Checks) {
+		return Session{}, fmt.Errorf("%w: %d", ErrCheckNotFound, index)
+	}
+	check := &s.Checks[index]
+	if check.Status != CheckPrompted {
+		return Session{}, fmt.Errorf("%w: check %d is %s", ErrCheckNotPrompted, index, check.Status)
+	}
+
+	m.touch(s)
+	answer(check, status, actor)
+	check.Note = note
+	select {
+	case s.answered <- struct{}{}:
+	default:
+	}
+	return s.snapshot(), nil
+}
+
+// Complete persists and loads the verified device with install. Failed
+// checks are accepted only with force. The device connected for the
+// onboarding is disconnected first, so install connects it anew; if install
+// fails the onboarding stays verified and may be completed again.
+func (m *Manager) Complete(id uuid.UUID, actor string, force bool, install func(comp types.DeviceComposition) error) (Session, error) {
+	s, err := m.owned(id, actor)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	if err := s.usable(StageVerified); err != nil {
+		s.mu.Unlock()
+		return Session{}, err
+	}
+	m.touch(s)
+	var failed []string
+	for _, check := range s.Checks {
+		if check.Status == CheckFailed {
+			failed = append(failed, check.Logical)
+		}
+	}
+	if len(failed) > 0 && !force {
+		s.mu.Unlock()
+		return Session{}, fmt.Errorf("%w: %v, confirm with force to persist anyway", ErrChecksFailed, failed)
+	}
+
+	s.disconnect(m.logger)
+	if err := install(s.comp); err != nil {
+		s.mu.Unlock()
+		return Session{}, err
+	}
+	m.storeIdentity(s)
+	s.Stage = StageCompleted
+	s.mu.Unlock()
+
+	completed, ok := m.end(id, StageCompleted)
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return completed, nil
+}
+
+// Cancel ends an onboarding without persisting the device
+func (m *Manager) Cancel(id uuid.UUID, actor string) (Session, error) {
+	if _, err := m.owned(id, actor); err != nil {
+		return Session{}, err
+	}
+	cancelled, ok := m.end(id, StageCancelled)
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return cancelled, nil
+}
+
+// Get returns an onboarding in progress
+func (m *Manager) Get(id uuid.UUID) (Session, bool) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	m.mu.Unlock()
+	if !ok {
+		return Session{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot(), true
+}
+
+// List returns the onboardings in progress, oldest first
+func (m *Manager) List() []Session {
+	m.mu.Lock()
+	open := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		open = append(open, s)
+	}
+	m.mu.Unlock()
+
+	list := make([]Session, 0, len(open))
+	for _, s := range open {
+		s.mu.Lock()
+		list = append(list, s.snapshot())
+		s.mu.Unlock()
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// owned returns an onboarding of actor
+func (m *Manager) owned(id uuid.UUID, actor string) (*session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if s.Actor != actor {
+		return nil, ErrNotOwner
+	}
+	return s, nil
+}
+
+// touch extends the session timeout; the caller holds the session lock
+func (m *Manager) touch(s *session) {
+	s.ExpiresAt = time.Now().Add(m.cfg.SessionTimeout)
+}
+
+func (m *Manager) loop(stop <-chan struct{}) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(max(m.cfg.SessionTimeout/10, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			m.expire(now)
+		}
+	}
+}
+
+// expire ends the onboardings without activity
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	var expired []uuid.UUID
+	for id, s := range m.sessions {
+		// Connecting or installing holds the session lock and is activity
+		if !s.mu.TryLock() {
+			continue
+		}
+		if now.After(s.ExpiresAt) {
+			expired = append(expired, id)
+		}
+		s.mu.Unlock()
+	}
+	m.mu.Unlock()
+
+	for _, id := range expired {
+		m.end(id, StageExpired)
+	}
+}
+
+// end removes an onboarding, stops its checks and disconnects its device;
+// ok is false if it already ended
+func (m *Manager) end(id uuid.UUID, stage Stage) (Session, bool) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	if !ok {
+		return Session{}, false
+	}
+
+	s.mu.Lock()
+	s.ending = true
+	stopChecks, checksDone := s.stopChecks, s.checksDone
+	s.mu.Unlock()
+
+	// The checks restore their output before they return
+	if stopChecks != nil {
+		stopChecks()
+		<-checksDone
+	}
+
+	s.mu.Lock()
+	s.disconnect(m.logger)
+	now := time.Now()
+	s.EndedAt = &now
+	s.Stage = stage
+	ended := s.snapshot()
+	s.mu.Unlock()
+
+	m.logger.Info("Device onboarding ended",
+		zap.String("onboarding_id", id.String()),
+		zap.String("instance_id", ended.InstanceID),
+		zap.String("stage", string(stage)))
+
+	action := AuditCancelled
+	if stage == StageCompleted {
+		action = AuditCompleted
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	m.audit(ctx, action, ended.Actor, map[string]any{
+		"onboarding_id": id,
+		"instance_id":   ended.InstanceID,
+		"stage":         stage,
+		"checks":        ended.Checks,
+	})
+	cancel()
+
+	m.updated(ended)
+	return ended, true
+}
+
+// runChecks prompts the checks in order and marks the session verified once
+// all are answered
+func (m *Manager) runChecks(ctx context.Context, s *session, device *modbus.Device, done chan struct{}) {
+	defer close(done)
+
+	// Answers meant for an earlier run are dropped
+	select {
+	case <-s.answered:
+	default:
+	}
+
+	for i := 0; ; i++ {
+		s.mu.Lock()
+		if i == len(s.Checks) {
+			s.Stage = StageVerified
+			s.stopChecks, s.checksDone = nil, nil
+			verified := s.snapshot()
+			s.mu.Unlock()
+			m.updated(verified)
+			return
+		}
+		s.Checks[i].Status = CheckPrompted
+		check := s.Checks[i]
+		prompted := s.snapshot()
+		s.mu.Unlock()
+
+		m.prompted(prompted, check)
+		switch check.Kind {
+		case CheckBlinkOutput:
+			m.blink(ctx, s, device, i)
+		case CheckReadInput:
+			m.watch(ctx, s, device, i)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mu.Lock()
+		finished := s.snapshot()
+		s.mu.Unlock()
+		m.updated(finished)
+	}
+}
+
+// blink toggles an output until its check is answered, then writes it back
+// to its safe state, or off without one
+func (m *Manager) blink(ctx context.Context, s *session, device *modbus.Device, index int) {
+	logical := s.check(index).Logical
+	defer func() {
+		restoreCtx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+		configured, err := device.WriteSafeState(restoreCtx, logical)
+		if err == nil && !configured {
+			err = device.WriteLogical(restoreCtx, logical, false)
+		}
+		if err != nil {
+			m.logger.Error("Failed to restore output after onboarding check",
+				zap.String("instance_id", device.Name),
+				zap.String("output", logical),
+				zap.Error(err))
+		}
+	}()
+
+	on := false
+	m.await(ctx, s, index, m.cfg.BlinkInterval, func(check *Check) {
+		on = !on
+		if err := device.WriteLogical(ctx, logical, on); err != nil {
+			check.Error = err.Error()
+			answer(check, CheckFailed, answeredByDevice)
+			return
+		}
+		check.Toggles++
+	})
+}
+
+// watch reads an input until it changes or its check is answered
+func (m *Manager) watch(ctx context.Context, s *session, device *modbus.Device, index int) {
+	logical := s.check(index).Logical
+	read := false
+	m.await(ctx, s, index, inputPollInterval, func(check *Check) {
+		sample, err := device.ReadLogicalSample(ctx, logical)
+		if err != nil {
+			check.Error = err.Error()
+			return
+		}
+		check.Error = ""
+		switch {
+		case !read:
+			check.Initial = sample.Value
+			read = true
+		case !reflect.DeepEqual(sample.Value, check.Initial):
+			check.Value = sample.Value
+			answer(check, CheckPassed, answeredByDevice)
+		}
+	})
+}
+
+// await calls tick with the check locked, first right away and then every
+// interval, until the check is answered, the check timeout elapsed or ctx
+// is cancelled
+func (m *Manager) await(ctx context.Context, s *session, index int, interval time.Duration, tick func(check *Check)) {
+	deadline := time.NewTimer(m.cfg.CheckTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := func(fn func(check *Check)) bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		check := &s.Checks[index]
+		if check.Status == CheckPrompted && fn != nil && ctx.Err() == nil {
+			fn(check)
+		}
+		return check.Status == CheckPrompted
+	}
+
+	for pending(tick) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			pending(func(check *Check) {
+				check.Error = "no answer within " + m.cfg.CheckTimeout.String()
+				answer(check, CheckFailed, answeredByDevice)
+			})
+			return
+		case <-s.answered:
+			if !pending(nil) {
+				return
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) prompted(session Session, check Check) {
+	m.mu.Lock()
+	onPrompt := m.onPrompt
+	m.mu.Unlock()
+	if onPrompt != nil {
+		onPrompt(session, check)
+	}
+}
+
+func (m *Manager) updated(session Session) {
+	m.mu.Lock()
+	onUpdate := m.onUpdate
+	m.mu.Unlock()
+	if onUpdate != nil {
+		onUpdate(session)
+	}
+}
+
+// storeIdentity keeps the identification read while connecting the device
+// with the device record, so later connects detect swapped hardware. The
+// caller holds the session lock.
+func (m *Manager) storeIdentity(s *session) {
+	if s.Connection == nil || s.Connection.Identity == nil || len(s.Connection.Identity.Fields) == 0 {
+		return
+	}
+
+	identity := &types.DeviceIdentity{Fields: s.Connection.Identity.Fields, ReadAt: s.Connection.Identity.ReadAt}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.storage.SetDeviceReferenceIdentity(ctx, s.comp.InstanceID, identity); err != nil {
+		m.logger.Error("Failed to store onboarding identity",
+			zap.String("onboarding_id", s.ID.String()),
+			zap.String("instance_id", s.comp.InstanceID),
+			zap.Error(err))
+	}
+}
+
+func (m *Manager) audit(ctx context.Context, action, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := m.storage.RecordAudit(ctx, entry); err != nil {
+		m.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
+
+// usable fails unless the session is in one of the stages; the caller holds
+// the session lock
+func (s *session) usable(stages ...Stage) error {
+	if s.ending {
+		return ErrSessionNotFound
+	}
+	if !slices.Contains(stages, s.Stage) {
+		return fmt.Errorf("%w: onboarding is %s", ErrWrongStage, s.Stage)
+	}
+	return nil
+}
+
+// disconnect closes the connected device; the caller holds the session lock
+func (s *session) disconnect(logger *zap.Logger) {
+	if s.device == nil {
+		return
+	}
+	if err := s.device.Disconnect(); err != nil {
+		logger.Warn("Failed to disconnect onboarded device",
+			zap.String("instance_id", s.InstanceID),
+			zap.Error(err))
+	}
+	s.device = nil
+}
+
+func (s *session) check(index int) Check {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Checks[index]
+}
+
+func (s *session) snapshot() Session {
+	copied := s.Session
+	copied.Checks = slices.Clone(s.Checks)
+	if s.Connection != nil {
+		connection := *s.Connection
+		copied.Connection = &connection
+	}
+	return copied
+}
+
+// answer finishes a check
+func answer(check *Check, status, by string) {
+	now := time.Now()
+	check.Status = status
+	check.AnsweredBy = by
+	check.AnsweredAt = &now
+}
+
+// preview lists the logical I/O of a composed profile
+func preview(comp types.DeviceComposition, profile *types.DeviceProfileDefinition) Preview {
+	p := Preview{
+		Model:          profile.DeviceProfile.Model,
+		Endpoint:       endpoint(comp.Composition.Coupler),
+		Registers:      len(profile.Registers),
+		Identification: len(profile.Identification),
+		IO:             []IO{},
+	}
+
+	registers := make(map[string]*types.RegisterDefinition, len(profile.Registers))
+	for i := range profile.Registers {
+		registers[profile.Registers[i].Name] = &profile.Registers[i]
+	}
+	virtuals := make(map[string]bool, len(profile.VirtualRegisters))
+	for _, v := range profile.VirtualRegisters {
+		virtuals[v.Name] = true
+	}
+
+	logicals := make([]string, 0, len(comp.IOMapping))
+	for logical := range comp.IOMapping {
+		logicals = append(logicals, logical)
+	}
+	sort.Strings(logicals)
+
+	for _, logical := range logicals {
+		target := comp.IOMapping[logical]
+		reg, ok := registers[target]
+		if !ok {
+			if !virtuals[target] {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("%s is mapped to unknown register %s", logical, target))
+			}
+			continue
+		}
+		io := IO{
+			Logical:  logical,
+			Register: target,
+			Type:     reg.Type,
+			DataType: reg.DataType,
+			Address:  reg.Address,
+			Bit:      reg.Bit,
+			Writable: reg.Access == types.AccessTypeReadWrite,
+		}
+		if io.Writable && !switched(io) {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("%s is an analog output and is not checked", logical))
+		}
+		p.IO = append(p.IO, io)
+	}
+	if p.Identification == 0 {
+		p.Warnings = append(p.Warnings, "The modules have no identification registers, the hardware can't be identified")
+	}
+	return p
+}
+
+// checks creates the checks of the I/O: bool outputs blink, inputs are read
+func checks(ios []IO) []Check {
+	list := []Check{}
+	for _, io := range ios {
+		check := Check{Logical: io.Logical, Register: io.Register, Status: CheckPending}
+		switch {
+		case io.Writable && switched(io):
+			check.Kind = CheckBlinkOutput
+			check.Prompt = fmt.Sprintf("Is output %s blinking?", io.Logical)
+		case !io.Writable:
+			check.Kind = CheckReadInput
+			check.Prompt = fmt.Sprintf("Actuate input %s", io.Logical)
+		default:
+			continue
+		}
+		check.Index = len(list)
+		list = append(list, check)
+	}
+	return list
+}
+
+// switched reports whether an I/O is a switch state rather than a value
+func switched(io IO) bool {
+	return io.DataType == types.DataTypeBool || io.Type == types.RegisterTypeCoil || io.Type == types.RegisterTypeDiscreteInput
+}
+
+// pingRegister picks the register pinged after connecting, like
+// POST /devices/:id/ping without body
+func pingRegister(profile *types.DeviceProfileDefinition) (types.RegisterType, uint16, bool) {
+	for _, reg := range profile.Registers {
+		if reg.Type == types.RegisterTypeHoldingRegister || reg.Type == types.RegisterTypeInputRegister {
+			return reg.Type, reg.Address, true
+		}
+	}
+	return "", 0, false
+}
+
+func endpoint(coupler types.CouplerConfig) string {
+	if coupler.Protocol == types.ProtocolModbusRTU && coupler.Serial != nil {
+		return coupler.Serial.Port
+	}
+	return net.JoinHostPort(coupler.IPAddress, strconv.Itoa(coupler.Port))
+}
@@ -0,0 +1,441 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Audit actions
+const (
+	AuditDeliveryRequeued = "outbox.requeued"
+)
+
+// Event sources
+const (
+	SourceWorkflow = storage.OutboxSourceWorkflow
+	SourceMachine  = storage.OutboxSourceMachine
+	SourceDevice   = storage.OutboxSourceDevice
+	SourceSystem   = storage.OutboxSourceSystem
+)
+
+// purgeInterval is how often delivered events past the retention are deleted
+const purgeInterval = time.Hour
+
+var ErrUnknownSink = errors.New("unknown outbox sink")
+
+// Sink delivers outbox events to an integration. Deliver must be safe to
+// repeat: events are delivered at least once.
+type Sink interface {
+	Deliver(ctx context.Context, event *storage.OutboxEvent) error
+}
+
+// SinkStatus describes a configured sink and its backlog
+type SinkStatus struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Events  []string               `json:"events"`
+	Backlog *storage.OutboxBacklog `json:"backlog"`
+}
+
+type route struct {
+	cfg  config.OutboxSinkConfig
+	sink Sink
+}
+
+// matches reports whether the sink subscribed to an event type
+func (r *route) matches(eventType string) bool {
+	if len(r.cfg.Events) == 0 {
+		return true
+	}
+	for _, pattern := range r.cfg.Events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher delivers the outbox to the configured sinks. Each sink follows
+// the outbox with its own cursor; failed deliveries are retried with backoff
+// and dead-lettered after max_attempts, so one failing event doesn't hold
+// back the others. Retried events may arrive out of order.
+type Dispatcher struct {
+	cfg     config.OutboxConfig
+	storage *storage.PostgresClient
+	logger  *zap.Logger
+	routes  []*route
+
+	mu       sync.Mutex
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewDispatcher(cfg config.OutboxConfig, store *storage.PostgresClient, secretStore *secrets.Manager, logger *zap.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		cfg:     cfg,
+		storage: store,
+		logger:  logger,
+	}
+
+	names := make(map[string]bool, len(cfg.Sinks))
+	for _, sinkCfg := range cfg.Sinks {
+		if sinkCfg.Name == "" {
+			return nil, fmt.Errorf("outbox sink without name")
+		}
+		if names[sinkCfg.Name] {
+			return nil, fmt.Errorf("duplicate outbox sink %s", sinkCfg.Name)
+		}
+		names[sinkCfg.Name] = true
+		for _, pattern := range sinkCfg.Events {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("outbox sink %s: invalid event pattern %q", sinkCfg.Name, pattern)
+			}
+		}
+
+		var sink Sink
+		switch sinkCfg.Type {
+		case "webhook":
+			webhook, err := newWebhookSink(sinkCfg, secretStore)
+			if err != nil {
+				return nil, fmt.Errorf("outbox sink %s: %w", sinkCfg.Name, err)
+			}
+			sink = webhook
+		default:
+			return nil, fmt.Errorf("outbox sink %s: unsupported type %q", sinkCfg.Name, sinkCfg.Type)
+		}
+		d.routes = append(d.routes, &route{cfg: sinkCfg, sink: sink})
+	}
+
+	return d, nil
+}
+
+// Enabled reports whether events are collected and delivered
+func (d *Dispatcher) Enabled() bool {
+	return d.cfg.Enabled && len(d.routes) > 0
+}
+
+// Start delivers the outbox to every sink, continuing after the last event
+// each sink attempted before the restart
+func (d *Dispatcher) Start(ctx context.Context) error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	cursors := make([]int64, len(d.routes))
+	for i, r := range d.routes {
+		cursor, err := d.storage.GetOutboxCursor(ctx, r.cfg.Name)
+		if err != nil {
+			return err
+		}
+		cursors[i] = cursor
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		return nil
+	}
+	d.running = true
+	d.stopChan = make(chan struct{})
+
+	for i, r := range d.routes {
+		d.wg.Add(1)
+		go d.run(r, cursors[i], d.stopChan)
+	}
+	d.wg.Add(1)
+	go d.purgeLoop(d.stopChan)
+
+	d.logger.Info("Event outbox started",
+		zap.Int("sinks", len(d.routes)),
+		zap.Duration("poll_interval", d.cfg.PollInterval))
+	return nil
+}
+
+// Stop ends delivery; undelivered events stay in the outbox
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = false
+	close(d.stopChan)
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// Publish adds an event to the outbox. Execution events are added by the
+// storage in the transaction that records them.
+func (d *Dispatcher) Publish(ctx context.Context, source, eventType string, payload map[string]any) {
+	if !d.Enabled() {
+		return
+	}
+	if err := d.storage.AppendOutbox(ctx, source, eventType, payload); err != nil {
+		d.logger.Error("Failed to add event to outbox",
+			zap.String("event_type", eventType),
+			zap.Error(err))
+	}
+}
+
+// Sinks returns the configured sinks with their backlog
+func (d *Dispatcher) Sinks(ctx context.Context) ([]SinkStatus, error) {
+	list := make([]SinkStatus, 0, len(d.routes))
+	for _, r := range d.routes {
+		backlog, err := d.storage.GetOutboxBacklog(ctx, r.cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		events := r.cfg.Events
+		if events == nil {
+			events = []string{}
+		}
+		list = append(list, SinkStatus{
+			Name:    r.cfg.Name,
+			Type:    r.cfg.Type,
+			Events:  events,
+			Backlog: backlog,
+		})
+	}
+	return list, nil
+}
+
+// Deliveries returns failed deliveries, newest first. Empty sink or status match all.
+func (d *Dispatcher) Deliveries(ctx context.Context, sink, status string, limit int) ([]storage.OutboxDelivery, error) {
+	return d.storage.ListOutboxDeliveries(ctx, sink, status, limit)
+}
+
+// Requeue retries a failed delivery on the next poll
+func (d *Dispatcher) Requeue(ctx context.Context, id int64, actor string) (*storage.OutboxDelivery, error) {
+	delivery, err := d.storage.RequeueOutboxDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	d.audit(ctx, actor, map[string]any{
+		"delivery_id": delivery.ID,
+		"sink":        delivery.Sink,
+		"event_id":    delivery.Event.ID,
+	})
+	return delivery, nil
+}
+
+// RequeueDead retries all dead-lettered deliveries of a sink
+func (d *Dispatcher) RequeueDead(ctx context.Context, sink, actor string) (int64, error) {
+	if d.route(sink) == nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownSink, sink)
+	}
+	n, err := d.storage.RequeueDeadOutboxDeliveries(ctx, sink)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		d.audit(ctx, actor, map[string]any{"sink": sink, "count": n})
+	}
+	return n, nil
+}
+
+func (d *Dispatcher) route(name string) *route {
+	for _, r := range d.routes {
+		if r.cfg.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) run(r *route, cursor int64, stop chan struct{}) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.retry(r, stop)
+			cursor = d.drain(r, cursor, stop)
+		}
+	}
+}
+
+// drain delivers the events after the cursor and returns the new cursor
+func (d *Dispatcher) drain(r *route, cursor int64, stop chan struct{}) int64 {
+	ctx := context.Background()
+	for {
+		events, err := d.storage.ListOutboxEvents(ctx, cursor, d.cfg.BatchSize)
+		if err != nil {
+			d.logger.Error("Failed to read outbox", zap.String("sink", r.cfg.Name), zap.Error(err))
+			return cursor
+		}
+
+		next := cursor
+		complete := true
+		for i := range events {
+			event := &events[i]
+			if stopped(stop) {
+				complete = false
+				break
+			}
+			if r.matches(event.EventType) {
+				if err := d.deliver(r, event); err != nil {
+					// The failure must be stored before the cursor moves past the event
+					if !d.recordFailure(r, event, 1, err) {
+						complete = false
+						break
+					}
+				}
+			}
+			next = event.ID
+		}
+
+		if next != cursor {
+			if err := d.storage.AdvanceOutboxCursor(ctx, r.cfg.Name, next); err != nil {
+				d.logger.Error("Failed to store outbox cursor", zap.String("sink", r.cfg.Name), zap.Error(err))
+				return cursor
+			}
+			cursor = next
+		}
+		if !complete || len(events) < d.cfg.BatchSize {
+			return cursor
+		}
+	}
+}
+
+// retry delivers the failed deliveries whose next attempt is due
+func (d *Dispatcher) retry(r *route, stop chan struct{}) {
+	ctx := context.Background()
+	due, err := d.storage.ListDueOutboxRetries(ctx, r.cfg.Name, d.cfg.BatchSize)
+	if err != nil {
+		d.logger.Error("Failed to read outbox retries", zap.String("sink", r.cfg.Name), zap.Error(err))
+		return
+	}
+
+	for i := range due {
+		if stopped(stop) {
+			return
+		}
+		delivery := &due[i]
+		if err := d.deliver(r, &delivery.Event); err != nil {
+			d.recordFailure(r, &delivery.Event, delivery.Attempts+1, err)
+			continue
+		}
+		if err := d.storage.DeleteOutboxDelivery(ctx, delivery.ID); err != nil {
+			d.logger.Error("Failed to clear outbox delivery", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(r *route, event *storage.OutboxEvent) error {
+	timeout := r.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return r.sink.Deliver(ctx, event)
+}
+
+// recordFailure schedules the next attempt or dead-letters the delivery and
+// reports whether the failure was stored
+func (d *Dispatcher) recordFailure(r *route, event *storage.OutboxEvent, attempts int, deliverErr error) bool {
+	status := storage.DeliveryRetrying
+	if attempts >= d.cfg.MaxAttempts {
+		status = storage.DeliveryDead
+	}
+
+	err := d.storage.SaveOutboxFailure(context.Background(), r.cfg.Name, event.ID, status, attempts,
+		deliverErr.Error(), time.Now().Add(d.backoff(attempts)))
+	if err != nil {
+		d.logger.Error("Failed to record outbox delivery failure",
+			zap.String("sink", r.cfg.Name),
+			zap.Int64("event_id", event.ID),
+			zap.Error(err))
+		return false
+	}
+
+	if status == storage.DeliveryDead {
+		d.logger.Warn("Outbox delivery dead-lettered",
+			zap.String("sink", r.cfg.Name),
+			zap.Int64("event_id", event.ID),
+			zap.String("event_type", event.EventType),
+			zap.Int("attempts", attempts),
+			zap.Error(deliverErr))
+	} else {
+		d.logger.Debug("Outbox delivery failed",
+			zap.String("sink", r.cfg.Name),
+			zap.Int64("event_id", event.ID),
+			zap.Int("attempts", attempts),
+			zap.Error(deliverErr))
+	}
+	return true
+}
+
+// backoff returns the delay before the next attempt, doubled per attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if d.cfg.MaxBackoff > 0 && delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) purgeLoop(stop chan struct{}) {
+	defer d.wg.Done()
+	if d.cfg.Retention <= 0 {
+		return
+	}
+
+	sinks := make([]string, len(d.routes))
+	for i, r := range d.routes {
+		sinks[i] = r.cfg.Name
+	}
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			n, err := d.storage.PurgeOutbox(context.Background(), time.Now().Add(-d.cfg.Retention), sinks)
+			if err != nil {
+				d.logger.Error("Failed to purge outbox", zap.Error(err))
+			} else if n > 0 {
+				d.logger.Debug("Purged delivered outbox events", zap.Int64("count", n))
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) audit(ctx context.Context, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: AuditDeliveryRequeued, Actor: actor, Details: details}
+	if err := d.storage.RecordAudit(ctx, entry); err != nil {
+		d.logger.Error("Failed to record audit entry",
+			zap.String("action", AuditDeliveryRequeued),
+			zap.Error(err))
+	}
+}
+
+func stopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
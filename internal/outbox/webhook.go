@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// webhookSink posts each event as JSON. Receivers deduplicate by the
+// X-OMC-Event-ID header, since an event may be delivered more than once.
+type webhookSink struct {
+	cfg     config.OutboxSinkConfig
+	secrets *secrets.Manager
+	client  *http.Client
+}
+
+func newWebhookSink(cfg config.OutboxSinkConfig, secretStore *secrets.Manager) (*webhookSink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", cfg.URL)
+	}
+	for header, name := range cfg.SecretHeaders {
+		if err := secrets.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("header %s: %w", header, err)
+		}
+	}
+
+	return &webhookSink{
+		cfg:     cfg,
+		secrets: secretStore,
+		client:  &http.Client{},
+	}, nil
+}
+
+func (w *webhookSink) Deliver(ctx context.Context, event *storage.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-OMC-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-OMC-Event-Type", event.EventType)
+	for header, value := range w.cfg.Headers {
+		req.Header.Set(header, value)
+	}
+	for header, name := range w.cfg.SecretHeaders {
+		value, err := w.secrets.Get(ctx, name)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", header, err)
+		}
+		req.Header.Set(header, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
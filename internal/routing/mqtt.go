@@ -0,0 +1,243 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttDisconnect = 0xE0
+)
+
+// mqttSender publishes routed events with QoS 0 to an MQTT 3.1.1 broker.
+// It connects on the first event and reconnects after a failed publish;
+// events sent while the broker is unreachable are lost.
+type mqttSender struct {
+	cfg      config.RoutingTargetConfig
+	secrets  *secrets.Manager
+	address  string
+	useTLS   bool
+	clientID string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newMQTTSender(cfg config.RoutingTargetConfig, secretStore *secrets.Manager) (*mqttSender, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid mqtt url %q, use tcp://host:1883", cfg.URL)
+	}
+
+	s := &mqttSender{cfg: cfg, secrets: secretStore, clientID: cfg.ClientID}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		s.useTLS = true
+		port = "8883"
+	default:
+		return nil, fmt.Errorf("invalid mqtt url %q, use tcp:// or tls://", cfg.URL)
+	}
+	s.address = u.Host
+	if u.Port() == "" {
+		s.address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if s.clientID == "" {
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		s.clientID = "omc-" + hex.EncodeToString(suffix)
+	}
+	return s, nil
+}
+
+func (s *mqttSender) send(ctx context.Context, routed *Routed) error {
+	payload, err := json.Marshal(routed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	s.conn.SetWriteDeadline(deadline)
+	if _, err := s.conn.Write(publishPacket(expandTopic(s.cfg.Topic, routed), payload, s.cfg.Retain)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("mqtt publish failed: %w", err)
+	}
+	return nil
+}
+
+// connect opens the broker connection and waits for its CONNACK. Callers
+// hold s.mu.
+func (s *mqttSender) connect(ctx context.Context) error {
+	password := ""
+	if s.cfg.PasswordSecret != "" {
+		value, err := s.secrets.Get(ctx, s.cfg.PasswordSecret)
+		if err != nil {
+			return fmt.Errorf("password_secret: %w", err)
+		}
+		password = value
+	}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if s.useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.address)
+	}
+	if err != nil {
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(connectPacket(s.clientID, s.cfg.Username, password)); err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt connect failed: %w", err)
+	}
+	if ack[0] != mqttConnAck || ack[1] != 2 {
+		conn.Close()
+		return errors.New("mqtt connect failed: invalid CONNACK")
+	}
+	if ack[3] != 0 {
+		conn.Close()
+		return fmt.Errorf("mqtt connect refused: %s", connAckReason(ack[3]))
+	}
+
+	conn.SetDeadline(time.Time{})
+	s.conn = conn
+	return nil
+}
+
+func (s *mqttSender) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		s.conn.Write([]byte{mqttDisconnect, 0})
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// connectPacket builds a CONNECT with a clean session and keep alive
+// disabled, so an idle connection is not dropped by the broker
+func connectPacket(clientID, username, password string) []byte {
+	var body bytes.Buffer
+	writeString(&body, "MQTT")
+	body.WriteByte(4) // Protocol level 3.1.1
+
+	flags := byte(0x02) // Clean session
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	body.WriteByte(flags)
+	body.Write([]byte{0, 0}) // Keep alive
+
+	writeString(&body, clientID)
+	if username != "" {
+		writeString(&body, username)
+		if password != "" {
+			writeString(&body, password)
+		}
+	}
+	return packet(mqttConnect, body.Bytes())
+}
+
+// publishPacket builds a QoS 0 PUBLISH
+func publishPacket(topic string, payload []byte, retain bool) []byte {
+	var body bytes.Buffer
+	writeString(&body, topic)
+	body.Write(payload)
+
+	header := byte(mqttPublish)
+	if retain {
+		header |= 0x01
+	}
+	return packet(header, body.Bytes())
+}
+
+// packet prefixes a body with the fixed header and remaining length
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+func connAckReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("return code %d", code)
+	}
+}
@@ -0,0 +1,276 @@
+// Package routing forwards machine events to additional destinations by
+// declarative rules: WebSocket topics, webhooks, MQTT topics and alarms.
+// Rules select events by type, source and payload values and shape the
+// payload before it leaves the machine.
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"go.uber.org/zap"
+)
+
+// Event sources
+const (
+	SourceWorkflow = "workflow"
+	SourceMachine  = "machine"
+	SourceDevice   = "device"
+	SourceSystem   = "system"
+	SourceAlarm    = "alarm"
+)
+
+// Sources lists the event sources rules can select
+var Sources = []string{SourceWorkflow, SourceMachine, SourceDevice, SourceSystem, SourceAlarm}
+
+// defaultQueueSize bounds the events waiting for routing without a configured size
+const defaultQueueSize = 1024
+
+// Event is an event offered to the routing rules
+type Event struct {
+	Source    string         `json:"source"`
+	Type      string         `json:"event_type"`
+	Payload   map[string]any `json:"payload"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Routed is the message a target receives
+type Routed struct {
+	Rule      string         `json:"rule"`
+	Source    string         `json:"source"`
+	EventType string         `json:"event_type"`
+	Payload   map[string]any `json:"payload"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// AlarmRaiser raises the event alarms matching an event type
+type AlarmRaiser interface {
+	HandleEvent(eventType string, details map[string]any)
+}
+
+// RuleStatus describes a rule and what it routed since start
+type RuleStatus struct {
+	Name    string         `json:"name"`
+	Events  []string       `json:"events"`
+	Sources []string       `json:"sources"`
+	Targets []TargetStatus `json:"targets"`
+	Matched int64          `json:"matched"`
+}
+
+// TargetStatus counts the deliveries of a target since start
+type TargetStatus struct {
+	Type      string `json:"type"`
+	Topic     string `json:"topic,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Delivered int64  `json:"delivered"`
+	Failed    int64  `json:"failed"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Status is the state of the router
+type Status struct {
+	Enabled bool         `json:"enabled"`
+	Queued  int          `json:"queued"`
+	Dropped int64        `json:"dropped"` // Events lost to a full queue
+	Rules   []RuleStatus `json:"rules"`
+}
+
+// Router matches events against the rules and delivers them to the rules'
+// targets. Events are queued and routed in order by a single worker, so
+// routing never blocks the publisher; a full queue drops events.
+type Router struct {
+	rules  []*rule
+	queue  chan Event
+	logger *zap.Logger
+
+	matched []atomic.Int64 // Per rule
+	dropped atomic.Int64
+
+	mu      sync.Mutex
+	running bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New compiles the routing rules. hub and alarms may be nil if no rule
+// targets them.
+func New(cfg config.RoutingConfig, hub *websocket.Hub, alarms AlarmRaiser, secretStore *secrets.Manager, logger *zap.Logger) (*Router, error) {
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	r := &Router{
+		queue:  make(chan Event, queueSize),
+		logger: logger,
+	}
+
+	names := make(map[string]bool, len(cfg.Rules))
+	for _, ruleCfg := range cfg.Rules {
+		rl, err := compileRule(ruleCfg)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %s: %w", ruleCfg.Name, err)
+		}
+		if names[rl.cfg.Name] {
+			return nil, fmt.Errorf("duplicate routing rule %s", rl.cfg.Name)
+		}
+		names[rl.cfg.Name] = true
+
+		for i, targetCfg := range ruleCfg.Targets {
+			t, err := newTarget(targetCfg, hub, alarms, secretStore)
+			if err != nil {
+				return nil, fmt.Errorf("routing rule %s target %d: %w", rl.cfg.Name, i, err)
+			}
+			rl.targets = append(rl.targets, t)
+		}
+		r.rules = append(r.rules, rl)
+	}
+	r.matched = make([]atomic.Int64, len(r.rules))
+
+	return r, nil
+}
+
+// Enabled reports whether any rule is configured
+func (r *Router) Enabled() bool {
+	return len(r.rules) > 0
+}
+
+// Start routes queued events until Stop
+func (r *Router) Start() {
+	if !r.Enabled() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return
+	}
+	r.running = true
+	r.stop = make(chan struct{})
+
+	r.wg.Add(1)
+	go r.run(r.stop)
+
+	r.logger.Info("Event routing started", zap.Int("rules", len(r.rules)))
+}
+
+// Stop routes the events still queued and ends routing
+func (r *Router) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	close(r.stop)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	for _, rl := range r.rules {
+		for _, t := range rl.targets {
+			t.close()
+		}
+	}
+}
+
+// Route offers an event to the rules without blocking
+func (r *Router) Route(source, eventType string, payload map[string]any) {
+	if !r.Enabled() {
+		return
+	}
+
+	event := Event{Source: source, Type: eventType, Payload: payload, Timestamp: time.Now()}
+	select {
+	case r.queue <- event:
+	default:
+		if r.dropped.Add(1) == 1 {
+			r.logger.Warn("Event routing queue full, events are dropped",
+				zap.String("event_type", eventType))
+		}
+	}
+}
+
+// Status returns the rules with their counters
+func (r *Router) Status() Status {
+	status := Status{
+		Enabled: r.Enabled(),
+		Queued:  len(r.queue),
+		Dropped: r.dropped.Load(),
+		Rules:   make([]RuleStatus, 0, len(r.rules)),
+	}
+	for i, rl := range r.rules {
+		rs := RuleStatus{
+			Name:    rl.cfg.Name,
+			Events:  nonNil(rl.cfg.Events),
+			Sources: nonNil(rl.cfg.Sources),
+			Matched: r.matched[i].Load(),
+			Targets: make([]TargetStatus, 0, len(rl.targets)),
+		}
+		for _, t := range rl.targets {
+			rs.Targets = append(rs.Targets, t.status())
+		}
+		status.Rules = append(status.Rules, rs)
+	}
+	return status
+}
+
+func (r *Router) run(stop chan struct{}) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case event := <-r.queue:
+			r.dispatch(&event)
+		case <-stop:
+			// Route what was queued before the stop
+			for {
+				select {
+				case event := <-r.queue:
+					r.dispatch(&event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// dispatch delivers an event to the targets of every matching rule
+func (r *Router) dispatch(event *Event) {
+	for i, rl := range r.rules {
+		if !rl.matches(event) {
+			continue
+		}
+		r.matched[i].Add(1)
+
+		routed := &Routed{
+			Rule:      rl.cfg.Name,
+			Source:    event.Source,
+			EventType: event.Type,
+			Payload:   rl.transform(event.Payload),
+			Timestamp: event.Timestamp,
+		}
+		for _, t := range rl.targets {
+			if err := t.deliver(context.Background(), routed); err != nil {
+				r.logger.Debug("Routed event not delivered",
+					zap.String("rule", rl.cfg.Name),
+					zap.String("target", t.cfg.Type),
+					zap.String("event_type", event.Type),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
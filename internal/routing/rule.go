@@ -0,0 +1,123 @@
+package routing
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+)
+
+// rule is a compiled routing rule
+type rule struct {
+	cfg     config.RoutingRuleConfig
+	match   map[string][]string // Payload path -> allowed values, formatted with fmt.Sprint
+	targets []*target
+}
+
+func compileRule(cfg config.RoutingRuleConfig) (*rule, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("routing rule without name")
+	}
+	for _, pattern := range cfg.Events {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid event pattern %q", pattern)
+		}
+	}
+	for _, source := range cfg.Sources {
+		if !slices.Contains(Sources, source) {
+			return nil, fmt.Errorf("unknown source %q, use %s", source, strings.Join(Sources, ", "))
+		}
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+
+	r := &rule{cfg: cfg, match: map[string][]string{}}
+	flattenMatch("", cfg.Match, r.match)
+	return r, nil
+}
+
+// flattenMatch turns nested match sections into dotted paths; YAML keys
+// with dots arrive nested
+func flattenMatch(prefix string, match map[string]any, out map[string][]string) {
+	for key, value := range match {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenMatch(key, v, out)
+		case []any:
+			for _, item := range v {
+				out[key] = append(out[key], fmt.Sprint(item))
+			}
+		default:
+			out[key] = []string{fmt.Sprint(v)}
+		}
+	}
+}
+
+// matches reports whether the rule selects an event
+func (r *rule) matches(event *Event) bool {
+	if len(r.cfg.Sources) > 0 && !slices.Contains(r.cfg.Sources, event.Source) {
+		return false
+	}
+	if len(r.cfg.Events) > 0 && !slices.ContainsFunc(r.cfg.Events, func(pattern string) bool {
+		ok, _ := path.Match(pattern, event.Type)
+		return ok
+	}) {
+		return false
+	}
+	for key, allowed := range r.match {
+		value, ok := lookup(event.Payload, key)
+		if !ok || !slices.Contains(allowed, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// transform returns the payload shaped by fields, rename and set. The event
+// payload is not modified.
+func (r *rule) transform(payload map[string]any) map[string]any {
+	out := make(map[string]any, len(payload))
+	if len(r.cfg.Fields) == 0 {
+		for k, v := range payload {
+			out[k] = v
+		}
+	} else {
+		for _, field := range r.cfg.Fields {
+			if value, ok := lookup(payload, field); ok {
+				out[field] = value
+			}
+		}
+	}
+
+	for from, to := range r.cfg.Rename {
+		if value, ok := out[from]; ok {
+			delete(out, from)
+			out[to] = value
+		}
+	}
+	for k, v := range r.cfg.Set {
+		out[k] = v
+	}
+	return out
+}
+
+// lookup resolves a dotted path in a payload
+func lookup(payload map[string]any, key string) (any, bool) {
+	var current any = payload
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,244 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+)
+
+// Target types
+const (
+	TargetWebSocket = "websocket"
+	TargetWebhook   = "webhook"
+	TargetMQTT      = "mqtt"
+	TargetAlarm     = "alarm"
+)
+
+// Defaults of targets without configured values
+const (
+	defaultTopic   = "routed_event"
+	defaultTimeout = 5 * time.Second
+)
+
+// sender delivers a routed event to one destination
+type sender interface {
+	send(ctx context.Context, routed *Routed) error
+	close()
+}
+
+// target is a destination of a rule with its delivery counters
+type target struct {
+	cfg    config.RoutingTargetConfig
+	sender sender
+
+	delivered atomic.Int64
+	failed    atomic.Int64
+	mu        sync.Mutex
+	lastError string
+}
+
+func newTarget(cfg config.RoutingTargetConfig, hub *websocket.Hub, alarms AlarmRaiser, secretStore *secrets.Manager) (*target, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	t := &target{cfg: cfg}
+	switch cfg.Type {
+	case TargetWebSocket:
+		if hub == nil {
+			return nil, errors.New("websocket hub unavailable")
+		}
+		if cfg.Topic == "" {
+			t.cfg.Topic = defaultTopic
+		}
+		t.sender = &websocketSender{hub: hub, messageType: websocket.MessageType(t.cfg.Topic)}
+
+	case TargetWebhook:
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook url %q", cfg.URL)
+		}
+		for header, name := range cfg.SecretHeaders {
+			if err := secrets.ValidateName(name); err != nil {
+				return nil, fmt.Errorf("header %s: %w", header, err)
+			}
+		}
+		t.sender = &webhookSender{cfg: cfg, secrets: secretStore, client: &http.Client{}}
+
+	case TargetMQTT:
+		if cfg.Topic == "" {
+			return nil, errors.New("mqtt target without topic")
+		}
+		if cfg.PasswordSecret != "" {
+			if err := secrets.ValidateName(cfg.PasswordSecret); err != nil {
+				return nil, fmt.Errorf("password_secret: %w", err)
+			}
+		}
+		mqtt, err := newMQTTSender(cfg, secretStore)
+		if err != nil {
+			return nil, err
+		}
+		t.sender = mqtt
+
+	case TargetAlarm:
+		if alarms == nil {
+			return nil, errors.New("alarms unavailable")
+		}
+		t.sender = &alarmSender{alarms: alarms, eventType: cfg.EventType}
+
+	default:
+		return nil, fmt.Errorf("unsupported type %q, use websocket, webhook, mqtt or alarm", cfg.Type)
+	}
+	return t, nil
+}
+
+// deliver sends a routed event within the target's timeout and counts the
+// result
+func (t *target) deliver(ctx context.Context, routed *Routed) error {
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.Timeout)
+	defer cancel()
+
+	err := t.sender.send(ctx, routed)
+	if err != nil {
+		t.failed.Add(1)
+		t.mu.Lock()
+		t.lastError = err.Error()
+		t.mu.Unlock()
+		return err
+	}
+	t.delivered.Add(1)
+	return nil
+}
+
+func (t *target) status() TargetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := TargetStatus{
+		Type:      t.cfg.Type,
+		Topic:     t.cfg.Topic,
+		Delivered: t.delivered.Load(),
+		Failed:    t.failed.Load(),
+		LastError: t.lastError,
+	}
+	if t.cfg.Type == TargetWebhook || t.cfg.Type == TargetMQTT {
+		status.URL = t.cfg.URL
+	}
+	return status
+}
+
+func (t *target) close() {
+	t.sender.close()
+}
+
+// expandTopic fills {source} and {event_type} placeholders
+func expandTopic(topic string, routed *Routed) string {
+	return strings.NewReplacer(
+		"{source}", routed.Source,
+		"{event_type}", routed.EventType,
+		"{rule}", routed.Rule,
+	).Replace(topic)
+}
+
+// websocketSender broadcasts routed events as WebSocket messages of the
+// target topic
+type websocketSender struct {
+	hub         *websocket.Hub
+	messageType websocket.MessageType
+}
+
+func (s *websocketSender) send(_ context.Context, routed *Routed) error {
+	msg := websocket.NewMessage(s.messageType, routed)
+	msg.Timestamp = routed.Timestamp
+	s.hub.Broadcast(msg)
+	return nil
+}
+
+func (s *websocketSender) close() {}
+
+// webhookSender posts routed events as JSON. Unlike outbox sinks it does not
+// retry; failed deliveries are counted and dropped.
+type webhookSender struct {
+	cfg     config.RoutingTargetConfig
+	secrets *secrets.Manager
+	client  *http.Client
+}
+
+func (s *webhookSender) send(ctx context.Context, routed *Routed) error {
+	body, err := json.Marshal(routed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-OMC-Event-Type", routed.EventType)
+	req.Header.Set("X-OMC-Route", routed.Rule)
+	for header, value := range s.cfg.Headers {
+		req.Header.Set(header, value)
+	}
+	for header, name := range s.cfg.SecretHeaders {
+		value, err := s.secrets.Get(ctx, name)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", header, err)
+		}
+		req.Header.Set(header, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSender) close() {}
+
+// alarmSender raises the event alarms matching the target's event type, or
+// the routed event's type without one. Alarm events are not routed into
+// alarms again, so rules can't raise alarms in a loop.
+type alarmSender struct {
+	alarms    AlarmRaiser
+	eventType string
+}
+
+func (s *alarmSender) send(_ context.Context, routed *Routed) error {
+	if routed.Source == SourceAlarm {
+		return errors.New("alarm events are not routed to alarms")
+	}
+	eventType := routed.EventType
+	if s.eventType != "" {
+		eventType = expandTopic(s.eventType, routed)
+	}
+
+	details := make(map[string]any, len(routed.Payload)+1)
+	for k, v := range routed.Payload {
+		details[k] = v
+	}
+	details["routed_by"] = routed.Rule
+	s.alarms.HandleEvent(eventType, details)
+	return nil
+}
+
+func (s *alarmSender) close() {}
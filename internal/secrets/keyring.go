@@ -0,0 +1,200 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+)
+
+const (
+	// keySize is the size of master and data keys (AES-256)
+	keySize = 32
+
+	// valuePrefix marks encrypted config values: enc:v1:<key id>:<encrypted key>:<ciphertext>
+	valuePrefix = "enc:v1:"
+
+	// configAAD binds encrypted config values to their use
+	configAAD = "config"
+)
+
+var (
+	ErrNoMasterKey = errors.New("no master key configured")
+	ErrKeyMismatch = errors.New("encrypted with a different master key")
+)
+
+// Keyring encrypts values with envelope encryption: every value gets its own
+// random data key, and only the data key is encrypted with the master key.
+// Both use AES-256-GCM with the nonce prepended to the ciphertext.
+type Keyring struct {
+	master cipher.AEAD
+	keyID  string
+}
+
+// NewKeyring loads the master key from the configured file or environment
+// variable. Returns ErrNoMasterKey if neither is set.
+func NewKeyring(cfg config.SecretsConfig) (*Keyring, error) {
+	var encoded string
+	switch {
+	case cfg.MasterKeyFile != "":
+		data, err := os.ReadFile(config.ResolvePath(cfg.MasterKeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	case cfg.MasterKeyEnv != "":
+		encoded = strings.TrimSpace(os.Getenv(cfg.MasterKeyEnv))
+	}
+	if encoded == "" {
+		return nil, ErrNoMasterKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, base64 encoded", keySize)
+	}
+	return newKeyring(key)
+}
+
+func newKeyring(key []byte) (*Keyring, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &Keyring{master: aead, keyID: hex.EncodeToString(sum[:4])}, nil
+}
+
+// GenerateMasterKey returns a new random master key, base64 encoded
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// KeyID identifies the master key without revealing it
+func (k *Keyring) KeyID() string {
+	return k.keyID
+}
+
+// Seal encrypts plaintext with a new data key. aad binds the ciphertext to
+// its context, e.g. the secret name, so it can't be moved elsewhere.
+func (k *Keyring) Seal(plaintext, aad []byte) (encryptedKey, ciphertext []byte, err error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ciphertext, err = seal(aead, plaintext, aad); err != nil {
+		return nil, nil, err
+	}
+	if encryptedKey, err = seal(k.master, dataKey, []byte(k.keyID)); err != nil {
+		return nil, nil, err
+	}
+	return encryptedKey, ciphertext, nil
+}
+
+// Open decrypts a value sealed with Seal
+func (k *Keyring) Open(keyID string, encryptedKey, ciphertext, aad []byte) ([]byte, error) {
+	if keyID != k.keyID {
+		return nil, fmt.Errorf("%w (key %s, current key %s)", ErrKeyMismatch, keyID, k.keyID)
+	}
+
+	dataKey, err := open(k.master, encryptedKey, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptValue encrypts a value for the config file
+func (k *Keyring) EncryptValue(plaintext string) (string, error) {
+	encryptedKey, ciphertext, err := k.Seal([]byte(plaintext), []byte(configAAD))
+	if err != nil {
+		return "", err
+	}
+	return valuePrefix + k.keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(encryptedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// IsEncrypted reports whether a config value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, valuePrefix)
+}
+
+// DecryptValue decrypts an encrypted config value; other values are returned
+// unchanged. The keyring may be nil if no value is encrypted.
+func (k *Keyring) DecryptValue(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoMasterKey
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, valuePrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	encryptedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	plaintext, err := k.Open(parts[0], encryptedKey, ciphertext, []byte(configAAD))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
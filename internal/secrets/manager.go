@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Audit actions
+const (
+	AuditSecretSaved   = "secret.saved"
+	AuditSecretDeleted = "secret.deleted"
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// ValidateName checks a secret name, e.g. "mqtt.broker_password"
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: use up to 128 letters, digits, '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// Manager stores credentials encrypted in the database. Values can only be
+// read back through Get, never through the API.
+type Manager struct {
+	keyring *Keyring // Nil without master key
+	storage *storage.PostgresClient
+	logger  *zap.Logger
+}
+
+func NewManager(keyring *Keyring, store *storage.PostgresClient, logger *zap.Logger) *Manager {
+	return &Manager{
+		keyring: keyring,
+		storage: store,
+		logger:  logger,
+	}
+}
+
+// Available reports whether a master key is configured
+func (m *Manager) Available() bool {
+	return m.keyring != nil
+}
+
+// Save encrypts and stores a secret and reports whether it was created
+func (m *Manager) Save(ctx context.Context, name, value, description, actor string) (*storage.Secret, bool, error) {
+	if m.keyring == nil {
+		return nil, false, ErrNoMasterKey
+	}
+	if err := ValidateName(name); err != nil {
+		return nil, false, err
+	}
+
+	encryptedKey, ciphertext, err := m.keyring.Seal([]byte(value), secretAAD(name))
+	if err != nil {
+		return nil, false, err
+	}
+	secret := &storage.Secret{
+		Name:         name,
+		Description:  description,
+		KeyID:        m.keyring.KeyID(),
+		EncryptedKey: encryptedKey,
+		Ciphertext:   ciphertext,
+		UpdatedBy:    actor,
+	}
+	created, err := m.storage.SaveSecret(ctx, secret)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.audit(ctx, AuditSecretSaved, actor, map[string]any{
+		"name":    name,
+		"created": created,
+		"key_id":  secret.KeyID,
+	})
+	return secret, created, nil
+}
+
+// Get returns the decrypted value of a secret
+func (m *Manager) Get(ctx context.Context, name string) (string, error) {
+	if m.keyring == nil {
+		return "", ErrNoMasterKey
+	}
+
+	secret, err := m.storage.GetSecret(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	value, err := m.keyring.Open(secret.KeyID, secret.EncryptedKey, secret.Ciphertext, secretAAD(name))
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return string(value), nil
+}
+
+// Metadata returns a secret without its value
+func (m *Manager) Metadata(ctx context.Context, name string) (*storage.Secret, error) {
+	secret, err := m.storage.GetSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	secret.EncryptedKey, secret.Ciphertext = nil, nil
+	return secret, nil
+}
+
+// List returns all secrets without their values
+func (m *Manager) List(ctx context.Context) ([]storage.Secret, error) {
+	return m.storage.ListSecrets(ctx)
+}
+
+// Delete removes a secret
+func (m *Manager) Delete(ctx context.Context, name, actor string) error {
+	if err := m.storage.DeleteSecret(ctx, name); err != nil {
+		return err
+	}
+	m.audit(ctx, AuditSecretDeleted, actor, map[string]any{"name": name})
+	return nil
+}
+
+// secretAAD binds a ciphertext to the secret name
+func secretAAD(name string) []byte {
+	return []byte("secret:" + name)
+}
+
+func (m *Manager) audit(ctx context.Context, action, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := m.storage.RecordAudit(ctx, entry); err != nil {
+		m.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// Listen opens the listener of a server. For unix sockets a socket file left
+// behind by an unclean shutdown is removed first, and the socket gets mode as
+// permissions; it is removed again when the listener is closed.
+func Listen(network, address string, mode os.FileMode) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	if info, err := os.Lstat(address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", address)
+		}
+		if err := os.Remove(address); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address, mode); err != nil {
+		lis.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return lis, nil
+}
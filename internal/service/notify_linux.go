@@ -0,0 +1,106 @@
+//go:build linux
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// platform is the systemd notification state
+type platform struct {
+	socket   string        // $NOTIFY_SOCKET, empty when not run by systemd with Type=notify
+	watchdog time.Duration // $WATCHDOG_USEC, 0 without WatchdogSec
+
+	stopWatchdog chan struct{}
+	wg           sync.WaitGroup
+}
+
+func (n *Notifier) start() error {
+	n.sys.socket = os.Getenv("NOTIFY_SOCKET")
+
+	if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+		// WATCHDOG_PID names the process the watchdog is meant for
+		if pid := os.Getenv("WATCHDOG_PID"); pid == "" || pid == strconv.Itoa(os.Getpid()) {
+			n.sys.watchdog = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	if n.sys.socket != "" {
+		n.logger.Info("systemd notification enabled", zap.Duration("watchdog", n.sys.watchdog))
+	}
+	return nil
+}
+
+func (n *Notifier) ready(health HealthFunc) {
+	n.notify("READY=1\nSTATUS=Running")
+
+	if n.sys.watchdog <= 0 || n.sys.socket == "" {
+		return
+	}
+	n.sys.stopWatchdog = make(chan struct{})
+	n.sys.wg.Add(1)
+	go n.feedWatchdog(health, n.sys.stopWatchdog)
+}
+
+func (n *Notifier) stopping() {
+	if n.sys.stopWatchdog != nil {
+		close(n.sys.stopWatchdog)
+		n.sys.wg.Wait()
+		n.sys.stopWatchdog = nil
+	}
+	n.notify("STOPPING=1\nSTATUS=Shutting down")
+}
+
+func (n *Notifier) stopped() {}
+
+// feedWatchdog pings the systemd watchdog at half its interval while healthy
+func (n *Notifier) feedWatchdog(health HealthFunc, stop <-chan struct{}) {
+	defer n.sys.wg.Done()
+
+	ticker := time.NewTicker(n.sys.watchdog / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if health != nil && !health() {
+				n.logger.Warn("Core unhealthy, not feeding the systemd watchdog")
+				continue
+			}
+			n.notify("WATCHDOG=1")
+		}
+	}
+}
+
+// notify sends a state to systemd (sd_notify protocol)
+func (n *Notifier) notify(state string) {
+	if n.sys.socket == "" {
+		return
+	}
+	if err := sdNotify(n.sys.socket, state); err != nil {
+		n.logger.Warn("Failed to notify systemd", zap.Error(err))
+	}
+}
+
+func sdNotify(socket, state string) error {
+	// A leading "@" is an abstract socket, which net handles itself
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("failed to write to notify socket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package service
+
+type platform struct{}
+
+func (n *Notifier) start() error { return nil }
+
+func (n *Notifier) ready(HealthFunc) {}
+
+func (n *Notifier) stopping() {}
+
+func (n *Notifier) stopped() {}
@@ -0,0 +1,101 @@
+//go:build windows
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows/svc"
+)
+
+// stopWaitHint tells the SCM how long a graceful shutdown may take
+const stopWaitHint = 30 * time.Second
+
+// platform is the Windows service state
+type platform struct {
+	isService bool
+	ready     chan struct{} // Closed by Ready
+	done      chan struct{} // Closed by Stopped
+	exited    chan struct{} // Closed when svc.Run returns
+}
+
+func (n *Notifier) start() error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("failed to detect Windows service: %w", err)
+	}
+	if !isService {
+		return nil
+	}
+
+	n.sys = platform{
+		isService: true,
+		ready:     make(chan struct{}),
+		done:      make(chan struct{}),
+		exited:    make(chan struct{}),
+	}
+	go func() {
+		defer close(n.sys.exited)
+		if err := svc.Run(Name, &serviceHandler{n: n}); err != nil {
+			n.logger.Error("Windows service control failed", zap.Error(err))
+			n.requestStop()
+		}
+	}()
+
+	n.logger.Info("Running as Windows service", zap.String("name", Name))
+	return nil
+}
+
+func (n *Notifier) ready(HealthFunc) {
+	if n.sys.isService {
+		close(n.sys.ready)
+	}
+}
+
+func (n *Notifier) stopping() {}
+
+// stopped lets the handler report SERVICE_STOPPED; the process must not exit before
+func (n *Notifier) stopped() {
+	if !n.sys.isService {
+		return
+	}
+	close(n.sys.done)
+	select {
+	case <-n.sys.exited:
+	case <-time.After(5 * time.Second):
+	}
+}
+
+// serviceHandler answers the service control manager
+type serviceHandler struct {
+	n *Notifier
+}
+
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+
+	status <- svc.Status{State: svc.StartPending}
+
+	ready := h.n.sys.ready
+	for {
+		select {
+		case <-ready:
+			ready = nil
+			status <- svc.Status{State: svc.Running, Accepts: accepts}
+
+		case <-h.n.sys.done:
+			return false, 0
+
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopWaitHint / time.Millisecond)}
+				h.n.requestStop()
+			}
+		}
+	}
+}
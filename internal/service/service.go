@@ -0,0 +1,64 @@
+package service
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Name is the service name registered with the operating system
+const Name = "openmachinecore"
+
+// HealthFunc reports whether the core is healthy. The systemd watchdog is
+// only fed while it returns true.
+type HealthFunc func() bool
+
+// Notifier reports the lifecycle of the server to the service manager of the
+// operating system: systemd (sd_notify, watchdog) on Linux, the service
+// control manager on Windows. Without a service manager all calls are no-ops.
+type Notifier struct {
+	logger *zap.Logger
+
+	stopRequested chan struct{}
+	stopOnce      sync.Once
+
+	sys platform // Platform specific state
+}
+
+// Start connects to the service manager. On Windows this registers the
+// service handler and must be called early, the SCM expects it within 30s.
+func Start(logger *zap.Logger) (*Notifier, error) {
+	n := &Notifier{
+		logger:        logger,
+		stopRequested: make(chan struct{}),
+	}
+	if err := n.start(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Ready reports that the core has started and serves requests
+func (n *Notifier) Ready(health HealthFunc) {
+	n.ready(health)
+}
+
+// Stopping reports that the shutdown has begun
+func (n *Notifier) Stopping() {
+	n.stopping()
+}
+
+// Stopped reports that the shutdown is complete
+func (n *Notifier) Stopped() {
+	n.stopped()
+}
+
+// StopRequested is closed when the service manager asks the server to stop
+// by other means than a signal (Windows service control)
+func (n *Notifier) StopRequested() <-chan struct{} {
+	return n.stopRequested
+}
+
+func (n *Notifier) requestStop() {
+	n.stopOnce.Do(func() { close(n.stopRequested) })
+}
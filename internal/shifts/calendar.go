@@ -0,0 +1,177 @@
+package shifts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// Shift is a concrete shift occurrence
+type Shift struct {
+	Name      string    `json:"name"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Exception bool      `json:"exception,omitempty"` // From a calendar exception
+}
+
+// Contains reports whether t lies within the shift
+func (s *Shift) Contains(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// Calendar combines the weekly pattern with the exceptions of a date range
+type Calendar struct {
+	Patterns   []storage.ShiftPattern
+	Exceptions map[string][]storage.ShiftException // By date (YYYY-MM-DD)
+	Location   *time.Location
+}
+
+// NewCalendar indexes exceptions by date
+func NewCalendar(patterns []storage.ShiftPattern, exceptions []storage.ShiftException, loc *time.Location) *Calendar {
+	cal := &Calendar{
+		Patterns:   patterns,
+		Exceptions: make(map[string][]storage.ShiftException),
+		Location:   loc,
+	}
+	for _, se := range exceptions {
+		cal.Exceptions[se.Date] = append(cal.Exceptions[se.Date], se)
+	}
+	return cal
+}
+
+// ShiftsOn returns the shifts starting on the date of day, ordered by start.
+// Exceptions of the date replace the weekly pattern.
+func (c *Calendar) ShiftsOn(day time.Time) []Shift {
+	day = day.In(c.Location)
+	date := day.Format(time.DateOnly)
+
+	shifts := make([]Shift, 0)
+	if exceptions, ok := c.Exceptions[date]; ok {
+		for _, se := range exceptions {
+			if se.Start == "" {
+				continue // No production
+			}
+			if shift, err := c.occurrence(day, se.Name, se.Start, se.End); err == nil {
+				shift.Exception = true
+				shifts = append(shifts, shift)
+			}
+		}
+	} else {
+		for _, sp := range c.Patterns {
+			if sp.Weekday != int(day.Weekday()) {
+				continue
+			}
+			if shift, err := c.occurrence(day, sp.Name, sp.Start, sp.End); err == nil {
+				shifts = append(shifts, shift)
+			}
+		}
+	}
+
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].Start.Before(shifts[j].Start) })
+	return shifts
+}
+
+// ShiftAt returns the shift running at t, nil between shifts. Overnight
+// shifts of the previous day are taken into account.
+func (c *Calendar) ShiftAt(t time.Time) *Shift {
+	t = t.In(c.Location)
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+		for _, shift := range c.ShiftsOn(day) {
+			if shift.Contains(t) {
+				return &shift
+			}
+		}
+	}
+	return nil
+}
+
+// Upcoming returns the shifts ending after from within the given number of days
+func (c *Calendar) Upcoming(from time.Time, days int) []Shift {
+	from = from.In(c.Location)
+
+	shifts := make([]Shift, 0)
+	for i := -1; i < days; i++ {
+		for _, shift := range c.ShiftsOn(from.AddDate(0, 0, i)) {
+			if shift.End.After(from) {
+				shifts = append(shifts, shift)
+			}
+		}
+	}
+	return shifts
+}
+
+func (c *Calendar) occurrence(day time.Time, name, start, end string) (Shift, error) {
+	startH, startM, err := ParseClock(start)
+	if err != nil {
+		return Shift{}, err
+	}
+	endH, endM, err := ParseClock(end)
+	if err != nil {
+		return Shift{}, err
+	}
+
+	y, m, d := day.Date()
+	shift := Shift{
+		Name:  name,
+		Start: time.Date(y, m, d, startH, startM, 0, 0, c.Location),
+		End:   time.Date(y, m, d, endH, endM, 0, 0, c.Location),
+	}
+	if !shift.End.After(shift.Start) {
+		shift.End = shift.End.AddDate(0, 0, 1) // Overnight shift
+	}
+	return shift, nil
+}
+
+// ParseClock parses a HH:MM time of day
+func ParseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil || len(s) != 5 {
+		return 0, 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ValidatePattern checks a weekly shift entry
+func ValidatePattern(sp *storage.ShiftPattern) error {
+	if sp.Name == "" {
+		return fmt.Errorf("shift name is required")
+	}
+	if sp.Weekday < 0 || sp.Weekday > 6 {
+		return fmt.Errorf("shift %s: weekday must be 0 (Sunday) to 6 (Saturday)", sp.Name)
+	}
+	if _, _, err := ParseClock(sp.Start); err != nil {
+		return fmt.Errorf("shift %s: start: %w", sp.Name, err)
+	}
+	if _, _, err := ParseClock(sp.End); err != nil {
+		return fmt.Errorf("shift %s: end: %w", sp.Name, err)
+	}
+	if sp.Start == sp.End {
+		return fmt.Errorf("shift %s: start and end must differ", sp.Name)
+	}
+	return nil
+}
+
+// ValidateException checks a calendar exception
+func ValidateException(se *storage.ShiftException) error {
+	if _, err := time.Parse(time.DateOnly, se.Date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", se.Date)
+	}
+	if (se.Start == "") != (se.End == "") {
+		return fmt.Errorf("start and end must be given together")
+	}
+	if se.Start == "" {
+		return nil
+	}
+	if _, _, err := ParseClock(se.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, _, err := ParseClock(se.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if se.Start == se.End {
+		return fmt.Errorf("start and end must differ")
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package shifts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Actor is recorded for automatic actions in the audit log and on cancelled executions
+const Actor = "shift_scheduler"
+
+// Audit actions
+const (
+	AuditAutoHome       = "shift.auto_home"
+	AuditAutoStart      = "shift.auto_start"
+	AuditAutoStop       = "shift.auto_stop"
+	AuditActionSkipped  = "shift.action_skipped"
+	AuditOverrideSet    = "shift.override_set"
+	AuditOverrideClear  = "shift.override_cleared"
+	AuditCalendarChange = "shift.calendar_changed"
+)
+
+// actionTimeout bounds a single automatic machine command
+const actionTimeout = 10 * time.Second
+
+// Status describes the shift automation at a point in time
+type Status struct {
+	Enabled      bool                   `json:"enabled"`
+	Timezone     string                 `json:"timezone"`
+	AutoHome     bool                   `json:"auto_home"`
+	AutoStart    bool                   `json:"auto_start"`
+	AutoStop     bool                   `json:"auto_stop"`
+	CurrentShift *Shift                 `json:"current_shift,omitempty"`
+	NextShift    *Shift                 `json:"next_shift,omitempty"`
+	Override     *storage.ShiftOverride `json:"override,omitempty"`
+}
+
+// Scheduler homes and starts the machine at shift begin and stops it at
+// shift end. Only boundaries passed while the scheduler runs trigger actions;
+// boundaries missed during downtime are not caught up.
+type Scheduler struct {
+	cfg        config.ShiftsConfig
+	storage    *storage.PostgresClient
+	controller *machine.Controller
+	logger     *zap.Logger
+	location   *time.Location
+
+	mu           sync.Mutex
+	current      *Shift
+	initialized  bool
+	pendingStart *Shift // Homed at shift begin, start once ready
+
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewScheduler(cfg config.ShiftsConfig, store *storage.PostgresClient, controller *machine.Controller, logger *zap.Logger) (*Scheduler, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shift timezone %q: %w", cfg.Timezone, err)
+	}
+
+	return &Scheduler{
+		cfg:        cfg,
+		storage:    store,
+		controller: controller,
+		logger:     logger,
+		location:   loc,
+	}, nil
+}
+
+// Location returns the time zone shift times are interpreted in
+func (s *Scheduler) Location() *time.Location {
+	return s.location
+}
+
+// Start begins watching shift boundaries
+func (s *Scheduler) Start() {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.initialized = false
+	s.stopChan = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.loop(s.stopChan)
+
+	s.logger.Info("Shift scheduler started",
+		zap.String("timezone", s.location.String()),
+		zap.Bool("auto_home", s.cfg.AutoHome),
+		zap.Bool("auto_start", s.cfg.AutoStart),
+		zap.Bool("auto_stop", s.cfg.AutoStop))
+}
+
+// Stop ends shift automation; Start may be called again afterwards
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Calendar loads the shift calendar covering from..to plus the day before
+// from, so overnight shifts reaching into the range are included
+func (s *Scheduler) Calendar(ctx context.Context, from, to time.Time) (*Calendar, error) {
+	patterns, err := s.storage.ListShiftPatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.storage.ListShiftExceptions(ctx, from.In(s.location).AddDate(0, 0, -1), to.In(s.location))
+	if err != nil {
+		return nil, err
+	}
+	return NewCalendar(patterns, exceptions, s.location), nil
+}
+
+// Status returns the current and next shift and the active override
+func (s *Scheduler) Status(ctx context.Context) (*Status, error) {
+	now := time.Now()
+	cal, err := s.Calendar(ctx, now, now.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.storage.ActiveShiftOverride(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Enabled:      s.cfg.Enabled,
+		Timezone:     s.location.String(),
+		AutoHome:     s.cfg.AutoHome,
+		AutoStart:    s.cfg.AutoStart,
+		AutoStop:     s.cfg.AutoStop,
+		CurrentShift: cal.ShiftAt(now),
+		Override:     override,
+	}
+	for _, shift := range cal.Upcoming(now, 7) {
+		if shift.Start.After(now) {
+			next := shift
+			status.NextShift = &next
+			break
+		}
+	}
+	return status, nil
+}
+
+// CurrentShift returns the shift running now, nil between shifts
+func (s *Scheduler) CurrentShift(ctx context.Context) (*Shift, error) {
+	now := time.Now()
+	cal, err := s.Calendar(ctx, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return cal.ShiftAt(now), nil
+}
+
+// Suspend skips automatic shift actions until the given time
+func (s *Scheduler) Suspend(ctx context.Context, until time.Time, reason, actor string) (*storage.ShiftOverride, error) {
+	if !until.After(time.Now()) {
+		return nil, errors.New("suspension must end in the future")
+	}
+
+	override := &storage.ShiftOverride{
+		SuspendedUntil: until,
+		Reason:         reason,
+		CreatedBy:      actor,
+	}
+	if err := s.storage.CreateShiftOverride(ctx, override); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, AuditOverrideSet, actor, map[string]any{
+		"suspended_until": until,
+		"reason":          reason,
+	})
+	return override, nil
+}
+
+// Resume clears all active overrides
+func (s *Scheduler) Resume(ctx context.Context, actor string) (int64, error) {
+	cleared, err := s.storage.ClearShiftOverrides(ctx, actor)
+	if err != nil {
+		return 0, err
+	}
+	if cleared > 0 {
+		s.audit(ctx, AuditOverrideClear, actor, map[string]any{"cleared": cleared})
+	}
+	return cleared, nil
+}
+
+// Audit records a change made through the API
+func (s *Scheduler) Audit(ctx context.Context, action, actor string, details map[string]any) {
+	s.audit(ctx, action, actor, details)
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	s.tick()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.tick()
+		}
+	}
+}
+
+// tick detects shift boundaries since the last check and acts on them
+func (s *Scheduler) tick() {
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+
+	now := time.Now()
+	cal, err := s.Calendar(ctx, now, now)
+	if err != nil {
+		s.logger.Error("Failed to load shift calendar", zap.Error(err))
+		return
+	}
+	shift := cal.ShiftAt(now)
+
+	s.mu.Lock()
+	prev := s.current
+	initialized := s.initialized
+	s.current = shift
+	s.initialized = true
+	s.mu.Unlock()
+
+	if !initialized {
+		// Don't act on a shift that was already running at startup
+		return
+	}
+
+	began := shift != nil && (prev == nil || !prev.Start.Equal(shift.Start))
+	ended := prev != nil && (shift == nil || !prev.Start.Equal(shift.Start))
+
+	switch {
+	case began && ended && !prev.End.Before(shift.Start):
+		// Back-to-back shifts: keep the machine running through the changeover
+		s.logger.Info("Shift changeover",
+			zap.String("from", prev.Name),
+			zap.String("to", shift.Name))
+	case ended:
+		s.onShiftEnd(ctx, prev)
+		if began {
+			s.onShiftBegin(ctx, shift)
+		}
+	case began:
+		s.onShiftBegin(ctx, shift)
+	}
+
+	s.startPending(ctx, shift)
+}
+
+func (s *Scheduler) onShiftBegin(ctx context.Context, shift *Shift) {
+	s.logger.Info("Shift begins", zap.String("shift", shift.Name), zap.Time("end", shift.End))
+
+	if s.suspended(ctx, "shift_begin", shift) {
+		return
+	}
+
+	state := s.controller.GetStatus().State
+	switch {
+	case state == machine.StateStopped && s.cfg.AutoHome:
+		if s.command(ctx, AuditAutoHome, machine.CommandHome, shift) && s.cfg.AutoStart {
+			s.mu.Lock()
+			s.pendingStart = shift
+			s.mu.Unlock()
+		}
+	case state == machine.StateReady && s.cfg.AutoStart:
+		s.command(ctx, AuditAutoStart, machine.CommandStart, shift)
+	default:
+		s.audit(ctx, AuditActionSkipped, Actor, map[string]any{
+			"trigger": "shift_begin",
+			"shift":   shift.Name,
+			"state":   state,
+			"reason":  "no automatic action for machine state",
+		})
+	}
+}
+
+func (s *Scheduler) onShiftEnd(ctx context.Context, shift *Shift) {
+	s.logger.Info("Shift ends", zap.String("shift", shift.Name))
+
+	s.mu.Lock()
+	s.pendingStart = nil
+	s.mu.Unlock()
+
+	if !s.cfg.AutoStop || s.suspended(ctx, "shift_end", shift) {
+		return
+	}
+
+	state := s.controller.GetStatus().State
+	if state == machine.StateStopped || state == machine.StateStopping {
+		return
+	}
+	s.command(ctx, AuditAutoStop, machine.CommandStop, shift)
+}
+
+// startPending starts production once a machine homed at shift begin is ready
+func (s *Scheduler) startPending(ctx context.Context, shift *Shift) {
+	s.mu.Lock()
+	pending := s.pendingStart
+	s.mu.Unlock()
+	if pending == nil {
+		return
+	}
+
+	if shift == nil || !shift.Start.Equal(pending.Start) {
+		s.mu.Lock()
+		s.pendingStart = nil
+		s.mu.Unlock()
+		return
+	}
+
+	switch s.controller.GetStatus().State {
+	case machine.StateHoming:
+		return // Still homing
+	case machine.StateReady:
+		s.command(ctx, AuditAutoStart, machine.CommandStart, shift)
+	}
+
+	s.mu.Lock()
+	s.pendingStart = nil
+	s.mu.Unlock()
+}
+
+// command runs a machine command and records it in the audit log
+func (s *Scheduler) command(ctx context.Context, action string, cmd machine.Command, shift *Shift) bool {
+	reason := "shift " + shift.Name + " begins"
+	if cmd == machine.CommandStop {
+		reason = "shift " + shift.Name + " ended"
+	}
+
+	result, err := s.controller.ExecuteCommand(ctx, cmd, machine.CommandOptions{
+		Queue:  cmd == machine.CommandStop,
+		Reason: reason,
+		Actor:  Actor,
+	})
+
+	details := map[string]any{
+		"command":     cmd,
+		"shift":       shift.Name,
+		"shift_start": shift.Start,
+		"shift_end":   shift.End,
+	}
+	if err != nil {
+		details["error"] = err.Error()
+		s.logger.Warn("Automatic shift action failed",
+			zap.String("command", string(cmd)),
+			zap.String("shift", shift.Name),
+			zap.Error(err))
+	} else {
+		details["result"] = result
+	}
+
+	s.audit(ctx, action, Actor, details)
+	return err == nil
+}
+
+// suspended records a skipped action if an override is active
+func (s *Scheduler) suspended(ctx context.Context, trigger string, shift *Shift) bool {
+	override, err := s.storage.ActiveShiftOverride(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to check shift override", zap.Error(err))
+		return false
+	}
+	if override == nil {
+		return false
+	}
+
+	s.audit(ctx, AuditActionSkipped, Actor, map[string]any{
+		"trigger":         trigger,
+		"shift":           shift.Name,
+		"reason":          "automation suspended",
+		"override_id":     override.ID,
+		"suspended_until": override.SuspendedUntil,
+	})
+	return true
+}
+
+func (s *Scheduler) audit(ctx context.Context, action, actor string, details map[string]any) {
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := s.storage.RecordAudit(ctx, entry); err != nil {
+		s.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
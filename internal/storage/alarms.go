@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Alarm states
+const (
+	AlarmStateActive       = "active"       // Condition present, not acknowledged
+	AlarmStateAcknowledged = "acknowledged" // Condition present, acknowledged
+	AlarmStateCleared      = "cleared"      // Condition gone, not acknowledged
+	AlarmStateLatched      = "latched"      // Condition gone, acknowledged, waiting for reset
+	AlarmStateClosed       = "closed"
+)
+
+var (
+	ErrAlarmDefinitionNotFound = errors.New("alarm definition not found")
+	ErrAlarmDefinitionExists   = errors.New("alarm definition already exists")
+)
+
+// UpdateState derives State from the timestamps of the alarm
+func (a *Alarm) UpdateState() {
+	switch {
+	case a.ClosedAt != nil:
+		a.State = AlarmStateClosed
+	case a.ClearedAt == nil && a.AcknowledgedAt == nil:
+		a.State = AlarmStateActive
+	case a.ClearedAt == nil:
+		a.State = AlarmStateAcknowledged
+	case a.AcknowledgedAt == nil:
+		a.State = AlarmStateCleared
+	default:
+		a.State = AlarmStateLatched
+	}
+}
+
+// CreateAlarmDefinition inserts a new alarm definition
+func (p *PostgresClient) CreateAlarmDefinition(ctx context.Context, def *AlarmDefinition) error {
+	condJSON, err := marshalAlarmCondition(def.Condition)
+	if err != nil {
+		return err
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        INSERT INTO alarm_definitions (alarm_name, alarm_text, priority, latch, enabled, condition, event_type)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+        RETURNING id, created_at, updated_at
+    `, def.Name, def.Text, def.Priority, def.Latch, def.Enabled, condJSON, def.EventType).Scan(&def.ID, &def.CreatedAt, &def.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", ErrAlarmDefinitionExists, def.Name)
+		}
+		return fmt.Errorf("failed to insert alarm definition: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAlarmDefinition replaces an alarm definition
+func (p *PostgresClient) UpdateAlarmDefinition(ctx context.Context, def *AlarmDefinition) error {
+	condJSON, err := marshalAlarmCondition(def.Condition)
+	if err != nil {
+		return err
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        UPDATE alarm_definitions
+        SET alarm_name = $2, alarm_text = $3, priority = $4, latch = $5, enabled = $6,
+            condition = $7, event_type = NULLIF($8, ''), updated_at = NOW()
+        WHERE id = $1
+        RETURNING created_at, updated_at
+    `, def.ID, def.Name, def.Text, def.Priority, def.Latch, def.Enabled, condJSON, def.EventType).Scan(&def.CreatedAt, &def.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrAlarmDefinitionNotFound, def.ID)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", ErrAlarmDefinitionExists, def.Name)
+		}
+		return fmt.Errorf("failed to update alarm definition: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAlarmDefinition removes an alarm definition. Its history is kept.
+func (p *PostgresClient) DeleteAlarmDefinition(ctx context.Context, id uuid.UUID) error {
+	result, err := p.pool.Exec(ctx, `
+        DELETE FROM alarm_definitions
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alarm definition: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrAlarmDefinitionNotFound, id)
+	}
+	return nil
+}
+
+// GetAlarmDefinition loads an alarm definition by ID
+func (p *PostgresClient) GetAlarmDefinition(ctx context.Context, id uuid.UUID) (*AlarmDefinition, error) {
+	row := p.pool.QueryRow(ctx, `
+        SELECT id, alarm_name, alarm_text, priority, latch, enabled, condition, COALESCE(event_type, ''), created_at, updated_at
+        FROM alarm_definitions
+        WHERE id = $1
+    `, id)
+
+	def, err := scanAlarmDefinition(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrAlarmDefinitionNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to load alarm definition: %w", err)
+	}
+	return def, nil
+}
+
+// ListAlarmDefinitions returns all alarm definitions ordered by name
+func (p *PostgresClient) ListAlarmDefinitions(ctx context.Context) ([]AlarmDefinition, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, alarm_name, alarm_text, priority, latch, enabled, condition, COALESCE(event_type, ''), created_at, updated_at
+        FROM alarm_definitions
+        ORDER BY alarm_name
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alarm definitions: %w", err)
+	}
+	defer rows.Close()
+
+	defs := make([]AlarmDefinition, 0)
+	for rows.Next() {
+		def, err := scanAlarmDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alarm definition: %w", err)
+		}
+		defs = append(defs, *def)
+	}
+
+	return defs, rows.Err()
+}
+
+// CreateAlarm records a raised alarm
+func (p *PostgresClient) CreateAlarm(ctx context.Context, alarm *Alarm) error {
+	detailsJSON, err := json.Marshal(alarm.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alarm details: %w", err)
+	}
+
+	_, err = p.pool.Exec(ctx, `
+        INSERT INTO alarm_history (id, definition_id, alarm_name, alarm_text, priority, latch, details, raised_at, cleared_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `, alarm.ID, alarm.DefinitionID, alarm.Name, alarm.Text, alarm.Priority, alarm.Latch, detailsJSON, alarm.RaisedAt, alarm.ClearedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert alarm: %w", err)
+	}
+	return nil
+}
+
+// UpdateAlarm stores the clear, acknowledge and close timestamps of an alarm
+func (p *PostgresClient) UpdateAlarm(ctx context.Context, alarm *Alarm) error {
+	_, err := p.pool.Exec(ctx, `
+        UPDATE alarm_history
+        SET cleared_at = $2, acknowledged_at = $3, acknowledged_by = NULLIF($4, ''),
+            closed_at = $5, closed_by = NULLIF($6, '')
+        WHERE id = $1
+    `, alarm.ID, alarm.ClearedAt, alarm.AcknowledgedAt, alarm.AcknowledgedBy, alarm.ClosedAt, alarm.ClosedBy)
+	if err != nil {
+		return fmt.Errorf("failed to update alarm: %w", err)
+	}
+	return nil
+}
+
+// ListOpenAlarms returns all alarms not closed yet, oldest first
+func (p *PostgresClient) ListOpenAlarms(ctx context.Context) ([]Alarm, error) {
+	return p.queryAlarms(ctx, `
+        SELECT id, definition_id, alarm_name, alarm_text, priority, latch, details, raised_at,
+               cleared_at, acknowledged_at, COALESCE(acknowledged_by, ''), closed_at, COALESCE(closed_by, '')
+        FROM alarm_history
+        WHERE closed_at IS NULL
+        ORDER BY raised_at
+    `)
+}
+
+// ListAlarmHistory returns alarm occurrences, newest first
+func (p *PostgresClient) ListAlarmHistory(ctx context.Context, filter AlarmHistoryFilter) ([]Alarm, error) {
+	return p.queryAlarms(ctx, `
+        SELECT id, definition_id, alarm_name, alarm_text, priority, latch, details, raised_at,
+               cleared_at, acknowledged_at, COALESCE(acknowledged_by, ''), closed_at, COALESCE(closed_by, '')
+        FROM alarm_history
+        WHERE ($1 = '' OR alarm_name = $1)
+          AND ($2::timestamp IS NULL OR raised_at >= $2)
+          AND ($3::timestamp IS NULL OR raised_at < $3)
+        ORDER BY raised_at DESC
+        LIMIT $4
+    `, filter.Name, filter.From, filter.To, filter.Limit)
+}
+
+func (p *PostgresClient) queryAlarms(ctx context.Context, query string, args ...any) ([]Alarm, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alarms: %w", err)
+	}
+	defer rows.Close()
+
+	alarms := make([]Alarm, 0)
+	for rows.Next() {
+		var alarm Alarm
+		var detailsJSON []byte
+		if err := rows.Scan(
+			&alarm.ID,
+			&alarm.DefinitionID,
+			&alarm.Name,
+			&alarm.Text,
+			&alarm.Priority,
+			&alarm.Latch,
+			&detailsJSON,
+			&alarm.RaisedAt,
+			&alarm.ClearedAt,
+			&alarm.AcknowledgedAt,
+			&alarm.AcknowledgedBy,
+			&alarm.ClosedAt,
+			&alarm.ClosedBy,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan alarm: %w", err)
+		}
+		if len(detailsJSON) > 0 {
+			if err := json.Unmarshal(detailsJSON, &alarm.Details); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal alarm details: %w", err)
+			}
+		}
+		alarm.UpdateState()
+		alarms = append(alarms, alarm)
+	}
+
+	return alarms, rows.Err()
+}
+
+func scanAlarmDefinition(row pgx.Row) (*AlarmDefinition, error) {
+	var def AlarmDefinition
+	var condJSON []byte
+	if err := row.Scan(
+		&def.ID,
+		&def.Name,
+		&def.Text,
+		&def.Priority,
+		&def.Latch,
+		&def.Enabled,
+		&condJSON,
+		&def.EventType,
+		&def.CreatedAt,
+		&def.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if len(condJSON) > 0 {
+		def.Condition = &AlarmCondition{}
+		if err := json.Unmarshal(condJSON, def.Condition); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal alarm condition: %w", err)
+		}
+	}
+	return &def, nil
+}
+
+func marshalAlarmCondition(cond *AlarmCondition) ([]byte, error) {
+	if cond == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(cond)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal alarm condition: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// RecordAudit appends an entry to the audit log
+func (p *PostgresClient) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	detailsJSON, err := json.Marshal(entry.Details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit details: %w", err)
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        INSERT INTO audit_log (action, actor, details)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `, entry.Action, entry.Actor, detailsJSON).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns audit entries, newest first. An action ending in "*"
+// matches by prefix, e.g. "shift.*".
+func (p *PostgresClient) ListAudit(ctx context.Context, action string, limit int) ([]AuditEntry, error) {
+	pattern := action
+	if n := len(pattern); n > 0 && pattern[n-1] == '*' {
+		pattern = pattern[:n-1] + "%"
+	}
+
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, action, actor, details, created_at
+        FROM audit_log
+        WHERE ($1 = '' OR action LIKE $1)
+        ORDER BY created_at DESC
+        LIMIT $2
+    `, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var entry AuditEntry
+		var detailsJSON []byte
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.Actor, &detailsJSON, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if len(detailsJSON) > 0 {
+			if err := json.Unmarshal(detailsJSON, &entry.Details); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit details: %w", err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
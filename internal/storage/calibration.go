@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+)
+
+var ErrCalibrationNotFound = errors.New("calibration not found")
+
+// SetCalibration stores the calibration of a device register and records the
+// change in the calibration history
+func (p *PostgresClient) SetCalibration(ctx context.Context, cal *DeviceCalibration) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO device_calibrations (device_name, register_name, "offset", gain, note, updated_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (device_name, register_name)
+        DO UPDATE SET
+            "offset" = EXCLUDED."offset",
+            gain = EXCLUDED.gain,
+            note = EXCLUDED.note,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = NOW()
+        RETURNING updated_at
+    `, cal.DeviceName, cal.RegisterName, cal.Offset, cal.Gain, cal.Note, cal.UpdatedBy).Scan(&cal.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save calibration: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO device_calibration_history (device_name, register_name, action, "offset", gain, note, changed_by, changed_at)
+        VALUES ($1, $2, 'set', $3, $4, $5, $6, $7)
+    `, cal.DeviceName, cal.RegisterName, cal.Offset, cal.Gain, cal.Note, cal.UpdatedBy, cal.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record calibration history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClearCalibration removes the calibration of a device register and records
+// the change in the calibration history
+func (p *PostgresClient) ClearCalibration(ctx context.Context, deviceName, registerName, actor, note string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+        DELETE FROM device_calibrations
+        WHERE device_name = $1 AND register_name = $2
+    `, deviceName, registerName)
+	if err != nil {
+		return fmt.Errorf("failed to delete calibration: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrCalibrationNotFound, deviceName, registerName)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO device_calibration_history (device_name, register_name, action, note, changed_by)
+        VALUES ($1, $2, 'cleared', $3, $4)
+    `, deviceName, registerName, note, actor)
+	if err != nil {
+		return fmt.Errorf("failed to record calibration history: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListCalibrations returns the active calibrations of a device ordered by register
+func (p *PostgresClient) ListCalibrations(ctx context.Context, deviceName string) ([]DeviceCalibration, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT device_name, register_name, "offset", gain, note, updated_by, updated_at
+        FROM device_calibrations
+        WHERE device_name = $1
+        ORDER BY register_name
+    `, deviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibrations: %w", err)
+	}
+	defer rows.Close()
+
+	cals := make([]DeviceCalibration, 0)
+	for rows.Next() {
+		var cal DeviceCalibration
+		if err := rows.Scan(
+			&cal.DeviceName,
+			&cal.RegisterName,
+			&cal.Offset,
+			&cal.Gain,
+			&cal.Note,
+			&cal.UpdatedBy,
+			&cal.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration: %w", err)
+		}
+		cals = append(cals, cal)
+	}
+
+	return cals, rows.Err()
+}
+
+// DeviceCalibrations returns the active calibrations of a device by register
+// name. It is the calibration source of the device manager.
+func (p *PostgresClient) DeviceCalibrations(ctx context.Context, deviceName string) (map[string]types.Calibration, error) {
+	cals, err := p.ListCalibrations(ctx, deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]types.Calibration, len(cals))
+	for _, cal := range cals {
+		result[cal.RegisterName] = types.Calibration{Offset: cal.Offset, Gain: cal.Gain}
+	}
+	return result, nil
+}
+
+// CalibrationHistory returns the calibration changes of a device, newest
+// first. An empty register name returns the history of all registers.
+func (p *PostgresClient) CalibrationHistory(ctx context.Context, deviceName, registerName string, limit int) ([]CalibrationChange, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, device_name, register_name, action, "offset", gain, note, changed_by, changed_at
+        FROM device_calibration_history
+        WHERE device_name = $1 AND ($2 = '' OR register_name = $2)
+        ORDER BY changed_at DESC
+        LIMIT $3
+    `, deviceName, registerName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calibration history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]CalibrationChange, 0)
+	for rows.Next() {
+		var change CalibrationChange
+		if err := rows.Scan(
+			&change.ID,
+			&change.DeviceName,
+			&change.RegisterName,
+			&change.Action,
+			&change.Offset,
+			&change.Gain,
+			&change.Note,
+			&change.ChangedBy,
+			&change.ChangedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
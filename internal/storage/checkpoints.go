@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ExecutionCheckpoint is the latest saved state of an execution
+type ExecutionCheckpoint struct {
+	ExecutionID        uuid.UUID                 `json:"execution_id"`
+	Sequence           int                       `json:"sequence"`
+	StepIndex          int                       `json:"step_index"` // Next top-level step to run
+	StepName           string                    `json:"step_name,omitempty"`
+	HierarchicalStepID string                    `json:"hierarchical_step_id,omitempty"`
+	Variables          map[string]string         `json:"-"`
+	Results            map[string]map[string]any `json:"-"` // Outputs of completed top-level steps by step number
+	Jumps              map[string]int            `json:"-"` // Jumps taken per transition of the top-level steps
+	ResumedAt          *time.Time                `json:"resumed_at,omitempty"`
+	CreatedAt          time.Time                 `json:"created_at"`
+}
+
+// SaveExecutionCheckpoint replaces the checkpoint of an execution and sets
+// its sequence and creation time
+func (p *PostgresClient) SaveExecutionCheckpoint(ctx context.Context, cp *ExecutionCheckpoint) error {
+	variablesJSON, err := json.Marshal(cp.Variables)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint variables: %w", err)
+	}
+	resultsJSON, err := json.Marshal(cp.Results)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint results: %w", err)
+	}
+	jumps := cp.Jumps
+	if jumps == nil {
+		jumps = map[string]int{}
+	}
+	jumpsJSON, err := json.Marshal(jumps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint jumps: %w", err)
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        INSERT INTO execution_checkpoints (execution_id, sequence, step_index, step_name, hierarchical_step_id, variables, results, jumps)
+        VALUES ($1, 1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7)
+        ON CONFLICT (execution_id) DO UPDATE SET
+            sequence = execution_checkpoints.sequence + 1,
+            step_index = EXCLUDED.step_index,
+            step_name = EXCLUDED.step_name,
+            hierarchical_step_id = EXCLUDED.hierarchical_step_id,
+            variables = EXCLUDED.variables,
+            results = EXCLUDED.results,
+            jumps = EXCLUDED.jumps,
+            created_at = NOW()
+        RETURNING sequence, resumed_at, created_at
+    `, cp.ExecutionID, cp.StepIndex, cp.StepName, cp.HierarchicalStepID, variablesJSON, resultsJSON, jumpsJSON).
+		Scan(&cp.Sequence, &cp.ResumedAt, &cp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save execution checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetExecutionCheckpoint returns the checkpoint of an execution, nil if none was saved
+func (p *PostgresClient) GetExecutionCheckpoint(ctx context.Context, executionID uuid.UUID) (*ExecutionCheckpoint, error) {
+	var cp ExecutionCheckpoint
+	var variablesJSON, resultsJSON, jumpsJSON []byte
+	err := p.pool.QueryRow(ctx, `
+        SELECT execution_id, sequence, step_index, COALESCE(step_name, ''), COALESCE(hierarchical_step_id, ''),
+               variables, results, jumps, resumed_at, created_at
+        FROM execution_checkpoints
+        WHERE execution_id = $1
+    `, executionID).Scan(&cp.ExecutionID, &cp.Sequence, &cp.StepIndex, &cp.StepName, &cp.HierarchicalStepID,
+		&variablesJSON, &resultsJSON, &jumpsJSON, &cp.ResumedAt, &cp.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution checkpoint: %w", err)
+	}
+
+	if len(variablesJSON) > 0 {
+		if err := json.Unmarshal(variablesJSON, &cp.Variables); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint variables: %w", err)
+		}
+	}
+	if err := json.Unmarshal(resultsJSON, &cp.Results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint results: %w", err)
+	}
+	if err := json.Unmarshal(jumpsJSON, &cp.Jumps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint jumps: %w", err)
+	}
+	return &cp, nil
+}
+
+// MarkCheckpointResumed records that an execution was resumed from its checkpoint
+func (p *PostgresClient) MarkCheckpointResumed(ctx context.Context, executionID uuid.UUID) error {
+	_, err := p.pool.Exec(ctx, `
+        UPDATE execution_checkpoints SET resumed_at = NOW() WHERE execution_id = $1
+    `, executionID)
+	if err != nil {
+		return fmt.Errorf("failed to mark checkpoint resumed: %w", err)
+	}
+	return nil
+}
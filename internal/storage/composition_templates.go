@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrCompositionTemplateNotFound = errors.New("composition template not found")
+	ErrCompositionTemplateExists   = errors.New("composition template already exists")
+)
+
+// SaveCompositionTemplate inserts a new composition template
+func (p *PostgresClient) SaveCompositionTemplate(ctx context.Context, tmpl *CompositionTemplate) error {
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO composition_templates (template_name, description, definition)
+        VALUES ($1, $2, $3)
+        RETURNING id, version, created_at, updated_at
+    `, tmpl.Name, tmpl.Description, tmpl.Definition).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", ErrCompositionTemplateExists, tmpl.Name)
+		}
+		return fmt.Errorf("failed to insert composition template: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCompositionTemplate loads a composition template by name
+func (p *PostgresClient) LoadCompositionTemplate(ctx context.Context, name string) (*CompositionTemplate, error) {
+	var tmpl CompositionTemplate
+	err := p.pool.QueryRow(ctx, `
+        SELECT id, template_name, description, definition, version, created_at, updated_at
+        FROM composition_templates
+        WHERE template_name = $1
+    `, name).Scan(
+		&tmpl.ID,
+		&tmpl.Name,
+		&tmpl.Description,
+		&tmpl.Definition,
+		&tmpl.Version,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrCompositionTemplateNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to load composition template: %w", err)
+	}
+
+	return &tmpl, nil
+}
+
+// ListCompositionTemplates returns all composition templates ordered by name
+func (p *PostgresClient) ListCompositionTemplates(ctx context.Context) ([]CompositionTemplate, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, template_name, description, definition, version, created_at, updated_at
+        FROM composition_templates
+        ORDER BY template_name
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query composition templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]CompositionTemplate, 0)
+	for rows.Next() {
+		var tmpl CompositionTemplate
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Description, &tmpl.Definition,
+			&tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpdateCompositionTemplate replaces the description and definition of a
+// composition template and increments its version. Devices created from it
+// keep their composition.
+func (p *PostgresClient) UpdateCompositionTemplate(ctx context.Context, tmpl *CompositionTemplate) error {
+	err := p.pool.QueryRow(ctx, `
+        UPDATE composition_templates
+        SET description = $2, definition = $3, version = version + 1, updated_at = NOW()
+        WHERE template_name = $1
+        RETURNING id, version, created_at, updated_at
+    `, tmpl.Name, tmpl.Description, tmpl.Definition).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrCompositionTemplateNotFound, tmpl.Name)
+		}
+		return fmt.Errorf("failed to update composition template: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCompositionTemplate removes a composition template
+func (p *PostgresClient) DeleteCompositionTemplate(ctx context.Context, name string) error {
+	tag, err := p.pool.Exec(ctx, `
+        DELETE FROM composition_templates WHERE template_name = $1
+    `, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete composition template: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrCompositionTemplateNotFound, name)
+	}
+
+	return nil
+}
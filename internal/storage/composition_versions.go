@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrCompositionVersionNotFound = errors.New("composition version not found")
+
+// CompositionVersion is a saved composition of a device
+type CompositionVersion struct {
+	ID          uuid.UUID               `json:"id"`
+	InstanceID  string                  `json:"instance_id"`
+	Version     int                     `json:"version"`
+	Composition types.CompositionConfig `json:"composition"`
+	IOMapping   map[string]string       `json:"io_mapping"`
+	Note        string                  `json:"note"`
+	ChangedBy   string                  `json:"changed_by"`
+	CreatedAt   time.Time               `json:"created_at"`
+}
+
+// DeviceComposition returns the version as composition of its device
+func (v *CompositionVersion) DeviceComposition() types.DeviceComposition {
+	return types.DeviceComposition{
+		InstanceID:  v.InstanceID,
+		Composition: v.Composition,
+		IOMapping:   v.IOMapping,
+	}
+}
+
+// recordCompositionVersion adds the composition as next version of its
+// device, unless it equals the latest version. Returns the version number.
+func recordCompositionVersion(ctx context.Context, tx pgx.Tx, instanceID string, compJSON, ioMappingJSON []byte, changedBy, note string) (int, error) {
+	var latest int
+	var unchanged bool
+	err := tx.QueryRow(ctx, `
+        SELECT version, composition = $2::jsonb AND io_mapping = $3::jsonb
+        FROM device_composition_versions
+        WHERE instance_id = $1
+        ORDER BY version DESC
+        LIMIT 1
+        FOR UPDATE
+    `, instanceID, compJSON, ioMappingJSON).Scan(&latest, &unchanged)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, fmt.Errorf("failed to load latest composition version: %w", err)
+	}
+	if unchanged {
+		return latest, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO device_composition_versions (instance_id, version, composition, io_mapping, note, changed_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, instanceID, latest+1, compJSON, ioMappingJSON, note, changedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record composition version: %w", err)
+	}
+	return latest + 1, nil
+}
+
+// ListCompositionVersions returns the composition versions of a device,
+// newest first
+func (p *PostgresClient) ListCompositionVersions(ctx context.Context, instanceID string) ([]CompositionVersion, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, instance_id, version, composition, io_mapping, note, changed_by, created_at
+        FROM device_composition_versions
+        WHERE instance_id = $1
+        ORDER BY version DESC
+    `, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query composition versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]CompositionVersion, 0)
+	for rows.Next() {
+		version, err := scanCompositionVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetCompositionVersion loads a composition version of a device; version 0
+// is the latest
+func (p *PostgresClient) GetCompositionVersion(ctx context.Context, instanceID string, version int) (*CompositionVersion, error) {
+	row := p.pool.QueryRow(ctx, `
+        SELECT id, instance_id, version, composition, io_mapping, note, changed_by, created_at
+        FROM device_composition_versions
+        WHERE instance_id = $1 AND ($2 = 0 OR version = $2)
+        ORDER BY version DESC
+        LIMIT 1
+    `, instanceID, version)
+
+	result, err := scanCompositionVersion(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s version %d", ErrCompositionVersionNotFound, instanceID, version)
+	}
+	return result, err
+}
+
+func scanCompositionVersion(row pgx.Row) (*CompositionVersion, error) {
+	var version CompositionVersion
+	var compJSON, ioMappingJSON []byte
+	if err := row.Scan(
+		&version.ID,
+		&version.InstanceID,
+		&version.Version,
+		&compJSON,
+		&ioMappingJSON,
+		&version.Note,
+		&version.ChangedBy,
+		&version.CreatedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan composition version: %w", err)
+	}
+
+	if err := json.Unmarshal(compJSON, &version.Composition); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal composition: %w", err)
+	}
+	if err := json.Unmarshal(ioMappingJSON, &version.IOMapping); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal io_mapping: %w", err)
+	}
+	return &version, nil
+}
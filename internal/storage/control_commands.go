@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimControlCommand records a control command before it is executed. It
+// returns false without recording if the actor already used the request ID.
+func (p *PostgresClient) ClaimControlCommand(ctx context.Context, cmd *ControlCommand) (bool, error) {
+	paramsJSON, err := json.Marshal(cmd.Params)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal control command params: %w", err)
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        INSERT INTO control_commands (actor, request_id, command, params)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (actor, request_id) DO NOTHING
+        RETURNING id, created_at
+    `, cmd.Actor, cmd.RequestID, cmd.Command, paramsJSON).Scan(&cmd.ID, &cmd.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert control command: %w", err)
+	}
+	return true, nil
+}
+
+// GetControlCommand returns the control command of an actor by request ID
+func (p *PostgresClient) GetControlCommand(ctx context.Context, actor, requestID string) (*ControlCommand, error) {
+	var cmd ControlCommand
+	var paramsJSON, resultJSON []byte
+	var status *string
+	err := p.pool.QueryRow(ctx, `
+        SELECT id, actor, request_id, command, params, status, result, created_at, completed_at
+        FROM control_commands
+        WHERE actor = $1 AND request_id = $2
+    `, actor, requestID).Scan(&cmd.ID, &cmd.Actor, &cmd.RequestID, &cmd.Command, &paramsJSON,
+		&status, &resultJSON, &cmd.CreatedAt, &cmd.CompletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get control command: %w", err)
+	}
+
+	if status != nil {
+		cmd.Status = *status
+	}
+	if err := json.Unmarshal(paramsJSON, &cmd.Params); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal control command params: %w", err)
+	}
+	if len(resultJSON) > 0 {
+		if err := json.Unmarshal(resultJSON, &cmd.Result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal control command result: %w", err)
+		}
+	}
+	return &cmd, nil
+}
+
+// CompleteControlCommand stores the outcome of an executed control command
+func (p *PostgresClient) CompleteControlCommand(ctx context.Context, id int64, status string, result map[string]any) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal control command result: %w", err)
+	}
+
+	_, err = p.pool.Exec(ctx, `
+        UPDATE control_commands
+        SET status = $2, result = $3, completed_at = NOW()
+        WHERE id = $1
+    `, id, status, resultJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update control command: %w", err)
+	}
+	return nil
+}
+
+// DeleteControlCommandsBefore removes control commands created before a
+// time; their request IDs can be used again
+func (p *PostgresClient) DeleteControlCommandsBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM control_commands WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete control commands: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListCounterTotals returns the stored counter totals by counter name
+func (p *PostgresClient) ListCounterTotals(ctx context.Context) (map[string]CounterTotal, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT counter_name, device_name, register_name, total, last_value, rollovers, resets, updated_at
+        FROM counter_totals
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query counter totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]CounterTotal)
+	for rows.Next() {
+		var ct CounterTotal
+		if err := rows.Scan(&ct.Name, &ct.Device, &ct.Register, &ct.Total, &ct.LastValue,
+			&ct.Rollovers, &ct.Resets, &ct.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan counter total: %w", err)
+		}
+		totals[ct.Name] = ct
+	}
+
+	return totals, rows.Err()
+}
+
+// SaveCounterTotals stores the totals and appends them with their rates to
+// the counter history
+func (p *PostgresClient) SaveCounterTotals(ctx context.Context, totals []CounterTotal, rates map[string]float64, at time.Time) error {
+	if len(totals) == 0 {
+		return nil
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, ct := range totals {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO counter_totals (counter_name, device_name, register_name, total, last_value, rollovers, resets, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            ON CONFLICT (counter_name) DO UPDATE
+            SET device_name = EXCLUDED.device_name,
+                register_name = EXCLUDED.register_name,
+                total = EXCLUDED.total,
+                last_value = EXCLUDED.last_value,
+                rollovers = EXCLUDED.rollovers,
+                resets = EXCLUDED.resets,
+                updated_at = EXCLUDED.updated_at
+        `, ct.Name, ct.Device, ct.Register, ct.Total, ct.LastValue, ct.Rollovers, ct.Resets, at)
+		if err != nil {
+			return fmt.Errorf("failed to store counter total: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+            INSERT INTO counter_history (counter_name, sampled_at, total, rate)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (counter_name, sampled_at) DO NOTHING
+        `, ct.Name, at, ct.Total, rates[ct.Name])
+		if err != nil {
+			return fmt.Errorf("failed to store counter sample: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CounterHistory returns the samples of a counter between from and to,
+// newest first
+func (p *PostgresClient) CounterHistory(ctx context.Context, name string, from, to time.Time, limit int) ([]CounterSample, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT sampled_at, total, rate
+        FROM counter_history
+        WHERE counter_name = $1 AND sampled_at >= $2 AND sampled_at < $3
+        ORDER BY sampled_at DESC
+        LIMIT $4
+    `, name, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query counter history: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]CounterSample, 0)
+	for rows.Next() {
+		var cs CounterSample
+		if err := rows.Scan(&cs.SampledAt, &cs.Total, &cs.Rate); err != nil {
+			return nil, fmt.Errorf("failed to scan counter sample: %w", err)
+		}
+		samples = append(samples, cs)
+	}
+
+	return samples, rows.Err()
+}
@@ -32,7 +32,7 @@ func (p *PostgresClient) SaveDeviceComposition(ctx context.Context, comp types.D
 	var deviceID uuid.UUID
 	err = tx.QueryRow(ctx, `
 		INSERT INTO devices (device_name, ip_address, port, unit_id, enabled)
-		VALUES ($1, $2, $3, $4, $5)
+		VALUES ($1, NULLIF($2::text, '')::inet, $3, $4, $5)
 		RETURNING id
 	`, comp.InstanceID,
 		comp.Composition.Coupler.IPAddress,
@@ -55,6 +55,10 @@ func (p *PostgresClient) SaveDeviceComposition(ctx context.Context, comp types.D
 		return uuid.Nil, fmt.Errorf("failed to save composition: %w", err)
 	}
 
+	if _, err := recordCompositionVersion(ctx, tx, comp.InstanceID, compJSON, ioMappingJSON, "", ""); err != nil {
+		return uuid.Nil, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -104,14 +108,31 @@ func (p *PostgresClient) LoadAllDeviceCompositions(ctx context.Context) ([]types
 	return compositions, nil
 }
 
-// DeleteDevice removes a device from database
+// DeleteDevice removes a device and its composition from database.
+// Returns a *ReferenceError if workflow steps still address the device.
 func (p *PostgresClient) DeleteDevice(ctx context.Context, instanceID string) error {
+	refs, err := p.DeviceReferences(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to check device references: %w", err)
+	}
+	if len(refs) > 0 {
+		return &ReferenceError{
+			Resource:     "device",
+			ID:           instanceID,
+			ReferencedBy: refs,
+			Guidance:     "Update or delete the workflows using this device before deleting it",
+		}
+	}
+
 	result, err := p.pool.Exec(ctx, `
 		DELETE FROM devices 
 		WHERE device_name = $1
 	`, instanceID)
 
 	if err != nil {
+		if refErr := asReferenceError(err, "device", instanceID); refErr != err {
+			return refErr
+		}
 		return fmt.Errorf("failed to delete device: %w", err)
 	}
 
@@ -122,29 +143,101 @@ func (p *PostgresClient) DeleteDevice(ctx context.Context, instanceID string) er
 	return nil
 }
 
-// SaveOrUpdateDeviceComposition saves or updates a device composition
-func (p *PostgresClient) SaveOrUpdateDeviceComposition(ctx context.Context, comp types.DeviceComposition) (uuid.UUID, error) {
+// UpdateDeviceIdentity stores the identification read from a connected device
+func (p *PostgresClient) UpdateDeviceIdentity(ctx context.Context, instanceID string, identity *types.DeviceIdentity) error {
+	identityJSON, err := json.Marshal(identity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity: %w", err)
+	}
+
+	result, err := p.pool.Exec(ctx, `
+		UPDATE devices
+		SET identity = $2, identified_at = $3
+		WHERE device_name = $1
+	`, instanceID, identityJSON, identity.ReadAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to update device identity: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetDeviceReferenceIdentity stores the identification confirmed at
+// onboarding, which later identifications are compared against
+func (p *PostgresClient) SetDeviceReferenceIdentity(ctx context.Context, instanceID string, identity *types.DeviceIdentity) error {
+	identityJSON, err := json.Marshal(identity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity: %w", err)
+	}
+
+	result, err := p.pool.Exec(ctx, `
+		UPDATE devices
+		SET reference_identity = $2
+		WHERE device_name = $1
+	`, instanceID, identityJSON)
+
+	if err != nil {
+		return fmt.Errorf("failed to update device reference identity: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeviceReferenceIdentity returns the identification confirmed at
+// onboarding, nil for devices not onboarded or unknown
+func (p *PostgresClient) DeviceReferenceIdentity(ctx context.Context, instanceID string) (*types.DeviceIdentity, error) {
+	var identityJSON []byte
+	err := p.pool.QueryRow(ctx, `
+		SELECT reference_identity FROM devices WHERE device_name = $1
+	`, instanceID).Scan(&identityJSON)
+	if err == pgx.ErrNoRows || (err == nil && identityJSON == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device reference identity: %w", err)
+	}
+
+	var identity types.DeviceIdentity
+	if err := json.Unmarshal(identityJSON, &identity); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reference identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// SaveOrUpdateDeviceComposition saves or updates a device composition and
+// records it as new composition version unless it is unchanged. Returns the
+// device ID and the composition version.
+func (p *PostgresClient) SaveOrUpdateDeviceComposition(ctx context.Context, comp types.DeviceComposition, changedBy, note string) (uuid.UUID, int, error) {
 	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	compJSON, err := json.Marshal(comp.Composition)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to marshal composition: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to marshal composition: %w", err)
 	}
 
 	ioMappingJSON, err := json.Marshal(comp.IOMapping)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to marshal io_mapping: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to marshal io_mapping: %w", err)
 	}
 
 	// Upsert into devices table
 	var deviceID uuid.UUID
 	err = tx.QueryRow(ctx, `
 		INSERT INTO devices (device_name, ip_address, port, unit_id, enabled)
-		VALUES ($1, $2, $3, $4, $5)
+		VALUES ($1, NULLIF($2::text, '')::inet, $3, $4, $5)
 		ON CONFLICT (device_name) 
 		DO UPDATE SET 
 			ip_address = EXCLUDED.ip_address,
@@ -161,7 +254,7 @@ func (p *PostgresClient) SaveOrUpdateDeviceComposition(ctx context.Context, comp
 	).Scan(&deviceID)
 
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to upsert device: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to upsert device: %w", err)
 	}
 
 	// Upsert into device_compositions table
@@ -176,12 +269,17 @@ func (p *PostgresClient) SaveOrUpdateDeviceComposition(ctx context.Context, comp
 	`, deviceID, comp.InstanceID, compJSON, ioMappingJSON)
 
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to upsert composition: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to upsert composition: %w", err)
+	}
+
+	version, err := recordCompositionVersion(ctx, tx, comp.InstanceID, compJSON, ioMappingJSON, changedBy, note)
+	if err != nil {
+		return uuid.Nil, 0, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return uuid.Nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return deviceID, nil
+	return deviceID, version, nil
 }
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ExecutionEventFilter narrows execution event queries
+type ExecutionEventFilter struct {
+	EventTypes []string // Empty = all types
+	Since      int64    // Only events with a higher sequence
+	Limit      int
+}
+
+// ListExecutionEvents returns the stored events of an execution in sequence order
+func (p *PostgresClient) ListExecutionEvents(ctx context.Context, executionID uuid.UUID, filter ExecutionEventFilter) ([]ExecutionEvent, error) {
+	eventTypes := filter.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{} // NULL would match nothing
+	}
+
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, sequence, execution_id, event_type, payload, timestamp
+        FROM execution_events
+        WHERE execution_id = $1
+          AND sequence > $2
+          AND (cardinality($3::text[]) = 0 OR event_type = ANY($3))
+        ORDER BY sequence
+        LIMIT $4
+    `, executionID, filter.Since, eventTypes, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query execution events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]ExecutionEvent, 0)
+	for rows.Next() {
+		var event ExecutionEvent
+		if err := rows.Scan(&event.ID, &event.Sequence, &event.ExecutionID, &event.EventType,
+			&event.Payload, &event.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan execution event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExecutionLogEntry is a log line captured while running an execution
+type ExecutionLogEntry struct {
+	ID                 int64          `json:"id"`
+	ExecutionID        uuid.UUID      `json:"execution_id"`
+	StepID             *uuid.UUID     `json:"step_id,omitempty"`
+	StepName           string         `json:"step_name,omitempty"`
+	HierarchicalStepID string         `json:"hierarchical_step_id,omitempty"`
+	Level              string         `json:"level"`
+	Message            string         `json:"message"`
+	Fields             map[string]any `json:"fields,omitempty"` // JSONB
+	LoggedAt           time.Time      `json:"logged_at"`
+}
+
+// TraceLogMessage is the message of traced Modbus frames in execution logs
+const TraceLogMessage = "Modbus frame"
+
+// ExecutionLogFilter narrows execution log queries
+type ExecutionLogFilter struct {
+	Levels []string   // Empty = all levels
+	StepID *uuid.UUID // Lines of one step
+	Trace  bool       // Include traced Modbus frames
+	Limit  int
+}
+
+// Matches reports whether an entry passes the filter, for entries not yet stored
+func (f *ExecutionLogFilter) Matches(entry *ExecutionLogEntry) bool {
+	if f.StepID != nil && (entry.StepID == nil || *entry.StepID != *f.StepID) {
+		return false
+	}
+	if !f.Trace && entry.Message == TraceLogMessage {
+		return false
+	}
+	if len(f.Levels) == 0 {
+		return true
+	}
+	for _, level := range f.Levels {
+		if entry.Level == level {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveExecutionLogs stores captured log lines
+func (p *PostgresClient) SaveExecutionLogs(ctx context.Context, entries []ExecutionLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range entries {
+		entry := &entries[i]
+		var fieldsJSON []byte
+		if len(entry.Fields) > 0 {
+			if fieldsJSON, err = json.Marshal(entry.Fields); err != nil {
+				return fmt.Errorf("failed to marshal log fields: %w", err)
+			}
+		}
+
+		err = tx.QueryRow(ctx, `
+            INSERT INTO execution_logs (execution_id, step_id, step_name, hierarchical_step_id, level, message, fields, logged_at)
+            VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8)
+            RETURNING id
+        `, entry.ExecutionID, entry.StepID, entry.StepName, entry.HierarchicalStepID,
+			entry.Level, entry.Message, fieldsJSON, entry.LoggedAt).Scan(&entry.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert execution log: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListExecutionLogs returns the stored log lines of an execution, oldest first
+func (p *PostgresClient) ListExecutionLogs(ctx context.Context, executionID uuid.UUID, filter ExecutionLogFilter) ([]ExecutionLogEntry, error) {
+	levels := filter.Levels
+	if levels == nil {
+		levels = []string{} // NULL would match nothing
+	}
+
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, execution_id, step_id, COALESCE(step_name, ''), COALESCE(hierarchical_step_id, ''),
+               level, message, fields, logged_at
+        FROM execution_logs
+        WHERE execution_id = $1
+          AND (cardinality($2::text[]) = 0 OR level = ANY($2))
+          AND ($3::uuid IS NULL OR step_id = $3)
+          AND ($5 OR message <> $6)
+        ORDER BY logged_at, id
+        LIMIT $4
+    `, executionID, levels, filter.StepID, filter.Limit, filter.Trace, TraceLogMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query execution logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]ExecutionLogEntry, 0)
+	for rows.Next() {
+		var entry ExecutionLogEntry
+		var fieldsJSON []byte
+		if err := rows.Scan(&entry.ID, &entry.ExecutionID, &entry.StepID, &entry.StepName, &entry.HierarchicalStepID,
+			&entry.Level, &entry.Message, &fieldsJSON, &entry.LoggedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan execution log: %w", err)
+		}
+		if len(fieldsJSON) > 0 {
+			if err := json.Unmarshal(fieldsJSON, &entry.Fields); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal log fields: %w", err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
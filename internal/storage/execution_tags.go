@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AnnotateExecution adds and removes tags of an execution and merges the
+// metadata object into its metadata; keys set to null are removed. Returns
+// the resulting tags and metadata.
+func (p *PostgresClient) AnnotateExecution(ctx context.Context, id uuid.UUID, addTags, removeTags []string, metadata json.RawMessage) ([]string, json.RawMessage, error) {
+	if len(metadata) > maxMetadataLength {
+		return nil, nil, fmt.Errorf("%w: metadata exceeds %d bytes", ErrInvalidTags, maxMetadataLength)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var tags []string
+	err = tx.QueryRow(ctx, `
+        SELECT tags FROM workflow_executions WHERE id = $1 FOR UPDATE
+    `, id).Scan(&tags)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("execution not found: %s", id)
+		}
+		return nil, nil, fmt.Errorf("failed to get execution tags: %w", err)
+	}
+
+	tags = slices.DeleteFunc(append(tags, addTags...), func(tag string) bool {
+		return slices.Contains(removeTags, tag)
+	})
+	if tags, err = NormalizeTags(tags); err != nil {
+		return nil, nil, err
+	}
+
+	var merged json.RawMessage
+	err = tx.QueryRow(ctx, `
+        UPDATE workflow_executions
+        SET tags = $2, metadata = jsonb_strip_nulls(metadata || COALESCE($3::jsonb, '{}'::jsonb))
+        WHERE id = $1
+        RETURNING metadata
+    `, id, tags, metadata).Scan(&merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to annotate execution: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return tags, merged, nil
+}
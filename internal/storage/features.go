@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ListFeatureFlags returns the flags changed from their default
+func (p *PostgresClient) ListFeatureFlags(ctx context.Context) ([]FeatureFlagState, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT name, enabled, workflows, updated_by, updated_at
+        FROM feature_flags
+        ORDER BY name
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]FeatureFlagState, 0)
+	for rows.Next() {
+		var flag FeatureFlagState
+		var workflows []byte
+		if err := rows.Scan(&flag.Name, &flag.Enabled, &workflows, &flag.UpdatedBy, &flag.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		if err := json.Unmarshal(workflows, &flag.Workflows); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal workflows of feature flag %s: %w", flag.Name, err)
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, rows.Err()
+}
+
+// SaveFeatureFlag creates or replaces the state of a feature flag
+func (p *PostgresClient) SaveFeatureFlag(ctx context.Context, flag *FeatureFlagState) error {
+	workflows, err := json.Marshal(flag.Workflows)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflows: %w", err)
+	}
+
+	err = p.pool.QueryRow(ctx, `
+        INSERT INTO feature_flags (name, enabled, workflows, updated_by)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (name) DO UPDATE SET
+            enabled = EXCLUDED.enabled,
+            workflows = EXCLUDED.workflows,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = NOW()
+        RETURNING updated_at
+    `, flag.Name, flag.Enabled, workflows, flag.UpdatedBy).Scan(&flag.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeatureFlag restores the default of a feature flag
+func (p *PostgresClient) DeleteFeatureFlag(ctx context.Context, name string) error {
+	if _, err := p.pool.Exec(ctx, `
+        DELETE FROM feature_flags WHERE name = $1
+    `, name); err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListJWTSigningKeys returns the signing keys, oldest first
+func (p *PostgresClient) ListJWTSigningKeys(ctx context.Context) ([]JWTSigningKey, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT key_id, COALESCE(master_key_id, ''), encrypted_key, ciphertext, created_by, created_at, retired_at
+        FROM jwt_signing_keys
+        ORDER BY created_at
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]JWTSigningKey, 0)
+	for rows.Next() {
+		var k JWTSigningKey
+		if err := rows.Scan(&k.KeyID, &k.MasterKeyID, &k.EncryptedKey, &k.Ciphertext,
+			&k.CreatedBy, &k.CreatedAt, &k.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RotateJWTSigningKey retires the current signing key and stores key as the
+// new one. On the first rotation the configured secret (configKeyID) is
+// recorded as retired.
+func (p *PostgresClient) RotateJWTSigningKey(ctx context.Context, key *JWTSigningKey, configKeyID string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+        UPDATE jwt_signing_keys SET retired_at = NOW()
+        WHERE retired_at IS NULL
+    `); err != nil {
+		return fmt.Errorf("failed to retire signing key: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+        INSERT INTO jwt_signing_keys (key_id, created_by, retired_at)
+        VALUES ($1, 'config', NOW())
+        ON CONFLICT (key_id) DO NOTHING
+    `, configKeyID); err != nil {
+		return fmt.Errorf("failed to retire configured signing key: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO jwt_signing_keys (key_id, master_key_id, encrypted_key, ciphertext, created_by)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING created_at
+    `, key.KeyID, key.MasterKeyID, key.EncryptedKey, key.Ciphertext, key.CreatedBy).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteRetiredJWTSigningKeys removes keys retired before the given time and
+// returns the number of deleted keys. The configured secret's row is kept so
+// it stays retired.
+func (p *PostgresClient) DeleteRetiredJWTSigningKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := p.pool.Exec(ctx, `
+        DELETE FROM jwt_signing_keys
+        WHERE retired_at < $1 AND ciphertext IS NOT NULL
+    `, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete retired signing keys: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
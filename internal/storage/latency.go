@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LatencyHook returns an artificial delay for the next query, 0 for none.
+// Used by the developer-mode fault injection.
+type LatencyHook func() time.Duration
+
+// SetLatencyHook delays queries by the duration the hook returns; nil removes it
+func (p *PostgresClient) SetLatencyHook(hook LatencyHook) {
+	if hook == nil {
+		p.latency.Store(nil)
+		return
+	}
+	p.latency.Store(&hook)
+}
+
+// latencyTracer applies the latency hook before each query is sent
+type latencyTracer struct {
+	client *PostgresClient
+}
+
+func (t *latencyTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	hook := t.client.latency.Load()
+	if hook == nil {
+		return ctx
+	}
+	delay := (*hook)()
+	if delay <= 0 {
+		return ctx
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return ctx
+}
+
+func (t *latencyTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
@@ -28,6 +28,197 @@ type Device struct {
 	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
+// DeviceCalibration is the active offset/gain correction of a device register
+type DeviceCalibration struct {
+	DeviceName   string    `json:"device_name"`
+	RegisterName string    `json:"register_name"`
+	Offset       float64   `json:"offset"`
+	Gain         float64   `json:"gain"`
+	Note         string    `json:"note"`
+	UpdatedBy    string    `json:"updated_by"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CalibrationChange is an entry of the calibration history
+type CalibrationChange struct {
+	ID           uuid.UUID `json:"id"`
+	DeviceName   string    `json:"device_name"`
+	RegisterName string    `json:"register_name"`
+	Action       string    `json:"action"` // set, cleared
+	Offset       *float64  `json:"offset,omitempty"`
+	Gain         *float64  `json:"gain,omitempty"`
+	Note         string    `json:"note"`
+	ChangedBy    string    `json:"changed_by"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// AlarmDefinition binds an alarm to a register condition or an event type
+type AlarmDefinition struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	Text      string          `json:"text"`
+	Priority  string          `json:"priority"` // low, medium, high, critical
+	Latch     bool            `json:"latch"`
+	Enabled   bool            `json:"enabled"`
+	Condition *AlarmCondition `json:"condition,omitempty"`  // JSONB
+	EventType string          `json:"event_type,omitempty"` // Glob, e.g. "execution.failed"
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// AlarmCondition compares the last polled value of a device register
+type AlarmCondition struct {
+	Device   string `json:"device"`
+	Register string `json:"register"` // Logical name or register name
+	Operator string `json:"operator"` // eq, ne, gt, ge, lt, le
+	Value    any    `json:"value"`
+}
+
+// Alarm is one occurrence of an alarm from raise to close
+type Alarm struct {
+	ID             uuid.UUID      `json:"id"`
+	DefinitionID   *uuid.UUID     `json:"definition_id,omitempty"`
+	Name           string         `json:"name"`
+	Text           string         `json:"text"`
+	Priority       string         `json:"priority"`
+	Latch          bool           `json:"latch"`
+	State          string         `json:"state"`             // active, acknowledged, cleared, latched, closed
+	Details        map[string]any `json:"details,omitempty"` // JSONB
+	RaisedAt       time.Time      `json:"raised_at"`
+	ClearedAt      *time.Time     `json:"cleared_at,omitempty"`
+	AcknowledgedAt *time.Time     `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string         `json:"acknowledged_by,omitempty"`
+	ClosedAt       *time.Time     `json:"closed_at,omitempty"`
+	ClosedBy       string         `json:"closed_by,omitempty"`
+}
+
+// AlarmHistoryFilter narrows alarm history queries
+type AlarmHistoryFilter struct {
+	Name  string
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+// CounterTotal is the accumulated total of a rolling device counter
+type CounterTotal struct {
+	Name      string    `json:"name"`
+	Device    string    `json:"device"`
+	Register  string    `json:"register"`
+	Total     float64   `json:"total"`
+	LastValue *float64  `json:"last_value,omitempty"` // Last sampled register value
+	Rollovers int64     `json:"rollovers"`
+	Resets    int64     `json:"resets"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CounterSample is a point of the counter history
+type CounterSample struct {
+	SampledAt time.Time `json:"sampled_at"`
+	Total     float64   `json:"total"`
+	Rate      float64   `json:"rate"` // Units per second
+}
+
+// DeviceSnapshot holds the cached register values of a device at one point in time
+type DeviceSnapshot struct {
+	ID         uuid.UUID      `json:"id"`
+	DeviceName string         `json:"device_name"`
+	Values     map[string]any `json:"values"`
+	CapturedAt time.Time      `json:"captured_at"`
+}
+
+// ShiftPattern is a recurring weekly shift
+type ShiftPattern struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Weekday   int       `json:"weekday"` // 0 = Sunday
+	Start     string    `json:"start"`   // HH:MM
+	End       string    `json:"end"`     // HH:MM, before Start for overnight shifts
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShiftException replaces the weekly pattern on a date. Without start and
+// end there is no production on that date.
+type ShiftException struct {
+	ID        uuid.UUID `json:"id"`
+	Date      string    `json:"date"` // YYYY-MM-DD
+	Name      string    `json:"name,omitempty"`
+	Start     string    `json:"start,omitempty"`
+	End       string    `json:"end,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShiftOverride suspends automatic shift actions
+type ShiftOverride struct {
+	ID             uuid.UUID  `json:"id"`
+	SuspendedUntil time.Time  `json:"suspended_until"`
+	Reason         string     `json:"reason"`
+	CreatedBy      string     `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClearedAt      *time.Time `json:"cleared_at,omitempty"`
+	ClearedBy      string     `json:"cleared_by,omitempty"`
+}
+
+// ShiftNote is a handover note for the next shift
+type ShiftNote struct {
+	ID             uuid.UUID   `json:"id"`
+	Text           string      `json:"text"`
+	Shift          string      `json:"shift,omitempty"` // Shift running when written
+	ExecutionIDs   []uuid.UUID `json:"execution_ids"`
+	Devices        []string    `json:"devices"`
+	AlarmIDs       []uuid.UUID `json:"alarm_ids"`
+	CreatedBy      string      `json:"created_by"`
+	CreatedAt      time.Time   `json:"created_at"`
+	AcknowledgedBy string      `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time  `json:"acknowledged_at,omitempty"`
+}
+
+// Secret is an encrypted credential. The crypto fields are never serialized.
+type Secret struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	KeyID        string    `json:"key_id"`
+	EncryptedKey []byte    `json:"-"`
+	Ciphertext   []byte    `json:"-"`
+	CreatedBy    string    `json:"created_by"`
+	UpdatedBy    string    `json:"updated_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// JWTSigningKey is a rotated JWT signing key. The crypto fields are nil for
+// the configured secret.
+type JWTSigningKey struct {
+	KeyID        string     `json:"key_id"`
+	MasterKeyID  string     `json:"-"`
+	EncryptedKey []byte     `json:"-"`
+	Ciphertext   []byte     `json:"-"`
+	CreatedBy    string     `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RetiredAt    *time.Time `json:"retired_at,omitempty"`
+}
+
+// FeatureFlagState is a feature flag changed from its default
+type FeatureFlagState struct {
+	Name      string          `json:"name"`
+	Enabled   bool            `json:"enabled"`
+	Workflows map[string]bool `json:"workflows"` // Workflow ID -> enabled
+	UpdatedBy string          `json:"updated_by"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// AuditEntry records an operational action and who or what performed it
+type AuditEntry struct {
+	ID        uuid.UUID      `json:"id"`
+	Action    string         `json:"action"`
+	Actor     string         `json:"actor"`
+	Details   map[string]any `json:"details,omitempty"` // JSONB
+	CreatedAt time.Time      `json:"created_at"`
+}
+
 type IOMapping struct {
 	ID           uuid.UUID `json:"id"`
 	DeviceID     uuid.UUID `json:"device_id"`
@@ -37,10 +228,61 @@ type IOMapping struct {
 }
 
 type Workflow struct {
-	ID           uuid.UUID `json:"id"`
-	WorkflowName string    `json:"workflow_name"`
-	Definition   []byte    `json:"definition"` // JSONB
-	Active       bool      `json:"active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID           `json:"id"`
+	WorkflowName string              `json:"workflow_name"`
+	Definition   []byte              `json:"definition"` // JSONB
+	Active       bool                `json:"active"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	Validation   *WorkflowValidation `json:"validation,omitempty"` // Latest bulk validation, set by the workflow list
+}
+
+// WorkflowACL restricts who may execute or modify a workflow. Entries are
+// principals like "user:alice", "role:operator" or "token:line-hmi".
+type WorkflowACL struct {
+	WorkflowID uuid.UUID `json:"workflow_id"`
+	Owner      string    `json:"owner"`   // Full access
+	Execute    []string  `json:"execute"` // May execute
+	Modify     []string  `json:"modify"`  // May modify, activate, delete and execute
+	UpdatedBy  string    `json:"updated_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type StepTemplate struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Definition  []byte    `json:"definition"` // JSONB
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CompositionTemplate struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Definition  []byte    `json:"definition"` // JSONB
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ControlCommand is a control command of a WebSocket client
+type ControlCommand struct {
+	ID          int64          `json:"id"`
+	Actor       string         `json:"actor"`
+	RequestID   string         `json:"request_id"`
+	Command     string         `json:"command"`
+	Params      map[string]any `json:"params"`           // JSONB
+	Status      string         `json:"status,omitempty"` // Empty while executing
+	Result      map[string]any `json:"result,omitempty"` // JSONB
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// SetupState records the completion of the first-run setup
+type SetupState struct {
+	CompletedAt time.Time `json:"completed_at"`
+	CompletedBy string    `json:"completed_by"`
 }
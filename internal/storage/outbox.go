@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Outbox event sources
+const (
+	OutboxSourceWorkflow = "workflow"
+	OutboxSourceMachine  = "machine"
+	OutboxSourceDevice   = "device"
+	OutboxSourceSystem   = "system"
+)
+
+// Outbox delivery states
+const (
+	DeliveryRetrying = "retrying"
+	DeliveryDead     = "dead"
+)
+
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// OutboxEvent is an event waiting to be delivered to the integration sinks
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Source    string          `json:"source"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OutboxDelivery is a failed delivery of an event to a sink
+type OutboxDelivery struct {
+	ID            int64       `json:"id"`
+	Sink          string      `json:"sink"`
+	Event         OutboxEvent `json:"event"`
+	Status        string      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	LastError     string      `json:"last_error,omitempty"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// OutboxBacklog counts the work left for a sink
+type OutboxBacklog struct {
+	Cursor   int64 `json:"cursor"`   // Last event attempted
+	Pending  int64 `json:"pending"`  // Events after the cursor
+	Retrying int64 `json:"retrying"` // Failed deliveries waiting for a retry
+	Dead     int64 `json:"dead"`     // Dead-lettered deliveries
+}
+
+// execer is implemented by the pool and by transactions
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// SetOutboxEnabled makes CreateExecutionEvent add execution events to the outbox
+func (p *PostgresClient) SetOutboxEnabled(enabled bool) {
+	p.outbox.Store(enabled)
+}
+
+// OutboxEnabled reports whether events are added to the outbox
+func (p *PostgresClient) OutboxEnabled() bool {
+	return p.outbox.Load()
+}
+
+// AppendOutbox adds an event to the outbox
+func (p *PostgresClient) AppendOutbox(ctx context.Context, source, eventType string, payload any) error {
+	return appendOutbox(ctx, p.pool, source, eventType, payload, time.Now())
+}
+
+func appendOutbox(ctx context.Context, db execer, source, eventType string, payload any, createdAt time.Time) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	_, err = db.Exec(ctx, `
+        INSERT INTO event_outbox (source, event_type, payload, created_at)
+        VALUES ($1, $2, $3, $4)
+    `, source, eventType, payloadJSON, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to append outbox event: %w", err)
+	}
+	return nil
+}
+
+// executionOutboxPayload wraps an execution event for the outbox
+func executionOutboxPayload(event *ExecutionEvent) map[string]any {
+	payload := map[string]any{"execution_id": event.ExecutionID}
+	if len(event.Payload) > 0 {
+		payload["data"] = event.Payload
+	}
+	return payload
+}
+
+// ListOutboxEvents returns up to limit events after the given ID, oldest first
+func (p *PostgresClient) ListOutboxEvents(ctx context.Context, afterID int64, limit int) ([]OutboxEvent, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, source, event_type, payload, created_at
+        FROM event_outbox
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2
+    `, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]OutboxEvent, 0)
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Source, &e.EventType, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetOutboxCursor returns the last event a sink has attempted. A new sink
+// starts at the newest event, so it doesn't replay the whole outbox.
+func (p *PostgresClient) GetOutboxCursor(ctx context.Context, sink string) (int64, error) {
+	var cursor int64
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO outbox_cursors (sink, last_event_id)
+        VALUES ($1, (SELECT COALESCE(MAX(id), 0) FROM event_outbox))
+        ON CONFLICT (sink) DO UPDATE SET sink = EXCLUDED.sink
+        RETURNING last_event_id
+    `, sink).Scan(&cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load outbox cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// AdvanceOutboxCursor stores the last event a sink has attempted. Failed
+// deliveries must be recorded before, so none is lost on a crash.
+func (p *PostgresClient) AdvanceOutboxCursor(ctx context.Context, sink string, eventID int64) error {
+	_, err := p.pool.Exec(ctx, `
+        UPDATE outbox_cursors
+        SET last_event_id = GREATEST(last_event_id, $2), updated_at = NOW()
+        WHERE sink = $1
+    `, sink, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to advance outbox cursor: %w", err)
+	}
+	return nil
+}
+
+// SaveOutboxFailure records a failed delivery attempt of an event to a sink
+func (p *PostgresClient) SaveOutboxFailure(ctx context.Context, sink string, eventID int64, status string, attempts int, lastError string, nextAttempt time.Time) error {
+	_, err := p.pool.Exec(ctx, `
+        INSERT INTO outbox_deliveries (sink, event_id, status, attempts, last_error, next_attempt_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (sink, event_id) DO UPDATE SET
+            status = EXCLUDED.status,
+            attempts = EXCLUDED.attempts,
+            last_error = EXCLUDED.last_error,
+            next_attempt_at = EXCLUDED.next_attempt_at,
+            updated_at = NOW()
+    `, sink, eventID, status, attempts, lastError, nextAttempt)
+	if err != nil {
+		return fmt.Errorf("failed to save outbox delivery: %w", err)
+	}
+	return nil
+}
+
+// DeleteOutboxDelivery removes a delivery once it succeeded
+func (p *PostgresClient) DeleteOutboxDelivery(ctx context.Context, id int64) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM outbox_deliveries WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDueOutboxRetries returns the retrying deliveries of a sink whose next
+// attempt is due, oldest event first
+func (p *PostgresClient) ListDueOutboxRetries(ctx context.Context, sink string, limit int) ([]OutboxDelivery, error) {
+	return p.queryOutboxDeliveries(ctx, `
+        WHERE d.sink = $1 AND d.status = 'retrying' AND d.next_attempt_at <= NOW()
+        ORDER BY d.event_id
+        LIMIT $2
+    `, sink, limit)
+}
+
+// ListOutboxDeliveries returns failed deliveries, newest first. Empty sink or
+// status match all.
+func (p *PostgresClient) ListOutboxDeliveries(ctx context.Context, sink, status string, limit int) ([]OutboxDelivery, error) {
+	return p.queryOutboxDeliveries(ctx, `
+        WHERE ($1 = '' OR d.sink = $1) AND ($2 = '' OR d.status = $2)
+        ORDER BY d.updated_at DESC
+        LIMIT $3
+    `, sink, status, limit)
+}
+
+func (p *PostgresClient) queryOutboxDeliveries(ctx context.Context, where string, args ...any) ([]OutboxDelivery, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT d.id, d.sink, d.status, d.attempts, COALESCE(d.last_error, ''), d.next_attempt_at, d.created_at, d.updated_at,
+               e.id, e.source, e.event_type, e.payload, e.created_at
+        FROM outbox_deliveries d
+        JOIN event_outbox e ON e.id = d.event_id
+    `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]OutboxDelivery, 0)
+	for rows.Next() {
+		var d OutboxDelivery
+		if err := rows.Scan(&d.ID, &d.Sink, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt,
+			&d.Event.ID, &d.Event.Source, &d.Event.EventType, &d.Event.Payload, &d.Event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RequeueOutboxDelivery schedules a failed delivery for an immediate retry
+// with a fresh attempt count
+func (p *PostgresClient) RequeueOutboxDelivery(ctx context.Context, id int64) (*OutboxDelivery, error) {
+	var d OutboxDelivery
+	err := p.pool.QueryRow(ctx, `
+        UPDATE outbox_deliveries
+        SET status = 'retrying', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+        WHERE id = $1
+        RETURNING id, sink, event_id, status
+    `, id).Scan(&d.ID, &d.Sink, &d.Event.ID, &d.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue outbox delivery: %w", err)
+	}
+	return &d, nil
+}
+
+// RequeueDeadOutboxDeliveries schedules all dead deliveries of a sink for an
+// immediate retry and returns their number
+func (p *PostgresClient) RequeueDeadOutboxDeliveries(ctx context.Context, sink string) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `
+        UPDATE outbox_deliveries
+        SET status = 'retrying', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+        WHERE sink = $1 AND status = 'dead'
+    `, sink)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue outbox deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetOutboxBacklog counts the events and failed deliveries left for a sink
+func (p *PostgresClient) GetOutboxBacklog(ctx context.Context, sink string) (*OutboxBacklog, error) {
+	var b OutboxBacklog
+	err := p.pool.QueryRow(ctx, `
+        SELECT COALESCE((SELECT last_event_id FROM outbox_cursors WHERE sink = $1), 0),
+               (SELECT COUNT(*) FROM event_outbox
+                WHERE id > COALESCE((SELECT last_event_id FROM outbox_cursors WHERE sink = $1), 0)),
+               (SELECT COUNT(*) FROM outbox_deliveries WHERE sink = $1 AND status = 'retrying'),
+               (SELECT COUNT(*) FROM outbox_deliveries WHERE sink = $1 AND status = 'dead')
+    `, sink).Scan(&b.Cursor, &b.Pending, &b.Retrying, &b.Dead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox backlog: %w", err)
+	}
+	return &b, nil
+}
+
+// PurgeOutbox deletes events created before the cutoff that every given
+// sink has attempted and that have no failed delivery left
+func (p *PostgresClient) PurgeOutbox(ctx context.Context, before time.Time, sinks []string) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `
+        DELETE FROM event_outbox e
+        WHERE e.created_at < $1
+          AND e.id <= COALESCE((SELECT MIN(last_event_id) FROM outbox_cursors WHERE sink = ANY($2)), 0)
+          AND NOT EXISTS (SELECT 1 FROM outbox_deliveries d WHERE d.event_id = e.id)
+    `, before, sinks)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
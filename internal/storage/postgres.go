@@ -3,13 +3,16 @@ package storage
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresClient struct {
-	pool *pgxpool.Pool
+	pool    *pgxpool.Pool
+	latency atomic.Pointer[LatencyHook]
+	outbox  atomic.Bool // Execution events are also written to the event outbox
 }
 
 func NewPostgresClient(cfg config.DatabaseConfig) (*PostgresClient, error) {
@@ -20,6 +23,9 @@ func NewPostgresClient(cfg config.DatabaseConfig) (*PostgresClient, error) {
 
 	poolConfig.MaxConns = int32(cfg.MaxConnections)
 
+	client := &PostgresClient{}
+	poolConfig.ConnConfig.Tracer = &latencyTracer{client: client}
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pool: %w", err)
@@ -30,7 +36,8 @@ func NewPostgresClient(cfg config.DatabaseConfig) (*PostgresClient, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &PostgresClient{pool: pool}, nil
+	client.pool = pool
+	return client, nil
 }
 
 func (p *PostgresClient) Close() {
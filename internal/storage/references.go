@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgForeignKeyViolation is the Postgres SQLSTATE for foreign key violations
+const pgForeignKeyViolation = "23503"
+
+// querier is implemented by the pool and by transactions
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Reference describes a record that depends on another record
+type Reference struct {
+	Type string `json:"type"` // "workflow", "execution"
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// ReferenceError is returned when a record cannot be deleted because
+// other records still depend on it
+type ReferenceError struct {
+	Resource     string      `json:"resource"`
+	ID           string      `json:"id"`
+	ReferencedBy []Reference `json:"referenced_by,omitempty"`
+	Guidance     string      `json:"guidance"`
+}
+
+func (e *ReferenceError) Error() string {
+	if len(e.ReferencedBy) == 0 {
+		return fmt.Sprintf("%s %s is still referenced", e.Resource, e.ID)
+	}
+	refs := make([]string, 0, len(e.ReferencedBy))
+	for _, ref := range e.ReferencedBy {
+		refs = append(refs, fmt.Sprintf("%s %s", ref.Type, ref.ID))
+	}
+	return fmt.Sprintf("%s %s is referenced by %s", e.Resource, e.ID, strings.Join(refs, ", "))
+}
+
+// asReferenceError converts a foreign key violation into a ReferenceError
+func asReferenceError(err error, resource, id string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+		return &ReferenceError{
+			Resource: resource,
+			ID:       id,
+			Guidance: fmt.Sprintf("Remove the records referencing this %s first (constraint %s)", resource, pgErr.ConstraintName),
+		}
+	}
+	return err
+}
+
+// WorkflowReferences returns the workflows calling the given workflow as
+// a sub-workflow and its executions that are still pending or running
+func (p *PostgresClient) WorkflowReferences(ctx context.Context, workflowID uuid.UUID) ([]Reference, error) {
+	return workflowReferences(ctx, p.pool, workflowID)
+}
+
+func workflowReferences(ctx context.Context, q querier, workflowID uuid.UUID) ([]Reference, error) {
+	containment, err := json.Marshal(map[string]any{
+		"steps": []map[string]string{{"workflow_id": workflowID.String()}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := referencingWorkflows(ctx, q, containment, &workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, `
+        SELECT id FROM workflow_executions
+        WHERE workflow_id = $1 AND status IN ($2, $3)
+    `, workflowID, StatusPending, StatusRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query executions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		refs = append(refs, Reference{Type: "execution", ID: id.String()})
+	}
+
+	return refs, rows.Err()
+}
+
+// DeviceReferences returns the workflows with steps addressing the given device
+func (p *PostgresClient) DeviceReferences(ctx context.Context, deviceName string) ([]Reference, error) {
+	containment, err := json.Marshal(map[string]any{
+		"steps": []map[string]string{{"device_id": deviceName}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return referencingWorkflows(ctx, p.pool, containment, nil)
+}
+
+func referencingWorkflows(ctx context.Context, q querier, containment []byte, exclude *uuid.UUID) ([]Reference, error) {
+	rows, err := q.Query(ctx, `
+        SELECT id, workflow_name FROM workflows
+        WHERE definition @> $1::jsonb
+        ORDER BY workflow_name
+    `, string(containment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow references: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []Reference
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		if exclude != nil && id == *exclude {
+			continue
+		}
+		refs = append(refs, Reference{Type: "workflow", ID: id.String(), Name: name})
+	}
+
+	return refs, rows.Err()
+}
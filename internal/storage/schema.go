@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DatabaseInfo describes the database for the system status
+type DatabaseInfo struct {
+	Backend       string `json:"backend"`
+	ServerVersion string `json:"server_version"`
+	SchemaVersion int    `json:"schema_version"` // Last applied migration, 0 if unknown
+	PoolAcquired  int32  `json:"pool_acquired"`
+	PoolIdle      int32  `json:"pool_idle"`
+	PoolMax       int32  `json:"pool_max"`
+}
+
+// DatabaseInfo returns the server and schema version and the pool usage
+func (p *PostgresClient) DatabaseInfo(ctx context.Context) (*DatabaseInfo, error) {
+	stat := p.pool.Stat()
+	info := &DatabaseInfo{
+		Backend:      "postgres",
+		PoolAcquired: stat.AcquiredConns(),
+		PoolIdle:     stat.IdleConns(),
+		PoolMax:      stat.MaxConns(),
+	}
+
+	if err := p.pool.QueryRow(ctx, `SHOW server_version`).Scan(&info.ServerVersion); err != nil {
+		return nil, fmt.Errorf("failed to query server version: %w", err)
+	}
+
+	// Databases created before migration 036 have no schema_version table
+	var exists bool
+	err := p.pool.QueryRow(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema version: %w", err)
+	}
+	if !exists {
+		return info, nil
+	}
+	err = p.pool.QueryRow(ctx, `SELECT version FROM schema_version`).Scan(&info.SchemaVersion)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query schema version: %w", err)
+	}
+	return info, nil
+}
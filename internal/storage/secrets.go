@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SaveSecret creates a secret or replaces the value of an existing one and
+// reports whether it was created
+func (p *PostgresClient) SaveSecret(ctx context.Context, secret *Secret) (bool, error) {
+	var created bool
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO secrets (name, description, key_id, encrypted_key, ciphertext, created_by, updated_by)
+        VALUES ($1, $2, $3, $4, $5, $6, $6)
+        ON CONFLICT (name) DO UPDATE SET
+            description = EXCLUDED.description,
+            key_id = EXCLUDED.key_id,
+            encrypted_key = EXCLUDED.encrypted_key,
+            ciphertext = EXCLUDED.ciphertext,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = NOW()
+        RETURNING id, created_by, created_at, updated_at, (xmax = 0)
+    `, secret.Name, secret.Description, secret.KeyID, secret.EncryptedKey, secret.Ciphertext, secret.UpdatedBy).Scan(
+		&secret.ID, &secret.CreatedBy, &secret.CreatedAt, &secret.UpdatedAt, &created)
+	if err != nil {
+		return false, fmt.Errorf("failed to save secret: %w", err)
+	}
+	return created, nil
+}
+
+// GetSecret returns a secret including its encrypted value
+func (p *PostgresClient) GetSecret(ctx context.Context, name string) (*Secret, error) {
+	var s Secret
+	err := p.pool.QueryRow(ctx, `
+        SELECT id, name, description, key_id, encrypted_key, ciphertext, created_by, updated_by, created_at, updated_at
+        FROM secrets
+        WHERE name = $1
+    `, name).Scan(&s.ID, &s.Name, &s.Description, &s.KeyID, &s.EncryptedKey, &s.Ciphertext,
+		&s.CreatedBy, &s.UpdatedBy, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to load secret: %w", err)
+	}
+	return &s, nil
+}
+
+// ListSecrets returns all secrets without their encrypted values, ordered by name
+func (p *PostgresClient) ListSecrets(ctx context.Context) ([]Secret, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, name, description, key_id, created_by, updated_by, created_at, updated_at
+        FROM secrets
+        ORDER BY name
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secrets: %w", err)
+	}
+	defer rows.Close()
+
+	secrets := make([]Secret, 0)
+	for rows.Next() {
+		var s Secret
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.KeyID,
+			&s.CreatedBy, &s.UpdatedBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan secret: %w", err)
+		}
+		secrets = append(secrets, s)
+	}
+	return secrets, rows.Err()
+}
+
+// DeleteSecret removes a secret
+func (p *PostgresClient) DeleteSecret(ctx context.Context, name string) error {
+	result, err := p.pool.Exec(ctx, `
+        DELETE FROM secrets
+        WHERE name = $1
+    `, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return nil
+}
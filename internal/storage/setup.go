@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSetupCompleted = errors.New("setup already completed")
+	ErrUsersExist     = errors.New("users already exist")
+)
+
+// GetSetupState returns the completed setup, nil while setup is pending
+func (p *PostgresClient) GetSetupState(ctx context.Context) (*SetupState, error) {
+	var state SetupState
+	err := p.pool.QueryRow(ctx, `
+        SELECT completed_at, completed_by FROM setup_state
+    `).Scan(&state.CompletedAt, &state.CompletedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get setup state: %w", err)
+	}
+	return &state, nil
+}
+
+// CountUsers returns the number of users
+func (p *PostgresClient) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	if err := p.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// CreateFirstUser creates a user only if there is none and setup is
+// pending. The users table is locked, so concurrent calls create one user.
+func (p *PostgresClient) CreateFirstUser(ctx context.Context, username, passwordHash, role string) (*User, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	var completed, usersExist bool
+	err = tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM setup_state), EXISTS (SELECT 1 FROM users)
+    `).Scan(&completed, &usersExist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check users: %w", err)
+	}
+	if completed {
+		return nil, ErrSetupCompleted
+	}
+	if usersExist {
+		return nil, ErrUsersExist
+	}
+
+	var user User
+	err = tx.QueryRow(ctx, `
+        INSERT INTO users (username, password_hash, role)
+        VALUES ($1, $2, $3)
+        RETURNING id, username, role, created_at, last_login_at, failed_login_attempts, locked_until
+    `, username, passwordHash, role).Scan(
+		&user.ID, &user.Username, &user.Role, &user.CreatedAt,
+		&user.LastLoginAt, &user.FailedLoginAttempts, &user.LockedUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &user, nil
+}
+
+// CompleteSetup marks the setup complete, ErrSetupCompleted if it already is
+func (p *PostgresClient) CompleteSetup(ctx context.Context, actor string) (*SetupState, error) {
+	var state SetupState
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO setup_state (completed_by)
+        VALUES ($1)
+        ON CONFLICT (id) DO NOTHING
+        RETURNING completed_at, completed_by
+    `, actor).Scan(&state.CompletedAt, &state.CompletedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSetupCompleted
+		}
+		return nil, fmt.Errorf("failed to complete setup: %w", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrShiftNoteNotFound = errors.New("shift note not found")
+
+// ShiftNoteFilter narrows shift note queries
+type ShiftNoteFilter struct {
+	From           *time.Time
+	To             *time.Time
+	Unacknowledged bool
+	Limit          int // 0 = no limit
+}
+
+const shiftNoteColumns = `id, note_text, shift_name, execution_ids, devices, alarm_ids,
+               created_by, created_at, COALESCE(acknowledged_by, ''), acknowledged_at`
+
+func scanShiftNote(row pgx.Row, sn *ShiftNote) error {
+	return row.Scan(&sn.ID, &sn.Text, &sn.Shift, &sn.ExecutionIDs, &sn.Devices, &sn.AlarmIDs,
+		&sn.CreatedBy, &sn.CreatedAt, &sn.AcknowledgedBy, &sn.AcknowledgedAt)
+}
+
+// CreateShiftNote stores a handover note
+func (p *PostgresClient) CreateShiftNote(ctx context.Context, sn *ShiftNote) error {
+	if sn.ExecutionIDs == nil {
+		sn.ExecutionIDs = []uuid.UUID{}
+	}
+	if sn.Devices == nil {
+		sn.Devices = []string{}
+	}
+	if sn.AlarmIDs == nil {
+		sn.AlarmIDs = []uuid.UUID{}
+	}
+
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO shift_notes (note_text, shift_name, execution_ids, devices, alarm_ids, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, sn.Text, sn.Shift, sn.ExecutionIDs, sn.Devices, sn.AlarmIDs, sn.CreatedBy).Scan(&sn.ID, &sn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert shift note: %w", err)
+	}
+	return nil
+}
+
+// ListShiftNotes returns the notes matching the filter, newest first
+func (p *PostgresClient) ListShiftNotes(ctx context.Context, filter ShiftNoteFilter) ([]ShiftNote, error) {
+	var limit *int
+	if filter.Limit > 0 {
+		limit = &filter.Limit
+	}
+
+	rows, err := p.pool.Query(ctx, `
+        SELECT `+shiftNoteColumns+`
+        FROM shift_notes
+        WHERE ($1::timestamptz IS NULL OR created_at >= $1)
+          AND ($2::timestamptz IS NULL OR created_at < $2)
+          AND (NOT $3 OR acknowledged_at IS NULL)
+        ORDER BY created_at DESC
+        LIMIT $4
+    `, filter.From, filter.To, filter.Unacknowledged, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]ShiftNote, 0)
+	for rows.Next() {
+		var sn ShiftNote
+		if err := scanShiftNote(rows, &sn); err != nil {
+			return nil, fmt.Errorf("failed to scan shift note: %w", err)
+		}
+		notes = append(notes, sn)
+	}
+
+	return notes, rows.Err()
+}
+
+// AcknowledgeShiftNote marks a note as read by the next shift. Acknowledging
+// again keeps the first acknowledgement.
+func (p *PostgresClient) AcknowledgeShiftNote(ctx context.Context, id uuid.UUID, actor string) (*ShiftNote, error) {
+	var sn ShiftNote
+	err := scanShiftNote(p.pool.QueryRow(ctx, `
+        UPDATE shift_notes
+        SET acknowledged_by = COALESCE(acknowledged_by, $2),
+            acknowledged_at = COALESCE(acknowledged_at, NOW())
+        WHERE id = $1
+        RETURNING `+shiftNoteColumns+`
+    `, id, actor), &sn)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrShiftNoteNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to acknowledge shift note: %w", err)
+	}
+	return &sn, nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrShiftExceptionNotFound = errors.New("shift exception not found")
+
+// ListShiftPatterns returns the weekly shift pattern ordered by weekday and start
+func (p *PostgresClient) ListShiftPatterns(ctx context.Context) ([]ShiftPattern, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, shift_name, weekday, start_time, end_time, created_at
+        FROM shift_patterns
+        ORDER BY weekday, start_time
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift patterns: %w", err)
+	}
+	defer rows.Close()
+
+	patterns := make([]ShiftPattern, 0)
+	for rows.Next() {
+		var sp ShiftPattern
+		if err := rows.Scan(&sp.ID, &sp.Name, &sp.Weekday, &sp.Start, &sp.End, &sp.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shift pattern: %w", err)
+		}
+		patterns = append(patterns, sp)
+	}
+
+	return patterns, rows.Err()
+}
+
+// ReplaceShiftPatterns replaces the whole weekly pattern
+func (p *PostgresClient) ReplaceShiftPatterns(ctx context.Context, patterns []ShiftPattern) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM shift_patterns`); err != nil {
+		return fmt.Errorf("failed to clear shift patterns: %w", err)
+	}
+
+	for i := range patterns {
+		sp := &patterns[i]
+		err := tx.QueryRow(ctx, `
+            INSERT INTO shift_patterns (shift_name, weekday, start_time, end_time)
+            VALUES ($1, $2, $3, $4)
+            RETURNING id, created_at
+        `, sp.Name, sp.Weekday, sp.Start, sp.End).Scan(&sp.ID, &sp.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert shift pattern: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListShiftExceptions returns the exceptions between from and to (dates,
+// inclusive) ordered by date
+func (p *PostgresClient) ListShiftExceptions(ctx context.Context, from, to time.Time) ([]ShiftException, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, exception_date::text, shift_name, COALESCE(start_time, ''), COALESCE(end_time, ''),
+               note, created_by, created_at
+        FROM shift_exceptions
+        WHERE exception_date BETWEEN $1::date AND $2::date
+        ORDER BY exception_date, start_time
+    `, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift exceptions: %w", err)
+	}
+	defer rows.Close()
+
+	exceptions := make([]ShiftException, 0)
+	for rows.Next() {
+		var se ShiftException
+		if err := rows.Scan(&se.ID, &se.Date, &se.Name, &se.Start, &se.End, &se.Note, &se.CreatedBy, &se.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shift exception: %w", err)
+		}
+		exceptions = append(exceptions, se)
+	}
+
+	return exceptions, rows.Err()
+}
+
+// CreateShiftException adds an exception to the shift calendar
+func (p *PostgresClient) CreateShiftException(ctx context.Context, se *ShiftException) error {
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO shift_exceptions (exception_date, shift_name, start_time, end_time, note, created_by)
+        VALUES ($1::date, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+        RETURNING id, created_at
+    `, se.Date, se.Name, se.Start, se.End, se.Note, se.CreatedBy).Scan(&se.ID, &se.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert shift exception: %w", err)
+	}
+	return nil
+}
+
+// DeleteShiftException removes an exception from the shift calendar
+func (p *PostgresClient) DeleteShiftException(ctx context.Context, id uuid.UUID) error {
+	result, err := p.pool.Exec(ctx, `
+        DELETE FROM shift_exceptions
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete shift exception: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrShiftExceptionNotFound, id)
+	}
+	return nil
+}
+
+// ActiveShiftOverride returns the override suspending shift automation at
+// the given time, nil if there is none
+func (p *PostgresClient) ActiveShiftOverride(ctx context.Context, at time.Time) (*ShiftOverride, error) {
+	var so ShiftOverride
+	err := p.pool.QueryRow(ctx, `
+        SELECT id, suspended_until, reason, created_by, created_at
+        FROM shift_overrides
+        WHERE cleared_at IS NULL AND suspended_until > $1
+        ORDER BY suspended_until DESC
+        LIMIT 1
+    `, at).Scan(&so.ID, &so.SuspendedUntil, &so.Reason, &so.CreatedBy, &so.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load shift override: %w", err)
+	}
+	return &so, nil
+}
+
+// CreateShiftOverride suspends shift automation until so.SuspendedUntil
+func (p *PostgresClient) CreateShiftOverride(ctx context.Context, so *ShiftOverride) error {
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO shift_overrides (suspended_until, reason, created_by)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `, so.SuspendedUntil, so.Reason, so.CreatedBy).Scan(&so.ID, &so.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert shift override: %w", err)
+	}
+	return nil
+}
+
+// ClearShiftOverrides ends all active overrides and returns how many were cleared
+func (p *PostgresClient) ClearShiftOverrides(ctx context.Context, actor string) (int64, error) {
+	result, err := p.pool.Exec(ctx, `
+        UPDATE shift_overrides
+        SET cleared_at = NOW(), cleared_by = $1
+        WHERE cleared_at IS NULL AND suspended_until > NOW()
+    `, actor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear shift overrides: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
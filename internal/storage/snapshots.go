@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SaveDeviceSnapshots stores snapshots taken in one cycle
+func (p *PostgresClient) SaveDeviceSnapshots(ctx context.Context, snapshots []DeviceSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range snapshots {
+		snap := &snapshots[i]
+		valuesJSON, err := json.Marshal(snap.Values)
+		if err != nil {
+			return fmt.Errorf("failed to marshal snapshot of %s: %w", snap.DeviceName, err)
+		}
+
+		err = tx.QueryRow(ctx, `
+            INSERT INTO device_snapshots (device_name, register_values, captured_at)
+            VALUES ($1, $2, $3)
+            RETURNING id
+        `, snap.DeviceName, valuesJSON, snap.CapturedAt).Scan(&snap.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert snapshot: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListDeviceSnapshots returns the snapshots of a device between from and to,
+// newest first
+func (p *PostgresClient) ListDeviceSnapshots(ctx context.Context, deviceName string, from, to time.Time, limit int) ([]DeviceSnapshot, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, device_name, register_values, captured_at
+        FROM device_snapshots
+        WHERE device_name = $1 AND captured_at >= $2 AND captured_at <= $3
+        ORDER BY captured_at DESC
+        LIMIT $4
+    `, deviceName, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]DeviceSnapshot, 0)
+	for rows.Next() {
+		var snap DeviceSnapshot
+		var valuesJSON []byte
+		if err := rows.Scan(&snap.ID, &snap.DeviceName, &valuesJSON, &snap.CapturedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		if err := json.Unmarshal(valuesJSON, &snap.Values); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal snapshot values: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// DeleteDeviceSnapshotsBefore removes snapshots older than cutoff and
+// returns how many were deleted
+func (p *PostgresClient) DeleteDeviceSnapshotsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := p.pool.Exec(ctx, `
+        DELETE FROM device_snapshots
+        WHERE captured_at < $1
+    `, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventCycleTimeExceeded is published when a run exceeds its workflow's cycle time
+const EventCycleTimeExceeded = "execution.cycle_time_exceeded"
+
+// WorkflowStatistics aggregates the executions of a workflow started in a time range.
+// Durations cover successful runs only.
+type WorkflowStatistics struct {
+	WorkflowID          uuid.UUID `json:"workflow_id"`
+	From                time.Time `json:"from"`
+	To                  time.Time `json:"to"`
+	Executions          int       `json:"executions"`
+	Succeeded           int       `json:"succeeded"`
+	Failed              int       `json:"failed"`
+	Cancelled           int       `json:"cancelled"`
+	AvgDurationMs       *float64  `json:"avg_duration_ms,omitempty"`
+	MinDurationMs       *float64  `json:"min_duration_ms,omitempty"`
+	MaxDurationMs       *float64  `json:"max_duration_ms,omitempty"`
+	P95DurationMs       *float64  `json:"p95_duration_ms,omitempty"`
+	CycleTimeViolations int       `json:"cycle_time_violations"` // Runs that emitted execution.cycle_time_exceeded
+}
+
+// GetWorkflowStatistics aggregates the executions of a workflow started in [from, to)
+func (p *PostgresClient) GetWorkflowStatistics(ctx context.Context, workflowID uuid.UUID, from, to time.Time) (*WorkflowStatistics, error) {
+	stats := WorkflowStatistics{WorkflowID: workflowID, From: from, To: to}
+	err := p.pool.QueryRow(ctx, `
+        WITH runs AS (
+            SELECT we.status,
+                   EXTRACT(EPOCH FROM (we.completed_at - we.started_at)) * 1000 AS duration_ms,
+                   EXISTS (
+                       SELECT 1 FROM execution_events ev
+                       WHERE ev.execution_id = we.id AND ev.event_type = $4
+                   ) AS violated
+            FROM workflow_executions we
+            WHERE we.workflow_id = $1 AND we.started_at >= $2 AND we.started_at < $3
+        )
+        SELECT COUNT(*),
+               COUNT(*) FILTER (WHERE status = 'success'),
+               COUNT(*) FILTER (WHERE status = 'failed'),
+               COUNT(*) FILTER (WHERE status = 'cancelled'),
+               AVG(duration_ms) FILTER (WHERE status = 'success'),
+               MIN(duration_ms) FILTER (WHERE status = 'success'),
+               MAX(duration_ms) FILTER (WHERE status = 'success'),
+               percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms) FILTER (WHERE status = 'success'),
+               COUNT(*) FILTER (WHERE violated)
+        FROM runs
+    `, workflowID, from, to, EventCycleTimeExceeded).Scan(&stats.Executions, &stats.Succeeded, &stats.Failed, &stats.Cancelled,
+		&stats.AvgDurationMs, &stats.MinDurationMs, &stats.MaxDurationMs, &stats.P95DurationMs, &stats.CycleTimeViolations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow statistics: %w", err)
+	}
+	return &stats, nil
+}
+
+// StepDuration is the measured duration of a step of a workflow's executions
+type StepDuration struct {
+	HierarchicalStepID string  `json:"hierarchical_step_id"`
+	Runs               int     `json:"runs"` // Successful runs, loops run a step more than once per execution
+	AvgDurationMs      float64 `json:"avg_duration_ms"`
+}
+
+// GetStepDurations returns the durations of the steps of a
+// workflow's successful executions started since the given time, by
+// hierarchical step ID, with the number of executions
+func (p *PostgresClient) GetStepDurations(ctx context.Context, workflowID uuid.UUID, since time.Time) (map[string]StepDuration, int, error) {
+	var executions int
+	err := p.pool.QueryRow(ctx, `
+        SELECT COUNT(*) FROM workflow_executions
+        WHERE workflow_id = $1 AND status = 'success' AND started_at >= $2
+    `, workflowID, since).Scan(&executions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count executions: %w", err)
+	}
+
+	rows, err := p.pool.Query(ctx, `
+        SELECT es.hierarchical_step_id,
+               COUNT(*),
+               AVG(EXTRACT(EPOCH FROM (es.completed_at - es.started_at)) * 1000)
+        FROM execution_steps es
+        JOIN workflow_executions we ON we.id = es.execution_id
+        WHERE we.workflow_id = $1 AND we.status = 'success' AND we.started_at >= $2
+          AND es.status = 'success' AND es.completed_at IS NOT NULL
+        GROUP BY es.hierarchical_step_id
+    `, workflowID, since)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query step durations: %w", err)
+	}
+	defer rows.Close()
+
+	durations := make(map[string]StepDuration)
+	for rows.Next() {
+		var d StepDuration
+		if err := rows.Scan(&d.HierarchicalStepID, &d.Runs, &d.AvgDurationMs); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan step duration: %w", err)
+		}
+		durations[d.HierarchicalStepID] = d
+	}
+	return durations, executions, rows.Err()
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is the Postgres SQLSTATE for unique constraint violations
+const pgUniqueViolation = "23505"
+
+var (
+	ErrStepTemplateNotFound = errors.New("step template not found")
+	ErrStepTemplateExists   = errors.New("step template already exists")
+)
+
+// SaveStepTemplate inserts a new step template
+func (p *PostgresClient) SaveStepTemplate(ctx context.Context, tmpl *StepTemplate) error {
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO step_templates (template_name, description, definition)
+        VALUES ($1, $2, $3)
+        RETURNING id, version, created_at, updated_at
+    `, tmpl.Name, tmpl.Description, tmpl.Definition).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", ErrStepTemplateExists, tmpl.Name)
+		}
+		return fmt.Errorf("failed to insert step template: %w", err)
+	}
+
+	return nil
+}
+
+// LoadStepTemplate loads a step template by name
+func (p *PostgresClient) LoadStepTemplate(ctx context.Context, name string) (*StepTemplate, error) {
+	var tmpl StepTemplate
+	err := p.pool.QueryRow(ctx, `
+        SELECT id, template_name, description, definition, version, created_at, updated_at
+        FROM step_templates
+        WHERE template_name = $1
+    `, name).Scan(
+		&tmpl.ID,
+		&tmpl.Name,
+		&tmpl.Description,
+		&tmpl.Definition,
+		&tmpl.Version,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrStepTemplateNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to load step template: %w", err)
+	}
+
+	return &tmpl, nil
+}
+
+// StepTemplateDefinition returns the stored definition of a step template.
+// It is the template source used to expand workflow steps.
+func (p *PostgresClient) StepTemplateDefinition(ctx context.Context, name string) ([]byte, error) {
+	tmpl, err := p.LoadStepTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Definition, nil
+}
+
+// ListStepTemplates returns all step templates ordered by name
+func (p *PostgresClient) ListStepTemplates(ctx context.Context) ([]StepTemplate, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, template_name, description, definition, version, created_at, updated_at
+        FROM step_templates
+        ORDER BY template_name
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query step templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]StepTemplate, 0)
+	for rows.Next() {
+		var tmpl StepTemplate
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Description, &tmpl.Definition,
+			&tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpdateStepTemplate replaces the description and definition of a step
+// template and increments its version
+func (p *PostgresClient) UpdateStepTemplate(ctx context.Context, tmpl *StepTemplate) error {
+	err := p.pool.QueryRow(ctx, `
+        UPDATE step_templates
+        SET description = $2, definition = $3, version = version + 1, updated_at = NOW()
+        WHERE template_name = $1
+        RETURNING id, version, created_at, updated_at
+    `, tmpl.Name, tmpl.Description, tmpl.Definition).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrStepTemplateNotFound, tmpl.Name)
+		}
+		return fmt.Errorf("failed to update step template: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStepTemplate removes a step template.
+// Returns a *ReferenceError if workflow steps still use it.
+func (p *PostgresClient) DeleteStepTemplate(ctx context.Context, name string) error {
+	refs, err := p.StepTemplateReferences(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check step template references: %w", err)
+	}
+	if len(refs) > 0 {
+		return &ReferenceError{
+			Resource:     "step_template",
+			ID:           name,
+			ReferencedBy: refs,
+			Guidance:     "Replace the template steps in the workflows using this template before deleting it",
+		}
+	}
+
+	tag, err := p.pool.Exec(ctx, `
+        DELETE FROM step_templates WHERE template_name = $1
+    `, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete step template: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrStepTemplateNotFound, name)
+	}
+
+	return nil
+}
+
+// StepTemplateReferences returns the workflows with steps using the given template
+func (p *PostgresClient) StepTemplateReferences(ctx context.Context, name string) ([]Reference, error) {
+	containment, err := json.Marshal(map[string]any{
+		"steps": []map[string]string{{"template": name}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return referencingWorkflows(ctx, p.pool, containment, nil)
+}
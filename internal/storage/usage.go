@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UsageRecord counts the calls of one principal to one endpoint class per hour
+type UsageRecord struct {
+	Bucket        time.Time `json:"bucket"`
+	PrincipalType string    `json:"principal_type"` // "user" or "machine_token"
+	PrincipalID   string    `json:"principal_id"`
+	PrincipalName string    `json:"principal_name"`
+	EndpointClass string    `json:"endpoint_class"`
+	SuccessCount  int64     `json:"success_count"`
+	FailureCount  int64     `json:"failure_count"`
+}
+
+// UsageFilter selects usage records; empty fields match everything
+type UsageFilter struct {
+	From          time.Time
+	To            time.Time
+	PrincipalType string
+	PrincipalID   string
+	EndpointClass string
+}
+
+// AddUsage adds the given counts to the stored hourly counters
+func (p *PostgresClient) AddUsage(ctx context.Context, records []UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, r := range records {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO api_usage (bucket, principal_type, principal_id, principal_name, endpoint_class, success_count, failure_count)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            ON CONFLICT (bucket, principal_type, principal_id, endpoint_class) DO UPDATE
+            SET success_count = api_usage.success_count + EXCLUDED.success_count,
+                failure_count = api_usage.failure_count + EXCLUDED.failure_count,
+                principal_name = EXCLUDED.principal_name
+        `, r.Bucket, r.PrincipalType, r.PrincipalID, r.PrincipalName, r.EndpointClass, r.SuccessCount, r.FailureCount)
+		if err != nil {
+			return fmt.Errorf("failed to store usage: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// QueryUsage returns the hourly usage records matching the filter
+func (p *PostgresClient) QueryUsage(ctx context.Context, filter UsageFilter) ([]UsageRecord, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT bucket, principal_type, principal_id, principal_name, endpoint_class, success_count, failure_count
+        FROM api_usage
+        WHERE bucket >= $1 AND bucket < $2
+          AND ($3 = '' OR principal_type = $3)
+          AND ($4 = '' OR principal_id = $4)
+          AND ($5 = '' OR endpoint_class = $5)
+        ORDER BY bucket DESC, principal_type, principal_id, endpoint_class
+    `, filter.From, filter.To, filter.PrincipalType, filter.PrincipalID, filter.EndpointClass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]UsageRecord, 0)
+	for rows.Next() {
+		var r UsageRecord
+		if err := rows.Scan(&r.Bucket, &r.PrincipalType, &r.PrincipalID, &r.PrincipalName,
+			&r.EndpointClass, &r.SuccessCount, &r.FailureCount); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkflowValidation is the latest bulk validation result of a workflow
+type WorkflowValidation struct {
+	WorkflowID   uuid.UUID       `json:"-"`
+	Valid        bool            `json:"valid"`
+	ErrorCount   int             `json:"error_count"`
+	WarningCount int             `json:"warning_count"`
+	LintProfile  string          `json:"lint_profile,omitempty"`
+	Issues       json.RawMessage `json:"issues"` // Errors followed by warnings
+	ValidatedAt  time.Time       `json:"validated_at"`
+}
+
+// SaveWorkflowValidations replaces the stored validation results of the given workflows
+func (p *PostgresClient) SaveWorkflowValidations(ctx context.Context, results []WorkflowValidation) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, r := range results {
+		issues := r.Issues
+		if len(issues) == 0 {
+			issues = json.RawMessage("[]")
+		}
+		_, err := tx.Exec(ctx, `
+            INSERT INTO workflow_validations (workflow_id, valid, error_count, warning_count, lint_profile, issues, validated_at)
+            VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+            ON CONFLICT (workflow_id) DO UPDATE SET
+                valid = EXCLUDED.valid,
+                error_count = EXCLUDED.error_count,
+                warning_count = EXCLUDED.warning_count,
+                lint_profile = EXCLUDED.lint_profile,
+                issues = EXCLUDED.issues,
+                validated_at = EXCLUDED.validated_at
+        `, r.WorkflowID, r.Valid, r.ErrorCount, r.WarningCount, r.LintProfile, issues, r.ValidatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save workflow validation: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListWorkflowValidations returns the stored validation results by workflow ID
+func (p *PostgresClient) ListWorkflowValidations(ctx context.Context) (map[uuid.UUID]*WorkflowValidation, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT workflow_id, valid, error_count, warning_count, COALESCE(lint_profile, ''), issues, validated_at
+        FROM workflow_validations
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow validations: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[uuid.UUID]*WorkflowValidation)
+	for rows.Next() {
+		var r WorkflowValidation
+		if err := rows.Scan(&r.WorkflowID, &r.Valid, &r.ErrorCount, &r.WarningCount, &r.LintProfile, &r.Issues, &r.ValidatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow validation: %w", err)
+		}
+		results[r.WorkflowID] = &r
+	}
+	return results, rows.Err()
+}
@@ -3,7 +3,12 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/types"
@@ -13,17 +18,101 @@ import (
 
 // Workflow execution types
 type WorkflowExecution struct {
-	ID            uuid.UUID
-	WorkflowID    uuid.UUID
-	Status        ExecutionStatus
-	CurrentStep   int             // Kept for backward compatibility
-	CurrentStepID string          // Hierarchical step ID, e.g., "main:S10:sub_pick:S20"
-	CallStack     json.RawMessage // JSON array of CallFrames
-	Input         json.RawMessage
-	Output        json.RawMessage
-	Error         string
-	StartedAt     time.Time
-	CompletedAt   *time.Time
+	ID            uuid.UUID       `json:"id"`
+	WorkflowID    uuid.UUID       `json:"workflow_id"`
+	Status        ExecutionStatus `json:"status"`
+	CurrentStep   int             `json:"current_step"`    // Kept for backward compatibility
+	CurrentStepID string          `json:"current_step_id"` // Hierarchical step ID, e.g., "main:S10:sub_pick:S20"
+	CallStack     json.RawMessage `json:"call_stack"`      // JSON array of CallFrames
+	Input         json.RawMessage `json:"input,omitempty"`
+	Output        json.RawMessage `json:"output,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CancelSource  string          `json:"cancel_source,omitempty"` // "operator", "machine" or "system"
+	CancelReason  string          `json:"cancel_reason,omitempty"`
+	CancelledBy   string          `json:"cancelled_by,omitempty"`
+	RetryOf       *uuid.UUID      `json:"retry_of,omitempty"`   // Execution this one retries
+	StartStep     int             `json:"start_step,omitempty"` // First executed step of a retry
+	SkipSteps     []string        `json:"skip_steps,omitempty"` // Top-level step numbers not executed
+	RetriedBy     []string        `json:"retried_by,omitempty"` // Retries of this execution (read only)
+	Correlation   Correlation     `json:"correlation"`
+	Tags          []string        `json:"tags"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"` // JSON object
+	StartedAt     time.Time       `json:"started_at"`
+	CompletedAt   *time.Time      `json:"completed_at"`
+}
+
+// Correlation links an execution to external MES records
+type Correlation struct {
+	OrderID      string `json:"order_id,omitempty"`
+	BatchID      string `json:"batch_id,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty"`
+}
+
+// maxCorrelationLength matches the column size
+const maxCorrelationLength = 255
+
+// IsZero reports whether no correlation ID is set
+func (c Correlation) IsZero() bool {
+	return c == Correlation{}
+}
+
+// Validate checks the length of the correlation IDs
+func (c Correlation) Validate() error {
+	fields := []struct{ name, value string }{
+		{"order_id", c.OrderID},
+		{"batch_id", c.BatchID},
+		{"serial_number", c.SerialNumber},
+	}
+	for _, f := range fields {
+		if len(f.value) > maxCorrelationLength {
+			return fmt.Errorf("%s exceeds %d characters", f.name, maxCorrelationLength)
+		}
+	}
+	return nil
+}
+
+// Limits of execution tags
+const (
+	MaxExecutionTags  = 32
+	maxExecutionTag   = 64
+	maxMetadataLength = 16 * 1024
+)
+
+// ErrInvalidTags is returned for too many or too long execution tags
+var ErrInvalidTags = errors.New("invalid tags")
+
+// NormalizeTags trims the tags and removes empty and duplicate ones
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(normalized, tag) {
+			continue
+		}
+		if len(tag) > maxExecutionTag {
+			return nil, fmt.Errorf("%w: tag %q exceeds %d characters", ErrInvalidTags, tag, maxExecutionTag)
+		}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxExecutionTags {
+		return nil, fmt.Errorf("%w: more than %d tags", ErrInvalidTags, MaxExecutionTags)
+	}
+	return normalized, nil
+}
+
+// ExecutionFilter selects executions in ListExecutions; empty fields match all
+type ExecutionFilter struct {
+	WorkflowID   *uuid.UUID
+	Status       ExecutionStatus
+	OrderID      string
+	BatchID      string
+	SerialNumber string
+	Tags         []string        // Executions having all of them
+	Input        json.RawMessage // JSON the input contains, e.g. {"part_type": "A"}
+	Metadata     json.RawMessage // JSON the metadata contains
+	From         *time.Time      // Started at or after
+	To           *time.Time      // Started before
+	Limit        int
 }
 
 type ExecutionStatus string
@@ -34,29 +123,36 @@ const (
 	StatusSuccess   ExecutionStatus = "success"
 	StatusFailed    ExecutionStatus = "failed"
 	StatusCancelled ExecutionStatus = "cancelled"
+	StatusSkipped   ExecutionStatus = "skipped" // Steps excluded by the step filter
 )
 
+// Finished reports whether an execution with this status has ended
+func (s ExecutionStatus) Finished() bool {
+	return s == StatusSuccess || s == StatusFailed || s == StatusCancelled
+}
+
 type ExecutionStep struct {
-	ID                 uuid.UUID
-	ExecutionID        uuid.UUID
-	StepIndex          int // Kept for backward compatibility
-	StepName           string
-	HierarchicalStepID string // Full hierarchical ID, e.g., "main:S10:sub_pick:S20"
-	Depth              int    // Nesting depth (0=main, 1=first sub, 2=nested sub, etc.)
-	Status             ExecutionStatus
-	Input              json.RawMessage
-	Output             json.RawMessage
-	Error              string
-	StartedAt          time.Time
-	CompletedAt        *time.Time
+	ID                 uuid.UUID       `json:"id"`
+	ExecutionID        uuid.UUID       `json:"execution_id"`
+	StepIndex          int             `json:"step_index"` // Kept for backward compatibility
+	StepName           string          `json:"step_name"`
+	HierarchicalStepID string          `json:"hierarchical_step_id"` // Full hierarchical ID, e.g., "main:S10:sub_pick:S20"
+	Depth              int             `json:"depth"`                // Nesting depth (0=main, 1=first sub, 2=nested sub, etc.)
+	Status             ExecutionStatus `json:"status"`
+	Input              json.RawMessage `json:"input,omitempty"`
+	Output             json.RawMessage `json:"output,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	StartedAt          time.Time       `json:"started_at"`
+	CompletedAt        *time.Time      `json:"completed_at"`
 }
 
 type ExecutionEvent struct {
-	ID          uuid.UUID
-	ExecutionID uuid.UUID
-	EventType   string
-	Payload     json.RawMessage
-	Timestamp   time.Time
+	ID          uuid.UUID       `json:"id"`
+	Sequence    int64           `json:"sequence"` // Set when stored, increasing within an execution
+	ExecutionID uuid.UUID       `json:"execution_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Timestamp   time.Time       `json:"timestamp"`
 }
 
 // SaveWorkflow stores a workflow with its compositions
@@ -221,16 +317,51 @@ func (p *PostgresClient) UpdateWorkflow(ctx context.Context, workflow *Workflow)
 	return nil
 }
 
-// DeleteWorkflow deletes a workflow and its compositions
+// DeleteWorkflow deletes a workflow, its compositions and its finished executions.
+// Returns a *ReferenceError if other workflows call it or executions are still active.
 func (p *PostgresClient) DeleteWorkflow(ctx context.Context, workflowID uuid.UUID) error {
-	_, err := p.pool.Exec(ctx, `
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Hold off workflows saved with a call to it and executions started
+	// until the delete commits, so the reference check stays true
+	if _, err := tx.Exec(ctx, `LOCK TABLE workflows IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock workflows: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM workflows WHERE id = $1 FOR UPDATE`, workflowID); err != nil {
+		return fmt.Errorf("failed to lock workflow: %w", err)
+	}
+
+	refs, err := workflowReferences(ctx, tx, workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to check workflow references: %w", err)
+	}
+	if len(refs) > 0 {
+		return &ReferenceError{
+			Resource:     "workflow",
+			ID:           workflowID.String(),
+			ReferencedBy: refs,
+			Guidance:     "Remove the sub-workflow steps calling this workflow and wait for or cancel its active executions",
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
         DELETE FROM workflows WHERE id = $1
     `, workflowID)
 
 	if err != nil {
+		if refErr := asReferenceError(err, "workflow", workflowID.String()); refErr != err {
+			return refErr
+		}
 		return fmt.Errorf("failed to delete workflow: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -286,9 +417,14 @@ func (p *PostgresClient) DeviceExistsEnabledByName(ctx context.Context, deviceNa
 func (p *PostgresClient) CreateExecution(ctx context.Context, exec *WorkflowExecution) error {
 	_, err := p.pool.Exec(ctx, `
         INSERT INTO workflow_executions
-        (id, workflow_id, status, current_step, current_step_id, call_stack, input, started_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    `, exec.ID, exec.WorkflowID, exec.Status, exec.CurrentStep, exec.CurrentStepID, exec.CallStack, exec.Input, exec.StartedAt)
+        (id, workflow_id, status, current_step, current_step_id, call_stack, input, retry_of, start_step, started_at,
+         order_id, batch_id, serial_number, tags, metadata, skip_steps)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''),
+                COALESCE($14, '{}'::text[]), COALESCE($15, '{}'::jsonb), COALESCE($16, '{}'::text[]))
+    `, exec.ID, exec.WorkflowID, exec.Status, exec.CurrentStep, exec.CurrentStepID, exec.CallStack, exec.Input,
+		exec.RetryOf, exec.StartStep, exec.StartedAt,
+		exec.Correlation.OrderID, exec.Correlation.BatchID, exec.Correlation.SerialNumber,
+		exec.Tags, exec.Metadata, exec.SkipSteps)
 	return err
 }
 
@@ -296,9 +432,21 @@ func (p *PostgresClient) CreateExecution(ctx context.Context, exec *WorkflowExec
 func (p *PostgresClient) UpdateExecution(ctx context.Context, exec *WorkflowExecution) error {
 	_, err := p.pool.Exec(ctx, `
         UPDATE workflow_executions
-        SET status = $1, current_step = $2, current_step_id = $3, call_stack = $4, output = $5, error = $6, completed_at = $7
-        WHERE id = $8
-    `, exec.Status, exec.CurrentStep, exec.CurrentStepID, exec.CallStack, exec.Output, exec.Error, exec.CompletedAt, exec.ID)
+        SET status = $1, current_step = $2, current_step_id = $3, call_stack = $4, output = $5, error = $6, completed_at = $7,
+            cancel_source = NULLIF($8, ''), cancel_reason = NULLIF($9, ''), cancelled_by = NULLIF($10, '')
+        WHERE id = $11
+    `, exec.Status, exec.CurrentStep, exec.CurrentStepID, exec.CallStack, exec.Output, exec.Error, exec.CompletedAt,
+		exec.CancelSource, exec.CancelReason, exec.CancelledBy, exec.ID)
+	return err
+}
+
+// UpdateExecutionPosition records the step an execution is currently running
+func (p *PostgresClient) UpdateExecutionPosition(ctx context.Context, id uuid.UUID, currentStep int, currentStepID string, callStack json.RawMessage) error {
+	_, err := p.pool.Exec(ctx, `
+        UPDATE workflow_executions
+        SET current_step = $1, current_step_id = $2, call_stack = $3
+        WHERE id = $4
+    `, currentStep, currentStepID, callStack, id)
 	return err
 }
 
@@ -306,10 +454,18 @@ func (p *PostgresClient) UpdateExecution(ctx context.Context, exec *WorkflowExec
 func (p *PostgresClient) GetExecution(ctx context.Context, id uuid.UUID) (*WorkflowExecution, error) {
 	var exec WorkflowExecution
 	err := p.pool.QueryRow(ctx, `
-        SELECT id, workflow_id, status, current_step, current_step_id, call_stack, input, output, error, started_at, completed_at
-        FROM workflow_executions WHERE id = $1
+        SELECT id, workflow_id, status, current_step, COALESCE(current_step_id, ''), call_stack, input, output, COALESCE(error, ''),
+               COALESCE(cancel_source, ''), COALESCE(cancel_reason, ''), COALESCE(cancelled_by, ''),
+               retry_of, start_step, skip_steps,
+               COALESCE((SELECT array_agg(r.id::text ORDER BY r.started_at) FROM workflow_executions r WHERE r.retry_of = we.id), '{}'),
+               COALESCE(order_id, ''), COALESCE(batch_id, ''), COALESCE(serial_number, ''),
+               tags, metadata, started_at, completed_at
+        FROM workflow_executions we WHERE id = $1
     `, id).Scan(&exec.ID, &exec.WorkflowID, &exec.Status, &exec.CurrentStep, &exec.CurrentStepID, &exec.CallStack,
-		&exec.Input, &exec.Output, &exec.Error, &exec.StartedAt, &exec.CompletedAt)
+		&exec.Input, &exec.Output, &exec.Error, &exec.CancelSource, &exec.CancelReason, &exec.CancelledBy,
+		&exec.RetryOf, &exec.StartStep, &exec.SkipSteps, &exec.RetriedBy,
+		&exec.Correlation.OrderID, &exec.Correlation.BatchID, &exec.Correlation.SerialNumber,
+		&exec.Tags, &exec.Metadata, &exec.StartedAt, &exec.CompletedAt)
 
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("execution not found: %s", id)
@@ -317,6 +473,54 @@ func (p *PostgresClient) GetExecution(ctx context.Context, id uuid.UUID) (*Workf
 	return &exec, err
 }
 
+// ListExecutions returns executions matching the filter, newest first.
+// Input, output and call stack are omitted; use GetExecution for details.
+// Input and metadata are matched by JSONB containment.
+func (p *PostgresClient) ListExecutions(ctx context.Context, filter ExecutionFilter) ([]WorkflowExecution, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, workflow_id, status, current_step, COALESCE(current_step_id, ''), COALESCE(error, ''),
+               retry_of, start_step,
+               COALESCE(order_id, ''), COALESCE(batch_id, ''), COALESCE(serial_number, ''),
+               tags, metadata, started_at, completed_at
+        FROM workflow_executions
+        WHERE ($1::uuid IS NULL OR workflow_id = $1)
+          AND ($2 = '' OR status = $2)
+          AND ($3 = '' OR order_id = $3)
+          AND ($4 = '' OR batch_id = $4)
+          AND ($5 = '' OR serial_number = $5)
+          AND ($7::text[] IS NULL OR tags @> $7)
+          AND ($8::jsonb IS NULL OR input @> $8)
+          AND ($9::jsonb IS NULL OR metadata @> $9)
+          AND ($10::timestamptz IS NULL OR started_at >= $10)
+          AND ($11::timestamptz IS NULL OR started_at < $11)
+        ORDER BY started_at DESC
+        LIMIT $6
+    `, filter.WorkflowID, string(filter.Status), filter.OrderID, filter.BatchID, filter.SerialNumber, filter.Limit,
+		filter.Tags, filter.Input, filter.Metadata, filter.From, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := make([]WorkflowExecution, 0)
+	for rows.Next() {
+		var exec WorkflowExecution
+		err := rows.Scan(&exec.ID, &exec.WorkflowID, &exec.Status, &exec.CurrentStep, &exec.CurrentStepID, &exec.Error,
+			&exec.RetryOf, &exec.StartStep,
+			&exec.Correlation.OrderID, &exec.Correlation.BatchID, &exec.Correlation.SerialNumber,
+			&exec.Tags, &exec.Metadata, &exec.StartedAt, &exec.CompletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan execution: %w", err)
+		}
+		executions = append(executions, exec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read executions: %w", err)
+	}
+
+	return executions, nil
+}
+
 // CreateExecutionStep creates a step execution record
 func (p *PostgresClient) CreateExecutionStep(ctx context.Context, step *ExecutionStep) error {
 	_, err := p.pool.Exec(ctx, `
@@ -337,22 +541,48 @@ func (p *PostgresClient) UpdateExecutionStep(ctx context.Context, step *Executio
 	return err
 }
 
-// CreateExecutionEvent creates an execution event for streaming
+// CreateExecutionEvent creates an execution event for streaming. With the
+// outbox enabled, the event is added to it in the same transaction.
 func (p *PostgresClient) CreateExecutionEvent(ctx context.Context, event *ExecutionEvent) error {
-	_, err := p.pool.Exec(ctx, `
+	if !p.outbox.Load() {
+		return p.pool.QueryRow(ctx, `
+            INSERT INTO execution_events (id, execution_id, event_type, payload, timestamp)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING sequence
+        `, event.ID, event.ExecutionID, event.EventType, event.Payload, event.Timestamp).Scan(&event.Sequence)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
         INSERT INTO execution_events (id, execution_id, event_type, payload, timestamp)
         VALUES ($1, $2, $3, $4, $5)
-    `, event.ID, event.ExecutionID, event.EventType, event.Payload, event.Timestamp)
-	return err
+        RETURNING sequence
+    `, event.ID, event.ExecutionID, event.EventType, event.Payload, event.Timestamp).Scan(&event.Sequence)
+	if err != nil {
+		return err
+	}
+	if err := appendOutbox(ctx, tx, OutboxSourceWorkflow, event.EventType, executionOutboxPayload(event), event.Timestamp); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-// GetExecutionSteps retrieves all steps for an execution
+// GetExecutionSteps retrieves all steps for an execution in the order they
+// ran, so repeated runs of a step in a loop stay apart. Steps started at the
+// same time are ordered by hierarchical step ID (a caller before the steps
+// of its sub-workflow).
 func (p *PostgresClient) GetExecutionSteps(ctx context.Context, executionID uuid.UUID) ([]ExecutionStep, error) {
 	rows, err := p.pool.Query(ctx, `
-        SELECT id, execution_id, step_index, step_name, hierarchical_step_id, depth, status, input, output, error, started_at, completed_at
+        SELECT id, execution_id, step_index, step_name, COALESCE(hierarchical_step_id, ''), COALESCE(depth, 0),
+               status, input, output, COALESCE(error, ''), started_at, completed_at
         FROM execution_steps
         WHERE execution_id = $1
-        ORDER BY step_index
+        ORDER BY started_at, step_index
     `, executionID)
 
 	if err != nil {
@@ -370,6 +600,69 @@ func (p *PostgresClient) GetExecutionSteps(ctx context.Context, executionID uuid
 		}
 		steps = append(steps, step)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read steps: %w", err)
+	}
 
+	sortExecutionSteps(steps)
 	return steps, nil
 }
+
+// sortExecutionSteps orders steps by start time, steps started at the same
+// time by hierarchical step ID
+func sortExecutionSteps(steps []ExecutionStep) {
+	sort.SliceStable(steps, func(i, j int) bool {
+		if !steps[i].StartedAt.Equal(steps[j].StartedAt) {
+			return steps[i].StartedAt.Before(steps[j].StartedAt)
+		}
+		return compareHierarchicalStepIDs(steps[i].HierarchicalStepID, steps[j].HierarchicalStepID) < 0
+	})
+}
+
+// compareHierarchicalStepIDs orders IDs like "main:S10:sub_pick:S20" segment
+// by segment. Step numbers compare numerically ("S2" < "S10", "S30.1" < "S30.2")
+// and a caller sorts before the steps of its sub-workflow.
+func compareHierarchicalStepIDs(a, b string) int {
+	if a == b {
+		return 0
+	}
+	// Steps without hierarchy (recorded before migration 009) sort after all
+	// others, among themselves in index order
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+
+	as := strings.Split(a, ":")
+	bs := strings.Split(b, ":")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareStepSegments(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func compareStepSegments(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if strings.HasPrefix(a, "S") && strings.HasPrefix(b, "S") {
+		ap := strings.Split(a[1:], ".")
+		bp := strings.Split(b[1:], ".")
+		for i := 0; i < len(ap) && i < len(bp); i++ {
+			an, aErr := strconv.Atoi(ap[i])
+			bn, bErr := strconv.Atoi(bp[i])
+			if aErr != nil || bErr != nil {
+				return strings.Compare(a, b)
+			}
+			if an != bn {
+				return an - bn
+			}
+		}
+		return len(ap) - len(bp)
+	}
+	return strings.Compare(a, b)
+}
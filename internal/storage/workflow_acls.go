@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const workflowACLColumns = `workflow_id, owner, execute, modify, updated_by, updated_at`
+
+func scanWorkflowACL(row pgx.Row, acl *WorkflowACL) error {
+	return row.Scan(&acl.WorkflowID, &acl.Owner, &acl.Execute, &acl.Modify, &acl.UpdatedBy, &acl.UpdatedAt)
+}
+
+// GetWorkflowACL returns the access list of a workflow, nil if it is
+// unrestricted
+func (p *PostgresClient) GetWorkflowACL(ctx context.Context, workflowID uuid.UUID) (*WorkflowACL, error) {
+	var acl WorkflowACL
+	err := scanWorkflowACL(p.pool.QueryRow(ctx, `
+        SELECT `+workflowACLColumns+`
+        FROM workflow_acls
+        WHERE workflow_id = $1
+    `, workflowID), &acl)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow access list: %w", err)
+	}
+	return &acl, nil
+}
+
+// ListWorkflowACLs returns the access lists of all restricted workflows
+func (p *PostgresClient) ListWorkflowACLs(ctx context.Context) (map[uuid.UUID]*WorkflowACL, error) {
+	rows, err := p.pool.Query(ctx, `
+        SELECT `+workflowACLColumns+`
+        FROM workflow_acls
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow access lists: %w", err)
+	}
+	defer rows.Close()
+
+	acls := make(map[uuid.UUID]*WorkflowACL)
+	for rows.Next() {
+		var acl WorkflowACL
+		if err := scanWorkflowACL(rows, &acl); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow access list: %w", err)
+		}
+		acls[acl.WorkflowID] = &acl
+	}
+
+	return acls, rows.Err()
+}
+
+// SaveWorkflowACL creates or replaces the access list of a workflow
+func (p *PostgresClient) SaveWorkflowACL(ctx context.Context, acl *WorkflowACL) error {
+	if acl.Execute == nil {
+		acl.Execute = []string{}
+	}
+	if acl.Modify == nil {
+		acl.Modify = []string{}
+	}
+
+	err := p.pool.QueryRow(ctx, `
+        INSERT INTO workflow_acls (workflow_id, owner, execute, modify, updated_by)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (workflow_id) DO UPDATE
+        SET owner = EXCLUDED.owner,
+            execute = EXCLUDED.execute,
+            modify = EXCLUDED.modify,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = NOW()
+        RETURNING updated_at
+    `, acl.WorkflowID, acl.Owner, acl.Execute, acl.Modify, acl.UpdatedBy).Scan(&acl.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save workflow access list: %w", err)
+	}
+	return nil
+}
+
+// DeleteWorkflowACL lifts the restrictions of a workflow; false if it had
+// none
+func (p *PostgresClient) DeleteWorkflowACL(ctx context.Context, workflowID uuid.UUID) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+        DELETE FROM workflow_acls WHERE workflow_id = $1
+    `, workflowID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete workflow access list: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
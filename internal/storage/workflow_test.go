@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompareHierarchicalStepIDs(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // Sign only
+	}{
+		{"main:S10", "main:S10", 0},
+		{"main:S2", "main:S10", -1},
+		{"main:S30.1", "main:S30.2", -1},
+		{"main:S30", "main:S30.1", -1},
+		{"main:S10", "main:S10:sub_pick:S10", -1},
+		{"main:S10:sub_pick:S20", "main:S20", -1},
+		{"main:S10:sub_pick:S20", "main:S10:sub_pick:S5", 1},
+		{"main:S10", "", -1},
+		{"", "main:S10", 1},
+		{"", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			got := compareHierarchicalStepIDs(tt.a, tt.b)
+			if sign(got) != tt.want {
+				t.Errorf("compareHierarchicalStepIDs(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortExecutionSteps(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	tests := []struct {
+		name  string
+		steps []ExecutionStep
+		want  []string
+	}{
+		{
+			name: "goto loop keeps runs in order",
+			steps: []ExecutionStep{
+				{StepName: "pick", HierarchicalStepID: "main:S10", StartedAt: at(0)},
+				{StepName: "pick", HierarchicalStepID: "main:S10", StartedAt: at(20)},
+				{StepName: "check", HierarchicalStepID: "main:S20", StartedAt: at(10)},
+				{StepName: "check", HierarchicalStepID: "main:S20", StartedAt: at(30)},
+			},
+			want: []string{"pick@0s", "check@10s", "pick@20s", "check@30s"},
+		},
+		{
+			name: "same start time by hierarchical ID",
+			steps: []ExecutionStep{
+				{StepName: "sub", HierarchicalStepID: "main:S10:sub_pick:S10", StartedAt: at(0)},
+				{StepName: "call", HierarchicalStepID: "main:S10", StartedAt: at(0)},
+				{StepName: "next", HierarchicalStepID: "main:S20", StartedAt: at(5)},
+			},
+			want: []string{"call@0s", "sub@0s", "next@5s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortExecutionSteps(tt.steps)
+			got := make([]string, len(tt.steps))
+			for i, step := range tt.steps {
+				got[i] = step.StepName + "@" + step.StartedAt.Sub(start).String()
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package system
+
+import (
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// startAlarms starts the alarm engine and forwards execution events and
+// machine state changes to it as event types "execution.*" and "machine.<state>"
+func (lm *LifecycleManager) startAlarms() {
+	if !lm.alarmManager.Enabled() {
+		return
+	}
+	ctx, cancel := lm.withTimeout(startupTimeout)
+	defer cancel()
+	if err := lm.alarmManager.Start(ctx); err != nil {
+		lm.logger.Error("Failed to start alarm engine", zap.Error(err))
+		return
+	}
+
+	stop := make(chan struct{})
+	lm.alarmEventsStop = stop
+
+	events := lm.eventStreamer.SubscribeAll()
+	states := lm.machineController.SubscribeState()
+
+	go func() {
+		defer lm.eventStreamer.UnsubscribeAll(events)
+		defer lm.machineController.UnsubscribeState(states)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				lm.alarmManager.HandleEvent(event.EventType, map[string]any{
+					"execution_id": event.ExecutionID.String(),
+				})
+			case change, ok := <-states:
+				if !ok {
+					return
+				}
+				lm.alarmManager.HandleEvent("machine."+string(change.Status.State), map[string]any{
+					"previous_state": string(change.PreviousState),
+					"error_message":  change.Status.ErrorMessage,
+				})
+			}
+		}
+	}()
+}
+
+// stopAlarms stops event forwarding and condition evaluation
+func (lm *LifecycleManager) stopAlarms() {
+	if lm.alarmEventsStop != nil {
+		close(lm.alarmEventsStop)
+		lm.alarmEventsStop = nil
+	}
+	lm.alarmManager.Stop()
+}
+
+// alarmHandlerAdapter adapts the alarm manager to the WebSocket AlarmHandler
+type alarmHandlerAdapter struct {
+	manager *alarms.Manager
+}
+
+func (a *alarmHandlerAdapter) AcknowledgeAlarm(id uuid.UUID, actor string) (any, error) {
+	return a.manager.Acknowledge(id, actor)
+}
+
+func (a *alarmHandlerAdapter) ResetAlarm(id uuid.UUID, actor string) (any, error) {
+	return a.manager.Reset(id, actor)
+}
@@ -0,0 +1,72 @@
+package system
+
+import (
+	"context"
+	"time"
+
+	ws "github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// auditTimeout bounds recording an audit entry outside of a request
+const auditTimeout = 5 * time.Second
+
+// AuditWriteRejected records a device write rejected by a write limit
+const AuditWriteRejected = "device.write_rejected"
+
+// commandAuditAdapter records WebSocket command audits in the audit log
+type commandAuditAdapter struct {
+	storage *storage.PostgresClient
+	logger  *zap.Logger
+}
+
+func (a *commandAuditAdapter) AuditCommand(action, actor string, details map[string]any) {
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+
+	entry := &storage.AuditEntry{Action: action, Actor: actor, Details: details}
+	if err := a.storage.RecordAudit(ctx, entry); err != nil {
+		a.logger.Error("Failed to record audit entry",
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
+
+// writeLimitAuditor records writes rejected by write limits in the audit log
+// and reports them to clients as device warnings
+func writeLimitAuditor(store *storage.PostgresClient, wsHub *ws.Hub, logger *zap.Logger) modbus.WriteLimitHandler {
+	return func(ctx context.Context, device *modbus.Device, violation *modbus.WriteLimitError) {
+		logger.Warn("Device write rejected by write limit",
+			zap.String("device", device.Name),
+			zap.String("register", violation.Register),
+			zap.String("rule", violation.Rule),
+			zap.String("writer", violation.Writer))
+
+		wsHub.Broadcast(ws.NewMessage(ws.MessageTypeDeviceWarning, ws.DeviceWarningData{
+			DeviceID:   device.ID.String(),
+			DeviceName: device.Name,
+			Code:       "WRITE_LIMIT",
+			Message:    violation.Error(),
+			Details:    violation,
+		}))
+
+		auditCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+		defer cancel()
+
+		entry := &storage.AuditEntry{Action: AuditWriteRejected, Actor: violation.Writer, Details: map[string]any{
+			"device":   violation.Device,
+			"register": violation.Register,
+			"rule":     violation.Rule,
+			"value":    violation.Value,
+			"limit":    violation.Limit,
+			"previous": violation.Previous,
+		}}
+		if err := store.RecordAudit(auditCtx, entry); err != nil {
+			logger.Error("Failed to record audit entry",
+				zap.String("action", AuditWriteRejected),
+				zap.Error(err))
+		}
+	}
+}
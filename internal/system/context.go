@@ -0,0 +1,44 @@
+package system
+
+import (
+	"context"
+	"time"
+)
+
+// startupTimeout bounds each storage call made while starting
+const startupTimeout = 30 * time.Second
+
+// newContext creates the root context of background work and hands it to
+// the components that start work from it. Shutdown cancels it.
+func (lm *LifecycleManager) newContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	lm.rootMu.Lock()
+	lm.rootCtx = ctx
+	lm.rootCancel = cancel
+	lm.rootMu.Unlock()
+
+	lm.workflowEngine.SetContext(ctx)
+	lm.machineController.SetContext(ctx)
+	lm.deviceManager.SetContext(ctx)
+}
+
+// Context returns the root context; it is done once shutdown begins
+func (lm *LifecycleManager) Context() context.Context {
+	lm.rootMu.RLock()
+	defer lm.rootMu.RUnlock()
+	return lm.rootCtx
+}
+
+// withTimeout derives a context for a bounded call from the root context
+func (lm *LifecycleManager) withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(lm.Context(), timeout)
+}
+
+// cancelContext stops the background work started from the root context
+func (lm *LifecycleManager) cancelContext() {
+	lm.rootMu.RLock()
+	cancel := lm.rootCancel
+	lm.rootMu.RUnlock()
+	cancel()
+}
@@ -0,0 +1,231 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ws "github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/manual"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Audit actions of WebSocket control commands
+const (
+	AuditWSMachineCommand = "websocket.machine_command"
+	AuditWSDeviceWrite    = "websocket.device_write"
+)
+
+// controlRetention is how long request IDs of control commands are
+// remembered; a client retrying later executes the command again
+const controlRetention = 24 * time.Hour
+
+// controlAdapter executes the control commands of WebSocket clients like the
+// REST endpoints do, recording them for idempotent retries
+type controlAdapter struct {
+	storage   *storage.PostgresClient
+	machine   *machine.Controller
+	devices   *devices.Manager
+	manual    *manual.Manager
+	logger    *zap.Logger
+	lastPrune time.Time // Only used by the single control worker
+}
+
+func (a *controlAdapter) ExecuteControl(ctx context.Context, req ws.ControlRequest) (ws.ControlResult, bool) {
+	a.prune(ctx)
+
+	cmd := &storage.ControlCommand{
+		Actor:     req.Actor,
+		RequestID: req.RequestID,
+		Command:   req.Command,
+		Params:    req.Params,
+	}
+	claimed, err := a.storage.ClaimControlCommand(ctx, cmd)
+	if err != nil {
+		// Without the record a retry could execute twice, so don't execute
+		a.logger.Error("Failed to record control command", zap.Error(err))
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "WS_500", Error: "failed to record command"}, false
+	}
+	if !claimed {
+		return a.storedResult(ctx, req), true
+	}
+
+	var result ws.ControlResult
+	switch req.Command {
+	case ws.CommandMachine:
+		result = a.machineCommand(ctx, req)
+	case ws.CommandDeviceWrite:
+		result = a.deviceWrite(ctx, req)
+	default:
+		result = ws.ControlResult{Status: ws.ControlFailed, Code: "WS_400", Error: "unknown command"}
+	}
+
+	if err := a.storage.CompleteControlCommand(ctx, cmd.ID, result.Status, controlResultMap(result)); err != nil {
+		a.logger.Error("Failed to store control command result", zap.Error(err))
+	}
+
+	action := AuditWSMachineCommand
+	if req.Command == ws.CommandDeviceWrite {
+		action = AuditWSDeviceWrite
+	}
+	entry := &storage.AuditEntry{Action: action, Actor: req.Actor, Details: map[string]any{
+		"request_id": req.RequestID,
+		"params":     req.Params,
+		"status":     result.Status,
+		"error":      result.Error,
+	}}
+	if err := a.storage.RecordAudit(ctx, entry); err != nil {
+		a.logger.Error("Failed to record audit entry", zap.String("action", action), zap.Error(err))
+	}
+
+	return result, false
+}
+
+// machineCommand runs a machine_command frame, see POST /machine/command
+func (a *controlAdapter) machineCommand(ctx context.Context, req ws.ControlRequest) ws.ControlResult {
+	command, _ := req.Params["command"].(string)
+	if command == "" {
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "MACHINE_400", Error: "command is required"}
+	}
+	queue, _ := req.Params["queue"].(bool)
+	reason, _ := req.Params["reason"].(string)
+	orderID, _ := req.Params["order_id"].(string)
+	batchID, _ := req.Params["batch_id"].(string)
+	serialNumber, _ := req.Params["serial_number"].(string)
+
+	result, err := a.machine.ExecuteCommand(ctx, machine.Command(command), machine.CommandOptions{
+		Queue:  queue,
+		Reason: reason,
+		Actor:  req.Actor,
+		Correlation: storage.Correlation{
+			OrderID:      orderID,
+			BatchID:      batchID,
+			SerialNumber: serialNumber,
+		},
+	})
+	if err != nil {
+		var rejection *machine.CommandRejection
+		if errors.As(err, &rejection) {
+			return ws.ControlResult{Status: ws.ControlRejected, Code: "MACHINE_409", Error: rejection.Reason, Details: map[string]any{
+				"reason_code":        rejection.Code,
+				"state":              rejection.State,
+				"allowed_commands":   machine.AllowedCommands(rejection.State),
+				"queueable_commands": machine.QueueableCommands(rejection.State),
+			}}
+		}
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "MACHINE_400", Error: err.Error()}
+	}
+
+	status := ws.ControlAccepted
+	if result == machine.CommandQueued {
+		status = ws.ControlQueued
+	}
+	return ws.ControlResult{Status: status, Details: map[string]any{"command": command}}
+}
+
+// deviceWrite runs a device_write frame, see POST /devices/:id/write. The
+// device is given by ID or name; session_id writes in a manual control session.
+func (a *controlAdapter) deviceWrite(ctx context.Context, req ws.ControlRequest) ws.ControlResult {
+	deviceRef, _ := req.Params["device_id"].(string)
+	register, _ := req.Params["register"].(string)
+	value, hasValue := req.Params["value"]
+	if deviceRef == "" || register == "" || !hasValue {
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "DEVICE_400", Error: "device_id, register and value are required"}
+	}
+
+	var device *modbus.Device
+	var exists bool
+	if id, err := uuid.Parse(deviceRef); err == nil {
+		device, exists = a.devices.GetDevice(id)
+	} else {
+		device, exists = a.devices.GetDeviceByName(deviceRef)
+	}
+	if !exists {
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "DEVICE_404", Error: fmt.Sprintf("device not found: %s", deviceRef)}
+	}
+
+	ctx = modbus.WithWriter(ctx, req.Actor)
+	var err error
+	sessionRef, _ := req.Params["session_id"].(string)
+	switch {
+	case sessionRef != "":
+		sessionID, parseErr := uuid.Parse(sessionRef)
+		if parseErr != nil {
+			return ws.ControlResult{Status: ws.ControlFailed, Code: "MANUAL_400", Error: "invalid session_id"}
+		}
+		_, err = a.manual.Write(ctx, sessionID, req.Actor, device, register, value)
+	case a.manual.RequireSession():
+		return ws.ControlResult{Status: ws.ControlRejected, Code: "MANUAL_428", Error: manual.ErrSessionRequired.Error()}
+	default:
+		err = device.WriteLogical(ctx, register, value)
+	}
+	switch {
+	case errors.Is(err, manual.ErrSessionNotFound):
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "MANUAL_404", Error: err.Error()}
+	case errors.Is(err, manual.ErrNotOwner):
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "MANUAL_403", Error: err.Error()}
+	case errors.Is(err, modbus.ErrWriteLimit):
+		return ws.ControlResult{Status: ws.ControlRejected, Code: "DEVICE_422", Error: err.Error()}
+	case err != nil:
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "DEVICE_500", Error: err.Error()}
+	}
+	return ws.ControlResult{Status: ws.ControlWritten, Details: map[string]any{
+		"device_id": device.ID,
+		"register":  register,
+		"value":     value,
+	}}
+}
+
+// storedResult returns the result of an earlier command with the same request ID
+func (a *controlAdapter) storedResult(ctx context.Context, req ws.ControlRequest) ws.ControlResult {
+	cmd, err := a.storage.GetControlCommand(ctx, req.Actor, req.RequestID)
+	if err != nil {
+		a.logger.Error("Failed to load control command", zap.Error(err))
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "WS_500", Error: "failed to load earlier result"}
+	}
+	if cmd.Command != req.Command {
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "WS_409", Error: fmt.Sprintf("request_id was used for %s", cmd.Command)}
+	}
+	if cmd.Status == "" {
+		// Interrupted by a restart before it completed
+		return ws.ControlResult{Status: ws.ControlFailed, Code: "WS_409", Error: "earlier command with this request_id did not complete, use a new request_id"}
+	}
+
+	result := ws.ControlResult{Status: cmd.Status}
+	result.Code, _ = cmd.Result["code"].(string)
+	result.Error, _ = cmd.Result["error"].(string)
+	result.Details, _ = cmd.Result["details"].(map[string]any)
+	return result
+}
+
+// prune deletes expired request IDs at most once per hour
+func (a *controlAdapter) prune(ctx context.Context) {
+	if time.Since(a.lastPrune) < time.Hour {
+		return
+	}
+	a.lastPrune = time.Now()
+
+	if _, err := a.storage.DeleteControlCommandsBefore(ctx, time.Now().Add(-controlRetention)); err != nil {
+		a.logger.Warn("Failed to delete expired control commands", zap.Error(err))
+	}
+}
+
+func controlResultMap(result ws.ControlResult) map[string]any {
+	m := map[string]any{"status": result.Status}
+	if result.Code != "" {
+		m["code"] = result.Code
+	}
+	if result.Error != "" {
+		m["error"] = result.Error
+	}
+	if result.Details != nil {
+		m["details"] = result.Details
+	}
+	return m
+}
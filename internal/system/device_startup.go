@@ -0,0 +1,229 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"go.uber.org/zap"
+)
+
+// loadDevicesFromDB composes and connects the stored devices, up to
+// device_profiles.startup.concurrency at once, so offline couplers don't
+// delay the others. Devices that fail to connect are retried in the
+// background.
+func (lm *LifecycleManager) loadDevicesFromDB() error {
+	ctx, cancel := lm.withTimeout(startupTimeout)
+	compositions, err := lm.storage.LoadAllDeviceCompositions(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to load compositions: %w", err)
+	}
+
+	concurrency := max(lm.config.Devices.Startup.Concurrency, 1)
+	report := &interfaces.DeviceStartupReport{
+		StartedAt:   time.Now(),
+		Concurrency: concurrency,
+		Devices:     make([]interfaces.DeviceStartup, len(compositions)),
+	}
+	for i, comp := range compositions {
+		report.Devices[i].InstanceID = comp.InstanceID
+	}
+	lm.startupMu.Lock()
+	lm.deviceStartup = report
+	lm.startupMu.Unlock()
+
+	lm.logger.Info("Loading devices from database",
+		zap.Int("count", len(compositions)),
+		zap.Int("concurrency", concurrency))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(compositions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				lm.startDevice(report, i, compositions[i])
+			}
+		}()
+	}
+	for i := range compositions {
+		if lm.Context().Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := lm.Context().Err(); err != nil {
+		return fmt.Errorf("stopped while loading devices: %w", err)
+	}
+
+	lm.startupMu.Lock()
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	countDeviceStartup(report)
+	summary := *report
+	lm.startupMu.Unlock()
+
+	lm.logger.Info("Devices loaded",
+		zap.Int("loaded", summary.Loaded),
+		zap.Int("retrying", summary.Retrying),
+		zap.Int("failed", summary.Failed),
+		zap.Int64("duration_ms", summary.DurationMs))
+
+	for i, comp := range compositions {
+		if summary.Devices[i].Status == interfaces.DeviceStartupRetrying {
+			go lm.retryDevice(report, i, comp)
+		}
+	}
+
+	return nil
+}
+
+// startDevice makes the first attempt to load a stored device
+func (lm *LifecycleManager) startDevice(report *interfaces.DeviceStartupReport, i int, comp types.DeviceComposition) {
+	started := time.Now()
+	err := lm.loadDevice(comp)
+
+	lm.startupMu.Lock()
+	defer lm.startupMu.Unlock()
+	entry := &report.Devices[i]
+	entry.DurationMs = time.Since(started).Milliseconds()
+	lm.recordDeviceAttempt(entry, err)
+}
+
+// retryDevice loads a device that was offline at startup once it becomes
+// reachable. It gives up when the device was deleted, disabled or loaded
+// otherwise, e.g. by recreating it.
+func (lm *LifecycleManager) retryDevice(report *interfaces.DeviceStartupReport, i int, comp types.DeviceComposition) {
+	ctx := lm.Context()
+	ticker := time.NewTicker(lm.config.Devices.Startup.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, loaded := lm.deviceManager.GetDeviceByName(comp.InstanceID); loaded {
+			lm.finishDeviceRetry(report, i, nil)
+			return
+		}
+		exists, enabled, err := lm.storage.DeviceExistsEnabledByName(ctx, comp.InstanceID)
+		if err == nil && (!exists || !enabled) {
+			lm.finishDeviceRetry(report, i, errors.New("device deleted or disabled"))
+			return
+		}
+
+		err = lm.loadDevice(comp)
+
+		lm.startupMu.Lock()
+		entry := &report.Devices[i]
+		lm.recordDeviceAttempt(entry, err)
+		countDeviceStartup(report)
+		status := entry.Status
+		lm.startupMu.Unlock()
+
+		if status != interfaces.DeviceStartupRetrying {
+			return
+		}
+	}
+}
+
+// loadDevice composes and connects a device and starts its poller
+func (lm *LifecycleManager) loadDevice(comp types.DeviceComposition) error {
+	timeout := time.Duration(lm.config.Modbus.DefaultTimeout)
+	device, err := lm.deviceManager.LoadDeviceFromComposition(comp, timeout)
+	if err != nil {
+		lm.logger.Error("Failed to load device",
+			zap.String("instance_id", comp.InstanceID),
+			zap.Error(err))
+		return err
+	}
+
+	pollInterval := time.Duration(lm.config.Modbus.DefaultPollInterval)
+	if err := lm.deviceManager.StartPoller(device.ID, pollInterval); err != nil {
+		lm.logger.Error("Failed to start poller",
+			zap.String("instance_id", comp.InstanceID),
+			zap.Error(err))
+	}
+
+	lm.logger.Info("Device loaded and poller started",
+		zap.String("instance_id", comp.InstanceID))
+	return nil
+}
+
+// recordDeviceAttempt updates a startup entry with the result of a load.
+// Only connection failures are retried. Callers hold startupMu.
+func (lm *LifecycleManager) recordDeviceAttempt(entry *interfaces.DeviceStartup, err error) {
+	entry.Attempts++
+	switch {
+	case err == nil:
+		now := time.Now()
+		entry.Status = interfaces.DeviceStartupLoaded
+		entry.Error = ""
+		entry.LoadedAt = &now
+	case errors.Is(err, devices.ErrConnect) && lm.config.Devices.Startup.RetryInterval > 0:
+		entry.Status = interfaces.DeviceStartupRetrying
+		entry.Error = err.Error()
+	default:
+		entry.Status = interfaces.DeviceStartupFailed
+		entry.Error = err.Error()
+	}
+}
+
+// finishDeviceRetry ends the background retry of a device without loading
+// it; a nil reason means the device was loaded otherwise
+func (lm *LifecycleManager) finishDeviceRetry(report *interfaces.DeviceStartupReport, i int, reason error) {
+	lm.startupMu.Lock()
+	defer lm.startupMu.Unlock()
+
+	entry := &report.Devices[i]
+	if reason == nil {
+		now := time.Now()
+		entry.Status = interfaces.DeviceStartupLoaded
+		entry.Error = ""
+		entry.LoadedAt = &now
+	} else {
+		entry.Status = interfaces.DeviceStartupFailed
+		entry.Error = reason.Error()
+	}
+	countDeviceStartup(report)
+}
+
+// countDeviceStartup recounts the devices per status. Callers hold startupMu.
+func countDeviceStartup(report *interfaces.DeviceStartupReport) {
+	report.Loaded, report.Retrying, report.Failed = 0, 0, 0
+	for _, entry := range report.Devices {
+		switch entry.Status {
+		case interfaces.DeviceStartupLoaded:
+			report.Loaded++
+		case interfaces.DeviceStartupRetrying:
+			report.Retrying++
+		case interfaces.DeviceStartupFailed:
+			report.Failed++
+		}
+	}
+}
+
+// DeviceStartup returns a copy of the device startup report, nil before
+// devices were loaded
+func (lm *LifecycleManager) DeviceStartup() *interfaces.DeviceStartupReport {
+	lm.startupMu.RLock()
+	defer lm.startupMu.RUnlock()
+
+	if lm.deviceStartup == nil {
+		return nil
+	}
+	report := *lm.deviceStartup
+	report.Devices = append([]interfaces.DeviceStartup(nil), lm.deviceStartup.Devices...)
+	return &report
+}
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/auth"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// GRPCServices are the services of the gRPC API, see internal/api/grpc. They
+// are set from outside so only the API package is built on the generated
+// code.
+type GRPCServices interface {
+	Register(server grpc.ServiceRegistrar)
+	Names() []string
+	Permission(fullMethod string) (auth.Permission, bool)
+}
+
+// grpcPermission returns the permission of a gRPC method. The health and
+// reflection services are public; methods unknown to the services require
+// admin, so a new method isn't open by accident.
+func grpcPermission(services GRPCServices) auth.MethodPermission {
+	return func(fullMethod string) (auth.Permission, bool) {
+		if strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") || strings.HasPrefix(fullMethod, "/grpc.reflection.") {
+			return "", false
+		}
+		if services != nil {
+			if permission, ok := services.Permission(fullMethod); ok {
+				return permission, true
+			}
+		}
+		return auth.PermAdmin, true
+	}
+}
+
+// grpcServerOptions builds the server options for authentication, message
+// sizes and keepalive
+func grpcServerOptions(cfg config.GRPCConfig, authService *auth.AuthService, services GRPCServices) []grpc.ServerOption {
+	unary, stream := authService.GRPCInterceptors(grpcPermission(services))
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary),
+		grpc.ChainStreamInterceptor(stream),
+	}
+
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize))
+	}
+
+	ka := cfg.Keepalive
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     ka.MaxConnectionIdle,
+			MaxConnectionAge:      ka.MaxConnectionAge,
+			MaxConnectionAgeGrace: ka.MaxConnectionAgeGrace,
+			Time:                  ka.Time,
+			Timeout:               ka.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             ka.MinTime,
+			PermitWithoutStream: ka.PermitWithoutStream,
+		}),
+	)
+
+	return opts
+}
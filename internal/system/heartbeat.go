@@ -0,0 +1,183 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
+	"go.uber.org/zap"
+)
+
+// HeartbeatWriter toggles a watchdog output so downstream safety relays
+// know the controller is alive. It stops toggling on shutdown or error,
+// letting the relay's own timeout drop the outputs.
+type HeartbeatWriter struct {
+	cfg           config.HeartbeatConfig
+	deviceManager *devices.Manager
+	logger        *zap.Logger
+
+	mu                  sync.RWMutex
+	running             bool
+	value               bool
+	lastWrite           time.Time
+	lastError           string
+	consecutiveFailures int
+	stopChan            chan struct{}
+	wg                  sync.WaitGroup
+}
+
+func NewHeartbeatWriter(cfg config.HeartbeatConfig, deviceManager *devices.Manager, logger *zap.Logger) *HeartbeatWriter {
+	return &HeartbeatWriter{
+		cfg:           cfg,
+		deviceManager: deviceManager,
+		logger:        logger,
+	}
+}
+
+// Start begins toggling the heartbeat register
+func (h *HeartbeatWriter) Start() error {
+	if !h.cfg.Enabled {
+		return nil
+	}
+	if h.cfg.Device == "" || h.cfg.Register == "" {
+		return fmt.Errorf("heartbeat requires device and register")
+	}
+	if h.cfg.Interval <= 0 {
+		return fmt.Errorf("heartbeat interval must be > 0")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running {
+		return nil
+	}
+
+	h.running = true
+	h.consecutiveFailures = 0
+	h.lastError = ""
+	h.stopChan = make(chan struct{})
+	h.wg.Add(1)
+
+	go h.loop(h.stopChan)
+
+	h.logger.Info("Heartbeat started",
+		zap.String("device", h.cfg.Device),
+		zap.String("register", h.cfg.Register),
+		zap.Duration("interval", h.cfg.Interval))
+
+	return nil
+}
+
+// Stop stops toggling the heartbeat register
+func (h *HeartbeatWriter) Stop() {
+	h.mu.Lock()
+	if !h.running {
+		h.mu.Unlock()
+		return
+	}
+	h.running = false
+	close(h.stopChan)
+	h.mu.Unlock()
+
+	h.wg.Wait()
+
+	h.logger.Info("Heartbeat stopped", zap.String("device", h.cfg.Device))
+}
+
+func (h *HeartbeatWriter) loop(stopChan chan struct{}) {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			h.toggle()
+		}
+	}
+}
+
+func (h *HeartbeatWriter) toggle() {
+	h.mu.RLock()
+	next := !h.value
+	h.mu.RUnlock()
+
+	err := h.write(next)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.consecutiveFailures++
+		h.lastError = err.Error()
+		if h.consecutiveFailures == h.failureThreshold() {
+			h.logger.Error("Heartbeat unhealthy",
+				zap.String("device", h.cfg.Device),
+				zap.String("register", h.cfg.Register),
+				zap.Int("consecutive_failures", h.consecutiveFailures),
+				zap.Error(err))
+		}
+		return
+	}
+
+	h.value = next
+	h.lastWrite = time.Now()
+	h.consecutiveFailures = 0
+	h.lastError = ""
+}
+
+func (h *HeartbeatWriter) write(value bool) error {
+	device, exists := h.deviceManager.GetDeviceByName(h.cfg.Device)
+	if !exists {
+		return fmt.Errorf("device not found: %s", h.cfg.Device)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.Interval)
+	defer cancel()
+
+	if _, mapped := device.IOMapping[h.cfg.Register]; mapped {
+		return device.WriteLogical(ctx, h.cfg.Register, value)
+	}
+	return device.WriteRegister(ctx, h.cfg.Register, value)
+}
+
+func (h *HeartbeatWriter) failureThreshold() int {
+	if h.cfg.FailureThreshold <= 0 {
+		return 1
+	}
+	return h.cfg.FailureThreshold
+}
+
+// Health returns the current heartbeat health for status reporting
+func (h *HeartbeatWriter) Health() *interfaces.HeartbeatStatus {
+	if !h.cfg.Enabled {
+		return nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status := &interfaces.HeartbeatStatus{
+		Running:             h.running,
+		Healthy:             h.running && h.consecutiveFailures < h.failureThreshold(),
+		Device:              h.cfg.Device,
+		Register:            h.cfg.Register,
+		Interval:            h.cfg.Interval.String(),
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastError:           h.lastError,
+	}
+	if !h.lastWrite.IsZero() {
+		lastWrite := h.lastWrite
+		status.LastWrite = &lastWrite
+	}
+
+	return status
+}
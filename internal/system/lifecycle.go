@@ -2,25 +2,44 @@ package system
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
 	"sync"
 	"time"
 
-	pb "github.com/KevinKickass/OpenMachineCore/api/proto"
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
 	"github.com/KevinKickass/OpenMachineCore/internal/api/rest"
 	ws "github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
 	"github.com/KevinKickass/OpenMachineCore/internal/auth"
 	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/counters"
+	"github.com/KevinKickass/OpenMachineCore/internal/descriptors"
 	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/faults"
+	"github.com/KevinKickass/OpenMachineCore/internal/features"
 	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
 	"github.com/KevinKickass/OpenMachineCore/internal/machine"
+	"github.com/KevinKickass/OpenMachineCore/internal/manual"
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/onboarding"
+	"github.com/KevinKickass/OpenMachineCore/internal/outbox"
+	"github.com/KevinKickass/OpenMachineCore/internal/routing"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/service"
+	"github.com/KevinKickass/OpenMachineCore/internal/shifts"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/types"
+	"github.com/KevinKickass/OpenMachineCore/internal/usage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/engine"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/executor"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/hooks"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/streaming"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 // ALLE Type Definitionen (SystemState, UpdateProgress, SystemStatus) ENTFERNEN
@@ -32,14 +51,35 @@ type LifecycleManager struct {
 	deviceManager     *devices.Manager
 	workflowEngine    *engine.Engine
 	eventStreamer     *streaming.EventStreamer
-	workflowService   *streaming.WorkflowService
 	machineController *machine.Controller
 	authService       *auth.AuthService
 	logger            *zap.Logger
 	wsHub             *ws.Hub
-
-	restServer *rest.Server
-	grpcServer *grpc.Server
+	heartbeat         *HeartbeatWriter
+	resources         *ResourceMonitor
+	snapshots         *SnapshotRecorder
+	usageTracker      *usage.Tracker
+	alarmManager      *alarms.Manager
+	alarmEventsStop   chan struct{}
+	shiftScheduler    *shifts.Scheduler
+	validation        *workflow.NightlyValidation
+	descriptorSync    *descriptors.Syncer
+	descriptorWatcher *devices.Watcher
+	counters          *counters.Accumulator
+	faultInjector     *faults.Injector // Nil unless fault injection is enabled
+	secrets           *secrets.Manager
+	outbox            *outbox.Dispatcher
+	features          *features.Service
+	manual            *manual.Manager
+	onboarding        *onboarding.Manager
+	outboxEventsStop  chan struct{}
+	router            *routing.Router
+	routingEventsStop chan struct{}
+
+	restServer   *rest.Server
+	grpcServer   *grpc.Server
+	grpcHealth   *health.Server
+	grpcServices GRPCServices // Optional; without them only health is served
 
 	stateMu        sync.RWMutex
 	currentState   SystemState
@@ -48,8 +88,18 @@ type LifecycleManager struct {
 	listenersMu     sync.RWMutex
 	statusListeners []chan SystemStatus
 
+	// Root of background work, cancelled on shutdown (see context.go)
+	rootMu     sync.RWMutex
+	rootCtx    context.Context
+	rootCancel context.CancelFunc
+
 	shutdownChan chan struct{}
 	shutdownOnce sync.Once
+
+	startedAt time.Time
+
+	startupMu     sync.RWMutex
+	deviceStartup *interfaces.DeviceStartupReport // Nil until devices were loaded
 }
 
 func NewLifecycleManager(
@@ -67,30 +117,307 @@ func NewLifecycleManager(
 	eventStreamer := streaming.NewEventStreamer()
 	stepExecutor := executor.NewStepExecutor(deviceManager, storage)
 	wsHub := ws.NewHub(logger, authService)
+	wsHub.SetReplayBuffer(cfg.Server.WebSocket.ReplayBuffer)
 	workflowEngine := engine.NewEngine(storage, stepExecutor, eventStreamer, logger, wsHub)
-	workflowService := streaming.NewWorkflowService(eventStreamer, storage)
 
 	// Initialize Machine Controller
 	machineController := machine.NewController(logger, workflowEngine, storage, wsHub)
 
+	workflowEngine.SetPayloadLimits(cfg.Limits)
+	stepExecutor.SetMaxCallDepth(cfg.Limits.MaxCallDepth)
+	stepExecutor.SetMaxLoopJumps(cfg.Limits.MaxLoopJumps)
+	if err := workflowEngine.SetExecutionLogs(cfg.ExecLogs); err != nil {
+		logger.Fatal("Invalid execution log configuration", zap.Error(err))
+	}
+	workflowEngine.SetCheckpoints(cfg.Checkpoints)
+
+	// Safe state policy; the executor reports the writes to the step hooks
+	if cfg.SafeState.OnCancel {
+		workflowEngine.SetSafeStateApplier(stepExecutor, cfg.SafeState.WriteTimeout)
+	}
+	if cfg.SafeState.OnStopFailure {
+		machineController.SetSafeStateApplier(deviceManager, cfg.SafeState.WriteTimeout)
+	}
+
+	// Alarm engine; acknowledge and reset are also accepted over WebSocket
+	alarmManager := alarms.NewManager(cfg.Alarms, storage, deviceManager, wsHub, logger)
+	wsHub.SetAlarmHandler(&alarmHandlerAdapter{manager: alarmManager})
+	wsHub.SetCommandAuditor(&commandAuditAdapter{storage: storage, logger: logger})
+
+	// Dead-man switch of manual control; clients see ended sessions
+	manualControl := manual.NewManager(cfg.Manual, storage, deviceManager, cfg.SafeState.WriteTimeout, logger)
+	manualControl.SetEndHandler(func(session manual.Session) {
+		wsHub.Broadcast(ws.NewMessage(ws.MessageTypeManualSessionEnded, session))
+		if session.EndReason == manual.ReasonExpired {
+			alarmManager.HandleEvent("manual.session_expired", map[string]any{
+				"session_id": session.ID.String(),
+				"actor":      session.Actor,
+			})
+		}
+	})
+
+	// Guided device commissioning; checks are prompted and answered over WebSocket
+	deviceOnboarding := onboarding.NewManager(cfg.Onboarding, storage, deviceManager, cfg.Modbus.DefaultTimeout, logger)
+	deviceOnboarding.SetHandlers(
+		func(session onboarding.Session, check onboarding.Check) {
+			wsHub.Broadcast(ws.NewMessage(ws.MessageTypeOnboardingPrompt, ws.OnboardingPromptData{
+				SessionID:  session.ID.String(),
+				InstanceID: session.InstanceID,
+				Check:      check,
+			}))
+		},
+		func(session onboarding.Session) {
+			wsHub.Broadcast(ws.NewMessage(ws.MessageTypeOnboardingUpdated, session))
+		},
+	)
+	wsHub.SetOnboardingHandler(&onboardingHandlerAdapter{manager: deviceOnboarding})
+
+	wsHub.SetControlHandler(&controlAdapter{
+		storage: storage,
+		machine: machineController,
+		devices: deviceManager,
+		manual:  manualControl,
+		logger:  logger,
+	}, cfg.Server.WebSocket.CommandQueue)
+
+	// Shift calendar automation
+	shiftScheduler, err := shifts.NewScheduler(cfg.Shifts, storage, machineController, logger)
+	if err != nil {
+		logger.Fatal("Failed to create shift scheduler", zap.Error(err))
+	}
+
+	// Executions are tagged with the shift they run in
+	workflowEngine.SetLabeler(func(ctx context.Context) ([]string, map[string]any) {
+		shift, err := shiftScheduler.CurrentShift(ctx)
+		if err != nil {
+			logger.Warn("Failed to get current shift for execution tags", zap.Error(err))
+			return nil, nil
+		}
+		if shift == nil {
+			return nil, nil
+		}
+		return []string{"shift:" + shift.Name}, map[string]any{"shift": shift.Name}
+	})
+
+	// Changed descriptors are picked up by devices loaded afterwards
+	descriptorWatcher, err := devices.NewWatcher(cfg.Devices.SearchPaths, deviceManager.ClearProfileCache, logger)
+	if err != nil {
+		logger.Fatal("Failed to create descriptor watcher", zap.Error(err))
+	}
+
+	// Bulk and nightly workflow validation; deprecated register names come from loaded devices
+	if err := workflow.CheckLintConfig(cfg.Lint); err != nil {
+		logger.Fatal("Invalid lint configuration", zap.Error(err))
+	}
+	validation := workflow.NewNightlyValidation(cfg.Validation, cfg.Lint, storage, func(deviceName string) map[string]string {
+		device, exists := deviceManager.GetDeviceByName(deviceName)
+		if !exists {
+			return nil
+		}
+		return device.Aliases()
+	}, logger)
+
+	// Encrypted credentials for integrations; unavailable without master key
+	keyring, err := secrets.NewKeyring(cfg.Secrets)
+	if err != nil {
+		if !errors.Is(err, secrets.ErrNoMasterKey) {
+			logger.Fatal("Failed to load master key", zap.Error(err))
+		}
+		logger.Info("No master key configured, secrets are unavailable")
+	}
+
+	secretStore := secrets.NewManager(keyring, storage, logger)
+
+	// External systems consulted before and after steps, e.g. an interlock server
+	stepHooks, err := hooks.FromConfig(cfg.StepHooks, secretStore, logger)
+	if err != nil {
+		logger.Fatal("Invalid step hook configuration", zap.Error(err))
+	}
+	stepExecutor.SetHooks(stepHooks)
+
+	// Event outbox for integrations; execution events are added with the event itself
+	outboxDispatcher, err := outbox.NewDispatcher(cfg.Outbox, storage, secretStore, logger)
+	if err != nil {
+		logger.Fatal("Invalid outbox configuration", zap.Error(err))
+	}
+	storage.SetOutboxEnabled(outboxDispatcher.Enabled())
+
+	// Rules forwarding events to WebSocket topics, webhooks, MQTT and alarms
+	router, err := routing.New(cfg.Routing, wsHub, alarmManager, secretStore, logger)
+	if err != nil {
+		logger.Fatal("Invalid routing configuration", zap.Error(err))
+	}
+	alarmManager.SetChangeHandler(alarmRouter(router))
+
+	// Feature flags, loaded on start; clients and integrations see changes
+	featureFlags := features.NewService(storage, logger)
+	featureFlags.OnChange(func(flag features.Flag) {
+		wsHub.Broadcast(ws.NewMessage(ws.MessageTypeFeatureChanged, flag))
+		payload := map[string]any{
+			"flag":      flag.Name,
+			"enabled":   flag.Enabled,
+			"workflows": flag.Workflows,
+		}
+		outboxDispatcher.Publish(context.Background(), outbox.SourceSystem, "feature.changed", payload)
+		router.Route(routing.SourceSystem, "feature.changed", payload)
+	})
+
+	// Clients and integrations follow switches of the active workflow
+	workflowEngine.SetActivationHandler(func(event engine.ActivationEvent) {
+		wsHub.Broadcast(ws.NewMessage(ws.MessageTypeWorkflowActivation, event))
+		payload := map[string]any{
+			"workflow_id":          event.WorkflowID,
+			"previous_workflow_id": event.PreviousWorkflowID,
+			"executions":           event.Executions,
+			"forced":               event.Forced,
+			"actor":                event.Actor,
+		}
+		outboxDispatcher.Publish(context.Background(), outbox.SourceWorkflow, "workflow.activation_"+event.Phase, payload)
+		router.Route(routing.SourceWorkflow, "workflow.activation_"+event.Phase, payload)
+	})
+
+	// Restore stored calibrations whenever a device is loaded
+	deviceManager.SetCalibrationSource(storage.DeviceCalibrations)
+	deviceManager.SetStaleAfter(cfg.Modbus.StaleAfter)
+
+	// Persist device identification and warn about mismatching or swapped hardware
+	deviceManager.SetIdentitySource(storage.DeviceReferenceIdentity)
+	deviceManager.SetIdentityHandler(func(device *modbus.Device, identity *types.DeviceIdentity) {
+		if err := storage.UpdateDeviceIdentity(context.Background(), device.Name, identity); err != nil {
+			logger.Warn("Failed to store device identity",
+				zap.String("device", device.Name),
+				zap.Error(err))
+		}
+		if len(identity.Changes) > 0 {
+			wsHub.Broadcast(ws.NewMessage(ws.MessageTypeDeviceWarning, ws.DeviceWarningData{
+				DeviceID:   device.ID.String(),
+				DeviceName: device.Name,
+				Code:       "IDENTITY_CHANGED",
+				Message:    "Connected hardware differs from the hardware the device was onboarded with",
+				Details:    identity.Changes,
+			}))
+		}
+		if len(identity.Mismatches) > 0 {
+			wsHub.Broadcast(ws.NewMessage(ws.MessageTypeDeviceWarning, ws.DeviceWarningData{
+				DeviceID:   device.ID.String(),
+				DeviceName: device.Name,
+				Code:       "IDENTITY_MISMATCH",
+				Message:    "Connected hardware doesn't match the configured modules",
+				Details:    identity.Mismatches,
+			}))
+		}
+		if len(identity.Mismatches) > 0 || len(identity.Changes) > 0 {
+			alarmManager.HandleEvent("device.identity_mismatch", map[string]any{
+				"device":     device.Name,
+				"mismatches": identity.Mismatches,
+				"changes":    identity.Changes,
+			})
+			payload := map[string]any{
+				"device_id":  device.ID.String(),
+				"device":     device.Name,
+				"mismatches": identity.Mismatches,
+				"changes":    identity.Changes,
+			}
+			outboxDispatcher.Publish(context.Background(), outbox.SourceDevice, "device.identity_mismatch", payload)
+			router.Route(routing.SourceDevice, "device.identity_mismatch", payload)
+		}
+	})
+
+	// Lost devices are dialed again in the background; clients, alarms and
+	// integrations follow their connection
+	deviceManager.SetReconnectBackoff(cfg.Modbus.ReconnectMinDelay, cfg.Modbus.ReconnectMaxDelay)
+	deviceManager.SetConnectionHandler(func(device *modbus.Device, event modbus.ConnectionEvent) {
+		data := ws.DeviceConnectionData{
+			DeviceID:   device.ID.String(),
+			DeviceName: device.Name,
+		}
+		payload := map[string]any{
+			"device_id": device.ID.String(),
+			"device":    device.Name,
+		}
+
+		if event.State == modbus.ConnectionLost {
+			data.Error = event.Err.Error()
+			payload["error"] = data.Error
+			wsHub.Broadcast(ws.NewMessage(ws.MessageTypeDeviceError, data))
+			alarmManager.HandleEvent("device.connection_lost", map[string]any{
+				"device": device.Name,
+				"error":  data.Error,
+			})
+			outboxDispatcher.Publish(context.Background(), outbox.SourceDevice, "device.connection_lost", payload)
+			router.Route(routing.SourceDevice, "device.connection_lost", payload)
+			return
+		}
+
+		data.DowntimeMs = float64(event.Downtime.Milliseconds())
+		payload["downtime_ms"] = data.DowntimeMs
+		wsHub.Broadcast(ws.NewMessage(ws.MessageTypeDeviceConnected, data))
+		outboxDispatcher.Publish(context.Background(), outbox.SourceDevice, "device.reconnected", payload)
+		router.Route(routing.SourceDevice, "device.reconnected", payload)
+	})
+
+	// Writes rejected by write limits are audited; clients see them as device warnings
+	deviceManager.SetWriteLimitHandler(writeLimitAuditor(storage, wsHub, logger))
+
+	// Preconditions of workflows are checked against devices and machine state
+	workflowEngine.SetEnvironment(&environmentAdapter{devices: deviceManager, machine: machineController})
+
 	// Set machine controller as status provider for WebSocket via wrapper
 	wsHub.SetMachineStatusProvider(&machineStatusAdapter{controller: machineController})
 
-	return &LifecycleManager{
+	// Developer-mode fault injection for devices, database and WebSocket
+	var faultInjector *faults.Injector
+	if cfg.Faults.Enabled {
+		faultInjector = faults.NewInjector(cfg.Faults, logger)
+		deviceManager.SetFaultSource(faultInjector.DeviceHook)
+		storage.SetLatencyHook(faultInjector.QueryLatency)
+		wsHub.SetConnectionFaults(faultInjector)
+		logger.Warn("Fault injection is enabled - never use this on a production machine")
+	}
+
+	// Self-monitoring; rejects new executions while resources are exhausted
+	resources := NewResourceMonitor(cfg.Resources, storage, wsHub, alarmManager, logger)
+	workflowEngine.SetAdmission(resources.Admit)
+
+	lm := &LifecycleManager{
 		config:            cfg,
 		storage:           storage,
 		deviceManager:     deviceManager,
 		workflowEngine:    workflowEngine,
 		eventStreamer:     eventStreamer,
-		workflowService:   workflowService,
 		machineController: machineController,
 		authService:       authService,
 		logger:            logger,
 		wsHub:             wsHub,
+		heartbeat:         NewHeartbeatWriter(cfg.Heartbeat, deviceManager, logger),
+		resources:         resources,
+		snapshots:         NewSnapshotRecorder(cfg.Snapshots, storage, deviceManager, logger),
+		usageTracker:      usage.NewTracker(cfg.Usage, storage, logger),
+		alarmManager:      alarmManager,
+		shiftScheduler:    shiftScheduler,
+		validation:        validation,
+		descriptorSync:    descriptors.NewSyncer(cfg.Devices.Sync, storage, deviceManager.ClearProfileCache, logger),
+		descriptorWatcher: descriptorWatcher,
+		counters:          counters.NewAccumulator(cfg.Counters, storage, deviceManager, logger),
+		faultInjector:     faultInjector,
+		secrets:           secretStore,
+		outbox:            outboxDispatcher,
+		router:            router,
+		features:          featureFlags,
+		manual:            manualControl,
+		onboarding:        deviceOnboarding,
 		currentState:      StateInitializing,
 		shutdownChan:      make(chan struct{}),
 		statusListeners:   make([]chan SystemStatus, 0),
+		startedAt:         time.Now(),
 	}
+	lm.newContext()
+	return lm
+}
+
+// SetGRPCServices sets the services of the gRPC API. Call it before Start.
+func (lm *LifecycleManager) SetGRPCServices(services GRPCServices) {
+	lm.grpcServices = services
 }
 
 // MachineController returns the machine controller
@@ -98,6 +425,76 @@ func (lm *LifecycleManager) MachineController() *machine.Controller {
 	return lm.machineController
 }
 
+// AlarmManager returns the alarm engine
+func (lm *LifecycleManager) AlarmManager() *alarms.Manager {
+	return lm.alarmManager
+}
+
+// ShiftScheduler returns the shift calendar automation
+func (lm *LifecycleManager) ShiftScheduler() *shifts.Scheduler {
+	return lm.shiftScheduler
+}
+
+// Validation returns the bulk and nightly workflow validation
+func (lm *LifecycleManager) Validation() *workflow.NightlyValidation {
+	return lm.validation
+}
+
+// DescriptorSync returns the vendor descriptor pack sync
+func (lm *LifecycleManager) DescriptorSync() *descriptors.Syncer {
+	return lm.descriptorSync
+}
+
+// DescriptorWatcher returns the watch of the descriptor search paths
+func (lm *LifecycleManager) DescriptorWatcher() *devices.Watcher {
+	return lm.descriptorWatcher
+}
+
+// Manual returns the manual control sessions
+func (lm *LifecycleManager) Manual() *manual.Manager {
+	return lm.manual
+}
+
+// Onboarding returns the guided device onboardings
+func (lm *LifecycleManager) Onboarding() *onboarding.Manager {
+	return lm.onboarding
+}
+
+// Counters returns the counter accumulation
+func (lm *LifecycleManager) Counters() *counters.Accumulator {
+	return lm.counters
+}
+
+// FaultInjector returns the fault injection, nil unless enabled
+func (lm *LifecycleManager) FaultInjector() *faults.Injector {
+	return lm.faultInjector
+}
+
+// Secrets returns the encrypted credential store
+func (lm *LifecycleManager) Secrets() *secrets.Manager {
+	return lm.secrets
+}
+
+// Outbox returns the event outbox dispatcher
+func (lm *LifecycleManager) Outbox() *outbox.Dispatcher {
+	return lm.outbox
+}
+
+// Router returns the event router
+func (lm *LifecycleManager) Router() *routing.Router {
+	return lm.router
+}
+
+// Features returns the feature flag service
+func (lm *LifecycleManager) Features() *features.Service {
+	return lm.features
+}
+
+// UsageTracker returns the API usage tracker
+func (lm *LifecycleManager) UsageTracker() *usage.Tracker {
+	return lm.usageTracker
+}
+
 // Start starts the entire system
 func (lm *LifecycleManager) Start() error {
 	lm.logger.Info("Starting OpenMachineCore with Workflow Engine")
@@ -106,12 +503,65 @@ func (lm *LifecycleManager) Start() error {
 	lm.setState(StateInitializing)
 	lm.broadcastStatus()
 
+	// Restarted after an update
+	if lm.Context().Err() != nil {
+		lm.newContext()
+	}
+
+	// Flags must be known before devices and workflows start
+	ctx, cancel := lm.withTimeout(startupTimeout)
+	err := lm.features.Load(ctx)
+	cancel()
+	if err != nil {
+		lm.logger.Error("Failed to load feature flags, using defaults", zap.Error(err))
+	}
+
+	// Start alarms before devices so identity mismatches raise alarms
+	lm.startAlarms()
+	lm.startOutbox()
+	lm.startRouting()
+
+	// Check for descriptor pack updates in the background
+	if err := lm.descriptorSync.Start(); err != nil {
+		lm.logger.Error("Failed to start descriptor sync", zap.Error(err))
+	}
+	if lm.config.Devices.Watch {
+		if err := lm.descriptorWatcher.Start(); err != nil {
+			lm.logger.Error("Failed to watch device descriptors", zap.Error(err))
+		}
+	}
+
 	// Load devices from database
 	if err := lm.loadDevicesFromDB(); err != nil {
 		lm.logger.Warn("Failed to load devices from database", zap.Error(err))
 		// Continue anyway, not critical
 	}
 
+	// Executions interrupted by a crash resume from their checkpoint or fail
+	ctx, cancel = lm.withTimeout(startupTimeout)
+	resumed, failed, err := lm.workflowEngine.RecoverInterrupted(ctx)
+	cancel()
+	if err != nil {
+		lm.logger.Error("Failed to recover interrupted executions", zap.Error(err))
+	} else if resumed+failed > 0 {
+		lm.logger.Info("Recovered interrupted executions",
+			zap.Int("resumed", resumed),
+			zap.Int("failed", failed))
+	}
+
+	// Sample counters once devices are polled
+	ctx, cancel = lm.withTimeout(startupTimeout)
+	err = lm.counters.Start(ctx)
+	cancel()
+	if err != nil {
+		lm.logger.Error("Failed to start counter accumulation", zap.Error(err))
+	}
+
+	// Record device snapshots once devices are polled
+	if err := lm.snapshots.Start(); err != nil {
+		lm.logger.Error("Failed to start device snapshots", zap.Error(err))
+	}
+
 	// Start gRPC Server (with Workflow Service)
 	if err := lm.startGRPCServer(); err != nil {
 		lm.setError(fmt.Errorf("failed to start gRPC: %w", err))
@@ -119,6 +569,7 @@ func (lm *LifecycleManager) Start() error {
 	}
 
 	// Start REST API Server
+	lm.usageTracker.Start()
 	if err := lm.startRESTServer(); err != nil {
 		lm.setError(fmt.Errorf("failed to start REST API: %w", err))
 		return err
@@ -127,51 +578,41 @@ func (lm *LifecycleManager) Start() error {
 	// Start WebSocket hub
 	go lm.wsHub.Run()
 
-	// State: Running
-	lm.setState(StateRunning)
-	lm.broadcastStatus()
-
-	lm.logger.Info("System started successfully",
-		zap.Int("grpc_port", lm.config.Server.GRPCPort),
-		zap.Int("http_port", lm.config.Server.HTTPPort),
-		zap.Bool("workflow_engine_enabled", true))
-
-	return nil
-}
-
-func (lm *LifecycleManager) loadDevicesFromDB() error {
-	ctx := context.Background()
+	// Watch the server's own resource usage
+	if err := lm.resources.Start(); err != nil {
+		lm.logger.Error("Failed to start resource monitoring", zap.Error(err))
+	}
 
-	compositions, err := lm.storage.LoadAllDeviceCompositions(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to load compositions: %w", err)
+	// Start watchdog heartbeat (devices must be loaded)
+	if err := lm.heartbeat.Start(); err != nil {
+		lm.logger.Error("Failed to start heartbeat", zap.Error(err))
 	}
 
-	lm.logger.Info("Loading devices from database", zap.Int("count", len(compositions)))
+	// Expire manual control sessions without heartbeat
+	lm.manual.Start()
 
-	timeout := time.Duration(lm.config.Modbus.DefaultTimeout)
+	// Cancel device onboardings without activity
+	lm.onboarding.Start()
 
-	for _, comp := range compositions {
-		device, err := lm.deviceManager.LoadDeviceFromComposition(comp, timeout)
-		if err != nil {
-			lm.logger.Error("Failed to load device",
-				zap.String("instance_id", comp.InstanceID),
-				zap.Error(err))
-			continue
-		}
-
-		// Start poller for this device
-		pollInterval := time.Duration(lm.config.Modbus.DefaultPollInterval)
-		if err := lm.deviceManager.StartPoller(device.ID, pollInterval); err != nil {
-			lm.logger.Error("Failed to start poller",
-				zap.String("instance_id", comp.InstanceID),
-				zap.Error(err))
-		}
+	// Start shift automation once devices and the machine are available
+	lm.shiftScheduler.Start()
 
-		lm.logger.Info("Device loaded and poller started",
-			zap.String("instance_id", comp.InstanceID))
+	// Nightly validation reports workflows broken by device changes
+	if err := lm.validation.Start(); err != nil {
+		lm.logger.Error("Failed to schedule nightly workflow validation", zap.Error(err))
 	}
 
+	// State: Running
+	lm.setState(StateRunning)
+	lm.broadcastStatus()
+
+	_, grpcAddress := lm.config.Server.GRPCListen()
+	_, httpAddress := lm.config.Server.HTTPListen()
+	lm.logger.Info("System started successfully",
+		zap.String("grpc_address", grpcAddress),
+		zap.String("http_address", httpAddress),
+		zap.Bool("workflow_engine_enabled", true))
+
 	return nil
 }
 
@@ -198,7 +639,60 @@ func (lm *LifecycleManager) Shutdown(ctx context.Context) error {
 
 func (lm *LifecycleManager) gracefulShutdown(ctx context.Context) error {
 	var wg sync.WaitGroup
-	errChan := make(chan error, 4)
+	errChan := make(chan error, 5)
+
+	// 0. Cancel running executions so no step writes after the safe states
+	if n := lm.workflowEngine.CancelAll(engine.CancelReason{
+		Source: engine.CancelSourceSystem,
+		Reason: "shutdown",
+	}); n > 0 {
+		lm.logger.Info("Cancelled running executions for shutdown", zap.Int("count", n))
+
+		// A step in flight may still write; the safe states come after it
+		waitCtx, cancel := context.WithTimeout(ctx, lm.config.SafeState.CancelTimeout)
+		if !lm.workflowEngine.WaitStopped(waitCtx) {
+			lm.logger.Warn("Executions still running after cancel, applying safe states anyway",
+				zap.Duration("timeout", lm.config.SafeState.CancelTimeout))
+		}
+		cancel()
+	}
+
+	// Stop machine monitors, pollers and everything else started from the root context
+	lm.cancelContext()
+
+	// No automatic machine commands while shutting down
+	lm.shiftScheduler.Stop()
+	lm.validation.Stop()
+	lm.descriptorSync.Stop()
+	lm.descriptorWatcher.Stop()
+
+	// Stop heartbeat first so the watchdog drops before devices disconnect
+	lm.heartbeat.Stop()
+
+	// Revert manually driven outputs while devices are still connected
+	lm.manual.Stop()
+
+	// Restore outputs under check and disconnect onboarded devices
+	lm.onboarding.Stop()
+
+	// Disconnecting devices must not raise alarms
+	lm.stopRouting()
+	lm.stopAlarms()
+	lm.stopOutbox()
+
+	// Store final counter totals before devices disconnect
+	lm.counters.Stop()
+	lm.snapshots.Stop()
+	lm.resources.Stop()
+
+	// Bring outputs into their safe states while devices are still connected
+	if lm.config.SafeState.OnShutdown {
+		safeCtx, cancel := context.WithTimeout(ctx, lm.config.SafeState.WriteTimeout)
+		if err := lm.deviceManager.ApplySafeStates(safeCtx, "shutdown"); err != nil {
+			lm.logger.Error("Failed to apply safe states on shutdown", zap.Error(err))
+		}
+		cancel()
+	}
 
 	// 1. Stop Device Manager (all pollers & connections)
 	wg.Add(1)
@@ -223,12 +717,24 @@ func (lm *LifecycleManager) gracefulShutdown(ctx context.Context) error {
 		}()
 	}
 
-	// 3. gRPC Server graceful stop
+	// 3. WebSocket clients: flush queued messages, then close with the reason
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := lm.wsHub.Stop(ctx, "server shutdown"); err != nil {
+			errChan <- fmt.Errorf("websocket hub stop failed: %w", err)
+		}
+	}()
+
+	// 4. gRPC Server graceful stop
 	if lm.grpcServer != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			lm.logger.Info("Stopping gRPC server (including Workflow Service)")
+			if lm.grpcHealth != nil {
+				lm.grpcHealth.Shutdown()
+			}
 			lm.grpcServer.GracefulStop()
 		}()
 	}
@@ -242,6 +748,8 @@ func (lm *LifecycleManager) gracefulShutdown(ctx context.Context) error {
 
 	select {
 	case <-done:
+		// REST is down, so no more calls are counted
+		lm.usageTracker.Stop(ctx)
 		lm.logger.Info("Graceful shutdown completed")
 		return nil
 	case <-ctx.Done():
@@ -253,21 +761,46 @@ func (lm *LifecycleManager) gracefulShutdown(ctx context.Context) error {
 }
 
 func (lm *LifecycleManager) startGRPCServer() error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", lm.config.Server.GRPCPort))
+	network, address := lm.config.Server.GRPCListen()
+	mode, err := lm.config.Server.SocketFileMode()
 	if err != nil {
-		return fmt.Errorf("failed to listen: %w", err)
+		return err
+	}
+	lis, err := service.Listen(network, address, mode)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	lm.grpcServer = grpc.NewServer(grpcServerOptions(lm.config.Server.GRPC, lm.authService, lm.grpcServices)...)
+
+	// Register services
+	var serviceNames []string
+	if lm.grpcServices != nil {
+		lm.grpcServices.Register(lm.grpcServer)
+		serviceNames = lm.grpcServices.Names()
+		lm.logger.Info("gRPC services registered")
+	} else {
+		lm.logger.Warn("No gRPC services set, serving only health")
 	}
 
-	lm.grpcServer = grpc.NewServer()
+	// Standard health service for load balancers and k8s probes
+	lm.grpcHealth = health.NewServer()
+	lm.grpcHealth.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	for _, service := range serviceNames {
+		lm.grpcHealth.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(lm.grpcServer, lm.grpcHealth)
 
-	// Register Workflow Service
-	pb.RegisterWorkflowServiceServer(lm.grpcServer, lm.workflowService)
-	lm.logger.Info("Workflow gRPC service registered")
+	if lm.config.Server.GRPC.Reflection {
+		reflection.Register(lm.grpcServer)
+		lm.logger.Info("gRPC reflection enabled")
+	}
 
 	go func() {
 		lm.logger.Info("gRPC server listening",
-			zap.Int("port", lm.config.Server.GRPCPort),
-			zap.String("services", "WorkflowService"))
+			zap.String("network", network),
+			zap.String("address", address),
+			zap.Strings("services", serviceNames))
 		if err := lm.grpcServer.Serve(lis); err != nil {
 			lm.logger.Error("gRPC server failed", zap.Error(err))
 		}
@@ -345,6 +878,13 @@ func (lm *LifecycleManager) setState(state SystemState) {
 }
 
 func (lm *LifecycleManager) setError(err error) {
+	// A controller in error must not keep the watchdog alive
+	lm.heartbeat.Stop()
+
+	if lm.grpcHealth != nil {
+		lm.grpcHealth.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+
 	lm.stateMu.Lock()
 	defer lm.stateMu.Unlock()
 	lm.currentState = StateError
@@ -380,23 +920,9 @@ func (lm *LifecycleManager) GetCurrentStatus() interfaces.SystemStatus {
 		State:            lm.currentState.String(),
 		DeviceCount:      len(devices),
 		ConnectedDevices: connected,
-	}
-}
-
-// GetCurrentStatusDetailed returns detailed status with update progress
-func (lm *LifecycleManager) GetCurrentStatusDetailed() interface{} {
-	lm.stateMu.RLock()
-	defer lm.stateMu.RUnlock()
-
-	return map[string]interface{}{
-		"state": lm.currentState.String(),
-		"update_progress": map[string]interface{}{
-			"phase":      lm.updateProgress.Phase,
-			"progress":   lm.updateProgress.Progress,
-			"message":    lm.updateProgress.Message,
-			"started_at": lm.updateProgress.StartedAt,
-		},
-		"timestamp": time.Now().Unix(),
+		Heartbeat:        lm.heartbeat.Health(),
+		Resources:        lm.resources.Health(),
+		Features:         lm.features.List(),
 	}
 }
 
@@ -472,6 +998,11 @@ func (lm *LifecycleManager) WorkflowEngine() *engine.Engine {
 	return lm.workflowEngine
 }
 
+// EventStreamer returns the streamer of execution events
+func (lm *LifecycleManager) EventStreamer() *streaming.EventStreamer {
+	return lm.eventStreamer
+}
+
 // Expose hub for other components to broadcast messages
 func (lm *LifecycleManager) GetWebSocketHub() *ws.Hub {
 	return lm.wsHub
@@ -485,3 +1016,22 @@ type machineStatusAdapter struct {
 func (a *machineStatusAdapter) GetStatus() any {
 	return a.controller.GetStatus()
 }
+
+// environmentAdapter provides device connections and the machine state to
+// the preflight checks of the workflow engine
+type environmentAdapter struct {
+	devices *devices.Manager
+	machine *machine.Controller
+}
+
+func (a *environmentAdapter) DeviceConnected(name string) (loaded, connected bool) {
+	device, exists := a.devices.GetDeviceByName(name)
+	if !exists {
+		return false, false
+	}
+	return true, device.Connected()
+}
+
+func (a *environmentAdapter) MachineState() string {
+	return string(a.machine.GetStatus().State)
+}
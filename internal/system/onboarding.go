@@ -0,0 +1,16 @@
+package system
+
+import (
+	"github.com/KevinKickass/OpenMachineCore/internal/onboarding"
+	"github.com/google/uuid"
+)
+
+// onboardingHandlerAdapter adapts device onboarding to the WebSocket
+// OnboardingHandler
+type onboardingHandlerAdapter struct {
+	manager *onboarding.Manager
+}
+
+func (a *onboardingHandlerAdapter) AnswerOnboardingCheck(id uuid.UUID, actor string, index int, result, note string) (any, error) {
+	return a.manager.Answer(id, actor, index, result, note)
+}
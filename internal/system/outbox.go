@@ -0,0 +1,55 @@
+package system
+
+import (
+	"context"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/outbox"
+	"go.uber.org/zap"
+)
+
+// startOutbox starts delivering the outbox and adds machine state changes
+// to it as event types "machine.<state>"
+func (lm *LifecycleManager) startOutbox() {
+	if !lm.outbox.Enabled() {
+		return
+	}
+	ctx, cancel := lm.withTimeout(startupTimeout)
+	defer cancel()
+	if err := lm.outbox.Start(ctx); err != nil {
+		lm.logger.Error("Failed to start event outbox", zap.Error(err))
+		return
+	}
+
+	stop := make(chan struct{})
+	lm.outboxEventsStop = stop
+
+	states := lm.machineController.SubscribeState()
+
+	go func() {
+		defer lm.machineController.UnsubscribeState(states)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case change, ok := <-states:
+				if !ok {
+					return
+				}
+				lm.outbox.Publish(context.Background(), outbox.SourceMachine, "machine."+string(change.Status.State), map[string]any{
+					"previous_state": string(change.PreviousState),
+					"error_message":  change.Status.ErrorMessage,
+				})
+			}
+		}
+	}()
+}
+
+// stopOutbox stops adding machine events and delivering the outbox
+func (lm *LifecycleManager) stopOutbox() {
+	if lm.outboxEventsStop != nil {
+		close(lm.outboxEventsStop)
+		lm.outboxEventsStop = nil
+	}
+	lm.outbox.Stop()
+}
@@ -0,0 +1,253 @@
+package system
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
+	ws "github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Monitored metrics, raised as event alarms "resource.<metric>"
+const (
+	resourceGoroutines = "goroutines"
+	resourceMemory     = "memory"
+	resourceDBPool     = "db_pool"
+	resourceWSClients  = "ws_clients"
+)
+
+// ResourceMonitor samples the server's own resource usage. Metrics above
+// their threshold are logged and raise event alarms; while goroutines,
+// memory or the database pool are exhausted new executions can be rejected,
+// and WebSocket clients above the limit are disconnected, so the edge device
+// degrades instead of running out of memory mid-production.
+type ResourceMonitor struct {
+	cfg     config.ResourcesConfig
+	storage *storage.PostgresClient
+	wsHub   *ws.Hub
+	alarms  *alarms.Manager
+	logger  *zap.Logger
+
+	mu       sync.RWMutex
+	running  bool
+	sample   interfaces.ResourceStatus
+	breaches map[string]bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewResourceMonitor(cfg config.ResourcesConfig, store *storage.PostgresClient, wsHub *ws.Hub, alarmManager *alarms.Manager, logger *zap.Logger) *ResourceMonitor {
+	return &ResourceMonitor{
+		cfg:      cfg,
+		storage:  store,
+		wsHub:    wsHub,
+		alarms:   alarmManager,
+		logger:   logger,
+		breaches: make(map[string]bool),
+	}
+}
+
+// Start begins sampling
+func (r *ResourceMonitor) Start() error {
+	if !r.cfg.Enabled {
+		return nil
+	}
+	if r.cfg.Interval <= 0 {
+		return fmt.Errorf("resource monitoring interval must be > 0")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return nil
+	}
+
+	r.running = true
+	r.stopChan = make(chan struct{})
+	r.wg.Add(1)
+
+	go r.loop(r.stopChan)
+
+	r.logger.Info("Resource monitoring started", zap.Duration("interval", r.cfg.Interval))
+	return nil
+}
+
+// Stop ends sampling; executions are no longer rejected
+func (r *ResourceMonitor) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	close(r.stopChan)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	r.mu.Lock()
+	clear(r.breaches)
+	r.mu.Unlock()
+}
+
+func (r *ResourceMonitor) loop(stopChan chan struct{}) {
+	defer r.wg.Done()
+
+	r.check()
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// check takes a sample, reports threshold changes and applies the
+// protective actions
+func (r *ResourceMonitor) check() {
+	sample := r.take()
+
+	values := map[string]float64{
+		resourceGoroutines: float64(sample.Goroutines),
+		resourceMemory:     float64(sample.MemoryMB),
+		resourceWSClients:  float64(sample.WSClients),
+	}
+	limits := map[string]float64{
+		resourceGoroutines: float64(r.cfg.MaxGoroutines),
+		resourceMemory:     float64(r.cfg.MaxMemoryMB),
+		resourceDBPool:     r.cfg.MaxDBPoolUsage,
+		resourceWSClients:  float64(r.cfg.MaxWSClients),
+	}
+	if sample.DBPoolMax > 0 {
+		values[resourceDBPool] = float64(sample.DBPoolAcquired) / float64(sample.DBPoolMax)
+	}
+
+	r.mu.Lock()
+	var raised, recovered []string
+	for metric, limit := range limits {
+		breached := limit > 0 && values[metric] > limit
+		switch {
+		case breached && !r.breaches[metric]:
+			raised = append(raised, metric)
+		case !breached && r.breaches[metric]:
+			recovered = append(recovered, metric)
+		}
+		r.breaches[metric] = breached
+	}
+	sample.Breaches = r.breached()
+	sample.RejectingExecutions = r.rejecting()
+	r.sample = sample
+	r.mu.Unlock()
+
+	for _, metric := range raised {
+		r.logger.Warn("Resource usage above threshold",
+			zap.String("metric", metric),
+			zap.Float64("value", values[metric]),
+			zap.Float64("threshold", limits[metric]),
+			zap.Bool("rejecting_executions", sample.RejectingExecutions))
+		r.alarms.HandleEvent("resource."+metric, map[string]any{
+			"metric":    metric,
+			"value":     values[metric],
+			"threshold": limits[metric],
+		})
+	}
+	for _, metric := range recovered {
+		r.logger.Info("Resource usage back below threshold",
+			zap.String("metric", metric),
+			zap.Float64("value", values[metric]),
+			zap.Float64("threshold", limits[metric]))
+	}
+
+	if r.cfg.ShedWSClients && r.cfg.MaxWSClients > 0 && sample.WSClients > r.cfg.MaxWSClients {
+		if n := r.wsHub.Shed(r.cfg.MaxWSClients); n > 0 {
+			r.logger.Warn("Disconnected WebSocket clients above the limit",
+				zap.Int("clients", n),
+				zap.Int("max_ws_clients", r.cfg.MaxWSClients))
+		}
+	}
+}
+
+// take samples the current resource usage
+func (r *ResourceMonitor) take() interfaces.ResourceStatus {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stat := r.storage.Pool().Stat()
+
+	return interfaces.ResourceStatus{
+		Goroutines:     runtime.NumGoroutine(),
+		MemoryMB:       int((mem.Sys - mem.HeapReleased) >> 20),
+		DBPoolAcquired: int(stat.AcquiredConns()),
+		DBPoolMax:      int(stat.MaxConns()),
+		WSClients:      r.wsHub.GetClientCount(),
+		SampledAt:      time.Now(),
+	}
+}
+
+// breached returns the metrics above their threshold, sorted. Callers hold mu.
+func (r *ResourceMonitor) breached() []string {
+	metrics := []string{}
+	for metric, breached := range r.breaches {
+		if breached {
+			metrics = append(metrics, metric)
+		}
+	}
+	slices.Sort(metrics)
+	return metrics
+}
+
+// rejecting reports whether new executions are rejected. Callers hold mu.
+func (r *ResourceMonitor) rejecting() bool {
+	return r.cfg.RejectExecutions &&
+		(r.breaches[resourceGoroutines] || r.breaches[resourceMemory] || r.breaches[resourceDBPool])
+}
+
+// Admit is the workflow engine's admission check: it rejects new executions
+// while resources are exhausted and reject_executions is set
+func (r *ResourceMonitor) Admit() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.rejecting() {
+		return nil
+	}
+	var exhausted []string
+	for _, metric := range r.breached() {
+		if metric != resourceWSClients {
+			exhausted = append(exhausted, metric)
+		}
+	}
+	return fmt.Errorf("resource usage above threshold: %s", strings.Join(exhausted, ", "))
+}
+
+// Health returns the last sample for status reporting
+func (r *ResourceMonitor) Health() *interfaces.ResourceStatus {
+	if !r.cfg.Enabled {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.sample.SampledAt.IsZero() {
+		return nil
+	}
+	sample := r.sample
+	sample.Breaches = slices.Clone(r.sample.Breaches)
+	return &sample
+}
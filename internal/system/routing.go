@@ -0,0 +1,85 @@
+package system
+
+import (
+	"encoding/json"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/alarms"
+	"github.com/KevinKickass/OpenMachineCore/internal/routing"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+)
+
+// startRouting offers execution events and machine state changes to the
+// routing rules. Alarm, device and system events are routed where they
+// happen.
+func (lm *LifecycleManager) startRouting() {
+	if !lm.router.Enabled() {
+		return
+	}
+	lm.router.Start()
+
+	stop := make(chan struct{})
+	lm.routingEventsStop = stop
+
+	events := lm.eventStreamer.SubscribeAll()
+	states := lm.machineController.SubscribeState()
+
+	go func() {
+		defer lm.eventStreamer.UnsubscribeAll(events)
+		defer lm.machineController.UnsubscribeState(states)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				lm.router.Route(routing.SourceWorkflow, event.EventType, executionPayload(event))
+			case change, ok := <-states:
+				if !ok {
+					return
+				}
+				lm.router.Route(routing.SourceMachine, "machine."+string(change.Status.State), map[string]any{
+					"previous_state": string(change.PreviousState),
+					"error_message":  change.Status.ErrorMessage,
+				})
+			}
+		}
+	}()
+}
+
+// stopRouting stops offering events and delivers the events still queued
+func (lm *LifecycleManager) stopRouting() {
+	if lm.routingEventsStop != nil {
+		close(lm.routingEventsStop)
+		lm.routingEventsStop = nil
+	}
+	lm.router.Stop()
+}
+
+// executionPayload has the shape of outbox execution events: the execution
+// ID and the event payload as data
+func executionPayload(event *storage.ExecutionEvent) map[string]any {
+	payload := map[string]any{"execution_id": event.ExecutionID.String()}
+	if len(event.Payload) > 0 {
+		var data any
+		if err := json.Unmarshal(event.Payload, &data); err == nil {
+			payload["data"] = data
+		}
+	}
+	return payload
+}
+
+// alarmRouter offers alarm transitions as "alarm.<change>" events. The
+// payload is the alarm in its JSON form, so rules select the field names
+// clients see.
+func alarmRouter(router *routing.Router) alarms.ChangeHandler {
+	return func(change string, alarm storage.Alarm) {
+		payload := map[string]any{}
+		if data, err := json.Marshal(alarm); err == nil {
+			json.Unmarshal(data, &payload)
+		}
+		router.Route(routing.SourceAlarm, "alarm."+change, payload)
+	}
+}
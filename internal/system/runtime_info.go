@@ -0,0 +1,82 @@
+package system
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/buildinfo"
+	"github.com/KevinKickass/OpenMachineCore/internal/interfaces"
+)
+
+// license of OpenMachineCore, see LICENSE
+const license = "Apache-2.0"
+
+// GetCurrentStatusDetailed returns the status with update progress, build,
+// clock, runtime, listener and database information. Database errors are
+// reported in the status instead of failing it.
+func (lm *LifecycleManager) GetCurrentStatusDetailed(ctx context.Context) interfaces.DetailedStatus {
+	status := interfaces.DetailedStatus{
+		SystemStatus: lm.GetCurrentStatus(),
+		Build:        buildinfo.Get(),
+		StartedAt:    lm.startedAt,
+		License:      license,
+	}
+
+	lm.stateMu.RLock()
+	status.UpdateProgress = interfaces.UpdateStatus(lm.updateProgress)
+	lm.stateMu.RUnlock()
+
+	now := time.Now()
+	zone, _ := now.Zone()
+	status.Clock = interfaces.ClockStatus{
+		Time:      now,
+		Unix:      now.Unix(),
+		Timezone:  now.Location().String() + " (" + zone + ")",
+		UTCOffset: now.Format("-07:00"),
+	}
+	uptime := now.Sub(lm.startedAt)
+	status.Uptime = uptime.Round(time.Second).String()
+	status.UptimeSeconds = int64(uptime.Seconds())
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status.Runtime = interfaces.RuntimeStatus{
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		CPUs:         runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapSys:      mem.HeapSys,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		GCPauseTotal: time.Duration(mem.PauseTotalNs).String(),
+	}
+
+	httpNetwork, httpAddress := lm.config.Server.HTTPListen()
+	grpcNetwork, grpcAddress := lm.config.Server.GRPCListen()
+	status.Listeners = interfaces.ListenerStatus{
+		HTTP: httpNetwork + " " + httpAddress,
+		GRPC: grpcNetwork + " " + grpcAddress,
+	}
+
+	status.EventStream = interfaces.EventStreamStatus(lm.eventStreamer.Stats())
+	status.DeviceStartup = lm.DeviceStartup()
+
+	database, err := lm.storage.DatabaseInfo(ctx)
+	if err != nil {
+		status.DatabaseError = err.Error()
+	} else {
+		status.Database = database
+	}
+
+	status.FeatureSummary.Enabled = []string{}
+	for _, flag := range status.Features {
+		status.FeatureSummary.Total++
+		if flag.Enabled {
+			status.FeatureSummary.Enabled = append(status.FeatureSummary.Enabled, flag.Name)
+		}
+	}
+	return status
+}
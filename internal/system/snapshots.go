@@ -0,0 +1,161 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/devices"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// snapshotTimeout bounds storing one snapshot cycle
+const snapshotTimeout = 10 * time.Second
+
+// SnapshotRecorder periodically stores the cached register values of all
+// devices, so the I/O state around a failure can be inspected afterwards.
+// Values come from the poller cache; devices are not read.
+type SnapshotRecorder struct {
+	cfg           config.SnapshotsConfig
+	storage       *storage.PostgresClient
+	deviceManager *devices.Manager
+	logger        *zap.Logger
+
+	mu        sync.Mutex
+	running   bool
+	lastPrune time.Time
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewSnapshotRecorder(cfg config.SnapshotsConfig, store *storage.PostgresClient, deviceManager *devices.Manager, logger *zap.Logger) *SnapshotRecorder {
+	return &SnapshotRecorder{
+		cfg:           cfg,
+		storage:       store,
+		deviceManager: deviceManager,
+		logger:        logger,
+	}
+}
+
+// Start begins recording snapshots
+func (r *SnapshotRecorder) Start() error {
+	if !r.cfg.Enabled {
+		return nil
+	}
+	if r.cfg.Interval <= 0 {
+		return fmt.Errorf("snapshot interval must be > 0")
+	}
+	for _, pattern := range r.cfg.Registers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid snapshot register pattern %q: %w", pattern, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return nil
+	}
+
+	r.running = true
+	r.stopChan = make(chan struct{})
+	r.wg.Add(1)
+
+	go r.loop(r.stopChan)
+
+	r.logger.Info("Device snapshots started",
+		zap.Duration("interval", r.cfg.Interval),
+		zap.Duration("retention", r.cfg.Retention),
+		zap.Strings("registers", r.cfg.Registers))
+
+	return nil
+}
+
+// Stop stops recording snapshots
+func (r *SnapshotRecorder) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.running = false
+	close(r.stopChan)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *SnapshotRecorder) loop(stopChan chan struct{}) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			r.record()
+		}
+	}
+}
+
+// record stores one snapshot per device with cached values
+func (r *SnapshotRecorder) record() {
+	now := time.Now()
+
+	snapshots := make([]storage.DeviceSnapshot, 0)
+	for _, device := range r.deviceManager.ListDevices() {
+		values := r.filter(device.LastValues())
+		if len(values) == 0 {
+			continue
+		}
+		snapshots = append(snapshots, storage.DeviceSnapshot{
+			DeviceName: device.Name,
+			Values:     values,
+			CapturedAt: now,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	if err := r.storage.SaveDeviceSnapshots(ctx, snapshots); err != nil {
+		r.logger.Error("Failed to store device snapshots", zap.Error(err))
+	}
+
+	// Prune about once an hour rather than every cycle
+	if r.cfg.Retention > 0 && now.Sub(r.lastPrune) >= time.Hour {
+		r.lastPrune = now
+		deleted, err := r.storage.DeleteDeviceSnapshotsBefore(ctx, now.Add(-r.cfg.Retention))
+		if err != nil {
+			r.logger.Error("Failed to prune device snapshots", zap.Error(err))
+		} else if deleted > 0 {
+			r.logger.Debug("Pruned device snapshots", zap.Int64("deleted", deleted))
+		}
+	}
+}
+
+// filter keeps the registers matching the configured patterns
+func (r *SnapshotRecorder) filter(values map[string]interface{}) map[string]any {
+	if len(r.cfg.Registers) == 0 {
+		return values
+	}
+
+	filtered := make(map[string]any, len(values))
+	for name, value := range values {
+		for _, pattern := range r.cfg.Registers {
+			if ok, _ := path.Match(pattern, name); ok {
+				filtered[name] = value
+				break
+			}
+		}
+	}
+	return filtered
+}
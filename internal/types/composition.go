@@ -9,13 +9,41 @@ type DeviceComposition struct {
 type CompositionConfig struct {
 	Coupler   CouplerConfig    `json:"coupler"`
 	Terminals []TerminalConfig `json:"terminals"`
+
+	// SafeStates maps logical or register names to the value written when
+	// outputs must be brought into a safe state (shutdown, stop failure, ...)
+	SafeStates map[string]any `json:"safe_states,omitempty"`
+
+	// VirtualRegisters are computed from the terminal registers on read
+	VirtualRegisters []VirtualRegister `json:"virtual_registers,omitempty"`
+
+	// Aliases map deprecated register or logical names to their current
+	// names; they take precedence over the aliases of the modules
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// WriteLimits protect actuators from writes that are too fast, too large
+	// a step or out of range; keyed by logical or register name
+	WriteLimits map[string]WriteLimit `json:"write_limits,omitempty"`
+}
+
+// WriteLimit constrains the writes of a register. Values are in engineering
+// units. Writes of safe states are not limited.
+type WriteLimit struct {
+	MinIntervalMs int      `json:"min_interval_ms,omitempty"` // Minimum time between writes
+	MaxDelta      *float64 `json:"max_delta,omitempty"`       // Largest change from the last written or polled value
+	Min           *float64 `json:"min,omitempty"`             // Lowest value accepted
+	Max           *float64 `json:"max,omitempty"`             // Highest value accepted
 }
 
 type CouplerConfig struct {
-	Module    string `json:"module"`
-	IPAddress string `json:"ip_address"`
-	Port      int    `json:"port"`
-	UnitID    int    `json:"unit_id"`
+	Module    string        `json:"module"`
+	Protocol  string        `json:"protocol,omitempty"` // See Protocol*, empty is Modbus TCP
+	IPAddress string        `json:"ip_address"`
+	Port      int           `json:"port"`
+	UnitID    int           `json:"unit_id"`
+	TimeoutMs int           `json:"timeout_ms,omitempty"` // Overrides modbus.default_timeout
+	Serial    *SerialConfig `json:"serial,omitempty"`     // Modbus RTU instead of ip_address and port
+	ByteOrder ByteOrder     `json:"byte_order,omitempty"` // 32 and 64 bit registers, empty is ABCD
 }
 
 type TerminalConfig struct {
@@ -29,6 +57,12 @@ type ModuleDefinition struct {
 	ProcessImage ProcessImageInfo     `json:"process_image"`
 	Channels     []ChannelInfo        `json:"channels"`
 	Registers    []RegisterDefinition `json:"registers,omitempty"`
+
+	// Identification lists registers reporting vendor, product and firmware
+	Identification []IdentificationRegister `json:"identification,omitempty"`
+
+	// Aliases map channel names of predecessor modules to the current names
+	Aliases map[string]string `json:"aliases,omitempty"`
 }
 
 type ModuleInfo struct {
@@ -52,3 +86,21 @@ type ChannelInfo struct {
 	BitOffset   int    `json:"bit_offset"`
 	Description string `json:"description"`
 }
+
+// VendorIndex is the index.yaml of a vendor directory of module descriptors
+type VendorIndex struct {
+	Vendor      string                 `yaml:"vendor"`
+	Description string                 `yaml:"description"`
+	Website     string                 `yaml:"website"`
+	Modules     map[string][]ModuleRef `yaml:"modules"`
+}
+
+// ModuleRef is a module of a vendor index; File is relative to the index
+type ModuleRef struct {
+	ID          string `yaml:"id"`
+	File        string `yaml:"file"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Tested      bool   `yaml:"tested"`
+	Datasheet   string `yaml:"datasheet"`
+}
@@ -1,6 +1,11 @@
 package types
 
 import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -9,6 +14,44 @@ type DeviceProfileDefinition struct {
 	Connection    ConnectionConfig     `json:"connection"`
 	Registers     []RegisterDefinition `json:"registers"`
 	Groups        []RegisterGroup      `json:"register_groups,omitempty"`
+
+	// Identification registers read once after connect
+	Identification []IdentificationRegister `json:"identification,omitempty"`
+
+	// VirtualRegisters are computed from other registers on read
+	VirtualRegisters []VirtualRegister `json:"virtual_registers,omitempty"`
+
+	// Aliases map deprecated register or logical names to their current
+	// names, so references keep working after hardware changes
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// Documentation links the modules of a composed device to their datasheets
+	Documentation []ModuleDocumentation `json:"documentation,omitempty"`
+}
+
+// ModuleDocumentation is the datasheet of a module of a composed device
+type ModuleDocumentation struct {
+	Module    string `json:"module"`             // Module ID
+	Position  *int   `json:"position,omitempty"` // Terminal position, nil for the coupler
+	Prefix    string `json:"prefix,omitempty"`   // Register prefix of the terminal
+	Datasheet string `json:"datasheet"`
+}
+
+// DocumentationFor returns the datasheet of the module providing a register.
+// Registers without terminal prefix belong to the coupler.
+func (p *DeviceProfileDefinition) DocumentationFor(register string) *ModuleDocumentation {
+	var coupler *ModuleDocumentation
+	for i := range p.Documentation {
+		doc := &p.Documentation[i]
+		if doc.Position == nil {
+			coupler = doc
+			continue
+		}
+		if register != "" && strings.HasPrefix(register, doc.Prefix+".") {
+			return doc
+		}
+	}
+	return coupler
 }
 
 type DeviceProfileInfo struct {
@@ -19,12 +62,29 @@ type DeviceProfileInfo struct {
 	Description string `json:"description"`
 }
 
+// Connection protocols
+const (
+	ProtocolModbusTCP = "modbus_tcp"
+	ProtocolModbusRTU = "modbus_rtu"
+)
+
 type ConnectionConfig struct {
-	Protocol       string `json:"protocol"`
-	Port           int    `json:"port"`
-	UnitID         int    `json:"unit_id"`
-	PollIntervalMs int    `json:"poll_interval_ms"`
-	TimeoutMs      int    `json:"timeout_ms"`
+	Protocol       string        `json:"protocol"` // See Protocol*, empty is Modbus TCP
+	Port           int           `json:"port"`
+	UnitID         int           `json:"unit_id"`
+	PollIntervalMs int           `json:"poll_interval_ms"`
+	TimeoutMs      int           `json:"timeout_ms"`
+	Serial         *SerialConfig `json:"serial,omitempty"`     // Modbus RTU
+	ByteOrder      ByteOrder     `json:"byte_order,omitempty"` // Default of the registers, empty is ABCD
+}
+
+// SerialConfig is the serial line of a Modbus RTU device. Devices on the
+// same port share the line and must use the same settings.
+type SerialConfig struct {
+	Port     string `json:"port"`                // e.g. "ttyUSB0", "/dev/ttyAMA0" or "COM3"
+	BaudRate int    `json:"baud_rate,omitempty"` // Default 19200
+	Parity   string `json:"parity,omitempty"`    // "even" (default), "odd" or "none"
+	StopBits int    `json:"stop_bits,omitempty"` // 1 (default) or 2
 }
 
 type RegisterDefinition struct {
@@ -36,6 +96,18 @@ type RegisterDefinition struct {
 	Unit        string       `json:"unit"`
 	Access      AccessType   `json:"access"`
 	Description string       `json:"description"`
+	Bit         *int         `json:"bit,omitempty"`        // Bit of a bool register sharing its word with others, 0-15
+	ByteOrder   ByteOrder    `json:"byte_order,omitempty"` // 32 and 64 bit data, overrides the connection's byte order
+}
+
+// VirtualRegister is a read-only register computed from an expression over
+// other registers, e.g. "scale(AI1.Channel_1, 0, 32767, 0, 10)"
+type VirtualRegister struct {
+	Name        string   `json:"name"`
+	Expression  string   `json:"expression"`
+	DataType    DataType `json:"data_type,omitempty"` // bool or float64 (default)
+	Unit        string   `json:"unit,omitempty"`
+	Description string   `json:"description,omitempty"`
 }
 
 type RegisterGroup struct {
@@ -63,6 +135,19 @@ const (
 	DataTypeUint32  DataType = "uint32"
 	DataTypeFloat32 DataType = "float32"
 	DataTypeFloat64 DataType = "float64"
+	DataTypeString  DataType = "string" // ASCII, two characters per register
+)
+
+// ByteOrder is the order of the bytes of 32 and 64 bit values across their
+// registers, A being the most significant byte. Four letters name a 32 bit
+// value; 64 bit values follow the same pattern across four registers.
+type ByteOrder string
+
+const (
+	ByteOrderABCD ByteOrder = "ABCD" // Big-endian, high word first (default)
+	ByteOrderCDAB ByteOrder = "CDAB" // Low word first
+	ByteOrderBADC ByteOrder = "BADC" // High word first, bytes swapped in each word
+	ByteOrderDCBA ByteOrder = "DCBA" // Little-endian
 )
 
 type AccessType string
@@ -72,6 +157,66 @@ const (
 	AccessTypeReadWrite AccessType = "read_write"
 )
 
+// Calibration corrects a channel determined during commissioning:
+// value = raw * scale_factor * gain + offset
+type Calibration struct {
+	Offset float64 `json:"offset"`
+	Gain   float64 `json:"gain"`
+}
+
+// IdentificationRegister describes a register exposing identification data
+// (vendor ID, product code, firmware revision, ...) and the value the
+// configured module is expected to report
+type IdentificationRegister struct {
+	Field    string       `json:"field"`
+	Module   string       `json:"module,omitempty"` // Set by the composer
+	Address  uint16       `json:"address"`
+	Type     RegisterType `json:"type"`
+	DataType DataType     `json:"data_type"`
+	Length   int          `json:"length,omitempty"` // Registers, string data only
+	Expected string       `json:"expected,omitempty"`
+}
+
+// DeviceIdentity is the identification read from connected hardware
+type DeviceIdentity struct {
+	Fields     map[string]string  `json:"fields"`
+	Errors     map[string]string  `json:"errors,omitempty"`
+	Mismatches []IdentityMismatch `json:"mismatches,omitempty"`
+	Changes    []IdentityMismatch `json:"changes,omitempty"` // Fields differing from the identity read at onboarding
+	ReadAt     time.Time          `json:"read_at"`
+}
+
+// Compare returns the fields read in both identities whose values differ,
+// expected being the reference value. Fields that failed to read in either
+// are not compared.
+func (id *DeviceIdentity) Compare(reference *DeviceIdentity) []IdentityMismatch {
+	if reference == nil {
+		return nil
+	}
+
+	var changes []IdentityMismatch
+	for _, field := range slices.Sorted(maps.Keys(reference.Fields)) {
+		actual, read := id.Fields[field]
+		if read && actual != reference.Fields[field] {
+			changes = append(changes, IdentityMismatch{
+				Field:    field,
+				Expected: reference.Fields[field],
+				Actual:   actual,
+			})
+		}
+	}
+	return changes
+}
+
+// IdentityMismatch is a field whose value differs from the configured module,
+// or from the identity read at onboarding
+type IdentityMismatch struct {
+	Module   string `json:"module,omitempty"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
 // Device Runtime Info
 type DeviceInfo struct {
 	ID        uuid.UUID
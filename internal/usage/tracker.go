@@ -0,0 +1,313 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Principal types
+const (
+	PrincipalUser         = "user"
+	PrincipalMachineToken = "machine_token"
+)
+
+// Principal identifies who made an API call
+type Principal struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// QuotaExceededError is returned when a principal has used up a quota
+type QuotaExceededError struct {
+	Quota         string    `json:"quota"`
+	EndpointClass string    `json:"endpoint_class"`
+	Limit         int       `json:"limit"`
+	Window        string    `json:"window"`
+	Used          int       `json:"used"`
+	ResetAt       time.Time `json:"reset_at"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota %s exceeded: %d of %d %s requests per %s", e.Quota, e.Used, e.Limit, e.EndpointClass, e.Window)
+}
+
+// QuotaStatus reports how much of a quota a principal has used
+type QuotaStatus struct {
+	Quota         string    `json:"quota"`
+	EndpointClass string    `json:"endpoint_class"`
+	Limit         int       `json:"limit"`
+	Window        string    `json:"window"`
+	Used          int       `json:"used"`
+	ResetAt       time.Time `json:"reset_at"`
+}
+
+type usageKey struct {
+	bucket        time.Time
+	principalType string
+	principalID   string
+	endpointClass string
+}
+
+type quotaKey struct {
+	quota         string
+	principalType string
+	principalID   string
+}
+
+type quotaWindow struct {
+	start time.Time
+	used  int
+}
+
+// Tracker counts API calls per principal and endpoint class, persists the
+// counters periodically and enforces the configured quotas. Quota windows are
+// kept in memory and restart empty.
+type Tracker struct {
+	cfg     config.UsageConfig
+	storage *storage.PostgresClient
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	pending map[usageKey]*storage.UsageRecord
+	windows map[quotaKey]*quotaWindow
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewTracker(cfg config.UsageConfig, store *storage.PostgresClient, logger *zap.Logger) *Tracker {
+	return &Tracker{
+		cfg:     cfg,
+		storage: store,
+		logger:  logger,
+		pending: make(map[usageKey]*storage.UsageRecord),
+		windows: make(map[quotaKey]*quotaWindow),
+	}
+}
+
+// Enabled reports whether usage is tracked
+func (t *Tracker) Enabled() bool {
+	return t != nil && t.cfg.Enabled
+}
+
+// Start begins persisting counters at the flush interval
+func (t *Tracker) Start() {
+	if !t.Enabled() || t.stopChan != nil {
+		return
+	}
+
+	interval := t.cfg.FlushInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	t.stopChan = make(chan struct{})
+	t.wg.Add(1)
+	go t.loop(interval, t.stopChan)
+
+	t.logger.Info("Usage tracking started",
+		zap.Duration("flush_interval", interval),
+		zap.Int("quotas", len(t.cfg.Quotas)))
+}
+
+// Stop ends the flush loop and persists the remaining counters
+func (t *Tracker) Stop(ctx context.Context) {
+	if t == nil || t.stopChan == nil {
+		return
+	}
+	close(t.stopChan)
+	t.wg.Wait()
+	t.stopChan = nil
+
+	if err := t.Flush(ctx); err != nil {
+		t.logger.Error("Failed to flush usage on shutdown", zap.Error(err))
+	}
+}
+
+func (t *Tracker) loop(interval time.Duration, stopChan chan struct{}) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Warn("Failed to flush usage", zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// Record counts one finished API call
+func (t *Tracker) Record(p Principal, endpointClass string, success bool) {
+	if !t.Enabled() {
+		return
+	}
+
+	key := usageKey{
+		bucket:        time.Now().UTC().Truncate(time.Hour),
+		principalType: p.Type,
+		principalID:   p.ID,
+		endpointClass: endpointClass,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.pending[key]
+	if !ok {
+		rec = &storage.UsageRecord{
+			Bucket:        key.bucket,
+			PrincipalType: p.Type,
+			PrincipalID:   p.ID,
+			EndpointClass: endpointClass,
+		}
+		t.pending[key] = rec
+	}
+	rec.PrincipalName = p.Name
+	if success {
+		rec.SuccessCount++
+	} else {
+		rec.FailureCount++
+	}
+}
+
+// Flush persists and resets the pending counters. On failure the counters
+// are kept for the next flush.
+func (t *Tracker) Flush(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[usageKey]*storage.UsageRecord)
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	records := make([]storage.UsageRecord, 0, len(pending))
+	for _, rec := range pending {
+		records = append(records, *rec)
+	}
+
+	if err := t.storage.AddUsage(ctx, records); err != nil {
+		t.mu.Lock()
+		for key, rec := range pending {
+			if cur, ok := t.pending[key]; ok {
+				cur.SuccessCount += rec.SuccessCount
+				cur.FailureCount += rec.FailureCount
+			} else {
+				t.pending[key] = rec
+			}
+		}
+		t.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Allow admits a request against every quota matching the principal and
+// endpoint class. Admitted requests count towards the quota whether they
+// succeed or not.
+func (t *Tracker) Allow(p Principal, endpointClass string) error {
+	if !t.Enabled() || len(t.cfg.Quotas) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var matched []*quotaWindow
+	for _, q := range t.cfg.Quotas {
+		if !quotaApplies(q, p, endpointClass) {
+			continue
+		}
+		w := t.window(q, p, now)
+		if w.used >= q.Limit {
+			return &QuotaExceededError{
+				Quota:         q.Name,
+				EndpointClass: q.EndpointClass,
+				Limit:         q.Limit,
+				Window:        q.Window.String(),
+				Used:          w.used,
+				ResetAt:       w.start.Add(q.Window),
+			}
+		}
+		matched = append(matched, w)
+	}
+
+	for _, w := range matched {
+		w.used++
+	}
+	return nil
+}
+
+// QuotaStatus returns the state of every quota applying to the principal
+func (t *Tracker) QuotaStatus(p Principal) []QuotaStatus {
+	statuses := make([]QuotaStatus, 0)
+	if !t.Enabled() {
+		return statuses
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, q := range t.cfg.Quotas {
+		if !quotaApplies(q, p, q.EndpointClass) {
+			continue
+		}
+		w := t.window(q, p, now)
+		statuses = append(statuses, QuotaStatus{
+			Quota:         q.Name,
+			EndpointClass: q.EndpointClass,
+			Limit:         q.Limit,
+			Window:        q.Window.String(),
+			Used:          w.used,
+			ResetAt:       w.start.Add(q.Window),
+		})
+	}
+	return statuses
+}
+
+// window returns the current fixed window of a quota, starting a new one if
+// the previous has expired. The caller holds t.mu.
+func (t *Tracker) window(q config.QuotaConfig, p Principal, now time.Time) *quotaWindow {
+	key := quotaKey{quota: q.Name, principalType: p.Type, principalID: p.ID}
+	w, ok := t.windows[key]
+	if !ok || !now.Before(w.start.Add(q.Window)) {
+		w = &quotaWindow{start: now}
+		t.windows[key] = w
+	}
+	return w
+}
+
+func quotaApplies(q config.QuotaConfig, p Principal, endpointClass string) bool {
+	if q.Limit <= 0 || q.Window <= 0 {
+		return false
+	}
+	if q.EndpointClass != endpointClass {
+		return false
+	}
+	return q.Principal == "" || q.Principal == p.Type
+}
@@ -0,0 +1,260 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// bulkValidationTimeout bounds a scheduled validation of all workflows
+const bulkValidationTimeout = 10 * time.Minute
+
+// WorkflowResult is the report of a single workflow within a bulk validation
+type WorkflowResult struct {
+	WorkflowID   uuid.UUID `json:"workflow_id"`
+	WorkflowName string    `json:"workflow_name"`
+	Active       bool      `json:"active"`
+	Report
+}
+
+// BulkReport aggregates the validation of all stored workflows
+type BulkReport struct {
+	LintProfile  string           `json:"lint_profile,omitempty"`
+	Total        int              `json:"total"`
+	Valid        int              `json:"valid"`
+	Invalid      int              `json:"invalid"`
+	WithWarnings int              `json:"with_warnings"`
+	ValidatedAt  time.Time        `json:"validated_at"`
+	Workflows    []WorkflowResult `json:"workflows"`
+}
+
+// Broken returns the names of all workflows with errors
+func (b *BulkReport) Broken() []string {
+	names := make([]string, 0, b.Invalid)
+	for _, r := range b.Workflows {
+		if !r.Valid {
+			names = append(names, r.WorkflowName)
+		}
+	}
+	return names
+}
+
+// ValidateAll validates every stored workflow like ValidateByIDWithProfile.
+// A workflow failing to load is reported invalid instead of aborting the run.
+func (v *Validator) ValidateAll(ctx context.Context, profile string) (*BulkReport, error) {
+	if v.linter != nil && profile != "" && !v.linter.HasProfile(profile) {
+		return nil, fmt.Errorf("unknown lint profile: %s", profile)
+	}
+
+	workflows, err := v.storage.ListWorkflows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bulk := &BulkReport{
+		ValidatedAt: time.Now(),
+		Workflows:   make([]WorkflowResult, 0, len(workflows)),
+	}
+	if v.linter != nil {
+		bulk.LintProfile = profile
+	}
+
+	for _, wf := range workflows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		rep, err := v.ValidateByIDWithProfile(ctx, wf.ID, profile)
+		if err != nil {
+			rep = Report{LintProfile: bulk.LintProfile}
+			rep.addError(Issue{
+				Code:       "WORKFLOW_901",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Failed to load workflow: %v", err),
+				WorkflowID: wf.ID.String(),
+			})
+			rep.finalize()
+		}
+
+		bulk.Total++
+		if rep.Valid {
+			bulk.Valid++
+		} else {
+			bulk.Invalid++
+		}
+		if len(rep.Warnings) > 0 {
+			bulk.WithWarnings++
+		}
+		bulk.Workflows = append(bulk.Workflows, WorkflowResult{
+			WorkflowID:   wf.ID,
+			WorkflowName: wf.WorkflowName,
+			Active:       wf.Active,
+			Report:       rep,
+		})
+	}
+	return bulk, nil
+}
+
+// NightlyValidation validates all stored workflows, persists the results so
+// the workflow list can show what broke (e.g. after a device was disabled)
+// and optionally repeats this once a day
+type NightlyValidation struct {
+	cfg     config.ValidationConfig
+	lint    config.LintConfig
+	storage *storage.PostgresClient
+	aliases AliasSource
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	stopChan chan struct{}
+	running  bool
+	wg       sync.WaitGroup
+}
+
+func NewNightlyValidation(cfg config.ValidationConfig, lint config.LintConfig, store *storage.PostgresClient, aliases AliasSource, logger *zap.Logger) *NightlyValidation {
+	return &NightlyValidation{
+		cfg:     cfg,
+		lint:    lint,
+		storage: store,
+		aliases: aliases,
+		logger:  logger,
+	}
+}
+
+// Start schedules the daily run if validation.nightly is enabled
+func (n *NightlyValidation) Start() error {
+	if !n.cfg.Nightly {
+		return nil
+	}
+	if _, err := time.Parse("15:04", n.cfg.NightlyAt); err != nil {
+		return fmt.Errorf("invalid validation.nightly_at %q: %w", n.cfg.NightlyAt, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.running {
+		return nil
+	}
+	n.running = true
+	n.stopChan = make(chan struct{})
+
+	n.wg.Add(1)
+	go n.loop(n.stopChan)
+
+	n.logger.Info("Nightly workflow validation scheduled",
+		zap.String("at", n.cfg.NightlyAt),
+		zap.Time("next_run", n.nextRun(time.Now())))
+	return nil
+}
+
+// Stop cancels the schedule; a run in progress is aborted
+func (n *NightlyValidation) Stop() {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return
+	}
+	n.running = false
+	close(n.stopChan)
+	n.mu.Unlock()
+
+	n.wg.Wait()
+}
+
+func (n *NightlyValidation) loop(stop chan struct{}) {
+	defer n.wg.Done()
+
+	for {
+		timer := time.NewTimer(time.Until(n.nextRun(time.Now())))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), bulkValidationTimeout)
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if _, err := n.Run(ctx, ""); err != nil {
+			n.logger.Error("Nightly workflow validation failed", zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// nextRun returns the next occurrence of validation.nightly_at in local time
+func (n *NightlyValidation) nextRun(now time.Time) time.Time {
+	at, _ := time.Parse("15:04", n.cfg.NightlyAt)
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Profile returns the lint profile used when none is requested
+func (n *NightlyValidation) Profile() string {
+	if n.cfg.Profile != "" {
+		return n.cfg.Profile
+	}
+	return NewLinter(n.lint).DefaultProfile()
+}
+
+// Run validates all workflows with the given lint profile, empty for the
+// configured one, and stores the result of each workflow
+func (n *NightlyValidation) Run(ctx context.Context, profile string) (*BulkReport, error) {
+	if profile == "" {
+		profile = n.Profile()
+	}
+
+	v := NewValidator(n.storage).WithLinter(NewLinter(n.lint)).WithAliases(n.aliases)
+	bulk, err := v.ValidateAll(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]storage.WorkflowValidation, 0, len(bulk.Workflows))
+	for _, r := range bulk.Workflows {
+		issues, err := json.Marshal(append(append([]Issue{}, r.Errors...), r.Warnings...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal validation issues: %w", err)
+		}
+		results = append(results, storage.WorkflowValidation{
+			WorkflowID:   r.WorkflowID,
+			Valid:        r.Valid,
+			ErrorCount:   len(r.Errors),
+			WarningCount: len(r.Warnings),
+			LintProfile:  r.LintProfile,
+			Issues:       issues,
+			ValidatedAt:  bulk.ValidatedAt,
+		})
+	}
+	if err := n.storage.SaveWorkflowValidations(ctx, results); err != nil {
+		return nil, err
+	}
+
+	if bulk.Invalid > 0 {
+		n.logger.Warn("Workflow validation found broken workflows",
+			zap.Int("total", bulk.Total),
+			zap.Int("invalid", bulk.Invalid),
+			zap.Strings("workflows", bulk.Broken()))
+	} else {
+		n.logger.Info("Workflow validation completed",
+			zap.Int("total", bulk.Total),
+			zap.Int("with_warnings", bulk.WithWarnings))
+	}
+	return bulk, nil
+}
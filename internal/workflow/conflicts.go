@@ -0,0 +1,236 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/executor"
+	"github.com/google/uuid"
+)
+
+// registerWrite is a device register written by a step, directly, by a
+// post-action or by a sub-workflow the step calls
+type registerWrite struct {
+	device    string
+	register  string
+	path      string   // e.g. "/steps/2/on_success_write/0" or "/steps/2 > <workflow>/steps/1"
+	step      string   // Name of the top-level step
+	resources []string // Resource tags of the top-level step
+}
+
+// parallelBranch splits a step number like "30.2" into its group "30" and
+// branch "2". Steps of different branches of a group may run concurrently.
+func parallelBranch(number string) (string, string, bool) {
+	group, rest, found := strings.Cut(number, ".")
+	if !found || group == "" || rest == "" {
+		return "", "", false
+	}
+	branch, _, _ := strings.Cut(rest, ".")
+	return group, branch, true
+}
+
+// validateWriteConflicts warns about registers written by more than one
+// branch of a parallel step group, including the writes of sub-workflows
+// called in a branch, and about registers written twice by a parallel batch.
+// Writes of steps sharing a resource tag never overlap and are left out.
+func (st *walkState) validateWriteConflicts(ctx context.Context, wid uuid.UUID, wf *definition.Workflow) {
+	type key struct{ group, device, register string }
+	branches := map[key]map[string][]registerWrite{}
+
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		base := fmt.Sprintf("/steps/%d", i)
+		st.checkBatchConflicts(wid, step, i, base)
+
+		group, branch, ok := parallelBranch(step.Number)
+		if !ok {
+			continue
+		}
+		for _, w := range st.stepWrites(ctx, step, base, map[uuid.UUID]bool{wid: true}) {
+			w.step = step.Name
+			w.resources = step.Resources
+			k := key{group, w.device, w.register}
+			if branches[k] == nil {
+				branches[k] = map[string][]registerWrite{}
+			}
+			branches[k][branch] = append(branches[k][branch], w)
+		}
+	}
+
+	keys := make([]key, 0, len(branches))
+	for k, byBranch := range branches {
+		if len(byBranch) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	for _, k := range keys {
+		byBranch := branches[k]
+		names := make([]string, 0, len(byBranch))
+		for branch := range byBranch {
+			names = append(names, branch)
+		}
+		sort.Strings(names)
+
+		// Branches conflict unless all their writes share a resource tag
+		var conflicting []registerWrite
+		for a := range names {
+			for b := a + 1; b < len(names); b++ {
+				for _, wa := range byBranch[names[a]] {
+					for _, wb := range byBranch[names[b]] {
+						if !shareResource(wa.resources, wb.resources) {
+							conflicting = appendWrite(conflicting, wa)
+							conflicting = appendWrite(conflicting, wb)
+						}
+					}
+				}
+			}
+		}
+		if len(conflicting) == 0 {
+			continue
+		}
+
+		paths := make([]string, len(conflicting))
+		steps := make([]string, 0, len(conflicting))
+		for i, w := range conflicting {
+			paths[i] = w.path
+			if !slices.Contains(steps, w.step) {
+				steps = append(steps, w.step)
+			}
+		}
+		st.report.addWarning(Issue{
+			Code:       "CONFLICT_001",
+			Severity:   SevWarning,
+			Message:    fmt.Sprintf("Register '%s' on device '%s' is written by parallel branches of step group %s: %s", k.register, k.device, k.group, strings.Join(steps, ", ")),
+			WorkflowID: wid.String(),
+			StepName:   conflicting[0].step,
+			Field:      "number",
+			Path:       paths[0],
+			Hint:       "Move the writes into one branch or give the steps a common resource tag",
+			Meta: map[string]any{
+				"group":    k.group,
+				"device":   k.device,
+				"register": k.register,
+				"paths":    paths,
+			},
+		})
+	}
+}
+
+// checkBatchConflicts warns about registers written by more than one item
+// of a parallel batch
+func (st *walkState) checkBatchConflicts(wid uuid.UUID, step *definition.Step, idx int, base string) {
+	if step.Type != definition.StepTypeDevice || step.Operation != "batch" {
+		return
+	}
+	mode, items, err := executor.ParseBatch(step.Parameters)
+	if err != nil || mode != executor.BatchParallel {
+		return
+	}
+
+	written := map[string][]string{}
+	var registers []string
+	for _, item := range items {
+		if !item.Writes() || item.Register == "" {
+			continue
+		}
+		if written[item.Register] == nil {
+			registers = append(registers, item.Register)
+		}
+		written[item.Register] = append(written[item.Register], item.Name)
+	}
+
+	for _, register := range registers {
+		names := written[register]
+		if len(names) < 2 {
+			continue
+		}
+		st.report.addWarning(Issue{
+			Code:       "CONFLICT_002",
+			Severity:   SevWarning,
+			Message:    fmt.Sprintf("Register '%s' is written by items %s of a parallel batch", register, strings.Join(names, ", ")),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "parameters.items",
+			Path:       base + "/parameters/items",
+			Hint:       "Use mode \"sequence\" to write the register in order",
+			Meta:       map[string]any{"step_index": idx, "register": register, "items": names},
+		})
+	}
+}
+
+// stepWrites lists the registers a step writes. Sub-workflows are followed
+// unless they are already on the call path.
+func (st *walkState) stepWrites(ctx context.Context, step *definition.Step, base string, calling map[uuid.UUID]bool) []registerWrite {
+	var writes []registerWrite
+	add := func(device, register, path string) {
+		if device != "" && register != "" {
+			writes = append(writes, registerWrite{device: device, register: register, path: path})
+		}
+	}
+
+	switch step.Type {
+	case definition.StepTypeDevice:
+		if _, isWrite := verificationOpFor(step.Operation); isWrite {
+			add(step.DeviceID, stepTarget(*step), base)
+		}
+		if step.Operation == "batch" {
+			if _, items, err := executor.ParseBatch(step.Parameters); err == nil {
+				for j, item := range items {
+					if item.Writes() {
+						add(step.DeviceID, item.Register, fmt.Sprintf("%s/parameters/items/%d", base, j))
+					}
+				}
+			}
+		}
+
+	case definition.StepTypeWorkflow:
+		subID, err := uuid.Parse(step.WorkflowID)
+		if err != nil || calling[subID] {
+			break
+		}
+		sub, err := st.getWorkflow(ctx, subID)
+		if err != nil || sub == nil {
+			break
+		}
+		calling[subID] = true
+		for i := range sub.Steps {
+			subBase := fmt.Sprintf("%s > %s/steps/%d", base, subID, i)
+			writes = append(writes, st.stepWrites(ctx, &sub.Steps[i], subBase, calling)...)
+		}
+		delete(calling, subID)
+	}
+
+	for j, action := range step.OnSuccessWrite {
+		add(action.DeviceID, action.Register, fmt.Sprintf("%s/on_success_write/%d", base, j))
+	}
+	for j, action := range step.OnFailureWrite {
+		add(action.DeviceID, action.Register, fmt.Sprintf("%s/on_failure_write/%d", base, j))
+	}
+	return writes
+}
+
+func shareResource(a, b []string) bool {
+	for _, tag := range a {
+		if slices.Contains(b, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendWrite(writes []registerWrite, w registerWrite) []registerWrite {
+	for _, existing := range writes {
+		if existing.path == w.path {
+			return writes
+		}
+	}
+	return append(writes, w)
+}
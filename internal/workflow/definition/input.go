@@ -0,0 +1,168 @@
+package definition
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// InputType is the JSON type of an execution input
+type InputType string
+
+const (
+	InputTypeString  InputType = "string"
+	InputTypeNumber  InputType = "number"
+	InputTypeInteger InputType = "integer"
+	InputTypeBoolean InputType = "boolean"
+	InputTypeObject  InputType = "object"
+	InputTypeArray   InputType = "array"
+)
+
+// InputParam declares one execution input of a workflow
+type InputParam struct {
+	Name        string    `json:"name"`
+	Type        InputType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	Default     any       `json:"default,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// InputError describes why a single input field was rejected
+type InputError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"` // "required", "type"
+	Message string `json:"message"`
+}
+
+// ValidInputType reports whether t is a supported input type
+func ValidInputType(t InputType) bool {
+	switch t {
+	case InputTypeString, InputTypeNumber, InputTypeInteger, InputTypeBoolean, InputTypeObject, InputTypeArray:
+		return true
+	}
+	return false
+}
+
+// ApplyInputSchema validates input against the declared inputs and returns a
+// copy with defaults filled in. Fields not declared in the schema are passed
+// through unchanged. Workflows without inputs accept any input.
+func (wf *Workflow) ApplyInputSchema(input map[string]any) (map[string]any, []InputError) {
+	return applySchema(wf.Inputs, input)
+}
+
+func applySchema(params []InputParam, input map[string]any) (map[string]any, []InputError) {
+	result := make(map[string]any, len(input)+len(params))
+	for k, v := range input {
+		result[k] = v
+	}
+
+	var errs []InputError
+	for _, param := range params {
+		value, ok := result[param.Name]
+		if !ok || value == nil {
+			if param.Default != nil {
+				// Steps may change the value, the definition must keep it
+				result[param.Name] = copyValue(param.Default)
+				continue
+			}
+			if param.Required {
+				errs = append(errs, InputError{
+					Field:   param.Name,
+					Code:    "required",
+					Message: fmt.Sprintf("input '%s' is required", param.Name),
+				})
+			}
+			continue
+		}
+
+		if !MatchesInputType(param.Type, value) {
+			errs = append(errs, InputError{
+				Field:   param.Name,
+				Code:    "type",
+				Message: fmt.Sprintf("input '%s' must be of type %s, got %s", param.Name, param.Type, jsonTypeName(value)),
+			})
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return result, errs
+}
+
+// copyValue deep-copies the objects and arrays of a decoded JSON value
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, item := range v {
+			c[k] = copyValue(item)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	}
+	return value
+}
+
+// MatchesInputType reports whether a decoded JSON value has the given type
+func MatchesInputType(t InputType, value any) bool {
+	switch t {
+	case InputTypeString:
+		_, ok := value.(string)
+		return ok
+	case InputTypeNumber:
+		_, ok := toFloat(value)
+		return ok
+	case InputTypeInteger:
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case InputTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case InputTypeObject:
+		_, ok := value.(map[string]any)
+		return ok
+	case InputTypeArray:
+		_, ok := value.([]any)
+		return ok
+	}
+	return false
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
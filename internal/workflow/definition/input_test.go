@@ -0,0 +1,81 @@
+package definition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyInputSchema(t *testing.T) {
+	params := []InputParam{
+		{Name: "count", Type: InputTypeInteger, Required: true},
+		{Name: "speed", Type: InputTypeNumber, Default: 1.5},
+		{Name: "options", Type: InputTypeObject, Default: map[string]any{"mode": "auto", "axes": []any{"x", "y"}}},
+	}
+
+	tests := []struct {
+		name      string
+		input     map[string]any
+		want      map[string]any
+		wantCodes []string
+	}{
+		{
+			name:  "defaults filled in",
+			input: map[string]any{"count": 3.0},
+			want: map[string]any{
+				"count":   3.0,
+				"speed":   1.5,
+				"options": map[string]any{"mode": "auto", "axes": []any{"x", "y"}},
+			},
+		},
+		{
+			name:  "given values kept, undeclared passed through",
+			input: map[string]any{"count": 3.0, "speed": 2.0, "options": map[string]any{}, "extra": "x"},
+			want:  map[string]any{"count": 3.0, "speed": 2.0, "options": map[string]any{}, "extra": "x"},
+		},
+		{
+			name:      "required missing",
+			input:     map[string]any{},
+			wantCodes: []string{"required"},
+		},
+		{
+			name:      "wrong types",
+			input:     map[string]any{"count": 1.5, "speed": "fast"},
+			wantCodes: []string{"type", "type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := applySchema(params, tt.input)
+			var codes []string
+			for _, e := range errs {
+				codes = append(codes, e.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Fatalf("error codes = %v, want %v (%v)", codes, tt.wantCodes, errs)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applySchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyInputSchemaCopiesDefaults(t *testing.T) {
+	params := []InputParam{
+		{Name: "options", Type: InputTypeObject, Default: map[string]any{"nested": map[string]any{"n": 1.0}}},
+		{Name: "list", Type: InputTypeArray, Default: []any{map[string]any{"n": 1.0}}},
+	}
+
+	first, _ := applySchema(params, nil)
+	first["options"].(map[string]any)["nested"].(map[string]any)["n"] = 2.0
+	first["list"].([]any)[0].(map[string]any)["n"] = 2.0
+
+	second, _ := applySchema(params, nil)
+	if n := second["options"].(map[string]any)["nested"].(map[string]any)["n"]; n != 1.0 {
+		t.Errorf("object default changed by an earlier execution: n = %v", n)
+	}
+	if n := second["list"].([]any)[0].(map[string]any)["n"]; n != 1.0 {
+		t.Errorf("array default changed by an earlier execution: n = %v", n)
+	}
+}
@@ -0,0 +1,130 @@
+package definition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reference prefixes usable in input_mapping values. A string starting with
+// "$$" is a literal string with one leading "$".
+const (
+	RefInput     = "$input"
+	RefVariables = "$variables"
+	RefSteps     = "$steps"
+)
+
+// Scope holds the values a step can reference while its workflow runs
+type Scope struct {
+	Input     map[string]any            // Execution (or sub-workflow) input
+	Variables map[string]string         // Workflow variables
+	Steps     map[string]map[string]any // Results of completed steps by step number
+}
+
+// IsReference reports whether a mapping value refers to the scope
+func IsReference(value any) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, "$") && !strings.HasPrefix(s, "$$")
+}
+
+// ParseReference splits "$steps.20.value" into ("$steps", "20", "value").
+// For $input and $variables the key is the first path segment.
+func ParseReference(ref string) (kind, key, path string, err error) {
+	parts := strings.SplitN(ref, ".", 3)
+	kind = parts[0]
+
+	switch kind {
+	case RefInput, RefVariables:
+		if len(parts) < 2 || parts[1] == "" {
+			return "", "", "", fmt.Errorf("reference %q needs a key", ref)
+		}
+		key = parts[1]
+		if len(parts) == 3 {
+			path = parts[2]
+		}
+	case RefSteps:
+		if len(parts) < 2 || parts[1] == "" {
+			return "", "", "", fmt.Errorf("reference %q needs a step number", ref)
+		}
+		key = parts[1]
+		if len(parts) == 3 {
+			path = parts[2]
+		}
+	default:
+		return "", "", "", fmt.Errorf("unknown reference %q (use $input, $variables or $steps)", ref)
+	}
+
+	return kind, key, path, nil
+}
+
+// Resolve returns the value a mapping entry stands for: static values as-is,
+// references looked up in the scope
+func (s *Scope) Resolve(value any) (any, error) {
+	str, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	if strings.HasPrefix(str, "$$") {
+		return str[1:], nil
+	}
+	if !strings.HasPrefix(str, "$") {
+		return value, nil
+	}
+
+	kind, key, path, err := ParseReference(str)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved any
+	var found bool
+	switch kind {
+	case RefInput:
+		resolved, found = lookupPath(s.Input, joinPath(key, path))
+	case RefVariables:
+		var v string
+		v, found = s.Variables[key]
+		resolved = v
+	case RefSteps:
+		resolved, found = lookupPath(s.Steps[key], path)
+	}
+	if !found {
+		return nil, fmt.Errorf("reference %q could not be resolved", str)
+	}
+
+	return resolved, nil
+}
+
+// ResolveMapping resolves every entry of an input mapping
+func (s *Scope) ResolveMapping(mapping map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(mapping))
+	for name, value := range mapping {
+		resolved, err := s.Resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("input_mapping.%s: %w", name, err)
+		}
+		result[name] = resolved
+	}
+	return result, nil
+}
+
+// MapOutputs builds a workflow step's result from a sub-workflow result. Each
+// output_mapping entry maps a parent key to a declared output of the
+// sub-workflow, or to a dotted path into its result if it declares none.
+func MapOutputs(mapping map[string]string, subOutput map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(mapping))
+	for parentKey, source := range mapping {
+		value, ok := lookupPath(subOutput, source)
+		if !ok {
+			return nil, fmt.Errorf("output_mapping.%s: %q not found in sub-workflow result", parentKey, source)
+		}
+		result[parentKey] = value
+	}
+	return result, nil
+}
+
+func joinPath(key, path string) string {
+	if path == "" {
+		return key
+	}
+	return key + "." + path
+}
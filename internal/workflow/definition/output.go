@@ -0,0 +1,62 @@
+package definition
+
+import "strings"
+
+// OutputRef points at a value in the result of a top-level step
+type OutputRef struct {
+	Step string `json:"step"`           // Step number, e.g. "30"
+	Path string `json:"path,omitempty"` // Dotted key path into the step output; empty = whole output
+}
+
+// ResolveOutputs builds the execution output from step results keyed by step
+// number. References to missing steps or keys are returned as missing and
+// resolve to nil.
+func (wf *Workflow) ResolveOutputs(results map[string]map[string]any) (map[string]any, []string) {
+	if len(wf.Outputs) == 0 {
+		return nil, nil
+	}
+
+	output := make(map[string]any, len(wf.Outputs))
+	var missing []string
+	for name, ref := range wf.Outputs {
+		value, ok := lookupPath(results[ref.Step], ref.Path)
+		if !ok {
+			missing = append(missing, name)
+		}
+		output[name] = value
+	}
+
+	return output, missing
+}
+
+func lookupPath(result map[string]any, path string) (any, bool) {
+	if result == nil {
+		return nil, false
+	}
+	if path == "" {
+		return result, true
+	}
+
+	var current any = result
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// HasStep reports whether the workflow has a top-level step with the number
+func (wf *Workflow) HasStep(number string) bool {
+	for _, step := range wf.Steps {
+		if step.Number == number {
+			return true
+		}
+	}
+	return false
+}
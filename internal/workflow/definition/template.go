@@ -0,0 +1,279 @@
+package definition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RefArgs prefixes template placeholders, e.g. "$args.position"
+const RefArgs = "$args"
+
+// StepTemplate is a parameterized step stored centrally and referenced by
+// workflow steps through their template name
+type StepTemplate struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Arguments   []InputParam `json:"arguments,omitempty"`
+	Step        Step         `json:"step"`
+}
+
+// TemplateSource returns the stored definition of a step template by name
+type TemplateSource func(ctx context.Context, name string) ([]byte, error)
+
+// TemplateError describes a step whose template could not be expanded
+type TemplateError struct {
+	StepIndex int    `json:"step_index"`
+	Template  string `json:"template"`
+	Err       error  `json:"-"`
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("step %d: template '%s': %v", e.StepIndex, e.Template, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+func ParseStepTemplate(data []byte) (*StepTemplate, error) {
+	var t StepTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks that a template can be expanded at all
+func (t *StepTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name is required")
+	}
+	switch t.Step.Type {
+	case StepTypeDevice, StepTypeWorkflow, StepTypeWait, StepTypeSample:
+	default:
+		return fmt.Errorf("unsupported step type: %s", t.Step.Type)
+	}
+	if t.Step.Template != "" {
+		return fmt.Errorf("templates cannot reference other templates")
+	}
+
+	seen := map[string]bool{}
+	for _, arg := range t.Arguments {
+		if strings.TrimSpace(arg.Name) == "" {
+			return fmt.Errorf("argument name is required")
+		}
+		if seen[arg.Name] {
+			return fmt.Errorf("duplicate argument '%s'", arg.Name)
+		}
+		seen[arg.Name] = true
+		if !ValidInputType(arg.Type) {
+			return fmt.Errorf("argument '%s' has unsupported type '%s'", arg.Name, arg.Type)
+		}
+	}
+	return nil
+}
+
+// Expand binds the arguments of a referencing step and returns the resulting
+// step. Number, name, condition, error strategy, timeout and post-actions of
+// the referencing step take precedence over the template.
+func (t *StepTemplate) Expand(step Step) (Step, error) {
+	declared := map[string]bool{}
+	for _, arg := range t.Arguments {
+		declared[arg.Name] = true
+	}
+	names := make([]string, 0, len(step.Arguments))
+	for name := range step.Arguments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !declared[name] {
+			return Step{}, fmt.Errorf("unknown argument '%s'", name)
+		}
+	}
+
+	args, errs := applySchema(t.Arguments, step.Arguments)
+	if len(errs) > 0 {
+		return Step{}, fmt.Errorf("%s", errs[0].Message)
+	}
+
+	expanded := t.Step
+	var err error
+	if expanded.DeviceID, err = bindString(expanded.DeviceID, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.Operation, err = bindString(expanded.Operation, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.WorkflowID, err = bindString(expanded.WorkflowID, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.Parameters, err = bindMap(expanded.Parameters, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.InputMapping, err = bindMap(expanded.InputMapping, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.OnSuccessWrite, err = bindPostActions(expanded.OnSuccessWrite, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.OnFailureWrite, err = bindPostActions(expanded.OnFailureWrite, args); err != nil {
+		return Step{}, err
+	}
+	if expanded.Documentation != nil {
+		doc := *expanded.Documentation
+		if doc.DeviceID, err = bindString(doc.DeviceID, args); err != nil {
+			return Step{}, err
+		}
+		if doc.Register, err = bindString(doc.Register, args); err != nil {
+			return Step{}, err
+		}
+		expanded.Documentation = &doc
+	}
+
+	expanded.Number = step.Number
+	expanded.Transitions = step.Transitions // Step numbers belong to the calling workflow
+	expanded.Template = step.Template
+	expanded.Arguments = step.Arguments
+	if step.Name != "" {
+		expanded.Name = step.Name
+	}
+	if step.Condition != "" {
+		expanded.Condition = step.Condition
+	}
+	if step.OnError != "" {
+		expanded.OnError = step.OnError
+	}
+	if step.Timeout.Duration > 0 {
+		expanded.Timeout = step.Timeout
+	}
+	if step.Resources != nil {
+		expanded.Resources = step.Resources
+	}
+	if step.OnSuccessWrite != nil {
+		expanded.OnSuccessWrite = step.OnSuccessWrite
+	}
+	if step.OnFailureWrite != nil {
+		expanded.OnFailureWrite = step.OnFailureWrite
+	}
+	if step.Documentation != nil {
+		expanded.Documentation = step.Documentation
+	}
+
+	return expanded, nil
+}
+
+// ExpandTemplates replaces steps referencing a template by their expanded
+// form. Steps that cannot be expanded are left unchanged and reported.
+func (wf *Workflow) ExpandTemplates(ctx context.Context, src TemplateSource) []*TemplateError {
+	templates := map[string]*StepTemplate{}
+	var errs []*TemplateError
+
+	for i, step := range wf.Steps {
+		if step.Template == "" {
+			continue
+		}
+
+		tmpl, ok := templates[step.Template]
+		if !ok {
+			data, err := src(ctx, step.Template)
+			if err == nil {
+				tmpl, err = ParseStepTemplate(data)
+			}
+			if err != nil {
+				errs = append(errs, &TemplateError{StepIndex: i, Template: step.Template, Err: err})
+				continue
+			}
+			templates[step.Template] = tmpl
+		}
+
+		expanded, err := tmpl.Expand(step)
+		if err != nil {
+			errs = append(errs, &TemplateError{StepIndex: i, Template: step.Template, Err: err})
+			continue
+		}
+		wf.Steps[i] = expanded
+	}
+
+	return errs
+}
+
+func bindString(s string, args map[string]any) (string, error) {
+	value, err := bindValue(s, args)
+	if err != nil {
+		return "", err
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("placeholder %q must bind to a string", s)
+	}
+	return str, nil
+}
+
+func bindMap(m map[string]any, args map[string]any) (map[string]any, error) {
+	if m == nil {
+		return nil, nil
+	}
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		bound, err := bindValue(v, args)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = bound
+	}
+	return result, nil
+}
+
+func bindPostActions(actions []PostAction, args map[string]any) ([]PostAction, error) {
+	if actions == nil {
+		return nil, nil
+	}
+	result := make([]PostAction, len(actions))
+	for i, action := range actions {
+		var err error
+		if result[i].DeviceID, err = bindString(action.DeviceID, args); err != nil {
+			return nil, err
+		}
+		if result[i].Register, err = bindString(action.Register, args); err != nil {
+			return nil, err
+		}
+		if result[i].Value, err = bindValue(action.Value, args); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// bindValue replaces "$args.<name>" strings, also inside nested objects and
+// arrays, by the bound argument value
+func bindValue(value any, args map[string]any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		name, ok := strings.CutPrefix(v, RefArgs+".")
+		if !ok {
+			return v, nil
+		}
+		bound, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("argument '%s' is not bound", name)
+		}
+		return bound, nil
+	case map[string]any:
+		return bindMap(v, args)
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			bound, err := bindValue(item, args)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = bound
+		}
+		return result, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,148 @@
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/expr"
+)
+
+// Transition jumps to another step after a step succeeded, like the
+// transitions of a classic step chain
+type Transition struct {
+	Condition string `json:"condition,omitempty"` // Expression over $input, $variables and $steps; empty = always
+	Goto      string `json:"goto"`                // Step number
+	MaxJumps  int    `json:"max_jumps,omitempty"` // Jumps per execution before it fails, 0 = the default limit for jumps back
+}
+
+// Jumps counts the jumps taken per transition during an execution
+type Jumps map[string]int
+
+// ParseCondition parses the condition of a transition; nil means always
+func (t *Transition) ParseCondition() (*expr.Expression, error) {
+	if strings.TrimSpace(t.Condition) == "" {
+		return nil, nil
+	}
+	parsed, err := expr.Parse(t.Condition)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range parsed.Refs() {
+		if _, _, _, err := ParseReference(ref); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// Holds reports whether the condition of the transition is true in the scope
+func (t *Transition) Holds(scope *Scope) (bool, error) {
+	condition, err := t.ParseCondition()
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", t.Condition, err)
+	}
+	if condition == nil {
+		return true, nil
+	}
+
+	v, err := condition.Eval(func(ref string) (float64, error) {
+		value, err := scope.Resolve(ref)
+		if err != nil {
+			return 0, err
+		}
+		f, ok := numericValue(value)
+		if !ok {
+			return 0, fmt.Errorf("reference %q is not numeric: %v", ref, value)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", t.Condition, err)
+	}
+	return v != 0, nil
+}
+
+// StepIndex returns the index of the top-level step with the number, -1 if
+// there is none
+func (wf *Workflow) StepIndex(number string) int {
+	for i, step := range wf.Steps {
+		if step.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextStep returns the index of the step following the step at index i: the
+// target of its first transition that holds, otherwise i+1. Taking a
+// transition more often than its max_jumps fails; jumps back without
+// max_jumps are limited to defaultMaxJumps (0 = unlimited).
+func (wf *Workflow) NextStep(i int, scope *Scope, jumps Jumps, defaultMaxJumps int) (int, error) {
+	step := &wf.Steps[i]
+	for j := range step.Transitions {
+		t := &step.Transitions[j]
+		holds, err := t.Holds(scope)
+		if err != nil {
+			return 0, fmt.Errorf("transition %d of step %s: %w", j, step.Number, err)
+		}
+		if !holds {
+			continue
+		}
+
+		target := wf.StepIndex(t.Goto)
+		if target < 0 {
+			return 0, fmt.Errorf("transition %d of step %s: unknown step %s", j, step.Number, t.Goto)
+		}
+		key := fmt.Sprintf("%d/%d", i, j)
+		jumps[key]++
+		if t.MaxJumps > 0 && jumps[key] > t.MaxJumps {
+			return 0, fmt.Errorf("transition from step %s to %s exceeded max_jumps %d", step.Number, t.Goto, t.MaxJumps)
+		}
+		if t.MaxJumps == 0 && target <= i && defaultMaxJumps > 0 && jumps[key] > defaultMaxJumps {
+			return 0, fmt.Errorf("transition from step %s to %s exceeded the default limit of %d jumps back", step.Number, t.Goto, defaultMaxJumps)
+		}
+		return target, nil
+	}
+	return i + 1, nil
+}
+
+// numericValue converts a resolved value for a condition; booleans count as
+// 1 and 0, strings are parsed
+func numericValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(n)) {
+		case "true":
+			return 1, true
+		case "false":
+			return 0, true
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,170 @@
+package definition
+
+import (
+	"testing"
+)
+
+func TestTransitionHolds(t *testing.T) {
+	scope := &Scope{
+		Input:     map[string]any{"retries": 2, "mode": "auto"},
+		Variables: map[string]string{"enabled": "true", "limit": "5"},
+		Steps:     map[string]map[string]any{"30": {"value": 0.0, "ok": false}},
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		want      bool
+		wantErr   bool
+	}{
+		{"empty always holds", "", true, false},
+		{"blank always holds", "  ", true, false},
+		{"step value", "$steps.30.value == 0", true, false},
+		{"input and step", "$steps.30.value == 0 && $input.retries > 2", false, false},
+		{"boolean step result", "!$steps.30.ok", true, false},
+		{"string variables", "$variables.enabled && $variables.limit >= 5", true, false},
+		{"non-numeric reference", "$input.mode == 1", false, true},
+		{"unresolved reference", "$steps.40.value == 1", false, true},
+		{"unknown reference kind", "$outputs.x == 1", false, true},
+		{"syntax error", "$steps.30.value ==", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transition{Condition: tt.condition, Goto: "10"}
+			got, err := tr.Holds(scope)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Holds(%q) error = %v, want error %v", tt.condition, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Holds(%q) = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextStep(t *testing.T) {
+	steps := func(transitions ...[]Transition) []Step {
+		numbers := []string{"10", "20", "30", "40"}
+		out := make([]Step, len(numbers))
+		for i, number := range numbers {
+			out[i] = Step{Number: number, Name: "step_" + number}
+			if i < len(transitions) {
+				out[i].Transitions = transitions[i]
+			}
+		}
+		return out
+	}
+	scope := &Scope{Steps: map[string]map[string]any{"30": {"value": 0.0}}}
+
+	tests := []struct {
+		name            string
+		steps           []Step
+		from            int
+		calls           int // NextStep calls with the same jumps, the last one is checked
+		defaultMaxJumps int
+		want            int
+		wantErr         bool
+	}{
+		{
+			name:  "no transitions",
+			steps: steps(),
+			from:  1,
+			calls: 1,
+			want:  2,
+		},
+		{
+			name:  "first transition that holds wins",
+			steps: steps(nil, nil, []Transition{{Condition: "$steps.30.value == 1", Goto: "10"}, {Condition: "$steps.30.value == 0", Goto: "20"}, {Goto: "40"}}),
+			from:  2,
+			calls: 1,
+			want:  1,
+		},
+		{
+			name:  "no transition holds",
+			steps: steps(nil, nil, []Transition{{Condition: "$steps.30.value == 1", Goto: "10"}}),
+			from:  2,
+			calls: 1,
+			want:  3,
+		},
+		{
+			name:  "jump forward",
+			steps: steps([]Transition{{Goto: "40"}}),
+			from:  0,
+			calls: 1,
+			want:  3,
+		},
+		{
+			name:    "unknown target",
+			steps:   steps([]Transition{{Goto: "99"}}),
+			from:    0,
+			calls:   1,
+			wantErr: true,
+		},
+		{
+			name:  "within max_jumps",
+			steps: steps(nil, nil, []Transition{{Goto: "10", MaxJumps: 3}}),
+			from:  2,
+			calls: 3,
+			want:  0,
+		},
+		{
+			name:    "max_jumps exceeded",
+			steps:   steps(nil, nil, []Transition{{Goto: "10", MaxJumps: 3}}),
+			from:    2,
+			calls:   4,
+			wantErr: true,
+		},
+		{
+			name:            "default limit for jumps back",
+			steps:           steps(nil, nil, []Transition{{Goto: "10"}}),
+			from:            2,
+			calls:           3,
+			defaultMaxJumps: 2,
+			wantErr:         true,
+		},
+		{
+			name:            "default limit ignores jumps forward",
+			steps:           steps([]Transition{{Goto: "30"}}),
+			from:            0,
+			calls:           3,
+			defaultMaxJumps: 2,
+			want:            2,
+		},
+		{
+			name:  "no default limit",
+			steps: steps(nil, nil, []Transition{{Goto: "10"}}),
+			from:  2,
+			calls: 100,
+			want:  0,
+		},
+		{
+			name:    "invalid condition",
+			steps:   steps([]Transition{{Condition: "$nope.x", Goto: "20"}}),
+			from:    0,
+			calls:   1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &Workflow{Steps: tt.steps}
+			jumps := Jumps{}
+			var got int
+			var err error
+			for range tt.calls {
+				got, err = wf.NextStep(tt.from, scope, jumps, tt.defaultMaxJumps)
+				if err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextStep() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("NextStep() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,14 +7,32 @@ import (
 )
 
 type Workflow struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	ProgramName string            `json:"program_name"` // "main", "sub_pick", etc.
-	Description string            `json:"description,omitempty"`
-	Version     string            `json:"version"`
-	Steps       []Step            `json:"steps"`
-	Variables   map[string]string `json:"variables,omitempty"`
-	Loop        *LoopConfig       `json:"loop,omitempty"`
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	ProgramName string               `json:"program_name"` // "main", "sub_pick", etc.
+	Description string               `json:"description,omitempty"`
+	Version     string               `json:"version"`
+	Steps       []Step               `json:"steps"`
+	Variables   map[string]string    `json:"variables,omitempty"`
+	Inputs      []InputParam         `json:"inputs,omitempty"`  // Execution input schema
+	Outputs     map[string]OutputRef `json:"outputs,omitempty"` // Execution output mapping
+	Loop        *LoopConfig          `json:"loop,omitempty"`
+	Tags        []string             `json:"tags,omitempty"` // Added to every execution, e.g. the recipe name
+
+	// Takt: runs longer than CycleTime emit execution.cycle_time_exceeded;
+	// with CycleTimeoutFactor they are cancelled at that multiple of it
+	CycleTime          Duration `json:"cycle_time,omitempty"`
+	CycleTimeoutFactor float64  `json:"cycle_timeout_factor,omitempty"`
+
+	// Checked before an execution starts; executions are rejected unless all hold
+	Requires *Preconditions `json:"requires,omitempty"`
+}
+
+// Preconditions of a workflow execution
+type Preconditions struct {
+	Devices       []string `json:"devices,omitempty"`        // Loaded and connected
+	MachineStates []string `json:"machine_states,omitempty"` // Machine in one of them
+	Variables     []string `json:"variables,omitempty"`      // Set in variables or execution input
 }
 
 type LoopConfig struct {
@@ -34,12 +52,65 @@ type Step struct {
 	Parameters map[string]any `json:"parameters,omitempty"`
 
 	// Workflow Step (Sub-Workflow)
-	WorkflowID string `json:"workflow_id,omitempty"`
+	WorkflowID    string            `json:"workflow_id,omitempty"`
+	InputMapping  map[string]any    `json:"input_mapping,omitempty"`  // Sub-workflow input: static values or $input/$variables/$steps references
+	OutputMapping map[string]string `json:"output_mapping,omitempty"` // Parent key -> sub-workflow output name or result path
+
+	// Sample Step (latest polled values, no device reads)
+	Samples []SampleRef `json:"samples,omitempty"`
+
+	// Template Step (expanded before validation and execution)
+	Template  string         `json:"template,omitempty"`  // Step template name
+	Arguments map[string]any `json:"arguments,omitempty"` // Bound to the template's $args placeholders
 
 	// Common
 	Condition string        `json:"condition,omitempty"`
 	OnError   ErrorStrategy `json:"on_error,omitempty"`
 	Timeout   Duration      `json:"timeout,omitempty"`
+	Resources []string      `json:"resources,omitempty"` // Shared resource tags; steps with the same tag never run concurrently
+
+	// Post-actions, e.g. handshake bits acknowledging the step to a PLC
+	OnSuccessWrite []PostAction `json:"on_success_write,omitempty"`
+	OnFailureWrite []PostAction `json:"on_failure_write,omitempty"`
+
+	// Transitions jump to another step after success; the first one that
+	// holds wins, without a match the next step runs
+	Transitions []Transition `json:"transitions,omitempty"`
+
+	// Documentation points operators to the datasheet section of the step
+	Documentation *DocumentationRef `json:"documentation,omitempty"`
+}
+
+// DocumentationRef selects a section of the datasheet of a device module
+type DocumentationRef struct {
+	DeviceID string `json:"device_id,omitempty"` // Default the step's device
+	Register string `json:"register,omitempty"`  // Selects the module, default the step's register
+	Page     int    `json:"page,omitempty"`
+	Anchor   string `json:"anchor,omitempty"` // Named destination in the datasheet
+	Label    string `json:"label,omitempty"`  // Shown instead of the page, e.g. "Wiring diagram"
+}
+
+// SampleRef selects a polled register value captured by a sample step
+type SampleRef struct {
+	DeviceID string   `json:"device_id"`
+	Register string   `json:"register"`          // Register, logical name or alias
+	As       string   `json:"as,omitempty"`      // Output key, default the register
+	MaxAge   Duration `json:"max_age,omitempty"` // Fail if the value is older, 0 = any age
+}
+
+// Key is the output key of the sampled value
+func (r SampleRef) Key() string {
+	if r.As != "" {
+		return r.As
+	}
+	return r.Register
+}
+
+// PostAction writes a value to a device register or logical name after a step
+type PostAction struct {
+	DeviceID string `json:"device_id"`
+	Register string `json:"register"` // Register or logical name
+	Value    any    `json:"value"`
 }
 
 // Duration is a wrapper around time.Duration that supports JSON string parsing
@@ -81,6 +152,7 @@ const (
 	StepTypeDevice   StepType = "device"
 	StepTypeWorkflow StepType = "workflow"
 	StepTypeWait     StepType = "wait"
+	StepTypeSample   StepType = "sample"
 )
 
 type ErrorStrategy string
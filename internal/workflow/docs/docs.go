@@ -0,0 +1,322 @@
+// Package docs renders workflow definitions as documentation for review by
+// process engineers
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// maxDepth bounds the sub-workflow tree
+const maxDepth = 10
+
+// ErrWorkflowNotFound is returned by Generate for unknown workflows
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// Document is the documentation of a workflow and its sub-workflows
+type Document struct {
+	ID           uuid.UUID   `json:"id"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description,omitempty"`
+	Version      string      `json:"version,omitempty"`
+	Active       bool        `json:"active"`
+	Tags         []string    `json:"tags,omitempty"`
+	CycleTime    string      `json:"cycle_time,omitempty"`
+	Inputs       []Input     `json:"inputs,omitempty"`
+	Outputs      []Output    `json:"outputs,omitempty"`
+	Variables    []Variable  `json:"variables,omitempty"`
+	Steps        []Step      `json:"steps"`
+	SubWorkflows []*TreeNode `json:"sub_workflows,omitempty"` // Called by workflow steps, recursively
+	Devices      []Device    `json:"devices"`                 // Used by this workflow and its sub-workflows
+}
+
+type Input struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Output struct {
+	Name string `json:"name"`
+	Step string `json:"step"`
+	Path string `json:"path,omitempty"`
+}
+
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Step is a step of the documented workflow
+type Step struct {
+	Number     string   `json:"number"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Target     string   `json:"target,omitempty"` // Device or sub-workflow
+	Operation  string   `json:"operation,omitempty"`
+	Parameters string   `json:"parameters,omitempty"` // key=value, sorted
+	Condition  string   `json:"condition,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"`
+	OnError    string   `json:"on_error"`
+	Template   string   `json:"template,omitempty"`
+	PostWrites []string `json:"post_writes,omitempty"` // "success: device.register = value"
+}
+
+// TreeNode is a sub-workflow called by a step
+type TreeNode struct {
+	Step     string      `json:"step"` // Calling step number
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Error    string      `json:"error,omitempty"` // E.g. not found or recursive
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// Device is a device referenced by steps or post-actions
+type Device struct {
+	Name       string   `json:"name"`
+	Exists     bool     `json:"exists"`
+	Enabled    bool     `json:"enabled"`
+	Operations []string `json:"operations"`
+	Steps      []string `json:"steps"` // "workflow name / step number"
+}
+
+// Generate builds the documentation of a stored workflow
+func Generate(ctx context.Context, store *storage.PostgresClient, workflowID uuid.UUID) (*Document, error) {
+	workflow, _, err := store.LoadWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWorkflowNotFound, err)
+	}
+	wf, templates, err := parse(ctx, store, workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		ID:          workflowID,
+		Name:        workflow.WorkflowName,
+		Description: wf.Description,
+		Version:     wf.Version,
+		Active:      workflow.Active,
+		Tags:        wf.Tags,
+	}
+	if wf.Name != "" {
+		doc.Name = wf.Name
+	}
+	if wf.CycleTime.Duration > 0 {
+		doc.CycleTime = wf.CycleTime.String()
+	}
+	for _, in := range wf.Inputs {
+		input := Input{Name: in.Name, Type: string(in.Type), Required: in.Required, Description: in.Description}
+		if in.Default != nil {
+			input.Default = format(in.Default)
+		}
+		doc.Inputs = append(doc.Inputs, input)
+	}
+	for _, name := range sortedKeys(wf.Outputs) {
+		ref := wf.Outputs[name]
+		doc.Outputs = append(doc.Outputs, Output{Name: name, Step: ref.Step, Path: ref.Path})
+	}
+	for _, name := range sortedKeys(wf.Variables) {
+		doc.Variables = append(doc.Variables, Variable{Name: name, Value: wf.Variables[name]})
+	}
+	for _, step := range wf.Steps {
+		doc.Steps = append(doc.Steps, describeStep(step, templates[step.Number]))
+	}
+
+	devices := map[string]*Device{}
+	collectDevices(devices, doc.Name, wf)
+
+	visited := []uuid.UUID{workflowID}
+	doc.SubWorkflows = subWorkflows(ctx, store, wf, visited, devices)
+
+	for _, name := range sortedKeys(devices) {
+		device := devices[name]
+		device.Exists, device.Enabled, err = store.DeviceExistsEnabledByName(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		doc.Devices = append(doc.Devices, *device)
+	}
+	return doc, nil
+}
+
+// parse parses a stored definition and expands its step templates. The
+// returned map holds the template names by step number.
+func parse(ctx context.Context, store *storage.PostgresClient, workflow *storage.Workflow) (*definition.Workflow, map[string]string, error) {
+	wf, err := definition.ParseWorkflow(workflow.Definition)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+
+	templates := map[string]string{}
+	for _, step := range wf.Steps {
+		if step.Template != "" {
+			templates[step.Number] = step.Template
+		}
+	}
+	// Steps whose template can't be expanded are documented as written
+	wf.ExpandTemplates(ctx, store.StepTemplateDefinition)
+	return wf, templates, nil
+}
+
+// subWorkflows builds the tree of the sub-workflows called by wf
+func subWorkflows(ctx context.Context, store *storage.PostgresClient, wf *definition.Workflow, visited []uuid.UUID, devices map[string]*Device) []*TreeNode {
+	var nodes []*TreeNode
+	for _, step := range wf.Steps {
+		if step.Type != definition.StepTypeWorkflow {
+			continue
+		}
+		node := &TreeNode{Step: step.Number, ID: step.WorkflowID}
+		nodes = append(nodes, node)
+
+		id, err := uuid.Parse(step.WorkflowID)
+		if err != nil {
+			node.Error = "invalid workflow ID"
+			continue
+		}
+		if slices.Contains(visited, id) {
+			node.Error = "recursive call"
+			continue
+		}
+		if len(visited) > maxDepth {
+			node.Error = fmt.Sprintf("nested deeper than %d levels", maxDepth)
+			continue
+		}
+
+		workflow, _, err := store.LoadWorkflow(ctx, id)
+		if err != nil {
+			node.Error = "workflow not found"
+			continue
+		}
+		sub, _, err := parse(ctx, store, workflow)
+		if err != nil {
+			node.Error = err.Error()
+			continue
+		}
+		node.Name = workflow.WorkflowName
+		if sub.Name != "" {
+			node.Name = sub.Name
+		}
+
+		collectDevices(devices, node.Name, sub)
+		node.Children = subWorkflows(ctx, store, sub, append(visited[:len(visited):len(visited)], id), devices)
+	}
+	return nodes
+}
+
+// collectDevices adds the devices used by the steps of wf
+func collectDevices(devices map[string]*Device, workflowName string, wf *definition.Workflow) {
+	use := func(name, operation, step string) {
+		if name == "" || strings.HasPrefix(name, "$") {
+			return // Resolved at runtime
+		}
+		device, ok := devices[name]
+		if !ok {
+			device = &Device{Name: name, Operations: []string{}, Steps: []string{}}
+			devices[name] = device
+		}
+		if operation != "" && !slices.Contains(device.Operations, operation) {
+			device.Operations = append(device.Operations, operation)
+		}
+		ref := workflowName + " / " + step
+		if !slices.Contains(device.Steps, ref) {
+			device.Steps = append(device.Steps, ref)
+		}
+	}
+
+	for _, step := range wf.Steps {
+		if step.Type == definition.StepTypeDevice {
+			use(step.DeviceID, step.Operation, step.Number)
+		}
+		for _, ref := range step.Samples {
+			use(ref.DeviceID, "sample", step.Number)
+		}
+		for _, action := range slices.Concat(step.OnSuccessWrite, step.OnFailureWrite) {
+			use(action.DeviceID, "write", step.Number)
+		}
+	}
+}
+
+func describeStep(step definition.Step, template string) Step {
+	doc := Step{
+		Number:    step.Number,
+		Name:      step.Name,
+		Type:      string(step.Type),
+		Operation: step.Operation,
+		Condition: step.Condition,
+		OnError:   string(step.OnError),
+		Template:  template,
+	}
+	if doc.OnError == "" {
+		doc.OnError = string(definition.ErrorStrategyFail)
+	}
+	if step.Timeout.Duration > 0 {
+		doc.Timeout = step.Timeout.String()
+	}
+
+	switch step.Type {
+	case definition.StepTypeDevice:
+		doc.Target = step.DeviceID
+		doc.Parameters = formatMap(step.Parameters)
+	case definition.StepTypeWorkflow:
+		doc.Target = step.WorkflowID
+		doc.Parameters = formatMap(step.InputMapping)
+	case definition.StepTypeSample:
+		samples := make(map[string]any, len(step.Samples))
+		for _, ref := range step.Samples {
+			samples[ref.Key()] = ref.DeviceID + "." + ref.Register
+		}
+		doc.Parameters = formatMap(samples)
+	default:
+		doc.Parameters = formatMap(step.Parameters)
+	}
+
+	for _, action := range step.OnSuccessWrite {
+		doc.PostWrites = append(doc.PostWrites, fmt.Sprintf("success: %s.%s = %s", action.DeviceID, action.Register, format(action.Value)))
+	}
+	for _, action := range step.OnFailureWrite {
+		doc.PostWrites = append(doc.PostWrites, fmt.Sprintf("failure: %s.%s = %s", action.DeviceID, action.Register, format(action.Value)))
+	}
+	return doc
+}
+
+// formatMap formats parameters as sorted key=value pairs
+func formatMap(m map[string]any) string {
+	pairs := make([]string, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		pairs = append(pairs, key+"="+format(m[key]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// format formats a value as compact JSON, strings without quotes
+func format(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package docs
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// cell escapes a value for a Markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+var funcs = map[string]any{
+	"cell": cell,
+	"join": strings.Join,
+	"indent": func(depth int) string {
+		return strings.Repeat("  ", depth)
+	},
+	"inc": func(n int) int { return n + 1 },
+}
+
+const markdownTemplate = `# {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+| | |
+|---|---|
+| ID | {{.ID}} |
+{{- if .Version}}
+| Version | {{cell .Version}} |{{end}}
+| Active | {{if .Active}}yes{{else}}no{{end}} |
+{{- if .CycleTime}}
+| Cycle time | {{.CycleTime}} |{{end}}
+{{- if .Tags}}
+| Tags | {{cell (join .Tags ", ")}} |{{end}}
+{{if .Inputs}}
+## Inputs
+
+| Name | Type | Required | Default | Description |
+|---|---|---|---|---|
+{{range .Inputs}}| {{cell .Name}} | {{.Type}} | {{if .Required}}yes{{else}}no{{end}} | {{cell .Default}} | {{cell .Description}} |
+{{end}}{{end}}
+## Steps
+
+| No. | Name | Type | Device / Workflow | Operation | Parameters | Condition | Timeout | On error |
+|---|---|---|---|---|---|---|---|---|
+{{range .Steps}}| {{cell .Number}} | {{cell .Name}}{{if .Template}} (template {{cell .Template}}){{end}} | {{.Type}} | {{cell .Target}} | {{cell .Operation}} | {{cell .Parameters}} | {{cell .Condition}} | {{.Timeout}} | {{.OnError}} |
+{{end}}
+{{- range .Steps}}{{if .PostWrites}}
+Step {{.Number}} writes afterwards:
+{{range .PostWrites}}
+- {{.}}{{end}}
+{{end}}{{end}}
+{{- if .Outputs}}
+## Outputs
+
+| Name | Step | Path |
+|---|---|---|
+{{range .Outputs}}| {{cell .Name}} | {{cell .Step}} | {{cell .Path}} |
+{{end}}{{end}}
+{{- if .Variables}}
+## Variables
+
+| Name | Value |
+|---|---|
+{{range .Variables}}| {{cell .Name}} | {{cell .Value}} |
+{{end}}{{end}}
+{{- if .SubWorkflows}}
+## Sub-workflows
+{{template "tree" (treeArgs .SubWorkflows 0)}}
+{{end}}
+## Devices
+{{if .Devices}}
+| Device | Status | Operations | Used by |
+|---|---|---|---|
+{{range .Devices}}| {{cell .Name}} | {{if not .Exists}}missing{{else if not .Enabled}}disabled{{else}}ok{{end}} | {{cell (join .Operations ", ")}} | {{cell (join .Steps ", ")}} |
+{{end}}{{else}}
+No devices are referenced.
+{{end}}`
+
+const markdownTree = `{{define "tree"}}{{$depth := .Depth}}{{range .Nodes}}
+{{indent $depth}}- Step {{.Step}}: {{if .Name}}{{.Name}} ({{.ID}}){{else}}{{.ID}}{{end}}{{if .Error}} - {{.Error}}{{end}}{{if .Children}}{{template "tree" (treeArgs .Children (inc $depth))}}{{end}}{{end}}{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+.missing { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<table>
+<tr><th>ID</th><td>{{.ID}}</td></tr>
+{{if .Version}}<tr><th>Version</th><td>{{.Version}}</td></tr>{{end}}
+<tr><th>Active</th><td>{{if .Active}}yes{{else}}no{{end}}</td></tr>
+{{if .CycleTime}}<tr><th>Cycle time</th><td>{{.CycleTime}}</td></tr>{{end}}
+{{if .Tags}}<tr><th>Tags</th><td>{{join .Tags ", "}}</td></tr>{{end}}
+</table>
+{{if .Inputs}}<h2>Inputs</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Required</th><th>Default</th><th>Description</th></tr>
+{{range .Inputs}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{if .Required}}yes{{else}}no{{end}}</td><td>{{.Default}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Steps</h2>
+<table>
+<tr><th>No.</th><th>Name</th><th>Type</th><th>Device / Workflow</th><th>Operation</th><th>Parameters</th><th>Condition</th><th>Timeout</th><th>On error</th><th>Writes afterwards</th></tr>
+{{range .Steps}}<tr><td>{{.Number}}</td><td>{{.Name}}{{if .Template}} (template {{.Template}}){{end}}</td><td>{{.Type}}</td><td>{{.Target}}</td><td>{{.Operation}}</td><td>{{.Parameters}}</td><td>{{.Condition}}</td><td>{{.Timeout}}</td><td>{{.OnError}}</td><td>{{range .PostWrites}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+{{if .Outputs}}<h2>Outputs</h2>
+<table>
+<tr><th>Name</th><th>Step</th><th>Path</th></tr>
+{{range .Outputs}}<tr><td>{{.Name}}</td><td>{{.Step}}</td><td>{{.Path}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Variables}}<h2>Variables</h2>
+<table>
+<tr><th>Name</th><th>Value</th></tr>
+{{range .Variables}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+{{if .SubWorkflows}}<h2>Sub-workflows</h2>
+{{template "tree" .SubWorkflows}}{{end}}
+<h2>Devices</h2>
+{{if .Devices}}<table>
+<tr><th>Device</th><th>Status</th><th>Operations</th><th>Used by</th></tr>
+{{range .Devices}}<tr><td>{{.Name}}</td><td{{if or (not .Exists) (not .Enabled)}} class="missing"{{end}}>{{if not .Exists}}missing{{else if not .Enabled}}disabled{{else}}ok{{end}}</td><td>{{join .Operations ", "}}</td><td>{{join .Steps ", "}}</td></tr>
+{{end}}</table>{{else}}<p>No devices are referenced.</p>{{end}}
+</body>
+</html>
+`
+
+const htmlTree = `{{define "tree"}}<ul>
+{{range .}}<li>Step {{.Step}}: {{if .Name}}{{.Name}} ({{.ID}}){{else}}{{.ID}}{{end}}{{if .Error}} <span class="missing">{{.Error}}</span>{{end}}{{if .Children}}{{template "tree" .Children}}{{end}}</li>
+{{end}}</ul>{{end}}`
+
+type treeArgs struct {
+	Nodes []*TreeNode
+	Depth int
+}
+
+var (
+	markdown = template.Must(template.New("markdown").Funcs(funcs).Funcs(template.FuncMap{
+		"treeArgs": func(nodes []*TreeNode, depth int) treeArgs { return treeArgs{nodes, depth} },
+	}).Parse(markdownTree + markdownTemplate))
+	html = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlTree + htmlTemplate))
+)
+
+// Markdown renders the document as Markdown
+func (d *Document) Markdown() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := markdown.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// HTML renders the document as a standalone HTML page
+func (d *Document) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := html.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// drainCheckInterval is how often draining checks the running executions
+const drainCheckInterval = 200 * time.Millisecond
+
+// DefaultDrainTimeout bounds draining without a timeout
+const DefaultDrainTimeout = 20 * time.Second
+
+// ErrWorkflowNotFound is returned when activating an unknown workflow
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// Activation phases
+const (
+	ActivationDraining  = "draining"  // Waiting for executions of the previous workflow
+	ActivationActivated = "activated" // Active flag switched
+	ActivationBlocked   = "blocked"   // Drain timeout elapsed, previous workflow stays active
+)
+
+// ActivationOptions control switching the active workflow
+type ActivationOptions struct {
+	Drain   bool          // Wait for running executions of the previously active workflow
+	Timeout time.Duration // Longest wait while draining, 0 = DefaultDrainTimeout
+	Force   bool          // Switch when the timeout elapses; the executions keep running
+	Actor   string
+}
+
+// ActivationEvent reports a phase of a workflow activation
+type ActivationEvent struct {
+	Phase              string      `json:"phase"`
+	WorkflowID         uuid.UUID   `json:"workflow_id"`
+	PreviousWorkflowID *uuid.UUID  `json:"previous_workflow_id,omitempty"`
+	Executions         []uuid.UUID `json:"executions,omitempty"` // Running executions of the previous workflow
+	Forced             bool        `json:"forced,omitempty"`
+	WaitedMs           int64       `json:"waited_ms"`
+	Actor              string      `json:"actor,omitempty"`
+}
+
+// ActivationBlockedError is returned when executions of the previously
+// active workflow did not finish within the drain timeout
+type ActivationBlockedError struct {
+	ActivationEvent
+}
+
+func (e *ActivationBlockedError) Error() string {
+	return fmt.Sprintf("activation blocked by %d running execution(s) of workflow %s", len(e.Executions), e.PreviousWorkflowID)
+}
+
+// SetActivationHandler registers a handler for the phases of workflow
+// activations, e.g. to notify clients
+func (e *Engine) SetActivationHandler(handler func(ActivationEvent)) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	e.activationHandler = handler
+}
+
+// RunningExecutions returns the IDs of the running executions of a workflow
+func (e *Engine) RunningExecutions(workflowID uuid.UUID) []uuid.UUID {
+	e.runningMu.RLock()
+	defer e.runningMu.RUnlock()
+
+	var ids []uuid.UUID
+	for executionID, id := range e.runningWorkflows {
+		if id == workflowID {
+			ids = append(ids, executionID)
+		}
+	}
+	return ids
+}
+
+// ActivateWorkflow makes a workflow the active one. With Drain it first
+// waits until the executions of the previously active workflow finished.
+// Activations are serialized.
+func (e *Engine) ActivateWorkflow(ctx context.Context, workflowID uuid.UUID, opts ActivationOptions) (*ActivationEvent, error) {
+	e.activationMu.Lock()
+	defer e.activationMu.Unlock()
+
+	exists, err := e.storage.WorkflowExists(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, workflowID)
+	}
+
+	result := &ActivationEvent{WorkflowID: workflowID, Actor: opts.Actor}
+	if previous, _, err := e.storage.GetActiveWorkflow(ctx); err == nil && previous.ID != workflowID {
+		result.PreviousWorkflowID = &previous.ID
+	}
+
+	if opts.Drain && result.PreviousWorkflowID != nil {
+		if err := e.drain(ctx, result, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.storage.ActivateWorkflow(ctx, workflowID); err != nil {
+		return nil, fmt.Errorf("failed to activate workflow: %w", err)
+	}
+
+	result.Phase = ActivationActivated
+	e.notifyActivation(*result)
+	e.logger.Info("Workflow activated",
+		zap.String("workflow_id", workflowID.String()),
+		zap.Int("running_previous", len(result.Executions)),
+		zap.Bool("forced", result.Forced),
+		zap.Int64("waited_ms", result.WaitedMs))
+	return result, nil
+}
+
+// drain waits until the previous workflow has no running executions or the
+// timeout elapsed. Without Force a timeout blocks the activation.
+func (e *Engine) drain(ctx context.Context, result *ActivationEvent, opts ActivationOptions) error {
+	running := e.RunningExecutions(*result.PreviousWorkflowID)
+	if len(running) == 0 {
+		return nil
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	started := time.Now()
+	result.Phase = ActivationDraining
+	result.Executions = running
+	e.notifyActivation(*result)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	check := time.NewTicker(drainCheckInterval)
+	defer check.Stop()
+
+	for {
+		select {
+		case <-check.C:
+			result.Executions = e.RunningExecutions(*result.PreviousWorkflowID)
+			if len(result.Executions) == 0 {
+				result.WaitedMs = time.Since(started).Milliseconds()
+				return nil
+			}
+		case <-deadline.C:
+			result.Executions = e.RunningExecutions(*result.PreviousWorkflowID)
+			result.WaitedMs = time.Since(started).Milliseconds()
+			if len(result.Executions) == 0 {
+				return nil
+			}
+			if opts.Force {
+				result.Forced = true
+				return nil
+			}
+			result.Phase = ActivationBlocked
+			e.notifyActivation(*result)
+			return &ActivationBlockedError{ActivationEvent: *result}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (e *Engine) notifyActivation(event ActivationEvent) {
+	e.runningMu.RLock()
+	handler := e.activationHandler
+	e.runningMu.RUnlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOverloaded is returned while new executions are rejected to protect
+// the system, e.g. because memory is running out
+var ErrOverloaded = errors.New("new executions are rejected")
+
+// SetAdmission installs a check run before every new execution. A non-nil
+// result rejects the execution with ErrOverloaded; running executions
+// continue.
+func (e *Engine) SetAdmission(check func() error) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	e.admission = check
+}
+
+// admit runs the admission check
+func (e *Engine) admit() error {
+	e.runningMu.RLock()
+	check := e.admission
+	e.runningMu.RUnlock()
+
+	if check == nil {
+		return nil
+	}
+	if err := check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrOverloaded, err)
+	}
+	return nil
+}
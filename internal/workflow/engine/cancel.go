@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CancelSource distinguishes who or what cancelled an execution
+type CancelSource string
+
+const (
+	CancelSourceOperator CancelSource = "operator" // Explicit cancel via API
+	CancelSourceMachine  CancelSource = "machine"  // Machine stop command
+	CancelSourceSystem   CancelSource = "system"   // Shutdown, emergency stop
+)
+
+// CancelReason describes why an execution was cancelled
+type CancelReason struct {
+	Source CancelSource `json:"source"`
+	Reason string       `json:"reason,omitempty"`
+	Actor  string       `json:"actor,omitempty"`
+}
+
+// CancellationError is the context cause of a cancelled execution
+type CancellationError struct {
+	CancelReason
+}
+
+func (e *CancellationError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("execution cancelled by %s", e.Source)
+	}
+	return fmt.Sprintf("execution cancelled by %s: %s", e.Source, e.Reason)
+}
+
+// cancelReasonFromContext returns the reason an execution context was
+// cancelled, falling back to a system cancel if no reason was attached
+func cancelReasonFromContext(ctx context.Context) CancelReason {
+	var cancelErr *CancellationError
+	if errors.As(context.Cause(ctx), &cancelErr) {
+		return cancelErr.CancelReason
+	}
+	return CancelReason{Source: CancelSourceSystem}
+}
+
+// CancelExecution stops a running workflow execution
+func (e *Engine) CancelExecution(ctx context.Context, executionID uuid.UUID, reason CancelReason) error {
+	e.runningMu.RLock()
+	cancel, exists := e.runningContexts[executionID]
+	e.runningMu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("execution not found or not running: %s", executionID)
+	}
+
+	if reason.Source == "" {
+		reason.Source = CancelSourceOperator
+	}
+	cancel(&CancellationError{CancelReason: reason})
+	return nil
+}
+
+// CancelAll stops every running execution with the same reason
+func (e *Engine) CancelAll(reason CancelReason) int {
+	e.runningMu.RLock()
+	cancels := make([]context.CancelCauseFunc, 0, len(e.runningContexts))
+	for _, cancel := range e.runningContexts {
+		cancels = append(cancels, cancel)
+	}
+	e.runningMu.RUnlock()
+
+	for _, cancel := range cancels {
+		cancel(&CancellationError{CancelReason: reason})
+	}
+	return len(cancels)
+}
+
+// WaitStopped waits until no execution is running, e.g. after CancelAll. It
+// reports false if executions are still running when ctx is done.
+func (e *Engine) WaitStopped(ctx context.Context) bool {
+	check := time.NewTicker(drainCheckInterval)
+	defer check.Stop()
+
+	for {
+		e.runningMu.RLock()
+		running := len(e.runningContexts)
+		e.runningMu.RUnlock()
+		if running == 0 {
+			return true
+		}
+
+		select {
+		case <-check.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// finishCancelled records a cancelled execution with its reason, publishes
+// the cancellation event and drives outputs to their safe states
+func (e *Engine) finishCancelled(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow, stepName string) {
+	reason := cancelReasonFromContext(ctx)
+
+	// The execution context is already cancelled; persist regardless
+	ctx = context.WithoutCancel(ctx)
+
+	now := time.Now()
+	exec.Status = storage.StatusCancelled
+	exec.CompletedAt = &now
+	exec.CancelSource = string(reason.Source)
+	exec.CancelReason = reason.Reason
+	exec.CancelledBy = reason.Actor
+
+	e.storage.UpdateExecution(ctx, exec)
+	e.executionLogger(exec.ID).Warn("Workflow execution cancelled",
+		zap.String("source", string(reason.Source)),
+		zap.String("reason", reason.Reason),
+		zap.String("actor", reason.Actor),
+		zap.String("step_name", stepName))
+	e.publishEvent(ctx, exec.ID, "execution.cancelled", map[string]any{
+		"source":    reason.Source,
+		"reason":    reason.Reason,
+		"actor":     reason.Actor,
+		"step_name": stepName,
+	})
+
+	e.applySafeStates(exec, workflowDef, "execution_cancelled")
+
+	if e.wsHub != nil {
+		msg := "Workflow execution cancelled by " + string(reason.Source)
+		if reason.Reason != "" {
+			msg += ": " + reason.Reason
+		}
+		metadata := map[string]interface{}{
+			"cancel_source": reason.Source,
+			"cancel_reason": reason.Reason,
+			"cancelled_by":  reason.Actor,
+		}
+		if !exec.Correlation.IsZero() {
+			metadata["correlation"] = exec.Correlation
+		}
+		e.wsHub.Broadcast(websocket.NewMessage(websocket.MessageTypeWorkflowCancelled, websocket.WorkflowExecutionData{
+			ExecutionID: exec.ID.String(),
+			WorkflowID:  exec.WorkflowID.String(),
+			StepName:    stepName,
+			Status:      string(storage.StatusCancelled),
+			Message:     msg,
+			Metadata:    metadata,
+		}))
+	}
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// checkpointTimeout bounds saving a checkpoint
+	checkpointTimeout = 5 * time.Second
+
+	// recoverLimit bounds the interrupted executions handled per status at startup
+	recoverLimit = 1000
+)
+
+// SetCheckpoints configures checkpointing of running executions
+func (e *Engine) SetCheckpoints(cfg config.CheckpointsConfig) {
+	e.checkpoints = cfg
+}
+
+// saveCheckpoint stores the state of an execution before its next top-level step
+func (e *Engine) saveCheckpoint(exec *storage.WorkflowExecution, workflowDef *definition.Workflow, next int, results map[string]map[string]any, jumps definition.Jumps, logger *zap.Logger) {
+	cp := &storage.ExecutionCheckpoint{
+		ExecutionID: exec.ID,
+		StepIndex:   next,
+		Variables:   workflowDef.Variables,
+		Results:     results,
+		Jumps:       jumps,
+	}
+	if next < len(workflowDef.Steps) {
+		cp.StepName = workflowDef.Steps[next].Name
+	}
+	e.runningMu.RLock()
+	if tracker, ok := e.executionTrackers[exec.ID]; ok {
+		cp.HierarchicalStepID = tracker.GetHierarchicalStepID()
+	}
+	e.runningMu.RUnlock()
+
+	// A cancelled execution still records how far it got
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	if err := e.storage.SaveExecutionCheckpoint(ctx, cp); err != nil {
+		logger.Warn("Failed to save checkpoint", zap.Error(err))
+		return
+	}
+	logger.Debug("Checkpoint saved",
+		zap.Int("sequence", cp.Sequence),
+		zap.Int("next_step", next))
+}
+
+// RecoverInterrupted handles executions left pending or running by a crash.
+// With checkpoints.resume_on_startup, executions with a checkpoint continue
+// at its step; all others are marked failed and can be retried.
+func (e *Engine) RecoverInterrupted(ctx context.Context) (resumed, failed int, err error) {
+	var interrupted []storage.WorkflowExecution
+	for _, status := range []storage.ExecutionStatus{storage.StatusRunning, storage.StatusPending} {
+		list, err := e.storage.ListExecutions(ctx, storage.ExecutionFilter{Status: status, Limit: recoverLimit})
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to list interrupted executions: %w", err)
+		}
+		interrupted = append(interrupted, list...)
+	}
+
+	for _, exec := range interrupted {
+		e.runningMu.RLock()
+		_, running := e.runningContexts[exec.ID]
+		e.runningMu.RUnlock()
+		if running {
+			continue // Still running in this process, e.g. after an in-place restart
+		}
+
+		if e.checkpoints.Enabled && e.checkpoints.ResumeOnStartup {
+			resumeErr := e.resumeExecution(ctx, exec.ID)
+			if resumeErr == nil {
+				resumed++
+				continue
+			}
+			e.logger.Warn("Failed to resume interrupted execution",
+				zap.String("execution_id", exec.ID.String()),
+				zap.Error(resumeErr))
+		}
+
+		if err := e.failInterrupted(ctx, exec.ID); err != nil {
+			e.logger.Error("Failed to mark interrupted execution failed",
+				zap.String("execution_id", exec.ID.String()),
+				zap.Error(err))
+			continue
+		}
+		failed++
+	}
+	return resumed, failed, nil
+}
+
+// resumeExecution continues an interrupted execution at its checkpoint
+func (e *Engine) resumeExecution(ctx context.Context, executionID uuid.UUID) error {
+	cp, err := e.storage.GetExecutionCheckpoint(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	if cp == nil {
+		return fmt.Errorf("no checkpoint")
+	}
+
+	exec, err := e.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return err
+	}
+
+	workflow, _, err := e.storage.LoadWorkflow(ctx, exec.WorkflowID)
+	if err != nil {
+		return fmt.Errorf("failed to load workflow: %w", err)
+	}
+	workflowDef, err := definition.ParseWorkflow(workflow.Definition)
+	if err != nil {
+		return fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	if errs := workflowDef.ExpandTemplates(ctx, e.storage.StepTemplateDefinition); len(errs) > 0 {
+		return fmt.Errorf("failed to expand step templates: %w", errs[0])
+	}
+	if cp.StepIndex > len(workflowDef.Steps) {
+		return fmt.Errorf("checkpoint step %d is beyond the workflow's %d steps", cp.StepIndex, len(workflowDef.Steps))
+	}
+
+	input := make(map[string]any)
+	if len(exec.Input) > 0 {
+		if err := json.Unmarshal(exec.Input, &input); err != nil {
+			return fmt.Errorf("failed to decode input: %w", err)
+		}
+	}
+
+	results := cp.Results
+	if results == nil {
+		results = make(map[string]map[string]any)
+	}
+
+	// Loop limits count on from the jumps taken before the interruption
+	jumps := definition.Jumps(cp.Jumps)
+	if jumps == nil {
+		jumps = definition.Jumps{}
+	}
+
+	exec.StartStep = cp.StepIndex
+	if err := e.storage.MarkCheckpointResumed(ctx, exec.ID); err != nil {
+		return err
+	}
+	e.launchExecution(exec, workflowDef, input, results, jumps)
+
+	e.publishEvent(ctx, exec.ID, "execution.resumed", map[string]any{
+		"checkpoint_sequence": cp.Sequence,
+		"step_index":          cp.StepIndex,
+		"checkpoint_at":       cp.CreatedAt,
+	})
+	e.logger.Info("Resumed interrupted execution from checkpoint",
+		zap.String("execution_id", exec.ID.String()),
+		zap.Int("sequence", cp.Sequence),
+		zap.Int("step_index", cp.StepIndex))
+	return nil
+}
+
+// failInterrupted marks an execution that is not resumed as failed
+func (e *Engine) failInterrupted(ctx context.Context, executionID uuid.UUID) error {
+	exec, err := e.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	exec.Status = storage.StatusFailed
+	exec.Error = "interrupted: server stopped while the execution was running"
+	exec.CompletedAt = &now
+	if err := e.storage.UpdateExecution(ctx, exec); err != nil {
+		return err
+	}
+	e.publishEvent(ctx, exec.ID, "execution.failed", map[string]any{"error": exec.Error})
+	return nil
+}
@@ -0,0 +1,313 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+)
+
+// ErrDifferentWorkflows is returned when comparing runs of different workflows
+var ErrDifferentWorkflows = errors.New("executions belong to different workflows")
+
+// ExecutionSummary describes one side of a comparison
+type ExecutionSummary struct {
+	ID          uuid.UUID               `json:"id"`
+	Status      storage.ExecutionStatus `json:"status"`
+	Error       string                  `json:"error,omitempty"`
+	StartStep   int                     `json:"start_step,omitempty"`
+	RetryOf     *uuid.UUID              `json:"retry_of,omitempty"`
+	StartedAt   time.Time               `json:"started_at"`
+	CompletedAt *time.Time              `json:"completed_at,omitempty"`
+	DurationMs  *int64                  `json:"duration_ms,omitempty"`
+}
+
+// ValueDiff is a value that differs between the runs. A or B is null if
+// the path exists on one side only.
+type ValueDiff struct {
+	Path string `json:"path"` // e.g. "value" or "result.positions.2"
+	A    any    `json:"a"`
+	B    any    `json:"b"`
+}
+
+// StepComparison compares the runs of a step, matched by hierarchical step ID
+type StepComparison struct {
+	HierarchicalStepID string                  `json:"hierarchical_step_id"`
+	StepName           string                  `json:"step_name"`
+	Depth              int                     `json:"depth"`
+	InA                bool                    `json:"in_a"`
+	InB                bool                    `json:"in_b"`
+	StatusA            storage.ExecutionStatus `json:"status_a,omitempty"`
+	StatusB            storage.ExecutionStatus `json:"status_b,omitempty"`
+	ErrorA             string                  `json:"error_a,omitempty"`
+	ErrorB             string                  `json:"error_b,omitempty"`
+	DurationAMs        *int64                  `json:"duration_a_ms,omitempty"`
+	DurationBMs        *int64                  `json:"duration_b_ms,omitempty"`
+	DurationDeltaMs    *int64                  `json:"duration_delta_ms,omitempty"` // B - A
+	Input              []ValueDiff             `json:"input,omitempty"`             // Differing parameters
+	Output             []ValueDiff             `json:"output,omitempty"`            // Differing values read or written
+	Differs            bool                    `json:"differs"`                     // Path, status, error or values differ
+}
+
+// ComparisonSummary counts the compared steps
+type ComparisonSummary struct {
+	Steps      int  `json:"steps"`
+	Differing  int  `json:"differing"`
+	OnlyInA    int  `json:"only_in_a"`
+	OnlyInB    int  `json:"only_in_b"`
+	SameResult bool `json:"same_result"` // Same status and error
+}
+
+// ExecutionComparison is a structured diff of two runs of a workflow
+type ExecutionComparison struct {
+	WorkflowID      uuid.UUID         `json:"workflow_id"`
+	A               ExecutionSummary  `json:"a"`
+	B               ExecutionSummary  `json:"b"`
+	DurationDeltaMs *int64            `json:"duration_delta_ms,omitempty"` // B - A
+	Input           []ValueDiff       `json:"input,omitempty"`
+	Output          []ValueDiff       `json:"output,omitempty"`
+	OnlyInA         []string          `json:"only_in_a"` // Steps run by A only, e.g. another sub-workflow path
+	OnlyInB         []string          `json:"only_in_b"`
+	Summary         ComparisonSummary `json:"summary"`
+	Steps           []StepComparison  `json:"steps"` // In step order of A, then steps of B only
+}
+
+// CompareExecutions diffs two executions of the same workflow
+func (e *Engine) CompareExecutions(ctx context.Context, a, b uuid.UUID) (*ExecutionComparison, error) {
+	execA, err := e.storage.GetExecution(ctx, a)
+	if err != nil {
+		return nil, fmt.Errorf("execution %s: %w", a, err)
+	}
+	execB, err := e.storage.GetExecution(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("execution %s: %w", b, err)
+	}
+	if execA.WorkflowID != execB.WorkflowID {
+		return nil, fmt.Errorf("%w: %s and %s", ErrDifferentWorkflows, execA.WorkflowID, execB.WorkflowID)
+	}
+
+	stepsA, err := e.storage.GetExecutionSteps(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	stepsB, err := e.storage.GetExecutionSteps(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &ExecutionComparison{
+		WorkflowID: execA.WorkflowID,
+		A:          summarizeExecution(execA),
+		B:          summarizeExecution(execB),
+		Input:      diffJSON(execA.Input, execB.Input),
+		Output:     diffJSON(execA.Output, execB.Output),
+		OnlyInA:    make([]string, 0),
+		OnlyInB:    make([]string, 0),
+		Steps:      make([]StepComparison, 0, len(stepsA)),
+	}
+	cmp.DurationDeltaMs = deltaMs(cmp.A.DurationMs, cmp.B.DurationMs)
+
+	indexB := make(map[string]*storage.ExecutionStep, len(stepsB))
+	for i := range stepsB {
+		indexB[stepKey(&stepsB[i])] = &stepsB[i] // A step run twice keeps its last run
+	}
+	seen := make(map[string]bool, len(stepsA))
+
+	for i := range stepsA {
+		key := stepKey(&stepsA[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sa := lastRun(stepsA, key)
+		cmp.Steps = append(cmp.Steps, compareSteps(key, sa, indexB[key]))
+	}
+	for i := range stepsB {
+		key := stepKey(&stepsB[i])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		cmp.Steps = append(cmp.Steps, compareSteps(key, nil, indexB[key]))
+	}
+
+	for _, s := range cmp.Steps {
+		cmp.Summary.Steps++
+		if s.Differs {
+			cmp.Summary.Differing++
+		}
+		switch {
+		case !s.InB:
+			cmp.Summary.OnlyInA++
+			cmp.OnlyInA = append(cmp.OnlyInA, s.HierarchicalStepID)
+		case !s.InA:
+			cmp.Summary.OnlyInB++
+			cmp.OnlyInB = append(cmp.OnlyInB, s.HierarchicalStepID)
+		}
+	}
+	cmp.Summary.SameResult = execA.Status == execB.Status && execA.Error == execB.Error
+	return cmp, nil
+}
+
+func summarizeExecution(exec *storage.WorkflowExecution) ExecutionSummary {
+	summary := ExecutionSummary{
+		ID:          exec.ID,
+		Status:      exec.Status,
+		Error:       exec.Error,
+		StartStep:   exec.StartStep,
+		RetryOf:     exec.RetryOf,
+		StartedAt:   exec.StartedAt,
+		CompletedAt: exec.CompletedAt,
+	}
+	if exec.CompletedAt != nil {
+		ms := exec.CompletedAt.Sub(exec.StartedAt).Milliseconds()
+		summary.DurationMs = &ms
+	}
+	return summary
+}
+
+// stepKey matches steps across runs; steps recorded before hierarchical IDs
+// fall back to their index and name
+func stepKey(step *storage.ExecutionStep) string {
+	if step.HierarchicalStepID != "" {
+		return step.HierarchicalStepID
+	}
+	return strconv.Itoa(step.StepIndex) + ":" + step.StepName
+}
+
+func lastRun(steps []storage.ExecutionStep, key string) *storage.ExecutionStep {
+	var last *storage.ExecutionStep
+	for i := range steps {
+		if stepKey(&steps[i]) == key {
+			last = &steps[i]
+		}
+	}
+	return last
+}
+
+func compareSteps(key string, a, b *storage.ExecutionStep) StepComparison {
+	sc := StepComparison{HierarchicalStepID: key, InA: a != nil, InB: b != nil}
+	if named := a; named != nil || b != nil {
+		if named == nil {
+			named = b
+		}
+		sc.StepName = named.StepName
+		sc.Depth = named.Depth
+	}
+
+	var inA, inB, outA, outB json.RawMessage
+	if a != nil {
+		sc.StatusA = a.Status
+		sc.ErrorA = a.Error
+		sc.DurationAMs = stepDurationMs(a)
+		inA, outA = a.Input, a.Output
+	}
+	if b != nil {
+		sc.StatusB = b.Status
+		sc.ErrorB = b.Error
+		sc.DurationBMs = stepDurationMs(b)
+		inB, outB = b.Input, b.Output
+	}
+	sc.DurationDeltaMs = deltaMs(sc.DurationAMs, sc.DurationBMs)
+
+	if a != nil && b != nil {
+		sc.Input = diffJSON(inA, inB)
+		sc.Output = diffJSON(outA, outB)
+	}
+	sc.Differs = a == nil || b == nil ||
+		sc.StatusA != sc.StatusB || sc.ErrorA != sc.ErrorB ||
+		len(sc.Input) > 0 || len(sc.Output) > 0
+	return sc
+}
+
+func stepDurationMs(step *storage.ExecutionStep) *int64 {
+	if step.CompletedAt == nil {
+		return nil
+	}
+	ms := step.CompletedAt.Sub(step.StartedAt).Milliseconds()
+	return &ms
+}
+
+func deltaMs(a, b *int64) *int64 {
+	if a == nil || b == nil {
+		return nil
+	}
+	delta := *b - *a
+	return &delta
+}
+
+// diffJSON returns the leaf values differing between two JSON documents,
+// ordered by path
+func diffJSON(a, b json.RawMessage) []ValueDiff {
+	flatA := make(map[string]any)
+	flatB := make(map[string]any)
+	flattenJSON(a, flatA)
+	flattenJSON(b, flatB)
+
+	paths := make(map[string]bool, len(flatA)+len(flatB))
+	for p := range flatA {
+		paths[p] = true
+	}
+	for p := range flatB {
+		paths[p] = true
+	}
+
+	var diffs []ValueDiff
+	for p := range paths {
+		va, okA := flatA[p]
+		vb, okB := flatB[p]
+		if okA && okB && reflect.DeepEqual(va, vb) {
+			continue
+		}
+		diffs = append(diffs, ValueDiff{Path: p, A: va, B: vb})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+func flattenJSON(raw json.RawMessage, out map[string]any) {
+	if len(raw) == 0 {
+		return
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		out[""] = string(raw)
+		return
+	}
+	flattenValue("", v, out)
+}
+
+func flattenValue(prefix string, v any, out map[string]any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 && prefix != "" {
+			out[prefix] = val
+		}
+		for k, child := range val {
+			flattenValue(join(k), child, out)
+		}
+	case []any:
+		if len(val) == 0 {
+			out[prefix] = val
+		}
+		for i, child := range val {
+			flattenValue(join(strconv.Itoa(i)), child, out)
+		}
+	default:
+		out[prefix] = val
+	}
+}
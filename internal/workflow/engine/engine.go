@@ -3,23 +3,29 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/api/websocket"
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/executor"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/streaming"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // ExecutionTracker maintains call stack and hierarchical step information for a running workflow
 type ExecutionTracker struct {
 	ExecutionID uuid.UUID
 	CallStack   []definition.CallFrame // Stack of (workflow_id, program_name, step_number)
+	StepIndex   int                    // Index of the running step of the root workflow
 	mu          sync.RWMutex
 }
 
@@ -60,6 +66,33 @@ func (et *ExecutionTracker) SetCurrentStep(stepNumber string) {
 	}
 }
 
+// SetRootStep returns to the root workflow, e.g. after a sub-workflow, and
+// sets its current step
+func (et *ExecutionTracker) SetRootStep(index int, stepNumber string) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	if len(et.CallStack) > 0 {
+		et.CallStack = et.CallStack[:1]
+		et.CallStack[0].StepNumber = stepNumber
+	}
+	et.StepIndex = index
+}
+
+// SetCallStack replaces the call stack with the position inside nested
+// sub-workflows; the root step index is kept
+func (et *ExecutionTracker) SetCallStack(callStack []definition.CallFrame) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	et.CallStack = callStack
+}
+
+// GetStepIndex returns the index of the running step of the root workflow
+func (et *ExecutionTracker) GetStepIndex() int {
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+	return et.StepIndex
+}
+
 // GetHierarchicalStepID returns the full hierarchical step ID
 func (et *ExecutionTracker) GetHierarchicalStepID() string {
 	et.mu.RLock()
@@ -83,6 +116,11 @@ func (et *ExecutionTracker) GetDepth() int {
 	return len(et.CallStack)
 }
 
+// SafeStateApplier drives device outputs to their configured safe states
+type SafeStateApplier interface {
+	ApplySafeStates(ctx context.Context, reason string, deviceNames ...string) error
+}
+
 type Engine struct {
 	storage  *storage.PostgresClient
 	executor *executor.StepExecutor
@@ -90,144 +128,292 @@ type Engine struct {
 	logger   *zap.Logger
 	wsHub    *websocket.Hub
 
+	// Optional: applied to the workflow's devices when an execution is cancelled
+	safeStates       SafeStateApplier
+	safeStateTimeout time.Duration
+
+	// Payload size limits (0 = unlimited)
+	limits config.LimitsConfig
+
+	// Per-execution log capture
+	execLogs     config.ExecLogsConfig
+	execLogLevel zapcore.Level
+
+	// Checkpoints of running executions
+	checkpoints config.CheckpointsConfig
+
 	runningMu         sync.RWMutex
-	runningContexts   map[uuid.UUID]context.CancelFunc
-	executionTrackers map[uuid.UUID]*ExecutionTracker // Track call stacks per execution
+	baseCtx           context.Context // Parent of execution contexts
+	admission         func() error    // Optional: rejects new executions while overloaded
+	labeler           Labeler         // Optional: automatic tags and metadata of new executions
+	environment       Environment     // Optional: device and machine state for preflight checks
+	runningContexts   map[uuid.UUID]context.CancelCauseFunc
+	runningWorkflows  map[uuid.UUID]uuid.UUID           // Workflow of each running execution
+	executionTrackers map[uuid.UUID]*ExecutionTracker   // Track call stacks per execution
+	correlations      map[uuid.UUID]storage.Correlation // External IDs of running executions, added to events
+	executionLoggers  map[uuid.UUID]*zap.Logger         // Loggers with the execution ID
+	executionLogs     map[uuid.UUID]*executionLog       // Captured lines not stored yet
+	frameTraces       map[uuid.UUID]*executor.FrameTrace
+
+	activationMu      sync.Mutex // Serializes workflow activations
+	activationHandler func(ActivationEvent)
 }
 
-func NewEngine(storage *storage.PostgresClient, executor *executor.StepExecutor, streamer *streaming.EventStreamer, logger *zap.Logger, wsHub *websocket.Hub) *Engine {
+func NewEngine(store *storage.PostgresClient, stepExecutor *executor.StepExecutor, streamer *streaming.EventStreamer, logger *zap.Logger, wsHub *websocket.Hub) *Engine {
 	return &Engine{
-		storage:           storage,
-		executor:          executor,
+		storage:           store,
+		executor:          stepExecutor,
 		streamer:          streamer,
-		runningContexts:   make(map[uuid.UUID]context.CancelFunc),
+		baseCtx:           context.Background(),
+		runningContexts:   make(map[uuid.UUID]context.CancelCauseFunc),
+		runningWorkflows:  make(map[uuid.UUID]uuid.UUID),
 		executionTrackers: make(map[uuid.UUID]*ExecutionTracker),
+		correlations:      make(map[uuid.UUID]storage.Correlation),
+		executionLoggers:  make(map[uuid.UUID]*zap.Logger),
+		executionLogs:     make(map[uuid.UUID]*executionLog),
+		frameTraces:       make(map[uuid.UUID]*executor.FrameTrace),
 		logger:            logger,
 		wsHub:             wsHub,
 	}
 }
 
-func (e *Engine) ExecuteWorkflow(ctx context.Context, workflowID uuid.UUID, input map[string]any) (uuid.UUID, error) {
+// SetSafeStateApplier enables writing safe states when an execution is cancelled
+func (e *Engine) SetSafeStateApplier(applier SafeStateApplier, timeout time.Duration) {
+	e.safeStates = applier
+	e.safeStateTimeout = timeout
+}
+
+// SetContext sets the parent context of executions started afterwards;
+// cancelling it cancels them
+func (e *Engine) SetContext(ctx context.Context) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	e.baseCtx = ctx
+}
+
+// ErrInvalidCorrelation is returned for correlation IDs that cannot be stored
+var ErrInvalidCorrelation = errors.New("invalid correlation")
+
+// InputValidationError is returned when execution input does not match the
+// workflow's input schema
+type InputValidationError struct {
+	Errors []definition.InputError `json:"errors"`
+}
+
+func (e *InputValidationError) Error() string {
+	return fmt.Sprintf("execution input invalid: %d field error(s)", len(e.Errors))
+}
+
+// ExecuteWorkflow starts an execution of a workflow. The correlation IDs are
+// stored on the execution and added to its events.
+func (e *Engine) ExecuteWorkflow(ctx context.Context, workflowID uuid.UUID, input map[string]any, correlation storage.Correlation) (uuid.UUID, error) {
+	exec, workflowDef, input, err := e.prepareExecution(ctx, workflowID, input, correlation)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return e.startExecution(ctx, exec, workflowDef, input)
+}
+
+// ExecuteWorkflowStream starts an execution like ExecuteWorkflow and returns
+// a subscription to its events with the given buffering. The subscription is
+// taken before the execution starts, so no event is missed; the caller
+// unsubscribes from the streamer.
+func (e *Engine) ExecuteWorkflowStream(ctx context.Context, workflowID uuid.UUID, input map[string]any, correlation storage.Correlation, opts streaming.SubscribeOptions) (uuid.UUID, <-chan *storage.ExecutionEvent, error) {
+	exec, workflowDef, input, err := e.prepareExecution(ctx, workflowID, input, correlation)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	events := e.streamer.SubscribeWith(exec.ID, opts)
+	if _, err := e.startExecution(ctx, exec, workflowDef, input); err != nil {
+		e.streamer.Unsubscribe(exec.ID, events)
+		return uuid.Nil, nil, err
+	}
+	return exec.ID, events, nil
+}
+
+// prepareExecution loads and validates a workflow and its input and builds
+// the execution record
+func (e *Engine) prepareExecution(ctx context.Context, workflowID uuid.UUID, input map[string]any, correlation storage.Correlation) (*storage.WorkflowExecution, *definition.Workflow, map[string]any, error) {
+	if err := correlation.Validate(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCorrelation, err)
+	}
+	if err := e.admit(); err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Load workflow definition
 	workflow, _, err := e.storage.LoadWorkflow(ctx, workflowID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to load workflow: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to load workflow: %w", err)
 	}
 
 	// Parse workflow definition JSON
 	workflowDef, err := definition.ParseWorkflow(workflow.Definition)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	if errs := workflowDef.ExpandTemplates(ctx, e.storage.StepTemplateDefinition); len(errs) > 0 {
+		return nil, nil, nil, fmt.Errorf("failed to expand step templates: %w", errs[0])
+	}
+
+	if err := e.CheckInputSize(input); err != nil {
+		return nil, nil, nil, err
+	}
+
+	input, inputErrs := workflowDef.ApplyInputSchema(input)
+	if len(inputErrs) > 0 {
+		return nil, nil, nil, &InputValidationError{Errors: inputErrs}
+	}
+	if report := e.preflight(workflowID, workflowDef, input); !report.Passed {
+		return nil, nil, nil, &PreflightError{Report: report}
+	}
+	if err := e.CheckParameterSizes(workflowDef); err != nil {
+		return nil, nil, nil, err
 	}
 
 	// Create execution record
-	executionID := uuid.New()
 	inputJSON, _ := json.Marshal(input)
 
 	exec := &storage.WorkflowExecution{
-		ID:         executionID,
-		WorkflowID: workflowID,
-		Status:     storage.StatusPending,
-		Input:      inputJSON,
-		StartedAt:  time.Now(),
+		ID:          uuid.New(),
+		WorkflowID:  workflowID,
+		Status:      storage.StatusPending,
+		Input:       inputJSON,
+		Correlation: correlation,
+		StartedAt:   time.Now(),
+	}
+	if err := e.label(ctx, exec, workflowDef); err != nil {
+		return nil, nil, nil, err
 	}
+	if exec.SkipSteps, err = skipSteps(ctx, workflowDef); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return exec, workflowDef, input, nil
+}
 
+// startExecution persists a new execution and runs it asynchronously
+func (e *Engine) startExecution(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow, input map[string]any) (uuid.UUID, error) {
 	if err := e.storage.CreateExecution(ctx, exec); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create execution: %w", err)
 	}
 
 	// Broadcast workflow started event
-	if e.wsHub != nil {
-		e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-			websocket.MessageTypeWorkflowStarted,
-			executionID.String(),
-			workflowID.String(),
-			"",
-			string(storage.StatusPending),
-			"",
-		))
-	}
+	e.broadcastWorkflow(websocket.MessageTypeWorkflowStarted, exec, "", string(storage.StatusPending), "")
 
-	// Create cancellable context for this execution
-	execCtx, cancel := context.WithCancel(context.Background())
+	// Top-level step results by step number, for references and the output mapping
+	results := e.retryResults(ctx, exec, workflowDef)
+	e.launchExecution(exec, workflowDef, input, results, definition.Jumps{})
+	return exec.ID, nil
+}
+
+// launchExecution runs a stored execution asynchronously, starting at
+// exec.StartStep with the jumps its transitions already took
+func (e *Engine) launchExecution(exec *storage.WorkflowExecution, workflowDef *definition.Workflow, input map[string]any, results map[string]map[string]any, jumps definition.Jumps) {
+	executionID := exec.ID
+	workflowID := exec.WorkflowID
+
+	e.runningMu.RLock()
+	baseCtx := e.baseCtx
+	e.runningMu.RUnlock()
+
+	// Create cancellable context for this execution; it owns the resources its steps hold
+	trace := executor.NewFrameTrace(e.execLogs.TraceMaxFrames, e.execLogs.TraceMaxFrameBytes)
+	execCtx, cancel := context.WithCancelCause(executor.WithFrameTrace(executor.WithExecution(baseCtx, executionID), trace))
+	execCtx = executor.WithCallFrame(execCtx, executor.CallFrame{WorkflowID: workflowID, ProgramName: workflowDef.ProgramName})
+	execCtx = executor.WithPositionFunc(execCtx, e.trackSubWorkflowStep(executionID))
 
 	// Create execution tracker for hierarchical step tracking
 	tracker := NewExecutionTracker(executionID)
 	// Push the root workflow onto the call stack
 	tracker.Push(workflowID.String(), workflowDef.ProgramName, "0")
 
+	logger, execLog := e.newExecutionLogger(executionID)
+
 	e.runningMu.Lock()
 	e.runningContexts[executionID] = cancel
+	e.runningWorkflows[executionID] = workflowID
 	e.executionTrackers[executionID] = tracker
+	if !exec.Correlation.IsZero() {
+		e.correlations[executionID] = exec.Correlation
+	}
+	e.executionLoggers[executionID] = logger
+	if execLog != nil {
+		e.executionLogs[executionID] = execLog
+	}
+	e.frameTraces[executionID] = trace
 	e.runningMu.Unlock()
 
 	// Execute asynchronously
 	go func() {
 		defer func() {
+			e.flushExecutionLogs(executionID)
 			e.runningMu.Lock()
 			delete(e.runningContexts, executionID)
+			delete(e.runningWorkflows, executionID)
 			delete(e.executionTrackers, executionID)
+			delete(e.correlations, executionID)
+			delete(e.executionLoggers, executionID)
+			delete(e.executionLogs, executionID)
+			delete(e.frameTraces, executionID)
 			e.runningMu.Unlock()
 		}()
-		e.runExecution(execCtx, exec, workflowDef, input)
+		e.runExecution(execCtx, exec, workflowDef, input, results, jumps)
 	}()
-
-	return executionID, nil
 }
 
-// CancelExecution stops a running workflow execution
-func (e *Engine) CancelExecution(ctx context.Context, executionID uuid.UUID) error {
-	e.runningMu.RLock()
-	cancel, exists := e.runningContexts[executionID]
-	e.runningMu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("execution not found or not running: %s", executionID)
-	}
-
-	cancel()
-	return nil
-}
-
-func (e *Engine) cancelExecution(ctx context.Context, exec *storage.WorkflowExecution) {
-	now := time.Now()
-	exec.Status = storage.StatusCancelled
-	exec.CompletedAt = &now
-	e.storage.UpdateExecution(ctx, exec)
-	e.publishEvent(ctx, exec.ID, "execution.cancelled", nil)
-}
-
-func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow, input map[string]any) {
+func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow, input map[string]any, results map[string]map[string]any, jumps definition.Jumps) {
 	// Get tracker for this execution
 	e.runningMu.RLock()
 	tracker, _ := e.executionTrackers[exec.ID]
 	e.runningMu.RUnlock()
+	logger := e.executionLogger(exec.ID)
 
 	// Update status to running
 	exec.Status = storage.StatusRunning
 	e.storage.UpdateExecution(ctx, exec)
 
 	// Broadcast running status
-	if e.wsHub != nil {
-		e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-			websocket.MessageTypeWorkflowStep,
-			exec.ID.String(),
-			exec.WorkflowID.String(),
-			"",
-			string(storage.StatusRunning),
-			"Workflow execution started",
-		))
-	}
-
-	// Execute steps
-	for i, step := range workflowDef.Steps {
+	e.broadcastWorkflow(websocket.MessageTypeWorkflowStep, exec, "", string(storage.StatusRunning), "Workflow execution started")
+	logger.Info("Workflow execution started",
+		zap.String("workflow_id", exec.WorkflowID.String()),
+		zap.String("program", workflowDef.ProgramName),
+		zap.Int("steps", len(workflowDef.Steps)))
+	if exec.RetryOf != nil {
+		logger.Info("Retrying execution",
+			zap.String("retry_of", exec.RetryOf.String()),
+			zap.Int("start_step", exec.StartStep))
+	}
+
+	stopCycleWatch := e.watchCycleTime(exec, workflowDef, logger)
+	defer stopCycleWatch()
+
+	scope := &definition.Scope{Input: input, Variables: workflowDef.Variables, Steps: results}
+	lastCheckpoint := time.Now()
+
+	// Execute steps; transitions may jump to another step
+	startStep := exec.StartStep
+	for i, next := 0, 0; i < len(workflowDef.Steps); i = next {
+		step := workflowDef.Steps[i]
+		next = i + 1
+		if i < startStep {
+			// Retry: step already succeeded in the original execution
+			e.publishEvent(ctx, exec.ID, "step.skipped", map[string]any{
+				"step_index": i,
+				"step_name":  step.Name,
+				"retry_of":   exec.RetryOf,
+			})
+			continue
+		}
+		if slices.Contains(exec.SkipSteps, step.Number) {
+			e.recordSkippedStep(ctx, exec, i, &step)
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
 			// Execution cancelled
-			exec.Status = storage.StatusCancelled
-			now := time.Now()
-			exec.CompletedAt = &now
-
 			if tracker != nil {
 				exec.CurrentStepID = tracker.GetHierarchicalStepID()
 				callStack := tracker.GetCallStackCopy()
@@ -236,37 +422,25 @@ func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecuti
 				}
 			}
 
-			e.storage.UpdateExecution(ctx, exec)
-
-			if e.wsHub != nil {
-				e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-					websocket.MessageTypeWorkflowCancelled,
-					exec.ID.String(),
-					exec.WorkflowID.String(),
-					step.Name,
-					string(storage.StatusCancelled),
-					"Workflow execution cancelled",
-				))
-			}
+			e.finishCancelled(ctx, exec, workflowDef, step.Name)
 			return
 
 		default:
 			// Broadcast step start
-			if e.wsHub != nil {
-				e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-					websocket.MessageTypeWorkflowStep,
-					exec.ID.String(),
-					exec.WorkflowID.String(),
-					step.Name,
-					"running",
-					fmt.Sprintf("Executing step: %s", step.Name),
-				))
-			}
+			e.broadcastWorkflow(websocket.MessageTypeWorkflowStep, exec, step.Name, "running", fmt.Sprintf("Executing step: %s", step.Name))
 
 			// Execute step with correct parameters
-			_, err := e.executeStep(ctx, exec.ID, i, &step, input)
+			output, err := e.executeStep(ctx, exec.ID, i, &step, scope)
+			if err == nil {
+				results[step.Number] = output
+				next, err = e.nextStep(ctx, exec.ID, workflowDef, i, scope, jumps)
+				if next != i+1 {
+					startStep = 0 // Steps jumped to run again, even on retry
+				}
+			}
 
 			// Update execution with current step tracking
+			exec.CurrentStep = i
 			if tracker != nil {
 				exec.CurrentStepID = tracker.GetHierarchicalStepID()
 				callStack := tracker.GetCallStackCopy()
@@ -275,36 +449,29 @@ func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecuti
 				}
 			}
 
+			if err != nil && ctx.Err() != nil {
+				// Step aborted by cancellation
+				e.finishCancelled(ctx, exec, workflowDef, step.Name)
+				return
+			}
+
 			if err != nil {
 				// Step failed
-				exec.Status = storage.StatusFailed
-				now := time.Now()
-				exec.CompletedAt = &now
-				e.storage.UpdateExecution(ctx, exec)
-
-				if e.wsHub != nil {
-					e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-						websocket.MessageTypeWorkflowFailed,
-						exec.ID.String(),
-						exec.WorkflowID.String(),
-						step.Name,
-						string(storage.StatusFailed),
-						fmt.Sprintf("Step failed: %v", err),
-					))
-				}
+				e.handleStepError(ctx, exec, &step, err)
+				logger.Error("Workflow execution failed",
+					zap.String("step_name", step.Name),
+					zap.Error(err))
+
+				e.broadcastWorkflow(websocket.MessageTypeWorkflowFailed, exec, step.Name, string(storage.StatusFailed), fmt.Sprintf("Step failed: %v", err))
 				return
 			}
 
 			// Broadcast step completed
-			if e.wsHub != nil {
-				e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-					websocket.MessageTypeWorkflowStep,
-					exec.ID.String(),
-					exec.WorkflowID.String(),
-					step.Name,
-					"completed",
-					fmt.Sprintf("Step completed: %s", step.Name),
-				))
+			e.broadcastWorkflow(websocket.MessageTypeWorkflowStep, exec, step.Name, "completed", fmt.Sprintf("Step completed: %s", step.Name))
+
+			if e.checkpoints.Enabled && time.Since(lastCheckpoint) >= e.checkpoints.Interval {
+				e.saveCheckpoint(exec, workflowDef, next, results, jumps, logger)
+				lastCheckpoint = time.Now()
 			}
 		}
 	}
@@ -314,6 +481,15 @@ func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecuti
 	now := time.Now()
 	exec.CompletedAt = &now
 
+	output, missing := workflowDef.ResolveOutputs(results)
+	if len(missing) > 0 {
+		logger.Warn("Workflow outputs could not be resolved",
+			zap.Strings("outputs", missing))
+	}
+	if output != nil {
+		_, exec.Output = e.truncateOutput(output)
+	}
+
 	if tracker != nil {
 		exec.CurrentStepID = tracker.GetHierarchicalStepID()
 		callStack := tracker.GetCallStackCopy()
@@ -323,20 +499,77 @@ func (e *Engine) runExecution(ctx context.Context, exec *storage.WorkflowExecuti
 	}
 
 	e.storage.UpdateExecution(ctx, exec)
+	logger.Info("Workflow execution completed",
+		zap.Int64("duration_ms", now.Sub(exec.StartedAt).Milliseconds()))
+	e.publishEvent(ctx, exec.ID, "execution.completed", map[string]any{
+		"output": json.RawMessage(exec.Output),
+	})
+
+	e.broadcastWorkflow(websocket.MessageTypeWorkflowCompleted, exec, "", string(storage.StatusSuccess), "Workflow execution completed successfully")
+}
+
+// applySafeStates writes safe states for the devices referenced by the workflow
+func (e *Engine) applySafeStates(exec *storage.WorkflowExecution, workflowDef *definition.Workflow, reason string) {
+	if e.safeStates == nil {
+		return
+	}
+
+	// The execution context is already cancelled at this point
+	ctx, cancel := context.WithTimeout(context.Background(), e.safeStateTimeout)
+	defer cancel()
 
-	if e.wsHub != nil {
-		e.wsHub.Broadcast(websocket.NewWorkflowMessage(
-			websocket.MessageTypeWorkflowCompleted,
-			exec.ID.String(),
-			exec.WorkflowID.String(),
-			"",
-			string(storage.StatusSuccess),
-			"Workflow execution completed successfully",
-		))
+	deviceNames := e.safeStateDevices(ctx, exec.WorkflowID, workflowDef)
+	if len(deviceNames) == 0 {
+		return
+	}
+	ctx = executor.WithLogger(executor.WithExecution(ctx, exec.ID), e.executionLogger(exec.ID))
+
+	if err := e.safeStates.ApplySafeStates(ctx, reason, deviceNames...); err != nil {
+		e.logger.Error("Failed to apply safe states after cancellation",
+			zap.String("execution_id", exec.ID.String()),
+			zap.Error(err))
 	}
 }
 
-func (e *Engine) executeStep(ctx context.Context, executionID uuid.UUID, index int, step *definition.Step, input map[string]any) (map[string]any, error) {
+// safeStateDevices returns the devices a workflow may have written to: those
+// of its device steps, including parallel branches, and post-actions, and
+// those of the sub-workflows it calls, directly or nested. Devices given as
+// $ references are resolved at runtime and can't be listed.
+func (e *Engine) safeStateDevices(ctx context.Context, workflowID uuid.UUID, workflowDef *definition.Workflow) []string {
+	var deviceNames []string
+	collect := func(wf *definition.Workflow) {
+		for _, step := range wf.Steps {
+			if step.Type == definition.StepTypeDevice {
+				deviceNames = append(deviceNames, step.DeviceID)
+			}
+			for _, action := range slices.Concat(step.OnSuccessWrite, step.OnFailureWrite) {
+				deviceNames = append(deviceNames, action.DeviceID)
+			}
+		}
+	}
+
+	collect(workflowDef)
+	for _, subID := range e.SubWorkflows(ctx, workflowID) {
+		workflow, _, err := e.storage.LoadWorkflow(ctx, subID)
+		if err != nil {
+			continue
+		}
+		sub, err := definition.ParseWorkflow(workflow.Definition)
+		if err != nil {
+			continue
+		}
+		sub.ExpandTemplates(ctx, e.storage.StepTemplateDefinition)
+		collect(sub)
+	}
+
+	deviceNames = slices.DeleteFunc(deviceNames, func(name string) bool {
+		return name == "" || strings.HasPrefix(name, "$")
+	})
+	slices.Sort(deviceNames)
+	return slices.Compact(deviceNames)
+}
+
+func (e *Engine) executeStep(ctx context.Context, executionID uuid.UUID, index int, step *definition.Step, scope *definition.Scope) (map[string]any, error) {
 	// Get tracker for this execution
 	e.runningMu.RLock()
 	tracker, exists := e.executionTrackers[executionID]
@@ -347,14 +580,17 @@ func (e *Engine) executeStep(ctx context.Context, executionID uuid.UUID, index i
 	}
 
 	// Update current step in tracker
-	tracker.SetCurrentStep(step.Number)
+	tracker.SetRootStep(index, step.Number)
 
 	stepID := uuid.New()
-	inputJSON, _ := json.Marshal(input)
+	inputJSON, _ := json.Marshal(scope.Input)
 
 	// Get the hierarchical step ID
 	hierarchicalID := tracker.GetHierarchicalStepID()
 
+	// Persist the position so status queries see it while the step runs
+	e.savePosition(ctx, executionID, tracker)
+
 	stepExec := &storage.ExecutionStep{
 		ID:                 stepID,
 		ExecutionID:        executionID,
@@ -367,6 +603,13 @@ func (e *Engine) executeStep(ctx context.Context, executionID uuid.UUID, index i
 		StartedAt:          time.Now(),
 	}
 
+	logger := e.executionLogger(executionID).With(
+		zap.String(logFieldStepID, stepID.String()),
+		zap.String(logFieldStepName, step.Name),
+		zap.String(logFieldHierarchicalStepID, hierarchicalID))
+	logger.Debug("Step started", zap.Int("step_index", index), zap.String("type", string(step.Type)))
+	defer e.flushExecutionLogs(executionID)
+
 	e.storage.CreateExecutionStep(ctx, stepExec)
 	e.publishEvent(ctx, executionID, "step.started", map[string]any{
 		"step_index":           index,
@@ -376,48 +619,116 @@ func (e *Engine) executeStep(ctx context.Context, executionID uuid.UUID, index i
 	})
 
 	// Execute step
-	output, err := e.executor.Execute(ctx, step, input)
+	output, err := e.executor.ExecuteInScope(executor.WithLogger(ctx, logger), step, scope)
 
 	now := time.Now()
 	stepExec.CompletedAt = &now
+	elapsed := zap.Int64("duration_ms", now.Sub(stepExec.StartedAt).Milliseconds())
 
 	if err != nil {
+		logger.Warn("Step failed", elapsed, zap.Error(err))
 		stepExec.Status = storage.StatusFailed
 		stepExec.Error = err.Error()
 		e.storage.UpdateExecutionStep(ctx, stepExec)
-		e.publishEvent(ctx, executionID, "step.failed", map[string]any{
+		payload := map[string]any{
 			"step_index":           index,
 			"step_name":            step.Name,
 			"hierarchical_step_id": hierarchicalID,
 			"error":                err.Error(),
-		})
+		}
+		if doc := e.executor.Documentation(step); doc != nil {
+			payload["documentation"] = doc
+		}
+		var depthErr *executor.CallDepthError
+		if errors.As(err, &depthErr) {
+			payload["call_path"] = depthErr.Path
+			e.publishEvent(ctx, executionID, "step.call_depth_exceeded", map[string]any{
+				"step_index":           index,
+				"step_name":            step.Name,
+				"hierarchical_step_id": hierarchicalID,
+				"max_depth":            depthErr.MaxDepth,
+				"recursive":            depthErr.Recursive,
+				"call_path":            depthErr.Path,
+			})
+		}
+		e.publishEvent(ctx, executionID, "step.failed", payload)
 		return nil, err
 	}
 
+	logger.Debug("Step completed", elapsed)
+	tracker.SetRootStep(index, step.Number) // Back from its sub-workflows
 	stepExec.Status = storage.StatusSuccess
-	outputJSON, _ := json.Marshal(output)
+	storedOutput, outputJSON := e.truncateOutput(output)
 	stepExec.Output = outputJSON
 	e.storage.UpdateExecutionStep(ctx, stepExec)
 	e.publishEvent(ctx, executionID, "step.completed", map[string]any{
 		"step_index":           index,
 		"step_name":            step.Name,
 		"hierarchical_step_id": hierarchicalID,
-		"output":               output,
+		"output":               storedOutput,
 	})
 
 	return output, nil
 }
 
+// trackSubWorkflowStep follows an execution into its sub-workflows. The
+// current step stays the index of the root workflow's step; the hierarchical
+// step ID and call stack locate the sub-workflow step.
+func (e *Engine) trackSubWorkflowStep(executionID uuid.UUID) executor.PositionFunc {
+	return func(ctx context.Context, path []executor.CallFrame) {
+		e.runningMu.RLock()
+		tracker := e.executionTrackers[executionID]
+		e.runningMu.RUnlock()
+		if tracker == nil {
+			return
+		}
+
+		callStack := make([]definition.CallFrame, 0, len(path))
+		for _, frame := range path {
+			callStack = append(callStack, definition.CallFrame{
+				WorkflowID:  frame.WorkflowID.String(),
+				ProgramName: frame.ProgramName,
+				StepNumber:  frame.StepNumber,
+			})
+		}
+		tracker.SetCallStack(callStack)
+		e.savePosition(ctx, executionID, tracker)
+	}
+}
+
+// savePosition persists the step an execution is running
+func (e *Engine) savePosition(ctx context.Context, executionID uuid.UUID, tracker *ExecutionTracker) {
+	callStackJSON, err := json.Marshal(tracker.GetCallStackCopy())
+	if err != nil {
+		return
+	}
+	if err := e.storage.UpdateExecutionPosition(ctx, executionID, tracker.GetStepIndex(), tracker.GetHierarchicalStepID(), callStackJSON); err != nil {
+		e.logger.Warn("Failed to update execution position",
+			zap.String("execution_id", executionID.String()),
+			zap.Error(err))
+	}
+}
+
 func (e *Engine) handleStepError(ctx context.Context, exec *storage.WorkflowExecution, step *definition.Step, err error) {
 	now := time.Now()
 	exec.Status = storage.StatusFailed
 	exec.CompletedAt = &now
 	exec.Error = err.Error()
 	e.storage.UpdateExecution(ctx, exec)
-	e.publishEvent(ctx, exec.ID, "execution.failed", map[string]any{"error": err.Error()})
+	e.publishEvent(ctx, exec.ID, "execution.failed", map[string]any{
+		"error":     exec.Error,
+		"step_name": step.Name,
+	})
 }
 
 func (e *Engine) publishEvent(ctx context.Context, executionID uuid.UUID, eventType string, payload map[string]any) {
+	if correlation, ok := e.correlation(executionID); ok {
+		if payload == nil {
+			payload = make(map[string]any)
+		}
+		payload["correlation"] = correlation
+	}
+
 	payloadJSON, _ := json.Marshal(payload)
 	event := &storage.ExecutionEvent{
 		ID:          uuid.New(),
@@ -430,6 +741,29 @@ func (e *Engine) publishEvent(ctx context.Context, executionID uuid.UUID, eventT
 	e.streamer.Broadcast(executionID, event)
 }
 
+// correlation returns the correlation IDs of a running execution
+func (e *Engine) correlation(executionID uuid.UUID) (storage.Correlation, bool) {
+	e.runningMu.RLock()
+	defer e.runningMu.RUnlock()
+	correlation, ok := e.correlations[executionID]
+	return correlation, ok
+}
+
+// broadcastWorkflow sends a workflow message with the execution's correlation IDs
+func (e *Engine) broadcastWorkflow(msgType websocket.MessageType, exec *storage.WorkflowExecution, stepName, status, message string) {
+	if e.wsHub == nil {
+		return
+	}
+
+	msg := websocket.NewWorkflowMessage(msgType, exec.ID.String(), exec.WorkflowID.String(), stepName, status, message)
+	if !exec.Correlation.IsZero() {
+		data := msg.Data.(websocket.WorkflowExecutionData)
+		data.Metadata = map[string]interface{}{"correlation": exec.Correlation}
+		msg.Data = data
+	}
+	e.wsHub.Broadcast(msg)
+}
+
 func (e *Engine) GetExecutionStatus(ctx context.Context, executionID uuid.UUID) (*storage.WorkflowExecution, []storage.ExecutionStep, error) {
 	exec, err := e.storage.GetExecution(ctx, executionID)
 	if err != nil {
@@ -444,6 +778,11 @@ func (e *Engine) GetExecutionStatus(ctx context.Context, executionID uuid.UUID)
 	return exec, steps, nil
 }
 
+// Resources returns the state of the step resource tags
+func (e *Engine) Resources() []executor.ResourceState {
+	return e.executor.Resources().List()
+}
+
 func (e *Engine) SetLogger(logger *zap.Logger) {
 	e.logger = logger
 }
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+)
+
+// truncationPreviewBytes bounds the raw preview kept in a truncation marker
+const truncationPreviewBytes = 256
+
+// PayloadTooLargeError is returned when a payload exceeds its configured limit
+type PayloadTooLargeError struct {
+	Field string `json:"field"`
+	Size  int    `json:"size"`
+	Limit int    `json:"limit"`
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, limit is %d bytes", e.Field, e.Size, e.Limit)
+}
+
+// SetPayloadLimits bounds execution input, step parameters and step outputs
+func (e *Engine) SetPayloadLimits(limits config.LimitsConfig) {
+	e.limits = limits
+}
+
+// PayloadLimits returns the configured payload limits
+func (e *Engine) PayloadLimits() config.LimitsConfig {
+	return e.limits
+}
+
+// CheckInputSize rejects execution input above the input limit
+func (e *Engine) CheckInputSize(input map[string]any) error {
+	return checkSize("input", input, e.limits.MaxInputBytes)
+}
+
+// CheckParameterSizes rejects step parameters above the parameter limit
+func (e *Engine) CheckParameterSizes(wf *definition.Workflow) error {
+	for i, step := range wf.Steps {
+		field := fmt.Sprintf("steps[%d].parameters", i)
+		if err := checkSize(field, step.Parameters, e.limits.MaxParameterBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSize(field string, v any, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", field, err)
+	}
+	if len(data) > limit {
+		return &PayloadTooLargeError{Field: field, Size: len(data), Limit: limit}
+	}
+	return nil
+}
+
+// truncateOutput replaces a step output above the output limit with a marker
+// so the DB and event stream only see a bounded payload
+func (e *Engine) truncateOutput(output map[string]any) (map[string]any, []byte) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return output, nil
+	}
+
+	limit := e.limits.MaxOutputBytes
+	if limit <= 0 || len(data) <= limit {
+		return output, data
+	}
+
+	preview := truncateUTF8(data, truncationPreviewBytes)
+	marker := map[string]any{
+		"_truncated":     true,
+		"original_bytes": len(data),
+		"limit":          limit,
+		"preview":        string(preview),
+	}
+	markerJSON, _ := json.Marshal(marker)
+
+	return marker, markerJSON
+}
+
+// truncateUTF8 cuts data to at most n bytes without splitting a UTF-8
+// character
+func truncateUTF8(data []byte, n int) []byte {
+	if len(data) <= n {
+		return data
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(data[cut]) {
+		cut--
+	}
+	return data[:cut]
+}
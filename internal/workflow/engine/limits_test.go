@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		n    int
+		want string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"exact", "abc", 3, "abc"},
+		{"ascii", "abcdef", 4, "abcd"},
+		{"before a two-byte character", "abcä", 3, "abc"},
+		{"inside a two-byte character", "abcä", 4, "abc"},
+		{"after a two-byte character", "abcäd", 5, "abcä"},
+		{"inside a four-byte character", "a😀b", 3, "a"},
+		{"zero", "abc", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateUTF8([]byte(tt.data), tt.n)
+			if string(got) != tt.want {
+				t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.data, tt.n, got, tt.want)
+			}
+			if !utf8.Valid(got) {
+				t.Errorf("truncateUTF8(%q, %d) is not valid UTF-8", tt.data, tt.n)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logFlushTimeout bounds storing the captured lines of an execution
+const logFlushTimeout = 5 * time.Second
+
+// Logger fields stored as columns of a captured line instead of in its fields
+const (
+	logFieldExecutionID        = "execution_id"
+	logFieldStepID             = "step_id"
+	logFieldStepName           = "step_name"
+	logFieldHierarchicalStepID = "hierarchical_step_id"
+)
+
+// executionLog buffers the captured lines of one execution until they are stored
+type executionLog struct {
+	executionID uuid.UUID
+	maxEntries  int // 0 = unlimited
+
+	mu       sync.Mutex
+	pending  []storage.ExecutionLogEntry
+	captured int // Stored and pending lines
+	dropped  int
+}
+
+func (l *executionLog) add(entry storage.ExecutionLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxEntries > 0 && l.captured >= l.maxEntries {
+		if l.dropped == 0 {
+			l.pending = append(l.pending, storage.ExecutionLogEntry{
+				ExecutionID: l.executionID,
+				Level:       zapcore.WarnLevel.String(),
+				Message:     "Log limit reached, further lines are dropped",
+				Fields:      map[string]any{"max_entries": l.maxEntries},
+				LoggedAt:    entry.LoggedAt,
+			})
+		}
+		l.dropped++
+		return
+	}
+	l.captured++
+	l.pending = append(l.pending, entry)
+}
+
+// take removes and returns the pending lines
+func (l *executionLog) take() []storage.ExecutionLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := l.pending
+	l.pending = nil
+	return entries
+}
+
+// matching returns copies of the pending lines passing the filter
+func (l *executionLog) matching(filter *storage.ExecutionLogFilter) []storage.ExecutionLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]storage.ExecutionLogEntry, 0)
+	for i := range l.pending {
+		if filter.Matches(&l.pending[i]) {
+			entries = append(entries, l.pending[i])
+		}
+	}
+	return entries
+}
+
+// captureCore is a zapcore.Core that writes into an execution log
+type captureCore struct {
+	zapcore.LevelEnabler
+	log    *executionLog
+	fields []zapcore.Field
+}
+
+func (c *captureCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &captureCore{LevelEnabler: c.LevelEnabler, log: c.log, fields: merged}
+}
+
+func (c *captureCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *captureCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	entry := storage.ExecutionLogEntry{
+		ExecutionID: c.log.executionID,
+		Level:       ent.Level.String(),
+		Message:     ent.Message,
+		LoggedAt:    ent.Time,
+	}
+	if v, ok := enc.Fields[logFieldStepID].(string); ok {
+		if id, err := uuid.Parse(v); err == nil {
+			entry.StepID = &id
+		}
+	}
+	entry.StepName, _ = enc.Fields[logFieldStepName].(string)
+	entry.HierarchicalStepID, _ = enc.Fields[logFieldHierarchicalStepID].(string)
+
+	for _, key := range []string{logFieldExecutionID, logFieldStepID, logFieldStepName, logFieldHierarchicalStepID} {
+		delete(enc.Fields, key)
+	}
+	if len(enc.Fields) > 0 {
+		entry.Fields = enc.Fields
+	}
+
+	c.log.add(entry)
+	return nil
+}
+
+func (c *captureCore) Sync() error {
+	return nil
+}
+
+// SetExecutionLogs configures capturing log lines per execution
+func (e *Engine) SetExecutionLogs(cfg config.ExecLogsConfig) error {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid execution log level %q: %w", cfg.Level, err)
+	}
+	e.execLogs = cfg
+	e.execLogLevel = level
+	return nil
+}
+
+// newExecutionLogger returns the logger of a new execution; its lines go to
+// the global logger and, if enabled, into the execution's log
+func (e *Engine) newExecutionLogger(executionID uuid.UUID) (*zap.Logger, *executionLog) {
+	logger := e.logger
+	var execLog *executionLog
+	if e.execLogs.Enabled {
+		execLog = &executionLog{executionID: executionID, maxEntries: e.execLogs.MaxEntries}
+		capture := &captureCore{LevelEnabler: e.execLogLevel, log: execLog}
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, capture)
+		}))
+	}
+	return logger.With(zap.String(logFieldExecutionID, executionID.String())), execLog
+}
+
+// Errors of SetExecutionTrace
+var (
+	ErrNotRunning   = errors.New("execution not found or not running")
+	ErrLogsDisabled = errors.New("execution logs are disabled")
+)
+
+// SetExecutionTrace switches tracing the Modbus frames of all device steps
+// of a running execution into its log
+func (e *Engine) SetExecutionTrace(executionID uuid.UUID, enabled bool) error {
+	if !e.execLogs.Enabled {
+		return ErrLogsDisabled
+	}
+
+	e.runningMu.RLock()
+	trace, ok := e.frameTraces[executionID]
+	e.runningMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotRunning, executionID)
+	}
+
+	trace.SetEnabled(enabled)
+	e.executionLogger(executionID).Info("Frame trace switched", zap.Bool("enabled", enabled))
+	return nil
+}
+
+// executionLogger returns the logger of a running execution
+func (e *Engine) executionLogger(executionID uuid.UUID) *zap.Logger {
+	e.runningMu.RLock()
+	logger, ok := e.executionLoggers[executionID]
+	e.runningMu.RUnlock()
+	if !ok {
+		return e.logger.With(zap.String(logFieldExecutionID, executionID.String()))
+	}
+	return logger
+}
+
+// flushExecutionLogs stores the pending lines of a running execution
+func (e *Engine) flushExecutionLogs(executionID uuid.UUID) {
+	e.runningMu.RLock()
+	execLog, ok := e.executionLogs[executionID]
+	e.runningMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	entries := execLog.take()
+	if len(entries) == 0 {
+		return
+	}
+
+	// Also runs after the execution context was cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), logFlushTimeout)
+	defer cancel()
+
+	if err := e.storage.SaveExecutionLogs(ctx, entries); err != nil {
+		e.logger.Warn("Failed to store execution logs",
+			zap.String("execution_id", executionID.String()),
+			zap.Int("lines", len(entries)),
+			zap.Error(err))
+	}
+}
+
+// ExecutionLogs returns the captured lines of an execution, including the
+// ones of a running execution not stored yet
+func (e *Engine) ExecutionLogs(ctx context.Context, executionID uuid.UUID, filter storage.ExecutionLogFilter) ([]storage.ExecutionLogEntry, error) {
+	entries, err := e.storage.ListExecutionLogs(ctx, executionID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	e.runningMu.RLock()
+	execLog, running := e.executionLogs[executionID]
+	e.runningMu.RUnlock()
+	if running {
+		entries = append(entries, execLog.matching(&filter)...)
+		if filter.Limit > 0 && len(entries) > filter.Limit {
+			entries = entries[:filter.Limit]
+		}
+	}
+	return entries, nil
+}
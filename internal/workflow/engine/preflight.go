@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// Environment tells the preflight check which devices are connected and
+// which state the machine is in
+type Environment interface {
+	DeviceConnected(name string) (loaded, connected bool)
+	MachineState() string
+}
+
+// Preflight check kinds
+const (
+	PreflightDevice       = "device"
+	PreflightMachineState = "machine_state"
+	PreflightVariable     = "variable"
+)
+
+// PreflightCheck is the result of a single precondition
+type PreflightCheck struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// PreflightReport lists the preconditions of a workflow and whether they hold
+type PreflightReport struct {
+	WorkflowID uuid.UUID        `json:"workflow_id"`
+	Passed     bool             `json:"passed"`
+	Checks     []PreflightCheck `json:"checks"`
+}
+
+// Failed returns the checks that did not pass
+func (r *PreflightReport) Failed() []PreflightCheck {
+	var failed []PreflightCheck
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// PreflightError is returned when an execution is rejected because
+// preconditions of its workflow don't hold
+type PreflightError struct {
+	Report *PreflightReport
+}
+
+func (e *PreflightError) Error() string {
+	failed := e.Report.Failed()
+	parts := make([]string, 0, len(failed))
+	for _, check := range failed {
+		parts = append(parts, fmt.Sprintf("%s %s: %s", check.Kind, check.Name, check.Message))
+	}
+	return fmt.Sprintf("preflight check failed: %s", strings.Join(parts, "; "))
+}
+
+// SetEnvironment installs the source of device and machine state for the
+// preflight checks. Without it device and machine state preconditions fail.
+func (e *Engine) SetEnvironment(env Environment) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	e.environment = env
+}
+
+// Preflight checks the preconditions of a stored workflow for the given
+// input without starting it
+func (e *Engine) Preflight(ctx context.Context, workflowID uuid.UUID, input map[string]any) (*PreflightReport, error) {
+	workflow, _, err := e.storage.LoadWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow: %w", err)
+	}
+	workflowDef, err := definition.ParseWorkflow(workflow.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+
+	// Defaults of the input schema count as set
+	input, _ = workflowDef.ApplyInputSchema(input)
+	return e.preflight(workflowID, workflowDef, input), nil
+}
+
+// preflight evaluates the preconditions of a workflow definition
+func (e *Engine) preflight(workflowID uuid.UUID, workflowDef *definition.Workflow, input map[string]any) *PreflightReport {
+	report := &PreflightReport{WorkflowID: workflowID, Passed: true, Checks: []PreflightCheck{}}
+	req := workflowDef.Requires
+	if req == nil {
+		return report
+	}
+
+	e.runningMu.RLock()
+	env := e.environment
+	e.runningMu.RUnlock()
+
+	add := func(kind, name, message string) {
+		report.Checks = append(report.Checks, PreflightCheck{Kind: kind, Name: name, Passed: message == "", Message: message})
+		if message != "" {
+			report.Passed = false
+		}
+	}
+
+	for _, name := range req.Devices {
+		if env == nil {
+			add(PreflightDevice, name, "device state unavailable")
+			continue
+		}
+		loaded, connected := env.DeviceConnected(name)
+		switch {
+		case !loaded:
+			add(PreflightDevice, name, "device not loaded")
+		case !connected:
+			add(PreflightDevice, name, "device not connected")
+		default:
+			add(PreflightDevice, name, "")
+		}
+	}
+
+	if len(req.MachineStates) > 0 {
+		allowed := strings.Join(req.MachineStates, ", ")
+		switch {
+		case env == nil:
+			add(PreflightMachineState, allowed, "machine state unavailable")
+		case !slices.Contains(req.MachineStates, env.MachineState()):
+			add(PreflightMachineState, allowed, fmt.Sprintf("machine is %s", env.MachineState()))
+		default:
+			add(PreflightMachineState, allowed, "")
+		}
+	}
+
+	for _, name := range req.Variables {
+		if value, ok := input[name]; ok && value != nil {
+			add(PreflightVariable, name, "")
+		} else if workflowDef.Variables[name] != "" {
+			add(PreflightVariable, name, "")
+		} else {
+			add(PreflightVariable, name, "not set in variables or input")
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotRetryable is returned for executions that did not fail or get cancelled
+	ErrNotRetryable = errors.New("execution is not retryable")
+	// ErrInvalidRetryStep is returned for a from_step outside the workflow
+	ErrInvalidRetryStep = errors.New("invalid retry step")
+)
+
+// RetryExecution starts a new execution of a failed or cancelled execution's
+// workflow with the same input. Steps before fromStep are skipped; without
+// fromStep the retry resumes at the step that failed.
+func (e *Engine) RetryExecution(ctx context.Context, executionID uuid.UUID, fromStep *int) (*storage.WorkflowExecution, error) {
+	original, err := e.storage.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Status != storage.StatusFailed && original.Status != storage.StatusCancelled {
+		return nil, fmt.Errorf("%w: status is %s", ErrNotRetryable, original.Status)
+	}
+	if err := e.admit(); err != nil {
+		return nil, err
+	}
+
+	workflow, _, err := e.storage.LoadWorkflow(ctx, original.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow: %w", err)
+	}
+
+	workflowDef, err := definition.ParseWorkflow(workflow.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	if errs := workflowDef.ExpandTemplates(ctx, e.storage.StepTemplateDefinition); len(errs) > 0 {
+		return nil, fmt.Errorf("failed to expand step templates: %w", errs[0])
+	}
+
+	startStep := 0
+	if fromStep != nil {
+		startStep = *fromStep
+	} else {
+		startStep, err = e.resumeStep(ctx, original)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if startStep < 0 || startStep >= len(workflowDef.Steps) {
+		return nil, fmt.Errorf("%w: %d (workflow has %d steps)", ErrInvalidRetryStep, startStep, len(workflowDef.Steps))
+	}
+
+	input := make(map[string]any)
+	if len(original.Input) > 0 {
+		if err := json.Unmarshal(original.Input, &input); err != nil {
+			return nil, fmt.Errorf("failed to decode original input: %w", err)
+		}
+	}
+
+	if err := e.CheckParameterSizes(workflowDef); err != nil {
+		return nil, err
+	}
+	if report := e.preflight(original.WorkflowID, workflowDef, input); !report.Passed {
+		return nil, &PreflightError{Report: report}
+	}
+
+	retryOf := original.ID
+	exec := &storage.WorkflowExecution{
+		ID:          uuid.New(),
+		WorkflowID:  original.WorkflowID,
+		Status:      storage.StatusPending,
+		Input:       original.Input,
+		RetryOf:     &retryOf,
+		StartStep:   startStep,
+		Correlation: original.Correlation,
+		Tags:        original.Tags, // Annotations of the original run carry over
+		Metadata:    original.Metadata,
+		SkipSteps:   original.SkipSteps,
+		StartedAt:   time.Now(),
+	}
+
+	if _, err := e.startExecution(ctx, exec, workflowDef, input); err != nil {
+		return nil, err
+	}
+
+	e.publishEvent(ctx, original.ID, "execution.retried", map[string]any{
+		"retry_execution_id": exec.ID,
+		"start_step":         startStep,
+	})
+
+	return exec, nil
+}
+
+// resumeStep returns the index of the first top-level step that did not succeed
+func (e *Engine) resumeStep(ctx context.Context, exec *storage.WorkflowExecution) (int, error) {
+	steps, err := e.storage.GetExecutionSteps(ctx, exec.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, step := range steps {
+		if step.Depth == 0 && step.Status != storage.StatusSuccess && step.Status != storage.StatusSkipped {
+			return step.StepIndex, nil
+		}
+	}
+
+	// No step failed: the execution stopped between steps
+	return exec.CurrentStep, nil
+}
+
+// retryResults returns the outputs of the steps a retry skips, so later
+// steps and the output mapping can still reference them. A retry of a retry
+// has no results of the steps its original skipped as well; they are taken
+// from further back in the RetryOf chain.
+func (e *Engine) retryResults(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow) map[string]map[string]any {
+	results := make(map[string]map[string]any)
+
+	current := exec
+	visited := map[uuid.UUID]bool{exec.ID: true}
+	for current.RetryOf != nil && current.StartStep > 0 && !visited[*current.RetryOf] {
+		originalID := *current.RetryOf
+		visited[originalID] = true
+
+		steps, err := e.storage.GetExecutionSteps(ctx, originalID)
+		if err != nil {
+			e.logger.Warn("Failed to load original step results for retry",
+				zap.String("execution_id", exec.ID.String()),
+				zap.String("retry_of", originalID.String()),
+				zap.Error(err))
+			return results
+		}
+
+		// Steps run several times by transitions keep their last result
+		ran := make(map[string]map[string]any)
+		for _, step := range steps {
+			if step.Depth != 0 || step.Status != storage.StatusSuccess ||
+				step.StepIndex >= current.StartStep || step.StepIndex >= len(workflowDef.Steps) {
+				continue
+			}
+			var output map[string]any
+			if err := json.Unmarshal(step.Output, &output); err == nil {
+				ran[workflowDef.Steps[step.StepIndex].Number] = output
+			}
+		}
+		// Results of later executions in the chain take precedence
+		for number, output := range ran {
+			if _, ok := results[number]; !ok {
+				results[number] = output
+			}
+		}
+
+		original, err := e.storage.GetExecution(ctx, originalID)
+		if err != nil {
+			e.logger.Warn("Failed to load original execution for retry",
+				zap.String("execution_id", exec.ID.String()),
+				zap.String("retry_of", originalID.String()),
+				zap.Error(err))
+			return results
+		}
+		current = original
+	}
+
+	return results
+}
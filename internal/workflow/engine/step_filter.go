@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidStepFilter is returned for step filters not matching the workflow
+var ErrInvalidStepFilter = errors.New("invalid step filter")
+
+// StepFilter selects the top-level steps of an execution, e.g. to commission
+// part of a sequence. Steps are given by number.
+type StepFilter struct {
+	Skip    []string // Steps not executed
+	StartAt string   // Steps before it are not executed
+}
+
+// IsZero reports whether the filter executes all steps
+func (f StepFilter) IsZero() bool {
+	return len(f.Skip) == 0 && f.StartAt == ""
+}
+
+type stepFilterKey struct{}
+
+// WithStepFilter applies a step filter to executions started with the context
+func WithStepFilter(ctx context.Context, filter StepFilter) context.Context {
+	return context.WithValue(ctx, stepFilterKey{}, filter)
+}
+
+// skipSteps resolves the step filter of the context to the numbers of the
+// skipped steps, in workflow order
+func skipSteps(ctx context.Context, workflowDef *definition.Workflow) ([]string, error) {
+	filter, _ := ctx.Value(stepFilterKey{}).(StepFilter)
+	if filter.IsZero() {
+		return nil, nil
+	}
+
+	start := 0
+	if filter.StartAt != "" {
+		start = slices.IndexFunc(workflowDef.Steps, func(step definition.Step) bool {
+			return step.Number == filter.StartAt
+		})
+		if start < 0 {
+			return nil, fmt.Errorf("%w: start_at step %s not found", ErrInvalidStepFilter, filter.StartAt)
+		}
+	}
+	for _, number := range filter.Skip {
+		if !slices.ContainsFunc(workflowDef.Steps, func(step definition.Step) bool { return step.Number == number }) {
+			return nil, fmt.Errorf("%w: step %s not found", ErrInvalidStepFilter, number)
+		}
+	}
+
+	skip := make([]string, 0, len(workflowDef.Steps))
+	for i, step := range workflowDef.Steps {
+		if i < start || slices.Contains(filter.Skip, step.Number) {
+			skip = append(skip, step.Number)
+		}
+	}
+	if len(skip) == len(workflowDef.Steps) {
+		return nil, fmt.Errorf("%w: all steps are skipped", ErrInvalidStepFilter)
+	}
+	return skip, nil
+}
+
+// recordSkippedStep stores a step excluded by the step filter as skipped
+func (e *Engine) recordSkippedStep(ctx context.Context, exec *storage.WorkflowExecution, index int, step *definition.Step) {
+	hierarchicalID := step.Number
+	e.runningMu.RLock()
+	tracker := e.executionTrackers[exec.ID]
+	e.runningMu.RUnlock()
+	if tracker != nil {
+		tracker.SetCurrentStep(step.Number)
+		hierarchicalID = tracker.GetHierarchicalStepID()
+	}
+
+	now := time.Now()
+	stepExec := &storage.ExecutionStep{
+		ID:                 uuid.New(),
+		ExecutionID:        exec.ID,
+		StepIndex:          index,
+		StepName:           step.Name,
+		HierarchicalStepID: hierarchicalID,
+		Status:             storage.StatusSkipped,
+		StartedAt:          now,
+		CompletedAt:        &now,
+	}
+	logger := e.executionLogger(exec.ID)
+	err := e.storage.CreateExecutionStep(ctx, stepExec)
+	if err == nil {
+		// Stores completed_at
+		err = e.storage.UpdateExecutionStep(ctx, stepExec)
+	}
+	if err != nil {
+		logger.Warn("Failed to record skipped step", zap.String("step_name", step.Name), zap.Error(err))
+	}
+
+	logger.Info("Step skipped by step filter",
+		zap.Int("step_index", index),
+		zap.String("step_name", step.Name))
+	e.publishEvent(ctx, exec.ID, "step.skipped", map[string]any{
+		"step_index":           index,
+		"step_name":            step.Name,
+		"hierarchical_step_id": hierarchicalID,
+		"reason":               "step_filter",
+	})
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"slices"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// SubWorkflows returns the IDs of the workflows a workflow calls through
+// sub-workflow steps, directly or nested, e.g. to check their access lists
+// before it starts. Steps of templates are included. Workflows that can't be
+// loaded are left out; their steps fail at runtime.
+func (e *Engine) SubWorkflows(ctx context.Context, workflowID uuid.UUID) []uuid.UUID {
+	var found []uuid.UUID
+	pending := []uuid.UUID{workflowID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+
+		workflow, _, err := e.storage.LoadWorkflow(ctx, id)
+		if err != nil {
+			continue
+		}
+		workflowDef, err := definition.ParseWorkflow(workflow.Definition)
+		if err != nil {
+			continue
+		}
+		workflowDef.ExpandTemplates(ctx, e.storage.StepTemplateDefinition)
+
+		for _, step := range workflowDef.Steps {
+			if step.Type != definition.StepTypeWorkflow {
+				continue
+			}
+			subID, err := uuid.Parse(step.WorkflowID)
+			if err != nil || subID == workflowID || slices.Contains(found, subID) {
+				continue
+			}
+			found = append(found, subID)
+			pending = append(pending, subID)
+		}
+	}
+	return found
+}
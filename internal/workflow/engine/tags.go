@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+)
+
+// ErrInvalidTags is returned for too many or too long execution tags
+var ErrInvalidTags = storage.ErrInvalidTags
+
+// Labeler returns automatic tags and metadata of a new execution, e.g. the
+// current shift
+type Labeler func(ctx context.Context) (tags []string, metadata map[string]any)
+
+type tagsKey struct{}
+
+// WithTags adds manual tags to executions started with the context
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	existing, _ := ctx.Value(tagsKey{}).([]string)
+	return context.WithValue(ctx, tagsKey{}, append(existing[:len(existing):len(existing)], tags...))
+}
+
+// SetLabeler installs automatic tags and metadata for new executions
+func (e *Engine) SetLabeler(labeler Labeler) {
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+	e.labeler = labeler
+}
+
+// label sets the tags and metadata of a new execution: the tags of the
+// workflow definition, the labeler and the context, and the workflow name
+// and version
+func (e *Engine) label(ctx context.Context, exec *storage.WorkflowExecution, workflowDef *definition.Workflow) error {
+	tags := append([]string{}, workflowDef.Tags...)
+	metadata := map[string]any{
+		"workflow_name":    workflowDef.Name,
+		"workflow_version": workflowDef.Version,
+	}
+
+	e.runningMu.RLock()
+	labeler := e.labeler
+	e.runningMu.RUnlock()
+	if labeler != nil {
+		autoTags, autoMetadata := labeler(ctx)
+		tags = append(tags, autoTags...)
+		maps.Copy(metadata, autoMetadata)
+	}
+
+	manual, _ := ctx.Value(tagsKey{}).([]string)
+	tags, err := storage.NormalizeTags(append(tags, manual...))
+	if err != nil {
+		return err
+	}
+
+	exec.Tags = tags
+	exec.Metadata, _ = json.Marshal(metadata)
+	return nil
+}
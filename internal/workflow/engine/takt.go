@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"go.uber.org/zap"
+)
+
+// watchCycleTime reports a run exceeding the cycle time of its workflow and
+// cancels it at cycle_timeout_factor times the cycle time. The returned
+// function stops watching.
+func (e *Engine) watchCycleTime(exec *storage.WorkflowExecution, workflowDef *definition.Workflow, logger *zap.Logger) func() {
+	cycleTime := workflowDef.CycleTime.Duration
+	if cycleTime <= 0 {
+		return func() {}
+	}
+
+	// Resumed executions keep their original start
+	exceeded := time.AfterFunc(time.Until(exec.StartedAt.Add(cycleTime)), func() {
+		elapsed := time.Since(exec.StartedAt)
+		logger.Warn("Cycle time exceeded",
+			zap.Duration("cycle_time", cycleTime),
+			zap.Duration("elapsed", elapsed))
+		e.publishEvent(context.Background(), exec.ID, storage.EventCycleTimeExceeded, map[string]any{
+			"workflow_id":   exec.WorkflowID.String(),
+			"cycle_time_ms": cycleTime.Milliseconds(),
+			"elapsed_ms":    elapsed.Milliseconds(),
+		})
+	})
+
+	var timeout *time.Timer
+	if factor := workflowDef.CycleTimeoutFactor; factor > 0 {
+		limit := time.Duration(float64(cycleTime) * factor)
+		timeout = time.AfterFunc(time.Until(exec.StartedAt.Add(limit)), func() {
+			err := e.CancelExecution(context.Background(), exec.ID, CancelReason{
+				Source: CancelSourceSystem,
+				Reason: fmt.Sprintf("cycle timeout: run exceeded %s (%g x cycle time)", limit, factor),
+			})
+			if err == nil {
+				logger.Warn("Cycle timeout, cancelling execution", zap.Duration("limit", limit))
+			}
+		})
+	}
+
+	return func() {
+		exceeded.Stop()
+		if timeout != nil {
+			timeout.Stop()
+		}
+	}
+}
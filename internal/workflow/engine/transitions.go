@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// nextStep evaluates the transitions of a completed step and returns the
+// index of the step to run next. Jumps are published as step.transition.
+func (e *Engine) nextStep(ctx context.Context, executionID uuid.UUID, workflowDef *definition.Workflow, index int, scope *definition.Scope, jumps definition.Jumps) (int, error) {
+	step := &workflowDef.Steps[index]
+	if len(step.Transitions) == 0 {
+		return index + 1, nil
+	}
+
+	next, err := workflowDef.NextStep(index, scope, jumps, e.executor.MaxLoopJumps())
+	if err != nil {
+		return 0, err
+	}
+	if next == index+1 {
+		return next, nil
+	}
+
+	target := workflowDef.Steps[next].Number
+	e.executionLogger(executionID).Debug("Step transition",
+		zap.String("from", step.Number),
+		zap.String("to", target))
+	e.publishEvent(ctx, executionID, "step.transition", map[string]any{
+		"step_index": index,
+		"step_name":  step.Name,
+		"from":       step.Number,
+		"to":         target,
+		"to_index":   next,
+	})
+	return next, nil
+}
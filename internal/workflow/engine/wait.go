@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/google/uuid"
+)
+
+// waitCheckInterval bounds how long WaitExecution misses an end whose event
+// was dropped, e.g. of an execution finished by another node
+const waitCheckInterval = time.Second
+
+// WaitExecution blocks until the execution has finished or the timeout
+// elapsed and returns its state; done reports whether it finished
+func (e *Engine) WaitExecution(ctx context.Context, executionID uuid.UUID, timeout time.Duration) (exec *storage.WorkflowExecution, done bool, err error) {
+	// Subscribe before the first check, so the end can't slip in between
+	events := e.streamer.Subscribe(executionID)
+	defer e.streamer.Unsubscribe(executionID, events)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	check := time.NewTicker(waitCheckInterval)
+	defer check.Stop()
+
+	for {
+		exec, err := e.storage.GetExecution(ctx, executionID)
+		if err != nil {
+			return nil, false, err
+		}
+		if exec.Status.Finished() {
+			return exec, true, nil
+		}
+
+		select {
+		case <-events:
+		case <-check.C:
+		case <-deadline.C:
+			return exec, false, nil
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
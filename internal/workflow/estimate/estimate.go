@@ -0,0 +1,367 @@
+// Package estimate predicts the run duration of a workflow and how long it
+// keeps each device busy, so planners can sequence jobs before committing a
+// line to a long recipe
+package estimate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// maxDepth bounds the sub-workflow tree
+const maxDepth = 10
+
+// Defaults of estimates without options
+const (
+	DefaultDeviceStep  = 50 * time.Millisecond
+	DefaultHistoryDays = 30
+	defaultWait        = time.Second // Wait steps without timeout, as in the executor
+)
+
+// Sources of a step estimate
+const (
+	SourceHistory    = "history"    // Average of the measured runs
+	SourceDefinition = "definition" // Wait duration or sub-workflow steps
+	SourceDefault    = "default"    // Assumed device step duration
+)
+
+// ErrWorkflowNotFound is returned by Run for unknown workflows
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// Options tune an estimate
+type Options struct {
+	Loops       map[string]int // Step number with a backward transition -> expected jumps per run
+	DeviceStep  time.Duration  // Device steps and post-action writes without history
+	HistoryDays int            // Executions considered for measured durations
+}
+
+// Estimate is the predicted run of a workflow
+type Estimate struct {
+	WorkflowID  uuid.UUID `json:"workflow_id"`
+	Name        string    `json:"name"`
+	EstimatedMs int64     `json:"estimated_ms"`
+	Estimated   string    `json:"estimated"`               // EstimatedMs as duration, e.g. "1m30s"
+	CycleTimeMs int64     `json:"cycle_time_ms,omitempty"` // Takt of the workflow for comparison
+	Executions  int       `json:"executions"`              // Successful runs the history is taken from
+	Steps       []Step    `json:"steps"`
+	Devices     []Device  `json:"devices"`
+	Loops       []Loop    `json:"loops,omitempty"`
+	Warnings    []string  `json:"warnings,omitempty"`
+}
+
+// Step is the estimate of a top-level step
+type Step struct {
+	Number      string  `json:"number"`
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Target      string  `json:"target,omitempty"` // Device or sub-workflow
+	Runs        float64 `json:"runs"`             // Per execution; loops run a step more than once
+	PerRunMs    float64 `json:"per_run_ms"`
+	TotalMs     float64 `json:"total_ms"`
+	Source      string  `json:"source"`
+	Conditional bool    `json:"conditional,omitempty"` // Counted as if its condition holds
+}
+
+// Device is the time a device is busy with the steps of a run
+type Device struct {
+	Name   string   `json:"name"`
+	BusyMs float64  `json:"busy_ms"`
+	Share  float64  `json:"share"` // Of the estimated run duration
+	Steps  []string `json:"steps"` // Step numbers, "30/sub_pick:20" inside sub-workflows
+}
+
+// Loop is a backward transition and the jumps assumed for it
+type Loop struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	MaxJumps int     `json:"max_jumps,omitempty"`
+	Jumps    float64 `json:"jumps"`
+	Source   string  `json:"source"` // "request", "history" or "default" (not taken)
+}
+
+// use is the time a step keeps a device busy
+type use struct {
+	device string
+	step   string // Step number, prefixed inside sub-workflows
+	ms     float64
+}
+
+// estimator walks a workflow and its sub-workflows
+type estimator struct {
+	ctx      context.Context
+	store    *storage.PostgresClient
+	opts     Options
+	devices  map[string]*Device
+	warnings []string
+}
+
+// Run estimates a stored workflow. Top-level steps use the average duration
+// and run count of the workflow's recent successful executions; without
+// history, wait steps take their duration and device steps the assumed
+// device step duration. Sub-workflows without history are estimated from
+// their steps.
+func Run(ctx context.Context, store *storage.PostgresClient, workflowID uuid.UUID, opts Options) (*Estimate, error) {
+	if opts.DeviceStep <= 0 {
+		opts.DeviceStep = DefaultDeviceStep
+	}
+	if opts.HistoryDays <= 0 {
+		opts.HistoryDays = DefaultHistoryDays
+	}
+
+	workflow, _, err := store.LoadWorkflow(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWorkflowNotFound, err)
+	}
+	wf, err := parse(ctx, store, workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().AddDate(0, 0, -opts.HistoryDays)
+	history, executions, err := store.GetStepDurations(ctx, workflowID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &estimator{ctx: ctx, store: store, opts: opts, devices: map[string]*Device{}}
+	result := &Estimate{
+		WorkflowID: workflowID,
+		Name:       workflow.WorkflowName,
+		Executions: executions,
+		Steps:      make([]Step, 0, len(wf.Steps)),
+	}
+	if wf.Name != "" {
+		result.Name = wf.Name
+	}
+	if wf.CycleTime.Duration > 0 {
+		result.CycleTimeMs = wf.CycleTime.Milliseconds()
+	}
+
+	loops, requested := e.loops(wf, history, executions)
+	result.Loops = loops
+
+	var total float64
+	for i, step := range wf.Steps {
+		s := Step{
+			Number:      step.Number,
+			Name:        step.Name,
+			Type:        string(step.Type),
+			Target:      target(&step),
+			Conditional: step.Condition != "",
+			Runs:        1 + requested[i],
+		}
+
+		var uses []use
+		s.PerRunMs, uses, s.Source = e.step(&step, []uuid.UUID{workflowID}, "")
+		if measured, ok := history[hierarchicalID(wf, &step)]; ok && measured.Runs > 0 && executions > 0 {
+			s.PerRunMs = measured.AvgDurationMs
+			s.Source = SourceHistory
+			if requested[i] == 0 {
+				s.Runs = float64(measured.Runs) / float64(executions)
+			}
+			// A device step keeps its device busy for the measured time
+			if step.Type == definition.StepTypeDevice && len(step.OnSuccessWrite) == 0 {
+				uses = []use{{device: step.DeviceID, step: step.Number, ms: s.PerRunMs}}
+			}
+		}
+		s.TotalMs = s.Runs * s.PerRunMs
+		total += s.TotalMs
+
+		for _, u := range uses {
+			e.busy(u.device, u.step, u.ms*s.Runs)
+		}
+		result.Steps = append(result.Steps, s)
+	}
+
+	result.EstimatedMs = int64(math.Round(total))
+	result.Estimated = time.Duration(result.EstimatedMs * int64(time.Millisecond)).String()
+	result.Devices = e.deviceList(total)
+	result.Warnings = e.warnings
+	return result, nil
+}
+
+// loops lists the backward transitions of the top-level steps with the jumps
+// assumed per run, and the extra runs they add to each step by index
+func (e *estimator) loops(wf *definition.Workflow, history map[string]storage.StepDuration, executions int) ([]Loop, []float64) {
+	extra := make([]float64, len(wf.Steps))
+	var loops []Loop
+
+	for i, step := range wf.Steps {
+		for _, t := range step.Transitions {
+			to := wf.StepIndex(t.Goto)
+			if to < 0 || to > i {
+				continue
+			}
+			loop := Loop{From: step.Number, To: t.Goto, MaxJumps: t.MaxJumps, Source: "default"}
+			if jumps, ok := e.opts.Loops[step.Number]; ok {
+				loop.Jumps = float64(jumps)
+				loop.Source = "request"
+				for k := to; k <= i; k++ {
+					extra[k] += loop.Jumps
+				}
+			} else if measured, ok := history[hierarchicalID(wf, &wf.Steps[to])]; ok && executions > 0 {
+				// The loop target runs once plus once per jump
+				loop.Jumps = math.Max(0, float64(measured.Runs)/float64(executions)-1)
+				loop.Source = "history"
+			} else {
+				e.warn("loop %s -> %s is assumed not to be taken, pass its jumps in loops", step.Number, t.Goto)
+			}
+			loops = append(loops, loop)
+			// Later transitions only apply if this one doesn't hold
+			break
+		}
+	}
+	return loops, extra
+}
+
+// step estimates one run of a step from its definition. It returns the
+// duration, the devices kept busy and the source of the estimate.
+func (e *estimator) step(step *definition.Step, visited []uuid.UUID, prefix string) (float64, []use, string) {
+	deviceStep := float64(e.opts.DeviceStep.Microseconds()) / 1000
+	number := prefix + step.Number
+
+	var ms float64
+	var uses []use
+	source := SourceDefinition
+	switch step.Type {
+	case definition.StepTypeWait:
+		wait := step.Timeout.Duration
+		if wait == 0 {
+			wait = defaultWait
+		}
+		ms = float64(wait.Microseconds()) / 1000
+	case definition.StepTypeDevice:
+		ms = deviceStep
+		uses = append(uses, use{device: step.DeviceID, step: number, ms: deviceStep})
+		source = SourceDefault
+	case definition.StepTypeWorkflow:
+		ms, uses = e.subWorkflow(step, visited, prefix)
+	}
+
+	// Post-actions write after the step
+	for _, action := range step.OnSuccessWrite {
+		ms += deviceStep
+		uses = append(uses, use{device: action.DeviceID, step: number, ms: deviceStep})
+		if source == SourceDefinition {
+			source = SourceDefault
+		}
+	}
+	return ms, uses, source
+}
+
+// subWorkflow estimates one run of a called workflow from its steps, each
+// running once
+func (e *estimator) subWorkflow(step *definition.Step, visited []uuid.UUID, prefix string) (float64, []use) {
+	number := prefix + step.Number
+	id, err := uuid.Parse(step.WorkflowID)
+	if err != nil {
+		e.warn("step %s calls an invalid workflow ID", number)
+		return 0, nil
+	}
+	if slices.Contains(visited, id) {
+		e.warn("step %s calls its workflow recursively, not estimated", number)
+		return 0, nil
+	}
+	if len(visited) > maxDepth {
+		e.warn("step %s is nested deeper than %d levels, not estimated", number, maxDepth)
+		return 0, nil
+	}
+
+	workflow, _, err := e.store.LoadWorkflow(e.ctx, id)
+	if err != nil {
+		e.warn("step %s calls an unknown workflow %s", number, id)
+		return 0, nil
+	}
+	wf, err := parse(e.ctx, e.store, workflow)
+	if err != nil {
+		e.warn("step %s: %v", number, err)
+		return 0, nil
+	}
+
+	subPrefix := number + "/" + wf.ProgramName + ":"
+	var total float64
+	var uses []use
+	for i := range wf.Steps {
+		sub := &wf.Steps[i]
+		ms, subUses, _ := e.step(sub, append(slices.Clone(visited), id), subPrefix)
+		total += ms
+		uses = append(uses, subUses...)
+		for _, t := range sub.Transitions {
+			if to := wf.StepIndex(t.Goto); to >= 0 && to <= i {
+				e.warn("loop %s%s -> %s in a sub-workflow is assumed not to be taken", subPrefix, sub.Number, t.Goto)
+				break
+			}
+		}
+	}
+	return total, uses
+}
+
+func (e *estimator) busy(device, step string, ms float64) {
+	if device == "" {
+		return
+	}
+	d, ok := e.devices[device]
+	if !ok {
+		d = &Device{Name: device}
+		e.devices[device] = d
+	}
+	d.BusyMs += ms
+	if !slices.Contains(d.Steps, step) {
+		d.Steps = append(d.Steps, step)
+	}
+}
+
+func (e *estimator) deviceList(total float64) []Device {
+	list := make([]Device, 0, len(e.devices))
+	for _, d := range e.devices {
+		if total > 0 {
+			d.Share = math.Min(1, d.BusyMs/total)
+		}
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BusyMs != list[j].BusyMs {
+			return list[i].BusyMs > list[j].BusyMs
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (e *estimator) warn(format string, args ...any) {
+	e.warnings = append(e.warnings, fmt.Sprintf(format, args...))
+}
+
+func parse(ctx context.Context, store *storage.PostgresClient, workflow *storage.Workflow) (*definition.Workflow, error) {
+	wf, err := definition.ParseWorkflow(workflow.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	// Steps whose template can't be expanded are estimated as written
+	wf.ExpandTemplates(ctx, store.StepTemplateDefinition)
+	return wf, nil
+}
+
+// hierarchicalID is the ID the engine records for a top-level step
+func hierarchicalID(wf *definition.Workflow, step *definition.Step) string {
+	return definition.BuildHierarchicalStepID([]definition.CallFrame{{ProgramName: wf.ProgramName, StepNumber: step.Number}})
+}
+
+func target(step *definition.Step) string {
+	switch step.Type {
+	case definition.StepTypeDevice:
+		return step.DeviceID
+	case definition.StepTypeWorkflow:
+		return step.WorkflowID
+	}
+	return ""
+}
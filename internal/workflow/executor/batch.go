@@ -0,0 +1,268 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+)
+
+// Batch modes
+const (
+	BatchSequence = "sequence" // Items run in order, the first failure stops the batch
+	BatchParallel = "parallel" // Items run unordered, all are attempted
+)
+
+// batchOperations are the operations a batch item may have
+var batchOperations = map[string]bool{
+	"read_register":  true,
+	"read_logical":   true,
+	"write_register": true,
+	"write_logical":  true,
+	"write_bits":     true,
+	"check_quality":  true,
+}
+
+// batchItem is one operation of a batch step
+type batchItem struct {
+	name      string
+	operation string
+	params    map[string]any
+}
+
+// register is the register an item reads in a combined request, empty if
+// it is run on its own
+func (item batchItem) register(device *modbus.Device) string {
+	name, _ := item.params["register"].(string)
+	switch item.operation {
+	case "read_register":
+		return name
+	case "read_logical":
+		return device.IOMapping[name]
+	}
+	return ""
+}
+
+// BatchItem describes an item of a batch step for static checks
+type BatchItem struct {
+	Name      string
+	Operation string
+	Register  string // Empty if not given as string, e.g. from the input
+}
+
+// Writes reports whether the item writes its register
+func (item BatchItem) Writes() bool {
+	return writesRegister(item.Operation)
+}
+
+func writesRegister(operation string) bool {
+	return operation == "write_register" || operation == "write_logical" || operation == "write_bits"
+}
+
+// ParseBatch checks the parameters of a batch step and returns its mode and
+// items in order
+func ParseBatch(params map[string]any) (string, []BatchItem, error) {
+	mode, items, err := parseBatch(params)
+	if err != nil {
+		return "", nil, err
+	}
+	described := make([]BatchItem, len(items))
+	for i, item := range items {
+		register, _ := item.params["register"].(string)
+		described[i] = BatchItem{Name: item.name, Operation: item.operation, Register: register}
+	}
+	return mode, described, nil
+}
+
+func parseBatch(params map[string]any) (string, []batchItem, error) {
+	mode := BatchSequence
+	if m, ok := params["mode"]; ok {
+		mode, _ = m.(string)
+		if mode != BatchSequence && mode != BatchParallel {
+			return "", nil, fmt.Errorf("invalid mode parameter: %v, use sequence or parallel", m)
+		}
+	}
+
+	list, ok := params["items"].([]any)
+	if !ok || len(list) == 0 {
+		return "", nil, fmt.Errorf("missing or invalid items parameter")
+	}
+
+	items := make([]batchItem, 0, len(list))
+	names := make(map[string]bool, len(list))
+	for i, raw := range list {
+		spec, ok := raw.(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("item %d: not an object", i)
+		}
+
+		item := batchItem{params: make(map[string]any, len(spec))}
+		for k, v := range spec {
+			switch k {
+			case "name":
+				item.name, _ = v.(string)
+			case "operation":
+				item.operation, _ = v.(string)
+			default:
+				item.params[k] = v
+			}
+		}
+		if !batchOperations[item.operation] {
+			return "", nil, fmt.Errorf("item %d: unsupported operation %q", i, item.operation)
+		}
+		if item.name == "" {
+			item.name, _ = item.params["register"].(string)
+		}
+		if item.name == "" {
+			return "", nil, fmt.Errorf("item %d: missing name and register", i)
+		}
+		if names[item.name] {
+			return "", nil, fmt.Errorf("item %d: name %s is used twice", i, item.name)
+		}
+		names[item.name] = true
+		items = append(items, item)
+	}
+	return mode, items, nil
+}
+
+// executeBatch runs the items of a batch step as a unit: the device's
+// connection is held for the whole batch, so polls and other writers can't
+// come in between. Adjacent reads are combined into one request. The output
+// has the result of every item under "results" and its value under "value",
+// both by item name.
+func (e *StepExecutor) executeBatch(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
+	mode, items, err := parseBatch(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var writes []string
+	for _, item := range items {
+		if writesRegister(item.operation) {
+			register, _ := item.params["register"].(string)
+			writes = append(writes, register)
+		}
+	}
+
+	results := make(map[string]any, len(items))
+	err = device.Exclusive(ctx, writes, func(ctx context.Context) error {
+		if mode == BatchParallel {
+			return e.runBatchParallel(ctx, device, items, results)
+		}
+		return e.runBatchSequence(ctx, device, items, results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(results))
+	for name, result := range results {
+		values[name] = result.(map[string]any)[ResultValue]
+	}
+	return map[string]any{
+		ResultValue: values,
+		"results":   results,
+		"mode":      mode,
+		"success":   true,
+	}, nil
+}
+
+// runBatchSequence runs the items in order. Runs of adjacent reads are read
+// together just before the first of them.
+func (e *StepExecutor) runBatchSequence(ctx context.Context, device *modbus.Device, items []batchItem, results map[string]any) error {
+	for i := 0; i < len(items); {
+		end := i
+		for end < len(items) && items[end].register(device) != "" {
+			end++
+		}
+		if end-i > 1 {
+			if err := e.readBatchItems(ctx, device, items[i:end], results); err != nil {
+				return err
+			}
+			i = end
+			continue
+		}
+
+		item := items[i]
+		result, err := e.executeOperation(ctx, device, item.operation, item.params)
+		if err != nil {
+			return fmt.Errorf("batch item %s: %w", item.name, err)
+		}
+		results[item.name] = result
+		i++
+	}
+	return nil
+}
+
+// runBatchParallel reads all combinable reads together and runs the other
+// items concurrently. Every item is attempted; the failures are joined.
+func (e *StepExecutor) runBatchParallel(ctx context.Context, device *modbus.Device, items []batchItem, results map[string]any) error {
+	var reads, others []batchItem
+	for _, item := range items {
+		if item.register(device) != "" {
+			reads = append(reads, item)
+		} else {
+			others = append(others, item)
+		}
+	}
+
+	var errs []error
+	if len(reads) > 0 {
+		if err := e.readBatchItems(ctx, device, reads, results); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, item := range others {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := e.executeOperation(ctx, device, item.operation, item.params)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("batch item %s: %w", item.name, err))
+				return
+			}
+			results[item.name] = result
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// readBatchItems reads the registers of read items with as few requests as
+// possible; items the device can't read in a block are run on their own
+func (e *StepExecutor) readBatchItems(ctx context.Context, device *modbus.Device, items []batchItem, results map[string]any) error {
+	registers := make([]string, len(items))
+	for i, item := range items {
+		registers[i] = item.register(device)
+	}
+
+	samples, err := device.ReadSamples(ctx, registers)
+	if err != nil {
+		return fmt.Errorf("batch read: %w", err)
+	}
+
+	for i, item := range items {
+		sample, ok := samples[registers[i]]
+		if !ok {
+			result, err := e.executeOperation(ctx, device, item.operation, item.params)
+			if err != nil {
+				return fmt.Errorf("batch item %s: %w", item.name, err)
+			}
+			results[item.name] = result
+			continue
+		}
+		result := sampleResult(sample)
+		result["register"] = item.params["register"]
+		results[item.name] = result
+	}
+	return nil
+}
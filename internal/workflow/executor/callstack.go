@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxCallDepth bounds sub-workflow nesting without a configured limit
+const DefaultMaxCallDepth = 8
+
+// DefaultMaxLoopJumps bounds jumps back without max_jumps without a
+// configured limit
+const DefaultMaxLoopJumps = 1000
+
+// CallFrame is a workflow on the sub-workflow call path of a step
+type CallFrame struct {
+	WorkflowID  uuid.UUID `json:"workflow_id"`
+	ProgramName string    `json:"program_name,omitempty"`
+	Step        string    `json:"step,omitempty"`        // Step calling the next frame
+	StepNumber  string    `json:"step_number,omitempty"` // Number of the step running in this frame
+}
+
+func (f CallFrame) String() string {
+	name := f.WorkflowID.String()
+	if f.ProgramName != "" {
+		name = f.ProgramName + " (" + name + ")"
+	}
+	if f.Step != "" {
+		name += " step " + f.Step
+	}
+	return name
+}
+
+// CallDepthError fails a workflow step whose sub-workflow would nest deeper
+// than the maximum call depth, e.g. a recursion the validator did not see
+type CallDepthError struct {
+	MaxDepth  int         `json:"max_depth"`
+	Path      []CallFrame `json:"path"`      // From the root workflow to the rejected sub-workflow
+	Recursive bool        `json:"recursive"` // The rejected sub-workflow is already on the path
+}
+
+func (e *CallDepthError) Error() string {
+	parts := make([]string, 0, len(e.Path))
+	for _, frame := range e.Path {
+		parts = append(parts, frame.String())
+	}
+	reason := "maximum sub-workflow depth"
+	if e.Recursive {
+		reason = "recursive sub-workflow exceeds maximum depth"
+	}
+	return fmt.Sprintf("%s %d reached: %s", reason, e.MaxDepth, strings.Join(parts, " -> "))
+}
+
+type callStackKey struct{}
+
+// WithCallFrame pushes a workflow onto the call path of ctx. The engine
+// pushes the root workflow of an execution.
+func WithCallFrame(ctx context.Context, frame CallFrame) context.Context {
+	path := callPath(ctx)
+	return context.WithValue(ctx, callStackKey{}, append(slices.Clip(path), frame))
+}
+
+// PositionFunc is told the call path each time a sub-workflow step starts;
+// the StepNumber of the last frame is the starting step
+type PositionFunc func(ctx context.Context, path []CallFrame)
+
+type positionKey struct{}
+
+// WithPositionFunc reports the sub-workflow steps run with ctx to fn. The
+// engine uses it to track the position of an execution in nested
+// sub-workflows.
+func WithPositionFunc(ctx context.Context, fn PositionFunc) context.Context {
+	return context.WithValue(ctx, positionKey{}, fn)
+}
+
+// reportPosition tells the position func of ctx, if any, that a sub-workflow
+// step starts
+func reportPosition(ctx context.Context, stepNumber string) {
+	fn, _ := ctx.Value(positionKey{}).(PositionFunc)
+	path := callPath(ctx)
+	if fn == nil || len(path) == 0 {
+		return
+	}
+	path = slices.Clone(path)
+	path[len(path)-1].StepNumber = stepNumber
+	fn(ctx, path)
+}
+
+// callPath returns the call path of ctx, root workflow first
+func callPath(ctx context.Context) []CallFrame {
+	path, _ := ctx.Value(callStackKey{}).([]CallFrame)
+	return path
+}
+
+// SetMaxCallDepth bounds how deep sub-workflows nest at runtime, 0 =
+// DefaultMaxCallDepth
+func (e *StepExecutor) SetMaxCallDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultMaxCallDepth
+	}
+	e.maxCallDepth = depth
+}
+
+// SetMaxLoopJumps bounds how often a transition without max_jumps may jump
+// back per execution, 0 = DefaultMaxLoopJumps
+func (e *StepExecutor) SetMaxLoopJumps(jumps int) {
+	if jumps <= 0 {
+		jumps = DefaultMaxLoopJumps
+	}
+	e.maxLoopJumps = jumps
+}
+
+// MaxLoopJumps returns the limit of jumps back without max_jumps
+func (e *StepExecutor) MaxLoopJumps() int {
+	return e.maxLoopJumps
+}
+
+// enterSubWorkflow records the calling step on the call path and pushes the
+// sub-workflow. It fails if the sub-workflow would exceed the maximum depth.
+func (e *StepExecutor) enterSubWorkflow(ctx context.Context, step *definition.Step, workflowID uuid.UUID, programName string) (context.Context, error) {
+	path := slices.Clone(callPath(ctx))
+	if len(path) > 0 {
+		path[len(path)-1].Step = step.Name
+		path[len(path)-1].StepNumber = step.Number
+	} else {
+		// Step run outside an execution
+		path = append(path, CallFrame{Step: step.Name, StepNumber: step.Number})
+	}
+	path = append(path, CallFrame{WorkflowID: workflowID, ProgramName: programName})
+
+	// The root workflow is depth 0
+	if depth := len(path) - 1; depth > e.maxCallDepth {
+		recursive := slices.ContainsFunc(path[:len(path)-1], func(frame CallFrame) bool {
+			return frame.WorkflowID == workflowID
+		})
+		return ctx, &CallDepthError{MaxDepth: e.maxCallDepth, Path: path, Recursive: recursive}
+	}
+	return context.WithValue(ctx, callStackKey{}, path), nil
+}
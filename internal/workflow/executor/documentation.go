@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+)
+
+// DocumentationLink is the datasheet section a step refers to, resolved for
+// the module providing the step's register
+type DocumentationLink struct {
+	DeviceID string `json:"device_id"`
+	Module   string `json:"module"`
+	URL      string `json:"url"` // Datasheet with page or anchor fragment
+	Page     int    `json:"page,omitempty"`
+	Anchor   string `json:"anchor,omitempty"`
+	Label    string `json:"label,omitempty"`
+}
+
+// Documentation resolves the documentation reference of a step. It returns
+// nil if the step has none or the module has no datasheet.
+func (e *StepExecutor) Documentation(step *definition.Step) *DocumentationLink {
+	ref := step.Documentation
+	if ref == nil {
+		return nil
+	}
+
+	deviceID := ref.DeviceID
+	if deviceID == "" {
+		deviceID = step.DeviceID
+	}
+	device, exists := e.deviceManager.GetDeviceByName(deviceID)
+	if !exists {
+		return nil
+	}
+
+	register := ref.Register
+	if register == "" {
+		register, _ = step.Parameters["register"].(string)
+	}
+	if current, ok := device.Profile.Aliases[register]; ok {
+		register = current
+	}
+	if mapped, ok := device.IOMapping[register]; ok {
+		register = mapped
+	}
+
+	doc := device.Profile.DocumentationFor(register)
+	if doc == nil || doc.Datasheet == "" {
+		return nil
+	}
+
+	return &DocumentationLink{
+		DeviceID: deviceID,
+		Module:   doc.Module,
+		URL:      datasheetURL(doc.Datasheet, ref),
+		Page:     ref.Page,
+		Anchor:   ref.Anchor,
+		Label:    ref.Label,
+	}
+}
+
+// datasheetURL appends the page or named destination as PDF open parameter
+// unless the datasheet URL already has a fragment
+func datasheetURL(datasheet string, ref *definition.DocumentationRef) string {
+	if strings.Contains(datasheet, "#") {
+		return datasheet
+	}
+	switch {
+	case ref.Anchor != "":
+		return datasheet + "#nameddest=" + url.QueryEscape(ref.Anchor)
+	case ref.Page > 0:
+		return fmt.Sprintf("%s#page=%d", datasheet, ref.Page)
+	}
+	return datasheet
+}
@@ -2,7 +2,11 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/devices"
@@ -10,38 +14,140 @@ import (
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
+// postActionTimeout bounds a single post-action write
+const postActionTimeout = 5 * time.Second
+
 type StepExecutor struct {
 	deviceManager *devices.Manager
 	storage       *storage.PostgresClient // NEU für Sub-Workflow Laden
+	resources     *ResourceLocks
+	hooks         []StepHook // Consulted before and after steps
+	maxCallDepth  int        // Deepest sub-workflow nesting at runtime
+	maxLoopJumps  int        // Jumps back per transition without max_jumps
 }
 
 func NewStepExecutor(dm *devices.Manager, storage *storage.PostgresClient) *StepExecutor {
 	return &StepExecutor{
 		deviceManager: dm,
 		storage:       storage,
+		resources:     NewResourceLocks(),
+		maxCallDepth:  DefaultMaxCallDepth,
+		maxLoopJumps:  DefaultMaxLoopJumps,
 	}
 }
 
+// Resources returns the locks of the steps' resource tags
+func (e *StepExecutor) Resources() *ResourceLocks {
+	return e.resources
+}
+
 func (e *StepExecutor) Execute(ctx context.Context, step *definition.Step, input map[string]any) (map[string]any, error) {
+	return e.ExecuteInScope(ctx, step, &definition.Scope{Input: input})
+}
+
+// ExecuteInScope runs a step with access to the variables and step results of
+// its workflow, which sub-workflow input mappings can reference. The step's
+// post-actions run afterwards; a failed on_success_write fails the step.
+// The step's resources are held until its post-actions are written. Step
+// hooks can deny the step before it runs and after it succeeded, and each
+// post-action write before it is made.
+func (e *StepExecutor) ExecuteInScope(ctx context.Context, step *definition.Step, scope *definition.Scope) (map[string]any, error) {
+	if len(step.Resources) > 0 {
+		release, err := e.resources.Acquire(ctx, step.Name, step.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("waiting for resources: %w", err)
+		}
+		defer release()
+	}
+
+	if err := e.runHooks(ctx, HookPhasePre, step, scope.Input, nil); err != nil {
+		return nil, err
+	}
+
+	output, err := e.executeInScope(ctx, step, scope)
+	if err == nil {
+		err = e.runHooks(ctx, HookPhasePost, step, scope.Input, output)
+	}
+	if err != nil {
+		// A cancelled execution drives its outputs to safe states instead
+		if ctx.Err() == nil {
+			if postErr := e.runPostActions(ctx, step, "on_failure_write", step.OnFailureWrite, scope.Input); postErr != nil {
+				err = errors.Join(err, fmt.Errorf("on_failure_write: %w", postErr))
+			}
+		}
+		return nil, err
+	}
+
+	if err := e.runPostActions(ctx, step, "on_success_write", step.OnSuccessWrite, scope.Input); err != nil {
+		return nil, fmt.Errorf("on_success_write: %w", err)
+	}
+	return output, nil
+}
+
+func (e *StepExecutor) executeInScope(ctx context.Context, step *definition.Step, scope *definition.Scope) (map[string]any, error) {
 	switch step.Type {
 	case definition.StepTypeDevice:
-		return e.executeDeviceStep(ctx, step, input)
+		return e.executeDeviceStep(ctx, step, scope.Input)
 	case definition.StepTypeWorkflow:
-		return e.executeWorkflowStep(ctx, step, input) // NEU
+		return e.executeWorkflowStep(ctx, step, scope)
 	case definition.StepTypeWait:
-		return e.executeWaitStep(ctx, step, input)
+		return e.executeWaitStep(ctx, step, scope.Input)
+	case definition.StepTypeSample:
+		return e.executeSampleStep(ctx, step)
 	default:
 		return nil, fmt.Errorf("unsupported step type: %s", step.Type)
 	}
 }
 
+// runPostActions writes the post-action values in order and stops at the
+// first failure. kind names the post-actions for the pre hooks.
+func (e *StepExecutor) runPostActions(ctx context.Context, step *definition.Step, kind string, actions []definition.PostAction, input map[string]any) error {
+	logger := loggerFrom(ctx)
+	for _, action := range actions {
+		device, exists := e.deviceManager.GetDeviceByName(action.DeviceID)
+		if !exists {
+			return fmt.Errorf("device not found: %s", action.DeviceID)
+		}
+
+		_, mapped := device.IOMapping[action.Register]
+		write := writeStep(step.Name+" "+kind, action.DeviceID, action.Register, action.Value, mapped)
+		if err := e.runHooks(ctx, HookPhasePre, write, input, nil); err != nil {
+			return fmt.Errorf("write %s.%s: %w", action.DeviceID, action.Register, err)
+		}
+
+		writeCtx, cancel := context.WithTimeout(ctx, postActionTimeout)
+		var err error
+		if mapped {
+			err = device.WriteLogical(writeCtx, action.Register, action.Value)
+		} else {
+			err = device.WriteRegister(writeCtx, action.Register, action.Value)
+		}
+		cancel()
+		if err != nil {
+			logger.Warn("Post-action write failed",
+				zap.String("device", action.DeviceID),
+				zap.String("register", action.Register),
+				zap.Error(err))
+			return fmt.Errorf("write %s.%s: %w", action.DeviceID, action.Register, err)
+		}
+		logger.Debug("Post-action written",
+			zap.String("device", action.DeviceID),
+			zap.String("register", action.Register),
+			zap.Any("value", action.Value))
+	}
+	return nil
+}
+
 func (e *StepExecutor) executeDeviceStep(ctx context.Context, step *definition.Step, input map[string]any) (map[string]any, error) {
 	if step.Timeout.Duration > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, step.Timeout.Duration)
 		defer cancel()
+		// The step timeout takes precedence over the device timeout
+		ctx = modbus.WithOperationTimeout(ctx, step.Timeout.Duration)
 	}
 
 	// Get device by name (instance_id)
@@ -59,12 +165,29 @@ func (e *StepExecutor) executeDeviceStep(ctx context.Context, step *definition.S
 		params[k] = v
 	}
 
+	// Rejected writes are reported with the execution
+	if executionID, ok := ctx.Value(ownerKey{}).(uuid.UUID); ok {
+		ctx = modbus.WithWriter(ctx, "execution:"+executionID.String())
+	}
+
 	// Execute operation based on type
+	ctx = traceFrames(ctx, device)
+	started := time.Now()
 	result, err := e.executeOperation(ctx, device, step.Operation, params)
+	elapsed := time.Since(started)
 	if err != nil {
+		loggerFrom(ctx).Warn("Device operation failed",
+			zap.String("device", step.DeviceID),
+			zap.String("operation", step.Operation),
+			zap.Int64("duration_ms", elapsed.Milliseconds()),
+			zap.Error(err))
 		return nil, fmt.Errorf("device operation failed: %w", err)
 	}
 
+	loggerFrom(ctx).Debug("Device operation completed",
+		zap.String("device", step.DeviceID),
+		zap.String("operation", step.Operation),
+		zap.Int64("duration_ms", elapsed.Milliseconds()))
 	return result, nil
 }
 
@@ -82,6 +205,12 @@ func (e *StepExecutor) executeOperation(ctx context.Context, device *modbus.Devi
 		return e.executeReadRegister(ctx, device, params)
 	case "write_register":
 		return e.executeWriteRegister(ctx, device, params)
+	case "write_bits":
+		return e.executeWriteBits(ctx, device, params)
+	case "check_quality":
+		return e.executeCheckQuality(device, params)
+	case "batch":
+		return e.executeBatch(ctx, device, params)
 	default:
 		return nil, fmt.Errorf("unsupported operation: %s", operation)
 	}
@@ -105,7 +234,7 @@ func (e *StepExecutor) executeRead(ctx context.Context, device *modbus.Device, p
 
 	unitID := uint8(device.Profile.Connection.UnitID)
 
-	var values interface{}
+	var values []uint16
 	var err error
 
 	switch registerType {
@@ -121,9 +250,13 @@ func (e *StepExecutor) executeRead(ctx context.Context, device *modbus.Device, p
 		return nil, err
 	}
 
-	return map[string]any{
-		"values": values,
-	}, nil
+	// A single register is a scalar value, more are a list
+	result := sampleResult(modbus.Sample{Value: values, Raw: values, Timestamp: time.Now()})
+	if len(values) == 1 {
+		result[ResultValue] = values[0]
+	}
+	result["values"] = values
+	return result, nil
 }
 
 func (e *StepExecutor) executeWrite(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
@@ -153,11 +286,24 @@ func (e *StepExecutor) executeWrite(ctx context.Context, device *modbus.Device,
 		return nil, err
 	}
 
-	return map[string]any{
-		"success": true,
-		"address": uint16(address),
-		"value":   uint16(value),
-	}, nil
+	result := sampleResult(modbus.Sample{Value: uint16(value), Raw: []uint16{uint16(value)}, Timestamp: time.Now()})
+	result["success"] = true
+	result["address"] = uint16(address)
+
+	if verify, _ := params["verify"].(bool); verify {
+		values, err := device.Client.ReadHoldingRegisters(ctx, unitID, uint16(address), 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify write: %w", err)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("failed to verify write: empty response")
+		}
+		if values[0] != uint16(value) {
+			return nil, &modbus.VerifyError{Register: fmt.Sprintf("holding@%d", uint16(address)), Written: uint16(value), ReadBack: values[0]}
+		}
+		result["verified"] = true
+	}
+	return result, nil
 }
 
 func (e *StepExecutor) executeReadRegister(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
@@ -166,15 +312,14 @@ func (e *StepExecutor) executeReadRegister(ctx context.Context, device *modbus.D
 		return nil, fmt.Errorf("missing or invalid register parameter")
 	}
 
-	value, err := device.ReadRegister(ctx, register)
+	sample, err := device.ReadSample(ctx, register)
 	if err != nil {
 		return nil, err
 	}
 
-	return map[string]any{
-		"register": register,
-		"value":    value,
-	}, nil
+	result := sampleResult(sample)
+	result["register"] = register
+	return result, nil
 }
 
 func (e *StepExecutor) executeWriteRegister(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
@@ -183,20 +328,78 @@ func (e *StepExecutor) executeWriteRegister(ctx context.Context, device *modbus.
 		return nil, fmt.Errorf("missing or invalid register parameter")
 	}
 
-	value, ok := params["value"]
+	requested, ok := params["value"]
 	if !ok {
 		return nil, fmt.Errorf("missing value parameter")
 	}
+	value, err := device.ResolveValue(register, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	sample, err := device.WriteSample(ctx, register, value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := writeResult(register, sample, requested)
+	if err := verifyWrite(ctx, device, register, sample, params, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// executeWriteBits sets the masked bits of a register word in one
+// serialized read-modify-write, leaving the other bits untouched
+func (e *StepExecutor) executeWriteBits(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
+	register, ok := params["register"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid register parameter")
+	}
+
+	mask, err := wordParam(params, "mask")
+	if err != nil {
+		return nil, err
+	}
+	bits, err := wordParam(params, "value")
+	if err != nil {
+		return nil, err
+	}
 
-	if err := device.WriteRegister(ctx, register, value); err != nil {
+	previous, word, err := device.WriteBits(ctx, register, mask, bits)
+	if err != nil {
 		return nil, err
 	}
 
-	return map[string]any{
-		"register": register,
-		"value":    value,
-		"success":  true,
-	}, nil
+	result := sampleResult(modbus.Sample{Value: bits & mask, Raw: []uint16{word}, Timestamp: time.Now()})
+	result["register"] = register
+	result["mask"] = mask
+	result["previous"] = previous
+	result["word"] = word
+	result["success"] = true
+	return result, nil
+}
+
+// wordParam reads a 16-bit parameter given as number or as "0x00ff" or
+// "0b0101" string
+func wordParam(params map[string]any, name string) (uint16, error) {
+	switch v := params[name].(type) {
+	case float64:
+		if v < 0 || v > 0xffff || v != float64(uint16(v)) {
+			return 0, fmt.Errorf("invalid %s parameter: %v is not a 16-bit word", name, v)
+		}
+		return uint16(v), nil
+	case string:
+		n, err := strconv.ParseUint(strings.ReplaceAll(v, "_", ""), 0, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s parameter: %q is not a 16-bit word", name, v)
+		}
+		return uint16(n), nil
+	case nil:
+		return 0, fmt.Errorf("missing %s parameter", name)
+	default:
+		return 0, fmt.Errorf("invalid %s parameter: %T", name, v)
+	}
 }
 
 func (e *StepExecutor) executeReadLogical(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
@@ -205,15 +408,66 @@ func (e *StepExecutor) executeReadLogical(ctx context.Context, device *modbus.De
 		return nil, fmt.Errorf("missing or invalid register parameter")
 	}
 
-	value, err := device.ReadLogical(ctx, register)
+	sample, err := device.ReadLogicalSample(ctx, register)
 	if err != nil {
 		return nil, err
 	}
 
-	return map[string]any{
-		"register": register,
-		"value":    value,
-	}, nil
+	result := sampleResult(sample)
+	result["register"] = register
+	return result, nil
+}
+
+// executeCheckQuality asserts on the cached value of a register without
+// reading the device: the step fails unless the quality is one of "quality"
+// (default "good") and the value is not older than "max_age"
+func (e *StepExecutor) executeCheckQuality(device *modbus.Device, params map[string]any) (map[string]any, error) {
+	register, ok := params["register"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid register parameter")
+	}
+
+	accepted := []string{QualityGood}
+	switch q := params["quality"].(type) {
+	case nil:
+	case string:
+		accepted = []string{q}
+	case []any:
+		accepted = accepted[:0]
+		for _, item := range q {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid quality parameter: %v", item)
+			}
+			accepted = append(accepted, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid quality parameter: %v", q)
+	}
+
+	var maxAge time.Duration
+	if s, ok := params["max_age"].(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age parameter: %w", err)
+		}
+		maxAge = d
+	}
+
+	sample, ok := device.LastLogicalSample(register)
+	if !ok {
+		return nil, fmt.Errorf("register %s has no cached value", register)
+	}
+	if !slices.Contains(accepted, sample.Quality) {
+		return nil, fmt.Errorf("register %s has quality %s, expected %s", register, sample.Quality, strings.Join(accepted, " or "))
+	}
+	if age := time.Since(sample.Timestamp); maxAge > 0 && age > maxAge {
+		return nil, fmt.Errorf("register %s value is %s old, max_age is %s", register, age.Round(time.Millisecond), maxAge)
+	}
+
+	result := sampleResult(sample)
+	result["register"] = register
+	return result, nil
 }
 
 func (e *StepExecutor) executeWriteLogical(ctx context.Context, device *modbus.Device, params map[string]any) (map[string]any, error) {
@@ -222,20 +476,54 @@ func (e *StepExecutor) executeWriteLogical(ctx context.Context, device *modbus.D
 		return nil, fmt.Errorf("missing or invalid register parameter")
 	}
 
-	value, ok := params["value"]
+	requested, ok := params["value"]
 	if !ok {
 		return nil, fmt.Errorf("missing value parameter")
 	}
+	value, err := device.ResolveValue(register, requested)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := device.WriteLogical(ctx, register, value); err != nil {
+	sample, err := device.WriteLogicalSample(ctx, register, value)
+	if err != nil {
 		return nil, err
 	}
 
-	return map[string]any{
-		"register": register,
-		"value":    value,
-		"success":  true,
-	}, nil
+	result := writeResult(register, sample, requested)
+	if err := verifyWrite(ctx, device, register, sample, params, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeResult is the output of a register write. Values given with a unit,
+// e.g. "2.5 bar", are reported with the converted value written.
+func writeResult(register string, sample modbus.Sample, requested any) map[string]any {
+	result := sampleResult(sample)
+	result["register"] = register
+	result["success"] = true
+	if _, ok := requested.(string); ok {
+		result["requested"] = requested
+	}
+	return result
+}
+
+// verifyWrite reads a written register back if the step sets "verify".
+// Scaled values may deviate by "tolerance" in engineering units.
+func verifyWrite(ctx context.Context, device *modbus.Device, register string, written modbus.Sample, params map[string]any, result map[string]any) error {
+	if verify, _ := params["verify"].(bool); !verify {
+		return nil
+	}
+	tolerance, _ := params["tolerance"].(float64)
+
+	readBack, err := device.VerifyWrite(ctx, register, written, tolerance)
+	if err != nil {
+		return err
+	}
+	result["verified"] = true
+	result["read_back"] = readBack.Value
+	return nil
 }
 
 func (e *StepExecutor) executeWaitStep(ctx context.Context, step *definition.Step, input map[string]any) (map[string]any, error) {
@@ -244,6 +532,8 @@ func (e *StepExecutor) executeWaitStep(ctx context.Context, step *definition.Ste
 		duration = 1 * time.Second
 	}
 
+	loggerFrom(ctx).Debug("Waiting", zap.Duration("duration", duration))
+
 	select {
 	case <-time.After(duration):
 		return input, nil
@@ -252,7 +542,7 @@ func (e *StepExecutor) executeWaitStep(ctx context.Context, step *definition.Ste
 	}
 }
 
-func (e *StepExecutor) executeWorkflowStep(ctx context.Context, step *definition.Step, input map[string]any) (map[string]any, error) {
+func (e *StepExecutor) executeWorkflowStep(ctx context.Context, step *definition.Step, scope *definition.Scope) (map[string]any, error) {
 	if step.Timeout.Duration > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, step.Timeout.Duration)
@@ -276,16 +566,69 @@ func (e *StepExecutor) executeWorkflowStep(ctx context.Context, step *definition
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse sub-workflow: %w", err)
 	}
+	ctx, err = e.enterSubWorkflow(ctx, step, workflowID, subWorkflow.ProgramName)
+	if err != nil {
+		return nil, err
+	}
+	if errs := subWorkflow.ExpandTemplates(ctx, e.storage.StepTemplateDefinition); len(errs) > 0 {
+		return nil, fmt.Errorf("failed to expand sub-workflow step templates: %w", errs[0])
+	}
+
+	// Without an input mapping the sub-workflow sees the raw parent input
+	input := scope.Input
+	if step.InputMapping != nil {
+		input, err = scope.ResolveMapping(step.InputMapping)
+		if err != nil {
+			return nil, err
+		}
+		var inputErrs []definition.InputError
+		input, inputErrs = subWorkflow.ApplyInputSchema(input)
+		if len(inputErrs) > 0 {
+			return nil, fmt.Errorf("sub-workflow input invalid: %s: %s", inputErrs[0].Field, inputErrs[0].Message)
+		}
+	}
 
-	// Execute all steps of sub-workflow
-	stepInput := input
-	for i, subStep := range subWorkflow.Steps {
-		result, err := e.Execute(ctx, &subStep, stepInput)
+	logger := loggerFrom(ctx)
+	logger.Debug("Sub-workflow started",
+		zap.String("workflow_id", step.WorkflowID),
+		zap.Int("steps", len(subWorkflow.Steps)))
+
+	// Execute all steps of sub-workflow. Every step sees the sub-workflow
+	// input; results are chained through the steps scope.
+	results := make(map[string]map[string]any, len(subWorkflow.Steps))
+	last := input
+	jumps := definition.Jumps{}
+	for i := 0; i < len(subWorkflow.Steps); {
+		subStep := subWorkflow.Steps[i]
+		subScope := &definition.Scope{Input: input, Variables: subWorkflow.Variables, Steps: results}
+		subCtx := WithLogger(ctx, logger.With(zap.String("sub_step", subStep.Name)))
+		reportPosition(subCtx, subStep.Number)
+		result, err := e.ExecuteInScope(subCtx, &subStep, subScope)
 		if err != nil {
 			return nil, fmt.Errorf("sub-workflow step %d (%s) failed: %w", i, subStep.Name, err)
 		}
-		stepInput = result
+		results[subStep.Number] = result
+		last = result
+
+		next, err := subWorkflow.NextStep(i, subScope, jumps, e.maxLoopJumps)
+		if err != nil {
+			return nil, fmt.Errorf("sub-workflow step %d (%s) failed: %w", i, subStep.Name, err)
+		}
+		if next <= i && ctx.Err() != nil {
+			// Loops end with the step's context
+			return nil, ctx.Err()
+		}
+		i = next
+	}
+
+	if step.OutputMapping == nil {
+		return last, nil
 	}
 
-	return stepInput, nil
+	// Map declared sub-workflow outputs (or the last result) into the parent
+	subOutput := last
+	if len(subWorkflow.Outputs) > 0 {
+		subOutput, _ = subWorkflow.ResolveOutputs(results)
+	}
+	return definition.MapOutputs(step.OutputMapping, subOutput)
 }
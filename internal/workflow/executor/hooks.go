@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Step hook phases
+const (
+	HookPhasePre  = "pre"  // Before the step runs
+	HookPhasePost = "post" // After the step succeeded, before its post-actions
+)
+
+// HookRequest is the step context sent to a step hook
+type HookRequest struct {
+	Hook        string           `json:"hook"`
+	Phase       string           `json:"phase"`
+	ExecutionID uuid.UUID        `json:"execution_id,omitempty"`
+	Step        *definition.Step `json:"step"`
+	Input       map[string]any   `json:"input,omitempty"`
+	Output      map[string]any   `json:"output,omitempty"` // Post hooks only
+}
+
+// HookDecision is the answer of a step hook
+type HookDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// StepHook lets an external system, e.g. an interlock server, veto steps
+type StepHook interface {
+	Name() string
+	Matches(phase string, step *definition.Step) bool
+	Call(ctx context.Context, req HookRequest) (HookDecision, error)
+}
+
+// HookDeniedError fails a step vetoed by a step hook
+type HookDeniedError struct {
+	Hook   string `json:"hook"`
+	Phase  string `json:"phase"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e *HookDeniedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("step denied by %s hook %s", e.Phase, e.Hook)
+	}
+	return fmt.Sprintf("step denied by %s hook %s: %s", e.Phase, e.Hook, e.Reason)
+}
+
+// SetHooks installs the hooks consulted before and after steps
+func (e *StepExecutor) SetHooks(hooks []StepHook) {
+	e.hooks = hooks
+}
+
+// writeStep describes a write the executor makes besides the steps' own
+// operations, i.e. post-actions and safe states, as a device step for the pre
+// hooks. Hooks filtering by device and operation see it like a write step.
+func writeStep(name, deviceID, register string, value any, logical bool) *definition.Step {
+	operation := "write_register"
+	if logical {
+		operation = "write_logical"
+	}
+	return &definition.Step{
+		Name:       name,
+		Type:       definition.StepTypeDevice,
+		DeviceID:   deviceID,
+		Operation:  operation,
+		Parameters: map[string]any{"register": register, "value": value},
+	}
+}
+
+// runHooks calls the matching hooks of a phase in order; the first denial or
+// failed call stops the step
+func (e *StepExecutor) runHooks(ctx context.Context, phase string, step *definition.Step, input, output map[string]any) error {
+	executionID, _ := ctx.Value(ownerKey{}).(uuid.UUID)
+	logger := loggerFrom(ctx)
+
+	for _, hook := range e.hooks {
+		if !hook.Matches(phase, step) {
+			continue
+		}
+
+		decision, err := hook.Call(ctx, HookRequest{
+			Hook:        hook.Name(),
+			Phase:       phase,
+			ExecutionID: executionID,
+			Step:        step,
+			Input:       input,
+			Output:      output,
+		})
+		if err != nil {
+			logger.Warn("Step hook failed",
+				zap.String("hook", hook.Name()),
+				zap.String("phase", phase),
+				zap.Error(err))
+			return fmt.Errorf("%s hook %s: %w", phase, hook.Name(), err)
+		}
+		if !decision.Allow {
+			logger.Warn("Step denied by hook",
+				zap.String("hook", hook.Name()),
+				zap.String("phase", phase),
+				zap.String("reason", decision.Reason))
+			return &HookDeniedError{Hook: hook.Name(), Phase: phase, Reason: decision.Reason}
+		}
+		logger.Debug("Step allowed by hook",
+			zap.String("hook", hook.Name()),
+			zap.String("phase", phase))
+	}
+	return nil
+}
+
+// notifyHooks calls the matching hooks of a phase for writes that may not be
+// vetoed, e.g. safe states. Every hook is called; denials and failed calls
+// are only logged.
+func (e *StepExecutor) notifyHooks(ctx context.Context, phase string, step *definition.Step, output map[string]any) {
+	executionID, _ := ctx.Value(ownerKey{}).(uuid.UUID)
+	logger := loggerFrom(ctx)
+
+	for _, hook := range e.hooks {
+		if !hook.Matches(phase, step) {
+			continue
+		}
+
+		decision, err := hook.Call(ctx, HookRequest{
+			Hook:        hook.Name(),
+			Phase:       phase,
+			ExecutionID: executionID,
+			Step:        step,
+			Output:      output,
+		})
+		if err != nil {
+			logger.Warn("Step hook failed, write not vetoable",
+				zap.String("hook", hook.Name()),
+				zap.String("phase", phase),
+				zap.String("step_name", step.Name),
+				zap.Error(err))
+			continue
+		}
+		if !decision.Allow {
+			logger.Warn("Step hook denied a write that can't be vetoed",
+				zap.String("hook", hook.Name()),
+				zap.String("phase", phase),
+				zap.String("step_name", step.Name),
+				zap.String("reason", decision.Reason))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package executor
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// WithLogger attaches the logger of an execution step to ctx. The executor
+// logs device errors, post-action writes and timings there.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the step logger of ctx, a no-op logger without one
+func loggerFrom(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.NewNop()
+}
@@ -0,0 +1,179 @@
+package executor
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ResourceHolder is the execution step holding a resource
+type ResourceHolder struct {
+	ExecutionID uuid.UUID `json:"execution_id"`
+	StepName    string    `json:"step_name"`
+	Since       time.Time `json:"since"`
+}
+
+// ResourceState describes a resource tag at a point in time
+type ResourceState struct {
+	Name         string          `json:"name"`
+	Held         bool            `json:"held"`
+	Holder       *ResourceHolder `json:"holder,omitempty"`
+	Waiting      int             `json:"waiting"`
+	Acquisitions int64           `json:"acquisitions"`
+	LastReleased *time.Time      `json:"last_released,omitempty"`
+}
+
+type resource struct {
+	holder       *ResourceHolder
+	depth        int // Nested holds of the holding execution (sub-workflow steps)
+	waiting      int
+	acquisitions int64
+	lastReleased *time.Time
+	released     chan struct{} // Closed and replaced on every release
+}
+
+// ResourceLocks serializes steps carrying the same resource tag across
+// executions. A resource is held by an execution, so sub-workflow steps of
+// the holding step may tag the same resource again.
+type ResourceLocks struct {
+	mu        sync.Mutex
+	resources map[string]*resource
+}
+
+func NewResourceLocks() *ResourceLocks {
+	return &ResourceLocks{resources: make(map[string]*resource)}
+}
+
+type ownerKey struct{}
+
+// WithExecution marks ctx as running on behalf of an execution, the owner of
+// the resources its steps acquire
+func WithExecution(ctx context.Context, executionID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ownerKey{}, executionID)
+}
+
+// Acquire waits until all resources are free or held by the execution of ctx
+// and holds them. Resources are taken in name order, so steps with
+// overlapping tags cannot deadlock. The returned function releases them.
+func (l *ResourceLocks) Acquire(ctx context.Context, stepName string, names []string) (func(), error) {
+	owner, _ := ctx.Value(ownerKey{}).(uuid.UUID)
+	if owner == uuid.Nil {
+		owner = uuid.New() // Step run outside an execution
+	}
+
+	names = slices.Clone(names)
+	sort.Strings(names)
+	names = slices.Compact(names)
+
+	acquired := make([]string, 0, len(names))
+	release := func() {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			l.release(acquired[i])
+		}
+	}
+
+	for _, name := range names {
+		if err := l.acquire(ctx, owner, stepName, name); err != nil {
+			release()
+			return nil, err
+		}
+		acquired = append(acquired, name)
+	}
+	return release, nil
+}
+
+func (l *ResourceLocks) acquire(ctx context.Context, owner uuid.UUID, stepName, name string) error {
+	logged := false
+	for {
+		l.mu.Lock()
+		r, ok := l.resources[name]
+		if !ok {
+			r = &resource{released: make(chan struct{})}
+			l.resources[name] = r
+		}
+		if r.holder == nil || r.holder.ExecutionID == owner {
+			if r.holder == nil {
+				r.holder = &ResourceHolder{ExecutionID: owner, StepName: stepName, Since: time.Now()}
+				r.acquisitions++
+			}
+			r.depth++
+			l.mu.Unlock()
+			return nil
+		}
+		holder := *r.holder
+		released := r.released
+		r.waiting++
+		l.mu.Unlock()
+
+		if !logged {
+			loggerFrom(ctx).Info("Waiting for resource",
+				zap.String("resource", name),
+				zap.String("held_by", holder.ExecutionID.String()),
+				zap.String("held_by_step", holder.StepName))
+			logged = true
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+		}
+
+		l.mu.Lock()
+		r.waiting--
+		l.mu.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *ResourceLocks) release(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r, ok := l.resources[name]
+	if !ok || r.holder == nil {
+		return
+	}
+	r.depth--
+	if r.depth > 0 {
+		return
+	}
+
+	now := time.Now()
+	r.holder = nil
+	r.lastReleased = &now
+	close(r.released)
+	r.released = make(chan struct{})
+}
+
+// List returns all resources used since startup, ordered by name
+func (l *ResourceLocks) List() []ResourceState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]ResourceState, 0, len(l.resources))
+	for name, r := range l.resources {
+		state := ResourceState{
+			Name:         name,
+			Held:         r.holder != nil,
+			Waiting:      r.waiting,
+			Acquisitions: r.acquisitions,
+			LastReleased: r.lastReleased,
+		}
+		if r.holder != nil {
+			holder := *r.holder
+			state.Holder = &holder
+		}
+		list = append(list, state)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
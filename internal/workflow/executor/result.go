@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+)
+
+// Quality of a step result value, see modbus.Quality*
+const (
+	QualityGood  = modbus.QualityGood
+	QualityStale = modbus.QualityStale
+	QualityBad   = modbus.QualityBad
+)
+
+// Normalized device step result fields. Every device operation returns
+// them, so templates can use $steps.<n>.value regardless of the operation.
+// The operation specific fields ("values", "success", "register", ...)
+// stay as aliases for existing workflows.
+const (
+	ResultValue     = "value"     // Engineering value, a list for multi-register reads
+	ResultRaw       = "raw"       // Register words, a list; absent for virtual registers
+	ResultUnit      = "unit"      // Unit of value, empty if the register has none
+	ResultQuality   = "quality"   // See Quality*
+	ResultTimestamp = "timestamp" // RFC 3339 time of the device answer
+)
+
+// sampleResult builds the normalized result of a register operation
+func sampleResult(sample modbus.Sample) map[string]any {
+	result := map[string]any{
+		ResultValue:     sample.Value,
+		ResultUnit:      sample.Unit,
+		ResultQuality:   sample.Quality,
+		ResultTimestamp: sample.Timestamp.UTC().Format(time.RFC3339Nano),
+	}
+	if sample.Quality == "" {
+		result[ResultQuality] = QualityGood
+	}
+	if sample.Raw != nil {
+		result[ResultRaw] = sample.Raw
+	}
+	return result
+}
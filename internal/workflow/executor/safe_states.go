@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ApplySafeStates writes the safe state values of the named devices (all
+// devices if none are given) like the device manager. Hooks can't veto safe
+// states; the post hooks are told about each written output for their audit.
+func (e *StepExecutor) ApplySafeStates(ctx context.Context, reason string, deviceNames ...string) error {
+	targets := make(map[string]bool, len(deviceNames))
+	for _, name := range deviceNames {
+		targets[name] = true
+	}
+
+	logger := loggerFrom(ctx)
+	var errs []error
+	for _, device := range e.deviceManager.ListDevices() {
+		if len(targets) > 0 && !targets[device.Name] {
+			continue
+		}
+
+		written := 0
+		for name, value := range device.SafeStates {
+			if _, err := device.WriteSafeState(ctx, name); err != nil {
+				logger.Error("Failed to apply safe state",
+					zap.String("device", device.Name),
+					zap.String("output", name),
+					zap.String("reason", reason),
+					zap.Error(err))
+				errs = append(errs, fmt.Errorf("device %s: %s: %w", device.Name, name, err))
+				continue
+			}
+			written++
+
+			_, mapped := device.IOMapping[name]
+			write := writeStep("safe_state "+reason, device.Name, name, value, mapped)
+			e.notifyHooks(ctx, HookPhasePost, write, map[string]any{"value": value})
+		}
+
+		if written > 0 {
+			logger.Info("Safe states applied",
+				zap.String("device", device.Name),
+				zap.String("reason", reason),
+				zap.Int("outputs", written))
+		}
+	}
+
+	return errors.Join(errs...)
+}
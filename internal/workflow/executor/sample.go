@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"go.uber.org/zap"
+)
+
+// executeSampleStep captures the latest polled values of the selected
+// registers without reading the devices. Each value is output under its key,
+// the full samples under "samples". A register without a polled value, with
+// bad quality or older than its max_age fails the step.
+func (e *StepExecutor) executeSampleStep(ctx context.Context, step *definition.Step) (map[string]any, error) {
+	output := make(map[string]any, len(step.Samples)+1)
+	samples := make(map[string]any, len(step.Samples))
+
+	for _, ref := range step.Samples {
+		device, exists := e.deviceManager.GetDeviceByName(ref.DeviceID)
+		if !exists {
+			return nil, fmt.Errorf("device not found: %s", ref.DeviceID)
+		}
+
+		sample, ok := device.LastLogicalSample(ref.Register)
+		if !ok {
+			return nil, fmt.Errorf("register %s.%s has no polled value", ref.DeviceID, ref.Register)
+		}
+		if sample.Quality == QualityBad {
+			return nil, fmt.Errorf("register %s.%s has quality %s", ref.DeviceID, ref.Register, sample.Quality)
+		}
+		if age := time.Since(sample.Timestamp); ref.MaxAge.Duration > 0 && age > ref.MaxAge.Duration {
+			return nil, fmt.Errorf("register %s.%s value is %s old, max_age is %s", ref.DeviceID, ref.Register, age.Round(time.Millisecond), ref.MaxAge.Duration)
+		}
+
+		output[ref.Key()] = sample.Value
+		samples[ref.Key()] = sampleResult(sample)
+	}
+	output["samples"] = samples
+
+	loggerFrom(ctx).Debug("Sampled polled values", zap.Int("count", len(step.Samples)))
+	return output, nil
+}
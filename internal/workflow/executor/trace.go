@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/modbus"
+	"github.com/KevinKickass/OpenMachineCore/internal/storage"
+	"go.uber.org/zap"
+)
+
+// FrameTrace records the raw Modbus frames of an execution's device steps in
+// its log. Frames are traced while the trace is enabled or the step's device
+// has tracing enabled, up to maxFrames per execution.
+type FrameTrace struct {
+	enabled   atomic.Bool
+	frames    atomic.Int64
+	maxFrames int64 // 0 = unlimited
+	maxBytes  int   // Longer frames are truncated, 0 = unlimited
+}
+
+// NewFrameTrace creates the disabled frame trace of an execution
+func NewFrameTrace(maxFrames, maxFrameBytes int) *FrameTrace {
+	return &FrameTrace{maxFrames: int64(maxFrames), maxBytes: maxFrameBytes}
+}
+
+// SetEnabled switches tracing for all devices of the execution
+func (t *FrameTrace) SetEnabled(enabled bool) {
+	t.enabled.Store(enabled)
+}
+
+// Enabled reports whether all devices of the execution are traced
+func (t *FrameTrace) Enabled() bool {
+	return t.enabled.Load()
+}
+
+type frameTraceKey struct{}
+
+// WithFrameTrace attaches the frame trace of an execution to ctx
+func WithFrameTrace(ctx context.Context, trace *FrameTrace) context.Context {
+	return context.WithValue(ctx, frameTraceKey{}, trace)
+}
+
+// traceFrames makes the Modbus requests of a device step report their frames
+// to the step logger if the execution or the device is traced
+func traceFrames(ctx context.Context, device *modbus.Device) context.Context {
+	trace, ok := ctx.Value(frameTraceKey{}).(*FrameTrace)
+	if !ok || (!trace.Enabled() && !device.Trace()) {
+		return ctx
+	}
+
+	logger := loggerFrom(ctx)
+	return modbus.WithFrameTracer(ctx, func(request, response []byte, elapsed time.Duration, err error) {
+		n := trace.frames.Add(1)
+		if trace.maxFrames > 0 && n > trace.maxFrames {
+			if n == trace.maxFrames+1 {
+				logger.Warn("Frame trace limit reached, further frames are not traced",
+					zap.Int64("max_frames", trace.maxFrames))
+			}
+			return
+		}
+
+		fields := []zap.Field{
+			zap.String("device", device.Name),
+			zap.String("request", trace.hex(request)),
+			zap.Int64("duration_us", elapsed.Microseconds()),
+		}
+		if response != nil {
+			fields = append(fields, zap.String("response", trace.hex(response)))
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		logger.Info(storage.TraceLogMessage, fields...)
+	})
+}
+
+// hex formats a frame as space separated hex bytes, truncated to maxBytes
+func (t *FrameTrace) hex(frame []byte) string {
+	if t.maxBytes > 0 && len(frame) > t.maxBytes {
+		return fmt.Sprintf("% X … (%d bytes)", frame[:t.maxBytes], len(frame))
+	}
+	return fmt.Sprintf("% X", frame)
+}
@@ -0,0 +1,164 @@
+// Package hooks calls external systems before and after workflow steps over
+// HTTP, so they can veto critical steps
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/secrets"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/executor"
+	"go.uber.org/zap"
+)
+
+// defaultTimeout bounds a hook call without configured timeout
+const defaultTimeout = 2 * time.Second
+
+// maxResponseBytes bounds the decision read from a hook
+const maxResponseBytes = 64 << 10
+
+// HTTPHook posts the step context as JSON and expects a decision
+// {"allow": bool, "reason": "..."} with a 2xx status
+type HTTPHook struct {
+	cfg     config.StepHookConfig
+	secrets *secrets.Manager
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// FromConfig creates the configured step hooks in order
+func FromConfig(cfgs []config.StepHookConfig, secretStore *secrets.Manager, logger *zap.Logger) ([]executor.StepHook, error) {
+	hooks := make([]executor.StepHook, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		hook, err := NewHTTPHook(cfg, secretStore, logger)
+		if err != nil {
+			return nil, fmt.Errorf("step hook %s: %w", cfg.Name, err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func NewHTTPHook(cfg config.StepHookConfig, secretStore *secrets.Manager, logger *zap.Logger) (*HTTPHook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid hook url %q", cfg.URL)
+	}
+	if cfg.Phase != executor.HookPhasePre && cfg.Phase != executor.HookPhasePost {
+		return nil, fmt.Errorf("invalid phase %q", cfg.Phase)
+	}
+	for _, pattern := range cfg.Devices {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid device pattern %q", pattern)
+		}
+	}
+	for header, name := range cfg.SecretHeaders {
+		if err := secrets.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("header %s: %w", header, err)
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &HTTPHook{
+		cfg:     cfg,
+		secrets: secretStore,
+		client:  &http.Client{},
+		logger:  logger.With(zap.String("hook", cfg.Name)),
+	}, nil
+}
+
+func (h *HTTPHook) Name() string {
+	return h.cfg.Name
+}
+
+// Matches reports whether the hook applies to the step in the phase
+func (h *HTTPHook) Matches(phase string, step *definition.Step) bool {
+	if phase != h.cfg.Phase {
+		return false
+	}
+	if len(h.cfg.StepTypes) > 0 && !slices.Contains(h.cfg.StepTypes, string(step.Type)) {
+		return false
+	}
+	if len(h.cfg.Operations) > 0 && !slices.Contains(h.cfg.Operations, step.Operation) {
+		return false
+	}
+	if len(h.cfg.Devices) == 0 {
+		return true
+	}
+	for _, pattern := range h.cfg.Devices {
+		if ok, _ := path.Match(pattern, step.DeviceID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Call asks the hook for a decision. With fail_open an unreachable or
+// failing hook allows the step.
+func (h *HTTPHook) Call(ctx context.Context, req executor.HookRequest) (executor.HookDecision, error) {
+	decision, err := h.call(ctx, req)
+	if err != nil && h.cfg.FailOpen && ctx.Err() == nil {
+		h.logger.Warn("Step hook unavailable, allowing step (fail_open)",
+			zap.String("step", req.Step.Name),
+			zap.Error(err))
+		return executor.HookDecision{Allow: true, Reason: "hook unavailable, fail_open"}, nil
+	}
+	return decision, err
+}
+
+func (h *HTTPHook) call(ctx context.Context, req executor.HookRequest) (executor.HookDecision, error) {
+	var decision executor.HookDecision
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return decision, fmt.Errorf("failed to marshal step context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return decision, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-OMC-Hook", h.cfg.Name)
+	httpReq.Header.Set("X-OMC-Hook-Phase", req.Phase)
+	for header, value := range h.cfg.Headers {
+		httpReq.Header.Set(header, value)
+	}
+	for header, name := range h.cfg.SecretHeaders {
+		value, err := h.secrets.Get(ctx, name)
+		if err != nil {
+			return decision, fmt.Errorf("header %s: %w", header, err)
+		}
+		httpReq.Header.Set(header, value)
+	}
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return decision, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+		return decision, fmt.Errorf("hook returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&decision); err != nil {
+		return decision, fmt.Errorf("invalid hook decision: %w", err)
+	}
+	return decision, nil
+}
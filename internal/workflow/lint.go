@@ -0,0 +1,458 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/config"
+	"github.com/KevinKickass/OpenMachineCore/internal/expr"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// Built-in lint profiles
+const (
+	LintProfileDefault = "default"
+	LintProfileStrict  = "strict"
+	LintProfileOff     = "off"
+)
+
+// LintRule inspects a single parsed workflow definition.
+// Rules report issues without severity; the active profile decides it.
+type LintRule interface {
+	ID() string
+	Code() string
+	Description() string
+	DefaultSeverity() Severity
+	Check(wid uuid.UUID, wf *definition.Workflow) []Issue
+}
+
+// ruleSetting is the effective configuration of a rule within a profile
+type ruleSetting struct {
+	enabled  bool
+	severity Severity
+}
+
+// Linter holds the registered rules and the configured profiles
+type Linter struct {
+	rules          []LintRule
+	profiles       map[string]map[string]ruleSetting
+	defaultProfile string
+}
+
+// NewLinter creates a linter with the built-in rules and profiles,
+// applying the profile overrides from config, checked by CheckLintConfig.
+func NewLinter(cfg config.LintConfig) *Linter {
+	l := &Linter{
+		profiles:       make(map[string]map[string]ruleSetting),
+		defaultProfile: cfg.DefaultProfile,
+	}
+	if l.defaultProfile == "" {
+		l.defaultProfile = LintProfileDefault
+	}
+
+	for _, rule := range builtinLintRules() {
+		l.Register(rule)
+	}
+
+	for name, profile := range cfg.Profiles {
+		settings := l.profileSettings(name)
+		for ruleID, ruleCfg := range profile.Rules {
+			setting, ok := settings[ruleID]
+			if !ok {
+				continue
+			}
+			if ruleCfg.Enabled != nil {
+				setting.enabled = *ruleCfg.Enabled
+			}
+			if ruleCfg.Severity != "" {
+				setting.severity = Severity(ruleCfg.Severity)
+			}
+			settings[ruleID] = setting
+		}
+	}
+
+	return l
+}
+
+// CheckLintConfig rejects rule overrides for unknown rule IDs, severities
+// other than "error" and "warning" and an unknown default profile, which
+// NewLinter would otherwise ignore
+func CheckLintConfig(cfg config.LintConfig) error {
+	known := map[string]bool{}
+	for _, rule := range builtinLintRules() {
+		known[rule.ID()] = true
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		rules := cfg.Profiles[name].Rules
+		for _, ruleID := range slices.Sorted(maps.Keys(rules)) {
+			if !known[ruleID] {
+				errs = append(errs, fmt.Errorf("lint.profiles.%s.rules.%s: unknown rule", name, ruleID))
+				continue
+			}
+			severity := Severity(rules[ruleID].Severity)
+			if severity != "" && severity != SevError && severity != SevWarning {
+				errs = append(errs, fmt.Errorf("lint.profiles.%s.rules.%s.severity: must be error or warning, got %q", name, ruleID, severity))
+			}
+		}
+	}
+
+	if profile := cfg.DefaultProfile; profile != "" {
+		_, configured := cfg.Profiles[profile]
+		if !configured && profile != LintProfileDefault && profile != LintProfileStrict && profile != LintProfileOff {
+			errs = append(errs, fmt.Errorf("lint.default_profile: unknown profile %q", profile))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Register adds a rule to the linter. It is enabled in the default and
+// strict profiles and disabled in the off profile.
+func (l *Linter) Register(rule LintRule) {
+	l.rules = append(l.rules, rule)
+
+	for _, name := range []string{LintProfileDefault, LintProfileStrict, LintProfileOff} {
+		l.profileSettings(name)
+	}
+	for name, settings := range l.profiles {
+		settings[rule.ID()] = builtinSetting(name, rule)
+	}
+}
+
+// HasProfile reports whether a profile is known
+func (l *Linter) HasProfile(name string) bool {
+	_, ok := l.profiles[name]
+	return ok
+}
+
+// Profiles returns the names of all known profiles
+func (l *Linter) Profiles() []string {
+	names := make([]string, 0, len(l.profiles))
+	for name := range l.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultProfile returns the profile used when none is selected
+func (l *Linter) DefaultProfile() string {
+	return l.defaultProfile
+}
+
+func (l *Linter) profileSettings(name string) map[string]ruleSetting {
+	settings, ok := l.profiles[name]
+	if ok {
+		return settings
+	}
+
+	settings = make(map[string]ruleSetting, len(l.rules))
+	for _, rule := range l.rules {
+		settings[rule.ID()] = builtinSetting(name, rule)
+	}
+	l.profiles[name] = settings
+	return settings
+}
+
+func builtinSetting(profile string, rule LintRule) ruleSetting {
+	switch profile {
+	case LintProfileOff:
+		return ruleSetting{enabled: false, severity: rule.DefaultSeverity()}
+	case LintProfileStrict:
+		return ruleSetting{enabled: true, severity: SevError}
+	default:
+		return ruleSetting{enabled: true, severity: rule.DefaultSeverity()}
+	}
+}
+
+// lint runs all enabled rules of a profile against a workflow
+func (l *Linter) lint(profile string, wid uuid.UUID, wf *definition.Workflow, rep *Report) {
+	settings, ok := l.profiles[profile]
+	if !ok {
+		return
+	}
+
+	for _, rule := range l.rules {
+		setting := settings[rule.ID()]
+		if !setting.enabled {
+			continue
+		}
+
+		for _, issue := range rule.Check(wid, wf) {
+			issue.Code = rule.Code()
+			issue.Severity = setting.severity
+			if issue.Meta == nil {
+				issue.Meta = map[string]any{}
+			}
+			issue.Meta["rule"] = rule.ID()
+
+			if setting.severity == SevError {
+				rep.addError(issue)
+			} else {
+				rep.addWarning(issue)
+			}
+		}
+	}
+}
+
+func builtinLintRules() []LintRule {
+	return []LintRule{
+		unusedVariablesRule{},
+		unreachableStepsRule{},
+		unverifiedWriteRule{},
+		missingTimeoutRule{},
+	}
+}
+
+// ==================== RULES ====================
+
+// unusedVariablesRule reports declared variables never referenced by a step
+type unusedVariablesRule struct{}
+
+func (unusedVariablesRule) ID() string                { return "unused_variables" }
+func (unusedVariablesRule) Code() string              { return "LINT_001" }
+func (unusedVariablesRule) DefaultSeverity() Severity { return SevWarning }
+func (unusedVariablesRule) Description() string {
+	return "Workflow variables that are never referenced by any step or workflow output"
+}
+
+func (r unusedVariablesRule) Check(wid uuid.UUID, wf *definition.Workflow) []Issue {
+	if len(wf.Variables) == 0 {
+		return nil
+	}
+
+	used := map[string]bool{}
+	for _, step := range wf.Steps {
+		variableRefs(step, used)
+	}
+	for _, ref := range wf.Outputs {
+		addVariableRef(ref.Step, used)
+		addVariableRef(ref.Path, used)
+	}
+
+	names := make([]string, 0, len(wf.Variables))
+	for name := range wf.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []Issue
+	for _, name := range names {
+		if used[name] {
+			continue
+		}
+		issues = append(issues, Issue{
+			Message:    fmt.Sprintf("Variable '%s' is never used", name),
+			WorkflowID: wid.String(),
+			Field:      "variables." + name,
+			Path:       "/variables/" + name,
+			Hint:       "Remove the variable or reference it from a step",
+		})
+	}
+	return issues
+}
+
+// addVariableRef adds the variable a value references as $variables.<name>
+// to used
+func addVariableRef(value string, used map[string]bool) {
+	if !definition.IsReference(value) {
+		return
+	}
+	if kind, key, _, err := definition.ParseReference(value); err == nil && kind == definition.RefVariables {
+		used[key] = true
+	}
+}
+
+// variableRefs adds the variables a step references as $variables.<name>,
+// in parameter, mapping and post-action values or in conditions, to used
+func variableRefs(step definition.Step, used map[string]bool) {
+	addCondition := func(condition string) {
+		if strings.TrimSpace(condition) == "" {
+			return
+		}
+		if parsed, err := expr.Parse(condition); err == nil {
+			for _, ref := range parsed.Refs() {
+				if kind, key, _, err := definition.ParseReference(ref); err == nil && kind == definition.RefVariables {
+					used[key] = true
+				}
+			}
+		}
+	}
+
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case string:
+			addVariableRef(v, used)
+		case map[string]any:
+			for _, item := range v {
+				walk(item)
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+
+	walk(step.Parameters)
+	walk(step.InputMapping)
+	walk(step.Arguments)
+	for _, action := range slices.Concat(step.OnSuccessWrite, step.OnFailureWrite) {
+		walk(action.DeviceID)
+		walk(action.Register)
+		walk(action.Value)
+	}
+	for _, target := range step.OutputMapping {
+		walk(target)
+	}
+	addCondition(step.Condition)
+	for _, t := range step.Transitions {
+		addCondition(t.Condition)
+	}
+}
+
+// unreachableStepsRule reports steps that can never execute
+type unreachableStepsRule struct{}
+
+func (unreachableStepsRule) ID() string                { return "unreachable_steps" }
+func (unreachableStepsRule) Code() string              { return "LINT_002" }
+func (unreachableStepsRule) DefaultSeverity() Severity { return SevWarning }
+func (unreachableStepsRule) Description() string {
+	return "Steps that can never be executed (constant false condition)"
+}
+
+func (r unreachableStepsRule) Check(wid uuid.UUID, wf *definition.Workflow) []Issue {
+	var issues []Issue
+	for i, step := range wf.Steps {
+		cond := strings.ToLower(strings.TrimSpace(step.Condition))
+		if cond != "false" && cond != "0" {
+			continue
+		}
+		issues = append(issues, Issue{
+			Message:    fmt.Sprintf("Step '%s' is unreachable: condition is always false", step.Name),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "condition",
+			Path:       fmt.Sprintf("/steps/%d/condition", i),
+			Meta:       map[string]any{"step_index": i},
+		})
+	}
+	return issues
+}
+
+// unverifiedWriteRule reports device writes that are never read back
+type unverifiedWriteRule struct{}
+
+func (unverifiedWriteRule) ID() string                { return "unverified_write" }
+func (unverifiedWriteRule) Code() string              { return "LINT_003" }
+func (unverifiedWriteRule) DefaultSeverity() Severity { return SevWarning }
+func (unverifiedWriteRule) Description() string {
+	return "Device writes without a subsequent read of the same register"
+}
+
+func (r unverifiedWriteRule) Check(wid uuid.UUID, wf *definition.Workflow) []Issue {
+	var issues []Issue
+	for i, step := range wf.Steps {
+		if step.Type != definition.StepTypeDevice {
+			continue
+		}
+		readOp, ok := verificationOpFor(step.Operation)
+		if !ok {
+			continue
+		}
+		if verify, _ := step.Parameters["verify"].(bool); verify && step.Operation != "write_bits" {
+			continue // Read back by the step itself
+		}
+		target := stepTarget(step)
+
+		verified := false
+		for _, next := range wf.Steps[i+1:] {
+			if next.Type == definition.StepTypeDevice && next.DeviceID == step.DeviceID &&
+				next.Operation == readOp && stepTarget(next) == target {
+				verified = true
+				break
+			}
+		}
+		if verified {
+			continue
+		}
+
+		hint := fmt.Sprintf("Add a subsequent '%s' step for the same target", readOp)
+		if step.Operation != "write_bits" {
+			hint = fmt.Sprintf("Set \"verify\": true or add a subsequent '%s' step for the same target", readOp)
+		}
+		issues = append(issues, Issue{
+			Message:    fmt.Sprintf("Write to '%s' on device '%s' is never verified", target, step.DeviceID),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "operation",
+			Path:       fmt.Sprintf("/steps/%d", i),
+			Hint:       hint,
+			Meta:       map[string]any{"step_index": i},
+		})
+	}
+	return issues
+}
+
+func verificationOpFor(op string) (string, bool) {
+	switch op {
+	case "write":
+		return "read", true
+	case "write_logical":
+		return "read_logical", true
+	case "write_register", "write_bits":
+		return "read_register", true
+	default:
+		return "", false
+	}
+}
+
+// stepTarget identifies the register a device step operates on
+func stepTarget(step definition.Step) string {
+	if step.Parameters == nil {
+		return ""
+	}
+	if reg, ok := step.Parameters["register"]; ok {
+		return fmt.Sprint(reg)
+	}
+	if addr, ok := step.Parameters["address"]; ok {
+		return fmt.Sprintf("%v@%v", step.Parameters["register_type"], addr)
+	}
+	return ""
+}
+
+// missingTimeoutRule reports device steps without a timeout
+type missingTimeoutRule struct{}
+
+func (missingTimeoutRule) ID() string                { return "missing_timeout" }
+func (missingTimeoutRule) Code() string              { return "LINT_004" }
+func (missingTimeoutRule) DefaultSeverity() Severity { return SevWarning }
+func (missingTimeoutRule) Description() string {
+	return "Device steps without an explicit timeout"
+}
+
+func (r missingTimeoutRule) Check(wid uuid.UUID, wf *definition.Workflow) []Issue {
+	var issues []Issue
+	for i, step := range wf.Steps {
+		if step.Type != definition.StepTypeDevice || step.Timeout.Duration > 0 {
+			continue
+		}
+		issues = append(issues, Issue{
+			Message:    fmt.Sprintf("Device step '%s' has no timeout", step.Name),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "timeout",
+			Path:       fmt.Sprintf("/steps/%d/timeout", i),
+			Hint:       "Set a timeout so a hung device cannot block the workflow",
+			Meta:       map[string]any{"step_index": i},
+		})
+	}
+	return issues
+}
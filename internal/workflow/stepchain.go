@@ -0,0 +1,309 @@
+package workflow
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+)
+
+// ErrInvalidStepChain is returned for step chain CSVs that can't be converted
+var ErrInvalidStepChain = errors.New("invalid step chain")
+
+// DeviceLookup reports whether a device exists and is enabled
+type DeviceLookup func(ctx context.Context, name string) (exists, enabled bool, err error)
+
+// stepChainColumns maps the accepted header names to the columns of a step
+// chain CSV
+var stepChainColumns = map[string]string{
+	"step":        "step",
+	"number":      "step",
+	"nr":          "step",
+	"description": "description",
+	"name":        "description",
+	"device":      "device",
+	"action":      "action",
+	"transition":  "transition",
+	"condition":   "transition",
+}
+
+// ImportStepChain converts a step chain documented as CSV into a draft
+// workflow definition. The first row names the columns step, description,
+// device, action and transition; the separator is ',' or ';'. Problems the
+// definition can be reviewed for, e.g. unresolved devices, are returned as
+// warnings.
+func ImportStepChain(ctx context.Context, r io.Reader, name string, devices DeviceLookup) (*definition.Workflow, []Issue, error) {
+	br := bufio.NewReader(r)
+	peek, _ := br.Peek(4096)
+	firstLine, _, _ := strings.Cut(string(peek), "\n")
+
+	reader := csv.NewReader(br)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: empty file", ErrInvalidStepChain)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidStepChain, err)
+	}
+
+	columns := map[string]int{}
+	for i, title := range header {
+		title = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(title, "\ufeff")))
+		if column, ok := stepChainColumns[title]; ok {
+			columns[column] = i
+		}
+	}
+	for _, required := range []string{"step", "action"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidStepChain, required)
+		}
+	}
+
+	wf := &definition.Workflow{
+		Name:        name,
+		ProgramName: "main",
+		Description: "Imported from step chain CSV",
+		Version:     "0.1",
+	}
+	var warnings []Issue
+	seen := map[string]int{}
+	pendingTransition, pendingLine := "", 0
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidStepChain, err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			idx, ok := columns[column]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		number := field("step")
+		if len(number) > 1 && (number[0] == 'S' || number[0] == 's') && number[1] >= '0' && number[1] <= '9' {
+			number = number[1:] // S10
+		}
+		if number == "" && field("action") == "" {
+			continue // Blank row
+		}
+		if number == "" {
+			return nil, nil, fmt.Errorf("%w: line %d: missing step number", ErrInvalidStepChain, line)
+		}
+		if first, ok := seen[number]; ok {
+			return nil, nil, fmt.Errorf("%w: line %d: step %s already defined in line %d", ErrInvalidStepChain, line, number, first)
+		}
+		seen[number] = line
+
+		idx := len(wf.Steps)
+		step, issues := parseStepChainAction(field("action"), field("device"))
+		step.Number = number
+		step.Name = field("description")
+		if step.Name == "" {
+			step.Name = "Step " + number
+		}
+		base := fmt.Sprintf("/steps/%d", idx)
+		for _, issue := range issues {
+			issue.StepName = step.Name
+			issue.Path = base + issue.Path
+			issue.Meta = map[string]any{"line": line, "step_index": idx}
+			warnings = append(warnings, issue)
+		}
+
+		// The transition after a step gates the next one
+		if pendingTransition != "" {
+			step.Condition = pendingTransition
+			warnings = append(warnings, Issue{
+				Code:     "IMPORT_010",
+				Severity: SevWarning,
+				Message:  fmt.Sprintf("Transition %q is kept as condition but not evaluated", pendingTransition),
+				StepName: step.Name,
+				Field:    "condition",
+				Path:     base + "/condition",
+				Hint:     "Implement it, e.g. as a device step reading the signal before this step",
+				Meta:     map[string]any{"line": pendingLine, "step_index": idx},
+			})
+		}
+		pendingTransition, pendingLine = field("transition"), line
+
+		if step.Type == definition.StepTypeDevice && step.DeviceID != "" {
+			exists, enabled, err := devices(ctx, step.DeviceID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to look up device %s: %w", step.DeviceID, err)
+			}
+			switch {
+			case !exists:
+				warnings = append(warnings, Issue{
+					Code:     "IMPORT_001",
+					Severity: SevWarning,
+					Message:  fmt.Sprintf("Device not found: %s", step.DeviceID),
+					StepName: step.Name,
+					Field:    "device_id",
+					Path:     base + "/device_id",
+					Hint:     "Create the device or correct the device column",
+					Meta:     map[string]any{"line": line, "step_index": idx},
+				})
+			case !enabled:
+				warnings = append(warnings, Issue{
+					Code:     "IMPORT_002",
+					Severity: SevWarning,
+					Message:  fmt.Sprintf("Device is disabled: %s", step.DeviceID),
+					StepName: step.Name,
+					Field:    "device_id",
+					Path:     base + "/device_id",
+					Meta:     map[string]any{"line": line, "step_index": idx},
+				})
+			}
+		}
+		wf.Steps = append(wf.Steps, step)
+	}
+
+	if len(wf.Steps) == 0 {
+		return nil, nil, fmt.Errorf("%w: no steps", ErrInvalidStepChain)
+	}
+	if pendingTransition != "" {
+		warnings = append(warnings, Issue{
+			Code:     "IMPORT_011",
+			Severity: SevWarning,
+			Message:  fmt.Sprintf("Transition %q after the last step is dropped", pendingTransition),
+			Meta:     map[string]any{"line": pendingLine},
+		})
+	}
+	sortIssues(warnings)
+	return wf, warnings, nil
+}
+
+// parseStepChainAction converts an action, e.g. "set valve_open",
+// "wait 2s" or "write_logical register=speed value=100", into a step.
+// Issue paths are relative to the step.
+func parseStepChainAction(action, device string) (definition.Step, []Issue) {
+	step := definition.Step{Type: definition.StepTypeDevice, DeviceID: device}
+	var issues []Issue
+
+	verb, rest, _ := strings.Cut(action, " ")
+	verb = strings.ToLower(verb)
+	rest = strings.TrimSpace(rest)
+
+	switch verb {
+	case "":
+		return definition.Step{Type: definition.StepTypeWait}, []Issue{{
+			Code:     "IMPORT_003",
+			Severity: SevWarning,
+			Message:  "Step has no action and is imported as a 1s wait",
+			Field:    "type",
+			Path:     "/type",
+		}}
+	case "wait":
+		step = definition.Step{Type: definition.StepTypeWait}
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			issues = append(issues, Issue{
+				Code:     "IMPORT_004",
+				Severity: SevWarning,
+				Message:  fmt.Sprintf("Invalid wait duration %q, using 1s", rest),
+				Field:    "timeout",
+				Path:     "/timeout",
+			})
+			d = time.Second
+		}
+		step.Timeout = definition.Duration{Duration: d}
+		return step, issues
+	case "set", "reset":
+		step.Operation = "write_logical"
+		step.Parameters = map[string]any{"register": rest, "value": verb == "set"}
+	case "read":
+		if !strings.Contains(rest, "=") {
+			step.Operation = "read_logical"
+			step.Parameters = map[string]any{"register": rest}
+			break
+		}
+		step.Operation = verb
+		step.Parameters = parseStepChainParameters(rest)
+	case "write":
+		if strings.Count(rest, "=") == 1 {
+			register, value, _ := strings.Cut(rest, "=")
+			step.Operation = "write_logical"
+			step.Parameters = map[string]any{
+				"register": strings.TrimSpace(register),
+				"value":    parseStepChainValue(strings.TrimSpace(value)),
+			}
+			break
+		}
+		step.Operation = verb
+		step.Parameters = parseStepChainParameters(rest)
+	default:
+		step.Operation = verb
+		step.Parameters = parseStepChainParameters(rest)
+		if requiredParamsForOp(verb) == nil {
+			issues = append(issues, Issue{
+				Code:     "IMPORT_005",
+				Severity: SevWarning,
+				Message:  fmt.Sprintf("Unsupported action: %s", verb),
+				Field:    "operation",
+				Path:     "/operation",
+				Hint:     "Use set, reset, read, write, wait or a device operation",
+			})
+		}
+	}
+
+	if device == "" {
+		issues = append(issues, Issue{
+			Code:     "IMPORT_006",
+			Severity: SevWarning,
+			Message:  "Device step without device",
+			Field:    "device_id",
+			Path:     "/device_id",
+		})
+	}
+	return step, issues
+}
+
+// parseStepChainParameters parses space separated key=value pairs
+func parseStepChainParameters(s string) map[string]any {
+	params := map[string]any{}
+	for pair := range strings.FieldsSeq(s) {
+		key, value, _ := strings.Cut(pair, "=")
+		params[key] = parseStepChainValue(value)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// parseStepChainValue converts booleans and numbers, other values stay strings
+func parseStepChainValue(s string) any {
+	switch strings.ToLower(s) {
+	case "true", "on":
+		return true
+	case "false", "off":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
@@ -1,55 +1,326 @@
 package streaming
 
 import (
+	"encoding/json"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/google/uuid"
 )
 
+// Buffering policies for a subscriber whose buffer is full
+const (
+	PolicyDropNewest = "drop_newest" // Drop the new event
+	PolicyDropOldest = "drop_oldest" // Drop the oldest buffered event to make room
+	PolicyBlock      = "block"       // Wait up to BlockTimeout for room, then drop the new event
+	PolicyDisconnect = "disconnect"  // End the subscription with a stream.disconnected event
+)
+
+// Event types the streamer adds to a subscription
+const (
+	EventGap          = "stream.gap"          // Events were dropped before this one
+	EventDisconnected = "stream.disconnected" // The subscriber was too slow; the channel closes after it
+)
+
+const (
+	defaultBuffer       = 100
+	defaultBlockTimeout = time.Second
+)
+
+// SubscribeOptions set the buffer of a subscription and what happens when it
+// is full. Final execution events are never dropped for a full buffer, they
+// replace the oldest buffered event instead.
+type SubscribeOptions struct {
+	Buffer       int           // Channel capacity, 0 = 100
+	Policy       string        // Empty = PolicyDropNewest
+	BlockTimeout time.Duration // PolicyBlock only, 0 = 1s
+}
+
+// Stats counts the events lost to slow subscribers since start
+type Stats struct {
+	Subscribers  int   `json:"subscribers"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"`
+}
+
+type subscriber struct {
+	ch   chan *storage.ExecutionEvent
+	opts SubscribeOptions
+	done chan struct{} // Closed on unsubscribe, ends a blocked send
+
+	mu       sync.Mutex // Serializes sends with closing ch
+	closed   bool
+	gap      int   // Dropped since the last gap event
+	gapFrom  int64 // Sequence of the first event of the gap
+	doneOnce sync.Once
+}
+
 type EventStreamer struct {
 	mu          sync.RWMutex
-	subscribers map[uuid.UUID][]chan *storage.ExecutionEvent
+	subscribers map[uuid.UUID][]*subscriber
+	all         []*subscriber // Events of every execution
+
+	dropped      atomic.Int64
+	disconnected atomic.Int64
 }
 
 func NewEventStreamer() *EventStreamer {
 	return &EventStreamer{
-		subscribers: make(map[uuid.UUID][]chan *storage.ExecutionEvent),
+		subscribers: make(map[uuid.UUID][]*subscriber),
+	}
+}
+
+func newSubscriber(opts SubscribeOptions) *subscriber {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultBuffer
+	}
+	if opts.Policy == "" {
+		opts.Policy = PolicyDropNewest
+	}
+	if opts.BlockTimeout <= 0 {
+		opts.BlockTimeout = defaultBlockTimeout
+	}
+	return &subscriber{
+		ch:   make(chan *storage.ExecutionEvent, opts.Buffer),
+		opts: opts,
+		done: make(chan struct{}),
 	}
 }
 
 func (s *EventStreamer) Subscribe(executionID uuid.UUID) <-chan *storage.ExecutionEvent {
+	return s.SubscribeWith(executionID, SubscribeOptions{})
+}
+
+// SubscribeWith is Subscribe with a buffering policy
+func (s *EventStreamer) SubscribeWith(executionID uuid.UUID, opts SubscribeOptions) <-chan *storage.ExecutionEvent {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	ch := make(chan *storage.ExecutionEvent, 100)
-	s.subscribers[executionID] = append(s.subscribers[executionID], ch)
-	return ch
+	sub := newSubscriber(opts)
+	s.subscribers[executionID] = append(s.subscribers[executionID], sub)
+	return sub.ch
 }
 
 func (s *EventStreamer) Unsubscribe(executionID uuid.UUID, ch <-chan *storage.ExecutionEvent) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	subs := s.subscribers[executionID]
+	var found *subscriber
 	for i, sub := range subs {
-		if sub == ch {
+		if sub.ch == ch {
 			s.subscribers[executionID] = append(subs[:i], subs[i+1:]...)
-			close(sub)
+			found = sub
 			break
 		}
 	}
+	if len(s.subscribers[executionID]) == 0 {
+		delete(s.subscribers, executionID)
+	}
+	s.mu.Unlock()
+
+	if found != nil {
+		found.close()
+	}
+}
+
+// SubscribeAll returns a channel receiving the events of every execution
+func (s *EventStreamer) SubscribeAll() <-chan *storage.ExecutionEvent {
+	return s.SubscribeAllWith(SubscribeOptions{})
+}
+
+// SubscribeAllWith is SubscribeAll with a buffering policy
+func (s *EventStreamer) SubscribeAllWith(opts SubscribeOptions) <-chan *storage.ExecutionEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := newSubscriber(opts)
+	s.all = append(s.all, sub)
+	return sub.ch
+}
+
+// UnsubscribeAll removes and closes a channel returned by SubscribeAll
+func (s *EventStreamer) UnsubscribeAll(ch <-chan *storage.ExecutionEvent) {
+	s.mu.Lock()
+	var found *subscriber
+	for i, sub := range s.all {
+		if sub.ch == ch {
+			s.all = append(s.all[:i], s.all[i+1:]...)
+			found = sub
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if found != nil {
+		found.close()
+	}
+}
+
+// Stats returns the number of subscribers and the events lost to full
+// buffers
+func (s *EventStreamer) Stats() Stats {
+	s.mu.RLock()
+	subscribers := len(s.all)
+	for _, subs := range s.subscribers {
+		subscribers += len(subs)
+	}
+	s.mu.RUnlock()
+
+	return Stats{
+		Subscribers:  subscribers,
+		Dropped:      s.dropped.Load(),
+		Disconnected: s.disconnected.Load(),
+	}
 }
 
+// Broadcast delivers an event to the subscribers of its execution and of all
+// executions. Subscribers with PolicyBlock can delay it up to their
+// BlockTimeout.
 func (s *EventStreamer) Broadcast(executionID uuid.UUID, event *storage.ExecutionEvent) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	targets := make([]*subscriber, 0, len(s.all)+len(s.subscribers[executionID]))
+	targets = append(targets, s.all...)
+	targets = append(targets, s.subscribers[executionID]...)
+	s.mu.RUnlock()
+
+	for _, sub := range targets {
+		if !s.deliver(sub, event) {
+			s.remove(executionID, sub)
+		}
+	}
+}
+
+// deliver sends an event to a subscriber according to its policy. It
+// returns false if the subscriber was disconnected.
+func (s *EventStreamer) deliver(sub *subscriber, event *storage.ExecutionEvent) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return true
+	}
+
+	// Announce a gap once there is room for the notice and the event
+	if sub.gap > 0 && len(sub.ch) < cap(sub.ch)-1 {
+		sub.ch <- sub.notice(event.ExecutionID, EventGap)
+		sub.gap = 0
+	}
+
+	select {
+	case sub.ch <- event:
+		return true
+	default:
+	}
 
-	for _, ch := range s.subscribers[executionID] {
+	switch {
+	case sub.opts.Policy == PolicyDisconnect:
+		s.drop(sub, event)
+		s.dropOldest(sub)
+		sub.ch <- sub.notice(event.ExecutionID, EventDisconnected)
+		sub.closed = true
+		close(sub.ch)
+		s.disconnected.Add(1)
+		return false
+
+	case sub.opts.Policy == PolicyDropOldest || FinalEvent(event.EventType):
+		s.dropOldest(sub)
 		select {
-		case ch <- event:
+		case sub.ch <- event:
 		default:
-			// Skip if channel is full
+			s.drop(sub, event)
+		}
+
+	case sub.opts.Policy == PolicyBlock:
+		timeout := time.NewTimer(sub.opts.BlockTimeout)
+		defer timeout.Stop()
+		select {
+		case sub.ch <- event:
+		case <-timeout.C:
+			s.drop(sub, event)
+		case <-sub.done:
+		}
+
+	default:
+		s.drop(sub, event)
+	}
+	return true
+}
+
+// FinalEvent reports whether an event type ends an execution
+func FinalEvent(eventType string) bool {
+	switch eventType {
+	case "execution.completed", "execution.failed", "execution.cancelled":
+		return true
+	}
+	return false
+}
+
+// dropOldest removes the oldest buffered event. Callers hold sub.mu.
+func (s *EventStreamer) dropOldest(sub *subscriber) {
+	select {
+	case event := <-sub.ch:
+		s.drop(sub, event)
+	default:
+	}
+}
+
+// drop counts a lost event. Callers hold sub.mu.
+func (s *EventStreamer) drop(sub *subscriber, event *storage.ExecutionEvent) {
+	if sub.gap == 0 {
+		sub.gapFrom = event.Sequence
+	}
+	sub.gap++
+	s.dropped.Add(1)
+}
+
+// remove unregisters a disconnected subscriber
+func (s *EventStreamer) remove(executionID uuid.UUID, sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.all {
+		if other == sub {
+			s.all = append(s.all[:i], s.all[i+1:]...)
+			return
 		}
 	}
+	subs := s.subscribers[executionID]
+	for i, other := range subs {
+		if other == sub {
+			s.subscribers[executionID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(s.subscribers[executionID]) == 0 {
+		delete(s.subscribers, executionID)
+	}
+}
+
+// notice builds a stream.gap or stream.disconnected event telling the
+// subscriber how many events it missed. Callers hold sub.mu.
+func (sub *subscriber) notice(executionID uuid.UUID, eventType string) *storage.ExecutionEvent {
+	payload, _ := json.Marshal(map[string]any{
+		"dropped":        sub.gap,
+		"first_sequence": sub.gapFrom,
+	})
+	return &storage.ExecutionEvent{
+		ID:          uuid.New(),
+		ExecutionID: executionID,
+		EventType:   eventType,
+		Payload:     payload,
+		Timestamp:   time.Now(),
+	}
+}
+
+// close ends a blocked send and closes the channel unless a disconnect
+// already did
+func (sub *subscriber) close() {
+	sub.doneOnce.Do(func() { close(sub.done) })
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
 }
@@ -2,12 +2,14 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/KevinKickass/OpenMachineCore/internal/storage"
 	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/executor"
 	"github.com/google/uuid"
 )
 
@@ -31,23 +33,57 @@ type Issue struct {
 }
 
 type Report struct {
-	Valid    bool    `json:"valid"`
-	Errors   []Issue `json:"errors"`
-	Warnings []Issue `json:"warnings"`
+	Valid       bool    `json:"valid"`
+	LintProfile string  `json:"lint_profile,omitempty"`
+	Errors      []Issue `json:"errors"`
+	Warnings    []Issue `json:"warnings"`
 }
 
+// AliasSource returns the deprecated names of a device and their current names
+type AliasSource func(deviceName string) map[string]string
+
 type Validator struct {
 	storage *storage.PostgresClient
+	linter  *Linter
+	aliases AliasSource
 }
 
 func NewValidator(storage *storage.PostgresClient) *Validator {
 	return &Validator{storage: storage}
 }
 
+// WithLinter enables lint rules on top of the structural checks
+func (v *Validator) WithLinter(linter *Linter) *Validator {
+	v.linter = linter
+	return v
+}
+
+// WithAliases enables warnings for references to deprecated register names
+func (v *Validator) WithAliases(source AliasSource) *Validator {
+	v.aliases = source
+	return v
+}
+
 // ValidateByID validates a stored workflow and all reachable sub-workflows.
 // Load failures return (Report{}, err). Definition/semantic failures are returned in the Report (err == nil).
 func (v *Validator) ValidateByID(ctx context.Context, workflowID uuid.UUID) (Report, error) {
+	profile := ""
+	if v.linter != nil {
+		profile = v.linter.DefaultProfile()
+	}
+	return v.ValidateByIDWithProfile(ctx, workflowID, profile)
+}
+
+// ValidateByIDWithProfile validates like ValidateByID and additionally runs the
+// lint rules of the given profile. An empty profile skips linting.
+func (v *Validator) ValidateByIDWithProfile(ctx context.Context, workflowID uuid.UUID, profile string) (Report, error) {
 	rep := Report{}
+	if v.linter != nil && profile != "" {
+		if !v.linter.HasProfile(profile) {
+			return rep, fmt.Errorf("unknown lint profile: %s", profile)
+		}
+		rep.LintProfile = profile
+	}
 
 	wf, _, err := v.storage.LoadWorkflow(ctx, workflowID)
 	if err != nil {
@@ -75,6 +111,7 @@ func (v *Validator) ValidateByID(ctx context.Context, workflowID uuid.UUID) (Rep
 		done:     map[uuid.UUID]bool{},
 		stack:    make([]uuid.UUID, 0, 8),
 		report:   &rep,
+		profile:  rep.LintProfile,
 	}
 
 	st.walk(ctx, workflowID)
@@ -90,6 +127,7 @@ type walkState struct {
 	done     map[uuid.UUID]bool
 	stack    []uuid.UUID
 	report   *Report
+	profile  string
 }
 
 func (st *walkState) walk(ctx context.Context, wid uuid.UUID) {
@@ -131,7 +169,11 @@ func (st *walkState) walk(ctx context.Context, wid uuid.UUID) {
 	st.visiting[wid] = true
 	st.stack = append(st.stack, wid)
 
+	st.expandTemplates(ctx, wid, def)
 	st.validateWorkflow(ctx, wid, def)
+	if st.v.linter != nil && st.profile != "" {
+		st.v.linter.lint(st.profile, wid, def, st.report)
+	}
 
 	st.stack = st.stack[:len(st.stack)-1]
 	st.visiting[wid] = false
@@ -216,6 +258,42 @@ func (st *walkState) validateWorkflow(ctx context.Context, wid uuid.UUID, wf *de
 		})
 	}
 
+	if wf.CycleTime.Duration < 0 {
+		st.report.addError(Issue{
+			Code:       "WORKFLOW_006",
+			Severity:   SevError,
+			Message:    "cycle_time must be >= 0",
+			WorkflowID: wid.String(),
+			Field:      "cycle_time",
+			Path:       "/cycle_time",
+		})
+	}
+	if wf.CycleTimeoutFactor != 0 && (wf.CycleTimeoutFactor < 1 || wf.CycleTime.Duration <= 0) {
+		st.report.addError(Issue{
+			Code:       "WORKFLOW_007",
+			Severity:   SevError,
+			Message:    "cycle_timeout_factor must be >= 1 and requires cycle_time",
+			WorkflowID: wid.String(),
+			Field:      "cycle_timeout_factor",
+			Path:       "/cycle_timeout_factor",
+		})
+	}
+
+	if _, err := storage.NormalizeTags(wf.Tags); err != nil {
+		st.report.addError(Issue{
+			Code:       "WORKFLOW_008",
+			Severity:   SevError,
+			Message:    err.Error(),
+			WorkflowID: wid.String(),
+			Field:      "tags",
+			Path:       "/tags",
+		})
+	}
+
+	st.validateInputs(wid, wf)
+	st.validateOutputs(wid, wf)
+	st.validateRequires(ctx, wid, wf)
+
 	for i := range wf.Steps {
 		step := wf.Steps[i]
 		base := fmt.Sprintf("/steps/%d", i)
@@ -236,9 +314,17 @@ func (st *walkState) validateWorkflow(ctx context.Context, wid uuid.UUID, wf *de
 		case definition.StepTypeDevice:
 			st.validateDeviceStep(ctx, wid, &step, i, base)
 		case definition.StepTypeWorkflow:
-			st.validateSubWorkflowStep(ctx, wid, &step, i, base)
+			st.validateSubWorkflowStep(ctx, wid, wf, &step, i, base)
 		case definition.StepTypeWait:
 			// ok
+		case definition.StepTypeSample:
+			st.validateSampleStep(ctx, wid, &step, i, base)
+		case "":
+			if step.Template != "" {
+				// Unexpanded template step, reported by expandTemplates
+				continue
+			}
+			fallthrough
 		default:
 			st.report.addError(Issue{
 				Code:       "STEP_002",
@@ -250,6 +336,433 @@ func (st *walkState) validateWorkflow(ctx context.Context, wid uuid.UUID, wf *de
 				Meta:       map[string]any{"step_index": i},
 			})
 		}
+
+		for j, name := range step.Resources {
+			if strings.TrimSpace(name) == "" {
+				st.report.addError(Issue{
+					Code:       "STEP_003",
+					Severity:   SevError,
+					Message:    "Resource tag must not be empty",
+					WorkflowID: wid.String(),
+					StepName:   step.Name,
+					Field:      "resources",
+					Path:       fmt.Sprintf("%s/resources/%d", base, j),
+					Meta:       map[string]any{"step_index": i},
+				})
+			}
+		}
+
+		st.validatePostActions(ctx, wid, &step, "on_success_write", step.OnSuccessWrite, i, base)
+		st.validatePostActions(ctx, wid, &step, "on_failure_write", step.OnFailureWrite, i, base)
+		st.validateDocumentation(wid, &step, i, base)
+		st.validateTransitions(wid, wf, &step, i, base)
+	}
+
+	st.validateWriteConflicts(ctx, wid, wf)
+}
+
+// validateTransitions checks the jump targets and conditions of a step and
+// rejects jumps that loop forever
+func (st *walkState) validateTransitions(wid uuid.UUID, wf *definition.Workflow, step *definition.Step, idx int, base string) {
+	for j := range step.Transitions {
+		t := &step.Transitions[j]
+		path := fmt.Sprintf("%s/transitions/%d", base, j)
+		meta := map[string]any{"step_index": idx, "transition_index": j}
+
+		target := wf.StepIndex(t.Goto)
+		if target < 0 {
+			st.report.addError(Issue{
+				Code:       "TRANSITION_001",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Transition goes to unknown step '%s'", t.Goto),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "transitions.goto",
+				Path:       path + "/goto",
+				Meta:       meta,
+			})
+		}
+		if _, err := t.ParseCondition(); err != nil {
+			st.report.addError(Issue{
+				Code:       "TRANSITION_002",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Invalid transition condition: %v", err),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "transitions.condition",
+				Path:       path + "/condition",
+				Hint:       "Reference values as $input.<name>, $variables.<name> or $steps.<number>.<path>",
+				Meta:       meta,
+			})
+		}
+		if t.MaxJumps < 0 {
+			st.report.addError(Issue{
+				Code:       "TRANSITION_003",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Invalid max_jumps: %d", t.MaxJumps),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "transitions.max_jumps",
+				Path:       path + "/max_jumps",
+				Meta:       meta,
+			})
+		}
+		if target < 0 || target > idx || t.MaxJumps != 0 {
+			continue
+		}
+
+		// Backward jump without limit
+		if strings.TrimSpace(t.Condition) == "" && endlessLoop(wf, target) {
+			st.report.addError(Issue{
+				Code:       "TRANSITION_004",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Transition to step '%s' loops forever", t.Goto),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "transitions",
+				Path:       path,
+				Hint:       "Add a condition or max_jumps, or a conditional transition leaving the loop",
+				Meta:       meta,
+			})
+		} else {
+			st.report.addWarning(Issue{
+				Code:       "TRANSITION_005",
+				Severity:   SevWarning,
+				Message:    fmt.Sprintf("Loop back to step '%s' has no max_jumps, limits.max_loop_jumps applies", t.Goto),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "transitions.max_jumps",
+				Path:       path + "/max_jumps",
+				Hint:       "Set max_jumps to the most jumps the loop may take; more fail the execution",
+				Meta:       meta,
+			})
+		}
+	}
+}
+
+// endlessLoop reports whether running the workflow from a step can never
+// leave a loop: no step on the way has a conditional or limited transition,
+// and unconditional jumps lead back to a step already visited
+func endlessLoop(wf *definition.Workflow, from int) bool {
+	visited := map[int]bool{}
+	for i := from; i >= 0 && i < len(wf.Steps); {
+		if visited[i] {
+			return true
+		}
+		visited[i] = true
+
+		next := i + 1
+		for _, t := range wf.Steps[i].Transitions {
+			if strings.TrimSpace(t.Condition) != "" || t.MaxJumps > 0 {
+				return false
+			}
+			next = wf.StepIndex(t.Goto) // The first unconditional transition wins
+			break
+		}
+		i = next
+	}
+	return false
+}
+
+// validateDocumentation checks the datasheet reference of a step
+func (st *walkState) validateDocumentation(wid uuid.UUID, step *definition.Step, idx int, base string) {
+	ref := step.Documentation
+	if ref == nil {
+		return
+	}
+
+	if strings.TrimSpace(ref.DeviceID) == "" && step.DeviceID == "" {
+		st.report.addError(Issue{
+			Code:       "DOC_001",
+			Severity:   SevError,
+			Message:    "documentation requires device_id outside device steps",
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "documentation.device_id",
+			Path:       base + "/documentation/device_id",
+			Meta:       map[string]any{"step_index": idx},
+		})
+	}
+	if ref.Page < 0 {
+		st.report.addError(Issue{
+			Code:       "DOC_002",
+			Severity:   SevError,
+			Message:    fmt.Sprintf("Invalid documentation page: %d", ref.Page),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "documentation.page",
+			Path:       base + "/documentation/page",
+			Meta:       map[string]any{"step_index": idx},
+		})
+	}
+}
+
+// validatePostActions checks the handshake writes of a step
+func (st *walkState) validatePostActions(ctx context.Context, wid uuid.UUID, step *definition.Step, field string, actions []definition.PostAction, idx int, base string) {
+	for j, action := range actions {
+		path := fmt.Sprintf("%s/%s/%d", base, field, j)
+		meta := map[string]any{"step_index": idx, "action_index": j}
+
+		if strings.TrimSpace(action.DeviceID) == "" || strings.TrimSpace(action.Register) == "" {
+			st.report.addError(Issue{
+				Code:       "POSTACTION_001",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("%s requires device_id and register", field),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      field,
+				Path:       path,
+				Meta:       meta,
+			})
+			continue
+		}
+		if action.Value == nil {
+			st.report.addError(Issue{
+				Code:       "POSTACTION_002",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("%s requires a value", field),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      field + ".value",
+				Path:       path + "/value",
+				Meta:       meta,
+			})
+		}
+
+		exists, enabled, err := st.v.storage.DeviceExistsEnabledByName(ctx, action.DeviceID)
+		switch {
+		case err != nil:
+			st.report.addError(Issue{
+				Code:       "DEVICE_999",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Device lookup failed: %v", err),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      field + ".device_id",
+				Path:       path + "/device_id",
+				Meta:       meta,
+			})
+		case !exists || !enabled:
+			message := fmt.Sprintf("Device not found: %s", action.DeviceID)
+			if exists {
+				message = fmt.Sprintf("Device is disabled: %s", action.DeviceID)
+			}
+			st.report.addError(Issue{
+				Code:       "POSTACTION_003",
+				Severity:   SevError,
+				Message:    message,
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      field + ".device_id",
+				Path:       path + "/device_id",
+				Meta:       meta,
+			})
+		}
+
+		if st.v.aliases != nil {
+			if current, deprecated := st.v.aliases(action.DeviceID)[action.Register]; deprecated {
+				st.report.addWarning(Issue{
+					Code:       "DEVICE_030",
+					Severity:   SevWarning,
+					Message:    fmt.Sprintf("Register '%s' is deprecated on device %s, use '%s'", action.Register, action.DeviceID, current),
+					WorkflowID: wid.String(),
+					StepName:   step.Name,
+					Field:      field + ".register",
+					Path:       path + "/register",
+					Hint:       "The alias keeps the write working until it is removed from the device",
+					Meta:       map[string]any{"step_index": idx, "action_index": j, "deprecated": action.Register, "current": current},
+				})
+			}
+		}
+	}
+}
+
+// expandTemplates replaces template steps by their expanded form so the
+// remaining checks see the steps that will actually run
+func (st *walkState) expandTemplates(ctx context.Context, wid uuid.UUID, wf *definition.Workflow) {
+	for _, tmplErr := range wf.ExpandTemplates(ctx, st.v.storage.StepTemplateDefinition) {
+		step := wf.Steps[tmplErr.StepIndex]
+		base := fmt.Sprintf("/steps/%d", tmplErr.StepIndex)
+
+		if errors.Is(tmplErr, storage.ErrStepTemplateNotFound) {
+			st.report.addError(Issue{
+				Code:       "TEMPLATE_001",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Step template not found: %s", tmplErr.Template),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "template",
+				Path:       base + "/template",
+				Meta:       map[string]any{"step_index": tmplErr.StepIndex},
+			})
+			continue
+		}
+
+		st.report.addError(Issue{
+			Code:       "TEMPLATE_002",
+			Severity:   SevError,
+			Message:    fmt.Sprintf("Step template '%s' could not be expanded: %v", tmplErr.Template, tmplErr.Err),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "arguments",
+			Path:       base + "/arguments",
+			Meta:       map[string]any{"step_index": tmplErr.StepIndex},
+		})
+	}
+}
+
+func (st *walkState) validateInputs(wid uuid.UUID, wf *definition.Workflow) {
+	seen := map[string]bool{}
+	for i, param := range wf.Inputs {
+		base := fmt.Sprintf("/inputs/%d", i)
+
+		if strings.TrimSpace(param.Name) == "" {
+			st.report.addError(Issue{
+				Code:       "INPUT_001",
+				Severity:   SevError,
+				Message:    "Input name is required",
+				WorkflowID: wid.String(),
+				Field:      "inputs.name",
+				Path:       base + "/name",
+			})
+			continue
+		}
+		if seen[param.Name] {
+			st.report.addError(Issue{
+				Code:       "INPUT_002",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Duplicate input '%s'", param.Name),
+				WorkflowID: wid.String(),
+				Field:      "inputs.name",
+				Path:       base + "/name",
+			})
+		}
+		seen[param.Name] = true
+
+		if !definition.ValidInputType(param.Type) {
+			st.report.addError(Issue{
+				Code:       "INPUT_003",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Input '%s' has unsupported type '%s'", param.Name, param.Type),
+				WorkflowID: wid.String(),
+				Field:      "inputs.type",
+				Path:       base + "/type",
+				Hint:       "Use string, number, integer, boolean, object or array",
+			})
+			continue
+		}
+
+		if param.Default != nil && !definition.MatchesInputType(param.Type, param.Default) {
+			st.report.addError(Issue{
+				Code:       "INPUT_004",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Default of input '%s' is not of type %s", param.Name, param.Type),
+				WorkflowID: wid.String(),
+				Field:      "inputs.default",
+				Path:       base + "/default",
+			})
+		}
+		if param.Default != nil && param.Required {
+			st.report.addWarning(Issue{
+				Code:       "INPUT_005",
+				Severity:   SevWarning,
+				Message:    fmt.Sprintf("Input '%s' is required but has a default", param.Name),
+				WorkflowID: wid.String(),
+				Field:      "inputs.required",
+				Path:       base + "/required",
+				Hint:       "The default is always used when the input is missing; drop 'required'",
+			})
+		}
+	}
+}
+
+func (st *walkState) validateOutputs(wid uuid.UUID, wf *definition.Workflow) {
+	names := make([]string, 0, len(wf.Outputs))
+	for name := range wf.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ref := wf.Outputs[name]
+		if !wf.HasStep(ref.Step) {
+			st.report.addError(Issue{
+				Code:       "OUTPUT_001",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Output '%s' references unknown step '%s'", name, ref.Step),
+				WorkflowID: wid.String(),
+				Field:      "outputs." + name + ".step",
+				Path:       "/outputs/" + name + "/step",
+				Hint:       "Reference the number of a top-level step",
+			})
+		}
+	}
+}
+
+// validateRequires checks the preconditions checked before an execution starts
+func (st *walkState) validateRequires(ctx context.Context, wid uuid.UUID, wf *definition.Workflow) {
+	if wf.Requires == nil {
+		return
+	}
+
+	lists := []struct {
+		field string
+		names []string
+	}{
+		{"devices", wf.Requires.Devices},
+		{"machine_states", wf.Requires.MachineStates},
+		{"variables", wf.Requires.Variables},
+	}
+	for _, list := range lists {
+		for j, name := range list.names {
+			if strings.TrimSpace(name) == "" {
+				st.report.addError(Issue{
+					Code:       "REQUIRES_001",
+					Severity:   SevError,
+					Message:    fmt.Sprintf("requires.%s entries must not be empty", list.field),
+					WorkflowID: wid.String(),
+					Field:      "requires." + list.field,
+					Path:       fmt.Sprintf("/requires/%s/%d", list.field, j),
+				})
+			}
+		}
+	}
+
+	for j, name := range wf.Requires.Devices {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		exists, _, err := st.v.storage.DeviceExistsEnabledByName(ctx, name)
+		if err != nil || exists {
+			continue
+		}
+		st.report.addError(Issue{
+			Code:       "REQUIRES_002",
+			Severity:   SevError,
+			Message:    fmt.Sprintf("Required device not found: %s", name),
+			WorkflowID: wid.String(),
+			Field:      "requires.devices",
+			Path:       fmt.Sprintf("/requires/devices/%d", j),
+		})
+	}
+
+	inputs := make(map[string]bool, len(wf.Inputs))
+	for _, in := range wf.Inputs {
+		inputs[in.Name] = true
+	}
+	for j, name := range wf.Requires.Variables {
+		if strings.TrimSpace(name) == "" || wf.Variables[name] != "" || inputs[name] {
+			continue
+		}
+		st.report.addWarning(Issue{
+			Code:       "REQUIRES_003",
+			Severity:   SevWarning,
+			Message:    fmt.Sprintf("Required variable '%s' is neither a variable nor a declared input", name),
+			WorkflowID: wid.String(),
+			Field:      "requires.variables",
+			Path:       fmt.Sprintf("/requires/variables/%d", j),
+			Hint:       "Executions are rejected unless the input sets it",
+		})
 	}
 }
 
@@ -322,6 +835,7 @@ func (st *walkState) validateDeviceStep(ctx context.Context, wid uuid.UUID, step
 
 	supported := map[string]struct{}{
 		"read": {}, "write": {}, "read_logical": {}, "write_logical": {}, "read_register": {}, "write_register": {},
+		"write_bits": {}, "check_quality": {}, "batch": {},
 	}
 	if _, ok := supported[op]; !ok {
 		st.report.addError(Issue{
@@ -369,6 +883,25 @@ func (st *walkState) validateDeviceStep(ctx context.Context, wid uuid.UUID, step
 		}
 	}
 
+	st.checkDeprecatedRegister(wid, step, idx, base)
+
+	if op == "batch" && step.Parameters != nil {
+		if _, ok := step.Parameters["items"]; ok {
+			if _, _, err := executor.ParseBatch(step.Parameters); err != nil {
+				st.report.addError(Issue{
+					Code:       "DEVICE_013",
+					Severity:   SevError,
+					Message:    fmt.Sprintf("Invalid batch: %v", err),
+					WorkflowID: wid.String(),
+					StepName:   stepName,
+					Field:      "parameters.items",
+					Path:       base + "/parameters/items",
+					Meta:       map[string]any{"step_index": idx},
+				})
+			}
+		}
+	}
+
 	// Light static checks if register_type is present.
 	if step.Parameters != nil && (op == "read" || op == "write") {
 		if v, ok := step.Parameters["register_type"]; ok {
@@ -388,6 +921,123 @@ func (st *walkState) validateDeviceStep(ctx context.Context, wid uuid.UUID, step
 	}
 }
 
+// checkDeprecatedRegister warns about register parameters using a deprecated name
+func (st *walkState) checkDeprecatedRegister(wid uuid.UUID, step *definition.Step, idx int, base string) {
+	if st.v.aliases == nil || step.Parameters == nil {
+		return
+	}
+	register, ok := step.Parameters["register"].(string)
+	if !ok {
+		return
+	}
+	current, deprecated := st.v.aliases(step.DeviceID)[register]
+	if !deprecated {
+		return
+	}
+
+	st.report.addWarning(Issue{
+		Code:       "DEVICE_030",
+		Severity:   SevWarning,
+		Message:    fmt.Sprintf("Register '%s' is deprecated on device %s, use '%s'", register, step.DeviceID, current),
+		WorkflowID: wid.String(),
+		StepName:   step.Name,
+		Field:      "parameters.register",
+		Path:       base + "/parameters/register",
+		Hint:       "The alias keeps the step working until it is removed from the device",
+		Meta:       map[string]any{"step_index": idx, "deprecated": register, "current": current},
+	})
+}
+
+// validateSampleStep checks the registers captured by a sample step
+func (st *walkState) validateSampleStep(ctx context.Context, wid uuid.UUID, step *definition.Step, idx int, base string) {
+	if len(step.Samples) == 0 {
+		st.report.addError(Issue{
+			Code:       "SAMPLE_001",
+			Severity:   SevError,
+			Message:    "samples are required for sample step",
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "samples",
+			Path:       base + "/samples",
+			Meta:       map[string]any{"step_index": idx},
+		})
+		return
+	}
+
+	keys := map[string]bool{}
+	for j, ref := range step.Samples {
+		path := fmt.Sprintf("%s/samples/%d", base, j)
+		meta := map[string]any{"step_index": idx, "sample_index": j}
+
+		if strings.TrimSpace(ref.DeviceID) == "" || strings.TrimSpace(ref.Register) == "" {
+			st.report.addError(Issue{
+				Code:       "SAMPLE_002",
+				Severity:   SevError,
+				Message:    "sample requires device_id and register",
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "samples",
+				Path:       path,
+				Meta:       meta,
+			})
+			continue
+		}
+
+		key := ref.Key()
+		if key == "samples" || keys[key] {
+			st.report.addError(Issue{
+				Code:       "SAMPLE_003",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Sample output key '%s' is reserved or used twice", key),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "samples.as",
+				Path:       path + "/as",
+				Hint:       "Set a unique 'as' for the sample",
+				Meta:       meta,
+			})
+		}
+		keys[key] = true
+
+		exists, enabled, err := st.v.storage.DeviceExistsEnabledByName(ctx, ref.DeviceID)
+		switch {
+		case err != nil:
+			st.report.addError(Issue{
+				Code:       "DEVICE_999",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Device lookup failed: %v", err),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "samples.device_id",
+				Path:       path + "/device_id",
+				Meta:       meta,
+			})
+		case !exists:
+			st.report.addError(Issue{
+				Code:       "DEVICE_001",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Device not found: %s", ref.DeviceID),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "samples.device_id",
+				Path:       path + "/device_id",
+				Meta:       meta,
+			})
+		case !enabled:
+			st.report.addError(Issue{
+				Code:       "DEVICE_002",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Device is disabled: %s", ref.DeviceID),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "samples.device_id",
+				Path:       path + "/device_id",
+				Meta:       meta,
+			})
+		}
+	}
+}
+
 func requiredParamsForOp(op string) []string {
 	switch op {
 	case "read":
@@ -398,16 +1048,20 @@ func requiredParamsForOp(op string) []string {
 		return []string{"register"}
 	case "write_logical":
 		return []string{"register", "value"}
-	case "read_register":
+	case "read_register", "check_quality":
 		return []string{"register"}
 	case "write_register":
 		return []string{"register", "value"}
+	case "write_bits":
+		return []string{"register", "mask", "value"}
+	case "batch":
+		return []string{"items"}
 	default:
 		return nil
 	}
 }
 
-func (st *walkState) validateSubWorkflowStep(ctx context.Context, wid uuid.UUID, step *definition.Step, idx int, base string) {
+func (st *walkState) validateSubWorkflowStep(ctx context.Context, wid uuid.UUID, wf *definition.Workflow, step *definition.Step, idx int, base string) {
 	stepName := step.Name
 
 	if strings.TrimSpace(step.WorkflowID) == "" {
@@ -467,6 +1121,12 @@ func (st *walkState) validateSubWorkflowStep(ctx context.Context, wid uuid.UUID,
 		return
 	}
 
+	if step.InputMapping != nil || step.OutputMapping != nil {
+		if sub, err := st.getWorkflow(ctx, subID); err == nil {
+			st.validateMappings(wid, wf, sub, step, idx, base)
+		}
+	}
+
 	// Cycle detection: sub-workflow already on stack.
 	if st.visiting[subID] {
 		st.report.addError(Issue{
@@ -488,6 +1148,141 @@ func (st *walkState) validateSubWorkflowStep(ctx context.Context, wid uuid.UUID,
 	st.walk(ctx, subID)
 }
 
+// validateMappings checks the input_mapping and output_mapping of a workflow
+// step against the parent scope and the sub-workflow's declared interface
+func (st *walkState) validateMappings(wid uuid.UUID, wf, sub *definition.Workflow, step *definition.Step, idx int, base string) {
+	declared := map[string]definition.InputParam{}
+	for _, param := range sub.Inputs {
+		declared[param.Name] = param
+	}
+
+	for _, key := range sortedKeys(step.InputMapping) {
+		path := base + "/input_mapping/" + key
+
+		if len(sub.Inputs) > 0 {
+			if _, ok := declared[key]; !ok {
+				st.report.addError(Issue{
+					Code:       "MAPPING_001",
+					Severity:   SevError,
+					Message:    fmt.Sprintf("Sub-workflow declares no input '%s'", key),
+					WorkflowID: wid.String(),
+					StepName:   step.Name,
+					Field:      "input_mapping." + key,
+					Path:       path,
+					Meta:       map[string]any{"step_index": idx},
+				})
+			}
+		}
+
+		value := step.InputMapping[key]
+		if !definition.IsReference(value) {
+			continue
+		}
+		kind, refKey, _, err := definition.ParseReference(value.(string))
+		if err != nil {
+			st.report.addError(Issue{
+				Code:       "MAPPING_002",
+				Severity:   SevError,
+				Message:    err.Error(),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "input_mapping." + key,
+				Path:       path,
+				Meta:       map[string]any{"step_index": idx},
+				Hint:       "Use $input.<key>, $variables.<name> or $steps.<number>.<path>; escape a literal '$' as '$$'",
+			})
+			continue
+		}
+
+		unknown := ""
+		switch kind {
+		case definition.RefVariables:
+			if _, ok := wf.Variables[refKey]; !ok {
+				unknown = fmt.Sprintf("unknown variable '%s'", refKey)
+			}
+		case definition.RefSteps:
+			if !wf.HasStep(refKey) {
+				unknown = fmt.Sprintf("unknown step '%s'", refKey)
+			} else if !stepBefore(wf, refKey, step.Number) {
+				unknown = fmt.Sprintf("step '%s' does not run before this step", refKey)
+			}
+		}
+		if unknown != "" {
+			st.report.addError(Issue{
+				Code:       "MAPPING_003",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("input_mapping.%s references %s", key, unknown),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "input_mapping." + key,
+				Path:       path,
+				Meta:       map[string]any{"step_index": idx},
+			})
+		}
+	}
+
+	if step.InputMapping != nil {
+		for _, param := range sub.Inputs {
+			if _, ok := step.InputMapping[param.Name]; ok || !param.Required || param.Default != nil {
+				continue
+			}
+			st.report.addError(Issue{
+				Code:       "MAPPING_004",
+				Severity:   SevError,
+				Message:    fmt.Sprintf("Required sub-workflow input '%s' is not mapped", param.Name),
+				WorkflowID: wid.String(),
+				StepName:   step.Name,
+				Field:      "input_mapping",
+				Path:       base + "/input_mapping",
+				Meta:       map[string]any{"step_index": idx},
+			})
+		}
+	}
+
+	if len(sub.Outputs) == 0 {
+		return
+	}
+	for _, key := range sortedKeys(step.OutputMapping) {
+		source := step.OutputMapping[key]
+		name, _, _ := strings.Cut(source, ".")
+		if _, ok := sub.Outputs[name]; ok {
+			continue
+		}
+		st.report.addError(Issue{
+			Code:       "MAPPING_005",
+			Severity:   SevError,
+			Message:    fmt.Sprintf("Sub-workflow declares no output '%s'", name),
+			WorkflowID: wid.String(),
+			StepName:   step.Name,
+			Field:      "output_mapping." + key,
+			Path:       base + "/output_mapping/" + key,
+			Meta:       map[string]any{"step_index": idx},
+		})
+	}
+}
+
+// stepBefore reports whether step number ref precedes step number current
+func stepBefore(wf *definition.Workflow, ref, current string) bool {
+	for _, step := range wf.Steps {
+		if step.Number == current {
+			return false
+		}
+		if step.Number == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (st *walkState) cyclePath(target uuid.UUID) []string {
 	start := -1
 	for i := range st.stack {
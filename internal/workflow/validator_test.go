@@ -0,0 +1,152 @@
+package workflow
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/KevinKickass/OpenMachineCore/internal/workflow/definition"
+	"github.com/google/uuid"
+)
+
+// numberedSteps returns steps 10, 20, 30, ... with the transitions given per
+// step
+func numberedSteps(transitions ...[]definition.Transition) []definition.Step {
+	steps := make([]definition.Step, len(transitions))
+	for i := range transitions {
+		number := strconv.Itoa((i + 1) * 10)
+		steps[i] = definition.Step{Number: number, Name: "step_" + number, Transitions: transitions[i]}
+	}
+	return steps
+}
+
+func TestEndlessLoop(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []definition.Step
+		from  int
+		want  bool
+	}{
+		{
+			name:  "runs off the end",
+			steps: numberedSteps(nil, nil, nil),
+			from:  0,
+			want:  false,
+		},
+		{
+			name:  "unconditional jump back",
+			steps: numberedSteps(nil, nil, []definition.Transition{{Goto: "10"}}),
+			from:  0,
+			want:  true,
+		},
+		{
+			name:  "conditional exit in the loop",
+			steps: numberedSteps(nil, []definition.Transition{{Condition: "$steps.10.done", Goto: "40"}}, []definition.Transition{{Goto: "10"}}, nil),
+			from:  0,
+			want:  false,
+		},
+		{
+			name:  "limited jump in the loop",
+			steps: numberedSteps(nil, []definition.Transition{{Goto: "10", MaxJumps: 3}}),
+			from:  0,
+			want:  false,
+		},
+		{
+			name:  "jump to an unknown step leaves",
+			steps: numberedSteps([]definition.Transition{{Goto: "99"}}),
+			from:  0,
+			want:  false,
+		},
+		{
+			name:  "loop entered later",
+			steps: numberedSteps(nil, nil, []definition.Transition{{Goto: "40"}}, []definition.Transition{{Goto: "30"}}),
+			from:  0,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := &definition.Workflow{Steps: tt.steps}
+			if got := endlessLoop(wf, tt.from); got != tt.want {
+				t.Errorf("endlessLoop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTransitions(t *testing.T) {
+	tests := []struct {
+		name         string
+		steps        []definition.Step
+		step         int
+		wantErrors   []string
+		wantWarnings []string
+	}{
+		{
+			name:  "jump forward",
+			steps: numberedSteps([]definition.Transition{{Goto: "30"}}, nil, nil),
+			step:  0,
+		},
+		{
+			name:       "unknown target",
+			steps:      numberedSteps([]definition.Transition{{Goto: "99"}}),
+			step:       0,
+			wantErrors: []string{"TRANSITION_001"},
+		},
+		{
+			name:       "invalid condition",
+			steps:      numberedSteps([]definition.Transition{{Condition: "$steps.10.value ==", Goto: "20"}}, nil),
+			step:       0,
+			wantErrors: []string{"TRANSITION_002"},
+		},
+		{
+			name:       "negative max_jumps",
+			steps:      numberedSteps(nil, []definition.Transition{{Goto: "10", MaxJumps: -1}}),
+			step:       1,
+			wantErrors: []string{"TRANSITION_003"},
+		},
+		{
+			name:       "endless loop",
+			steps:      numberedSteps(nil, []definition.Transition{{Goto: "10"}}),
+			step:       1,
+			wantErrors: []string{"TRANSITION_004"},
+		},
+		{
+			name:         "conditional loop without max_jumps",
+			steps:        numberedSteps(nil, []definition.Transition{{Condition: "$steps.20.value == 0", Goto: "10"}}),
+			step:         1,
+			wantWarnings: []string{"TRANSITION_005"},
+		},
+		{
+			name:  "loop with max_jumps",
+			steps: numberedSteps(nil, []definition.Transition{{Goto: "10", MaxJumps: 3}}),
+			step:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := Report{}
+			st := &walkState{report: &rep}
+			wf := &definition.Workflow{Steps: tt.steps}
+
+			st.validateTransitions(uuid.New(), wf, &wf.Steps[tt.step], tt.step, "/steps/0")
+
+			if got := issueCodes(rep.Errors); !slices.Equal(got, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", got, tt.wantErrors)
+			}
+			if got := issueCodes(rep.Warnings); !slices.Equal(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func issueCodes(issues []Issue) []string {
+	var codes []string
+	for _, issue := range issues {
+		codes = append(codes, issue.Code)
+	}
+	return codes
+}
@@ -0,0 +1,64 @@
+-- Migration 010: Reference integrity for executions, compositions and audit records
+
+-- 1. Remove orphans left behind before the cascades of migration 008 existed
+DELETE FROM execution_events
+WHERE execution_id NOT IN (SELECT id FROM workflow_executions);
+
+DELETE FROM execution_steps
+WHERE execution_id NOT IN (SELECT id FROM workflow_executions);
+
+DELETE FROM workflow_executions
+WHERE workflow_id NOT IN (SELECT id FROM workflows);
+
+DELETE FROM device_compositions
+WHERE device_id IS NULL OR device_id NOT IN (SELECT id FROM devices);
+
+-- 2. Re-create execution foreign keys (idempotent with migration 008)
+ALTER TABLE workflow_executions
+DROP CONSTRAINT IF EXISTS workflow_executions_workflow_id_fkey;
+
+ALTER TABLE workflow_executions
+DROP CONSTRAINT IF EXISTS fk_workflow;
+
+ALTER TABLE workflow_executions
+ADD CONSTRAINT workflow_executions_workflow_id_fkey
+FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE;
+
+-- 3. A composition always belongs to a device
+ALTER TABLE device_compositions
+ALTER COLUMN device_id SET NOT NULL;
+
+ALTER TABLE device_compositions
+DROP CONSTRAINT IF EXISTS device_compositions_device_id_fkey;
+
+ALTER TABLE device_compositions
+ADD CONSTRAINT device_compositions_device_id_fkey
+FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE;
+
+-- 4. Audit records must not block deleting users or tokens
+ALTER TABLE auth_events
+DROP CONSTRAINT IF EXISTS auth_events_user_id_fkey;
+
+ALTER TABLE auth_events
+ADD CONSTRAINT auth_events_user_id_fkey
+FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE auth_events
+DROP CONSTRAINT IF EXISTS auth_events_machine_token_id_fkey;
+
+ALTER TABLE auth_events
+ADD CONSTRAINT auth_events_machine_token_id_fkey
+FOREIGN KEY (machine_token_id) REFERENCES machine_tokens(id) ON DELETE SET NULL;
+
+ALTER TABLE machine_tokens
+DROP CONSTRAINT IF EXISTS machine_tokens_created_by_user_id_fkey;
+
+ALTER TABLE machine_tokens
+ADD CONSTRAINT machine_tokens_created_by_user_id_fkey
+FOREIGN KEY (created_by_user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- 5. Workflow definitions reference devices (steps[].device_id) and
+-- sub-workflows (steps[].workflow_id) inside JSONB, which cannot carry
+-- foreign keys. The storage layer checks these with containment queries.
+CREATE INDEX IF NOT EXISTS idx_workflows_definition
+ON workflows USING GIN (definition jsonb_path_ops);
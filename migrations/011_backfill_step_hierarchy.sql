@@ -0,0 +1,28 @@
+-- Migration 011: Backfill hierarchy data for steps recorded before migration 009
+
+-- Top-level steps: "<program_name>:S<step number>" from the workflow definition
+UPDATE execution_steps es
+SET hierarchical_step_id = COALESCE(NULLIF(w.definition->>'program_name', ''), 'main')
+        || ':S' || COALESCE(w.definition->'steps'->es.step_index->>'number', (es.step_index + 1)::text)
+FROM workflow_executions we
+JOIN workflows w ON w.id = we.workflow_id
+WHERE es.execution_id = we.id
+  AND es.hierarchical_step_id IS NULL;
+
+UPDATE execution_steps SET depth = 0 WHERE depth IS NULL;
+
+ALTER TABLE execution_steps ALTER COLUMN depth SET NOT NULL;
+
+-- Executions: current step ID from the last recorded step
+UPDATE workflow_executions we
+SET current_step_id = (
+    SELECT es.hierarchical_step_id
+    FROM execution_steps es
+    WHERE es.execution_id = we.id
+    ORDER BY es.started_at DESC
+    LIMIT 1
+)
+WHERE we.current_step_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_execution_steps_execution_order
+ON execution_steps(execution_id, step_index, started_at);
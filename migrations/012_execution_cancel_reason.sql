@@ -0,0 +1,12 @@
+-- Migration 012: Record why and by whom an execution was cancelled
+
+ALTER TABLE workflow_executions
+ADD COLUMN cancel_source VARCHAR(20),
+ADD COLUMN cancel_reason TEXT,
+ADD COLUMN cancelled_by VARCHAR(255);
+
+ALTER TABLE workflow_executions
+ADD CONSTRAINT workflow_executions_cancel_source_check
+CHECK (cancel_source IS NULL OR cancel_source IN ('operator', 'machine', 'system'));
+
+COMMENT ON COLUMN workflow_executions.cancel_source IS 'operator = API cancel, machine = machine stop, system = shutdown / emergency stop';
@@ -0,0 +1,10 @@
+-- Migration 013: Link retried executions to the execution they retry
+
+ALTER TABLE workflow_executions
+ADD COLUMN retry_of UUID REFERENCES workflow_executions(id) ON DELETE SET NULL,
+ADD COLUMN start_step INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_workflow_executions_retry_of ON workflow_executions(retry_of);
+
+COMMENT ON COLUMN workflow_executions.retry_of IS 'Failed or cancelled execution this execution retries';
+COMMENT ON COLUMN workflow_executions.start_step IS 'Index of the first executed step; earlier steps were skipped';
@@ -0,0 +1,14 @@
+-- Migration 014: Reusable step templates referenced by workflow steps
+
+CREATE TABLE step_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    template_name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    definition JSONB NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE step_templates IS 'Parameterized steps expanded into referencing workflows at validation and execution time';
+COMMENT ON COLUMN step_templates.version IS 'Incremented on every update';
@@ -0,0 +1,16 @@
+-- Migration 015: API usage counters per user / machine token and endpoint class
+
+CREATE TABLE api_usage (
+    bucket TIMESTAMPTZ NOT NULL,                -- Start of the hour
+    principal_type TEXT NOT NULL,               -- 'user' or 'machine_token'
+    principal_id TEXT NOT NULL,
+    principal_name TEXT NOT NULL DEFAULT '',
+    endpoint_class TEXT NOT NULL,
+    success_count BIGINT NOT NULL DEFAULT 0,
+    failure_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, principal_type, principal_id, endpoint_class)
+);
+
+CREATE INDEX idx_api_usage_principal ON api_usage(principal_type, principal_id, bucket DESC);
+
+COMMENT ON TABLE api_usage IS 'Hourly API call counts, flushed periodically from memory';
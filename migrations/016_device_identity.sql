@@ -0,0 +1,8 @@
+-- Migration 016: Store the identification read from connected hardware
+
+ALTER TABLE devices
+ADD COLUMN identity JSONB,
+ADD COLUMN identified_at TIMESTAMP;
+
+COMMENT ON COLUMN devices.identity IS 'Identification registers read on connect (fields, read errors, mismatches against the configured modules)';
+COMMENT ON COLUMN devices.identified_at IS 'When the identification was last read';
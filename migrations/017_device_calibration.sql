@@ -0,0 +1,30 @@
+-- Migration 017: Per-channel calibration (offset/gain) with history
+
+CREATE TABLE device_calibrations (
+    device_name VARCHAR(255) NOT NULL REFERENCES devices(device_name) ON DELETE CASCADE,
+    register_name VARCHAR(255) NOT NULL,
+    "offset" DOUBLE PRECISION NOT NULL DEFAULT 0,
+    gain DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (gain <> 0),
+    note TEXT NOT NULL DEFAULT '',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (device_name, register_name)
+);
+
+CREATE TABLE device_calibration_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    device_name VARCHAR(255) NOT NULL,
+    register_name VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('set', 'cleared')),
+    "offset" DOUBLE PRECISION,
+    gain DOUBLE PRECISION,
+    note TEXT NOT NULL DEFAULT '',
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_calibration_history_lookup
+ON device_calibration_history (device_name, register_name, changed_at DESC);
+
+COMMENT ON TABLE device_calibrations IS 'Active calibration per device register: value = raw * scale_factor * gain + offset';
+COMMENT ON TABLE device_calibration_history IS 'Every calibration change; kept when the device is deleted';
@@ -0,0 +1,39 @@
+-- Migration 018: Alarm definitions and alarm history
+
+CREATE TABLE alarm_definitions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alarm_name VARCHAR(255) NOT NULL UNIQUE,
+    alarm_text TEXT NOT NULL,
+    priority VARCHAR(20) NOT NULL DEFAULT 'medium' CHECK (priority IN ('low', 'medium', 'high', 'critical')),
+    latch BOOLEAN NOT NULL DEFAULT false,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    condition JSONB,
+    event_type VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((condition IS NULL) <> (event_type IS NULL))
+);
+
+CREATE TABLE alarm_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    definition_id UUID REFERENCES alarm_definitions(id) ON DELETE SET NULL,
+    alarm_name VARCHAR(255) NOT NULL,
+    alarm_text TEXT NOT NULL,
+    priority VARCHAR(20) NOT NULL,
+    latch BOOLEAN NOT NULL DEFAULT false,
+    details JSONB,
+    raised_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    cleared_at TIMESTAMP,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by VARCHAR(255),
+    closed_at TIMESTAMP,
+    closed_by VARCHAR(255)
+);
+
+CREATE INDEX idx_alarm_history_raised ON alarm_history (raised_at DESC);
+CREATE INDEX idx_alarm_history_open ON alarm_history (raised_at) WHERE closed_at IS NULL;
+
+COMMENT ON COLUMN alarm_definitions.condition IS 'Register condition {device, register, operator, value}; NULL for event alarms';
+COMMENT ON COLUMN alarm_definitions.event_type IS 'Event type (glob) raising the alarm, e.g. execution.failed or machine.*';
+COMMENT ON COLUMN alarm_definitions.latch IS 'Latched alarms stay open after the condition clears until they are reset';
+COMMENT ON TABLE alarm_history IS 'One row per alarm occurrence; rows with closed_at NULL are the active alarm list';
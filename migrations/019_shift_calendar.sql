@@ -0,0 +1,50 @@
+-- Migration 019: Shift calendar, automation overrides and audit log
+
+CREATE TABLE shift_patterns (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    shift_name VARCHAR(255) NOT NULL,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time VARCHAR(5) NOT NULL CHECK (start_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    end_time VARCHAR(5) NOT NULL CHECK (end_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE shift_exceptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    exception_date DATE NOT NULL,
+    shift_name VARCHAR(255) NOT NULL DEFAULT '',
+    start_time VARCHAR(5) CHECK (start_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    end_time VARCHAR(5) CHECK (end_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    note TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((start_time IS NULL) = (end_time IS NULL))
+);
+
+CREATE INDEX idx_shift_exceptions_date ON shift_exceptions (exception_date);
+
+CREATE TABLE shift_overrides (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    suspended_until TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    cleared_at TIMESTAMPTZ,
+    cleared_by VARCHAR(255)
+);
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action VARCHAR(100) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    details JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_created ON audit_log (created_at DESC);
+CREATE INDEX idx_audit_log_action ON audit_log (action, created_at DESC);
+
+COMMENT ON COLUMN shift_patterns.weekday IS '0 = Sunday ... 6 = Saturday; end_time before start_time means the shift ends the next day';
+COMMENT ON TABLE shift_exceptions IS 'Exceptions replace the weekly pattern of their date; NULL times mean no production';
+COMMENT ON TABLE shift_overrides IS 'Automatic shift actions are skipped until suspended_until unless the override was cleared';
+COMMENT ON TABLE audit_log IS 'Automatic and manual operational actions (shift automation, overrides, ...)';
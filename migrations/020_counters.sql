@@ -0,0 +1,23 @@
+-- Migration 020: Monotonic totals of rolling device counters
+
+CREATE TABLE counter_totals (
+    counter_name VARCHAR(255) PRIMARY KEY,
+    device_name VARCHAR(255) NOT NULL,
+    register_name VARCHAR(255) NOT NULL,
+    total DOUBLE PRECISION NOT NULL DEFAULT 0,
+    last_value DOUBLE PRECISION,                -- Last sampled register value
+    rollovers BIGINT NOT NULL DEFAULT 0,
+    resets BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE counter_history (
+    counter_name VARCHAR(255) NOT NULL,
+    sampled_at TIMESTAMPTZ NOT NULL,
+    total DOUBLE PRECISION NOT NULL,
+    rate DOUBLE PRECISION NOT NULL,             -- Units per second
+    PRIMARY KEY (counter_name, sampled_at)
+);
+
+COMMENT ON TABLE counter_totals IS 'Accumulated totals; last_value lets counts made while the server was down be picked up';
+COMMENT ON TABLE counter_history IS 'Totals and rates written every counters.persist_interval';
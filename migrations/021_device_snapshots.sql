@@ -0,0 +1,13 @@
+-- Migration 021: Periodic snapshots of the polled device values
+
+CREATE TABLE device_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    device_name VARCHAR(255) NOT NULL,
+    register_values JSONB NOT NULL,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_snapshots_device ON device_snapshots (device_name, captured_at DESC);
+CREATE INDEX idx_device_snapshots_captured ON device_snapshots (captured_at);
+
+COMMENT ON TABLE device_snapshots IS 'Cached register values per device, written every snapshots.interval and kept for snapshots.retention';
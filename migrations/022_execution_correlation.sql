@@ -0,0 +1,14 @@
+-- Migration 022: External correlation IDs (order, batch, serial number) on executions
+
+ALTER TABLE workflow_executions
+ADD COLUMN order_id VARCHAR(255),
+ADD COLUMN batch_id VARCHAR(255),
+ADD COLUMN serial_number VARCHAR(255);
+
+CREATE INDEX idx_workflow_executions_order_id ON workflow_executions(order_id) WHERE order_id IS NOT NULL;
+CREATE INDEX idx_workflow_executions_batch_id ON workflow_executions(batch_id) WHERE batch_id IS NOT NULL;
+CREATE INDEX idx_workflow_executions_serial_number ON workflow_executions(serial_number) WHERE serial_number IS NOT NULL;
+
+COMMENT ON COLUMN workflow_executions.order_id IS 'Production order ID supplied by the MES';
+COMMENT ON COLUMN workflow_executions.batch_id IS 'Batch or lot ID supplied by the MES';
+COMMENT ON COLUMN workflow_executions.serial_number IS 'Serial number of the produced part';
@@ -0,0 +1,19 @@
+-- Migration 023: Encrypted secrets for device credentials and integrations
+
+CREATE TABLE secrets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(128) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    key_id VARCHAR(16) NOT NULL,
+    encrypted_key BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    updated_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE secrets IS 'Credentials encrypted with a per-secret data key (AES-256-GCM), which is encrypted with the master key';
+COMMENT ON COLUMN secrets.key_id IS 'Identifies the master key the data key was encrypted with';
+COMMENT ON COLUMN secrets.encrypted_key IS 'Data key encrypted with the master key, nonce prepended';
+COMMENT ON COLUMN secrets.ciphertext IS 'Value encrypted with the data key and bound to the name, nonce prepended';
@@ -0,0 +1,18 @@
+-- Migration 024: Log lines captured per workflow execution
+
+CREATE TABLE execution_logs (
+    id BIGSERIAL PRIMARY KEY,
+    execution_id UUID NOT NULL REFERENCES workflow_executions(id) ON DELETE CASCADE,
+    step_id UUID,
+    step_name VARCHAR(255),
+    hierarchical_step_id VARCHAR(500),
+    level VARCHAR(10) NOT NULL,
+    message TEXT NOT NULL,
+    fields JSONB,
+    logged_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_execution_logs_execution ON execution_logs (execution_id, logged_at, id);
+
+COMMENT ON TABLE execution_logs IS 'Log lines of the engine and step executor while running an execution, up to execution_logs.max_entries per execution';
+COMMENT ON COLUMN execution_logs.step_id IS 'Execution step (execution_steps.id) the line was logged in, NULL outside of steps';
@@ -0,0 +1,19 @@
+-- Migration 025: Checkpoints of running executions for crash recovery
+
+CREATE TABLE execution_checkpoints (
+    execution_id UUID PRIMARY KEY REFERENCES workflow_executions(id) ON DELETE CASCADE,
+    sequence INT NOT NULL,
+    step_index INT NOT NULL,
+    step_name VARCHAR(255),
+    hierarchical_step_id VARCHAR(500),
+    variables JSONB,
+    results JSONB NOT NULL DEFAULT '{}',
+    resumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE execution_checkpoints IS 'Latest saved state of an execution, overwritten by every checkpoint';
+COMMENT ON COLUMN execution_checkpoints.sequence IS 'Number of checkpoints saved for the execution';
+COMMENT ON COLUMN execution_checkpoints.step_index IS 'Index of the next top-level step to run when resuming';
+COMMENT ON COLUMN execution_checkpoints.results IS 'Outputs of the completed top-level steps by step number';
+COMMENT ON COLUMN execution_checkpoints.resumed_at IS 'When the execution was last resumed from this checkpoint';
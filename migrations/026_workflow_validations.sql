@@ -0,0 +1,14 @@
+-- Migration 026: Results of the latest bulk workflow validation
+
+CREATE TABLE workflow_validations (
+    workflow_id UUID PRIMARY KEY REFERENCES workflows(id) ON DELETE CASCADE,
+    valid BOOLEAN NOT NULL,
+    error_count INT NOT NULL,
+    warning_count INT NOT NULL,
+    lint_profile VARCHAR(100),
+    issues JSONB NOT NULL DEFAULT '[]',
+    validated_at TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE workflow_validations IS 'Latest result of validate-all or the nightly validation per workflow';
+COMMENT ON COLUMN workflow_validations.issues IS 'Errors followed by warnings of the validation report';
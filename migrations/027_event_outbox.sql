@@ -0,0 +1,38 @@
+-- Migration 027: Event outbox for integrations (webhooks, alerting)
+
+CREATE TABLE event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(50) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_outbox_created ON event_outbox(created_at);
+
+CREATE TABLE outbox_cursors (
+    sink VARCHAR(100) PRIMARY KEY,
+    last_event_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE outbox_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    sink VARCHAR(100) NOT NULL,
+    event_id BIGINT NOT NULL REFERENCES event_outbox(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('retrying', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (sink, event_id)
+);
+
+CREATE INDEX idx_outbox_deliveries_due ON outbox_deliveries(sink, status, next_attempt_at);
+
+COMMENT ON TABLE event_outbox IS 'Workflow, machine and device events waiting to be delivered to integration sinks';
+COMMENT ON COLUMN event_outbox.source IS 'workflow, machine or device';
+COMMENT ON TABLE outbox_cursors IS 'Last outbox event each sink has attempted';
+COMMENT ON TABLE outbox_deliveries IS 'Failed deliveries; retried until delivered or dead-lettered';
+COMMENT ON COLUMN outbox_deliveries.status IS 'retrying: waiting for next_attempt_at, dead: attempts exhausted, requeue via the API';
@@ -0,0 +1,14 @@
+-- Migration 028: Composition templates, device presets per machine type
+
+CREATE TABLE composition_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    template_name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    definition JSONB NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE composition_templates IS 'Coupler, terminal stack and IO mapping with ${parameter} placeholders, instantiated as devices';
+COMMENT ON COLUMN composition_templates.version IS 'Incremented on every update; existing devices keep their composition';
@@ -0,0 +1,12 @@
+-- Migration 029: Runtime feature flags
+
+CREATE TABLE feature_flags (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL,
+    workflows JSONB NOT NULL DEFAULT '{}',
+    updated_by VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE feature_flags IS 'Flags changed from their built-in default; deleting a row restores the default';
+COMMENT ON COLUMN feature_flags.workflows IS 'Per-workflow overrides, workflow ID -> enabled';
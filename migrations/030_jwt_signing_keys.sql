@@ -0,0 +1,16 @@
+-- Migration 030: Rotated JWT signing keys
+
+CREATE TABLE jwt_signing_keys (
+    key_id VARCHAR(32) PRIMARY KEY,
+    master_key_id VARCHAR(16),
+    encrypted_key BYTEA,
+    ciphertext BYTEA,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_jwt_signing_keys_retired ON jwt_signing_keys(retired_at);
+
+COMMENT ON TABLE jwt_signing_keys IS 'HS256 keys created by rotation; the key without retired_at signs, retired keys are accepted during the grace period';
+COMMENT ON COLUMN jwt_signing_keys.ciphertext IS 'Key encrypted like secrets; NULL for the configured secret (auth.jwt_secret_env), which only records its retirement';
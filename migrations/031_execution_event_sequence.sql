@@ -0,0 +1,8 @@
+-- Migration 031: Sequence numbers for execution events
+
+-- Existing events are numbered in table order
+ALTER TABLE execution_events ADD COLUMN sequence BIGSERIAL;
+
+CREATE UNIQUE INDEX idx_execution_events_sequence ON execution_events (execution_id, sequence);
+
+COMMENT ON COLUMN execution_events.sequence IS 'Increasing across all executions, so events of one execution are ordered but not gapless';
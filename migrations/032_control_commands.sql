@@ -0,0 +1,19 @@
+-- Migration 032: Control commands of WebSocket clients, for idempotent retries
+
+CREATE TABLE control_commands (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) NOT NULL,
+    command VARCHAR(50) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20),
+    result JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    UNIQUE (actor, request_id)
+);
+
+CREATE INDEX idx_control_commands_created ON control_commands (created_at);
+
+COMMENT ON TABLE control_commands IS 'machine_command and device_write frames of WebSocket clients; a repeated request_id returns the stored result';
+COMMENT ON COLUMN control_commands.status IS 'NULL while executing';
@@ -0,0 +1,13 @@
+-- Migration 033: First-run setup state
+
+CREATE TABLE setup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    completed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_by VARCHAR(255) NOT NULL
+);
+
+-- Installations with users were set up before the setup API existed
+INSERT INTO setup_state (completed_by)
+SELECT 'migration' WHERE EXISTS (SELECT 1 FROM users);
+
+COMMENT ON TABLE setup_state IS 'Single row once the first-run setup is complete; the /setup endpoints are disabled then';
@@ -0,0 +1,12 @@
+-- Migration 034: Execution tags and metadata, searchable with the input
+
+ALTER TABLE workflow_executions
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_workflow_executions_tags ON workflow_executions USING GIN (tags);
+CREATE INDEX idx_workflow_executions_metadata ON workflow_executions USING GIN (metadata jsonb_path_ops);
+CREATE INDEX idx_workflow_executions_input ON workflow_executions USING GIN (input jsonb_path_ops);
+
+COMMENT ON COLUMN workflow_executions.tags IS 'Manual tags and automatic ones from the workflow definition and the current shift';
+COMMENT ON COLUMN workflow_executions.metadata IS 'Workflow name and version, shift and annotations';
@@ -0,0 +1,5 @@
+-- Migration 035: Steps skipped by the step filter of an execution
+
+ALTER TABLE workflow_executions ADD COLUMN skip_steps TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN workflow_executions.skip_steps IS 'Top-level step numbers not executed, from skip_steps and start_at; recorded as skipped in execution_steps';
@@ -0,0 +1,14 @@
+-- Migration 036: Schema version
+-- Migrations are applied by the database init scripts without bookkeeping;
+-- from now on every migration updates this row, so the system status can
+-- report which schema the database has.
+
+CREATE TABLE schema_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_version (version) VALUES (36);
+
+COMMENT ON TABLE schema_version IS 'Single row with the number of the last applied migration';
@@ -0,0 +1,22 @@
+-- Migration 037: Version history of device compositions
+
+CREATE TABLE device_composition_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    instance_id VARCHAR(255) NOT NULL,
+    version INT NOT NULL,
+    composition JSONB NOT NULL,
+    io_mapping JSONB NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    changed_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (instance_id, version)
+);
+
+-- The current compositions become version 1
+INSERT INTO device_composition_versions (instance_id, version, composition, io_mapping, note)
+SELECT instance_id, 1, composition, io_mapping, 'existing composition'
+FROM device_compositions;
+
+UPDATE schema_version SET version = 37, updated_at = NOW();
+
+COMMENT ON TABLE device_composition_versions IS 'Every saved composition of a device, numbered per instance; kept when the device is deleted';
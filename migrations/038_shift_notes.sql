@@ -0,0 +1,22 @@
+-- Migration 038: Shift handover notes
+
+CREATE TABLE shift_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    note_text TEXT NOT NULL,
+    shift_name VARCHAR(255) NOT NULL DEFAULT '',
+    execution_ids UUID[] NOT NULL DEFAULT '{}',
+    devices TEXT[] NOT NULL DEFAULT '{}',
+    alarm_ids UUID[] NOT NULL DEFAULT '{}',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    acknowledged_by VARCHAR(255),
+    acknowledged_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_shift_notes_created_at ON shift_notes (created_at);
+CREATE INDEX idx_shift_notes_unacknowledged ON shift_notes (created_at) WHERE acknowledged_at IS NULL;
+
+UPDATE schema_version SET version = 38, updated_at = NOW();
+
+COMMENT ON TABLE shift_notes IS 'Handover notes written by operators, acknowledged by the next shift';
+COMMENT ON COLUMN shift_notes.shift_name IS 'Shift running when the note was written, empty between shifts';
@@ -0,0 +1,7 @@
+-- Migration 039: Modbus RTU devices have a serial port instead of an IP address
+
+ALTER TABLE devices ALTER COLUMN ip_address DROP NOT NULL;
+
+COMMENT ON COLUMN devices.ip_address IS 'Device IP address or hostname, NULL for Modbus RTU devices';
+
+UPDATE schema_version SET version = 39, updated_at = NOW();
@@ -0,0 +1,17 @@
+-- Migration 040: Per-workflow access lists
+
+CREATE TABLE workflow_acls (
+    workflow_id UUID PRIMARY KEY REFERENCES workflows(id) ON DELETE CASCADE,
+    owner VARCHAR(255) NOT NULL,
+    execute TEXT[] NOT NULL DEFAULT '{}',
+    modify TEXT[] NOT NULL DEFAULT '{}',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+UPDATE schema_version SET version = 40, updated_at = NOW();
+
+COMMENT ON TABLE workflow_acls IS 'Restricts who may execute or modify a workflow; workflows without a row are unrestricted';
+COMMENT ON COLUMN workflow_acls.owner IS 'Principal with full access, e.g. user:alice';
+COMMENT ON COLUMN workflow_acls.execute IS 'Principals allowed to execute: user:<name>, role:<role> or token:<name>';
+COMMENT ON COLUMN workflow_acls.modify IS 'Principals allowed to modify, activate, delete and execute';
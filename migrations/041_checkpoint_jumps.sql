@@ -0,0 +1,7 @@
+-- Migration 041: Checkpoints keep the transition jump counters, so a resumed execution doesn't reset its loop limits
+
+ALTER TABLE execution_checkpoints ADD COLUMN jumps JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN execution_checkpoints.jumps IS 'Jumps taken per transition of a top-level step, keyed <step index>/<transition index>';
+
+UPDATE schema_version SET version = 41, updated_at = NOW();
@@ -0,0 +1,7 @@
+-- Migration 042: Keep the identification confirmed at onboarding, so later connects detect swapped hardware
+
+ALTER TABLE devices ADD COLUMN reference_identity JSONB;
+
+COMMENT ON COLUMN devices.reference_identity IS 'Identification read when the device was onboarded; later reads are compared against it';
+
+UPDATE schema_version SET version = 42, updated_at = NOW();